@@ -0,0 +1,116 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionSynced is the condition type reporting whether the files of a VaultFileImport
+// were written to Vault.
+const ConditionSynced = "Synced"
+
+// Reasons of the Synced condition.
+const (
+	ReasonSynced      = "Synced"
+	ReasonSourceError = "SourceError"
+	ReasonWriteFailed = "WriteFailed"
+)
+
+// Formats of the source of a VaultFileImport.
+const (
+	// FileImportFormatDirectory reads every file of a directory as a key named after the file
+	FileImportFormatDirectory = "Directory"
+	// FileImportFormatJSON reads a file holding a JSON object of keys
+	FileImportFormatJSON = "JSON"
+	// FileImportFormatEnv reads a file of KEY=VALUE lines
+	FileImportFormatEnv = "Env"
+)
+
+// VaultFileImportSpec describes files mounted into the operator that are written to Vault.
+type VaultFileImportSpec struct {
+	// Source is the file or directory to read, relative to the directory of the namespace
+	// under the operator's --import-root
+	// +kubebuilder:validation:MinLength=1
+	Source string `json:"source"`
+
+	// Format is the layout of the source: Directory, JSON or Env
+	// +optional
+	// +kubebuilder:validation:Enum=Directory;JSON;Env
+	// +kubebuilder:default=Directory
+	Format string `json:"format,omitempty"`
+
+	// Path is the Vault path the keys are written to, resolved like vault-sync.io/path
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+
+	// Interval is how often the source is read again, the operator's --import-interval when unset
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// PreserveOnDelete keeps the Vault path when the VaultFileImport is deleted
+	// +optional
+	PreserveOnDelete bool `json:"preserveOnDelete,omitempty"`
+}
+
+// VaultFileImportStatus reports the last import of the files.
+type VaultFileImportStatus struct {
+	// ObservedGeneration is the generation the operator last imported
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// VaultPath is the resolved Vault path the keys were last written to
+	// +optional
+	VaultPath string `json:"vaultPath,omitempty"`
+	// ContentHash is an HMAC of the data last written, keyed with a key only the operator
+	// holds, so unchanged files are not written again
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+	// LastSyncTime is when the data was last written to Vault
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Conditions holds the Synced condition
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Path",type=string,JSONPath=`.spec.path`
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.conditions[?(@.type=="Synced")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VaultFileImport writes secrets from files mounted into the operator to Vault.
+type VaultFileImport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VaultFileImportSpec   `json:"spec,omitempty"`
+	Status VaultFileImportStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VaultFileImportList contains a list of VaultFileImport.
+type VaultFileImportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VaultFileImport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VaultFileImport{}, &VaultFileImportList{})
+}
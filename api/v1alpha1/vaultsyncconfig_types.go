@@ -0,0 +1,176 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionApplied is the condition type reporting whether the operator runs with the
+// current generation of a VaultSyncConfig.
+const ConditionApplied = "Applied"
+
+// Reasons of the Applied condition.
+const (
+	ReasonApplied        = "Applied"
+	ReasonInvalid        = "Invalid"
+	ReasonRestartPending = "RestartPending"
+)
+
+// VaultSyncConfigSpec holds operator-wide configuration. Fields that are set take precedence
+// over the corresponding command-line flags and config file settings.
+type VaultSyncConfigSpec struct {
+	// Vault holds the Vault connection settings
+	// +optional
+	Vault *VaultConnection `json:"vault,omitempty"`
+
+	// ClusterName prefixes every Vault path with clusters/<name>/, as --cluster-name
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?$`
+	ClusterName string `json:"clusterName,omitempty"`
+
+	// NamespaceMounts maps Kubernetes namespaces to the Vault mount prefix their paths are
+	// written under
+	// +optional
+	NamespaceMounts map[string]string `json:"namespaceMounts,omitempty"`
+
+	// VaultNamespaces maps Kubernetes namespaces to the Vault Enterprise namespace their
+	// paths are written to, instead of the namespace of the connection
+	// +optional
+	VaultNamespaces map[string]string `json:"vaultNamespaces,omitempty"`
+
+	// Profiles define the controllers run for each set of namespaces
+	// +optional
+	Profiles []Profile `json:"profiles,omitempty"`
+
+	// SecretTypes allows or denies Secret types per namespace, in addition to --skip-secret-types
+	// +optional
+	SecretTypes []SecretTypeRule `json:"secretTypes,omitempty"`
+	// PreserveOnDeleteNamespaces lists namespace patterns, such as prod-*, whose resources keep
+	// their Vault paths when deleted, as --preserve-on-delete-namespaces
+	// +optional
+	PreserveOnDeleteNamespaces []string `json:"preserveOnDeleteNamespaces,omitempty"`
+	// KVMounts declares KV secrets engine mounts the operator creates when they are missing.
+	// Only applied with --provision-kv-mounts, and existing mounts are never modified.
+	// +optional
+	KVMounts []KVMount `json:"kvMounts,omitempty"`
+
+	// FeatureGates enables or disables operator features, as --feature-gates
+	// +optional
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// VaultConnection holds the settings used to connect and log in to Vault.
+type VaultConnection struct {
+	// Address is the URL of the Vault server
+	// +optional
+	Address string `json:"address,omitempty"`
+	// Role is the Vault Kubernetes auth role
+	// +optional
+	Role string `json:"role,omitempty"`
+	// AuthPath is the mount path of the Kubernetes auth method
+	// +optional
+	AuthPath string `json:"authPath,omitempty"`
+	// Namespace is the Vault Enterprise namespace
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// CACert is the path of a CA certificate file in the operator container
+	// +optional
+	CACert string `json:"caCert,omitempty"`
+}
+
+// Profile runs a set of controllers restricted to a set of namespaces.
+type Profile struct {
+	// Name identifies the profile and is used to name its controllers
+	Name string `json:"name"`
+	// Controllers lists the controller kinds to run
+	// +kubebuilder:validation:MinItems=1
+	Controllers []string `json:"controllers"`
+	// Namespaces restricts the profile to these namespaces. Empty means all namespaces.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// SecretTypeRule allows or denies Secret types in the namespaces matching its patterns.
+type SecretTypeRule struct {
+	// Namespaces lists namespace patterns such as team-*. Empty means all namespaces.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Allow lists the only Secret types that may be synced
+	// +optional
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists Secret types that are never synced
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+}
+
+// KVMount declares a KV secrets engine mount.
+type KVMount struct {
+	// Path is the mount path, such as kv-payments or teams/payments
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+	// Version is the KV engine version, 2 when unset
+	// +optional
+	// +kubebuilder:validation:Enum=1;2
+	Version int `json:"version,omitempty"`
+	// Description is shown in Vault's list of mounts
+	// +optional
+	Description string `json:"description,omitempty"`
+	// MaxVersions is the number of versions kept per secret on a KV v2 mount
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxVersions int `json:"maxVersions,omitempty"`
+}
+
+// VaultSyncConfigStatus reports whether the operator applied the configuration.
+type VaultSyncConfigStatus struct {
+	// ObservedGeneration is the generation the operator last evaluated
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions holds the Applied condition
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Applied",type=string,JSONPath=`.status.conditions[?(@.type=="Applied")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// VaultSyncConfig is the operator-wide configuration of the vault-sync-operator.
+type VaultSyncConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VaultSyncConfigSpec   `json:"spec,omitempty"`
+	Status VaultSyncConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VaultSyncConfigList contains a list of VaultSyncConfig.
+type VaultSyncConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VaultSyncConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VaultSyncConfig{}, &VaultSyncConfigList{})
+}
@@ -2,23 +2,38 @@
 package main
 
 import (
+	"cmp"
+	"context"
 	"flag"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+	"github.com/danieldonoghue/vault-sync-operator/internal/config"
 	"github.com/danieldonoghue/vault-sync-operator/internal/controller"
+	"github.com/danieldonoghue/vault-sync-operator/internal/features"
 	"github.com/danieldonoghue/vault-sync-operator/internal/goruntime"
-	_ "github.com/danieldonoghue/vault-sync-operator/internal/metrics" // Initialize metrics
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
 	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
 
 	// Import automaxprocs to automatically set GOMAXPROCS based on container limits.
@@ -35,9 +50,13 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+// leaderElectionID names the leader election Lease in the operator namespace.
+const leaderElectionID = "vault-sync-operator.io"
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(appsv1.AddToScheme(scheme))
+	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 }
 
 func main() {
@@ -45,25 +64,259 @@ func main() {
 	var enableLeaderElection bool
 	var probeAddr string
 	var vaultAddr string
+	var vaultReplicaAddr string
 	var vaultRole string
 	var vaultAuthPath string
+	var vaultNamespace string
+	var vaultCACert string
+	var vaultConfigDir string
+	var vaultTokenAudience string
+	var vaultTokenTTL time.Duration
+	var vaultTokenServiceAccount string
 	var clusterName string
+	var clusterNamePattern string
 	var showVersion bool
+	var printSchema bool
 	var enableMetricsAuth bool
+	var skipSecretTypes string
+	var sharedSecretsPath string
+	var vaultMaxPendingRequests int
+	var vaultStartupTimeout time.Duration
+	var vaultLazyAuth bool
+	var warmupRate float64
+	var enableDeploymentController bool
+	var enableSecretController bool
+	var warmupTimeout time.Duration
+	var requeueStaggerWindow time.Duration
+	var configFile string
+	var configResourceName string
+	var syncHistorySize int
+	var largeSecretThreshold int
+	var skipAgentInjected bool
+	var waitForRollout bool
+	var transactionalWrites bool
+	var failureBudget int
+	var failureBudgetWindow time.Duration
+	var degradedRetryInterval time.Duration
+	var vaultMetadataKeys string
+	var namespaceCleanup bool
+	var enableImportController bool
+	var importRoot string
+	var importInterval time.Duration
+	var externalSecretPolicy string
+	var stateBackend string
+	var stateHashKeyFile string
+	var disableRotationCheck bool
+	var forceRotationCheck bool
+	var eventAggregationWindow time.Duration
+	var eventBurst int
+	var allowCrossNamespaceRefs bool
+	var crossNamespaceAllowlist string
+	var preserveOnDeleteNamespaces string
+	var selfTest bool
+	var removeFinalizers bool
+	var preserveVaultData bool
+	var selfTestPath string
+	var managedPathInfoMetric bool
+	var metricsPathLabel string
+	var metricsNamespaceAggregation bool
+	var metricsDetailedNamespaces string
+	var policyWebhookURL string
+	var policyWebhookTimeout time.Duration
+	var policyFailOpen bool
+	var vaultHeaders string
+	var versionSkewInterval time.Duration
+	var tokenHandoff bool
+	var logSampleRate float64
+	var check bool
+	var runOnce bool
+	var heartbeatInterval time.Duration
+	var heartbeatPrefix string
+	var clusterIdentityInterval time.Duration
+	var provisionKVMounts bool
+	var aclCheckInterval time.Duration
+	var aclCheckSampleSize int
+	var reconcileBounds controller.ReconcileIntervalBounds
+	var defaultReconcileInterval time.Duration
+	var workloadKindsFlag string
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&tokenHandoff, "token-handoff", false,
+		"With --leader-elect, record the accessor and lifetime of the leader's Vault token on the leader election Lease "+
+			"and revoke the previous leader's token after a failover, if Vault reports it issued to the operator's role.")
+	flag.DurationVar(&versionSkewInterval, "version-skew-interval", controller.DefaultVersionSkewInterval,
+		"How often each replica reports its version to detect mixed versions reconciling during upgrades. Set to 0 to disable.")
 	flag.BoolVar(&enableMetricsAuth, "enable-metrics-auth", true,
 		"Enable authentication and authorization for metrics endpoint. "+
 			"Set to false to disable authentication (not recommended for production).")
-	flag.StringVar(&vaultAddr, "vault-addr", "http://vault:8200", "Vault server address")
+	flag.StringVar(&vaultAddr, "vault-addr", "http://vault:8200",
+		"Vault server address, or comma-separated addresses of the same Vault cluster that requests fail over between when one stops responding.")
+	flag.StringVar(&vaultReplicaAddr, "vault-replica-addr", "",
+		"Address of a Vault Enterprise performance replica used for reads and health checks. Writes always go to --vault-addr.")
 	flag.StringVar(&vaultRole, "vault-role", "vault-sync-operator", "Vault Kubernetes auth role")
 	flag.StringVar(&vaultAuthPath, "vault-auth-path", "kubernetes", "Vault Kubernetes auth path")
+	flag.StringVar(&vaultNamespace, "vault-namespace", "", "Vault Enterprise namespace")
+	flag.StringVar(&vaultCACert, "vault-cacert", "", "Path to a PEM encoded CA bundle used to verify the Vault server")
+	flag.StringVar(&vaultConfigDir, "vault-config-dir", "",
+		"Optional directory (e.g. a mounted Secret) with files named VAULT_ADDR, VAULT_ROLE, VAULT_AUTH_PATH, "+
+			"VAULT_NAMESPACE and ca.crt")
+	flag.StringVar(&vaultTokenAudience, "vault-token-audience", "",
+		"Log in to Vault with a short-lived token for this audience minted with the TokenRequest API "+
+			"instead of the mounted service account token. Disabled when empty.")
+	flag.DurationVar(&vaultTokenTTL, "vault-token-ttl", vault.DefaultTokenRequestTTL,
+		"Lifetime of the tokens minted with --vault-token-audience, at least 10m.")
+	flag.StringVar(&vaultTokenServiceAccount, "vault-token-service-account", "",
+		"Service account in the operator namespace the login tokens are minted for. Defaults to $POD_SERVICE_ACCOUNT.")
+	flag.DurationVar(&vaultStartupTimeout, "vault-startup-timeout", 0,
+		"How long to retry the Vault login with backoff at startup. If Vault is still unavailable, the operator starts "+
+			"unready and keeps retrying instead of exiting. 0 exits when the first login fails.")
+	flag.BoolVar(&vaultLazyAuth, "vault-lazy-auth", false,
+		"Start without logging in to Vault. The first Vault request or readiness check logs in, and a failed login "+
+			"fails only that request, so the operator starts while Vault is down. Overrides --vault-startup-timeout.")
+	flag.StringVar(&vaultHeaders, "vault-headers", "",
+		"Comma-separated Name=value HTTP headers added to every Vault request, e.g. for audit attribution.")
 	flag.StringVar(&clusterName, "cluster-name", "", "Optional cluster name for multi-cluster Vault path organization")
+	flag.StringVar(&clusterNamePattern, "cluster-name-pattern", "",
+		"Regular expression the whole --cluster-name must match, enforcing an organization's naming convention, e.g. (prod|staging)-[a-z]+-[0-9]+.")
+	flag.StringVar(&skipSecretTypes, "skip-secret-types", strings.Join(controller.DefaultSkippedSecretTypes, ","),
+		"Comma-separated list of Secret types that are never synced to Vault. Set to empty to allow all types.")
+	flag.StringVar(&sharedSecretsPath, "shared-secrets-path", "",
+		"Optional Vault base path for shared-secret mode. When set, auto-discovered secrets are written once to "+
+			"<path>/<namespace>/<secret> instead of once per referencing Deployment.")
+	flag.IntVar(&vaultMaxPendingRequests, "vault-max-pending-requests", vault.DefaultMaxPendingRequests,
+		"Number of Vault requests waiting on the rate limiter before reconciles are requeued with a delay. Set to 0 to disable backpressure.")
+	flag.BoolVar(&enableDeploymentController, "enable-deployment-controller", true,
+		"Enable the controller that syncs secrets referenced by annotated Deployments.")
+	flag.BoolVar(&enableSecretController, "enable-secret-controller", true,
+		"Enable the controller that syncs annotated Secrets directly.")
+	flag.Float64Var(&warmupRate, "warmup-rate", 5,
+		"Maximum syncs per second during the startup warm-up. Set to 0 to disable warm-up pacing.")
+	flag.DurationVar(&warmupTimeout, "warmup-timeout", 10*time.Minute,
+		"Maximum duration of the startup warm-up before normal operation resumes.")
+	flag.DurationVar(&requeueStaggerWindow, "requeue-stagger-window", 0,
+		"Window over which the first syncs after startup and periodic requeues are spread by a hash of each object. Set to 0 to disable.")
+	flag.StringVar(&configFile, "config", "",
+		"Optional operator config file. Controller profiles defined there replace the --enable-*-controller flags.")
+	flag.StringVar(&configResourceName, "config-resource", "",
+		"Name of a cluster-scoped VaultSyncConfig whose settings take precedence over the flags and config file. "+
+			"The operator restarts when it changes.")
+	flag.IntVar(&syncHistorySize, "sync-history-size", 0,
+		"Number of recent sync operations kept per resource in the vault-sync-history ConfigMap of its namespace. "+
+			"Disabled by default; each ConfigMap is kept below 256 KiB.")
+	flag.IntVar(&largeSecretThreshold, "large-secret-threshold", controller.DefaultLargeSecretThreshold,
+		"Serialized size in bytes above which a Vault write is reported with a LargeSecret warning event. Set to 0 to disable.")
+	flag.BoolVar(&waitForRollout, "wait-for-rollout", false,
+		"Defer syncing Deployments while they are rolling out, so Vault only reflects the secrets of a healthy revision. "+
+			"The vault-sync.io/wait-for-rollout annotation overrides it per Deployment.")
+	flag.BoolVar(&transactionalWrites, "transactional-writes", false,
+		"Write the sub-paths of an auto-discovery sync all-or-nothing: stage and verify every document, then promote them, "+
+			"rolling back on a partial failure. The vault-sync.io/transactional-writes annotation overrides it per Deployment.")
+	flag.IntVar(&failureBudget, "failure-budget", 0,
+		"Consecutive sync failures, spanning at least --failure-budget-window, after which a resource is degraded and only "+
+			"retried every --degraded-retry-interval, so misconfigured resources do not consume the shared Vault rate limit. "+
+			"Set to 0 to disable.")
+	flag.DurationVar(&failureBudgetWindow, "failure-budget-window", controller.DefaultFailureWindow,
+		"How long a resource must keep failing before --failure-budget degrades it.")
+	flag.DurationVar(&degradedRetryInterval, "degraded-retry-interval", controller.DefaultDegradedRetryInterval,
+		"Interval between sync attempts of a degraded resource.")
+	flag.BoolVar(&namespaceCleanup, "namespace-cleanup", true,
+		"Delete the Vault paths managed in a namespace as soon as the namespace starts terminating, "+
+			"so paths are not left behind when the resources' own deletion races the namespace teardown.")
+	flag.BoolVar(&enableImportController, "enable-import-controller", false,
+		"Enable the VaultFileImport controller, which writes secrets from files mounted under --import-root to Vault.")
+	flag.StringVar(&importRoot, "import-root", "/var/run/vault-sync-import",
+		"Directory the sources of VaultFileImports are read from, with a subdirectory per namespace. "+
+			"Sources cannot reach files outside the directory of their namespace.")
+	flag.DurationVar(&importInterval, "import-interval", controller.DefaultImportInterval,
+		"How often the sources of VaultFileImports are read again, unless a VaultFileImport sets its own interval.")
+	flag.StringVar(&vaultMetadataKeys, "vault-metadata-keys", "",
+		"Comma-separated label and annotation keys copied from synced Deployments into the KV v2 custom_metadata of their Vault paths, e.g. team,cost-center,app-id.")
+	flag.BoolVar(&skipAgentInjected, "skip-agent-injected", false,
+		"Skip Deployments whose Vault agent injection (vault.hashicorp.com annotations) reads the path they sync to.")
+	flag.StringVar(&externalSecretPolicy, "external-secret-policy", controller.ExternalSecretPolicyWarn,
+		"How to handle Secrets managed by the External Secrets Operator: warn (sync and emit a warning), skip, or ignore.")
+	flag.StringVar(&stateBackend, "state-backend", controller.StateBackendAnnotation,
+		"Where the state of the last sync is recorded for rotation detection: annotation (vault-sync.io/secret-versions on each resource) "+
+			"or vault (a content hash in the KV v2 custom_metadata of each path, without writing to the synced resources).")
+	flag.StringVar(&stateHashKeyFile, "state-hash-key-file", "",
+		"File holding the key of the content hashes recorded with --state-backend=vault and in the status of VaultFileImports, at least 32 bytes. Required with --state-backend=vault.")
+	flag.BoolVar(&disableRotationCheck, "disable-rotation-check", false,
+		"Turn off version-based change detection for every resource, so each reconcile writes to Vault.")
+	flag.BoolVar(&forceRotationCheck, "force-rotation-check", false,
+		"Ignore vault-sync.io/rotation-check: disabled on resources, so unchanged Secrets are never written again.")
+	flag.DurationVar(&eventAggregationWindow, "event-aggregation-window", time.Minute,
+		"Window in which identical warning events are deduplicated by reason. Set to 0 to disable aggregation.")
+	flag.IntVar(&eventBurst, "event-burst", 10,
+		"Number of warning events per reason recorded in each aggregation window before further ones are summarized.")
+	flag.Float64Var(&logSampleRate, "log-sample-rate", 1,
+		"Fraction of repetitive INFO lines, such as syncs without changes, that are logged (0 < rate <= 1). Errors are always logged.")
+	flag.BoolVar(&allowCrossNamespaceRefs, "allow-cross-namespace-refs", false,
+		"Allow namespace/name references to Secrets in other namespaces in the vault-sync.io/secrets annotation.")
+	flag.StringVar(&crossNamespaceAllowlist, "cross-namespace-allowlist", "",
+		"Comma-separated namespaces that cross-namespace references may point to, or * for any namespace. Required with --allow-cross-namespace-refs.")
+	flag.StringVar(&preserveOnDeleteNamespaces, "preserve-on-delete-namespaces", "",
+		"Comma-separated namespace patterns (e.g. prod-*) whose resources always keep their Vault paths when deleted, "+
+			"even when annotated with vault-sync.io/preserve-on-delete: \"false\".")
+	flag.BoolVar(&selfTest, "self-test", false,
+		"Run an end-to-end Vault check (authenticate, write, read back and delete a scratch secret) and exit with its status.")
+	flag.StringVar(&selfTestPath, "self-test-path", vault.DefaultSelfTestPath,
+		"Scratch Vault path used by --self-test. The --cluster-name prefix is applied as for synced paths.")
+	flag.BoolVar(&removeFinalizers, "remove-finalizers", false,
+		"Remove the operator's finalizer from every Deployment, Secret and --workload-kinds resource in the cluster and exit, "+
+			"for uninstalling the operator. The Vault data of resources already being deleted is deleted first.")
+	flag.BoolVar(&preserveVaultData, "preserve-vault-data", false,
+		"With --remove-finalizers, keep the Vault data of resources already being deleted instead of deleting it.")
+	flag.BoolVar(&managedPathInfoMetric, "managed-path-info-metric", false,
+		"Export vault_sync_operator_managed_path_info with one series per managed Vault path, labeled by a hash of the path.")
+	flag.StringVar(&metricsPathLabel, "metrics-path-label", string(metrics.PathLabelFull),
+		"Value of the path label of metrics such as vault_sync_operator_vault_write_errors_total: full, mount, hashed or disabled.")
+	flag.BoolVar(&metricsNamespaceAggregation, "metrics-namespace-aggregation", false,
+		"Aggregate sync metrics per namespace by leaving their resource label empty, for clusters with too many per-resource series.")
+	flag.StringVar(&metricsDetailedNamespaces, "metrics-detailed-namespaces", "",
+		"Comma-separated namespaces that keep per-resource sync metrics with --metrics-namespace-aggregation, e.g. while debugging.")
+	flag.StringVar(&policyWebhookURL, "policy-webhook-url", "",
+		"Optional OPA data API or webhook URL asked to allow every Vault write, with resource metadata and the target path as input.")
+	flag.DurationVar(&policyWebhookTimeout, "policy-webhook-timeout", controller.DefaultPolicyTimeout,
+		"Maximum duration of a single policy evaluation.")
+	flag.BoolVar(&policyFailOpen, "policy-fail-open", false,
+		"Allow Vault writes when the policy endpoint cannot be evaluated instead of denying them.")
+	flag.Var(features.DefaultFeatureGate, "feature-gates",
+		"Comma-separated list of Feature=true|false pairs enabling or disabling operator behaviors. Options are:\n"+
+			strings.Join(features.DefaultFeatureGate.KnownFeatures(), "\n"))
+	flag.DurationVar(&reconcileBounds.Min, "min-reconcile-interval", controller.DefaultMinReconcileInterval,
+		"Shortest interval accepted in vault-sync.io/reconcile. Shorter intervals are raised to it.")
+	flag.DurationVar(&reconcileBounds.Max, "max-reconcile-interval", 0,
+		"Longest interval accepted in vault-sync.io/reconcile. Longer intervals are lowered to it. Set to 0 to disable.")
+	flag.DurationVar(&defaultReconcileInterval, "default-reconcile-interval", 0,
+		"Periodic reconciliation interval of resources without vault-sync.io/reconcile. Set to 0 to disable.")
+	flag.StringVar(&workloadKindsFlag, "workload-kinds", "",
+		"Comma-separated Deployment-like kinds synced like Deployments, as Kind.version.group[=pod template path], "+
+			"e.g. Rollout.v1alpha1.argoproj.io.")
+	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", 0,
+		"Interval of synthetic heartbeat writes to <heartbeat-prefix>/_heartbeat in Vault. Set to 0 to disable.")
+	flag.StringVar(&heartbeatPrefix, "heartbeat-prefix", controller.DefaultHeartbeatPrefix,
+		"Vault path prefix of the heartbeat written with --heartbeat-interval.")
+	flag.DurationVar(&clusterIdentityInterval, "cluster-identity-interval", 0,
+		"Interval of the check for another cluster writing to Vault with the same --cluster-name, "+
+			"using a marker under --heartbeat-prefix, e.g. 1m. Only runs with --cluster-name. Set to 0 to disable.")
+	flag.DurationVar(&aclCheckInterval, "acl-check-interval", 0,
+		"Interval of the check that the Vault token can still write a sample of the managed paths. Set to 0 to disable.")
+	flag.IntVar(&aclCheckSampleSize, "acl-check-sample-size", controller.DefaultACLCheckSampleSize,
+		"Number of managed paths checked every --acl-check-interval.")
+	flag.BoolVar(&provisionKVMounts, "provision-kv-mounts", false,
+		"Create the KV mounts declared in kvMounts of the config file or VaultSyncConfig when missing. "+
+			"Requires permissions on sys/mounts.")
+	flag.BoolVar(&check, "check", false,
+		"Validate RBAC permissions, Vault connectivity and annotation usage, print a report and exit.")
+	flag.BoolVar(&runOnce, "run-once", false,
+		"Sync every annotated resource once and exit, with status 1 when any sync fails, instead of running the controllers")
 	flag.BoolVar(&showVersion, "version", false, "Show version information and exit")
+	flag.BoolVar(&printSchema, "print-schema", false,
+		"Print the JSON Schema of the vault-sync.io/secrets annotation and exit.")
 
 	opts := zap.Options{
 		Development: true,
@@ -77,18 +330,166 @@ func main() {
 		os.Exit(0)
 	}
 
+	// The schema is printed for editors and CI validators without connecting anywhere
+	if printSchema {
+		_, _ = os.Stdout.Write(controller.SecretsAnnotationSchema())
+		os.Exit(0)
+	}
+
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if !controller.IsValidExternalSecretPolicy(externalSecretPolicy) {
+		setupLog.Error(fmt.Errorf("invalid external secret policy %q", externalSecretPolicy),
+			"--external-secret-policy must be one of warn, skip or ignore")
+		os.Exit(1)
+	}
+
+	if err := controller.ValidateStateBackend(stateBackend); err != nil {
+		setupLog.Error(err, "--state-backend must be annotation or vault")
+		os.Exit(1)
+	}
+	var stateHashKey []byte
+	if stateBackend == controller.StateBackendVault && stateHashKeyFile == "" {
+		setupLog.Error(fmt.Errorf("missing state hash key"), "--state-backend=vault requires --state-hash-key-file")
+		os.Exit(1)
+	}
+	if stateHashKeyFile != "" {
+		key, err := controller.LoadStateHashKey(stateHashKeyFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load state hash key", "file", stateHashKeyFile)
+			os.Exit(1)
+		}
+		stateHashKey = key
+	}
+
+	rotationCheckPolicy := controller.RotationCheckPolicyAnnotation
+	switch {
+	case disableRotationCheck && forceRotationCheck:
+		setupLog.Error(fmt.Errorf("conflicting rotation check flags"), "--disable-rotation-check and --force-rotation-check are mutually exclusive")
+		os.Exit(1)
+	case disableRotationCheck:
+		rotationCheckPolicy = controller.RotationCheckPolicyDisabled
+	case forceRotationCheck:
+		rotationCheckPolicy = controller.RotationCheckPolicyForced
+	}
+
+	if err := reconcileBounds.Validate(); err != nil {
+		setupLog.Error(err, "invalid --min-reconcile-interval or --max-reconcile-interval")
+		os.Exit(1)
+	}
+	workloadKinds, err := controller.ParseWorkloadKinds(workloadKindsFlag)
+	if err != nil {
+		setupLog.Error(err, "invalid --workload-kinds")
+		os.Exit(1)
+	}
+
+	pathLabelStrategy, err := metrics.ParsePathLabelStrategy(metricsPathLabel)
+	if err != nil {
+		setupLog.Error(err, "invalid --metrics-path-label")
+		os.Exit(1)
+	}
+	metrics.SetPathLabelStrategy(pathLabelStrategy)
+
+	if defaultReconcileInterval < 0 {
+		setupLog.Error(fmt.Errorf("negative interval %s", defaultReconcileInterval), "invalid --default-reconcile-interval")
+		os.Exit(1)
+	}
+
+	// Resolve controller profiles: profiles from the config file take precedence over the enable flags
+	operatorConfig := &config.Config{}
+	if configFile != "" {
+		loaded, err := config.Load(configFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load operator config", "config", configFile)
+			os.Exit(1)
+		}
+		operatorConfig = loaded
+	}
+
+	// Settings of the VaultSyncConfig resource take precedence over the flags and config file
+	configResource := &v1alpha1.VaultSyncConfig{}
+	if configResourceName != "" {
+		directClient, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to create kubernetes client")
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err = directClient.Get(ctx, client.ObjectKey{Name: configResourceName}, configResource)
+		cancel()
+		switch {
+		case apierrors.IsNotFound(err):
+			setupLog.Info("configuration resource not found, using the command-line configuration",
+				"vaultsyncconfig", configResourceName)
+		case err != nil:
+			setupLog.Error(err, "unable to read configuration resource", "vaultsyncconfig", configResourceName)
+			os.Exit(1)
+		default:
+			if err := operatorConfig.ApplyResource(&configResource.Spec); err != nil {
+				setupLog.Error(err, "invalid configuration resource", "vaultsyncconfig", configResourceName)
+				os.Exit(1)
+			}
+			if err := features.DefaultFeatureGate.SetFromMap(configResource.Spec.FeatureGates); err != nil {
+				setupLog.Error(err, "invalid feature gates in configuration resource", "vaultsyncconfig", configResourceName)
+				os.Exit(1)
+			}
+			if configResource.Spec.ClusterName != "" {
+				clusterName = configResource.Spec.ClusterName
+			}
+			if len(configResource.Spec.PreserveOnDeleteNamespaces) > 0 {
+				preserveOnDeleteNamespaces = strings.Join(configResource.Spec.PreserveOnDeleteNamespaces, ",")
+			}
+			setupLog.Info("using configuration resource",
+				"vaultsyncconfig", configResourceName,
+				"generation", configResource.Generation)
+		}
+	}
+	// Refuse cluster names that would produce ambiguous or colliding Vault prefixes
+	clusterNameRegexp, err := controller.CompileClusterNamePattern(clusterNamePattern)
+	if err != nil {
+		setupLog.Error(err, "invalid --cluster-name-pattern")
+		os.Exit(1)
+	}
+	if err := controller.ValidateClusterName(clusterName, clusterNameRegexp); err != nil {
+		setupLog.Error(err, "invalid cluster name")
+		os.Exit(1)
+	}
+	if warning := controller.ClusterNameWarning(clusterName); warning != "" {
+		setupLog.Info(warning)
+	}
+	metrics.ClusterInfo.WithLabelValues(clusterName).Set(1)
+
+	if len(operatorConfig.Profiles) == 0 {
+		if !enableDeploymentController && !enableSecretController {
+			setupLog.Error(fmt.Errorf("no controllers enabled"),
+				"at least one of --enable-deployment-controller or --enable-secret-controller must be set")
+			os.Exit(1)
+		}
+		defaultProfile := config.Profile{}
+		if enableDeploymentController {
+			defaultProfile.Controllers = append(defaultProfile.Controllers, config.ControllerDeployment)
+		}
+		if enableSecretController {
+			defaultProfile.Controllers = append(defaultProfile.Controllers, config.ControllerSecret)
+		}
+		operatorConfig.Profiles = []config.Profile{defaultProfile}
+	}
+
 	// Log version information at startup
 	setupLog.Info("starting vault-sync-operator",
 		"version", version,
 		"commit", commit,
 		"build_date", date)
 
+	setupLog.Info("feature gates", "features", features.DefaultFeatureGate.EnabledFeatures())
+
 	// Log Go runtime configuration for container awareness
 	goruntime.LogRuntimeConfiguration(setupLog)
 	goruntime.ValidateRuntimeConfiguration(setupLog)
 
+	// Also export the workqueue metrics of the controllers in the operator's namespace
+	ctrlmetrics.Registry = metrics.WithWorkqueueMetrics(ctrlmetrics.Registry)
+
 	// Configure metrics options based on authentication setting
 	metricsOptions := metricsserver.Options{
 		BindAddress: metricsAddr,
@@ -105,19 +506,262 @@ func main() {
 		Metrics:                metricsOptions,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "vault-sync-operator.io",
+		LeaderElectionID:       leaderElectionID,
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
-	// Initialize Vault client
-	vaultClient, err := vault.NewClient(vaultAddr, vaultRole, vaultAuthPath)
+	// Resolve Vault settings: flag defaults < config directory < VAULT_* environment < explicit flags
+	vaultConfig := vault.Config{
+		Address:        vaultAddr,
+		ReplicaAddress: vaultReplicaAddr,
+		Role:           vaultRole,
+		AuthPath:       vaultAuthPath,
+		Namespace:      vaultNamespace,
+		CACert:         vaultCACert,
+		UserAgent:      vault.UserAgent(version, clusterName),
+	}
+	if vaultConfig.Headers, err = vault.ParseHeaders(vaultHeaders); err != nil {
+		setupLog.Error(err, "invalid --vault-headers")
+		os.Exit(1)
+	}
+	if vaultConfigDir != "" {
+		if err := vaultConfig.LoadConfigFromDir(vaultConfigDir); err != nil {
+			setupLog.Error(err, "unable to load vault configuration directory", "dir", vaultConfigDir)
+			os.Exit(1)
+		}
+	}
+	vaultConfig.ApplyEnvironment()
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "vault-addr":
+			vaultConfig.Address = vaultAddr
+		case "vault-replica-addr":
+			vaultConfig.ReplicaAddress = vaultReplicaAddr
+		case "vault-role":
+			vaultConfig.Role = vaultRole
+		case "vault-auth-path":
+			vaultConfig.AuthPath = vaultAuthPath
+		case "vault-namespace":
+			vaultConfig.Namespace = vaultNamespace
+		case "vault-cacert":
+			vaultConfig.CACert = vaultCACert
+		}
+	})
+	if connection := configResource.Spec.Vault; connection != nil {
+		vaultConfig.Address = cmp.Or(connection.Address, vaultConfig.Address)
+		vaultConfig.Role = cmp.Or(connection.Role, vaultConfig.Role)
+		vaultConfig.AuthPath = cmp.Or(connection.AuthPath, vaultConfig.AuthPath)
+		vaultConfig.Namespace = cmp.Or(connection.Namespace, vaultConfig.Namespace)
+		vaultConfig.CACert = cmp.Or(connection.CACert, vaultConfig.CACert)
+	}
+	vaultConfig.NamespaceMapping = operatorConfig.VaultNamespaces
+	operatorNamespace := os.Getenv("POD_NAMESPACE")
+	if operatorNamespace == "" {
+		operatorNamespace = "default"
+	}
+
+	// Mint short-lived, audience-bound login tokens instead of reading the mounted token
+	if vaultTokenAudience != "" {
+		if vaultTokenServiceAccount == "" {
+			vaultTokenServiceAccount = os.Getenv("POD_SERVICE_ACCOUNT")
+		}
+		if vaultTokenServiceAccount == "" {
+			setupLog.Error(fmt.Errorf("no service account"),
+				"--vault-token-audience requires --vault-token-service-account or $POD_SERVICE_ACCOUNT")
+			os.Exit(1)
+		}
+		if vaultTokenTTL < vault.DefaultTokenRequestTTL {
+			setupLog.Error(fmt.Errorf("token lifetime %s is shorter than %s", vaultTokenTTL, vault.DefaultTokenRequestTTL),
+				"invalid --vault-token-ttl")
+			os.Exit(1)
+		}
+		vaultConfig.JWTSource = &vault.TokenRequestJWTSource{
+			Client:         mgr.GetClient(),
+			Namespace:      operatorNamespace,
+			ServiceAccount: vaultTokenServiceAccount,
+			Audience:       vaultTokenAudience,
+			TTL:            vaultTokenTTL,
+		}
+	}
+	setupLog.Info("vault configuration",
+		"address", vaultConfig.Address,
+		"role", vaultConfig.Role,
+		"auth_path", vaultConfig.AuthPath,
+		"namespace", vaultConfig.Namespace,
+		"ca_cert", vaultConfig.CACert,
+		"user_agent", vaultConfig.UserAgent,
+		"token_audience", vaultTokenAudience)
+
+	// Run the preflight checks and exit instead of starting the controllers
+	if check {
+		preflight := &controller.Preflight{
+			Client:            mgr.GetClient(),
+			Reader:            mgr.GetAPIReader(),
+			OperatorNamespace: operatorNamespace,
+			ClusterName:       clusterName,
+			NamespaceMounts:   operatorConfig.NamespaceMounts,
+			VaultConfig:       vaultConfig,
+		}
+		allNamespaces := false
+		for _, profile := range operatorConfig.Profiles {
+			preflight.Deployments = preflight.Deployments || profile.Enables(config.ControllerDeployment)
+			preflight.Secrets = preflight.Secrets || profile.Enables(config.ControllerSecret)
+			allNamespaces = allNamespaces || len(profile.Namespaces) == 0
+			preflight.Namespaces = append(preflight.Namespaces, profile.Namespaces...)
+		}
+		if allNamespaces {
+			preflight.Namespaces = nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		report := preflight.Run(ctx)
+		cancel()
+		if err := report.Print(os.Stdout); err != nil {
+			setupLog.Error(err, "unable to print preflight report")
+			os.Exit(1)
+		}
+		if report.Failed() {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	var preservePatterns []string
+	for _, pattern := range strings.Split(preserveOnDeleteNamespaces, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			preservePatterns = append(preservePatterns, pattern)
+		}
+	}
+	preserveOnDelete, err := controller.NewPreserveOnDeletePolicy(preservePatterns)
 	if err != nil {
-		setupLog.Error(err, "unable to initialize vault client")
+		setupLog.Error(err, "invalid --preserve-on-delete-namespaces")
 		os.Exit(1)
 	}
+	if len(preservePatterns) > 0 {
+		setupLog.Info("vault paths preserved on deletion by default", "namespaces", preservePatterns)
+	}
+
+	// Remove the finalizers left on resources and exit, so they stay deletable once the
+	// operator is uninstalled
+	if removeFinalizers {
+		directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create kubernetes client")
+			os.Exit(1)
+		}
+		deploymentKind := appsv1.SchemeGroupVersion.WithKind("Deployment")
+		secretKind := corev1.SchemeGroupVersion.WithKind("Secret")
+		removal := &controller.FinalizerRemoval{
+			Client: directClient,
+			Kinds:  []schema.GroupVersionKind{deploymentKind, secretKind},
+			Log:    ctrl.Log.WithName("remove-finalizers"),
+		}
+		for _, kind := range workloadKinds {
+			removal.Kinds = append(removal.Kinds, kind.GroupVersionKind)
+		}
+
+		// Resources being deleted are finalized by the regular deletion handling
+		if !preserveVaultData {
+			finalizerClient, err := vault.NewClientFromConfig(vaultConfig)
+			if err != nil {
+				setupLog.Error(err, "unable to initialize vault client, use --preserve-vault-data to remove finalizers without vault")
+				os.Exit(1)
+			}
+			deploymentReconciler := &controller.DeploymentReconciler{
+				Client:           directClient,
+				Scheme:           mgr.GetScheme(),
+				Log:              ctrl.Log.WithName("remove-finalizers").WithName("Deployment"),
+				VaultClient:      finalizerClient,
+				ClusterName:      clusterName,
+				NamespaceMounts:  operatorConfig.NamespaceMounts,
+				PreserveOnDelete: preserveOnDelete,
+				APIReader:        directClient,
+			}
+			removal.Finalizers = map[schema.GroupVersionKind]reconcile.Reconciler{
+				deploymentKind: deploymentReconciler,
+				secretKind: &controller.SecretReconciler{
+					Client:           directClient,
+					Scheme:           mgr.GetScheme(),
+					Log:              ctrl.Log.WithName("remove-finalizers").WithName("Secret"),
+					VaultClient:      finalizerClient,
+					ClusterName:      clusterName,
+					NamespaceMounts:  operatorConfig.NamespaceMounts,
+					PreserveOnDelete: preserveOnDelete,
+				},
+			}
+			for _, kind := range workloadKinds {
+				workloadReconciler := *deploymentReconciler
+				workloadReconciler.WorkloadKind = &kind
+				workloadReconciler.Log = ctrl.Log.WithName("remove-finalizers").WithName(kind.Kind)
+				removal.Finalizers[kind.GroupVersionKind] = &workloadReconciler
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		result, err := removal.Run(ctx)
+		cancel()
+		setupLog.Info("finalizer removal finished",
+			"removed", result.Removed,
+			"finalized", result.Finalized,
+			"failed", result.Failed,
+			"vault_data_preserved", preserveVaultData)
+		if err != nil {
+			setupLog.Error(err, "finalizer removal failed")
+			os.Exit(1)
+		}
+		if result.Failed > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Initialize Vault client, logging in on first use with --vault-lazy-auth or waiting up to
+	// --vault-startup-timeout for a successful login
+	var vaultClient *vault.Client
+	if vaultLazyAuth {
+		vaultClient, err = vault.NewUnauthenticatedClient(vaultConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to initialize vault client")
+			os.Exit(1)
+		}
+		setupLog.Info("vault login deferred until first use")
+	} else if vaultStartupTimeout > 0 {
+		vaultClient, err = vault.NewUnauthenticatedClient(vaultConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to initialize vault client")
+			os.Exit(1)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), vaultStartupTimeout)
+		if err := vaultClient.AuthenticateWithRetry(ctx, setupLog); err != nil {
+			setupLog.Error(err, "vault unavailable after --vault-startup-timeout, starting unready until a login succeeds",
+				"timeout", vaultStartupTimeout)
+		}
+		cancel()
+	} else {
+		vaultClient, err = vault.NewClientFromConfig(vaultConfig)
+		if err != nil {
+			setupLog.Error(err, "unable to initialize vault client")
+			os.Exit(1)
+		}
+	}
+	vaultClient.SetMaxPendingRequests(vaultMaxPendingRequests)
+
+	// Run the end-to-end self-test and exit instead of starting the controllers
+	if selfTest {
+		path := controller.ApplyClusterPrefix(selfTestPath, clusterName, false)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := vaultClient.SelfTest(ctx, path)
+		cancel()
+		if err != nil {
+			setupLog.Error(err, "self-test failed", "path", path)
+			os.Exit(1)
+		}
+		setupLog.Info("self-test passed", "path", path)
+		os.Exit(0)
+	}
 
 	// Log cluster configuration
 	if clusterName != "" {
@@ -126,29 +770,517 @@ func main() {
 		setupLog.Info("single-cluster mode (no cluster prefix for vault paths)")
 	}
 
-	if err = (&controller.DeploymentReconciler{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		Log:         ctrl.Log.WithName("controllers").WithName("Deployment"),
-		VaultClient: vaultClient,
-		ClusterName: clusterName,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Deployment")
+	var skippedSecretTypes []string
+	for _, t := range strings.Split(skipSecretTypes, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			skippedSecretTypes = append(skippedSecretTypes, t)
+		}
+	}
+	if len(skippedSecretTypes) > 0 {
+		setupLog.Info("secret types excluded from sync", "types", skippedSecretTypes)
+	}
+	var secretTypes controller.SecretTypePolicy
+	for _, rule := range operatorConfig.SecretTypes {
+		secretTypes.Rules = append(secretTypes.Rules, controller.SecretTypeRule(rule))
+	}
+	if len(secretTypes.Rules) > 0 {
+		setupLog.Info("secret type policy enabled", "rules", len(secretTypes.Rules))
+	}
+
+	var metadataKeys []string
+	for _, key := range strings.Split(vaultMetadataKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			metadataKeys = append(metadataKeys, key)
+		}
+	}
+	if len(metadataKeys) > 0 {
+		setupLog.Info("workload metadata copied to vault custom metadata", "keys", metadataKeys)
+	}
+
+	crossNamespace := controller.CrossNamespacePolicy{Enabled: allowCrossNamespaceRefs}
+	for _, ns := range strings.Split(crossNamespaceAllowlist, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			crossNamespace.AllowedNamespaces = append(crossNamespace.AllowedNamespaces, ns)
+		}
+	}
+	if crossNamespace.Enabled {
+		if len(crossNamespace.AllowedNamespaces) == 0 {
+			setupLog.Error(nil, "--allow-cross-namespace-refs requires --cross-namespace-allowlist, use * to allow any namespace")
+			os.Exit(1)
+		}
+		setupLog.Info("cross-namespace secret references enabled", "allowed_namespaces", crossNamespace.AllowedNamespaces)
+	}
+
+	if metricsNamespaceAggregation {
+		var detailedNamespaces []string
+		for _, ns := range strings.Split(metricsDetailedNamespaces, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				detailedNamespaces = append(detailedNamespaces, ns)
+			}
+		}
+		metrics.SetNamespaceAggregation(detailedNamespaces)
+		setupLog.Info("sync metrics aggregated per namespace", "detailed_namespaces", detailedNamespaces)
+	}
+
+	var sharedSecrets *controller.SharedSecretRegistry
+	if sharedSecretsPath != "" {
+		setupLog.Info("shared-secret mode enabled", "shared_secrets_path", sharedSecretsPath)
+		sharedSecrets = controller.NewSharedSecretRegistry(sharedSecretsPath)
+	}
+
+	// Run-once syncs read through the API server, since the manager and its cache never start
+	syncClient := mgr.GetClient()
+	if runOnce {
+		syncClient, err = client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create kubernetes client")
+			os.Exit(1)
+		}
+	}
+
+	var warmup *controller.WarmupCoordinator
+	if runOnce {
+		// The sweep is paced by the warm-up, which also records the resources that synced
+		ratePerSecond := math.Inf(1)
+		if warmupRate > 0 {
+			ratePerSecond = warmupRate
+		}
+		warmup = controller.NewWarmupCoordinator(mgr.GetAPIReader(), ratePerSecond, warmupTimeout,
+			ctrl.Log.WithName("run-once"))
+	} else if warmupRate > 0 {
+		warmup = controller.NewWarmupCoordinator(mgr.GetAPIReader(), warmupRate, warmupTimeout,
+			ctrl.Log.WithName("warmup"))
+		warmup.Handles = operatorConfig.Handles
+		if err := mgr.Add(warmup); err != nil {
+			setupLog.Error(err, "unable to set up startup warm-up")
+			os.Exit(1)
+		}
+	}
+
+	var stagger *controller.RequeueStagger
+	if requeueStaggerWindow > 0 && !runOnce {
+		stagger = controller.NewRequeueStagger(requeueStaggerWindow)
+	}
+
+	var syncHistory *controller.SyncHistory
+	if syncHistorySize > 0 && features.Enabled(features.SyncHistory) {
+		syncHistory = controller.NewSyncHistory(syncClient, mgr.GetAPIReader(), syncHistorySize, ctrl.Log.WithName("history"))
+	}
+
+	if len(operatorConfig.NamespaceMounts) > 0 {
+		setupLog.Info("namespace mount mapping enabled", "namespace_mounts", operatorConfig.NamespaceMounts)
+	}
+	if len(operatorConfig.VaultNamespaces) > 0 {
+		setupLog.Info("vault namespace mapping enabled", "vault_namespaces", operatorConfig.VaultNamespaces)
+	}
+
+	identity, err := os.Hostname()
+	if err != nil {
+		setupLog.Error(err, "unable to determine replica identity")
+		os.Exit(1)
+	}
+
+	// Report this replica's version so mixed versions reconciling during upgrades are detected
+	if versionSkewInterval > 0 {
+		if err := mgr.Add(&controller.VersionSkewDetector{
+			Client:    mgr.GetClient(),
+			Reader:    mgr.GetAPIReader(),
+			Namespace: operatorNamespace,
+			Identity:  identity,
+			Version:   version,
+			Interval:  versionSkewInterval,
+			Elected:   mgr.Elected(),
+			Log:       ctrl.Log.WithName("version-skew"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up version skew detection")
+			os.Exit(1)
+		}
+	}
+
+	// Revoke the token of the previous leader after a failover instead of leaving it valid
+	if enableLeaderElection && tokenHandoff {
+		if err := mgr.Add(&controller.TokenHandoff{
+			Client:      mgr.GetClient(),
+			Reader:      mgr.GetAPIReader(),
+			VaultClient: vaultClient,
+			Namespace:   operatorNamespace,
+			LeaseName:   leaderElectionID,
+			Identity:    identity,
+			Interval:    controller.DefaultTokenHandoffInterval,
+			Log:         ctrl.Log.WithName("token-handoff"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up vault token handoff")
+			os.Exit(1)
+		}
+	}
+
+	// Write a heartbeat so a broken Vault write path is noticed even when no secret changes
+	if heartbeatInterval > 0 {
+		heartbeatPath := controller.ApplyClusterPrefix(controller.HeartbeatPath(heartbeatPrefix), clusterName, false)
+		setupLog.Info("heartbeat enabled", "path", heartbeatPath, "interval", heartbeatInterval)
+		if err := mgr.Add(&controller.HeartbeatWriter{
+			VaultClient: vaultClient,
+			Path:        heartbeatPath,
+			Interval:    heartbeatInterval,
+			Identity:    identity,
+			Log:         ctrl.Log.WithName("heartbeat"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up heartbeat")
+			os.Exit(1)
+		}
+	}
+
+	// Create the declared KV mounts that are missing; syncs to them are retried until they exist
+	var kvMounts *controller.KVMountProvisioner
+	if provisionKVMounts && len(operatorConfig.KVMounts) > 0 {
+		kvMounts = &controller.KVMountProvisioner{
+			VaultClient: vaultClient,
+			Interval:    controller.DefaultKVMountRetryInterval,
+			Log:         ctrl.Log.WithName("kv-mounts"),
+		}
+		for _, mount := range operatorConfig.KVMounts {
+			kvMounts.Mounts = append(kvMounts.Mounts, vault.KVMount(mount))
+		}
+		setupLog.Info("kv mount provisioning enabled", "mounts", len(kvMounts.Mounts))
+		if !runOnce {
+			if err := mgr.Add(kvMounts); err != nil {
+				setupLog.Error(err, "unable to set up kv mount provisioning")
+				os.Exit(1)
+			}
+		}
+	} else if len(operatorConfig.KVMounts) > 0 {
+		setupLog.Info("kv mounts declared but not provisioned without --provision-kv-mounts", "mounts", len(operatorConfig.KVMounts))
+	}
+
+	// Deduplicate warnings that share a root cause, summarizing them on the operator namespace
+	var recorder events.EventRecorder = mgr.GetEventRecorder("vault-sync-operator")
+	if eventAggregationWindow > 0 && !runOnce {
+		aggregator := controller.NewEventAggregator(recorder, eventAggregationWindow, eventBurst,
+			&corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: operatorNamespace})
+		if err := mgr.Add(aggregator); err != nil {
+			setupLog.Error(err, "unable to set up event aggregation")
+			os.Exit(1)
+		}
+		recorder = aggregator
+	}
+
+	// Detect another cluster writing to the same cluster prefix, which would overwrite our secrets
+	if clusterName != "" && clusterIdentityInterval > 0 {
+		identityPath := controller.ApplyClusterPrefix(controller.ClusterIdentityPath(heartbeatPrefix), clusterName, false)
+		if err := mgr.Add(&controller.ClusterIdentityGuard{
+			VaultClient: vaultClient,
+			Reader:      mgr.GetAPIReader(),
+			Path:        identityPath,
+			ClusterName: clusterName,
+			Identity:    identity,
+			Interval:    clusterIdentityInterval,
+			Recorder:    recorder,
+			Namespace:   operatorNamespace,
+			Log:         ctrl.Log.WithName("cluster-identity"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up cluster identity check")
+			os.Exit(1)
+		}
+	}
+
+	// Shared by every Secret controller so propagation delay is measured once per Secret;
+	// workload reconcilers key changes by workload and each get their own tracker
+	propagation := controller.NewPropagationTracker()
+
+	logSampler, err := controller.NewLogSampler(logSampleRate)
+	if err != nil {
+		setupLog.Error(err, "invalid --log-sample-rate")
+		os.Exit(1)
+	}
+
+	var policy *controller.PolicyHook
+	if policyWebhookURL != "" {
+		setupLog.Info("policy hook enabled", "url", policyWebhookURL, "fail_open", policyFailOpen)
+		policy = &controller.PolicyHook{URL: policyWebhookURL, Timeout: policyWebhookTimeout, FailOpen: policyFailOpen}
+	}
+
+	// Shared by every controller so paths written by several resources are counted once
+	inventory := controller.NewManagedPathInventory(managedPathInfoMetric)
+	secretSizes := controller.NewSecretSizeTracker(largeSecretThreshold)
+
+	// Notice Vault policy changes that take away write access before the next sync fails
+	if aclCheckInterval > 0 && !runOnce {
+		setupLog.Info("acl drift check enabled", "interval", aclCheckInterval, "sample_size", aclCheckSampleSize)
+		if err := mgr.Add(&controller.ACLDriftDetector{
+			VaultClient: vaultClient,
+			Inventory:   inventory,
+			Interval:    aclCheckInterval,
+			SampleSize:  aclCheckSampleSize,
+			Recorder:    recorder,
+			Namespace:   operatorNamespace,
+			Log:         ctrl.Log.WithName("acl-drift"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up acl drift check")
+			os.Exit(1)
+		}
+	}
+
+	// Recent errors and the inventory are summarized on /statusz of the metrics server
+	errorLog := controller.NewErrorLog(controller.DefaultRecentErrors)
+	retryBudget := controller.NewRetryBudget(failureBudget, failureBudgetWindow, degradedRetryInterval)
+	if err := mgr.AddMetricsServerExtraHandler("/statusz", &controller.StatusHandler{
+		Vault:       vaultClient,
+		Inventory:   inventory,
+		Errors:      errorLog,
+		RetryBudget: retryBudget,
+		Gatherer:    ctrlmetrics.Registry,
+		Version:     version,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up status page")
 		os.Exit(1)
 	}
 
-	if err = (&controller.SecretReconciler{
-		Client:      mgr.GetClient(),
-		Scheme:      mgr.GetScheme(),
-		Log:         ctrl.Log.WithName("controllers").WithName("Secret"),
-		VaultClient: vaultClient,
-		ClusterName: clusterName,
-	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Secret")
+	// The schema of the vault-sync.io/secrets annotation is served for admission webhooks and validators
+	if err := mgr.AddMetricsServerExtraHandler(controller.SecretsSchemaPath, controller.SchemaHandler{}); err != nil {
+		setupLog.Error(err, "unable to set up schema endpoint")
+		os.Exit(1)
+	}
+
+	// Secret to Vault path mappings are exported on /inventory of the metrics server
+	if err := mgr.AddMetricsServerExtraHandler("/inventory", &controller.InventoryHandler{
+		Reader:          mgr.GetClient(),
+		WorkloadKinds:   workloadKinds,
+		Handles:         operatorConfig.Handles,
+		ClusterName:     clusterName,
+		NamespaceMounts: operatorConfig.NamespaceMounts,
+		SharedSecrets:   sharedSecrets,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up inventory export")
 		os.Exit(1)
 	}
 
+	// With --run-once every kind is synced by a single sweep instead of controllers
+	deploymentKind := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	secretKind := corev1.SchemeGroupVersion.WithKind("Secret")
+	var sweep *controller.SyncSweep
+	if runOnce {
+		sweep = &controller.SyncSweep{
+			Client:      syncClient,
+			Reconcilers: map[schema.GroupVersionKind]reconcile.Reconciler{},
+			Handles: func(kind schema.GroupVersionKind, namespace string) bool {
+				if kind == secretKind {
+					return operatorConfig.Handles(config.ControllerSecret, namespace)
+				}
+				return operatorConfig.Handles(config.ControllerDeployment, namespace)
+			},
+			Warmup: warmup,
+			Log:    ctrl.Log.WithName("run-once"),
+		}
+	}
+
+	for _, profile := range operatorConfig.Profiles {
+		// Named profiles get their own controller names so several instances can run side by side
+		deploymentName, secretName := "", ""
+		profileLog := ctrl.Log.WithName("controllers")
+		if profile.Name != "" {
+			deploymentName = "deployment-" + profile.Name
+			secretName = "secret-" + profile.Name
+			profileLog = profileLog.WithValues("profile", profile.Name)
+			setupLog.Info("configuring controller profile",
+				"profile", profile.Name,
+				"controllers", profile.Controllers,
+				"namespaces", profile.Namespaces)
+		}
+
+		if profile.Enables(config.ControllerDeployment) {
+			deploymentReconciler := &controller.DeploymentReconciler{
+				Client:                   syncClient,
+				Scheme:                   mgr.GetScheme(),
+				Log:                      profileLog.WithName("Deployment"),
+				VaultClient:              vaultClient,
+				ClusterName:              clusterName,
+				SkippedSecretTypes:       skippedSecretTypes,
+				SecretTypes:              secretTypes,
+				SharedSecrets:            sharedSecrets,
+				Warmup:                   warmup,
+				Stagger:                  stagger,
+				Recorder:                 recorder,
+				History:                  syncHistory,
+				Propagation:              controller.NewPropagationTracker(),
+				SkipAgentInjected:        skipAgentInjected,
+				WaitForRollout:           waitForRollout,
+				TransactionalWrites:      transactionalWrites,
+				MetadataKeys:             metadataKeys,
+				ExternalSecretPolicy:     externalSecretPolicy,
+				NamespaceMounts:          operatorConfig.NamespaceMounts,
+				PreserveOnDelete:         preserveOnDelete,
+				APIReader:                mgr.GetAPIReader(),
+				CrossNamespace:           crossNamespace,
+				Inventory:                inventory,
+				ReconcileBounds:          reconcileBounds,
+				DefaultReconcileInterval: defaultReconcileInterval,
+				Policy:                   policy,
+				LogSampler:               logSampler,
+				Errors:                   errorLog,
+				SecretSizes:              secretSizes,
+				StateBackend:             stateBackend,
+				StateHashKey:             stateHashKey,
+				RotationCheckPolicy:      rotationCheckPolicy,
+				RetryBudget:              retryBudget,
+				Name:                     deploymentName,
+				Namespaces:               profile.Namespaces,
+			}
+			if sweep != nil {
+				// Profiles only differ in their namespaces, which the sweep selects itself
+				sweep.Reconcilers[deploymentKind] = deploymentReconciler
+			} else if err = deploymentReconciler.SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "Deployment", "profile", profile.Name)
+				os.Exit(1)
+			}
+
+			// Deployment-like kinds are synced by copies of the Deployment reconciler
+			for _, kind := range workloadKinds {
+				workloadReconciler := *deploymentReconciler
+				workloadReconciler.WorkloadKind = &kind
+				workloadReconciler.Log = profileLog.WithName(kind.Kind)
+				workloadReconciler.Propagation = controller.NewPropagationTracker()
+				workloadReconciler.Name = ""
+				if profile.Name != "" {
+					workloadReconciler.Name = strings.ToLower(kind.Kind) + "-" + profile.Name
+				}
+				if sweep != nil {
+					sweep.Reconcilers[kind.GroupVersionKind] = &workloadReconciler
+				} else if err = workloadReconciler.SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", kind.String(), "profile", profile.Name)
+					os.Exit(1)
+				}
+			}
+		}
+
+		if profile.Enables(config.ControllerSecret) {
+			secretReconciler := &controller.SecretReconciler{
+				Client:                   syncClient,
+				Scheme:                   mgr.GetScheme(),
+				Log:                      profileLog.WithName("Secret"),
+				VaultClient:              vaultClient,
+				ClusterName:              clusterName,
+				SkippedSecretTypes:       skippedSecretTypes,
+				SecretTypes:              secretTypes,
+				Warmup:                   warmup,
+				Stagger:                  stagger,
+				Recorder:                 recorder,
+				History:                  syncHistory,
+				ExternalSecretPolicy:     externalSecretPolicy,
+				Propagation:              propagation,
+				NamespaceMounts:          operatorConfig.NamespaceMounts,
+				PreserveOnDelete:         preserveOnDelete,
+				CrossNamespace:           crossNamespace,
+				Inventory:                inventory,
+				ReconcileBounds:          reconcileBounds,
+				DefaultReconcileInterval: defaultReconcileInterval,
+				Policy:                   policy,
+				LogSampler:               logSampler,
+				Errors:                   errorLog,
+				SecretSizes:              secretSizes,
+				StateBackend:             stateBackend,
+				StateHashKey:             stateHashKey,
+				RotationCheckPolicy:      rotationCheckPolicy,
+				RetryBudget:              retryBudget,
+				Name:                     secretName,
+				Namespaces:               profile.Namespaces,
+			}
+			if sweep != nil {
+				sweep.Reconcilers[secretKind] = secretReconciler
+			} else if err = secretReconciler.SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "Secret", "profile", profile.Name)
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Sync every annotated resource once and exit instead of starting the controllers
+	if sweep != nil {
+		ctx, cancel := context.WithTimeout(ctrl.SetupSignalHandler(), warmupTimeout)
+		if kvMounts != nil && !kvMounts.Provision(ctx) {
+			setupLog.Info("some kv mounts could not be provisioned, syncs to them will fail")
+		}
+		result, err := sweep.Run(ctx)
+		cancel()
+		setupLog.Info("sync sweep finished",
+			"synced", result.Synced,
+			"skipped", result.Skipped,
+			"failed", result.Failed)
+		if err != nil {
+			setupLog.Error(err, "sync sweep failed")
+			os.Exit(1)
+		}
+		if result.Failed > 0 {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if namespaceCleanup {
+		if err := (&controller.NamespaceReconciler{
+			Client:      mgr.GetClient(),
+			Log:         ctrl.Log.WithName("controllers").WithName("Namespace"),
+			VaultClient: vaultClient,
+			Inventory:   inventory,
+			Recorder:    recorder,
+			SecretSizes: secretSizes,
+			Errors:      errorLog,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Namespace")
+			os.Exit(1)
+		}
+	}
+
+	if enableImportController {
+		if err := (&controller.FileImportReconciler{
+			Client:           mgr.GetClient(),
+			Log:              ctrl.Log.WithName("controllers").WithName("VaultFileImport"),
+			VaultClient:      vaultClient,
+			Root:             importRoot,
+			Interval:         importInterval,
+			ClusterName:      clusterName,
+			NamespaceMounts:  operatorConfig.NamespaceMounts,
+			Inventory:        inventory,
+			Policy:           policy,
+			PreserveOnDelete: preserveOnDelete,
+			Recorder:         recorder,
+			SecretSizes:      secretSizes,
+			Errors:           errorLog,
+			HashKey:          stateHashKey,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "VaultFileImport")
+			os.Exit(1)
+		}
+	}
+
+	// Restart with the new settings when the configuration resource changes
+	ctx, restart := context.WithCancel(ctrl.SetupSignalHandler())
+	defer restart()
+	if configResourceName != "" {
+		if err := (&controller.VaultSyncConfigReconciler{
+			Client:     mgr.GetClient(),
+			Log:        ctrl.Log.WithName("controllers").WithName("VaultSyncConfig"),
+			Name:       configResourceName,
+			Generation: configResource.Generation,
+			Restart:    restart,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "VaultSyncConfig")
+			os.Exit(1)
+		}
+	}
+
+	// A client that could not log in at startup keeps retrying; until then the operator stays
+	// alive but reports unready. Deferred logins are retried by requests and readiness checks.
+	if !vaultLazyAuth && !vaultClient.Authenticated() {
+		if err := mgr.Add(&vault.BackgroundLogin{Client: vaultClient, Log: ctrl.Log.WithName("vault")}); err != nil {
+			setupLog.Error(err, "unable to set up vault login retries")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", func(req *http.Request) error {
+		if !vaultClient.Authenticated() {
+			return nil
+		}
 		return vaultClient.HealthCheck(req.Context())
 	}); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -162,7 +1294,7 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
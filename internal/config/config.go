@@ -0,0 +1,200 @@
+// Package config provides the operator configuration file used to define controller profiles
+// and namespace mount and Vault namespace mappings.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Controller kinds that can be enabled in a profile.
+const (
+	ControllerDeployment = "deployment"
+	ControllerSecret     = "secret"
+)
+
+// Config is the top-level operator configuration file.
+type Config struct {
+	// Profiles define independent controller sets. When empty, the --enable-*-controller flags apply.
+	Profiles []Profile `json:"profiles,omitempty"`
+	// NamespaceMounts maps Kubernetes namespaces to the Vault mount prefix their paths are written under
+	NamespaceMounts map[string]string `json:"namespaceMounts,omitempty"`
+	// VaultNamespaces maps Kubernetes namespaces to the Vault Enterprise namespace their paths are written to
+	VaultNamespaces map[string]string `json:"vaultNamespaces,omitempty"`
+	// SecretTypes allows or denies Secret types per namespace, in addition to --skip-secret-types
+	SecretTypes []SecretTypeRule `json:"secretTypes,omitempty"`
+	// KVMounts declares KV mounts created when missing, with --provision-kv-mounts
+	KVMounts []KVMount `json:"kvMounts,omitempty"`
+}
+
+// KVMount declares a KV secrets engine mount.
+type KVMount struct {
+	// Path is the mount path, such as "kv-payments/"
+	Path string `json:"path"`
+	// Version is the KV engine version, 2 when unset
+	Version int `json:"version,omitempty"`
+	// Description is shown in Vault's list of mounts
+	Description string `json:"description,omitempty"`
+	// MaxVersions is the number of versions kept per secret on a KV v2 mount
+	MaxVersions int `json:"maxVersions,omitempty"`
+}
+
+// SecretTypeRule allows or denies Secret types in the namespaces matching its patterns.
+type SecretTypeRule struct {
+	// Namespaces lists namespace patterns such as "team-*". Empty means all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Allow lists the only Secret types that may be synced
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists Secret types that are never synced
+	Deny []string `json:"deny,omitempty"`
+}
+
+// Profile runs a set of controllers restricted to a set of namespaces.
+type Profile struct {
+	// Name identifies the profile and is used to name its controllers
+	Name string `json:"name"`
+	// Controllers lists the controller kinds to run ("deployment", "secret")
+	Controllers []string `json:"controllers"`
+	// Namespaces restricts the profile to these namespaces. Empty means all namespaces.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// Load reads and validates a configuration file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // Path is provided by the operator administrator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that profiles are well-formed and that no two profiles run the same
+// controller for the same namespace.
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	// owners maps controller kind -> namespace ("" for all) -> profile name
+	owners := map[string]map[string]string{
+		ControllerDeployment: {},
+		ControllerSecret:     {},
+	}
+
+	for namespace, mount := range c.NamespaceMounts {
+		if namespace == "" || strings.Trim(mount, "/") == "" {
+			return fmt.Errorf("namespace mount for namespace %q must not be empty", namespace)
+		}
+	}
+
+	for namespace, vaultNamespace := range c.VaultNamespaces {
+		if namespace == "" || strings.Trim(vaultNamespace, "/") == "" {
+			return fmt.Errorf("vault namespace for namespace %q must not be empty", namespace)
+		}
+	}
+
+	for i, rule := range c.SecretTypes {
+		if len(rule.Allow) == 0 && len(rule.Deny) == 0 {
+			return fmt.Errorf("secret type rule %d must allow or deny at least one type", i)
+		}
+		for _, pattern := range rule.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("secret type rule %d has invalid namespace pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+
+	mountPaths := make(map[string]bool)
+	for _, mount := range c.KVMounts {
+		mountPath := strings.Trim(mount.Path, "/")
+		if mountPath == "" {
+			return fmt.Errorf("kv mount path must not be empty")
+		}
+		if mountPaths[mountPath] {
+			return fmt.Errorf("duplicate kv mount %q", mount.Path)
+		}
+		mountPaths[mountPath] = true
+		if mount.Version != 0 && mount.Version != 1 && mount.Version != 2 {
+			return fmt.Errorf("kv mount %q has unsupported version %d", mount.Path, mount.Version)
+		}
+		if mount.MaxVersions < 0 {
+			return fmt.Errorf("kv mount %q must not have negative maxVersions", mount.Path)
+		}
+	}
+
+	for _, profile := range c.Profiles {
+		if profile.Name == "" {
+			return fmt.Errorf("profile name must not be empty")
+		}
+		if names[profile.Name] {
+			return fmt.Errorf("duplicate profile name %q", profile.Name)
+		}
+		names[profile.Name] = true
+
+		if len(profile.Controllers) == 0 {
+			return fmt.Errorf("profile %q must enable at least one controller", profile.Name)
+		}
+
+		for _, kind := range profile.Controllers {
+			byNamespace, known := owners[kind]
+			if !known {
+				return fmt.Errorf("profile %q has unknown controller %q", profile.Name, kind)
+			}
+
+			namespaces := profile.Namespaces
+			if len(namespaces) == 0 {
+				namespaces = []string{""}
+			}
+			for _, ns := range namespaces {
+				// A cluster-wide profile ("") overlaps with every other profile for the same controller
+				for existing, other := range byNamespace {
+					if existing == ns || existing == "" || ns == "" {
+						return fmt.Errorf("profiles %q and %q both run the %s controller for namespace %q", other, profile.Name, kind, ns)
+					}
+				}
+				byNamespace[ns] = profile.Name
+			}
+		}
+	}
+
+	return nil
+}
+
+// Enables reports whether the profile runs the given controller kind.
+func (p Profile) Enables(kind string) bool {
+	for _, k := range p.Controllers {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Handles reports whether any profile runs the given controller kind for namespace.
+func (c *Config) Handles(kind, namespace string) bool {
+	for _, profile := range c.Profiles {
+		if !profile.Enables(kind) {
+			continue
+		}
+		if len(profile.Namespaces) == 0 {
+			return true
+		}
+		for _, ns := range profile.Namespaces {
+			if ns == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `profiles:
+- name: team-a
+  controllers: [secret]
+  namespaces: [team-a]
+- name: team-b
+  controllers: [deployment, secret]
+  namespaces: [team-b]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Profiles) != 2 {
+		t.Fatalf("Expected 2 profiles, got %d", len(cfg.Profiles))
+	}
+	if !cfg.Handles(ControllerSecret, "team-a") {
+		t.Errorf("Expected team-a secrets to be handled")
+	}
+	if cfg.Handles(ControllerDeployment, "team-a") {
+		t.Errorf("Expected team-a deployments not to be handled")
+	}
+	if !cfg.Handles(ControllerDeployment, "team-b") {
+		t.Errorf("Expected team-b deployments to be handled")
+	}
+	if cfg.Handles(ControllerSecret, "other") {
+		t.Errorf("Expected namespaces outside all profiles not to be handled")
+	}
+}
+
+func TestLoadNamespaceMounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `namespaceMounts:
+  payments: kv-payments/
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.NamespaceMounts["payments"] != "kv-payments/" {
+		t.Errorf("Expected payments to map to kv-payments/, got %q", cfg.NamespaceMounts["payments"])
+	}
+
+	invalid := &Config{NamespaceMounts: map[string]string{"payments": "/"}}
+	if err := invalid.Validate(); err == nil {
+		t.Errorf("Expected an error for an empty mount")
+	}
+}
+
+func TestLoadVaultNamespaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `vaultNamespaces:
+  payments: admin/finance
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VaultNamespaces["payments"] != "admin/finance" {
+		t.Errorf("Expected payments to map to admin/finance, got %q", cfg.VaultNamespaces["payments"])
+	}
+
+	invalid := &Config{VaultNamespaces: map[string]string{"payments": ""}}
+	if err := invalid.Validate(); err == nil {
+		t.Errorf("Expected an error for an empty vault namespace")
+	}
+}
+
+func TestLoadSecretTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `secretTypes:
+- namespaces: ["team-*"]
+  allow: [Opaque, kubernetes.io/tls]
+- deny: [kubernetes.io/dockerconfigjson]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.SecretTypes) != 2 || cfg.SecretTypes[0].Namespaces[0] != "team-*" || len(cfg.SecretTypes[0].Allow) != 2 {
+		t.Errorf("Unexpected secret type rules %+v", cfg.SecretTypes)
+	}
+
+	for _, invalid := range []SecretTypeRule{
+		{Namespaces: []string{"team-*"}},
+		{Namespaces: []string{"team-["}, Deny: []string{"Opaque"}},
+	} {
+		if err := (&Config{SecretTypes: []SecretTypeRule{invalid}}).Validate(); err == nil {
+			t.Errorf("Expected an error for rule %+v", invalid)
+		}
+	}
+}
+
+func TestLoadKVMounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `kvMounts:
+- path: kv-payments/
+  description: Payments team secrets
+  maxVersions: 10
+- path: teams/legacy
+  version: 1
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.KVMounts) != 2 || cfg.KVMounts[0].MaxVersions != 10 || cfg.KVMounts[1].Version != 1 {
+		t.Errorf("Unexpected kv mounts %+v", cfg.KVMounts)
+	}
+
+	for _, invalid := range []KVMount{
+		{Path: "/"},
+		{Path: "kv-payments", Version: 3},
+		{Path: "kv-payments", MaxVersions: -1},
+	} {
+		if err := (&Config{KVMounts: []KVMount{invalid}}).Validate(); err == nil {
+			t.Errorf("Expected an error for mount %+v", invalid)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []Profile
+		wantErr  bool
+	}{
+		{
+			name: "disjoint profiles",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{ControllerSecret}, Namespaces: []string{"a"}},
+				{Name: "b", Controllers: []string{ControllerSecret}, Namespaces: []string{"b"}},
+			},
+		},
+		{
+			name: "same namespace, different controllers",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{ControllerSecret}, Namespaces: []string{"a"}},
+				{Name: "b", Controllers: []string{ControllerDeployment}, Namespaces: []string{"a"}},
+			},
+		},
+		{
+			name: "overlapping namespace",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{ControllerSecret}, Namespaces: []string{"a"}},
+				{Name: "b", Controllers: []string{ControllerSecret}, Namespaces: []string{"a", "b"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cluster-wide profile overlaps namespaced profile",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{ControllerSecret}, Namespaces: []string{"a"}},
+				{Name: "all", Controllers: []string{ControllerSecret}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown controller",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{"configmap"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate name",
+			profiles: []Profile{
+				{Name: "a", Controllers: []string{ControllerSecret}, Namespaces: []string{"a"}},
+				{Name: "a", Controllers: []string{ControllerDeployment}, Namespaces: []string{"b"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "no controllers",
+			profiles: []Profile{
+				{Name: "a"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Profiles: tt.profiles}
+			if err := cfg.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+	"github.com/danieldonoghue/vault-sync-operator/internal/features"
+)
+
+// ApplyResource overrides the namespace mounts, Vault namespaces, profiles, Secret type rules
+// and KV mounts with those set in the spec of a VaultSyncConfig, then validates the result
+// together with the spec's feature gates. Vault settings, the cluster name, preserved
+// namespaces and feature gates are applied by the caller.
+func (c *Config) ApplyResource(spec *v1alpha1.VaultSyncConfigSpec) error {
+	if spec.NamespaceMounts != nil {
+		c.NamespaceMounts = spec.NamespaceMounts
+	}
+	if spec.VaultNamespaces != nil {
+		c.VaultNamespaces = spec.VaultNamespaces
+	}
+	if len(spec.Profiles) > 0 {
+		c.Profiles = make([]Profile, 0, len(spec.Profiles))
+		for _, profile := range spec.Profiles {
+			c.Profiles = append(c.Profiles, Profile{
+				Name:        profile.Name,
+				Controllers: profile.Controllers,
+				Namespaces:  profile.Namespaces,
+			})
+		}
+	}
+
+	if len(spec.SecretTypes) > 0 {
+		c.SecretTypes = make([]SecretTypeRule, 0, len(spec.SecretTypes))
+		for _, rule := range spec.SecretTypes {
+			c.SecretTypes = append(c.SecretTypes, SecretTypeRule{
+				Namespaces: rule.Namespaces,
+				Allow:      rule.Allow,
+				Deny:       rule.Deny,
+			})
+		}
+	}
+
+	if len(spec.KVMounts) > 0 {
+		c.KVMounts = make([]KVMount, 0, len(spec.KVMounts))
+		for _, mount := range spec.KVMounts {
+			c.KVMounts = append(c.KVMounts, KVMount{
+				Path:        mount.Path,
+				Version:     mount.Version,
+				Description: mount.Description,
+				MaxVersions: mount.MaxVersions,
+			})
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	return features.Validate(spec.FeatureGates)
+}
+
+// ValidateResource checks that the spec of a VaultSyncConfig can be applied.
+func ValidateResource(spec *v1alpha1.VaultSyncConfigSpec) error {
+	return (&Config{}).ApplyResource(spec)
+}
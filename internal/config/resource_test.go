@@ -0,0 +1,90 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+)
+
+func TestApplyResource(t *testing.T) {
+	cfg := &Config{
+		NamespaceMounts: map[string]string{"legacy": "kv-legacy/"},
+		Profiles:        []Profile{{Name: "file", Controllers: []string{ControllerDeployment}}},
+	}
+	spec := &v1alpha1.VaultSyncConfigSpec{
+		NamespaceMounts: map[string]string{"payments": "kv-payments/"},
+		Profiles:        []v1alpha1.Profile{{Name: "team-a", Controllers: []string{ControllerSecret}, Namespaces: []string{"team-a"}}},
+		KVMounts:        []v1alpha1.KVMount{{Path: "kv-payments", MaxVersions: 5}},
+		SecretTypes:     []v1alpha1.SecretTypeRule{{Namespaces: []string{"team-*"}, Deny: []string{"kubernetes.io/tls"}}},
+	}
+
+	if err := cfg.ApplyResource(spec); err != nil {
+		t.Fatalf("ApplyResource() error = %v", err)
+	}
+	if len(cfg.NamespaceMounts) != 1 || cfg.NamespaceMounts["payments"] != "kv-payments/" {
+		t.Errorf("namespace mounts = %v, expected only those of the resource", cfg.NamespaceMounts)
+	}
+	if len(cfg.Profiles) != 1 || !cfg.Handles(ControllerSecret, "team-a") || cfg.Handles(ControllerDeployment, "team-a") {
+		t.Errorf("profiles = %+v, expected only the team-a profile of the resource", cfg.Profiles)
+	}
+	if len(cfg.KVMounts) != 1 || cfg.KVMounts[0].Path != "kv-payments" || cfg.KVMounts[0].MaxVersions != 5 {
+		t.Errorf("kv mounts = %+v, expected the kv-payments mount of the resource", cfg.KVMounts)
+	}
+	if len(cfg.SecretTypes) != 1 || cfg.SecretTypes[0].Deny[0] != "kubernetes.io/tls" {
+		t.Errorf("secret types = %+v, expected the rule of the resource", cfg.SecretTypes)
+	}
+
+	// Unset fields keep the config file settings
+	cfg = &Config{Profiles: []Profile{{Name: "file", Controllers: []string{ControllerDeployment}}}}
+	if err := cfg.ApplyResource(&v1alpha1.VaultSyncConfigSpec{}); err != nil {
+		t.Fatalf("ApplyResource() error = %v", err)
+	}
+	if len(cfg.Profiles) != 1 || cfg.Profiles[0].Name != "file" {
+		t.Errorf("profiles = %+v, expected the config file profile", cfg.Profiles)
+	}
+}
+
+func TestValidateResource(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1alpha1.VaultSyncConfigSpec
+		wantErr bool
+	}{
+		{name: "empty", spec: v1alpha1.VaultSyncConfigSpec{}},
+		{
+			name: "valid",
+			spec: v1alpha1.VaultSyncConfigSpec{
+				Profiles:     []v1alpha1.Profile{{Name: "default", Controllers: []string{ControllerDeployment, ControllerSecret}}},
+				FeatureGates: map[string]bool{"SyncHistory": false},
+			},
+		},
+		{
+			name:    "unknown controller",
+			spec:    v1alpha1.VaultSyncConfigSpec{Profiles: []v1alpha1.Profile{{Name: "default", Controllers: []string{"ingress"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "empty mount",
+			spec:    v1alpha1.VaultSyncConfigSpec{NamespaceMounts: map[string]string{"payments": "/"}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate kv mount",
+			spec:    v1alpha1.VaultSyncConfigSpec{KVMounts: []v1alpha1.KVMount{{Path: "kv-payments"}, {Path: "kv-payments/"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown feature gate",
+			spec:    v1alpha1.VaultSyncConfigSpec{FeatureGates: map[string]bool{"UnknownFeature": true}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateResource(&tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateResource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
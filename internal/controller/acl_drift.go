@@ -0,0 +1,153 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the continuous check of the Vault token's permissions.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// DefaultACLCheckSampleSize is the number of managed paths checked by each ACL drift check.
+const DefaultACLCheckSampleSize = 20
+
+// ACLDriftDetector periodically looks up the token's capabilities on a sample of the managed
+// paths, so a Vault policy change that takes away write access is noticed before the next
+// rotation fails. Each check takes the next paths of the inventory in turn, so every path is
+// checked over time. A path that loses a capability it had at an earlier check raises a
+// Warning event on Namespace.
+type ACLDriftDetector struct {
+	VaultClient CapabilitiesChecker
+	Inventory   *ManagedPathInventory
+	Interval    time.Duration
+	// SampleSize is the number of paths checked each interval, DefaultACLCheckSampleSize when 0
+	SampleSize int
+	// Recorder emits a Warning event on Namespace when capabilities are lost (optional)
+	Recorder  events.EventRecorder
+	Namespace string
+	Log       logr.Logger
+
+	// next is the index in the sorted inventory of the first path of the next sample
+	next int
+	// granted holds the capabilities last seen on each checked path
+	granted map[string][]string
+}
+
+// Start checks a sample of paths every interval until ctx is done. It implements manager.Runnable.
+func (d *ACLDriftDetector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			d.check(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection restricts the checks to the replica that reconciles, whose inventory
+// holds the managed paths.
+func (d *ACLDriftDetector) NeedLeaderElection() bool {
+	return true
+}
+
+// sample returns the next paths to check, wrapping around the sorted inventory.
+func (d *ACLDriftDetector) sample(paths []string) []string {
+	size := d.SampleSize
+	if size <= 0 {
+		size = DefaultACLCheckSampleSize
+	}
+	if size >= len(paths) {
+		d.next = 0
+		return paths
+	}
+	if d.next >= len(paths) {
+		d.next = 0
+	}
+	sample := make([]string, 0, size)
+	for i := 0; i < size; i++ {
+		sample = append(sample, paths[(d.next+i)%len(paths)])
+	}
+	d.next = (d.next + size) % len(paths)
+	return sample
+}
+
+// check looks up the capabilities on the next sample of paths and reports the paths that
+// cannot be written and those that lost capabilities.
+func (d *ACLDriftDetector) check(ctx context.Context) {
+	paths := d.Inventory.Paths()
+	if d.granted == nil {
+		d.granted = make(map[string][]string)
+	}
+	// Forget paths that are no longer managed
+	for path := range d.granted {
+		if _, found := slices.BinarySearch(paths, path); !found {
+			delete(d.granted, path)
+		}
+	}
+	if len(paths) == 0 {
+		metrics.VaultUnwritablePaths.Set(0)
+		return
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, d.Interval)
+	defer cancel()
+
+	sample := d.sample(paths)
+	capabilities, err := d.VaultClient.Capabilities(checkCtx, sample)
+	if err != nil {
+		d.Log.Error(err, "failed to check vault token capabilities", "paths", len(sample))
+		return
+	}
+
+	unwritable := 0
+	for _, path := range sample {
+		granted := capabilities[path]
+		if !canWrite(granted) {
+			unwritable++
+			d.Log.Error(fmt.Errorf("vault token cannot write managed path"), "syncs to this path will fail",
+				"path", path, "capabilities", granted)
+		}
+		if lost := lostCapabilities(d.granted[path], granted); len(lost) > 0 {
+			metrics.VaultPermissionReductions.Inc()
+			recordEvent(d.Recorder, &corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: d.Namespace},
+				corev1.EventTypeWarning, "VaultPermissionsReduced", "Check",
+				"Vault token lost %s on %s (capabilities: %s)",
+				strings.Join(lost, ", "), path, strings.Join(granted, ", "))
+		}
+		d.granted[path] = granted
+	}
+	metrics.VaultUnwritablePaths.Set(float64(unwritable))
+}
+
+// canWrite reports whether the capabilities allow writing secrets to a path.
+func canWrite(granted []string) bool {
+	return slices.Contains(granted, "root") || (slices.Contains(granted, "create") && slices.Contains(granted, "update"))
+}
+
+// lostCapabilities returns the capabilities of previous missing from current. Losing root
+// is not reported when current still allows writing.
+func lostCapabilities(previous, current []string) []string {
+	var lost []string
+	for _, capability := range previous {
+		if slices.Contains(current, capability) {
+			continue
+		}
+		if capability == "root" && canWrite(current) {
+			continue
+		}
+		lost = append(lost, capability)
+	}
+	return lost
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// fakeCapabilities returns the capabilities configured per path and records the paths checked.
+type fakeCapabilities struct {
+	granted map[string][]string
+	checked [][]string
+}
+
+func (f *fakeCapabilities) Capabilities(_ context.Context, paths []string) (map[string][]string, error) {
+	f.checked = append(f.checked, paths)
+	capabilities := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		capabilities[path] = f.granted[path]
+	}
+	return capabilities, nil
+}
+
+func TestACLDriftDetector(t *testing.T) {
+	inventory := NewManagedPathInventory(false)
+	inventory.Set("deployment", types.NamespacedName{Namespace: "default", Name: "web"},
+		[]string{"secret/data/a", "secret/data/b", "secret/data/c"})
+	write := []string{"create", "read", "update"}
+	vaultClient := &fakeCapabilities{granted: map[string][]string{
+		"secret/data/a": write,
+		"secret/data/b": write,
+		"secret/data/c": write,
+	}}
+	recorder := events.NewFakeRecorder(10)
+	d := &ACLDriftDetector{
+		VaultClient: vaultClient,
+		Inventory:   inventory,
+		Interval:    time.Minute,
+		SampleSize:  2,
+		Recorder:    recorder,
+		Namespace:   "vault-sync-operator-system",
+		Log:         logr.Discard(),
+	}
+	reductions := testutil.ToFloat64(metrics.VaultPermissionReductions)
+
+	// Samples take the next paths in turn
+	d.check(context.Background())
+	d.check(context.Background())
+	if got := len(vaultClient.checked); got != 2 ||
+		strings.Join(vaultClient.checked[0], ",") != "secret/data/a,secret/data/b" ||
+		strings.Join(vaultClient.checked[1], ",") != "secret/data/c,secret/data/a" {
+		t.Fatalf("checked = %v, expected rotating samples", vaultClient.checked)
+	}
+	if value := testutil.ToFloat64(metrics.VaultUnwritablePaths); value != 0 {
+		t.Errorf("unwritable paths = %v, expected 0", value)
+	}
+
+	// A policy change takes away update on b
+	vaultClient.granted["secret/data/b"] = []string{"create", "read"}
+	d.check(context.Background())
+	if value := testutil.ToFloat64(metrics.VaultUnwritablePaths); value != 1 {
+		t.Errorf("unwritable paths = %v, expected 1", value)
+	}
+	if value := testutil.ToFloat64(metrics.VaultPermissionReductions); value != reductions+1 {
+		t.Errorf("permission reductions = %v, expected %v", value, reductions+1)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "VaultPermissionsReduced") || !strings.Contains(event, "update on secret/data/b") {
+			t.Errorf("event = %q, expected a VaultPermissionsReduced warning naming the lost capability", event)
+		}
+	default:
+		t.Errorf("expected a VaultPermissionsReduced event")
+	}
+
+	// The reduction is reported once, and forgotten with the path
+	d.check(context.Background())
+	d.check(context.Background())
+	if value := testutil.ToFloat64(metrics.VaultPermissionReductions); value != reductions+1 {
+		t.Errorf("permission reductions = %v, expected the reduction to be reported once", value)
+	}
+	inventory.Forget("deployment", types.NamespacedName{Namespace: "default", Name: "web"})
+	d.check(context.Background())
+	if len(d.granted) != 0 {
+		t.Errorf("granted = %v, expected unmanaged paths to be forgotten", d.granted)
+	}
+}
+
+func TestLostCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		current  []string
+		expected string
+	}{
+		{name: "first check", current: []string{"create", "update"}},
+		{name: "unchanged", previous: []string{"create", "update"}, current: []string{"update", "create"}},
+		{name: "gained", previous: []string{"create"}, current: []string{"create", "update"}},
+		{name: "lost", previous: []string{"create", "delete", "update"}, current: []string{"update"}, expected: "create,delete"},
+		{name: "root replaced by write", previous: []string{"root"}, current: []string{"create", "update"}},
+		{name: "root revoked", previous: []string{"root"}, current: []string{"deny"}, expected: "root"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(lostCapabilities(tt.previous, tt.current), ","); got != tt.expected {
+				t.Errorf("lostCapabilities() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file detects Vault agent injection that reads the same Vault paths the operator writes.
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Vault agent injector annotations read from the pod template.
+const (
+	AgentInjectAnnotation             = "vault.hashicorp.com/agent-inject"
+	AgentInjectSecretAnnotationPrefix = "vault.hashicorp.com/agent-inject-secret-" //nolint:gosec // This is an annotation prefix, not a credential
+)
+
+// AgentInjectedPaths returns the Vault paths the agent injector reads for the pod template.
+// It returns nil when agent injection is not enabled.
+func AgentInjectedPaths(template corev1.PodTemplateSpec) []string {
+	if template.Annotations[AgentInjectAnnotation] != "true" {
+		return nil
+	}
+
+	var paths []string
+	for key, value := range template.Annotations {
+		if strings.HasPrefix(key, AgentInjectSecretAnnotationPrefix) && value != "" {
+			paths = append(paths, value)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ConflictingAgentInjectedPaths returns the agent-injected paths that read from vaultPath or
+// one of its sub-paths, which would make the injector consume what the operator just wrote.
+func ConflictingAgentInjectedPaths(template corev1.PodTemplateSpec, vaultPath string) []string {
+	base := strings.Trim(vaultPath, "/")
+
+	var conflicts []string
+	for _, path := range AgentInjectedPaths(template) {
+		injected := strings.Trim(path, "/")
+		if injected == base || strings.HasPrefix(injected, base+"/") {
+			conflicts = append(conflicts, path)
+		}
+	}
+	return conflicts
+}
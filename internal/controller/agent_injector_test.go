@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TestConflictingAgentInjectedPaths tests detection of agent injection reading operator-written paths.
+func TestConflictingAgentInjectedPaths(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		vaultPath   string
+		expected    []string
+	}{
+		{
+			name:        "no injection",
+			annotations: nil,
+			vaultPath:   "secret/data/my-app",
+			expected:    nil,
+		},
+		{
+			name: "injection disabled",
+			annotations: map[string]string{
+				AgentInjectSecretAnnotationPrefix + "config": "secret/data/my-app",
+			},
+			vaultPath: "secret/data/my-app",
+			expected:  nil,
+		},
+		{
+			name: "same path",
+			annotations: map[string]string{
+				AgentInjectAnnotation:                        "true",
+				AgentInjectSecretAnnotationPrefix + "config": "secret/data/my-app",
+			},
+			vaultPath: "secret/data/my-app",
+			expected:  []string{"secret/data/my-app"},
+		},
+		{
+			name: "sub-path written by auto-discovery",
+			annotations: map[string]string{
+				AgentInjectAnnotation:                    "true",
+				AgentInjectSecretAnnotationPrefix + "db": "/secret/data/my-app/db-credentials",
+			},
+			vaultPath: "secret/data/my-app",
+			expected:  []string{"/secret/data/my-app/db-credentials"},
+		},
+		{
+			name: "unrelated path",
+			annotations: map[string]string{
+				AgentInjectAnnotation:                    "true",
+				AgentInjectSecretAnnotationPrefix + "db": "secret/data/my-app-other",
+			},
+			vaultPath: "secret/data/my-app",
+			expected:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := corev1.PodTemplateSpec{}
+			template.Annotations = tt.annotations
+			result := ConflictingAgentInjectedPaths(template, tt.vaultPath)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ConflictingAgentInjectedPaths() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements annotation updates that touch only the operator's own annotation keys.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// jsonPatchPathEscaper escapes annotation keys for use in a JSON Pointer (RFC 6901).
+var jsonPatchPathEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// jsonPatchOperation is a single RFC 6902 JSON Patch operation.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// PatchAnnotations sets annotations on obj with a JSON Patch limited to the given keys.
+// Unlike an Update, the patch carries no resourceVersion and leaves every other field
+// alone, so it neither conflicts with nor reverts concurrent writers such as a
+// HorizontalPodAutoscaler scaling a Deployment. Conflicts are retried against the
+// latest version of the object. obj itself is not modified.
+func PatchAnnotations(ctx context.Context, k8sClient client.Client, obj client.Object, annotations map[string]string) error {
+	current := obj.DeepCopyObject().(client.Object)
+	refresh := false
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(current), current); err != nil {
+				return err
+			}
+		}
+		refresh = true
+
+		patch, err := annotationsJSONPatch(current.GetAnnotations(), annotations)
+		if err != nil {
+			return err
+		}
+		return k8sClient.Patch(ctx, current, client.RawPatch(types.JSONPatchType, patch))
+	})
+}
+
+// annotationsJSONPatch builds a JSON Patch setting the given annotations. When the object has
+// no annotations yet, the map is created as a whole behind a test that it is still absent, so
+// annotations added concurrently by another writer are never replaced.
+func annotationsJSONPatch(existing, annotations map[string]string) ([]byte, error) {
+	var ops []jsonPatchOperation
+	if existing == nil {
+		ops = []jsonPatchOperation{
+			{Op: "test", Path: "/metadata/annotations", Value: nil},
+			{Op: "add", Path: "/metadata/annotations", Value: annotations},
+		}
+	} else {
+		keys := make([]string, 0, len(annotations))
+		for key := range annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			ops = append(ops, jsonPatchOperation{
+				Op:    "add",
+				Path:  "/metadata/annotations/" + jsonPatchPathEscaper.Replace(key),
+				Value: annotations[key],
+			})
+		}
+	}
+
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal annotation patch: %w", err)
+	}
+	return patch, nil
+}
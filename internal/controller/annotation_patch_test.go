@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// newPatchTestDeployment returns a Deployment with the given annotations.
+func newPatchTestDeployment(annotations map[string]string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = annotations
+	deployment.Spec.Replicas = ptr.To[int32](2)
+	return deployment
+}
+
+// TestAnnotationsJSONPatch tests the JSON Patch built for annotation updates.
+func TestAnnotationsJSONPatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		existing    map[string]string
+		annotations map[string]string
+		expected    string
+	}{
+		{
+			name:        "no existing annotations",
+			existing:    nil,
+			annotations: map[string]string{"a": "1"},
+			expected:    `[{"op":"test","path":"/metadata/annotations","value":null},{"op":"add","path":"/metadata/annotations","value":{"a":"1"}}]`,
+		},
+		{
+			name:        "existing annotations",
+			existing:    map[string]string{"other": "x"},
+			annotations: map[string]string{"b": "2", "a": "1"},
+			expected:    `[{"op":"add","path":"/metadata/annotations/a","value":"1"},{"op":"add","path":"/metadata/annotations/b","value":"2"}]`,
+		},
+		{
+			name:        "escaped key",
+			existing:    map[string]string{},
+			annotations: map[string]string{"vault-sync.io/secret-versions": "{}"},
+			expected:    `[{"op":"add","path":"/metadata/annotations/vault-sync.io~1secret-versions","value":"{}"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := annotationsJSONPatch(tt.existing, tt.annotations)
+			if err != nil {
+				t.Fatalf("annotationsJSONPatch() error = %v", err)
+			}
+			if string(patch) != tt.expected {
+				t.Errorf("annotationsJSONPatch() = %s, expected %s", patch, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPatchAnnotationsPreservesConcurrentChanges tests that patching annotations from a
+// stale copy neither fails nor reverts a concurrent scale of the Deployment.
+func TestPatchAnnotationsPreservesConcurrentChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{name: "existing annotations", annotations: map[string]string{VaultPathAnnotation: "secret/web"}},
+		{name: "no annotations", annotations: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			k8sClient := fake.NewClientBuilder().WithObjects(newPatchTestDeployment(tt.annotations)).Build()
+
+			stale := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, stale); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+
+			// Scale the Deployment the way a HorizontalPodAutoscaler would
+			scaled := stale.DeepCopy()
+			scaled.Spec.Replicas = ptr.To[int32](5)
+			if err := k8sClient.Update(ctx, scaled); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			if err := PatchAnnotations(ctx, k8sClient, stale, map[string]string{VaultSecretVersionsAnnotation: `{"db":"2"}`}); err != nil {
+				t.Fatalf("PatchAnnotations() error = %v", err)
+			}
+
+			result := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(stale), result); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if *result.Spec.Replicas != 5 {
+				t.Errorf("replicas = %d, expected 5", *result.Spec.Replicas)
+			}
+			if result.Annotations[VaultSecretVersionsAnnotation] != `{"db":"2"}` {
+				t.Errorf("secret versions annotation = %q", result.Annotations[VaultSecretVersionsAnnotation])
+			}
+			for key, value := range tt.annotations {
+				if result.Annotations[key] != value {
+					t.Errorf("annotation %s = %q, expected %q", key, result.Annotations[key], value)
+				}
+			}
+			if stale.Annotations[VaultSecretVersionsAnnotation] != "" {
+				t.Error("PatchAnnotations() modified the passed object")
+			}
+		})
+	}
+}
+
+// TestPatchAnnotationsConcurrentWriters tests that concurrent patches of different keys are all applied.
+func TestPatchAnnotationsConcurrentWriters(t *testing.T) {
+	ctx := context.Background()
+	deployment := newPatchTestDeployment(map[string]string{VaultPathAnnotation: "secret/web"})
+	k8sClient := fake.NewClientBuilder().WithObjects(deployment).Build()
+
+	const writers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("example.com/writer-%d", i)
+			errs <- PatchAnnotations(ctx, k8sClient, deployment, map[string]string{key: "done"})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("PatchAnnotations() error = %v", err)
+		}
+	}
+
+	result := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), result); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	for i := 0; i < writers; i++ {
+		key := fmt.Sprintf("example.com/writer-%d", i)
+		if result.Annotations[key] != "done" {
+			t.Errorf("annotation %s missing", key)
+		}
+	}
+}
+
+// TestPatchAnnotationsRetriesOnConflict tests that a conflicting patch is retried.
+func TestPatchAnnotationsRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	deployment := newPatchTestDeployment(map[string]string{VaultPathAnnotation: "secret/web"})
+
+	calls := 0
+	k8sClient := fake.NewClientBuilder().
+		WithObjects(deployment).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				calls++
+				if calls == 1 {
+					return apierrors.NewConflict(schema.GroupResource{Group: "apps", Resource: "deployments"}, obj.GetName(), fmt.Errorf("object was modified"))
+				}
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+
+	if err := PatchAnnotations(ctx, k8sClient, deployment, map[string]string{VaultSecretVersionsAnnotation: "{}"}); err != nil {
+		t.Fatalf("PatchAnnotations() error = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("patch calls = %d, expected 2", calls)
+	}
+}
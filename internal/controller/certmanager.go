@@ -0,0 +1,148 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file defers syncing TLS Secrets until their cert-manager Certificate is ready.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/features"
+)
+
+// Markers set by cert-manager on the Secrets it issues.
+const (
+	certManagerGroup                     = "cert-manager.io"
+	certManagerCertificateKind           = "Certificate"
+	CertManagerCertificateNameAnnotation = "cert-manager.io/certificate-name"
+)
+
+// certificateGVK is the cert-manager Certificate kind, read as an unstructured object so the
+// operator does not depend on cert-manager.
+var certificateGVK = schema.GroupVersionKind{Group: certManagerGroup, Version: "v1", Kind: certManagerCertificateKind}
+
+// CertificateRequeueDelay is how often a sync deferred until a Certificate is ready is retried.
+const CertificateRequeueDelay = 30 * time.Second
+
+// certificateRenewalMargin is added to the renewal time of a Certificate before resyncing,
+// leaving cert-manager time to issue the renewed certificate.
+const certificateRenewalMargin = time.Minute
+
+// SkipReasonCertificateNotReady counts syncs deferred until a Certificate is ready.
+const SkipReasonCertificateNotReady = "certificate_not_ready"
+
+// CertificateNotReadyError reports an auto-discovered Secret whose cert-manager Certificate is
+// not ready, so its content may be a temporary or incomplete certificate.
+type CertificateNotReadyError struct {
+	Secret      string
+	Certificate string
+	Reason      string
+}
+
+func (e *CertificateNotReadyError) Error() string {
+	return fmt.Sprintf("certificate %s of secret %s is not ready: %s", e.Certificate, e.Secret, e.Reason)
+}
+
+// CertificateName returns the name of the cert-manager Certificate that issued the Secret, from
+// its owner reference or cert-manager's certificate-name annotation, or "" when there is none.
+func CertificateName(secret *corev1.Secret) string {
+	for _, owner := range secret.OwnerReferences {
+		group, _, _ := strings.Cut(owner.APIVersion, "/")
+		if owner.Kind == certManagerCertificateKind && group == certManagerGroup {
+			return owner.Name
+		}
+	}
+	return secret.Annotations[CertManagerCertificateNameAnnotation]
+}
+
+// CertificateReadiness returns whether a Certificate's Ready condition is true with the reason
+// when it is not, and the time cert-manager will renew it (zero when unknown).
+func CertificateReadiness(certificate *unstructured.Unstructured) (bool, string, time.Time) {
+	var renewal time.Time
+	if value, found, _ := unstructured.NestedString(certificate.Object, "status", "renewalTime"); found {
+		renewal, _ = time.Parse(time.RFC3339, value)
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		if condition["status"] == string(corev1.ConditionTrue) {
+			return true, "", renewal
+		}
+		reason, _ := condition["reason"].(string)
+		if message, _ := condition["message"].(string); message != "" {
+			reason = strings.TrimSpace(reason + " " + message)
+		}
+		return false, reason, renewal
+	}
+	return false, "no Ready condition", renewal
+}
+
+// checkCertificates returns a CertificateNotReadyError for the first of the auto-discovered
+// secrets whose Certificate is not ready. Otherwise it returns the earliest renewal time of
+// their Certificates, zero when none is known. Secrets whose Certificate no longer exists and
+// clusters without cert-manager are not held back.
+func (r *DeploymentReconciler) checkCertificates(ctx context.Context, namespace string, secrets map[string]*corev1.Secret) (time.Time, error) {
+	var renewal time.Time
+	if !features.Enabled(features.CertManagerReadiness) {
+		return renewal, nil
+	}
+
+	// Certificates are read uncached so the operator does not watch every Certificate
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, secretName := range names {
+		certificateName := CertificateName(secrets[secretName])
+		if certificateName == "" {
+			continue
+		}
+
+		certificate := &unstructured.Unstructured{}
+		certificate.SetGroupVersionKind(certificateGVK)
+		if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: certificateName}, certificate); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return renewal, fmt.Errorf("failed to get certificate %s of secret %s: %w", certificateName, secretName, err)
+		}
+
+		ready, reason, renewalTime := CertificateReadiness(certificate)
+		if !ready {
+			return renewal, &CertificateNotReadyError{Secret: secretName, Certificate: certificateName, Reason: reason}
+		}
+		if !renewalTime.IsZero() && (renewal.IsZero() || renewalTime.Before(renewal)) {
+			renewal = renewalTime
+		}
+	}
+	return renewal, nil
+}
+
+// certificateRenewalRequeue returns the delay until the Deployment is resynced after the
+// renewal of one of its Certificates, zero when there is none.
+func certificateRenewalRequeue(renewal time.Time) time.Duration {
+	if renewal.IsZero() {
+		return 0
+	}
+	return max(time.Until(renewal), 0) + certificateRenewalMargin
+}
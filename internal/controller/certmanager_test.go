@@ -0,0 +1,125 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// newTestCertificate returns a cert-manager Certificate with the given Ready status.
+func newTestCertificate(name, ready, renewalTime string) *unstructured.Unstructured {
+	certificate := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": ready, "reason": "Issuing", "message": "Issuing certificate as Secret does not exist"},
+			},
+		},
+	}}
+	if renewalTime != "" {
+		certificate.Object["status"].(map[string]interface{})["renewalTime"] = renewalTime
+	}
+	certificate.SetGroupVersionKind(certificateGVK)
+	certificate.SetNamespace("default")
+	certificate.SetName(name)
+	return certificate
+}
+
+func TestCertificateName(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   *corev1.Secret
+		expected string
+	}{
+		{name: "plain secret", secret: &corev1.Secret{}},
+		{
+			name:     "annotation",
+			secret:   &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{CertManagerCertificateNameAnnotation: "web-tls"}}},
+			expected: "web-tls",
+		},
+		{
+			name: "owner reference",
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "cert-manager.io/v1", Kind: "Certificate", Name: "api-tls"},
+			}}},
+			expected: "api-tls",
+		},
+		{
+			name: "other certificate kind",
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "example.com/v1", Kind: "Certificate", Name: "other"},
+			}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CertificateName(tt.secret); got != tt.expected {
+				t.Errorf("CertificateName() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCertificateReadiness(t *testing.T) {
+	ready, _, renewal := CertificateReadiness(newTestCertificate("web-tls", "True", "2026-01-02T03:04:05Z"))
+	if !ready || !renewal.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("CertificateReadiness() = %v with renewal %v, expected ready with the status renewal time", ready, renewal)
+	}
+
+	ready, reason, _ := CertificateReadiness(newTestCertificate("web-tls", "False", ""))
+	if ready || reason != "Issuing Issuing certificate as Secret does not exist" {
+		t.Errorf("CertificateReadiness() = %v (%q), expected not ready with the condition reason", ready, reason)
+	}
+
+	if ready, _, _ := CertificateReadiness(&unstructured.Unstructured{Object: map[string]interface{}{}}); ready {
+		t.Error("CertificateReadiness() of a Certificate without status = true")
+	}
+}
+
+func TestCheckCertificates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	scheme.AddKnownTypeWithName(certificateGVK, &unstructured.Unstructured{})
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newTestCertificate("ready-tls", "True", time.Now().Add(time.Hour).UTC().Format(time.RFC3339)),
+		newTestCertificate("issuing-tls", "False", ""),
+	).Build()
+	r := &DeploymentReconciler{Client: k8sClient}
+
+	issued := func(certificate string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{CertManagerCertificateNameAnnotation: certificate}}}
+	}
+
+	// Ready and deleted Certificates do not hold back the sync
+	renewal, err := r.checkCertificates(context.Background(), "default", map[string]*corev1.Secret{
+		"ready": issued("ready-tls"),
+		"gone":  issued("deleted-tls"),
+		"plain": {},
+	})
+	if err != nil {
+		t.Fatalf("checkCertificates() error = %v", err)
+	}
+	if requeue := certificateRenewalRequeue(renewal); requeue < time.Hour || requeue > time.Hour+certificateRenewalMargin {
+		t.Errorf("renewal requeue = %v, expected an hour plus the margin", requeue)
+	}
+
+	// A Certificate being issued defers the sync
+	_, err = r.checkCertificates(context.Background(), "default", map[string]*corev1.Secret{
+		"ready":   issued("ready-tls"),
+		"issuing": issued("issuing-tls"),
+	})
+	var notReady *CertificateNotReadyError
+	if !errors.As(err, &notReady) || notReady.Certificate != "issuing-tls" || notReady.Secret != "issuing" {
+		t.Errorf("checkCertificates() error = %v, expected issuing-tls not to be ready", err)
+	}
+}
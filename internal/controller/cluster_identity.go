@@ -0,0 +1,137 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the detection of two clusters sharing a cluster name.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// ClusterIdentityPathSuffix is appended to the heartbeat prefix to form the cluster identity marker path.
+const ClusterIdentityPathSuffix = "_cluster_identity"
+
+// ClusterIdentityMetadataKey is the custom_metadata entry of the marker holding the cluster UID.
+const ClusterIdentityMetadataKey = "vault-sync-cluster-uid"
+
+// ClusterIdentityPath returns the cluster identity marker path under prefix.
+func ClusterIdentityPath(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/" + ClusterIdentityPathSuffix
+}
+
+// ClusterUID returns the UID of the kube-system namespace, which identifies a cluster.
+func ClusterUID(ctx context.Context, reader client.Reader) (string, error) {
+	namespace := &corev1.Namespace{}
+	if err := reader.Get(ctx, types.NamespacedName{Name: "kube-system"}, namespace); err != nil {
+		return "", fmt.Errorf("failed to get kube-system namespace: %w", err)
+	}
+	return string(namespace.UID), nil
+}
+
+// ClusterIdentityGuard periodically records the cluster's UID in a marker under its cluster
+// prefix and raises an alarm when it finds the UID of another cluster there. Two clusters
+// started with the same --cluster-name write to the same Vault paths and silently overwrite
+// each other's secrets; since both keep writing the marker, each finds the other's UID.
+type ClusterIdentityGuard struct {
+	VaultClient VaultReadWriter
+	// Reader reads the kube-system namespace (typically the manager's API reader)
+	Reader client.Reader
+	// Path is the marker path, including the cluster prefix
+	Path        string
+	ClusterName string
+	// Identity names the writing replica in the marker data
+	Identity string
+	Interval time.Duration
+	// Recorder emits a Warning event on Namespace when a conflict is found (optional)
+	Recorder  events.EventRecorder
+	Namespace string
+	Log       logr.Logger
+
+	uid string
+}
+
+// Start checks and writes the marker every interval until ctx is done. It implements manager.Runnable.
+func (g *ClusterIdentityGuard) Start(ctx context.Context) error {
+	ticker := time.NewTicker(g.Interval)
+	defer ticker.Stop()
+
+	for {
+		g.check(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection restricts the marker to the replica that reconciles.
+func (g *ClusterIdentityGuard) NeedLeaderElection() bool {
+	return true
+}
+
+// check compares the marker with the cluster's UID and records the UID in it.
+func (g *ClusterIdentityGuard) check(ctx context.Context, now time.Time) {
+	checkCtx, cancel := context.WithTimeout(ctx, g.Interval)
+	defer cancel()
+
+	if g.uid == "" {
+		uid, err := ClusterUID(checkCtx, g.Reader)
+		if err != nil {
+			g.Log.Error(err, "failed to determine cluster identity")
+			return
+		}
+		g.uid = uid
+	}
+
+	marker, err := g.VaultClient.ReadSecret(checkCtx, g.Path)
+	if err != nil {
+		g.Log.Error(err, "failed to read cluster identity marker", "path", g.Path)
+		return
+	}
+	if other, _ := marker["cluster_uid"].(string); other != "" && other != g.uid {
+		metrics.ClusterIdentityConflicts.Inc()
+		g.Log.Error(fmt.Errorf("cluster identity conflict"),
+			"another cluster is writing to vault with the same cluster name; secrets of both clusters are overwriting each other",
+			"cluster_name", g.ClusterName,
+			"path", g.Path,
+			"cluster_uid", g.uid,
+			"other_cluster_uid", other,
+			"other_writer", marker["writer"],
+			"other_timestamp", marker["timestamp"])
+		recordEvent(g.Recorder, &corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: g.Namespace},
+			corev1.EventTypeWarning, "ClusterNameConflict", "Sync",
+			"Cluster %s found cluster %s writing to Vault with the same cluster name %q at %s",
+			g.uid, other, g.ClusterName, g.Path)
+	}
+
+	data := map[string]interface{}{
+		"cluster_uid":  g.uid,
+		"cluster_name": g.ClusterName,
+		"writer":       g.Identity,
+		"timestamp":    now.UTC().Format(time.RFC3339),
+	}
+	if err := g.VaultClient.WriteSecret(checkCtx, g.Path, data); err != nil {
+		g.Log.Error(err, "failed to write cluster identity marker", "path", g.Path)
+		return
+	}
+
+	// Record the UID in the marker's metadata too, where Vault audits can find it
+	if writer, ok := g.VaultClient.(metadataWriter); ok {
+		md := vault.KVMetadata{CustomMetadata: map[string]string{ClusterIdentityMetadataKey: g.uid}}
+		if _, err := writer.EnsureSecretMetadata(checkCtx, g.Path, md); err != nil {
+			g.Log.V(1).Info("failed to record cluster identity in marker metadata", "path", g.Path, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// readableVault is a fakeVault that also reads the secrets written to it.
+type readableVault struct{ fakeVault }
+
+func (f *readableVault) ReadSecret(_ context.Context, path string) (map[string]interface{}, error) {
+	return f.secrets[path], nil
+}
+
+func TestClusterIdentityPath(t *testing.T) {
+	for prefix, expected := range map[string]string{
+		"secret/data/vault-sync-operator":  "secret/data/vault-sync-operator/_cluster_identity",
+		"secret/data/vault-sync-operator/": "secret/data/vault-sync-operator/_cluster_identity",
+	} {
+		if got := ClusterIdentityPath(prefix); got != expected {
+			t.Errorf("ClusterIdentityPath(%q) = %q, expected %q", prefix, got, expected)
+		}
+	}
+}
+
+func TestClusterIdentityGuard(t *testing.T) {
+	kubeSystem := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uid-a"}}
+	vaultClient := &readableVault{}
+	recorder := events.NewFakeRecorder(10)
+	g := &ClusterIdentityGuard{
+		VaultClient: vaultClient,
+		Reader:      fake.NewClientBuilder().WithObjects(kubeSystem).Build(),
+		Path:        "clusters/prod/secret/data/vault-sync-operator/_cluster_identity",
+		ClusterName: "prod",
+		Identity:    "operator-0",
+		Interval:    time.Minute,
+		Recorder:    recorder,
+		Namespace:   "vault-sync-operator-system",
+		Log:         logr.Discard(),
+	}
+	conflicts := testutil.ToFloat64(metrics.ClusterIdentityConflicts)
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// The first check writes the marker; checks finding our own UID raise nothing
+	g.check(context.Background(), now)
+	g.check(context.Background(), now.Add(time.Minute))
+	marker := vaultClient.secrets[g.Path]
+	if marker["cluster_uid"] != "uid-a" || marker["cluster_name"] != "prod" || marker["writer"] != "operator-0" {
+		t.Errorf("marker = %v", marker)
+	}
+	if value := testutil.ToFloat64(metrics.ClusterIdentityConflicts); value != conflicts {
+		t.Errorf("conflicts = %v, expected none for our own marker", value)
+	}
+
+	// Another cluster with the same name overwrote the marker
+	vaultClient.secrets[g.Path] = map[string]interface{}{"cluster_uid": "uid-b", "writer": "operator-0"}
+	g.check(context.Background(), now.Add(2*time.Minute))
+	if value := testutil.ToFloat64(metrics.ClusterIdentityConflicts); value != conflicts+1 {
+		t.Errorf("conflicts = %v, expected %v", value, conflicts+1)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "ClusterNameConflict") || !strings.Contains(event, "uid-b") {
+			t.Errorf("event = %q, expected a ClusterNameConflict warning naming the other cluster", event)
+		}
+	default:
+		t.Errorf("expected a ClusterNameConflict event")
+	}
+	if vaultClient.secrets[g.Path]["cluster_uid"] != "uid-a" {
+		t.Errorf("expected the marker to be written again, got %v", vaultClient.secrets[g.Path])
+	}
+}
+
+func TestClusterIdentityGuardMetadata(t *testing.T) {
+	kubeSystem := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system", UID: "uid-a"}}
+	vaultClient := &readableMetadataVault{}
+	g := &ClusterIdentityGuard{
+		VaultClient: vaultClient,
+		Reader:      fake.NewClientBuilder().WithObjects(kubeSystem).Build(),
+		Path:        "clusters/prod/secret/data/vault-sync-operator/_cluster_identity",
+		Interval:    time.Minute,
+		Log:         logr.Discard(),
+	}
+
+	g.check(context.Background(), time.Now())
+	if got := vaultClient.custom[g.Path][ClusterIdentityMetadataKey]; got != "uid-a" {
+		t.Errorf("custom metadata %s = %q, expected uid-a", ClusterIdentityMetadataKey, got)
+	}
+}
+
+// readableMetadataVault is a fakeMetadataVault that also reads the secrets written to it.
+type readableMetadataVault struct{ fakeMetadataVault }
+
+func (f *readableMetadataVault) ReadSecret(_ context.Context, path string) (map[string]interface{}, error) {
+	return f.secrets[path], nil
+}
@@ -0,0 +1,63 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the naming policy of cluster names.
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxClusterNameLength is the longest cluster name accepted, the length of a DNS label.
+const MaxClusterNameLength = 63
+
+// clusterNameFormat allows alphanumerics separated by '-', '.' or '_'. Slashes would nest one
+// cluster's prefix inside another's (clusters/prod/eu/ is also path eu/ of cluster prod).
+var clusterNameFormat = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]*[a-zA-Z0-9])?$`)
+
+// ValidateClusterName checks a cluster name against the naming policy: at most
+// MaxClusterNameLength alphanumerics, '-', '.' or '_', starting and ending with an
+// alphanumeric, and no ".." so it cannot resolve to another path. When pattern is set, the
+// name must also match it, see CompileClusterNamePattern. An empty name disables multi-cluster
+// mode and is valid. Uppercase letters are accepted, as renaming a cluster moves its Vault
+// prefix; see ClusterNameWarning.
+func ValidateClusterName(name string, pattern *regexp.Regexp) error {
+	if name == "" {
+		return nil
+	}
+	if len(name) > MaxClusterNameLength {
+		return fmt.Errorf("cluster name %q is longer than %d characters", name, MaxClusterNameLength)
+	}
+	if !clusterNameFormat.MatchString(name) || strings.Contains(name, "..") {
+		return fmt.Errorf("cluster name %q must consist of alphanumerics, '-', '.' or '_', "+
+			"and start and end with an alphanumeric", name)
+	}
+	if pattern != nil && !pattern.MatchString(name) {
+		return fmt.Errorf("cluster name %q does not match the pattern %s", name, pattern)
+	}
+	return nil
+}
+
+// ClusterNameWarning returns why a valid cluster name is discouraged, or "" when it is not.
+// Vault paths are case-sensitive, so Prod and prod are different prefixes, and a name that
+// differs from another cluster's only in case is easily mistaken for it.
+func ClusterNameWarning(name string) string {
+	if name != strings.ToLower(name) {
+		return fmt.Sprintf("cluster name %q contains uppercase letters; Vault paths are case-sensitive, so prefer %q for new clusters", name, strings.ToLower(name))
+	}
+	return ""
+}
+
+// CompileClusterNamePattern compiles an organization's cluster naming pattern, such as
+// `(prod|staging)-[a-z]+-[0-9]+`, anchored so it must match the whole name. An empty pattern
+// returns nil.
+func CompileClusterNamePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	compiled, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster name pattern: %w", err)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateClusterName(t *testing.T) {
+	pattern, err := CompileClusterNamePattern(`(prod|staging)-[a-z]+-[0-9]+`)
+	if err != nil {
+		t.Fatalf("CompileClusterNamePattern() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		clusterName string
+		withPattern bool
+		valid       bool
+	}{
+		{name: "single-cluster mode", clusterName: "", valid: true},
+		{name: "dns label", clusterName: "prod-eu-1", valid: true},
+		{name: "dots and underscores", clusterName: "eu.prod_1", valid: true},
+		{name: "nested prefix", clusterName: "prod/eu"},
+		{name: "uppercase is grandfathered", clusterName: "Prod-EU", valid: true},
+		{name: "leading separator", clusterName: "-prod"},
+		{name: "trailing separator", clusterName: "prod."},
+		{name: "parent reference", clusterName: "prod..eu"},
+		{name: "whitespace", clusterName: "prod eu"},
+		{name: "too long", clusterName: strings.Repeat("a", MaxClusterNameLength+1)},
+		{name: "matching pattern", clusterName: "staging-eu-2", withPattern: true, valid: true},
+		{name: "pattern must match the whole name", clusterName: "prod-eu-1x", withPattern: true},
+		{name: "pattern violated", clusterName: "dev-eu-1", withPattern: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p = pattern
+			if !tt.withPattern {
+				p = nil
+			}
+			err := ValidateClusterName(tt.clusterName, p)
+			if (err == nil) != tt.valid {
+				t.Errorf("ValidateClusterName(%q) error = %v, expected valid = %v", tt.clusterName, err, tt.valid)
+			}
+		})
+	}
+
+	if warning := ClusterNameWarning("Prod-EU"); !strings.Contains(warning, `"prod-eu"`) {
+		t.Errorf("ClusterNameWarning(Prod-EU) = %q, expected the lowercase name to be suggested", warning)
+	}
+	if warning := ClusterNameWarning("prod-eu"); warning != "" {
+		t.Errorf("ClusterNameWarning(prod-eu) = %q, expected none", warning)
+	}
+
+	if _, err := CompileClusterNamePattern("prod-("); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+	if p, err := CompileClusterNamePattern(""); p != nil || err != nil {
+		t.Errorf("CompileClusterNamePattern(\"\") = %v, %v, expected no pattern", p, err)
+	}
+}
@@ -0,0 +1,121 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the opt-in compression of large secret values.
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultCompressValuesAnnotation stores large string values gzip-compressed and base64-encoded
+// ("true"), to keep documents holding rendered configs or keystore dumps below Vault's
+// max_request_size.
+const VaultCompressValuesAnnotation = "vault-sync.io/compress-values"
+
+// CompressedKeySuffix marks the keys whose value is compressed: "config.yaml" is stored as
+// "config.yaml.gz.b64".
+const CompressedKeySuffix = ".gz.b64"
+
+// CompressionThreshold is the size in bytes from which values are compressed. Smaller
+// values gain little and would only become harder to consume.
+const CompressionThreshold = 1024
+
+// CompressesValues reports whether obj stores large values compressed.
+func CompressesValues(obj client.Object) bool {
+	return strings.EqualFold(strings.TrimSpace(obj.GetAnnotations()[VaultCompressValuesAnnotation]), "true")
+}
+
+// compressValues returns data with every string value of at least CompressionThreshold bytes
+// replaced by its gzip-compressed, base64-encoded form under the key with CompressedKeySuffix,
+// when that is smaller. Already compressed data, such as keystores, is stored as it is.
+// Compressing a key onto an existing key fails rather than overwriting it.
+func compressValues(data map[string]interface{}) (map[string]interface{}, error) {
+	compressed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		text, ok := value.(string)
+		if !ok || len(text) < CompressionThreshold {
+			compressed[key] = value
+			continue
+		}
+		encoded, err := gzipBase64(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress key %s: %w", key, err)
+		}
+		if len(encoded) >= len(text) {
+			compressed[key] = value
+			continue
+		}
+		compressedKey := key + CompressedKeySuffix
+		if _, exists := data[compressedKey]; exists {
+			return nil, fmt.Errorf("compressed key %s of key %s collides with an existing key", compressedKey, key)
+		}
+		compressed[compressedKey] = encoded
+	}
+	return compressed, nil
+}
+
+// gzipBase64 returns text compressed with gzip and encoded with standard base64. The gzip
+// header carries no name or modification time, so equal values compress to equal output and
+// rotation detection by content is unaffected.
+func gzipBase64(text string) (string, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(text)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// DecompressValues returns data read from Vault with every value stored under a key with
+// CompressedKeySuffix decompressed and restored under its original key, so readers see the
+// values as they are in Kubernetes. Other keys are returned as they are.
+func DecompressValues(data map[string]interface{}) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+	decompressed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		encoded, isString := value.(string)
+		if !isCompressedKey(key) || !isString {
+			decompressed[key] = value
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode compressed key %s: %w", key, err)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress key %s: %w", key, err)
+		}
+		text, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress key %s: %w", key, err)
+		}
+		decompressed[uncompressedKey(key)] = string(text)
+	}
+	return decompressed, nil
+}
+
+// isCompressedKey reports whether key holds a compressed value.
+func isCompressedKey(key string) bool {
+	originalKey, ok := strings.CutSuffix(key, CompressedKeySuffix)
+	return ok && originalKey != ""
+}
+
+// uncompressedKey returns the key a value is synced from, without CompressedKeySuffix.
+func uncompressedKey(key string) string {
+	if isCompressedKey(key) {
+		return strings.TrimSuffix(key, CompressedKeySuffix)
+	}
+	return key
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompressValues(t *testing.T) {
+	config := strings.Repeat("server.port=8080\n", 200)
+	tests := []struct {
+		name      string
+		data      map[string]interface{}
+		keys      []string
+		wantErr   bool
+		unchanged bool
+	}{
+		{name: "large value", data: map[string]interface{}{"app.properties": config, "user": "admin"}, keys: []string{"app.properties.gz.b64", "user"}},
+		{name: "small value", data: map[string]interface{}{"password": "s3cr3t"}, unchanged: true},
+		{name: "structured value", data: map[string]interface{}{"config": map[string]interface{}{"port": config}}, unchanged: true},
+		{name: "collision", data: map[string]interface{}{"config": config, "config.gz.b64": "x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, err := compressValues(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("compressValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.unchanged && !reflect.DeepEqual(compressed, tt.data) {
+				t.Errorf("compressValues() = %v, expected the data unchanged", compressed)
+			}
+			for _, key := range tt.keys {
+				if _, ok := compressed[key]; !ok {
+					t.Errorf("compressValues() has no key %s: %v", key, compressed)
+				}
+			}
+
+			restored, err := DecompressValues(compressed)
+			if err != nil {
+				t.Fatalf("DecompressValues() error = %v", err)
+			}
+			if !reflect.DeepEqual(restored, tt.data) {
+				t.Errorf("DecompressValues() did not restore the data")
+			}
+		})
+	}
+}
+
+func TestCompressValuesIncompressible(t *testing.T) {
+	// Random-looking text gains nothing from gzip and base64
+	var builder strings.Builder
+	seed := uint32(1)
+	for builder.Len() < 2*CompressionThreshold {
+		seed = seed*1664525 + 1013904223
+		builder.WriteByte(byte('!' + seed>>24%90))
+	}
+	data := map[string]interface{}{"keystore": builder.String()}
+
+	compressed, err := compressValues(data)
+	if err != nil {
+		t.Fatalf("compressValues() error = %v", err)
+	}
+	if !reflect.DeepEqual(compressed, data) {
+		t.Errorf("compressValues() compressed a value that did not get smaller")
+	}
+}
+
+func TestCompressValuesDeterministic(t *testing.T) {
+	data := map[string]interface{}{"config": strings.Repeat("a=b\n", 1000)}
+	first, _ := compressValues(data)
+	second, _ := compressValues(data)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("compressing the same value twice gave different output")
+	}
+}
+
+func TestDecompressValuesInvalid(t *testing.T) {
+	for _, value := range []string{"not base64!", "aGVsbG8="} {
+		if _, err := DecompressValues(map[string]interface{}{"config.gz.b64": value}); err == nil {
+			t.Errorf("DecompressValues(%q) succeeded, expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements "namespace/name" references to Secrets in other namespaces.
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// AnyNamespace in CrossNamespacePolicy.AllowedNamespaces allows references to any namespace.
+const AnyNamespace = "*"
+
+// CrossNamespacePolicy controls "namespace/name" references in the secrets annotation.
+// The zero value only allows Secrets in the namespace of the annotated resource.
+type CrossNamespacePolicy struct {
+	// Enabled allows references to Secrets outside the resource's namespace
+	Enabled bool
+	// AllowedNamespaces lists the namespaces that may be referenced, or AnyNamespace. With
+	// none, every cross-namespace reference is denied.
+	AllowedNamespaces []string
+}
+
+// ResolveSecretRef resolves a secret reference from the secrets annotation, which is either
+// a plain name in namespace or "namespace/name".
+func (p CrossNamespacePolicy) ResolveSecretRef(ref, namespace string) (types.NamespacedName, error) {
+	refNamespace, name, found := strings.Cut(ref, "/")
+	if !found {
+		return types.NamespacedName{Namespace: namespace, Name: ref}, nil
+	}
+	if refNamespace == "" || name == "" || strings.Contains(name, "/") {
+		return types.NamespacedName{}, fmt.Errorf("invalid secret reference %q, expected <name> or <namespace>/<name>", ref)
+	}
+	if refNamespace == namespace {
+		return types.NamespacedName{Namespace: namespace, Name: name}, nil
+	}
+
+	if !p.Enabled {
+		return types.NamespacedName{}, fmt.Errorf("cross-namespace secret reference %q is not allowed, start the operator with --allow-cross-namespace-refs to enable it", ref)
+	}
+	if !p.allows(refNamespace) {
+		return types.NamespacedName{}, fmt.Errorf("cross-namespace secret reference %q is not allowed, namespace %s is not in --cross-namespace-allowlist", ref, refNamespace)
+	}
+	return types.NamespacedName{Namespace: refNamespace, Name: name}, nil
+}
+
+// allows reports whether namespace is in the allowlist.
+func (p CrossNamespacePolicy) allows(namespace string) bool {
+	for _, allowed := range p.AllowedNamespaces {
+		if allowed == namespace || allowed == AnyNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// secretGetError explains a failure to read a referenced Secret. A permission error names
+// the RBAC grant the operator is missing instead of suggesting the Secret does not exist.
+func secretGetError(key types.NamespacedName, err error) error {
+	if apierrors.IsForbidden(err) {
+		return fmt.Errorf("operator is not permitted to read secret %s, grant it get on secrets in namespace %s: %w", key, key.Namespace, err)
+	}
+	return fmt.Errorf("failed to get secret %s (check if secret generators have run): %w", key.Name, err)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestCrossNamespacePolicyResolveSecretRef(t *testing.T) {
+	disabled := CrossNamespacePolicy{}
+	enabled := CrossNamespacePolicy{Enabled: true}
+	anyNamespace := CrossNamespacePolicy{Enabled: true, AllowedNamespaces: []string{AnyNamespace}}
+	allowlisted := CrossNamespacePolicy{Enabled: true, AllowedNamespaces: []string{"platform"}}
+
+	tests := []struct {
+		name     string
+		policy   CrossNamespacePolicy
+		ref      string
+		expected types.NamespacedName
+		wantErr  bool
+	}{
+		{name: "plain name", policy: disabled, ref: "db", expected: types.NamespacedName{Namespace: "team-a", Name: "db"}},
+		{name: "own namespace", policy: disabled, ref: "team-a/db", expected: types.NamespacedName{Namespace: "team-a", Name: "db"}},
+		{name: "other namespace disabled", policy: disabled, ref: "platform/db", wantErr: true},
+		{name: "other namespace enabled without allowlist", policy: enabled, ref: "platform/db", wantErr: true},
+		{name: "other namespace with any allowed", policy: anyNamespace, ref: "platform/db", expected: types.NamespacedName{Namespace: "platform", Name: "db"}},
+		{name: "allowlisted namespace", policy: allowlisted, ref: "platform/db", expected: types.NamespacedName{Namespace: "platform", Name: "db"}},
+		{name: "namespace not allowlisted", policy: allowlisted, ref: "team-b/db", wantErr: true},
+		{name: "empty namespace", policy: anyNamespace, ref: "/db", wantErr: true},
+		{name: "too many segments", policy: anyNamespace, ref: "platform/db/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := tt.policy.ResolveSecretRef(tt.ref, "team-a")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSecretRef() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if key != tt.expected {
+				t.Errorf("ResolveSecretRef() = %v, expected %v", key, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSecretGetError(t *testing.T) {
+	key := types.NamespacedName{Namespace: "platform", Name: "db"}
+
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "db", errors.New("rbac denied"))
+	if err := secretGetError(key, forbidden); !strings.Contains(err.Error(), "grant it get on secrets in namespace platform") {
+		t.Errorf("secretGetError() = %v, expected a permission hint", err)
+	}
+
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "db")
+	if err := secretGetError(key, notFound); !strings.Contains(err.Error(), "check if secret generators have run") {
+		t.Errorf("secretGetError() = %v, expected a generator hint", err)
+	}
+}
@@ -5,76 +5,173 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/danieldonoghue/vault-sync-operator/internal/features"
 	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
 	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
 )
 
 // VaultPathAnnotation specifies the Vault path for secret retrieval.
 const (
-	VaultPathAnnotation             = "vault-sync.io/path"
-	VaultSecretsAnnotation          = "vault-sync.io/secrets" //nolint:gosec // This is an annotation name, not a credential
-	VaultPreserveOnDeleteAnnotation = "vault-sync.io/preserve-on-delete"
-	VaultSecretVersionsAnnotation   = "vault-sync.io/secret-versions" //nolint:gosec // This is an annotation name, not a credential
-	VaultRotationCheckAnnotation    = "vault-sync.io/rotation-check"  // Control rotation detection (enabled|disabled|<frequency>)
-	VaultReconcileAnnotation        = "vault-sync.io/reconcile"       // Control periodic reconciliation (off|<duration>)
+	VaultPathAnnotation              = "vault-sync.io/path"
+	VaultSecretsAnnotation           = "vault-sync.io/secrets" //nolint:gosec // This is an annotation name, not a credential
+	VaultPreserveOnDeleteAnnotation  = "vault-sync.io/preserve-on-delete"
+	VaultSecretVersionsAnnotation    = "vault-sync.io/secret-versions"     //nolint:gosec // This is an annotation name, not a credential
+	VaultRotationCheckAnnotation     = "vault-sync.io/rotation-check"      // Control rotation detection (enabled|disabled|<frequency>)
+	VaultReconcileAnnotation         = "vault-sync.io/reconcile"           // Control periodic reconciliation (off|<duration>)
+	VaultAbsolutePathAnnotation      = "vault-sync.io/absolute-path"       // Skip cluster prefixing for this path ("true")
+	VaultForceSyncAnnotation         = "vault-sync.io/force-sync"          // Any new value forces a sync ignoring version checks
+	VaultForceSyncConsumedAnnotation = "vault-sync.io/force-sync-consumed" // Last force-sync value that was applied
+	VaultIncludeKeysAnnotation       = "vault-sync.io/include-keys"        // Comma-separated keys synced from auto-discovered secrets
+	VaultIgnoreContainersAnnotation  = "vault-sync.io/ignore-containers"   // Comma-separated containers skipped by auto-discovery
+	VaultDeletedPathAnnotation       = "vault-sync.io/deleted-path"        // Vault path already deleted while finalizing
+	VaultPriorityAnnotation          = "vault-sync.io/priority"            // Sync ordering under load (high|normal|low)
+	VaultSyncedConfigAnnotation      = "vault-sync.io/synced-config"       // Hash of the configuration the Vault documents were written with
+	VaultSyncedAtAnnotation          = "vault-sync.io/synced-at"           // Time the versions of the synced secrets were last recorded
 )
 
 // VaultSyncFinalizer is the finalizer name used by the operator.
 const VaultSyncFinalizer = "vault-sync.io/finalizer"
 
-// DefaultRotationCheckFrequency is the default rotation check frequency for future periodic checks.
+// DefaultRotationCheckFrequency is a suggested rotation check frequency for vault-sync.io/rotation-check.
 const DefaultRotationCheckFrequency = "5m"
 
-// DeploymentReconciler reconciles a Deployment object.
+// DeploymentReconciler reconciles a Deployment object, or an object of a Deployment-like
+// WorkloadKind such as an Argo Rollout.
 type DeploymentReconciler struct {
 	client.Client
 	Scheme      *runtime.Scheme
 	Log         logr.Logger
-	VaultClient *vault.Client
+	VaultClient VaultWriterDeleter
 	ClusterName string // Optional cluster identifier for multi-cluster Vault paths
+	// SkippedSecretTypes lists Secret types that are never synced to Vault (e.g. service account tokens)
+	SkippedSecretTypes []string
+	// SecretTypes allows or denies Secret types per namespace (optional)
+	SecretTypes SecretTypePolicy
+	// Warmup paces the initial reconcile storm after startup (optional)
+	Warmup *WarmupCoordinator
+	// Stagger spreads first syncs and periodic requeues over a window (optional)
+	Stagger *RequeueStagger
+	// Recorder emits Kubernetes events for notable sync conditions (optional)
+	Recorder events.EventRecorder
+	// Name overrides the controller name, allowing several instances to run side by side
+	Name string
+	// Namespaces restricts the controller to these namespaces (empty means all)
+	Namespaces []string
+	// SharedSecrets enables shared-secret mode for auto-discovery when non-nil
+	SharedSecrets *SharedSecretRegistry
+	// History records recent sync operations per Deployment (optional)
+	History *SyncHistory
+	// Propagation measures the delay from a data change of a synced Secret to its Vault write (optional)
+	Propagation *PropagationTracker
+	// SkipAgentInjected skips Deployments whose Vault agent injection reads the path they sync to
+	SkipAgentInjected bool
+	// ExternalSecretPolicy controls Secrets managed by the External Secrets Operator (warn|skip|ignore)
+	ExternalSecretPolicy string
+	// NamespaceMounts places the paths of mapped namespaces under a fixed Vault mount prefix
+	NamespaceMounts NamespaceMounts
+	// APIReader reads objects named in vault-sync.io/discover-from without caching them (optional)
+	APIReader client.Reader
+	// CrossNamespace controls "namespace/name" references in the secrets annotation
+	CrossNamespace CrossNamespacePolicy
+	// Inventory tracks the Vault paths managed by each Deployment (optional)
+	Inventory *ManagedPathInventory
+	// ReconcileBounds limits the vault-sync.io/reconcile intervals (30s minimum when zero)
+	ReconcileBounds ReconcileIntervalBounds
+	// DefaultReconcileInterval applies to resources without vault-sync.io/reconcile (disabled when zero)
+	DefaultReconcileInterval time.Duration
+	// Policy is evaluated before every Vault write and can deny it (optional)
+	Policy *PolicyHook
+	// LogSampler thins out repetitive log lines such as syncs without changes (optional)
+	LogSampler *LogSampler
+	// WorkloadKind syncs a Deployment-like kind, read as unstructured objects, instead of Deployments (optional)
+	WorkloadKind *WorkloadKind
+	// Errors keeps the recent sync and delete errors for the status page (optional)
+	Errors *ErrorLog
+	// SecretSizes tracks the size of Vault writes and warns about large ones (optional)
+	SecretSizes *SecretSizeTracker
+	// PreserveOnDelete decides which resources keep their Vault paths when they are deleted
+	PreserveOnDelete PreserveOnDeletePolicy
+	// WaitForRollout defers syncs while the workload is rolling out, unless overridden by
+	// vault-sync.io/wait-for-rollout
+	WaitForRollout bool
+	// TransactionalWrites writes the sub-paths of a sync all-or-nothing, unless overridden by
+	// vault-sync.io/transactional-writes
+	TransactionalWrites bool
+	// MetadataKeys lists the labels and annotations copied into the Vault custom_metadata of the paths (optional)
+	MetadataKeys []string
+	// StateBackend records what was synced in the resource's annotations or in Vault (StateBackendAnnotation when empty)
+	StateBackend string
+	// StateHashKey keys the content hashes recorded by the Vault state backend
+	StateHashKey []byte
+	// RotationCheckPolicy overrides vault-sync.io/rotation-check operator-wide
+	// (RotationCheckPolicyAnnotation when empty)
+	RotationCheckPolicy string
+	// RetryBudget backs off resources whose syncs keep failing (optional)
+	RetryBudget *RetryBudget
 }
 
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=apps,resources=deployments/finalizers,verbs=update
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",namespace=system,resources=serviceaccounts/token,resourceNames=vault-sync-operator-controller-manager,verbs=create
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
 // +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=create;get;list;update;watch
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get
+// +kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get
 // +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
 // +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := r.Log.WithValues("deployment", req.NamespacedName)
+	kind := r.kindLabel()
+	log := r.Log.WithValues(kind, req.NamespacedName)
 
-	// Fetch the Deployment instance
-	deployment := &appsv1.Deployment{}
+	// Vault requests take turns with those of other namespaces
+	ctx = vault.WithSourceNamespace(ctx, req.Namespace)
+
+	// Fetch the Deployment or workload instance
+	deployment := r.newWorkload()
 	err := r.Get(ctx, req.NamespacedName, deployment)
 	if err != nil {
 		if client.IgnoreNotFound(err) == nil {
 			// Deployment not found, probably deleted
+			r.Inventory.Forget(kind, req.NamespacedName)
+			r.RetryBudget.Forget(kind + "/" + req.Namespace + "/" + req.Name)
+			r.Propagation.Forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
-		log.Error(err, "unable to fetch Deployment")
+		log.Error(err, "unable to fetch "+r.kindName())
 		return ctrl.Result{}, err
 	}
 
 	// Check if vault-sync is enabled for this deployment (presence of vault path annotation)
-	vaultPath, vaultSyncEnabled := deployment.Annotations[VaultPathAnnotation]
+	vaultPath, vaultSyncEnabled := deployment.GetAnnotations()[VaultPathAnnotation]
 	if !vaultSyncEnabled || vaultPath == "" {
+		r.Inventory.Forget(kind, req.NamespacedName)
+		r.RetryBudget.Forget(WarmupKey(kind, deployment))
+		r.Propagation.Forget(req.NamespacedName)
 		// Remove finalizer if it exists but sync is disabled
 		if controllerutil.ContainsFinalizer(deployment, VaultSyncFinalizer) {
 			controllerutil.RemoveFinalizer(deployment, VaultSyncFinalizer)
@@ -83,8 +180,15 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, nil
 	}
 
+	// Hold writes and deletions while Vault is sealed instead of failing repeatedly
+	if holdWhileSealed(ctx, r.VaultClient, r.Recorder, deployment) {
+		metrics.SyncsHeldWhileSealed.WithLabelValues(kind).Inc()
+		log.Info("vault is sealed, holding sync", "requeue_after", VaultSealedRequeueDelay)
+		return ctrl.Result{RequeueAfter: VaultSealedRequeueDelay}, nil
+	}
+
 	// Handle deletion
-	if deployment.DeletionTimestamp != nil {
+	if deployment.GetDeletionTimestamp() != nil {
 		return r.handleDeletion(ctx, deployment)
 	}
 
@@ -94,11 +198,125 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, r.Update(ctx, deployment)
 	}
 
-	// Sync secrets to Vault
-	result, err := r.syncSecretsToVault(ctx, deployment)
+	// Warn when the Vault agent injector reads what this deployment writes, which can cause sync loops
+	prefixedPath := r.NamespaceMounts.ResolvePath(deployment.GetNamespace(), vaultPath, r.ClusterName, IsAbsolutePath(deployment))
+	podTemplate, err := r.podTemplate(deployment)
+	if err != nil {
+		log.Error(err, "unable to read pod template")
+		return ctrl.Result{}, err
+	}
+	// Per-resource gauges cannot be aggregated, so they are only kept with detailed metrics
+	detailedMetrics := metrics.ResourceDetailed(deployment.GetNamespace())
+	if conflicts := ConflictingAgentInjectedPaths(podTemplate, prefixedPath); len(conflicts) > 0 {
+		if detailedMetrics {
+			metrics.AgentInjectorConflicts.WithLabelValues(deployment.GetNamespace(), deployment.GetName()).Set(1)
+		}
+		recordEvent(r.Recorder, deployment, corev1.EventTypeWarning, "AgentInjectorConflict", "Sync",
+			"Vault agent injection reads %v, which is written by vault-sync from this %s", conflicts, r.kindName())
+		if r.SkipAgentInjected {
+			log.Info("skipping deployment with conflicting vault agent injection",
+				"path", prefixedPath,
+				"injected_paths", conflicts)
+			return ctrl.Result{}, nil
+		}
+		log.Info("vault agent injection reads the synced path",
+			"path", prefixedPath,
+			"injected_paths", conflicts)
+	} else if detailedMetrics {
+		metrics.AgentInjectorConflicts.WithLabelValues(deployment.GetNamespace(), deployment.GetName()).Set(0)
+	}
+
+	// Defer the sync until the rollout completes, so Vault only reflects a healthy revision.
+	// Status updates of the workload trigger the next attempt.
+	if WaitsForRollout(deployment, r.WaitForRollout) {
+		if inProgress, reason := RolloutInProgress(deployment); inProgress {
+			metrics.SyncSkipped.WithLabelValues(SkipReasonRolloutInProgress).Inc()
+			log.Info("rollout in progress, deferring sync",
+				"reason", reason,
+				"requeue_after", RolloutRequeueDelay)
+			return ctrl.Result{RequeueAfter: RolloutRequeueDelay}, nil
+		}
+	}
+
+	// Keep a degraded resource at its long retry interval until its configuration changes or
+	// a sync is forced
+	if delay := r.RetryBudget.Hold(WarmupKey(kind, deployment), RetryFingerprint(deployment)); delay > 0 && !IsForceSyncRequested(deployment) {
+		metrics.SyncSkipped.WithLabelValues(SkipReasonDegraded).Inc()
+		log.V(1).Info("sync degraded after repeated failures, deferring", "requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Defer the sync while the Vault rate limiter is saturated
+	priority := GetSyncPriority(deployment)
+	if delay, saturated := priorityBackpressureDelay(r.VaultClient, req.Namespace, priority); saturated {
+		metrics.BackpressureRequeues.WithLabelValues(kind).Inc()
+		log.V(1).Info("vault request queue saturated, requeueing",
+			"pending_requests", pendingVaultRequests(r.VaultClient),
+			"priority", priority,
+			"requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Defer the sync while Vault asks requests to back off; high-priority syncs still go
+	// through at the lowered rate
+	if delay, throttled := vaultThrottleDelay(r.VaultClient); throttled && priority != SyncPriorityHigh {
+		metrics.BackpressureRequeues.WithLabelValues(kind).Inc()
+		log.V(1).Info("vault is throttling requests, requeueing",
+			"priority", priority,
+			"requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Spread the first syncs after startup so Vault does not receive them all at once
+	if delay := r.Stagger.InitialDelay(WarmupKey(kind, deployment)); delay > 0 {
+		log.V(1).Info("staggering first sync after startup", "requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Pace syncs while the startup warm-up is in progress
+	if err := r.Warmup.Wait(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Sync secrets to Vault, recording attempted writes in the sync history
+	syncStart := time.Now()
+	changedKeys, certificateRenewal, err := r.syncSecretsToVault(ctx, deployment)
+	var notReady *CertificateNotReadyError
+	if errors.As(err, &notReady) {
+		metrics.SyncSkipped.WithLabelValues(SkipReasonCertificateNotReady).Inc()
+		log.Info("certificate not ready, deferring sync",
+			"secret", notReady.Secret,
+			"certificate", notReady.Certificate,
+			"reason", notReady.Reason,
+			"requeue_after", CertificateRequeueDelay)
+		return ctrl.Result{RequeueAfter: CertificateRequeueDelay}, nil
+	}
+	logOutcome(log, r.LogSampler, kind, deployment, prefixedPath, LogOpSync, changedKeys, time.Since(syncStart), err)
+	r.Errors.Record(kind, deployment, prefixedPath, LogOpSync, err)
+	if changedKeys > 0 || err != nil {
+		r.History.Record(ctx, kind, deployment, changedKeys, err)
+	}
 	if err != nil {
-		return result, err
+		// Retry once Vault's back-off has passed rather than at the work queue's rate
+		if delay, throttled := vaultThrottleDelay(r.VaultClient); throttled {
+			log.Info("vault is throttling requests, requeueing failed sync", "requeue_after", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+		// Stop competing for the shared rate limit once the retry budget is exhausted
+		if delay, degraded := degradeOnFailure(r.RetryBudget, r.Recorder, deployment, WarmupKey(kind, deployment), err); degraded {
+			log.Info("sync keeps failing, retrying at the degraded interval", "requeue_after", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+		return ctrl.Result{}, err
+	}
+	restoreOnSuccess(r.RetryBudget, r.Recorder, deployment, WarmupKey(kind, deployment))
+	if changedKeys > 0 {
+		r.Propagation.Synced(req.NamespacedName)
+	} else {
+		r.Propagation.Forget(req.NamespacedName)
+		metrics.SyncSkipped.WithLabelValues(SkipReasonNoChange).Inc()
 	}
+	r.Warmup.MarkSynced(WarmupKey(kind, deployment))
 
 	// Check if periodic reconciliation is enabled
 	reconcileInterval := r.getReconcileInterval(deployment)
@@ -106,125 +324,355 @@ func (r *DeploymentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		log.V(1).Info("periodic reconciliation enabled",
 			"interval", reconcileInterval,
 			"next_reconcile", time.Now().Add(reconcileInterval))
-		result.RequeueAfter = reconcileInterval
 	}
 
-	return result, nil
+	// Check if scheduled rotation checks are enabled; they are skipped when no referenced
+	// secret can change
+	rotationInterval := GetRotationCheckInterval(deployment, r.Log)
+	if rotationInterval > 0 && OnlyImmutableSecrets(r.getLastKnownSecretVersions(deployment)) {
+		log.V(1).Info("all referenced secrets are immutable, skipping scheduled rotation checks")
+		rotationInterval = 0
+	}
+	if rotationInterval > 0 {
+		log.V(1).Info("scheduled rotation check enabled",
+			"frequency", rotationInterval,
+			"next_check", time.Now().Add(rotationInterval))
+	}
+
+	// Renewed certificates are synced once cert-manager has issued them
+	renewalInterval := certificateRenewalRequeue(certificateRenewal)
+
+	if requeueAfter := EarliestInterval(reconcileInterval, rotationInterval, renewalInterval); requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: r.Stagger.Periodic(WarmupKey(kind, deployment), r.Warmup.DeferRequeue(requeueAfter))}, nil
+	}
+
+	return ctrl.Result{}, nil
 }
 
 // handleDeletion handles the deletion of secrets from Vault when a deployment is deleted.
-func (r *DeploymentReconciler) handleDeletion(ctx context.Context, deployment *appsv1.Deployment) (ctrl.Result, error) {
-	log := r.Log.WithValues("deployment", deployment.Name, "namespace", deployment.Namespace)
+func (r *DeploymentReconciler) handleDeletion(ctx context.Context, deployment client.Object) (ctrl.Result, error) {
+	log := r.Log.WithValues(r.kindLabel(), deployment.GetName(), "namespace", deployment.GetNamespace())
 
 	if controllerutil.ContainsFinalizer(deployment, VaultSyncFinalizer) {
 		// Check if deletion should be preserved
-		preserveOnDelete := deployment.Annotations[VaultPreserveOnDeleteAnnotation] == "true"
+		preserveOnDelete := r.PreserveOnDelete.Preserves(deployment)
 
 		// Get the vault path
-		vaultPath, exists := deployment.Annotations[VaultPathAnnotation]
+		vaultPath, exists := deployment.GetAnnotations()[VaultPathAnnotation]
 		if exists && vaultPath != "" && !preserveOnDelete {
-			// Add cluster prefix if cluster name is configured
-			if r.ClusterName != "" {
-				vaultPath = fmt.Sprintf("clusters/%s/%s", r.ClusterName, vaultPath)
+			// Add namespace mount and cluster prefixes if configured
+			vaultPath = r.NamespaceMounts.ResolvePath(deployment.GetNamespace(), vaultPath, r.ClusterName, IsAbsolutePath(deployment))
+
+			// Serialize with other reconciles targeting the same path
+			unlock, err := lockVaultPath(ctx, r.VaultClient, vaultPath)
+			if err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to lock vault path %s: %w", vaultPath, err)
 			}
+			defer unlock()
 
-			// Delete the secret from Vault
-			if err := r.VaultClient.DeleteSecret(ctx, vaultPath); err != nil {
-				log.Error(err, "failed to delete secret from vault",
-					"path", vaultPath,
-					"deployment", deployment.Name,
-					"namespace", deployment.Namespace,
-					"error_details", err.Error())
-				return ctrl.Result{}, err
+			// Delete the secret from Vault, unless an earlier attempt already did
+			if isVaultPathDeleted(deployment, vaultPath) {
+				log.Info("secret already deleted from vault by an earlier attempt", "path", vaultPath)
+			} else {
+				recordDeleteAttempt(r.Recorder, deployment, vaultPath)
+
+				// Per-revision paths are deleted before the base path
+				if revisions := GetSyncedRevisions(deployment); len(revisions) > 0 {
+					secretNames := slices.Sorted(maps.Keys(r.getLastKnownSecretVersions(deployment)))
+					if failed := r.deleteRevisions(ctx, deployment, vaultPath, revisions, secretNames, log); len(failed) > 0 {
+						err := fmt.Errorf("failed to delete revisions %s from vault", strings.Join(failed, ","))
+						recordDeleteOutcome(r.Recorder, deployment, vaultPath, err)
+						return ctrl.Result{}, err
+					}
+				}
+
+				deleteStart := time.Now()
+				err := r.VaultClient.DeleteSecret(ctx, vaultPath)
+				logOutcome(log, r.LogSampler, r.kindLabel(), deployment, vaultPath, LogOpDelete, 0, time.Since(deleteStart), err)
+				r.Errors.Record(r.kindLabel(), deployment, vaultPath, LogOpDelete, err)
+				recordDeleteOutcome(r.Recorder, deployment, vaultPath, err)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				r.SecretSizes.Forget(vaultPath)
+				markVaultPathDeleted(ctx, r.Client, deployment, vaultPath, log)
 			}
-			log.Info("successfully deleted secret from vault",
-				"path", vaultPath,
-				"deployment", deployment.Name,
-				"namespace", deployment.Namespace)
 		} else if preserveOnDelete {
-			log.Info("preserving vault secret due to preserve annotation",
+			log.Info("preserving vault secret due to preserve-on-delete policy",
 				"path", vaultPath,
-				"deployment", deployment.Name,
-				"namespace", deployment.Namespace,
-				"preserve_annotation", "true")
+				"deployment", deployment.GetName(),
+				"namespace", deployment.GetNamespace(),
+				"preserve_annotation", deployment.GetAnnotations()[VaultPreserveOnDeleteAnnotation])
+			recordEvent(r.Recorder, deployment, corev1.EventTypeNormal, "VaultPathPreserved", "Delete",
+				"Keeping %s in Vault due to the preserve-on-delete policy", vaultPath)
+		}
+
+		// Drop shared-secret references held by this deployment, deleting unreferenced paths
+		if r.SharedSecrets != nil {
+			if err := r.releaseSharedSecrets(ctx, deployment, preserveOnDelete, log); err != nil {
+				log.Error(err, "failed to release shared secrets")
+				return ctrl.Result{}, err
+			}
 		}
 
 		// Remove finalizer
-		controllerutil.RemoveFinalizer(deployment, VaultSyncFinalizer)
-		return ctrl.Result{}, r.Update(ctx, deployment)
+		r.Inventory.Forget(r.kindLabel(), client.ObjectKeyFromObject(deployment))
+		r.RetryBudget.Forget(WarmupKey(r.kindLabel(), deployment))
+		r.History.Forget(ctx, r.kindLabel(), deployment)
+		r.Propagation.Forget(client.ObjectKeyFromObject(deployment))
+		return ctrl.Result{}, finishDeletion(ctx, r.Client, r.Recorder, deployment)
 	}
 
 	return ctrl.Result{}, nil
 }
 
-// syncSecretsToVault syncs the specified secrets to Vault.
-func (r *DeploymentReconciler) syncSecretsToVault(ctx context.Context, deployment *appsv1.Deployment) (ctrl.Result, error) {
-	log := r.Log.WithValues("deployment", deployment.Name, "namespace", deployment.Namespace)
+// syncSecretsToVault syncs the specified secrets to Vault and returns the number of keys written,
+// which is zero when no changes were detected, and the earliest renewal time of the cert-manager
+// Certificates of the auto-discovered secrets.
+func (r *DeploymentReconciler) syncSecretsToVault(ctx context.Context, deployment client.Object) (int, time.Time, error) {
+	log := r.Log.WithValues("deployment", deployment.GetName(), "namespace", deployment.GetNamespace())
 
 	// Start timing the operation
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start).Seconds()
-		metrics.SecretsyncDuration.WithLabelValues(deployment.Namespace, deployment.Name).Observe(duration)
+		metrics.SecretsyncDuration.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName())).Observe(duration)
 	}()
 
 	// Get the vault path (we already know it exists from reconcile check)
-	vaultPath := deployment.Annotations[VaultPathAnnotation]
+	vaultPath := deployment.GetAnnotations()[VaultPathAnnotation]
 
-	// Add cluster prefix if cluster name is configured
-	if r.ClusterName != "" {
-		vaultPath = fmt.Sprintf("clusters/%s/%s", r.ClusterName, vaultPath)
+	// Add namespace mount and cluster prefixes if configured
+	vaultPath = r.NamespaceMounts.ResolvePath(deployment.GetNamespace(), vaultPath, r.ClusterName, IsAbsolutePath(deployment))
+
+	// Per-revision paths nest the secrets under the current revision of the workload
+	revision, err := r.getRevision(deployment)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "revision_error").Inc()
+		log.Error(err, "failed to determine revision")
+		return 0, time.Time{}, err
+	}
+	revisionHistory, err := GetRevisionHistory(deployment)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "revision_error").Inc()
+		log.Error(err, "invalid revision history annotation")
+		return 0, time.Time{}, err
 	}
+	basePath := vaultPath
+	syncedRevisions := GetSyncedRevisions(deployment)
+	revisionChanged := revision != "" && (len(syncedRevisions) == 0 || syncedRevisions[len(syncedRevisions)-1] != revision)
+	if revision != "" {
+		vaultPath = basePath + "/" + revision
+	}
+
+	// Serialize with other reconciles (e.g. the Secret controller) targeting the same path
+	unlock, err := lockVaultPath(ctx, r.VaultClient, vaultPath)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to lock vault path %s: %w", vaultPath, err)
+	}
+	defer unlock()
 
 	// Check if custom secrets configuration is provided
-	secretsToSync, hasCustomConfig := deployment.Annotations[VaultSecretsAnnotation]
+	secretsToSync, hasCustomConfig := deployment.GetAnnotations()[VaultSecretsAnnotation]
 
 	var vaultData map[string]interface{}
 	var currentSecretVersions map[string]string
-	var err error
+	var discoveredSecrets map[string]*corev1.Secret
+	var certificateRenewal time.Time
 
 	if hasCustomConfig && secretsToSync != "" {
 		// Use custom configuration
 		log.Info("using custom secret configuration", "config", secretsToSync)
+		if _, ok := deployment.GetAnnotations()[VaultIncludeKeysAnnotation]; ok {
+			log.Info("include-keys annotation only applies to auto-discovered secrets, ignoring")
+		}
+		if _, ok := GetKeyPrefix(deployment); ok {
+			log.Info("key-prefix annotation only applies to auto-discovered secrets, ignoring")
+		}
 		vaultData, currentSecretVersions, err = r.syncCustomSecretsWithVersions(ctx, deployment, secretsToSync)
 		if err != nil {
-			metrics.SecretsyncAttempts.WithLabelValues(deployment.Namespace, deployment.Name, "failed").Inc()
-			log.Error(err, "failed to sync custom secrets")
-			return ctrl.Result{}, err
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			return 0, time.Time{}, err
 		}
 	} else {
 		// Auto-discover secrets from deployment pod template
 		log.Info("using auto-discovery mode")
-		currentSecretVersions, err = r.syncAutoDiscoveredSecretsToSubPaths(ctx, deployment, vaultPath)
+		discoveredSecrets, currentSecretVersions, err = r.discoverSecrets(ctx, deployment)
+		if err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			log.Error(err, "failed to discover secrets")
+			return 0, time.Time{}, err
+		}
+		// TLS secrets are only synced once their cert-manager Certificate is ready
+		certificateRenewal, err = r.checkCertificates(ctx, deployment.GetNamespace(), discoveredSecrets)
 		if err != nil {
-			metrics.SecretsyncAttempts.WithLabelValues(deployment.Namespace, deployment.Name, "failed").Inc()
-			log.Error(err, "failed to sync auto-discovered secrets")
-			return ctrl.Result{}, err
+			return 0, time.Time{}, err
 		}
 		// In auto-discovery mode, secrets are written to individual sub-paths
 		vaultData = make(map[string]interface{})
+		// unless a key prefix puts all keys into the document at the path itself
+		if prefix, ok := GetKeyPrefix(deployment); ok {
+			vaultData, err = flattenDiscoveredSecrets(discoveredSecrets, prefix, GetIncludeKeys(deployment))
+			if err != nil {
+				metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+				log.Error(err, "failed to prefix keys of auto-discovered secrets")
+				return 0, time.Time{}, err
+			}
+			discoveredSecrets = nil
+		}
+	}
+
+	// Rewrite key names according to the key sanitization policy
+	keyPolicy, err := GetKeySanitizationPolicy(deployment)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "key_sanitization_error").Inc()
+		log.Error(err, "invalid key sanitization annotation",
+			"annotation", deployment.GetAnnotations()[VaultKeySanitizationAnnotation])
+		return 0, time.Time{}, err
+	}
+	if vaultData, err = keyPolicy.SanitizeVaultData(vaultData); err != nil {
+		metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+		log.Error(err, "failed to sanitize secret keys")
+		return 0, time.Time{}, err
+	}
+	if ParsesJSONValues(deployment) {
+		vaultData = parseJSONValues(vaultData)
+	}
+	if CompressesValues(deployment) {
+		if vaultData, err = compressValues(vaultData); err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			log.Error(err, "failed to compress secret values")
+			return 0, time.Time{}, err
+		}
+	}
+
+	// Validate the KV metadata settings before anything is written
+	kvMetadata, err := GetKVMetadata(deployment)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "kv_metadata_error").Inc()
+		log.Error(err, "invalid kv metadata annotation")
+		return 0, time.Time{}, err
+	}
+	if len(r.MetadataKeys) > 0 {
+		podTemplate, err := r.podTemplate(deployment)
+		if err != nil {
+			log.Error(err, "unable to read pod template")
+			return 0, time.Time{}, err
+		}
+		// Entries set by annotations, such as the refresh hint, take precedence over the allowlist
+		customMetadata := WorkloadCustomMetadata(deployment, podTemplate, r.MetadataKeys)
+		maps.Copy(customMetadata, kvMetadata.CustomMetadata)
+		kvMetadata.CustomMetadata = customMetadata
 	}
 
 	// Check if secret versions have changed (rotation detection)
 	lastKnownVersions := r.getLastKnownSecretVersions(deployment)
 	var hasChanges bool
 
-	// Check if rotation detection is disabled
+	// A changed path moves the secrets: the new paths are written, then the old ones are deleted
+	secretNames := slices.Sorted(maps.Keys(currentSecretVersions))
+	for secretName := range lastKnownVersions {
+		if _, ok := currentSecretVersions[secretName]; !ok {
+			secretNames = append(secretNames, secretName)
+		}
+	}
+	move := r.detectVaultPathMove(deployment, basePath, vaultPath, vaultData, discoveredSecrets, secretNames, keyPolicy)
+
+	// Check if rotation detection is disabled or a manual resync was requested
 	if r.isRotationCheckDisabled(deployment) {
 		log.Info("secret rotation check disabled, performing sync anyway")
 		hasChanges = true
+	} else if IsForceSyncRequested(deployment) {
+		log.Info("manual resync requested, ignoring version checks",
+			"force_sync", deployment.GetAnnotations()[VaultForceSyncAnnotation])
+		hasChanges = true
+	} else if move != nil {
+		log.Info("vault path changed, moving secrets", "from", move.From, "to", move.To)
+		hasChanges = true
+	} else if r.StateBackend != StateBackendVault && SyncConfigChanged(deployment) {
+		log.Info("secrets configuration changed, rewriting vault documents without the keys no longer configured")
+		hasChanges = true
+	} else if revisionChanged {
+		log.Info("new revision, syncing secrets to its path", "revision", revision, "path", vaultPath)
+		hasChanges = true
+	} else if r.StateBackend == StateBackendVault {
+		// The content hashes recorded in Vault decide which paths are written below
+		hasChanges = true
+	} else if discoveredSecrets != nil {
+		// Auto-discovered secrets have their own sub-paths, so a secret that is no longer
+		// referenced does not require the remaining ones to be written again
+		hasChanges = r.detectSecretChanges(pruneSecretVersions(lastKnownVersions, currentSecretVersions), currentSecretVersions)
 	} else {
 		hasChanges = r.detectSecretChanges(lastKnownVersions, currentSecretVersions)
 	}
 
-	if !hasChanges && len(lastKnownVersions) > 0 {
-		log.Info("no secret changes detected, skipping vault sync",
-			"last_versions", lastKnownVersions,
-			"current_versions", currentSecretVersions)
-		return ctrl.Result{}, nil
+	// Entries for secrets that are no longer referenced are dropped from the versions annotation
+	staleSecrets := StaleSecretVersions(lastKnownVersions, currentSecretVersions)
+	reportStaleSecretVersions(r.Recorder, deployment, staleSecrets, log)
+
+	// Record the paths this Deployment manages, whether or not they are written now
+	managedPaths := []string{vaultPath}
+	if discoveredSecrets != nil {
+		managedPaths = make([]string, 0, len(discoveredSecrets))
+		for secretName := range discoveredSecrets {
+			managedPaths = append(managedPaths, r.autoDiscoveredSecretPath(deployment, vaultPath, secretName))
+		}
+	}
+
+	// Versions recorded on the resource cannot tell that a path was deleted in Vault, so
+	// resources reconciled periodically check that their paths still exist and restore them
+	if !hasChanges && r.StateBackend != StateBackendVault && len(lastKnownVersions) > 0 && r.getReconcileInterval(deployment) > 0 {
+		if missing := missingVaultPaths(ctx, r.VaultClient, managedPaths, log); len(missing) > 0 {
+			log.Info("vault paths deleted out of band, restoring them", "paths", missing)
+			hasChanges = true
+		}
 	}
 
-	if hasChanges {
+	// Previous revisions kept in Vault are managed as well, so namespace cleanup deletes them,
+	// and so are the staging paths of transactional writes, which are left behind when a sync
+	// is interrupted
+	inventoryPaths := managedPaths
+	if discoveredSecrets != nil && WritesTransactionally(deployment, r.TransactionalWrites) {
+		inventoryPaths = append(slices.Clone(inventoryPaths), stagingPaths(managedPaths)...)
+	}
+	for _, syncedRevision := range syncedRevisions {
+		if syncedRevision != revision {
+			inventoryPaths = append(slices.Clone(inventoryPaths), r.revisionPaths(deployment, basePath, syncedRevision, secretNames)...)
+		}
+	}
+
+	// With the Vault state backend, paths whose content matches the hash recorded in Vault are not written again
+	var unchangedPaths map[string]bool
+	if r.StateBackend == StateBackendVault && !r.isRotationCheckDisabled(deployment) && !IsForceSyncRequested(deployment) {
+		unchangedPaths, hasChanges, err = r.unchangedVaultPaths(ctx, deployment, vaultPath, vaultData, discoveredSecrets, keyPolicy)
+		if err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			log.Error(err, "failed to read content hashes from vault")
+			return 0, time.Time{}, err
+		}
+	}
+
+	if !hasChanges && (len(lastKnownVersions) > 0 || unchangedPaths != nil) {
+		if len(staleSecrets) > 0 && r.StateBackend != StateBackendVault {
+			if err := r.updateSecretVersionsAnnotation(ctx, deployment, currentSecretVersions); err != nil {
+				log.Error(err, "failed to prune secret versions annotation", "versions", currentSecretVersions)
+			}
+		} else if r.StateBackend != StateBackendVault {
+			recordSyncedConfig(ctx, r.Client, deployment, log)
+		}
+		if err := applyKVMetadata(ctx, r.VaultClient, r.Recorder, deployment, kvMetadata, managedPaths, log); err != nil {
+			return 0, time.Time{}, err
+		}
+		// Content already found at the new path still completes a move
+		if err := recordSyncedPath(ctx, r.Client, r.VaultClient, r.Recorder, r.Inventory, r.kindLabel(), deployment, basePath, move, r.PreserveOnDelete.Preserves(deployment), log); err != nil {
+			return 0, time.Time{}, err
+		}
+		r.Inventory.Set(r.kindLabel(), client.ObjectKeyFromObject(deployment), inventoryPaths)
+		r.Inventory.SetPreserved(r.kindLabel(), client.ObjectKeyFromObject(deployment), r.PreserveOnDelete.Preserves(deployment))
+		return 0, certificateRenewal, nil
+	}
+
+	if hasChanges && r.StateBackend != StateBackendVault {
 		log.Info("secret rotation detected, syncing to vault",
 			"changed_secrets", r.getChangedSecrets(lastKnownVersions, currentSecretVersions))
 	}
@@ -235,48 +683,90 @@ func (r *DeploymentReconciler) syncSecretsToVault(ctx context.Context, deploymen
 		"secret_count", len(vaultData),
 		"mode", map[bool]string{true: "custom", false: "auto-discovery"}[hasCustomConfig && secretsToSync != ""])
 
+	// Auto-discovered secrets are only written once changes have been detected
+	var changedKeys int
+	if len(discoveredSecrets) > 0 {
+		changedKeys, err = r.writeAutoDiscoveredSecrets(ctx, deployment, vaultPath, discoveredSecrets, GetIncludeKeys(deployment), keyPolicy, unchangedPaths)
+		if err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			return changedKeys, time.Time{}, err
+		}
+	}
+
 	// Write to Vault (batch operation for performance)
-	// Skip writing for auto-discovery mode as secrets are already written to sub-paths
-	if len(vaultData) > 0 {
-		if err := r.VaultClient.WriteSecret(ctx, vaultPath, vaultData); err != nil {
-			metrics.SecretsyncAttempts.WithLabelValues(deployment.Namespace, deployment.Name, "failed").Inc()
+	// Skip writing for auto-discovery mode as secrets are written to sub-paths above
+	if len(vaultData) > 0 && !unchangedPaths[vaultPath] {
+		writeData, err := retainDeletedKeys(ctx, r.VaultClient, deployment, vaultPath, vaultData, log)
+		if err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			return 0, time.Time{}, err
+		}
+		if err := checkWritePolicy(ctx, r.Policy, r.Recorder, r.kindName(), deployment, vaultPath, r.ClusterName, writeData); err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+			log.Error(err, "vault write denied by policy", "path", vaultPath)
+			return 0, time.Time{}, err
+		}
+		if err := r.VaultClient.WriteSecret(ctx, vaultPath, writeData); err != nil {
+			metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
 			log.Error(err, "failed to write secret to vault",
 				"path", vaultPath,
 				"secret_count", len(vaultData),
 				"error_details", err.Error())
-			return ctrl.Result{}, fmt.Errorf("failed to write secret to vault: %w", err)
+			return len(vaultData), time.Time{}, fmt.Errorf("failed to write secret to vault: %w", err)
 		}
+		r.SecretSizes.Record(r.Recorder, deployment, vaultPath, writeData)
+		changedKeys += len(vaultData)
+		r.recordContentHash(ctx, vaultPath, vaultData, log)
 	}
 
 	// Update secret versions annotation for future rotation detection
-	err = r.updateSecretVersionsAnnotation(ctx, deployment, currentSecretVersions)
-	if err != nil {
+	if r.StateBackend == StateBackendVault {
+		if err := consumeForceSync(ctx, r.Client, deployment); err != nil {
+			log.Error(err, "failed to record force sync as applied")
+		}
+	} else if err := r.updateSecretVersionsAnnotation(ctx, deployment, currentSecretVersions); err != nil {
 		log.Error(err, "failed to update secret versions annotation", "versions", currentSecretVersions)
 		// Don't fail the whole operation for annotation update failure
 	}
 
+	// Previous revisions are only deleted once the new revision has been written
+	if revisionChanged {
+		if err := r.recordRevision(ctx, deployment, basePath, revision, revisionHistory, secretNames, log); err != nil {
+			log.Error(err, "failed to record synced revision", "revision", revision)
+		}
+	}
+
+	if err := applyKVMetadata(ctx, r.VaultClient, r.Recorder, deployment, kvMetadata, managedPaths, log); err != nil {
+		metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+		return changedKeys, time.Time{}, err
+	}
+
+	// The previous paths are only deleted once the secrets have been written to the new ones
+	if err := recordSyncedPath(ctx, r.Client, r.VaultClient, r.Recorder, r.Inventory, r.kindLabel(), deployment, basePath, move, r.PreserveOnDelete.Preserves(deployment), log); err != nil {
+		metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "failed").Inc()
+		return changedKeys, time.Time{}, err
+	}
+
 	// Success metrics and logging
-	metrics.SecretsyncAttempts.WithLabelValues(deployment.Namespace, deployment.Name, "success").Inc()
-	log.Info("successfully synced secrets to vault",
-		"path", vaultPath,
-		"secret_count", len(vaultData),
-		"duration_seconds", time.Since(start).Seconds())
-	return ctrl.Result{}, nil
+	r.Inventory.Set(r.kindLabel(), client.ObjectKeyFromObject(deployment), inventoryPaths)
+	r.Inventory.SetPreserved(r.kindLabel(), client.ObjectKeyFromObject(deployment), r.PreserveOnDelete.Preserves(deployment))
+	metrics.SecretsyncAttempts.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "success").Inc()
+	return changedKeys, certificateRenewal, nil
 }
 
 // syncCustomSecretsWithVersions handles custom secret configuration and returns version information.
-func (r *DeploymentReconciler) syncCustomSecretsWithVersions(ctx context.Context, deployment *appsv1.Deployment, secretsConfig string) (map[string]interface{}, map[string]string, error) {
-	log := r.Log.WithValues("deployment", deployment.Name, "namespace", deployment.Namespace)
+func (r *DeploymentReconciler) syncCustomSecretsWithVersions(ctx context.Context, deployment client.Object, secretsConfig string) (map[string]interface{}, map[string]string, error) {
+	log := r.Log.WithValues("deployment", deployment.GetName(), "namespace", deployment.GetNamespace())
 
 	// Parse the secrets annotation (JSON format)
 	var secretConfigs []SecretConfig
 	if err := json.Unmarshal([]byte(secretsConfig), &secretConfigs); err != nil {
-		metrics.ConfigParseErrors.WithLabelValues(deployment.Namespace, deployment.Name, "json_parse_error").Inc()
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "json_parse_error").Inc()
 		log.Error(err, "failed to parse secrets annotation",
 			"annotation", secretsConfig,
 			"error_type", "json_parse_error",
-			"deployment", deployment.Name,
-			"namespace", deployment.Namespace)
+			"deployment", deployment.GetName(),
+			"namespace", deployment.GetNamespace())
 		return nil, nil, fmt.Errorf("failed to parse secrets annotation: %w", err)
 	}
 
@@ -287,28 +777,64 @@ func (r *DeploymentReconciler) syncCustomSecretsWithVersions(ctx context.Context
 	secretVersions := make(map[string]string)
 
 	for _, secretConfig := range secretConfigs {
-		secret := &corev1.Secret{}
-		secretKey := types.NamespacedName{
-			Name:      secretConfig.Name,
-			Namespace: deployment.Namespace,
+		if err := secretConfig.validateRules(); err != nil {
+			metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "validation_rule_error").Inc()
+			log.Error(err, "invalid validation rule", "secret", secretConfig.Name)
+			return nil, nil, err
+		}
+
+		// Resolve "namespace/name" references against the cross-namespace policy
+		secretKey, err := r.CrossNamespace.ResolveSecretRef(secretConfig.Name, deployment.GetNamespace())
+		if err != nil {
+			metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "cross_namespace_denied").Inc()
+			log.Error(err, "refusing secret reference",
+				"secret", secretConfig.Name,
+				"deployment", deployment.GetName())
+			return nil, nil, err
 		}
 
+		secret := &corev1.Secret{}
 		if err := r.Get(ctx, secretKey, secret); err != nil {
-			metrics.SecretNotFoundErrors.WithLabelValues(deployment.Namespace, secretConfig.Name).Inc()
+			metrics.SecretNotFoundErrors.WithLabelValues(secretKey.Namespace, secretKey.Name).Inc()
 			log.Error(err, "failed to get secret - it may be generated by kustomize or similar tools",
-				"secret", secretConfig.Name,
-				"namespace", deployment.Namespace,
-				"deployment", deployment.Name,
+				"secret", secretKey.Name,
+				"namespace", secretKey.Namespace,
+				"deployment", deployment.GetName(),
 				"suggestion", "ensure secret generators run before operator sync")
-			return nil, nil, fmt.Errorf("failed to get secret %s (check if secret generators have run): %w", secretConfig.Name, err)
+			return nil, nil, secretGetError(secretKey, err)
+		}
+
+		if secretTypeDenied(secret, r.SkippedSecretTypes, r.SecretTypes) {
+			log.Error(fmt.Errorf("secret type is denylisted"), "refusing to sync secret of skipped type",
+				"secret", secretConfig.Name,
+				"type", secret.Type,
+				"namespace", deployment.GetNamespace(),
+				"deployment", deployment.GetName())
+			return nil, nil, fmt.Errorf("secret %s has type %s which is not allowed to be synced", secretConfig.Name, secret.Type)
+		}
+
+		if skipExternalSecret(r.Recorder, deployment, secret, r.ExternalSecretPolicy, log) {
+			return nil, nil, fmt.Errorf("secret %s is managed by the external secrets operator and is not allowed to be synced", secretConfig.Name)
 		}
 
 		// Track secret version for rotation detection
-		secretVersions[secretConfig.Name] = secret.ResourceVersion
+		secretVersions[secretConfig.Name] = SecretVersion(secret)
 
 		// Add specified keys to vault data
 		for _, key := range secretConfig.Keys {
 			if data, exists := secret.Data[key]; exists {
+				// Refuse values that break their validation rule
+				if err := secretConfig.validateValue(key, data); err != nil {
+					metrics.ValueValidationFailures.WithLabelValues(secretKey.Namespace, secretKey.Name, key).Inc()
+					log.Error(err, "secret value failed validation",
+						"secret", secretConfig.Name,
+						"key", key,
+						"deployment", deployment.GetName())
+					recordEvent(r.Recorder, deployment, corev1.EventTypeWarning, "ValueValidationFailed", "Sync",
+						"Refusing to sync: %v", err)
+					return nil, nil, err
+				}
+
 				// Use prefix if specified
 				vaultKey := key
 				if secretConfig.Prefix != "" {
@@ -316,13 +842,13 @@ func (r *DeploymentReconciler) syncCustomSecretsWithVersions(ctx context.Context
 				}
 				vaultData[vaultKey] = string(data)
 			} else {
-				metrics.SecretKeyMissingError.WithLabelValues(deployment.Namespace, secretConfig.Name, key).Inc()
+				metrics.SecretKeyMissingError.WithLabelValues(deployment.GetNamespace(), secretConfig.Name, key).Inc()
 				log.Error(fmt.Errorf("key not found in secret"), "key not found",
 					"secret", secretConfig.Name,
 					"key", key,
 					"available_keys", getSecretKeys(secret.Data),
-					"namespace", deployment.Namespace,
-					"deployment", deployment.Name)
+					"namespace", deployment.GetNamespace(),
+					"deployment", deployment.GetName())
 				return nil, nil, fmt.Errorf("key %s not found in secret %s", key, secretConfig.Name)
 			}
 		}
@@ -331,93 +857,322 @@ func (r *DeploymentReconciler) syncCustomSecretsWithVersions(ctx context.Context
 	return vaultData, secretVersions, nil
 }
 
-// syncAutoDiscoveredSecretsToSubPaths auto-discovers secrets and writes each to its own sub-path.
-func (r *DeploymentReconciler) syncAutoDiscoveredSecretsToSubPaths(ctx context.Context, deployment *appsv1.Deployment, basePath string) (map[string]string, error) {
-	log := r.Log.WithValues("deployment", deployment.Name, "namespace", deployment.Namespace)
+// discoverSecrets auto-discovers the secrets referenced by the deployment pod template and
+// returns them with their versions. Nothing is written to Vault, so this is cheap enough to run
+// on every rotation check.
+func (r *DeploymentReconciler) discoverSecrets(ctx context.Context, deployment client.Object) (map[string]*corev1.Secret, map[string]string, error) {
+	log := r.Log.WithValues("deployment", deployment.GetName(), "namespace", deployment.GetNamespace())
 
 	// Extract secret names from the deployment pod template
-	secretNames := r.extractSecretNamesFromPodTemplate(deployment.Spec.Template)
+	podTemplate, err := r.podTemplate(deployment)
+	if err != nil {
+		return nil, nil, err
+	}
+	secretNames := r.extractSecretNamesFromPodTemplate(podTemplate, GetIgnoreContainers(deployment))
+
+	// Follow references from Ingresses and Gateways listed in the discover-from annotation
+	referencedNames, err := r.discoverReferencedSecrets(ctx, deployment)
+	if err != nil {
+		log.Error(err, "failed to discover secrets referenced by other objects",
+			"annotation", deployment.GetAnnotations()[VaultDiscoverFromAnnotation])
+		return nil, nil, err
+	}
+	for secretName := range referencedNames {
+		secretNames[secretName] = true
+	}
 
 	if len(secretNames) == 0 {
 		log.Info("no secrets found in deployment pod template")
-		return map[string]string{}, nil
+		return map[string]*corev1.Secret{}, map[string]string{}, nil
 	}
 
 	log.Info("auto-discovered secrets", "secrets", secretNames)
 
-	// Track discovered secrets metric
-	metrics.SecretsDiscovered.WithLabelValues(deployment.Namespace, deployment.Name).Set(float64(len(secretNames)))
+	// Track discovered secrets metric (a per-resource gauge, only kept with detailed metrics)
+	if metrics.ResourceDetailed(deployment.GetNamespace()) {
+		metrics.SecretsDiscovered.WithLabelValues(deployment.GetNamespace(), deployment.GetName()).Set(float64(len(secretNames)))
+	}
 
-	// Collect secret versions and write each secret to its own sub-path
+	// Collect secrets and their versions
+	secrets := make(map[string]*corev1.Secret)
 	secretVersions := make(map[string]string)
 
 	for secretName := range secretNames {
 		secret := &corev1.Secret{}
 		secretKey := types.NamespacedName{
 			Name:      secretName,
-			Namespace: deployment.Namespace,
+			Namespace: deployment.GetNamespace(),
 		}
 
 		if err := r.Get(ctx, secretKey, secret); err != nil {
-			metrics.SecretNotFoundErrors.WithLabelValues(deployment.Namespace, secretName).Inc()
+			metrics.SecretNotFoundErrors.WithLabelValues(deployment.GetNamespace(), secretName).Inc()
 			log.Error(err, "failed to get auto-discovered secret",
 				"secret", secretName,
-				"namespace", deployment.Namespace,
-				"deployment", deployment.Name)
-			return nil, fmt.Errorf("failed to get secret %s: %w", secretName, err)
+				"namespace", deployment.GetNamespace(),
+				"deployment", deployment.GetName())
+			return nil, nil, fmt.Errorf("failed to get secret %s: %w", secretName, err)
+		}
+
+		if secretTypeDenied(secret, r.SkippedSecretTypes, r.SecretTypes) {
+			log.Info("skipping auto-discovered secret of denylisted type",
+				"secret", secretName,
+				"type", secret.Type)
+			continue
+		}
+
+		if skipExternalSecret(r.Recorder, deployment, secret, r.ExternalSecretPolicy, log) {
+			continue
 		}
 
 		// Track secret version for rotation detection
-		secretVersions[secretName] = secret.ResourceVersion
+		secrets[secretName] = secret
+		secretVersions[secretName] = SecretVersion(secret)
+	}
+
+	return secrets, secretVersions, nil
+}
 
+// writeAutoDiscoveredSecrets writes each auto-discovered secret to its own sub-path and
+// returns the number of keys written. When includeKeys is non-nil, only those keys are written.
+func (r *DeploymentReconciler) writeAutoDiscoveredSecrets(ctx context.Context, deployment client.Object, basePath string, secrets map[string]*corev1.Secret, includeKeys map[string]bool, keyPolicy KeySanitizationPolicy, unchangedPaths map[string]bool) (int, error) {
+	log := r.Log.WithValues("deployment", deployment.GetName(), "namespace", deployment.GetNamespace())
+
+	// Writes and deletions are collected first, so a transactional sync changes no path
+	// before every document passed the policy checks
+	type subPathWrite struct {
+		secretName string
+		secret     *corev1.Secret
+		// data is the secret's data, doc.Data adds the retained keys
+		data map[string]interface{}
+		doc  vaultDocument
+	}
+	var writes []subPathWrite
+	var emptyPaths []string
+	var sharedPaths []string
+	var writtenKeys int
+	ref := deployment.GetNamespace() + "/" + deployment.GetName()
+
+	for secretName, secret := range secrets {
 		// Create vault data for this secret (flattened structure)
-		secretData := make(map[string]interface{})
-		for key, value := range secret.Data {
-			secretData[key] = string(value)
+		secretData, err := autoDiscoveredSecretData(secret, includeKeys, keyPolicy)
+		if err != nil {
+			return writtenKeys, fmt.Errorf("failed to sanitize keys of secret %s: %w", secretName, err)
+		}
+		if ParsesJSONValues(deployment) {
+			secretData = parseJSONValues(secretData)
+		}
+		if CompressesValues(deployment) {
+			if secretData, err = compressValues(secretData); err != nil {
+				return writtenKeys, fmt.Errorf("failed to compress values of secret %s: %w", secretName, err)
+			}
+		}
+		// Write to sub-path: basePath/secretName, or the canonical path in shared-secret mode
+		secretPath := r.autoDiscoveredSecretPath(deployment, basePath, secretName)
+
+		if len(secretData) == 0 {
+			log.Info("auto-discovered secret has no included keys, skipping",
+				"secret", secretName,
+				"include_keys", deployment.GetAnnotations()[VaultIncludeKeysAnnotation])
+			// Keys written before are removed along with the sub-path; canonical paths of
+			// shared secrets may still be written with other keys for other workloads
+			if r.SharedSecrets == nil && !RetainsDeletedKeys(deployment) {
+				emptyPaths = append(emptyPaths, secretPath)
+			}
+			continue
+		}
+
+		// In shared-secret mode, write once to the canonical path and only when the secret changed
+		if r.SharedSecrets != nil {
+			sharedPaths = append(sharedPaths, secretPath)
+			if !r.SharedSecrets.AddReference(secretPath, deployment.GetNamespace(), secretName, SecretVersion(secret), ref) {
+				log.V(1).Info("shared secret already written at current version, skipping",
+					"secret", secretName,
+					"path", secretPath,
+					"references", r.SharedSecrets.References(secretPath))
+				continue
+			}
 		}
 
-		// Write to sub-path: basePath/secretName
-		secretPath := fmt.Sprintf("%s/%s", basePath, secretName)
+		// Content that matches the hash recorded in Vault is not written again
+		if unchangedPaths[secretPath] {
+			if r.SharedSecrets != nil {
+				r.SharedSecrets.MarkWritten(secretPath, SecretVersion(secret))
+			}
+			continue
+		}
+
+		writeData, err := retainDeletedKeys(ctx, r.VaultClient, deployment, secretPath, secretData, log)
+		if err != nil {
+			return writtenKeys, err
+		}
+		if err := checkWritePolicy(ctx, r.Policy, r.Recorder, r.kindName(), deployment, secretPath, r.ClusterName, writeData); err != nil {
+			return writtenKeys, err
+		}
 
 		log.Info("writing secret to vault sub-path",
 			"secret", secretName,
 			"path", secretPath,
 			"keys", len(secretData))
+		writes = append(writes, subPathWrite{
+			secretName: secretName,
+			secret:     secret,
+			data:       secretData,
+			doc:        vaultDocument{Path: secretPath, Data: writeData},
+		})
+	}
 
-		if err := r.VaultClient.WriteSecret(ctx, secretPath, secretData); err != nil {
-			log.Error(err, "failed to write secret to vault sub-path",
-				"secret", secretName,
-				"path", secretPath,
+	transactional := len(writes) > 1 && WritesTransactionally(deployment, r.TransactionalWrites)
+	if transactional {
+		docs := make([]vaultDocument, 0, len(writes))
+		for _, write := range writes {
+			// Documents are staged before they are promoted, which the policy has to allow as well
+			stagingPath := write.doc.Path + StagingPathSuffix
+			if err := checkWritePolicy(ctx, r.Policy, r.Recorder, r.kindName(), deployment, stagingPath, r.ClusterName, write.doc.Data); err != nil {
+				return writtenKeys, err
+			}
+			docs = append(docs, write.doc)
+		}
+		if err := writeVaultDocuments(ctx, r.VaultClient, r.Recorder, deployment, docs, log); err != nil {
+			log.Error(err, "failed to write secrets to vault sub-paths transactionally",
+				"paths", len(docs),
 				"error_details", err.Error())
-			return nil, fmt.Errorf("failed to write secret %s to vault: %w", secretName, err)
+			return writtenKeys, err
+		}
+	}
+	for _, write := range writes {
+		if !transactional {
+			if err := r.VaultClient.WriteSecret(ctx, write.doc.Path, write.doc.Data); err != nil {
+				log.Error(err, "failed to write secret to vault sub-path",
+					"secret", write.secretName,
+					"path", write.doc.Path,
+					"error_details", err.Error())
+				return writtenKeys, fmt.Errorf("failed to write secret %s to vault: %w", write.secretName, err)
+			}
+		}
+		r.SecretSizes.Record(r.Recorder, deployment, write.doc.Path, write.doc.Data)
+		writtenKeys += len(write.data)
+		r.recordContentHash(ctx, write.doc.Path, write.data, log)
+
+		if r.SharedSecrets != nil {
+			r.SharedSecrets.MarkWritten(write.doc.Path, SecretVersion(write.secret))
+		}
+	}
+
+	// Keys written before are removed along with the sub-paths of secrets without included keys
+	for _, path := range emptyPaths {
+		if err := r.VaultClient.DeleteSecret(ctx, path); err != nil {
+			return writtenKeys, fmt.Errorf("failed to delete sub-path %s without included keys: %w", path, err)
 		}
+		r.SecretSizes.Forget(path)
 	}
 
-	return secretVersions, nil
+	// References to canonical paths are recorded in Vault once they are written
+	if r.SharedSecrets != nil {
+		if err := r.persistSharedReferences(ctx, sharedPaths, ref); err != nil {
+			return writtenKeys, err
+		}
+	}
+
+	return writtenKeys, nil
 }
 
-// extractSecretNamesFromPodTemplate extracts all secret names referenced in the pod template.
-func (r *DeploymentReconciler) extractSecretNamesFromPodTemplate(podTemplate corev1.PodTemplateSpec) map[string]bool {
-	secretNames := make(map[string]bool)
+// autoDiscoveredSecretData returns the data of an auto-discovered secret as written to Vault,
+// empty when none of its keys are included.
+func autoDiscoveredSecretData(secret *corev1.Secret, includeKeys map[string]bool, keyPolicy KeySanitizationPolicy) (map[string]interface{}, error) {
+	secretData := make(map[string]interface{})
+	for key, value := range secret.Data {
+		if includeKeys != nil && !includeKeys[key] {
+			continue
+		}
+		secretData[key] = string(value)
+	}
+	if len(secretData) == 0 {
+		return secretData, nil
+	}
+	return keyPolicy.SanitizeVaultData(secretData)
+}
 
-	// Check environment variables
-	for _, container := range podTemplate.Spec.Containers {
-		for _, env := range container.Env {
-			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
-				secretNames[env.ValueFrom.SecretKeyRef.Name] = true
-			}
+// unchangedVaultPaths compares the data to be written with the content hashes recorded in
+// Vault. It returns the paths whose content is unchanged and whether any path needs writing.
+func (r *DeploymentReconciler) unchangedVaultPaths(ctx context.Context, deployment client.Object, vaultPath string, vaultData map[string]interface{}, discoveredSecrets map[string]*corev1.Secret, keyPolicy KeySanitizationPolicy) (map[string]bool, bool, error) {
+	unchanged := make(map[string]bool)
+	changed := false
+	check := func(path string, data map[string]interface{}) error {
+		same, err := vaultContentUnchanged(ctx, r.VaultClient, r.StateHashKey, path, data)
+		if err != nil {
+			return fmt.Errorf("failed to read content hash of %s: %w", path, err)
 		}
+		unchanged[path] = same
+		changed = changed || !same
+		return nil
+	}
 
-		// Check envFrom
-		for _, envFrom := range container.EnvFrom {
-			if envFrom.SecretRef != nil {
-				secretNames[envFrom.SecretRef.Name] = true
+	if len(vaultData) > 0 {
+		if err := check(vaultPath, vaultData); err != nil {
+			return nil, false, err
+		}
+	}
+	includeKeys := GetIncludeKeys(deployment)
+	for secretName, secret := range discoveredSecrets {
+		secretData, err := autoDiscoveredSecretData(secret, includeKeys, keyPolicy)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to sanitize keys of secret %s: %w", secretName, err)
+		}
+		if ParsesJSONValues(deployment) {
+			secretData = parseJSONValues(secretData)
+		}
+		if CompressesValues(deployment) {
+			if secretData, err = compressValues(secretData); err != nil {
+				return nil, false, fmt.Errorf("failed to compress values of secret %s: %w", secretName, err)
 			}
 		}
+		if len(secretData) == 0 {
+			continue
+		}
+		if err := check(r.autoDiscoveredSecretPath(deployment, vaultPath, secretName), secretData); err != nil {
+			return nil, false, err
+		}
+	}
+	return unchanged, changed, nil
+}
+
+// recordContentHash records the hash of data written to path when Vault is the state backend.
+// A hash that could not be recorded only means the data is written again on the next sync.
+func (r *DeploymentReconciler) recordContentHash(ctx context.Context, path string, data map[string]interface{}, log logr.Logger) {
+	if r.StateBackend != StateBackendVault {
+		return
+	}
+	if err := recordContentHash(ctx, r.VaultClient, r.StateHashKey, path, data); err != nil {
+		log.Error(err, "failed to record content hash in vault", "path", path)
+	}
+}
+
+// autoDiscoveredSecretPath returns the Vault path an auto-discovered secret is written to.
+func (r *DeploymentReconciler) autoDiscoveredSecretPath(deployment client.Object, basePath, secretName string) string {
+	if r.SharedSecrets != nil {
+		return r.NamespaceMounts.ResolvePath(deployment.GetNamespace(), r.SharedSecrets.CanonicalPath(deployment.GetNamespace(), secretName), r.ClusterName, false)
 	}
+	return fmt.Sprintf("%s/%s", basePath, secretName)
+}
+
+// extractSecretNamesFromPodTemplate extracts all secret names referenced in the pod template.
+// Containers named in ignoreContainers are skipped, together with the secret volumes that only
+// they mount, so secrets of injected sidecars are not discovered.
+func (r *DeploymentReconciler) extractSecretNamesFromPodTemplate(podTemplate corev1.PodTemplateSpec, ignoreContainers map[string]bool) map[string]bool {
+	secretNames := make(map[string]bool)
+	mountedVolumes := make(map[string]bool)
+	ignoredVolumes := make(map[string]bool)
+
+	// Check environment variables, envFrom and volume mounts of containers and init containers
+	containers := append(slices.Clone(podTemplate.Spec.InitContainers), podTemplate.Spec.Containers...)
+	for _, container := range containers {
+		if ignoreContainers[container.Name] {
+			for _, mount := range container.VolumeMounts {
+				ignoredVolumes[mount.Name] = true
+			}
+			continue
+		}
 
-	// Check init containers
-	for _, container := range podTemplate.Spec.InitContainers {
 		for _, env := range container.Env {
 			if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
 				secretNames[env.ValueFrom.SecretKeyRef.Name] = true
@@ -429,13 +1184,21 @@ func (r *DeploymentReconciler) extractSecretNamesFromPodTemplate(podTemplate cor
 				secretNames[envFrom.SecretRef.Name] = true
 			}
 		}
+
+		for _, mount := range container.VolumeMounts {
+			mountedVolumes[mount.Name] = true
+		}
 	}
 
-	// Check volumes
+	// Check volumes, skipping those mounted only by ignored containers
 	for _, volume := range podTemplate.Spec.Volumes {
-		if volume.Secret != nil {
-			secretNames[volume.Secret.SecretName] = true
+		if volume.Secret == nil {
+			continue
 		}
+		if ignoredVolumes[volume.Name] && !mountedVolumes[volume.Name] {
+			continue
+		}
+		secretNames[volume.Secret.SecretName] = true
 	}
 
 	return secretNames
@@ -455,18 +1218,40 @@ type SecretConfig struct {
 	Name   string   `json:"name"`
 	Keys   []string `json:"keys"`
 	Prefix string   `json:"prefix,omitempty"`
+	// Validate holds write-time validation rules per key
+	Validate map[string]ValueRule `json:"validate,omitempty"`
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&appsv1.Deployment{}).
-		Complete(r)
+	// Deployments are watched with PriorityEventHandler so vault-sync.io/priority orders the queue
+	name := r.Name
+	if name == "" {
+		name = r.kindLabel()
+	}
+	builder := ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		Watches(r.newWorkload(), PriorityEventHandler{})
+
+	// Changed Secrets requeue the workloads referencing them, found through the index. Indexing
+	// resolves the workload kind at setup, so it is only registered when the index is used.
+	if features.Enabled(features.SecretChangeRequeue) {
+		if err := r.indexSecretReferences(context.Background(), mgr.GetFieldIndexer()); err != nil {
+			return fmt.Errorf("failed to index secret references: %w", err)
+		}
+		builder = builder.Watches(&corev1.Secret{},
+			r.Propagation.WorkloadHandler(r.workloadsForSecret),
+			ctrlbuilder.WithPredicates(secretContentChanged))
+	}
+	if len(r.Namespaces) > 0 {
+		builder = builder.WithEventFilter(NamespaceFilter(r.Namespaces))
+	}
+	return builder.Complete(r)
 }
 
 // getLastKnownSecretVersions retrieves the last known secret versions from deployment annotations.
-func (r *DeploymentReconciler) getLastKnownSecretVersions(deployment *appsv1.Deployment) map[string]string {
-	versionsAnnotation, exists := deployment.Annotations[VaultSecretVersionsAnnotation]
+func (r *DeploymentReconciler) getLastKnownSecretVersions(deployment client.Object) map[string]string {
+	versionsAnnotation, exists := deployment.GetAnnotations()[VaultSecretVersionsAnnotation]
 	if !exists || versionsAnnotation == "" {
 		return make(map[string]string)
 	}
@@ -475,8 +1260,8 @@ func (r *DeploymentReconciler) getLastKnownSecretVersions(deployment *appsv1.Dep
 	if err := json.Unmarshal([]byte(versionsAnnotation), &versions); err != nil {
 		r.Log.Error(err, "failed to parse secret versions annotation",
 			"annotation", versionsAnnotation,
-			"deployment", deployment.Name,
-			"namespace", deployment.Namespace)
+			"deployment", deployment.GetName(),
+			"namespace", deployment.GetNamespace())
 		return make(map[string]string)
 	}
 
@@ -529,21 +1314,20 @@ func (r *DeploymentReconciler) getChangedSecrets(lastVersions, currentVersions m
 }
 
 // updateSecretVersionsAnnotation updates the deployment with current secret versions.
-func (r *DeploymentReconciler) updateSecretVersionsAnnotation(ctx context.Context, deployment *appsv1.Deployment, versions map[string]string) error {
+// The annotations are patched rather than updated so that the write cannot race with
+// a HorizontalPodAutoscaler or other controller changing the Deployment.
+func (r *DeploymentReconciler) updateSecretVersionsAnnotation(ctx context.Context, deployment client.Object, versions map[string]string) error {
 	versionsJSON, err := json.Marshal(versions)
 	if err != nil {
 		return fmt.Errorf("failed to marshal secret versions: %w", err)
 	}
 
-	// Create a copy of the deployment to update
-	updatedDeployment := deployment.DeepCopy()
-	if updatedDeployment.Annotations == nil {
-		updatedDeployment.Annotations = make(map[string]string)
-	}
-	updatedDeployment.Annotations[VaultSecretVersionsAnnotation] = string(versionsJSON)
+	annotations := map[string]string{VaultSecretVersionsAnnotation: string(versionsJSON)}
+	markForceSyncConsumed(deployment, annotations)
+	markSyncedConfig(deployment, annotations)
+	markSyncedAt(annotations)
 
-	// Update the deployment
-	if err := r.Update(ctx, updatedDeployment); err != nil {
+	if err := PatchAnnotations(ctx, r.Client, deployment, annotations); err != nil {
 		return fmt.Errorf("failed to update deployment annotations: %w", err)
 	}
 
@@ -551,36 +1335,73 @@ func (r *DeploymentReconciler) updateSecretVersionsAnnotation(ctx context.Contex
 }
 
 // isRotationCheckDisabled checks if secret rotation detection is disabled for this deployment.
-func (r *DeploymentReconciler) isRotationCheckDisabled(deployment *appsv1.Deployment) bool {
-	rotationCheck, exists := deployment.Annotations[VaultRotationCheckAnnotation]
-	return exists && rotationCheck == "disabled"
+func (r *DeploymentReconciler) isRotationCheckDisabled(deployment client.Object) bool {
+	return rotationCheckDisabled(deployment, r.RotationCheckPolicy)
+}
+
+// GetIgnoreContainers returns the containers listed in the vault-sync.io/ignore-containers
+// annotation, whose secret references are left out of auto-discovery.
+func GetIgnoreContainers(obj client.Object) map[string]bool {
+	value := strings.TrimSpace(obj.GetAnnotations()[VaultIgnoreContainersAnnotation])
+	if value == "" {
+		return nil
+	}
+
+	ignoreContainers := make(map[string]bool)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			ignoreContainers[name] = true
+		}
+	}
+	return ignoreContainers
+}
+
+// GetIncludeKeys returns the keys listed in the vault-sync.io/include-keys annotation,
+// or nil when all keys of auto-discovered secrets should be synced.
+func GetIncludeKeys(obj client.Object) map[string]bool {
+	value := strings.TrimSpace(obj.GetAnnotations()[VaultIncludeKeysAnnotation])
+	if value == "" {
+		return nil
+	}
+
+	includeKeys := make(map[string]bool)
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			includeKeys[key] = true
+		}
+	}
+	return includeKeys
 }
 
 // getReconcileInterval parses the reconciliation interval from the vault-sync.io/reconcile annotation.
-// Returns the duration if valid, or zero duration if disabled or invalid.
-func (r *DeploymentReconciler) getReconcileInterval(deployment *appsv1.Deployment) time.Duration {
-	reconcileValue, exists := deployment.Annotations[VaultReconcileAnnotation]
-	if !exists || reconcileValue == "" || reconcileValue == "off" {
+// Returns the duration if valid, zero if disabled, or the default interval if unset or invalid.
+func (r *DeploymentReconciler) getReconcileInterval(deployment client.Object) time.Duration {
+	reconcileValue, exists := deployment.GetAnnotations()[VaultReconcileAnnotation]
+	if reconcileValue == "off" {
 		return 0 // Disabled
 	}
+	if !exists || reconcileValue == "" {
+		return r.ReconcileBounds.Default(r.DefaultReconcileInterval)
+	}
 
 	duration, err := time.ParseDuration(reconcileValue)
 	if err != nil {
-		r.Log.Error(err, "invalid reconcile interval annotation, disabling periodic reconciliation",
-			"deployment", deployment.Name,
-			"namespace", deployment.Namespace,
-			"annotation_value", reconcileValue)
-		return 0 // Disabled on parse error
-	}
-
-	// Enforce minimum interval of 30 seconds to prevent excessive reconciliation
-	if duration < 30*time.Second {
-		r.Log.Info("reconcile interval too short, using minimum of 30 seconds",
-			"deployment", deployment.Name,
-			"namespace", deployment.Namespace,
+		r.Log.Error(err, "invalid reconcile interval annotation, using the default interval",
+			"deployment", deployment.GetName(),
+			"namespace", deployment.GetNamespace(),
+			"annotation_value", reconcileValue,
+			"default", r.ReconcileBounds.Default(r.DefaultReconcileInterval))
+		return r.ReconcileBounds.Default(r.DefaultReconcileInterval)
+	}
+
+	// Enforce the configured bounds to prevent excessive or too rare reconciliation
+	if enforced := r.ReconcileBounds.Clamp(duration); enforced != duration {
+		r.Log.Info("reconcile interval out of bounds, using the nearest bound",
+			"deployment", deployment.GetName(),
+			"namespace", deployment.GetNamespace(),
 			"requested", duration,
-			"enforced", 30*time.Second)
-		return 30 * time.Second
+			"enforced", enforced)
+		return enforced
 	}
 
 	return duration
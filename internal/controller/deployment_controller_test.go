@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 func TestExtractSecretNamesFromPodTemplate(t *testing.T) {
@@ -74,7 +76,7 @@ func TestExtractSecretNamesFromPodTemplate(t *testing.T) {
 		},
 	}
 
-	secretNames := r.extractSecretNamesFromPodTemplate(podTemplate)
+	secretNames := r.extractSecretNamesFromPodTemplate(podTemplate, nil)
 
 	// Expected secrets
 	expected := map[string]bool{
@@ -101,6 +103,92 @@ func TestExtractSecretNamesFromPodTemplate(t *testing.T) {
 	}
 }
 
+func TestExtractSecretNamesIgnoresContainers(t *testing.T) {
+	r := &DeploymentReconciler{}
+
+	secretEnv := func(secretName string) []corev1.EnvVar {
+		return []corev1.EnvVar{{
+			Name: "VALUE",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+				Key:                  "value",
+			}},
+		}}
+	}
+	secretVolume := func(name, secretName string) corev1.Volume {
+		return corev1.Volume{Name: name, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: secretName}}}
+	}
+
+	podTemplate := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "istio-init", Env: secretEnv("istio-init-secret")},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:         "app",
+					Env:          secretEnv("app-secret"),
+					VolumeMounts: []corev1.VolumeMount{{Name: "shared-certs"}},
+				},
+				{
+					Name:         "istio-proxy",
+					Env:          secretEnv("istio-token"),
+					VolumeMounts: []corev1.VolumeMount{{Name: "istio-certs"}, {Name: "shared-certs"}},
+				},
+			},
+			Volumes: []corev1.Volume{
+				secretVolume("istio-certs", "istio-ca"),
+				secretVolume("shared-certs", "shared-ca"),
+				secretVolume("unmounted", "unmounted-secret"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		ignoreContainers map[string]bool
+		expected         map[string]bool
+	}{
+		{
+			name:             "no ignored containers",
+			ignoreContainers: nil,
+			expected: map[string]bool{
+				"istio-init-secret": true, "app-secret": true, "istio-token": true,
+				"istio-ca": true, "shared-ca": true, "unmounted-secret": true,
+			},
+		},
+		{
+			name:             "sidecar and init container ignored",
+			ignoreContainers: map[string]bool{"istio-proxy": true, "istio-init": true},
+			expected: map[string]bool{
+				"app-secret": true, "shared-ca": true, "unmounted-secret": true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretNames := r.extractSecretNamesFromPodTemplate(podTemplate, tt.ignoreContainers)
+			if !reflect.DeepEqual(secretNames, tt.expected) {
+				t.Errorf("extractSecretNamesFromPodTemplate() = %v, expected %v", secretNames, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetIgnoreContainers(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		VaultIgnoreContainersAnnotation: " istio-proxy, linkerd-proxy,,",
+	}}}
+	expected := map[string]bool{"istio-proxy": true, "linkerd-proxy": true}
+	if got := GetIgnoreContainers(deployment); !reflect.DeepEqual(got, expected) {
+		t.Errorf("GetIgnoreContainers() = %v, expected %v", got, expected)
+	}
+	if got := GetIgnoreContainers(&appsv1.Deployment{}); got != nil {
+		t.Errorf("GetIgnoreContainers() without annotation = %v, expected nil", got)
+	}
+}
+
 func TestVaultSyncDetection(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -252,3 +340,45 @@ func TestGetReconcileInterval(t *testing.T) {
 		})
 	}
 }
+
+func TestGetIncludeKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *string
+		expected map[string]bool
+	}{
+		{
+			name:     "no annotation - all keys",
+			value:    nil,
+			expected: nil,
+		},
+		{
+			name:     "empty annotation - all keys",
+			value:    ptr.To(" "),
+			expected: nil,
+		},
+		{
+			name:     "key list",
+			value:    ptr.To("username,password"),
+			expected: map[string]bool{"username": true, "password": true},
+		},
+		{
+			name:     "spaces and empty entries",
+			value:    ptr.To(" username , ,password,"),
+			expected: map[string]bool{"username": true, "password": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{}
+			if tt.value != nil {
+				deployment.Annotations = map[string]string{VaultIncludeKeysAnnotation: *tt.value}
+			}
+			result := GetIncludeKeys(deployment)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("GetIncludeKeys() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
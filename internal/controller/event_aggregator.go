@@ -0,0 +1,145 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the EventAggregator which rate-limits and summarizes warning events.
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// EventAggregator wraps an EventRecorder and deduplicates warning events by reason. Within each
+// window only the first Burst warnings of a reason reach their objects; the rest are suppressed
+// and reported in a single summary event on SummaryTarget once the window ends. This keeps a
+// shared root cause, such as a sealed Vault, from flooding the cluster with identical events.
+// Normal events are passed through unchanged.
+type EventAggregator struct {
+	Recorder events.EventRecorder
+	// Window is the deduplication window per reason
+	Window time.Duration
+	// Burst is the number of warnings per reason passed through in each window
+	Burst int
+	// SummaryTarget is the object summary events are recorded against (e.g. the operator namespace)
+	SummaryTarget runtime.Object
+
+	mu      sync.Mutex
+	windows map[string]*warningWindow
+	now     func() time.Time
+}
+
+// warningWindow tracks the warnings of one reason within the current window.
+type warningWindow struct {
+	start      time.Time
+	action     string
+	emitted    int
+	suppressed int
+	objects    map[string]struct{}
+}
+
+// NewEventAggregator creates an aggregator that passes burst warnings per reason and window to recorder.
+func NewEventAggregator(recorder events.EventRecorder, window time.Duration, burst int, summaryTarget runtime.Object) *EventAggregator {
+	return &EventAggregator{
+		Recorder:      recorder,
+		Window:        window,
+		Burst:         burst,
+		SummaryTarget: summaryTarget,
+		windows:       make(map[string]*warningWindow),
+		now:           time.Now,
+	}
+}
+
+// Eventf implements events.EventRecorder.
+func (a *EventAggregator) Eventf(regarding runtime.Object, related runtime.Object, eventtype, reason, action, note string, args ...interface{}) {
+	if eventtype != corev1.EventTypeWarning {
+		a.Recorder.Eventf(regarding, related, eventtype, reason, action, note, args...)
+		return
+	}
+
+	a.mu.Lock()
+	now := a.now()
+	window, exists := a.windows[reason]
+	var expired *warningWindow
+	if exists && now.Sub(window.start) >= a.Window {
+		expired = window
+		exists = false
+	}
+	if !exists {
+		window = &warningWindow{start: now, action: action, objects: make(map[string]struct{})}
+		a.windows[reason] = window
+	}
+
+	pass := window.emitted < a.Burst
+	if pass {
+		window.emitted++
+	} else {
+		window.suppressed++
+		window.objects[eventObjectKey(regarding)] = struct{}{}
+	}
+	a.mu.Unlock()
+
+	a.summarize(reason, expired)
+	if pass {
+		a.Recorder.Eventf(regarding, related, eventtype, reason, action, note, args...)
+		return
+	}
+	metrics.EventsSuppressed.WithLabelValues(reason).Inc()
+}
+
+// Flush summarizes every window that has ended.
+func (a *EventAggregator) Flush() {
+	a.mu.Lock()
+	now := a.now()
+	expired := make(map[string]*warningWindow)
+	for reason, window := range a.windows {
+		if now.Sub(window.start) >= a.Window {
+			expired[reason] = window
+			delete(a.windows, reason)
+		}
+	}
+	a.mu.Unlock()
+
+	for reason, window := range expired {
+		a.summarize(reason, window)
+	}
+}
+
+// Start flushes ended windows periodically so summaries are emitted even when warnings stop.
+// It implements manager.Runnable.
+func (a *EventAggregator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(a.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			a.Flush()
+		}
+	}
+}
+
+// summarize records a summary event for a window that suppressed warnings.
+func (a *EventAggregator) summarize(reason string, window *warningWindow) {
+	if window == nil || window.suppressed == 0 || a.SummaryTarget == nil {
+		return
+	}
+	a.Recorder.Eventf(a.SummaryTarget, nil, corev1.EventTypeWarning, reason, window.action,
+		"Suppressed %d %s warnings for %d objects in the last %s",
+		window.suppressed, reason, len(window.objects), a.Window)
+}
+
+// eventObjectKey identifies the object an event is recorded against.
+func eventObjectKey(obj runtime.Object) string {
+	if o, ok := obj.(client.Object); ok {
+		return o.GetNamespace() + "/" + o.GetName()
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakeEventRecorder collects recorded events as "type reason note" strings.
+type fakeEventRecorder struct {
+	events []string
+}
+
+func (f *fakeEventRecorder) Eventf(_ runtime.Object, _ runtime.Object, eventtype, reason, _, note string, args ...interface{}) {
+	f.events = append(f.events, fmt.Sprintf("%s %s %s", eventtype, reason, fmt.Sprintf(note, args...)))
+}
+
+// TestEventAggregator tests that warnings beyond the burst are suppressed and summarized.
+func TestEventAggregator(t *testing.T) {
+	recorder := &fakeEventRecorder{}
+	target := &corev1.ObjectReference{Kind: "Namespace", Name: "vault-sync-operator-system"}
+	aggregator := NewEventAggregator(recorder, time.Minute, 2, target)
+	current := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	aggregator.now = func() time.Time { return current }
+
+	for i := 0; i < 5; i++ {
+		secret := &corev1.Secret{}
+		secret.Name = fmt.Sprintf("secret-%d", i)
+		secret.Namespace = "default"
+		aggregator.Eventf(secret, nil, corev1.EventTypeWarning, "VaultSealed", "Sync", "Vault is sealed")
+	}
+	aggregator.Eventf(&corev1.Secret{}, nil, corev1.EventTypeNormal, "Synced", "Sync", "ok")
+
+	if len(recorder.events) != 3 {
+		t.Fatalf("Expected 2 warnings and 1 normal event, got %v", recorder.events)
+	}
+
+	// Nothing is summarized before the window ends
+	aggregator.Flush()
+	if len(recorder.events) != 3 {
+		t.Fatalf("Expected no summary before the window ends, got %v", recorder.events)
+	}
+
+	current = current.Add(time.Minute)
+	aggregator.Flush()
+	if len(recorder.events) != 4 {
+		t.Fatalf("Expected a summary event, got %v", recorder.events)
+	}
+	summary := recorder.events[3]
+	if !strings.Contains(summary, "Suppressed 3 VaultSealed warnings for 3 objects") {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+
+	// A new window passes warnings through again
+	aggregator.Eventf(&corev1.Secret{}, nil, corev1.EventTypeWarning, "VaultSealed", "Sync", "Vault is sealed")
+	if len(recorder.events) != 5 {
+		t.Errorf("Expected warnings to pass in a new window, got %v", recorder.events)
+	}
+}
@@ -0,0 +1,97 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file guards against syncing Secrets managed by the External Secrets Operator.
+package controller
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// Policies for Secrets managed by the External Secrets Operator.
+const (
+	// ExternalSecretPolicyWarn syncs the Secret but emits a warning (default)
+	ExternalSecretPolicyWarn = "warn"
+	// ExternalSecretPolicySkip refuses to sync the Secret
+	ExternalSecretPolicySkip = "skip"
+	// ExternalSecretPolicyIgnore syncs the Secret without any check
+	ExternalSecretPolicyIgnore = "ignore"
+)
+
+// Markers set by the External Secrets Operator on the Secrets it manages.
+const (
+	externalSecretsGroup        = "external-secrets.io"
+	externalSecretKind          = "ExternalSecret"
+	ExternalSecretsManagedLabel = "reconcile.external-secrets.io/managed"
+	managedByLabel              = "app.kubernetes.io/managed-by"
+	externalSecretsManagerName  = "external-secrets"
+)
+
+// IsManagedByExternalSecrets reports whether the Secret is owned by an ExternalSecret or
+// carries an External Secrets Operator managed-by label.
+func IsManagedByExternalSecrets(secret *corev1.Secret) bool {
+	for _, owner := range secret.OwnerReferences {
+		group, _, _ := strings.Cut(owner.APIVersion, "/")
+		if owner.Kind == externalSecretKind && group == externalSecretsGroup {
+			return true
+		}
+	}
+
+	return secret.Labels[ExternalSecretsManagedLabel] == "true" ||
+		secret.Labels[managedByLabel] == externalSecretsManagerName
+}
+
+// IsValidExternalSecretPolicy reports whether policy is a known external secret policy.
+func IsValidExternalSecretPolicy(policy string) bool {
+	switch policy {
+	case ExternalSecretPolicyWarn, ExternalSecretPolicySkip, ExternalSecretPolicyIgnore:
+		return true
+	}
+	return false
+}
+
+// externalSecretConflicts records the conflicts already reported, by the UID of the object
+// synced and the namespace/name of the Secret, so that a conflict is reported when it starts
+// rather than on every reconcile.
+var externalSecretConflicts sync.Map // map[string]struct{}
+
+// skipExternalSecret applies the external secret policy to a Secret about to be synced on behalf
+// of obj and reports whether it must be skipped. An empty policy behaves like "warn".
+// Syncing an ExternalSecret target back to Vault can loop: ESO pulls from Vault, the operator
+// pushes the result back, and every round creates a new version. The conflict is reported
+// once per object and Secret until the Secret is no longer managed by ESO. obj may be nil.
+func skipExternalSecret(recorder events.EventRecorder, obj client.Object, secret *corev1.Secret, policy string, log logr.Logger) bool {
+	conflictKey := secret.Namespace + "/" + secret.Name
+	if obj != nil {
+		conflictKey = string(obj.GetUID()) + "/" + conflictKey
+	}
+	if policy == ExternalSecretPolicyIgnore || !IsManagedByExternalSecrets(secret) {
+		externalSecretConflicts.Delete(conflictKey)
+		return false
+	}
+
+	if _, reported := externalSecretConflicts.LoadOrStore(conflictKey, struct{}{}); !reported {
+		metrics.ExternalSecretConflicts.WithLabelValues(secret.Namespace, secret.Name).Inc()
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "ExternalSecretConflict", "Sync",
+			"Secret %s is managed by the External Secrets Operator; syncing it to Vault may cause version churn", secret.Name)
+		if policy != ExternalSecretPolicySkip {
+			log.Info("syncing secret managed by the external secrets operator",
+				"secret", secret.Name,
+				"namespace", secret.Namespace)
+		}
+	}
+
+	if policy == ExternalSecretPolicySkip {
+		log.Info("skipping secret managed by the external secrets operator",
+			"secret", secret.Name,
+			"namespace", secret.Namespace)
+		return true
+	}
+	return false
+}
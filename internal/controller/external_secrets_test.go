@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+)
+
+// TestIsManagedByExternalSecrets tests detection of Secrets managed by the External Secrets Operator.
+func TestIsManagedByExternalSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		owners   []metav1.OwnerReference
+		labels   map[string]string
+		expected bool
+	}{
+		{
+			name:     "plain secret",
+			expected: false,
+		},
+		{
+			name: "owned by ExternalSecret",
+			owners: []metav1.OwnerReference{
+				{APIVersion: "external-secrets.io/v1beta1", Kind: "ExternalSecret", Name: "db"},
+			},
+			expected: true,
+		},
+		{
+			name: "owned by unrelated ExternalSecret kind",
+			owners: []metav1.OwnerReference{
+				{APIVersion: "example.com/v1", Kind: "ExternalSecret", Name: "db"},
+			},
+			expected: false,
+		},
+		{
+			name:     "managed label",
+			labels:   map[string]string{ExternalSecretsManagedLabel: "true"},
+			expected: true,
+		},
+		{
+			name:     "managed-by label",
+			labels:   map[string]string{"app.kubernetes.io/managed-by": "external-secrets"},
+			expected: true,
+		},
+		{
+			name:     "managed by another tool",
+			labels:   map[string]string{"app.kubernetes.io/managed-by": "helm"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{}
+			secret.OwnerReferences = tt.owners
+			secret.Labels = tt.labels
+			if result := IsManagedByExternalSecrets(secret); result != tt.expected {
+				t.Errorf("IsManagedByExternalSecrets() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestSkipExternalSecretReportsOnChange tests that a conflict is reported when it starts, and
+// again only after the Secret stopped being managed by ESO in between.
+func TestSkipExternalSecretReportsOnChange(t *testing.T) {
+	deployment := &corev1.Secret{}
+	deployment.UID = "external-secret-report-test"
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Labels = map[string]string{ExternalSecretsManagedLabel: "true"}
+	recorder := events.NewFakeRecorder(10)
+
+	for i := 0; i < 3; i++ {
+		if skipExternalSecret(recorder, deployment, secret, ExternalSecretPolicyWarn, logr.Discard()) {
+			t.Fatalf("skipExternalSecret() = true with the warn policy")
+		}
+	}
+	if got := len(recorder.Events); got != 1 {
+		t.Fatalf("events = %d, expected one while the conflict lasts", got)
+	}
+
+	secret.Labels = nil
+	skipExternalSecret(recorder, deployment, secret, ExternalSecretPolicyWarn, logr.Discard())
+	secret.Labels = map[string]string{ExternalSecretsManagedLabel: "true"}
+	if !skipExternalSecret(recorder, deployment, secret, ExternalSecretPolicySkip, logr.Discard()) {
+		t.Fatalf("skipExternalSecret() = false with the skip policy")
+	}
+	if got := len(recorder.Events); got != 2 {
+		t.Errorf("events = %d, expected a second one for the new conflict", got)
+	}
+}
@@ -0,0 +1,397 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the controller importing secrets from files mounted into the operator.
+package controller
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// DefaultImportInterval is how often the sources of VaultFileImports are read again.
+const DefaultImportInterval = time.Minute
+
+// fileImportKind identifies VaultFileImports in the path inventory and the error log.
+const fileImportKind = "fileimport"
+
+// FileImportReconciler writes secrets from files mounted into the operator, such as those
+// dropped by a legacy tool, to Vault as described by VaultFileImport resources. Sources are
+// read from under Root/<namespace> only, so an import can neither read the operator's own
+// files nor those mounted for other namespaces. Paths are resolved, tracked, checked against
+// the write policy and deleted like those of annotated resources.
+type FileImportReconciler struct {
+	client.Client
+	Log         logr.Logger
+	VaultClient VaultWriterDeleter
+	// Root holds a directory per namespace the sources of its imports are relative to
+	Root string
+	// Interval is how often sources are read again, DefaultImportInterval when 0
+	Interval        time.Duration
+	ClusterName     string
+	NamespaceMounts NamespaceMounts
+	// Inventory tracks the Vault paths managed by each resource (optional)
+	Inventory *ManagedPathInventory
+	// Policy is evaluated before every Vault write and can deny it (optional)
+	Policy *PolicyHook
+	// PreserveOnDelete decides which imports keep their Vault paths besides those setting
+	// preserveOnDelete
+	PreserveOnDelete PreserveOnDeletePolicy
+	// Recorder emits Kubernetes events for the imports (optional)
+	Recorder events.EventRecorder
+	// SecretSizes tracks the size of the written paths (optional)
+	SecretSizes *SecretSizeTracker
+	// Errors keeps the recent sync and delete errors for the status page (optional)
+	Errors *ErrorLog
+	// HashKey keys the content hash recorded in the status of the imports. When empty a
+	// random key is used, so every source is written once more after a restart.
+	HashKey []byte
+
+	hashKeyOnce sync.Once
+}
+
+// +kubebuilder:rbac:groups=vault-sync.io,resources=vaultfileimports,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=vault-sync.io,resources=vaultfileimports/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vault-sync.io,resources=vaultfileimports/finalizers,verbs=update
+
+// Reconcile reads the source of a VaultFileImport and writes it to Vault when it changed.
+func (r *FileImportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("vaultfileimport", req.Name, "namespace", req.Namespace)
+	ctx = vault.WithSourceNamespace(ctx, req.Namespace)
+
+	imp := &v1alpha1.VaultFileImport{}
+	if err := r.Get(ctx, req.NamespacedName, imp); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !imp.DeletionTimestamp.IsZero() {
+		return r.handleDeletion(ctx, imp)
+	}
+	if !controllerutil.ContainsFinalizer(imp, VaultSyncFinalizer) {
+		controllerutil.AddFinalizer(imp, VaultSyncFinalizer)
+		return ctrl.Result{}, r.Update(ctx, imp)
+	}
+
+	key := client.ObjectKeyFromObject(imp)
+	resource := metrics.ResourceLabel(imp.Namespace, imp.Name)
+	interval := r.interval(imp)
+	path := r.NamespaceMounts.ResolvePath(imp.Namespace, imp.Spec.Path, r.ClusterName, false)
+
+	// A missing or malformed source may be fixed by the tool writing it, so it is read again
+	// at the next interval rather than retried with backoff
+	data, err := readImportSource(r.Root, imp.Namespace, imp.Spec)
+	if err != nil {
+		log.Error(err, "failed to read import source", "source", imp.Spec.Source)
+		metrics.SecretsyncAttempts.WithLabelValues(imp.Namespace, resource, "failed").Inc()
+		recordEvent(r.Recorder, imp, corev1.EventTypeWarning, "ImportFailed", "Import",
+			"Failed to read %s: %v", imp.Spec.Source, err)
+		return ctrl.Result{RequeueAfter: interval}, r.updateStatus(ctx, imp, metav1.ConditionFalse, v1alpha1.ReasonSourceError, err.Error(), nil)
+	}
+	hash, err := ContentHMAC(r.hashKey(), data)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if imp.Status.ContentHash == hash && imp.Status.VaultPath == path && imp.Status.ObservedGeneration == imp.Generation {
+		// Nothing changed since the last import; the inventory is refreshed after restarts
+		r.Inventory.Set(fileImportKind, key, []string{path})
+		r.Inventory.SetPreserved(fileImportKind, key, r.PreserveOnDelete.Preserves(imp) || imp.Spec.PreserveOnDelete)
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
+
+	if err := r.write(ctx, imp, path, data); err != nil {
+		log.Error(err, "failed to write imported secret to vault", "path", path)
+		metrics.SecretsyncAttempts.WithLabelValues(imp.Namespace, resource, "failed").Inc()
+		recordEvent(r.Recorder, imp, corev1.EventTypeWarning, "ImportFailed", "Import",
+			"Failed to write %s to Vault: %v", path, err)
+		if statusErr := r.updateStatus(ctx, imp, metav1.ConditionFalse, v1alpha1.ReasonWriteFailed, err.Error(), nil); statusErr != nil {
+			log.Error(statusErr, "failed to update status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// The previous path is deleted when the import moves, unless it is kept or still used
+	if previous := imp.Status.VaultPath; previous != "" && previous != path &&
+		!r.PreserveOnDelete.Preserves(imp) && !imp.Spec.PreserveOnDelete && !r.Inventory.ManagedByOthers(fileImportKind, key, previous) {
+		err := r.VaultClient.DeleteSecret(ctx, previous)
+		r.Errors.Record(fileImportKind, imp, previous, LogOpDelete, err)
+		if err != nil {
+			log.Error(err, "failed to delete previous vault path of import", "path", previous)
+		} else {
+			r.SecretSizes.Forget(previous)
+		}
+	}
+
+	r.Inventory.Set(fileImportKind, key, []string{path})
+	r.Inventory.SetPreserved(fileImportKind, key, r.PreserveOnDelete.Preserves(imp) || imp.Spec.PreserveOnDelete)
+	r.SecretSizes.Record(r.Recorder, imp, path, data)
+	metrics.SecretsyncAttempts.WithLabelValues(imp.Namespace, resource, "success").Inc()
+	log.Info("imported secret to vault", "path", path, "keys", len(data))
+	recordEvent(r.Recorder, imp, corev1.EventTypeNormal, "Imported", "Import",
+		"Imported %d keys from %s to %s", len(data), imp.Spec.Source, path)
+
+	synced := &v1alpha1.VaultFileImportStatus{VaultPath: path, ContentHash: hash}
+	message := fmt.Sprintf("imported %d keys to %s", len(data), path)
+	return ctrl.Result{RequeueAfter: interval}, r.updateStatus(ctx, imp, metav1.ConditionTrue, v1alpha1.ReasonSynced, message, synced)
+}
+
+// write writes data to path once the write policy allowed it, serialized with other
+// reconciles targeting the same path.
+func (r *FileImportReconciler) write(ctx context.Context, imp *v1alpha1.VaultFileImport, path string, data map[string]interface{}) error {
+	if err := checkWritePolicy(ctx, r.Policy, r.Recorder, "VaultFileImport", imp, path, r.ClusterName, data); err != nil {
+		return err
+	}
+	unlock, err := lockVaultPath(ctx, r.VaultClient, path)
+	if err != nil {
+		return fmt.Errorf("failed to lock vault path %s: %w", path, err)
+	}
+	defer unlock()
+
+	err = r.VaultClient.WriteSecret(ctx, path, data)
+	r.Errors.Record(fileImportKind, imp, path, LogOpSync, err)
+	return err
+}
+
+// updateStatus sets the Synced condition of imp. synced carries the path and hash of a
+// successful write, which are kept unchanged on failures.
+func (r *FileImportReconciler) updateStatus(ctx context.Context, imp *v1alpha1.VaultFileImport, status metav1.ConditionStatus, reason, message string, synced *v1alpha1.VaultFileImportStatus) error {
+	current := imp.DeepCopy()
+	if synced != nil {
+		now := metav1.Now()
+		imp.Status.VaultPath = synced.VaultPath
+		imp.Status.ContentHash = synced.ContentHash
+		imp.Status.LastSyncTime = &now
+		imp.Status.ObservedGeneration = imp.Generation
+	}
+	meta.SetStatusCondition(&imp.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionSynced,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: imp.Generation,
+	})
+	if err := r.Status().Patch(ctx, imp, client.MergeFrom(current)); err != nil {
+		return fmt.Errorf("failed to update status of %s: %w", client.ObjectKeyFromObject(imp), err)
+	}
+	return nil
+}
+
+// handleDeletion deletes the imported path from Vault and removes the finalizer.
+func (r *FileImportReconciler) handleDeletion(ctx context.Context, imp *v1alpha1.VaultFileImport) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(imp, VaultSyncFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	log := r.Log.WithValues("vaultfileimport", imp.Name, "namespace", imp.Namespace)
+	key := client.ObjectKeyFromObject(imp)
+	path := imp.Status.VaultPath
+
+	switch {
+	case path == "":
+		// Nothing was written
+	case r.PreserveOnDelete.Preserves(imp) || imp.Spec.PreserveOnDelete:
+		log.Info("preserving imported vault secret", "path", path)
+		recordEvent(r.Recorder, imp, corev1.EventTypeNormal, "VaultPathPreserved", "Delete",
+			"Keeping %s in Vault due to the preserve-on-delete policy", path)
+	case r.Inventory.ManagedByOthers(fileImportKind, key, path):
+		log.Info("keeping imported vault secret managed by other resources", "path", path)
+	case isVaultPathDeleted(imp, path):
+		log.Info("secret already deleted from vault by an earlier attempt", "path", path)
+	default:
+		unlock, err := lockVaultPath(ctx, r.VaultClient, path)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to lock vault path %s: %w", path, err)
+		}
+		defer unlock()
+
+		recordDeleteAttempt(r.Recorder, imp, path)
+		err = r.VaultClient.DeleteSecret(ctx, path)
+		r.Errors.Record(fileImportKind, imp, path, LogOpDelete, err)
+		recordDeleteOutcome(r.Recorder, imp, path, err)
+		if err != nil {
+			log.Error(err, "failed to delete imported secret from vault", "path", path)
+			return ctrl.Result{}, err
+		}
+		r.SecretSizes.Forget(path)
+		markVaultPathDeleted(ctx, r.Client, imp, path, log)
+	}
+
+	r.Inventory.Forget(fileImportKind, key)
+	return ctrl.Result{}, finishDeletion(ctx, r.Client, r.Recorder, imp)
+}
+
+// hashKey returns the key of the content hashes, generating a random one when none is set.
+// The status is readable by anyone allowed to get the import, so the hash must be keyed.
+func (r *FileImportReconciler) hashKey() []byte {
+	r.hashKeyOnce.Do(func() {
+		if len(r.HashKey) > 0 {
+			return
+		}
+		r.HashKey = make([]byte, MinStateHashKeyLength)
+		if _, err := rand.Read(r.HashKey); err != nil {
+			panic(fmt.Sprintf("failed to generate content hash key: %v", err))
+		}
+	})
+	return r.HashKey
+}
+
+// interval returns how often the source of imp is read again.
+func (r *FileImportReconciler) interval(imp *v1alpha1.VaultFileImport) time.Duration {
+	if imp.Spec.Interval != nil && imp.Spec.Interval.Duration > 0 {
+		return imp.Spec.Interval.Duration
+	}
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return DefaultImportInterval
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *FileImportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("vaultfileimport").
+		For(&v1alpha1.VaultFileImport{}).
+		Complete(r)
+}
+
+// readImportSource reads the keys of the source of spec from under the directory of namespace
+// in root. Sources must be relative paths that stay inside that directory.
+func readImportSource(root, namespace string, spec v1alpha1.VaultFileImportSpec) (map[string]interface{}, error) {
+	if !filepath.IsLocal(spec.Source) {
+		return nil, fmt.Errorf("source %q must be a relative path inside the import directory of namespace %s", spec.Source, namespace)
+	}
+	rootPath, err := filepath.EvalSymlinks(filepath.Join(root, namespace))
+	if err != nil {
+		return nil, fmt.Errorf("invalid import root: %w", err)
+	}
+	source, err := resolveImportPath(rootPath, filepath.Join(rootPath, spec.Source))
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	switch spec.Format {
+	case v1alpha1.FileImportFormatDirectory, "":
+		data, err = readImportDirectory(rootPath, source)
+	case v1alpha1.FileImportFormatJSON:
+		data, err = readImportJSON(source)
+	case v1alpha1.FileImportFormatEnv:
+		data, err = readImportEnv(source)
+	default:
+		return nil, fmt.Errorf("unknown format %q", spec.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("source %s has no keys", spec.Source)
+	}
+	return data, nil
+}
+
+// resolveImportPath follows the symlinks of path and fails unless it stays under rootPath,
+// so a source or a link inside it cannot point at files elsewhere in the operator.
+func resolveImportPath(rootPath, path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved != rootPath && !strings.HasPrefix(resolved, rootPath+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s resolves outside of the import root", strings.TrimPrefix(path, rootPath+string(filepath.Separator)))
+	}
+	return resolved, nil
+}
+
+// readImportDirectory reads every regular file of dir as a key named after the file. Hidden
+// entries, such as the ..data links of Kubernetes volumes, and subdirectories are skipped.
+func readImportDirectory(rootPath, dir string) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		file, err := resolveImportPath(rootPath, filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		value, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		data[entry.Name()] = string(value)
+	}
+	return data, nil
+}
+
+// readImportJSON reads a file holding a JSON object of keys. Nested values are kept as they are.
+func readImportJSON(file string) (map[string]interface{}, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(content)))
+	decoder.UseNumber()
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object: %w", filepath.Base(file), err)
+	}
+	return data, nil
+}
+
+// readImportEnv reads a file of KEY=VALUE lines. Blank lines and lines starting with # are
+// skipped, and values may be wrapped in single or double quotes.
+func readImportEnv(file string) (map[string]interface{}, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	data := make(map[string]interface{})
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(text, "=")
+		key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+		if !found || key == "" {
+			return nil, fmt.Errorf("%s line %d: expected KEY=VALUE", filepath.Base(file), line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		data[key] = value
+	}
+	return data, scanner.Err()
+}
@@ -0,0 +1,290 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+)
+
+func TestReadImportSource(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "default")
+	outside := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A directory laid out like a Kubernetes Secret volume
+	writeFile(filepath.Join(root, "db", "..2024_01_01", "password"), "s3cret")
+	writeFile(filepath.Join(root, "db", "..2024_01_01", "username"), "app")
+	if err := os.Symlink("..2024_01_01", filepath.Join(root, "db", "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"password", "username"} {
+		if err := os.Symlink(filepath.Join("..data", key), filepath.Join(root, "db", key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(filepath.Join(root, "api.json"), `{"token": "abc", "limits": {"rps": 10}}`)
+	writeFile(filepath.Join(root, "app.env"), "# legacy settings\nAPI_KEY=abc\n\nexport REGION = \"eu west\"\n")
+	writeFile(filepath.Join(root, "broken.env"), "API_KEY\n")
+	writeFile(filepath.Join(base, "other", "db.env"), "PASSWORD=other\n")
+	writeFile(filepath.Join(outside, "token"), "operator token")
+	if err := os.Symlink(filepath.Join(outside, "token"), filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		spec     v1alpha1.VaultFileImportSpec
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "directory",
+			spec:     v1alpha1.VaultFileImportSpec{Source: "db"},
+			expected: `{"password":"s3cret","username":"app"}`,
+		},
+		{
+			name:     "json",
+			spec:     v1alpha1.VaultFileImportSpec{Source: "api.json", Format: v1alpha1.FileImportFormatJSON},
+			expected: `{"limits":{"rps":10},"token":"abc"}`,
+		},
+		{
+			name:     "env",
+			spec:     v1alpha1.VaultFileImportSpec{Source: "app.env", Format: v1alpha1.FileImportFormatEnv},
+			expected: `{"API_KEY":"abc","REGION":"eu west"}`,
+		},
+		{
+			name:    "malformed env",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "broken.env", Format: v1alpha1.FileImportFormatEnv},
+			wantErr: "line 1",
+		},
+		{
+			name:     "parent references inside the namespace directory",
+			spec:     v1alpha1.VaultFileImportSpec{Source: "db/../app.env", Format: v1alpha1.FileImportFormatEnv},
+			expected: `{"API_KEY":"abc","REGION":"eu west"}`,
+		},
+		{
+			name:    "parent references out of the root",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "../../db"},
+			wantErr: "must be a relative path",
+		},
+		{
+			name:    "directory of another namespace",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "../other/db.env", Format: v1alpha1.FileImportFormatEnv},
+			wantErr: "must be a relative path",
+		},
+		{
+			name:    "absolute path",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "/etc/passwd", Format: v1alpha1.FileImportFormatEnv},
+			wantErr: "must be a relative path",
+		},
+		{
+			name:    "symlink out of the root",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "escape", Format: v1alpha1.FileImportFormatEnv},
+			wantErr: "outside of the import root",
+		},
+		{
+			name:    "missing source",
+			spec:    v1alpha1.VaultFileImportSpec{Source: "missing"},
+			wantErr: "no such file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := readImportSource(base, "default", tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readImportSource() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readImportSource() error = %v", err)
+			}
+			encoded, _ := json.Marshal(data)
+			if string(encoded) != tt.expected {
+				t.Errorf("readImportSource() = %s, expected %s", encoded, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFileImportReconciler(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "default"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "default", "app.env"), []byte("API_KEY=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	imp := &v1alpha1.VaultFileImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default", Generation: 1},
+		Spec: v1alpha1.VaultFileImportSpec{
+			Source: "app.env",
+			Format: v1alpha1.FileImportFormatEnv,
+			Path:   "secret/data/legacy",
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.VaultFileImport{}).WithObjects(imp).Build()
+	vaultClient := &fakeVault{}
+	inventory := NewManagedPathInventory(false)
+	r := &FileImportReconciler{
+		Client:          k8sClient,
+		Log:             logr.Discard(),
+		VaultClient:     vaultClient,
+		Root:            root,
+		ClusterName:     "prod",
+		NamespaceMounts: NamespaceMounts{},
+		Inventory:       inventory,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "legacy"}}
+	reconcile := func() {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	reconcileUntilSynced(t, r, req)
+	written := vaultClient.secrets["clusters/prod/secret/data/legacy"]
+	if written["API_KEY"] != "abc" {
+		t.Fatalf("written = %v, expected API_KEY to be imported under the cluster prefix", vaultClient.secrets)
+	}
+	result := &v1alpha1.VaultFileImport{}
+	if err := k8sClient.Get(ctx, req.NamespacedName, result); err != nil {
+		t.Fatal(err)
+	}
+	condition := meta.FindStatusCondition(result.Status.Conditions, v1alpha1.ConditionSynced)
+	if condition == nil || condition.Status != metav1.ConditionTrue || result.Status.VaultPath != "clusters/prod/secret/data/legacy" {
+		t.Errorf("status = %+v, expected a Synced import of clusters/prod/secret/data/legacy", result.Status)
+	}
+	if plain, _ := ContentHash(written); result.Status.ContentHash == "" || result.Status.ContentHash == plain {
+		t.Errorf("status content hash = %q, expected a keyed hash", result.Status.ContentHash)
+	}
+	if inventory.Count() != 1 {
+		t.Errorf("inventory count = %d, expected 1", inventory.Count())
+	}
+
+	// An unchanged file is not written again
+	delete(vaultClient.secrets, "clusters/prod/secret/data/legacy")
+	reconcile()
+	if _, found := vaultClient.secrets["clusters/prod/secret/data/legacy"]; found {
+		t.Errorf("expected an unchanged source not to be written again")
+	}
+
+	// A source error is reported in the status without touching Vault
+	if err := os.Remove(filepath.Join(root, "default", "app.env")); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if err := k8sClient.Get(ctx, req.NamespacedName, result); err != nil {
+		t.Fatal(err)
+	}
+	condition = meta.FindStatusCondition(result.Status.Conditions, v1alpha1.ConditionSynced)
+	if condition == nil || condition.Reason != v1alpha1.ReasonSourceError {
+		t.Errorf("condition = %+v, expected %s", condition, v1alpha1.ReasonSourceError)
+	}
+
+	// Deleting the import deletes its path
+	if err := k8sClient.Delete(ctx, result); err != nil {
+		t.Fatal(err)
+	}
+	reconcile()
+	if len(vaultClient.deletes) != 1 || vaultClient.deletes[0] != "clusters/prod/secret/data/legacy" {
+		t.Errorf("deletes = %v, expected clusters/prod/secret/data/legacy", vaultClient.deletes)
+	}
+	if inventory.Count() != 0 {
+		t.Errorf("inventory count = %d, expected the path to be forgotten", inventory.Count())
+	}
+}
+
+// TestFileImportPreserveOnDeletePolicy tests that imports in namespaces of the preserve-on-delete
+// policy keep their path, however preserveOnDelete is set.
+func TestFileImportPreserveOnDeletePolicy(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "prod"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "prod", "app.env"), []byte("API_KEY=abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	imp := &v1alpha1.VaultFileImport{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "prod", Generation: 1},
+		Spec:       v1alpha1.VaultFileImportSpec{Source: "app.env", Format: v1alpha1.FileImportFormatEnv, Path: "secret/data/legacy"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.VaultFileImport{}).WithObjects(imp).Build()
+	vaultClient := &fakeVault{}
+	policy, err := NewPreserveOnDeletePolicy([]string{"prod"})
+	if err != nil {
+		t.Fatalf("NewPreserveOnDeletePolicy() error = %v", err)
+	}
+	r := &FileImportReconciler{
+		Client:           k8sClient,
+		Log:              logr.Discard(),
+		VaultClient:      vaultClient,
+		Root:             root,
+		Inventory:        NewManagedPathInventory(false),
+		PreserveOnDelete: policy,
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "prod", Name: "legacy"}}
+	reconcileUntilSynced(t, r, req)
+
+	// Moving the import keeps the previous path
+	if err := k8sClient.Get(ctx, req.NamespacedName, imp); err != nil {
+		t.Fatal(err)
+	}
+	imp.Spec.Path = "secret/data/moved"
+	imp.Generation++
+	if err := k8sClient.Update(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// Deleting the import keeps the current path
+	if err := k8sClient.Delete(ctx, imp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(vaultClient.deletes) != 0 {
+		t.Errorf("deletes = %v, expected the paths of a preserved namespace to be kept", vaultClient.deletes)
+	}
+	if vaultClient.secrets["secret/data/moved"]["API_KEY"] != "abc" {
+		t.Errorf("secrets = %v, expected the import to be moved to secret/data/moved", vaultClient.secrets)
+	}
+}
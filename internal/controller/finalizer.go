@@ -0,0 +1,82 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements finalization helpers that are safe to re-enter.
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// RemoveFinalizer removes the operator's finalizer from obj. Conflicts with other writers,
+// such as the garbage collector or other controllers finalizing a foreground deletion, are
+// retried against the latest version of the object. An object that is already gone or no
+// longer carries the finalizer is left alone.
+func RemoveFinalizer(ctx context.Context, k8sClient client.Client, obj client.Object) error {
+	current := obj.DeepCopyObject().(client.Object)
+	refresh := false
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(current), current); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+		}
+		refresh = true
+
+		if !controllerutil.RemoveFinalizer(current, VaultSyncFinalizer) {
+			return nil
+		}
+		return client.IgnoreNotFound(k8sClient.Update(ctx, current))
+	})
+}
+
+// isVaultPathDeleted reports whether an earlier finalization attempt already deleted path
+// from Vault, so a retried finalization does not delete it again.
+func isVaultPathDeleted(obj client.Object, path string) bool {
+	return obj.GetAnnotations()[VaultDeletedPathAnnotation] == path
+}
+
+// markVaultPathDeleted records that path was deleted from Vault while finalizing obj.
+// Failures are logged only: at worst the idempotent delete is repeated on the next attempt.
+func markVaultPathDeleted(ctx context.Context, k8sClient client.Client, obj client.Object, path string, log logr.Logger) {
+	if err := PatchAnnotations(ctx, k8sClient, obj, map[string]string{VaultDeletedPathAnnotation: path}); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "failed to record vault deletion", "path", path)
+	}
+}
+
+// finishDeletion removes the operator's finalizer from obj as the last step of deletion
+// handling, and records the outcome as an event.
+func finishDeletion(ctx context.Context, k8sClient client.Client, recorder events.EventRecorder, obj client.Object) error {
+	if err := RemoveFinalizer(ctx, k8sClient, obj); err != nil {
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "FinalizerRemovalFailed", "Delete",
+			"Failed to remove finalizer %s: %v", VaultSyncFinalizer, err)
+		return err
+	}
+	recordEvent(recorder, obj, corev1.EventTypeNormal, "FinalizerRemoved", "Delete",
+		"Removed finalizer %s", VaultSyncFinalizer)
+	return nil
+}
+
+// recordDeleteAttempt records that deletion handling of obj is about to delete path from
+// Vault, so a delete that hangs shows which step it is stuck in.
+func recordDeleteAttempt(recorder events.EventRecorder, obj client.Object, path string) {
+	recordEvent(recorder, obj, corev1.EventTypeNormal, "VaultDeleteAttempted", "Delete",
+		"Deleting %s from Vault", path)
+}
+
+// recordDeleteOutcome records whether deleting path from Vault for obj succeeded.
+func recordDeleteOutcome(recorder events.EventRecorder, obj client.Object, path string, err error) {
+	if err != nil {
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultDeleteFailed", "Delete",
+			"Failed to delete %s from Vault: %v", path, err)
+		return
+	}
+	recordEvent(recorder, obj, corev1.EventTypeNormal, "VaultDeleteSucceeded", "Delete",
+		"Deleted %s from Vault", path)
+}
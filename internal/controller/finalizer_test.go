@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestRemoveFinalizerForegroundDeletion tests finalizer removal while another finalizer
+// is removed concurrently from a foreground-deleted Deployment.
+func TestRemoveFinalizerForegroundDeletion(t *testing.T) {
+	ctx := context.Background()
+
+	deployment := newPatchTestDeployment(nil)
+	deployment.Finalizers = []string{metav1.FinalizerDeleteDependents, VaultSyncFinalizer, "example.com/cleanup"}
+	k8sClient := fake.NewClientBuilder().WithObjects(deployment).Build()
+	if err := k8sClient.Delete(ctx, deployment, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil {
+		t.Fatalf("failed to delete deployment: %v", err)
+	}
+
+	stale := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), stale); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+
+	// Another controller finishes first, so the operator's copy is out of date
+	latest := stale.DeepCopy()
+	latest.Finalizers = []string{metav1.FinalizerDeleteDependents, VaultSyncFinalizer}
+	if err := k8sClient.Update(ctx, latest); err != nil {
+		t.Fatalf("failed to update deployment: %v", err)
+	}
+
+	if err := RemoveFinalizer(ctx, k8sClient, stale); err != nil {
+		t.Fatalf("RemoveFinalizer() error = %v", err)
+	}
+
+	result := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), result); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if expected := []string{metav1.FinalizerDeleteDependents}; !reflect.DeepEqual(result.Finalizers, expected) {
+		t.Errorf("finalizers = %v, expected %v", result.Finalizers, expected)
+	}
+
+	// Removing it again is a no-op
+	if err := RemoveFinalizer(ctx, k8sClient, result); err != nil {
+		t.Errorf("RemoveFinalizer() on an already finalized object error = %v", err)
+	}
+}
+
+// TestVaultPathDeleted tests the tracking of Vault deletions across finalization attempts.
+func TestVaultPathDeleted(t *testing.T) {
+	ctx := context.Background()
+	deployment := newPatchTestDeployment(map[string]string{VaultPathAnnotation: "secret/data/web"})
+	k8sClient := fake.NewClientBuilder().WithObjects(deployment).Build()
+
+	if isVaultPathDeleted(deployment, "secret/data/web") {
+		t.Fatal("path reported deleted before any deletion")
+	}
+
+	markVaultPathDeleted(ctx, k8sClient, deployment, "secret/data/web", logr.Discard())
+
+	result := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), result); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if !isVaultPathDeleted(result, "secret/data/web") {
+		t.Error("path not reported deleted after the deletion was recorded")
+	}
+	if isVaultPathDeleted(result, "secret/data/other") {
+		t.Error("a different path reported deleted")
+	}
+}
+
+// flakyDeleteVault is a VaultWriterDeleter whose first deletes fail.
+type flakyDeleteVault struct {
+	fakeVault
+	failures int
+}
+
+func (f *flakyDeleteVault) DeleteSecret(ctx context.Context, path string) error {
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("connection refused")
+	}
+	return f.fakeVault.DeleteSecret(ctx, path)
+}
+
+// TestDeletionEvents tests the events recorded for each step of deletion handling.
+func TestDeletionEvents(t *testing.T) {
+	ctx := context.Background()
+	deployment := newPatchTestDeployment(map[string]string{VaultPathAnnotation: "secret/data/web"})
+	deployment.Finalizers = []string{VaultSyncFinalizer}
+	k8sClient := fake.NewClientBuilder().WithObjects(deployment).Build()
+	if err := k8sClient.Delete(ctx, deployment); err != nil {
+		t.Fatalf("failed to delete deployment: %v", err)
+	}
+
+	recorder := events.NewFakeRecorder(10)
+	r := &DeploymentReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(),
+		VaultClient: &flakyDeleteVault{failures: 1}, Recorder: recorder}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	reasons := func() []string {
+		var reasons []string
+		for len(recorder.Events) > 0 {
+			reasons = append(reasons, strings.Fields(<-recorder.Events)[1])
+		}
+		return reasons
+	}
+
+	if _, err := r.Reconcile(ctx, req); err == nil {
+		t.Fatal("expected the failed vault delete to fail the reconcile")
+	}
+	if got, expected := reasons(), []string{"VaultDeleteAttempted", "VaultDeleteFailed"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("events = %v, expected %v", got, expected)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got, expected := reasons(), []string{"VaultDeleteAttempted", "VaultDeleteSucceeded", "FinalizerRemoved"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("events = %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,80 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the synthetic heartbeat writer.
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// HeartbeatPathSuffix is appended to the heartbeat prefix to form the heartbeat path.
+const HeartbeatPathSuffix = "_heartbeat"
+
+// DefaultHeartbeatPrefix is the path prefix the heartbeat is written under.
+const DefaultHeartbeatPrefix = "secret/data/vault-sync-operator"
+
+// HeartbeatPath returns the heartbeat path under prefix.
+func HeartbeatPath(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/" + HeartbeatPathSuffix
+}
+
+// HeartbeatWriter periodically writes a timestamp to a dedicated Vault path, giving a
+// continuous black-box signal that the full write path works even when no synced secret
+// changes. Only the leader writes, so the heartbeat also shows that a replica reconciles.
+type HeartbeatWriter struct {
+	VaultClient VaultWriter
+	// Path is the heartbeat path, including any cluster prefix
+	Path     string
+	Interval time.Duration
+	// Identity names the writing replica in the heartbeat data
+	Identity string
+	Log      logr.Logger
+}
+
+// Start writes a heartbeat every interval until ctx is done. It implements manager.Runnable.
+func (h *HeartbeatWriter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		h.beat(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection restricts the heartbeat to the replica that reconciles.
+func (h *HeartbeatWriter) NeedLeaderElection() bool {
+	return true
+}
+
+// beat writes a single heartbeat and records its outcome.
+func (h *HeartbeatWriter) beat(ctx context.Context, now time.Time) {
+	writeCtx, cancel := context.WithTimeout(ctx, h.Interval)
+	defer cancel()
+
+	data := map[string]interface{}{
+		"timestamp": now.UTC().Format(time.RFC3339),
+		"writer":    h.Identity,
+	}
+	start := time.Now()
+	err := h.VaultClient.WriteSecret(writeCtx, h.Path, data)
+	metrics.HeartbeatDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.HeartbeatWrites.WithLabelValues("error").Inc()
+		h.Log.Error(err, "heartbeat write failed", "path", h.Path)
+		return
+	}
+	metrics.HeartbeatWrites.WithLabelValues("success").Inc()
+	metrics.HeartbeatLastSuccess.Set(float64(now.Unix()))
+	h.Log.V(1).Info("heartbeat written", "path", h.Path)
+}
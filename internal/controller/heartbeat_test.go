@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// failingVault is a VaultWriterDeleter whose writes fail.
+type failingVault struct{ fakeVault }
+
+func (failingVault) WriteSecret(context.Context, string, map[string]interface{}) error {
+	return errors.New("permission denied")
+}
+
+func TestHeartbeatPath(t *testing.T) {
+	for prefix, expected := range map[string]string{
+		"secret/data/vault-sync-operator":  "secret/data/vault-sync-operator/_heartbeat",
+		"secret/data/vault-sync-operator/": "secret/data/vault-sync-operator/_heartbeat",
+	} {
+		if got := HeartbeatPath(prefix); got != expected {
+			t.Errorf("HeartbeatPath(%q) = %q, expected %q", prefix, got, expected)
+		}
+	}
+}
+
+func TestHeartbeatWriter(t *testing.T) {
+	vaultClient := &fakeVault{}
+	h := &HeartbeatWriter{
+		VaultClient: vaultClient,
+		Path:        "secret/data/vault-sync-operator/_heartbeat",
+		Interval:    time.Minute,
+		Identity:    "operator-0",
+		Log:         logr.Discard(),
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	successes := testutil.ToFloat64(metrics.HeartbeatWrites.WithLabelValues("success"))
+	h.beat(context.Background(), now)
+
+	data := vaultClient.secrets[h.Path]
+	if data["timestamp"] != "2026-01-02T03:04:05Z" || data["writer"] != "operator-0" {
+		t.Errorf("heartbeat data = %v", data)
+	}
+	if value := testutil.ToFloat64(metrics.HeartbeatWrites.WithLabelValues("success")); value != successes+1 {
+		t.Errorf("successful heartbeats = %v, expected %v", value, successes+1)
+	}
+	if value := testutil.ToFloat64(metrics.HeartbeatLastSuccess); value != float64(now.Unix()) {
+		t.Errorf("last heartbeat success = %v, expected %v", value, now.Unix())
+	}
+
+	// A failed write does not move the last success
+	errorsBefore := testutil.ToFloat64(metrics.HeartbeatWrites.WithLabelValues("error"))
+	h.VaultClient = failingVault{}
+	h.beat(context.Background(), now.Add(time.Minute))
+	if value := testutil.ToFloat64(metrics.HeartbeatWrites.WithLabelValues("error")); value != errorsBefore+1 {
+		t.Errorf("failed heartbeats = %v, expected %v", value, errorsBefore+1)
+	}
+	if value := testutil.ToFloat64(metrics.HeartbeatLastSuccess); value != float64(now.Unix()) {
+		t.Errorf("last heartbeat success after a failure = %v, expected %v", value, now.Unix())
+	}
+}
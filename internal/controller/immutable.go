@@ -0,0 +1,43 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements rotation detection for immutable Secrets.
+package controller
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImmutableSecretVersionPrefix marks the entries of vault-sync.io/secret-versions recorded for
+// immutable Secrets, whose version is their UID.
+const ImmutableSecretVersionPrefix = "immutable:"
+
+// SecretVersion returns the version of secret recorded for rotation detection. The data of
+// an immutable Secret cannot change, so it is recorded by UID, which only changes when the
+// Secret is deleted and recreated; label or annotation updates no longer trigger a sync.
+func SecretVersion(secret *corev1.Secret) string {
+	if IsSecretImmutable(secret) {
+		return ImmutableSecretVersionPrefix + string(secret.UID)
+	}
+	return secret.ResourceVersion
+}
+
+// IsSecretImmutable reports whether the data of secret is immutable.
+func IsSecretImmutable(secret *corev1.Secret) bool {
+	return secret.Immutable != nil && *secret.Immutable
+}
+
+// OnlyImmutableSecrets reports whether every secret in versions was immutable when it was
+// recorded. Scheduled rotation checks are pointless for such resources, since none of their
+// secrets can change without being recreated, which is seen as a watch event.
+func OnlyImmutableSecrets(versions map[string]string) bool {
+	if len(versions) == 0 {
+		return false
+	}
+	for _, version := range versions {
+		if !strings.HasPrefix(version, ImmutableSecretVersionPrefix) {
+			return false
+		}
+	}
+	return true
+}
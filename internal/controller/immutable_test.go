@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestOnlyImmutableSecrets(t *testing.T) {
+	immutable := &corev1.Secret{Immutable: ptr.To(true)}
+	immutable.UID = "0b5b5c0e"
+	immutable.ResourceVersion = "42"
+	mutable := &corev1.Secret{}
+	mutable.ResourceVersion = "43"
+
+	if version := SecretVersion(immutable); version != "immutable:0b5b5c0e" {
+		t.Errorf("SecretVersion() = %q for an immutable secret, expected its UID", version)
+	}
+	if version := SecretVersion(mutable); version != "43" {
+		t.Errorf("SecretVersion() = %q for a mutable secret, expected its resource version", version)
+	}
+
+	tests := []struct {
+		name     string
+		versions map[string]string
+		expected bool
+	}{
+		{name: "no secrets", versions: nil, expected: false},
+		{name: "immutable only", versions: map[string]string{"tls": SecretVersion(immutable), "ca": "immutable:7f3a"}, expected: true},
+		{name: "mixed", versions: map[string]string{"tls": SecretVersion(immutable), "db": SecretVersion(mutable)}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OnlyImmutableSecrets(tt.versions); got != tt.expected {
+				t.Errorf("OnlyImmutableSecrets() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestImmutableSecretReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"tls.crt": []byte("certificate")}, Immutable: ptr.To(true)}
+	secret.Name = "web-tls"
+	secret.Namespace = "default"
+	secret.UID = "0b5b5c0e"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web", VaultRotationCheckAnnotation: "1h"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+	vaultClient := &fakeVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	reconcileUntilSynced(t, r, req)
+	if got := vaultClient.secrets["secret/data/web/web-tls"]["tls.crt"]; got != "certificate" {
+		t.Fatalf("tls.crt = %v, expected the certificate", got)
+	}
+
+	// Metadata updates change the resource version of the secret, but not its data
+	current := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), current); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	current.Labels = map[string]string{"team": "web"}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+
+	vaultClient.secrets = nil
+	result, err := r.Reconcile(ctx, req)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(vaultClient.secrets) != 0 {
+		t.Errorf("immutable secret written again after a label update: %v", vaultClient.secrets)
+	}
+	if result.RequeueAfter != 0 {
+		t.Errorf("RequeueAfter = %v, expected no scheduled rotation check for immutable secrets", result.RequeueAfter)
+	}
+
+	// A mutable secret keeps its scheduled rotation checks
+	current.Immutable = nil
+	current.UID = ""
+	mutable := current.DeepCopy()
+	mutable.Name = "web-config"
+	mutable.ResourceVersion = ""
+	if err := k8sClient.Create(ctx, mutable); err != nil {
+		t.Fatalf("failed to create secret: %v", err)
+	}
+	synced := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, req.NamespacedName, synced); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	synced.Spec.Template.Spec.Containers[0].EnvFrom = append(synced.Spec.Template.Spec.Containers[0].EnvFrom,
+		corev1.EnvFromSource{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: mutable.Name}}})
+	if err := k8sClient.Update(ctx, synced); err != nil {
+		t.Fatalf("failed to update deployment: %v", err)
+	}
+	if result := reconcileUntilSynced(t, r, req); result.RequeueAfter != time.Hour {
+		t.Errorf("RequeueAfter = %v, expected the 1h rotation check", result.RequeueAfter)
+	}
+}
@@ -0,0 +1,241 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the /inventory export of secret-to-path mappings.
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InventoryRecord maps a Kubernetes Secret synced for a resource to the Vault path it is
+// written to.
+type InventoryRecord struct {
+	// Kind, Namespace and Name identify the annotated resource
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Secret names the synced Secret; it is empty for a workload that has not synced yet
+	Secret    string    `json:"secret,omitempty"`
+	VaultPath string    `json:"vaultPath"`
+	Created   time.Time `json:"created"`
+	// LastSynced is when the versions of the synced Secrets were last recorded, unknown for
+	// resources that have not synced yet and with the vault state backend
+	LastSynced *time.Time `json:"lastSynced,omitempty"`
+}
+
+// Inventory is the export served by InventoryHandler.
+type Inventory struct {
+	Generated time.Time         `json:"generated"`
+	Records   []InventoryRecord `json:"records"`
+}
+
+// inventoryCSVHeader is the header row of the CSV export.
+var inventoryCSVHeader = []string{"kind", "namespace", "name", "secret", "vault_path", "created", "last_synced"}
+
+// InventoryHandler serves the Secret to Vault path mappings of every annotated resource as
+// JSON, or as CSV with ?format=csv, for ingestion by a CMDB. The mappings are read from the
+// operator's informer cache and annotations, so the export neither scrapes the API server nor
+// calls Vault.
+type InventoryHandler struct {
+	// Reader lists the annotated resources (typically the manager's cached client)
+	Reader client.Reader
+	// WorkloadKinds are the Deployment-like kinds synced besides Deployments
+	WorkloadKinds []WorkloadKind
+	// Handles selects the resources synced by a controller by controller kind ("deployment"
+	// or "secret") and namespace. A nil function includes every resource.
+	Handles         func(kind, namespace string) bool
+	ClusterName     string
+	NamespaceMounts NamespaceMounts
+	SharedSecrets   *SharedSecretRegistry
+}
+
+// ServeHTTP implements http.Handler.
+func (h *InventoryHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	inventory, err := h.Inventory(req.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if req.URL.Query().Get("format") == "csv" ||
+		(req.URL.Query().Get("format") == "" && strings.Contains(req.Header.Get("Accept"), "text/csv")) {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		_ = writeInventoryCSV(w, inventory)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(inventory)
+}
+
+// Inventory collects the mappings of every annotated resource, sorted by resource and Secret.
+func (h *InventoryHandler) Inventory(ctx context.Context) (Inventory, error) {
+	inventory := Inventory{Generated: time.Now().UTC(), Records: []InventoryRecord{}}
+
+	deployments := &appsv1.DeploymentList{}
+	if err := h.Reader.List(ctx, deployments); err != nil {
+		return inventory, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		inventory.Records = append(inventory.Records, h.workloadRecords("Deployment", &deployments.Items[i])...)
+	}
+
+	for _, kind := range h.WorkloadKinds {
+		workloads := &unstructured.UnstructuredList{}
+		workloads.SetGroupVersionKind(kind.GroupVersion().WithKind(kind.Kind + "List"))
+		if err := h.Reader.List(ctx, workloads); err != nil {
+			return inventory, fmt.Errorf("failed to list %s: %w", kind.Kind, err)
+		}
+		for i := range workloads.Items {
+			inventory.Records = append(inventory.Records, h.workloadRecords(kind.Kind, &workloads.Items[i])...)
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := h.Reader.List(ctx, secrets); err != nil {
+		return inventory, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !h.includes("secret", secret) {
+			continue
+		}
+		// A Secret is written as one document, also when vault-sync.io/secrets merges others into it
+		names := syncedSecretNames(secret)
+		if len(names) == 0 {
+			names = []string{secret.Name}
+		}
+		for _, name := range names {
+			inventory.Records = append(inventory.Records, newInventoryRecord("Secret", secret, name, h.documentPath(secret)))
+		}
+	}
+
+	sort.SliceStable(inventory.Records, func(i, j int) bool {
+		a, b := inventory.Records[i], inventory.Records[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Secret < b.Secret
+	})
+	return inventory, nil
+}
+
+// workloadRecords returns the mappings of a Deployment or workload. Secrets configured with
+// vault-sync.io/secrets share one document, while auto-discovered Secrets each have a sub-path.
+func (h *InventoryHandler) workloadRecords(kind string, obj client.Object) []InventoryRecord {
+	if !h.includes("deployment", obj) {
+		return nil
+	}
+	path := h.documentPath(obj)
+	names := syncedSecretNames(obj)
+	if len(names) == 0 {
+		return []InventoryRecord{newInventoryRecord(kind, obj, "", path)}
+	}
+
+	records := make([]InventoryRecord, 0, len(names))
+	for _, name := range names {
+		secretPath := path
+		if obj.GetAnnotations()[VaultSecretsAnnotation] == "" {
+			if h.SharedSecrets != nil {
+				secretPath = h.NamespaceMounts.ResolvePath(obj.GetNamespace(), h.SharedSecrets.CanonicalPath(obj.GetNamespace(), name), h.ClusterName, false)
+			} else {
+				secretPath = path + "/" + name
+			}
+		}
+		records = append(records, newInventoryRecord(kind, obj, name, secretPath))
+	}
+	return records
+}
+
+// includes reports whether obj is annotated for sync and handled by a controller of kind.
+func (h *InventoryHandler) includes(kind string, obj client.Object) bool {
+	if obj.GetAnnotations()[VaultPathAnnotation] == "" {
+		return false
+	}
+	return h.Handles == nil || h.Handles(kind, obj.GetNamespace())
+}
+
+// documentPath returns the Vault path obj was last synced to, including the current revision,
+// or the path it will be synced to when it has not synced yet.
+func (h *InventoryHandler) documentPath(obj client.Object) string {
+	path := GetSyncedPath(obj)
+	if path == "" {
+		path = h.NamespaceMounts.ResolvePath(obj.GetNamespace(), obj.GetAnnotations()[VaultPathAnnotation], h.ClusterName, IsAbsolutePath(obj))
+	}
+	if revisions := GetSyncedRevisions(obj); len(revisions) > 0 {
+		path += "/" + revisions[len(revisions)-1]
+	}
+	return path
+}
+
+// syncedSecretNames returns the sorted names of the Secrets recorded in the secret versions of obj.
+func syncedSecretNames(obj client.Object) []string {
+	var versions map[string]string
+	if err := json.Unmarshal([]byte(obj.GetAnnotations()[VaultSecretVersionsAnnotation]), &versions); err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newInventoryRecord returns the mapping of secret to path for obj.
+func newInventoryRecord(kind string, obj client.Object, secret, path string) InventoryRecord {
+	record := InventoryRecord{
+		Kind:      kind,
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Secret:    secret,
+		VaultPath: path,
+		Created:   obj.GetCreationTimestamp().UTC(),
+	}
+	if syncedAt, ok := GetSyncedAt(obj); ok {
+		record.LastSynced = &syncedAt
+	}
+	return record
+}
+
+// writeInventoryCSV writes the records of inventory as CSV with a header row. Timestamps use
+// RFC 3339 and unknown ones are left empty.
+func writeInventoryCSV(w io.Writer, inventory Inventory) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+	for _, record := range inventory.Records {
+		lastSynced := ""
+		if record.LastSynced != nil {
+			lastSynced = record.LastSynced.Format(time.RFC3339)
+		}
+		if err := writer.Write([]string{
+			record.Kind, record.Namespace, record.Name, record.Secret, record.VaultPath,
+			record.Created.Format(time.RFC3339), lastSynced,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestInventoryHandler(t *testing.T) {
+	ctx := context.Background()
+
+	credentials := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	credentials.Name = "web-credentials"
+	credentials.Namespace = "default"
+	web := &appsv1.Deployment{}
+	web.Name = "web"
+	web.Namespace = "default"
+	web.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web"}
+	web.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: credentials.Name}}}},
+	}}
+	api := &appsv1.Deployment{}
+	api.Name = "api"
+	api.Namespace = "default"
+	api.Annotations = map[string]string{
+		VaultPathAnnotation:           "secret/data/api",
+		VaultSecretsAnnotation:        `[{"name":"api-db"},{"name":"api-tls"}]`,
+		VaultSecretVersionsAnnotation: `{"api-db":"1","api-tls":"2"}`,
+		VaultSyncedAtAnnotation:       "2026-01-02T03:04:05Z",
+	}
+	db := &corev1.Secret{}
+	db.Name = "db"
+	db.Namespace = "payments"
+	db.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+	unmanaged := &appsv1.Deployment{}
+	unmanaged.Name = "batch"
+	unmanaged.Namespace = "default"
+
+	k8sClient := fake.NewClientBuilder().WithObjects(credentials, web, api, db, unmanaged).Build()
+	r := &DeploymentReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(), VaultClient: &fakeVault{}, ClusterName: "prod"}
+	reconcileUntilSynced(t, r, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(web)})
+
+	handler := &InventoryHandler{Reader: k8sClient, ClusterName: "prod"}
+	inventory, err := handler.Inventory(ctx)
+	if err != nil {
+		t.Fatalf("Inventory() error = %v", err)
+	}
+
+	var mappings [][]string
+	for _, record := range inventory.Records {
+		mappings = append(mappings, []string{record.Kind, record.Namespace, record.Name, record.Secret, record.VaultPath})
+	}
+	expected := [][]string{
+		{"Deployment", "default", "api", "api-db", "clusters/prod/secret/data/api"},
+		{"Deployment", "default", "api", "api-tls", "clusters/prod/secret/data/api"},
+		{"Deployment", "default", "web", "web-credentials", "clusters/prod/secret/data/web/web-credentials"},
+		{"Secret", "payments", "db", "db", "clusters/prod/secret/data/db"},
+	}
+	if !reflect.DeepEqual(mappings, expected) {
+		t.Errorf("mappings = %v, expected %v", mappings, expected)
+	}
+	if synced := inventory.Records[2].LastSynced; synced == nil {
+		t.Errorf("expected the synced deployment to have a last synced time")
+	}
+	if inventory.Records[3].LastSynced != nil {
+		t.Errorf("expected no last synced time for a secret that has not synced")
+	}
+
+	// Resources of namespaces without a controller are left out
+	handler.Handles = func(_, namespace string) bool { return namespace == "default" }
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/inventory?format=csv", nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q, expected CSV", contentType)
+	}
+	rows, err := csv.NewReader(recorder.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(rows) != 4 || !reflect.DeepEqual(rows[0], inventoryCSVHeader) {
+		t.Fatalf("rows = %v, expected a header and three mappings", rows)
+	}
+	if last := rows[1][len(rows[1])-1]; last != "2026-01-02T03:04:05Z" {
+		t.Errorf("last_synced = %q, expected the recorded time", last)
+	}
+}
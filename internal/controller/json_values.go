@@ -0,0 +1,45 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the parsing of JSON-valued secret keys into structured data.
+package controller
+
+import (
+	"encoding/json"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultParseJSONValuesAnnotation stores values holding a JSON object or array as structured
+// data in Vault instead of a string ("true"), so consumers can address nested fields.
+const VaultParseJSONValuesAnnotation = "vault-sync.io/parse-json-values"
+
+// ParsesJSONValues reports whether obj stores JSON object and array values as structured data.
+func ParsesJSONValues(obj client.Object) bool {
+	return strings.EqualFold(strings.TrimSpace(obj.GetAnnotations()[VaultParseJSONValuesAnnotation]), "true")
+}
+
+// parseJSONValues returns data with every string value holding a JSON object or array
+// replaced by the parsed value. Scalars such as "123" or "true" stay strings, since their
+// type cannot be told from the value alone, and so do values that are not valid JSON.
+// Numbers in parsed values keep their exact text.
+func parseJSONValues(data map[string]interface{}) map[string]interface{} {
+	parsed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		parsed[key] = value
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		trimmed := strings.TrimSpace(text)
+		if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") || !json.Valid([]byte(trimmed)) {
+			continue
+		}
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var structured interface{}
+		if err := decoder.Decode(&structured); err == nil {
+			parsed[key] = structured
+		}
+	}
+	return parsed
+}
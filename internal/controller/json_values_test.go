@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestParseJSONValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected interface{}
+	}{
+		{
+			name:     "object",
+			value:    `{"host": "db", "port": 5432}`,
+			expected: map[string]interface{}{"host": "db", "port": json.Number("5432")},
+		},
+		{
+			name:     "array with surrounding whitespace",
+			value:    " [\"a\", \"b\"]\n",
+			expected: []interface{}{"a", "b"},
+		},
+		{name: "number stays a string", value: "123", expected: "123"},
+		{name: "boolean stays a string", value: "true", expected: "true"},
+		{name: "quoted string stays as is", value: `"quoted"`, expected: `"quoted"`},
+		{name: "invalid json", value: "{not json}", expected: "{not json}"},
+		{name: "trailing data", value: `{"a": 1} {"b": 2}`, expected: `{"a": 1} {"b": 2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := parseJSONValues(map[string]interface{}{"key": tt.value})
+			if !reflect.DeepEqual(parsed["key"], tt.expected) {
+				t.Errorf("parseJSONValues() = %#v, expected %#v", parsed["key"], tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseJSONValuesReconcile tests that annotated Secrets are written with structured values.
+func TestParseJSONValuesReconcile(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"config":   []byte(`{"database": {"host": "db", "port": 5432}}`),
+		"password": []byte("s3cret"),
+	}}
+	secret.Name = "app"
+	secret.Namespace = "default"
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/app", VaultParseJSONValuesAnnotation: "true"}
+
+	vaultClient := &fakeVault{}
+	r := &SecretReconciler{
+		Client:      fake.NewClientBuilder().WithObjects(secret).Build(),
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	reconcileUntilSynced(t, r, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+
+	data := vaultClient.secrets["secret/data/app"]
+	config, ok := data["config"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("config = %#v, expected structured data", data["config"])
+	}
+	if database, _ := config["database"].(map[string]interface{}); database["host"] != "db" {
+		t.Errorf("config = %v, expected the nested database host", config)
+	}
+	if data["password"] != "s3cret" {
+		t.Errorf("password = %v, expected the plain string", data["password"])
+	}
+}
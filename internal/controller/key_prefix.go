@@ -0,0 +1,60 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the flat layout of auto-discovered secrets with prefixed keys.
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultKeyPrefixAnnotation writes the keys of all auto-discovered secrets into a single
+// document at the resource's path, each key prefixed with the annotation value, instead of
+// one sub-path per secret. The placeholder KeyPrefixSecretPlaceholder is replaced with the
+// name of the secret holding the key, e.g. "{secret}_" turns key password of Secret db into
+// db_password.
+const VaultKeyPrefixAnnotation = "vault-sync.io/key-prefix"
+
+// KeyPrefixSecretPlaceholder is replaced with the secret name in vault-sync.io/key-prefix.
+const KeyPrefixSecretPlaceholder = "{secret}"
+
+// GetKeyPrefix returns the key prefix template of obj, and whether its auto-discovered
+// secrets are written in the flat layout.
+func GetKeyPrefix(obj client.Object) (string, bool) {
+	prefix, ok := obj.GetAnnotations()[VaultKeyPrefixAnnotation]
+	return prefix, ok
+}
+
+// flattenDiscoveredSecrets merges the keys of the auto-discovered secrets into a single
+// document, each key prefixed with the prefix template expanded for its secret. Keys that
+// end up with the same name, as when the template has no placeholder, are an error rather
+// than one secret silently overwriting another.
+func flattenDiscoveredSecrets(secrets map[string]*corev1.Secret, prefix string, includeKeys map[string]bool) (map[string]interface{}, error) {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	data := make(map[string]interface{})
+	sources := make(map[string]string)
+	for _, name := range names {
+		secretPrefix := strings.ReplaceAll(prefix, KeyPrefixSecretPlaceholder, name)
+		for key, value := range secrets[name].Data {
+			if includeKeys != nil && !includeKeys[key] {
+				continue
+			}
+			vaultKey := secretPrefix + key
+			if source, exists := sources[vaultKey]; exists {
+				return nil, fmt.Errorf("keys of secrets %s and %s both map to %s with %s %q",
+					source, name, vaultKey, VaultKeyPrefixAnnotation, prefix)
+			}
+			sources[vaultKey] = name
+			data[vaultKey] = string(value)
+		}
+	}
+	return data, nil
+}
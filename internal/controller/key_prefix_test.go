@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestFlattenDiscoveredSecrets(t *testing.T) {
+	db := &corev1.Secret{Data: map[string][]byte{"username": []byte("app"), "password": []byte("s3cret")}}
+	cache := &corev1.Secret{Data: map[string][]byte{"password": []byte("hunter2")}}
+	secrets := map[string]*corev1.Secret{"db": db, "cache": cache}
+
+	tests := []struct {
+		name        string
+		prefix      string
+		includeKeys map[string]bool
+		expected    map[string]interface{}
+		wantErr     bool
+	}{
+		{
+			name:     "secret name placeholder",
+			prefix:   "{secret}_",
+			expected: map[string]interface{}{"db_username": "app", "db_password": "s3cret", "cache_password": "hunter2"},
+		},
+		{
+			name:        "included keys only",
+			prefix:      "{secret}.",
+			includeKeys: map[string]bool{"password": true},
+			expected:    map[string]interface{}{"db.password": "s3cret", "cache.password": "hunter2"},
+		},
+		{
+			name:    "colliding keys",
+			prefix:  "app_",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := flattenDiscoveredSecrets(secrets, tt.prefix, tt.includeKeys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("flattenDiscoveredSecrets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(data) != len(tt.expected) {
+				t.Fatalf("flattenDiscoveredSecrets() = %v, expected %v", data, tt.expected)
+			}
+			for key, value := range tt.expected {
+				if data[key] != value {
+					t.Errorf("flattenDiscoveredSecrets()[%s] = %v, expected %v", key, data[key], value)
+				}
+			}
+		})
+	}
+}
+
+// TestKeyPrefixReconcile tests that a key prefix writes all auto-discovered secrets to the
+// document at the Deployment's path instead of sub-paths.
+func TestKeyPrefixReconcile(t *testing.T) {
+	db := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	db.Name = "db"
+	db.Namespace = "default"
+	cache := &corev1.Secret{Data: map[string][]byte{"password": []byte("hunter2")}}
+	cache.Name = "cache"
+	cache.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web", VaultKeyPrefixAnnotation: "{secret}_"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		EnvFrom: []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: db.Name}}},
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: cache.Name}}},
+		},
+	}}
+
+	vaultClient := &fakeVault{}
+	r := &DeploymentReconciler{
+		Client:      fake.NewClientBuilder().WithObjects(db, cache, deployment).Build(),
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	reconcileUntilSynced(t, r, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)})
+
+	data := vaultClient.secrets["secret/data/web"]
+	if data["db_password"] != "s3cret" || data["cache_password"] != "hunter2" {
+		t.Errorf("data in vault = %v, expected the prefixed keys of both secrets", data)
+	}
+	if _, ok := vaultClient.secrets["secret/data/web/db"]; ok {
+		t.Errorf("expected no sub-path to be written in the flat layout")
+	}
+}
@@ -0,0 +1,89 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the retention of keys removed from synced Secrets.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultRetainDeletedKeysAnnotation keeps keys in Vault after they were removed from the synced
+// Secrets ("true").
+const VaultRetainDeletedKeysAnnotation = "vault-sync.io/retain-deleted-keys"
+
+// RetainsDeletedKeys reports whether obj keeps keys in Vault that were removed from its Secrets.
+func RetainsDeletedKeys(obj client.Object) bool {
+	return strings.EqualFold(strings.TrimSpace(obj.GetAnnotations()[VaultRetainDeletedKeysAnnotation]), "true")
+}
+
+// retainDeletedKeys returns the data to write to path for obj. Every write replaces the whole
+// document, so keys removed from a Secret disappear from Vault with the next write. Resources
+// annotated with vault-sync.io/retain-deleted-keys instead keep the keys found at path that
+// are missing from data, which requires a client that can read from Vault. Keys are compared
+// without CompressedKeySuffix, so a value that is now stored compressed, or no longer is,
+// does not keep its previous form next to the new one; retained values are stored compressed
+// or not as the resource's data is.
+func retainDeletedKeys(ctx context.Context, vaultClient VaultWriterDeleter, obj client.Object, path string, data map[string]interface{}, log logr.Logger) (map[string]interface{}, error) {
+	if !RetainsDeletedKeys(obj) {
+		return data, nil
+	}
+	reader, ok := vaultClient.(VaultReader)
+	if !ok {
+		return nil, fmt.Errorf("%s requires a vault client that can read secrets", VaultRetainDeletedKeysAnnotation)
+	}
+
+	synced := make(map[string]bool, len(data))
+	for key := range data {
+		synced[uncompressedKey(key)] = true
+	}
+
+	// The values are only read when the keys show there is something to retain
+	keys, err := vaultSecretKeys(ctx, vaultClient, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys of %s to retain deleted keys: %w", path, err)
+	}
+	if len(keys) > 0 && !slices.ContainsFunc(keys, func(key string) bool { return !synced[uncompressedKey(key)] }) {
+		return data, nil
+	}
+
+	stored, err := reader.ReadSecret(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s to retain deleted keys: %w", path, err)
+	}
+	current, err := DecompressValues(stored)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s to retain deleted keys: %w", path, err)
+	}
+
+	var retained []string
+	retainedData := make(map[string]interface{})
+	for key, value := range current {
+		if !synced[key] {
+			retained = append(retained, key)
+			retainedData[key] = value
+		}
+	}
+	if len(retained) == 0 {
+		return data, nil
+	}
+	if CompressesValues(obj) {
+		if retainedData, err = compressValues(retainedData); err != nil {
+			return nil, err
+		}
+	}
+	merged := make(map[string]interface{}, len(data)+len(retainedData))
+	for key, value := range retainedData {
+		merged[key] = value
+	}
+	for key, value := range data {
+		merged[key] = value
+	}
+	slices.Sort(retained)
+	log.Info("retaining keys no longer present in kubernetes", "path", path, "keys", retained)
+	return merged, nil
+}
@@ -0,0 +1,369 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// removeSecretKey deletes dataKey from the data of the Secret at key.
+func removeSecretKey(t *testing.T, k8sClient client.Client, key client.ObjectKey, dataKey string) {
+	t.Helper()
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(context.Background(), key, secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	delete(secret.Data, dataKey)
+	if err := k8sClient.Update(context.Background(), secret); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+}
+
+// TestSecretKeyDeletion tests that keys removed from a Secret are removed from its Vault
+// document, unless they are retained.
+func TestSecretKeyDeletion(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		ctx := context.Background()
+
+		secret := &corev1.Secret{Data: map[string][]byte{"username": []byte("app"), "password": []byte("s3cret")}}
+		secret.Name = "db"
+		secret.Namespace = "default"
+		secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+		if retain {
+			secret.Annotations[VaultRetainDeletedKeysAnnotation] = "true"
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+		vaultClient := &readableVault{}
+		r := &SecretReconciler{
+			Client:      k8sClient,
+			Scheme:      runtime.NewScheme(),
+			Log:         logr.Discard(),
+			VaultClient: vaultClient,
+		}
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+
+		reconcileUntilSynced(t, r, req)
+		removeSecretKey(t, k8sClient, req.NamespacedName, "password")
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		data := vaultClient.secrets["secret/data/db"]
+		if _, ok := data["password"]; ok != retain {
+			t.Errorf("retain=%v: data in vault = %v, expected password present = %v", retain, data, retain)
+		}
+		if data["username"] != "app" {
+			t.Errorf("retain=%v: data in vault = %v, expected username to be kept", retain, data)
+		}
+	}
+}
+
+// TestDeploymentKeyDeletion tests that keys removed from an auto-discovered Secret are removed
+// from its sub-path, and that a sub-path left without included keys is deleted.
+func TestDeploymentKeyDeletion(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"username": []byte("app"), "password": []byte("s3cret")}}
+	secret.Name = "web-credentials"
+	secret.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+	const subPath = "secret/data/web/web-credentials"
+
+	reconcileUntilSynced(t, r, req)
+	removeSecretKey(t, k8sClient, client.ObjectKeyFromObject(secret), "password")
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if data := vaultClient.secrets[subPath]; len(data) != 1 || data["username"] != "app" {
+		t.Errorf("data in vault = %v, expected only username", data)
+	}
+
+	// Excluding every remaining key deletes the sub-path
+	if err := k8sClient.Get(ctx, req.NamespacedName, deployment); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	deployment.Annotations[VaultIncludeKeysAnnotation] = "password"
+	if err := k8sClient.Update(ctx, deployment); err != nil {
+		t.Fatalf("failed to update deployment: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if _, ok := vaultClient.secrets[subPath]; ok || !slices.Contains(vaultClient.deletes, subPath) {
+		t.Errorf("deletes = %v, expected the sub-path without included keys to be deleted", vaultClient.deletes)
+	}
+}
+
+// TestDeploymentCustomConfigKeyDeletion tests that keys removed from the vault-sync.io/secrets
+// configuration of a Deployment, which are written with their prefix to a single document,
+// are removed from Vault, unless they are retained.
+func TestDeploymentCustomConfigKeyDeletion(t *testing.T) {
+	for _, retain := range []bool{false, true} {
+		ctx := context.Background()
+
+		secret := &corev1.Secret{Data: map[string][]byte{"username": []byte("app"), "password": []byte("s3cret")}}
+		secret.Name = "db"
+		secret.Namespace = "default"
+		deployment := &appsv1.Deployment{}
+		deployment.Name = "web"
+		deployment.Namespace = "default"
+		deployment.Annotations = map[string]string{
+			VaultPathAnnotation:    "secret/data/web",
+			VaultSecretsAnnotation: `[{"name":"db","keys":["username","password"],"prefix":"db_"}]`,
+		}
+		if retain {
+			deployment.Annotations[VaultRetainDeletedKeysAnnotation] = "true"
+		}
+
+		k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+		vaultClient := &readableVault{}
+		r := &DeploymentReconciler{
+			Client:      k8sClient,
+			Scheme:      runtime.NewScheme(),
+			Log:         logr.Discard(),
+			VaultClient: vaultClient,
+		}
+		req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+		reconcileUntilSynced(t, r, req)
+		if err := k8sClient.Get(ctx, req.NamespacedName, deployment); err != nil {
+			t.Fatalf("failed to get deployment: %v", err)
+		}
+		deployment.Annotations[VaultSecretsAnnotation] = `[{"name":"db","keys":["username"],"prefix":"db_"}]`
+		if err := k8sClient.Update(ctx, deployment); err != nil {
+			t.Fatalf("failed to update deployment: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+
+		data := vaultClient.secrets["secret/data/web"]
+		if _, ok := data["db_password"]; ok != retain {
+			t.Errorf("retain=%v: data in vault = %v, expected db_password present = %v", retain, data, retain)
+		}
+		if data["db_username"] != "app" {
+			t.Errorf("retain=%v: data in vault = %v, expected db_username to be kept", retain, data)
+		}
+	}
+}
+
+// TestDeploymentRetainDeletedKeys tests that keys removed from an auto-discovered Secret are
+// kept in its sub-path when the Deployment retains deleted keys.
+func TestDeploymentRetainDeletedKeys(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"username": []byte("app"), "password": []byte("s3cret")}}
+	secret.Name = "web-credentials"
+	secret.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{
+		VaultPathAnnotation:              "secret/data/web",
+		VaultRetainDeletedKeysAnnotation: "true",
+	}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	reconcileUntilSynced(t, r, req)
+	removeSecretKey(t, k8sClient, client.ObjectKeyFromObject(secret), "password")
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if data := vaultClient.secrets["secret/data/web/web-credentials"]; data["password"] != "s3cret" || data["username"] != "app" {
+		t.Errorf("data in vault = %v, expected the removed password to be retained", data)
+	}
+}
+
+// TestRetainDeletedKeysRequiresReader tests that retaining keys fails without a client that
+// can read from Vault, and that resources not retaining keys need none.
+func TestRetainDeletedKeysRequiresReader(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Annotations = map[string]string{VaultRetainDeletedKeysAnnotation: "true"}
+	if _, err := retainDeletedKeys(context.Background(), &fakeVault{}, secret, "secret/data/db", nil, logr.Discard()); err == nil {
+		t.Errorf("expected an error for a client that cannot read")
+	}
+
+	secret.Annotations = nil
+	data := map[string]interface{}{"password": "s3cret"}
+	if got, err := retainDeletedKeys(context.Background(), &fakeVault{}, secret, "secret/data/db", data, logr.Discard()); err != nil || len(got) != 1 {
+		t.Errorf("retainDeletedKeys() = %v, %v, expected the data unchanged", got, err)
+	}
+}
+
+// subkeysVault is a readableVault that also serves the keys of its secrets and counts full reads.
+type subkeysVault struct {
+	readableVault
+	reads int
+}
+
+func (f *subkeysVault) ReadSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	f.reads++
+	return f.readableVault.ReadSecret(ctx, path)
+}
+
+func (f *subkeysVault) SecretSubkeys(_ context.Context, path string) ([]string, error) {
+	return slices.Sorted(maps.Keys(f.secrets[path])), nil
+}
+
+// TestRetainDeletedKeysSubkeys tests that the keys served by the subkeys endpoint spare reading
+// the values when there is nothing to retain or verify.
+func TestRetainDeletedKeysSubkeys(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Annotations = map[string]string{VaultRetainDeletedKeysAnnotation: "true"}
+	vaultClient := &subkeysVault{}
+	vaultClient.secrets = map[string]map[string]interface{}{"secret/data/db": {"password": "old"}}
+
+	// Nothing to retain: the values are not read
+	data, err := retainDeletedKeys(context.Background(), vaultClient, secret, "secret/data/db", map[string]interface{}{"password": "new"}, logr.Discard())
+	if err != nil || data["password"] != "new" || vaultClient.reads != 0 {
+		t.Errorf("retainDeletedKeys() = %v, %v with %d reads, expected the new data without reading", data, err, vaultClient.reads)
+	}
+
+	// A key missing from the data is read and retained
+	data, err = retainDeletedKeys(context.Background(), vaultClient, secret, "secret/data/db", map[string]interface{}{"username": "app"}, logr.Discard())
+	if err != nil || data["password"] != "old" || data["username"] != "app" || vaultClient.reads != 1 {
+		t.Errorf("retainDeletedKeys() = %v, %v with %d reads, expected the retained password", data, err, vaultClient.reads)
+	}
+
+	// Written paths are verified from their keys
+	if err := verifyVaultPath(context.Background(), vaultClient, "secret/data/db"); err != nil || vaultClient.reads != 1 {
+		t.Errorf("verifyVaultPath() = %v with %d reads, expected verification without reading", err, vaultClient.reads)
+	}
+	if err := verifyVaultPath(context.Background(), vaultClient, "secret/data/missing"); err == nil {
+		t.Errorf("expected verification of a missing path to fail")
+	}
+}
+
+// TestRetainDeletedKeysCompressed tests that a value whose stored form changed between
+// compressed and uncompressed is not retained in its previous form, and that retained values
+// follow the compression of the resource.
+func TestRetainDeletedKeysCompressed(t *testing.T) {
+	large := strings.Repeat("setting: value\n", 200)
+	compressed, err := gzipBase64(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		compress bool
+		stored   map[string]interface{}
+		data     map[string]interface{}
+		expected []string
+	}{
+		{
+			name:     "value now compressed",
+			compress: true,
+			stored:   map[string]interface{}{"config": "small"},
+			data:     map[string]interface{}{"config" + CompressedKeySuffix: compressed},
+			expected: []string{"config" + CompressedKeySuffix},
+		},
+		{
+			name:     "value no longer compressed",
+			stored:   map[string]interface{}{"config" + CompressedKeySuffix: compressed},
+			data:     map[string]interface{}{"config": "small"},
+			expected: []string{"config"},
+		},
+		{
+			name:     "retained value compressed",
+			compress: true,
+			stored:   map[string]interface{}{"config": large, "password": "old"},
+			data:     map[string]interface{}{"password": "new"},
+			expected: []string{"config" + CompressedKeySuffix, "password"},
+		},
+		{
+			name:     "retained value decompressed",
+			stored:   map[string]interface{}{"config" + CompressedKeySuffix: compressed, "password": "old"},
+			data:     map[string]interface{}{"password": "new"},
+			expected: []string{"config", "password"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{}
+			secret.Annotations = map[string]string{VaultRetainDeletedKeysAnnotation: "true"}
+			if tt.compress {
+				secret.Annotations[VaultCompressValuesAnnotation] = "true"
+			}
+			vaultClient := &subkeysVault{}
+			vaultClient.secrets = map[string]map[string]interface{}{"secret/data/app": tt.stored}
+
+			data, err := retainDeletedKeys(context.Background(), vaultClient, secret, "secret/data/app", tt.data, logr.Discard())
+			if err != nil {
+				t.Fatalf("retainDeletedKeys() error = %v", err)
+			}
+			if keys := slices.Sorted(maps.Keys(data)); !slices.Equal(keys, tt.expected) {
+				t.Errorf("retainDeletedKeys() keys = %v, expected %v", keys, tt.expected)
+			}
+			decompressed, err := DecompressValues(data)
+			if err != nil {
+				t.Fatalf("DecompressValues() error = %v", err)
+			}
+			if value, ok := decompressed["config"]; ok && value != large && value != "small" {
+				t.Errorf("config = %q, expected the synced or retained value", value)
+			}
+		})
+	}
+}
+
+// TestVerifyVaultPathCompressed tests that documents with compressed values are read back and
+// fail verification when a value does not decompress.
+func TestVerifyVaultPathCompressed(t *testing.T) {
+	compressed, err := gzipBase64(strings.Repeat("setting: value\n", 200))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vaultClient := &subkeysVault{}
+	vaultClient.secrets = map[string]map[string]interface{}{
+		"secret/data/valid":   {"config" + CompressedKeySuffix: compressed},
+		"secret/data/corrupt": {"config" + CompressedKeySuffix: "not base64!"},
+	}
+
+	if err := verifyVaultPath(context.Background(), vaultClient, "secret/data/valid"); err != nil || vaultClient.reads != 1 {
+		t.Errorf("verifyVaultPath() = %v with %d reads, expected the compressed values to be read back", err, vaultClient.reads)
+	}
+	if err := verifyVaultPath(context.Background(), vaultClient, "secret/data/corrupt"); err == nil {
+		t.Errorf("expected verification of a value that does not decompress to fail")
+	}
+}
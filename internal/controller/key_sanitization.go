@@ -0,0 +1,108 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the key sanitization policy applied to Vault key names.
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultKeySanitizationAnnotation selects how Secret key names are rewritten before they are written to Vault.
+const VaultKeySanitizationAnnotation = "vault-sync.io/key-sanitization"
+
+// Key sanitization options, combined as a comma-separated list in the annotation.
+const (
+	// KeySanitizeReplaceDots replaces "." with "_" (e.g. tls.crt -> tls_crt)
+	KeySanitizeReplaceDots = "replace-dots"
+	// KeySanitizeReplaceSlashes replaces "/" with "_"
+	KeySanitizeReplaceSlashes = "replace-slashes"
+	// KeySanitizeLowercase converts key names to lower case
+	KeySanitizeLowercase = "lowercase"
+	// KeySanitizeUppercase converts key names to upper case
+	KeySanitizeUppercase = "uppercase"
+)
+
+// KeySanitizationPolicy describes how key names are rewritten. The zero value keeps keys unchanged.
+type KeySanitizationPolicy struct {
+	ReplaceDots    bool
+	ReplaceSlashes bool
+	Lowercase      bool
+	Uppercase      bool
+}
+
+// ParseKeySanitizationPolicy parses a comma-separated list of sanitization options.
+func ParseKeySanitizationPolicy(value string) (KeySanitizationPolicy, error) {
+	var policy KeySanitizationPolicy
+	for _, option := range strings.Split(value, ",") {
+		switch strings.TrimSpace(option) {
+		case "":
+		case KeySanitizeReplaceDots:
+			policy.ReplaceDots = true
+		case KeySanitizeReplaceSlashes:
+			policy.ReplaceSlashes = true
+		case KeySanitizeLowercase:
+			policy.Lowercase = true
+		case KeySanitizeUppercase:
+			policy.Uppercase = true
+		default:
+			return KeySanitizationPolicy{}, fmt.Errorf("unknown key sanitization option %q", strings.TrimSpace(option))
+		}
+	}
+
+	if policy.Lowercase && policy.Uppercase {
+		return KeySanitizationPolicy{}, fmt.Errorf("key sanitization options %s and %s are mutually exclusive",
+			KeySanitizeLowercase, KeySanitizeUppercase)
+	}
+	return policy, nil
+}
+
+// GetKeySanitizationPolicy reads the key sanitization policy from the vault-sync.io/key-sanitization annotation.
+func GetKeySanitizationPolicy(obj client.Object) (KeySanitizationPolicy, error) {
+	return ParseKeySanitizationPolicy(obj.GetAnnotations()[VaultKeySanitizationAnnotation])
+}
+
+// SanitizeKey rewrites a single key name according to the policy.
+func (p KeySanitizationPolicy) SanitizeKey(key string) string {
+	if p.ReplaceDots {
+		key = strings.ReplaceAll(key, ".", "_")
+	}
+	if p.ReplaceSlashes {
+		key = strings.ReplaceAll(key, "/", "_")
+	}
+	if p.Lowercase {
+		key = strings.ToLower(key)
+	}
+	if p.Uppercase {
+		key = strings.ToUpper(key)
+	}
+	return key
+}
+
+// SanitizeVaultData returns a copy of data with every key sanitized. It fails when two keys
+// map to the same sanitized name, since one value would silently overwrite the other.
+func (p KeySanitizationPolicy) SanitizeVaultData(data map[string]interface{}) (map[string]interface{}, error) {
+	if p == (KeySanitizationPolicy{}) {
+		return data, nil
+	}
+
+	sanitized := make(map[string]interface{}, len(data))
+	sources := make(map[string]string, len(data))
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		newKey := p.SanitizeKey(key)
+		if existing, exists := sources[newKey]; exists {
+			return nil, fmt.Errorf("keys %s and %s both sanitize to %s", existing, key, newKey)
+		}
+		sources[newKey] = key
+		sanitized[newKey] = data[key]
+	}
+	return sanitized, nil
+}
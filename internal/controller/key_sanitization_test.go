@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseKeySanitizationPolicy tests parsing of the key sanitization annotation.
+func TestParseKeySanitizationPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected KeySanitizationPolicy
+		wantErr  bool
+	}{
+		{name: "empty", value: "", expected: KeySanitizationPolicy{}},
+		{name: "replace dots", value: "replace-dots", expected: KeySanitizationPolicy{ReplaceDots: true}},
+		{
+			name:     "combined",
+			value:    "replace-dots, replace-slashes,lowercase",
+			expected: KeySanitizationPolicy{ReplaceDots: true, ReplaceSlashes: true, Lowercase: true},
+		},
+		{name: "unknown option", value: "replace-dashes", wantErr: true},
+		{name: "conflicting case", value: "lowercase,uppercase", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseKeySanitizationPolicy(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeySanitizationPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if policy != tt.expected {
+				t.Errorf("ParseKeySanitizationPolicy() = %+v, expected %+v", policy, tt.expected)
+			}
+		})
+	}
+}
+
+// TestKeySanitizationPolicySanitizeVaultData tests key rewriting and collision detection.
+func TestKeySanitizationPolicySanitizeVaultData(t *testing.T) {
+	policy := KeySanitizationPolicy{ReplaceDots: true, Uppercase: true}
+
+	data, err := policy.SanitizeVaultData(map[string]interface{}{
+		"tls.crt":           "cert",
+		".dockerconfigjson": "config",
+	})
+	if err != nil {
+		t.Fatalf("SanitizeVaultData() error = %v", err)
+	}
+	expected := map[string]interface{}{
+		"TLS_CRT":           "cert",
+		"_DOCKERCONFIGJSON": "config",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("SanitizeVaultData() = %v, expected %v", data, expected)
+	}
+
+	if _, err := policy.SanitizeVaultData(map[string]interface{}{"tls.crt": "a", "tls_crt": "b"}); err == nil {
+		t.Errorf("Expected an error when two keys sanitize to the same name")
+	}
+}
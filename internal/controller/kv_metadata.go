@@ -0,0 +1,126 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the KV v2 metadata annotations.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// KV v2 metadata annotations, applied to every Vault path written for the resource.
+const (
+	VaultMaxVersionsAnnotation        = "vault-sync.io/max-versions"         // Versions kept by Vault (0 uses the mount setting)
+	VaultDeleteVersionAfterAnnotation = "vault-sync.io/delete-version-after" // Age after which versions are deleted (0s keeps them)
+	VaultRefreshHintAnnotation        = "vault-sync.io/refresh-hint"         // Advisory cache duration for consumers of the paths
+)
+
+// RefreshHintMetadataKey is the custom_metadata entry holding the vault-sync.io/refresh-hint
+// duration, for Vault Agent templates and other consumers choosing how long to cache a path.
+const RefreshHintMetadataKey = "refresh_hint"
+
+// Limits Vault puts on custom_metadata. Entries beyond them are left out rather than failing the write.
+const (
+	maxCustomMetadataEntries     = 64
+	maxCustomMetadataKeyLength   = 128
+	maxCustomMetadataValueLength = 512
+)
+
+// GetKVMetadata returns the KV v2 metadata settings declared on an object, including the
+// custom_metadata entry of a refresh hint.
+func GetKVMetadata(obj client.Object) (vault.KVMetadata, error) {
+	var md vault.KVMetadata
+	annotations := obj.GetAnnotations()
+
+	if value, ok := annotations[VaultMaxVersionsAnnotation]; ok {
+		maxVersions, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || maxVersions < 0 {
+			return md, fmt.Errorf("invalid %s annotation %q: expected a non-negative number", VaultMaxVersionsAnnotation, value)
+		}
+		md.MaxVersions = &maxVersions
+	}
+
+	if value, ok := annotations[VaultDeleteVersionAfterAnnotation]; ok {
+		after, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || after < 0 {
+			return md, fmt.Errorf("invalid %s annotation %q: expected a non-negative duration", VaultDeleteVersionAfterAnnotation, value)
+		}
+		md.DeleteVersionAfter = &after
+	}
+
+	if value, ok := annotations[VaultRefreshHintAnnotation]; ok {
+		value = strings.TrimSpace(value)
+		hint, err := time.ParseDuration(value)
+		if err != nil || hint <= 0 {
+			return md, fmt.Errorf("invalid %s annotation %q: expected a positive duration", VaultRefreshHintAnnotation, value)
+		}
+		// The value is recorded as written, e.g. 24h rather than 24h0m0s
+		md.CustomMetadata = map[string]string{RefreshHintMetadataKey: value}
+	}
+
+	return md, nil
+}
+
+// WorkloadCustomMetadata returns the labels and annotations of a workload whose keys are in
+// the allowlist, for the custom_metadata of its Vault paths. Annotations take precedence over
+// labels, and those of the workload over those of its pod template.
+func WorkloadCustomMetadata(obj client.Object, template corev1.PodTemplateSpec, keys []string) map[string]string {
+	sources := []map[string]string{obj.GetAnnotations(), obj.GetLabels(), template.Annotations, template.Labels}
+
+	custom := make(map[string]string)
+	for _, key := range keys {
+		if len(custom) == maxCustomMetadataEntries || len(key) > maxCustomMetadataKeyLength {
+			continue
+		}
+		for _, source := range sources {
+			if value, ok := source[key]; ok {
+				if value != "" && len(value) <= maxCustomMetadataValueLength {
+					custom[key] = value
+				}
+				break
+			}
+		}
+	}
+	return custom
+}
+
+// applyKVMetadata applies the KV v2 metadata settings to the Vault paths of a resource,
+// recording an event for each path whose metadata changed.
+func applyKVMetadata(ctx context.Context, vc VaultWriterDeleter, recorder events.EventRecorder, obj client.Object, md vault.KVMetadata, paths []string, log logr.Logger) error {
+	if md.IsEmpty() {
+		return nil
+	}
+	writer, ok := vc.(metadataWriter)
+	if !ok {
+		return fmt.Errorf("vault client does not support kv metadata")
+	}
+
+	for _, path := range paths {
+		updated, err := writer.EnsureSecretMetadata(ctx, path, md)
+		if err != nil {
+			recordEvent(recorder, obj, corev1.EventTypeWarning, "KVMetadataFailed", "Sync",
+				"Failed to apply KV metadata to %s: %v", path, err)
+			return fmt.Errorf("failed to apply kv metadata to %s: %w", path, err)
+		}
+		if updated {
+			log.Info("applied kv metadata", "path", path,
+				"max_versions", obj.GetAnnotations()[VaultMaxVersionsAnnotation],
+				"delete_version_after", obj.GetAnnotations()[VaultDeleteVersionAfterAnnotation],
+				"custom_metadata", slices.Sorted(maps.Keys(md.CustomMetadata)))
+			recordEvent(recorder, obj, corev1.EventTypeNormal, "KVMetadataApplied", "Sync",
+				"Applied KV metadata to %s", path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/utils/ptr"
+)
+
+func TestGetKVMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		maxVersions *int
+		after       *time.Duration
+		hint        string
+		wantErr     bool
+	}{
+		{name: "no annotations"},
+		{
+			name:        "both settings",
+			annotations: map[string]string{VaultMaxVersionsAnnotation: "5", VaultDeleteVersionAfterAnnotation: "720h"},
+			maxVersions: ptr.To(5),
+			after:       ptr.To(720 * time.Hour),
+		},
+		{
+			name:        "zero values",
+			annotations: map[string]string{VaultMaxVersionsAnnotation: "0", VaultDeleteVersionAfterAnnotation: "0s"},
+			maxVersions: ptr.To(0),
+			after:       ptr.To[time.Duration](0),
+		},
+		{name: "negative versions", annotations: map[string]string{VaultMaxVersionsAnnotation: "-1"}, wantErr: true},
+		{name: "invalid duration", annotations: map[string]string{VaultDeleteVersionAfterAnnotation: "30 days"}, wantErr: true},
+		{name: "refresh hint", annotations: map[string]string{VaultRefreshHintAnnotation: " 24h "}, hint: "24h"},
+		{name: "zero refresh hint", annotations: map[string]string{VaultRefreshHintAnnotation: "0s"}, wantErr: true},
+		{name: "invalid refresh hint", annotations: map[string]string{VaultRefreshHintAnnotation: "daily"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md, err := GetKVMetadata(newPatchTestDeployment(tt.annotations))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetKVMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (md.MaxVersions == nil) != (tt.maxVersions == nil) || (md.MaxVersions != nil && *md.MaxVersions != *tt.maxVersions) {
+				t.Errorf("MaxVersions = %v, expected %v", md.MaxVersions, tt.maxVersions)
+			}
+			if (md.DeleteVersionAfter == nil) != (tt.after == nil) || (md.DeleteVersionAfter != nil && *md.DeleteVersionAfter != *tt.after) {
+				t.Errorf("DeleteVersionAfter = %v, expected %v", md.DeleteVersionAfter, tt.after)
+			}
+			if md.CustomMetadata[RefreshHintMetadataKey] != tt.hint {
+				t.Errorf("refresh hint = %q, expected %q", md.CustomMetadata[RefreshHintMetadataKey], tt.hint)
+			}
+		})
+	}
+}
+
+func TestWorkloadCustomMetadata(t *testing.T) {
+	deployment := newPatchTestDeployment(map[string]string{"team": "payments", "description": strings.Repeat("x", 513)})
+	deployment.Labels = map[string]string{"team": "checkout", "cost-center": "cc-42", "empty": ""}
+	deployment.Spec.Template.Labels = map[string]string{"app-id": "gateway", "cost-center": "cc-7"}
+
+	custom := WorkloadCustomMetadata(deployment, deployment.Spec.Template,
+		[]string{"team", "cost-center", "app-id", "description", "empty", "missing"})
+	expected := map[string]string{"team": "payments", "cost-center": "cc-42", "app-id": "gateway"}
+	if !maps.Equal(custom, expected) {
+		t.Errorf("WorkloadCustomMetadata() = %v, expected %v", custom, expected)
+	}
+}
@@ -0,0 +1,75 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the provisioning of declared KV mounts.
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// DefaultKVMountRetryInterval is how long the provisioner waits before retrying mounts it
+// could not create.
+const DefaultKVMountRetryInterval = time.Minute
+
+// KVMountProvisioner creates the declared KV mounts that are missing and keeps their
+// MaxVersions applied. It runs alongside the controllers, so syncs to a mount that does not
+// exist yet fail and are retried with backoff until it is created. Mounts that could not be
+// checked, created or configured are retried every interval until all of them are in place.
+type KVMountProvisioner struct {
+	VaultClient KVMountEnsurer
+	Mounts      []vault.KVMount
+	Interval    time.Duration
+	Log         logr.Logger
+}
+
+// Start provisions the mounts, retrying failures every interval until all mounts are in place
+// or ctx is done. It implements manager.Runnable.
+func (p *KVMountProvisioner) Start(ctx context.Context) error {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = DefaultKVMountRetryInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for !p.Provision(ctx) {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// NeedLeaderElection restricts provisioning to the replica that reconciles.
+func (p *KVMountProvisioner) NeedLeaderElection() bool {
+	return true
+}
+
+// Provision ensures every declared mount exists and is configured, and reports whether all of
+// them are.
+func (p *KVMountProvisioner) Provision(ctx context.Context) bool {
+	provisioned := true
+	for _, mount := range p.Mounts {
+		created, err := p.VaultClient.EnsureKVMount(ctx, mount)
+		switch {
+		case err != nil:
+			metrics.KVMountProvisions.WithLabelValues("error").Inc()
+			p.Log.Error(err, "failed to provision kv mount", "path", mount.Path)
+			provisioned = false
+		case created:
+			metrics.KVMountProvisions.WithLabelValues("created").Inc()
+			p.Log.Info("created kv mount", "path", mount.Path)
+		default:
+			metrics.KVMountProvisions.WithLabelValues("exists").Inc()
+			p.Log.V(1).Info("kv mount exists", "path", mount.Path)
+		}
+	}
+	return provisioned
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// fakeMountEnsurer creates mounts, failing the first attempts for the paths in failures.
+type fakeMountEnsurer struct {
+	existing map[string]bool
+	failures map[string]int
+	attempts int
+}
+
+func (f *fakeMountEnsurer) EnsureKVMount(_ context.Context, mount vault.KVMount) (bool, error) {
+	f.attempts++
+	if f.failures[mount.Path] > 0 {
+		f.failures[mount.Path]--
+		return false, errors.New("permission denied")
+	}
+	if f.existing[mount.Path] {
+		return false, nil
+	}
+	f.existing[mount.Path] = true
+	return true, nil
+}
+
+func TestKVMountProvisioner(t *testing.T) {
+	ensurer := &fakeMountEnsurer{
+		existing: map[string]bool{"secret": true},
+		failures: map[string]int{"teams/payments": 1},
+	}
+	p := &KVMountProvisioner{
+		VaultClient: ensurer,
+		Mounts:      []vault.KVMount{{Path: "secret"}, {Path: "kv-payments"}, {Path: "teams/payments"}},
+		Interval:    time.Millisecond,
+		Log:         logr.Discard(),
+	}
+
+	created := testutil.ToFloat64(metrics.KVMountProvisions.WithLabelValues("created"))
+	failed := testutil.ToFloat64(metrics.KVMountProvisions.WithLabelValues("error"))
+	if p.Provision(context.Background()) {
+		t.Errorf("expected Provision() to report the failed mount")
+	}
+	if got := testutil.ToFloat64(metrics.KVMountProvisions.WithLabelValues("error")) - failed; got != 1 {
+		t.Errorf("failed provisions = %v, expected 1", got)
+	}
+
+	// Start retries until every mount exists
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ensurer.failures["teams/payments"] = 2
+	if err := p.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if ctx.Err() != nil || !ensurer.existing["teams/payments"] || !ensurer.existing["kv-payments"] {
+		t.Errorf("expected all mounts to be provisioned, got %v", ensurer.existing)
+	}
+	if got := testutil.ToFloat64(metrics.KVMountProvisions.WithLabelValues("created")) - created; got != 2 {
+		t.Errorf("created provisions = %v, expected 2", got)
+	}
+}
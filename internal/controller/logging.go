@@ -0,0 +1,97 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file defines the structured log schema and sampling of repetitive log lines.
+package controller
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Keys of the structured log schema. Every sync and delete outcome is logged with these
+// keys, which are kept stable across releases so log pipelines can rely on them.
+const (
+	// LogKeyResource identifies the resource as <kind>/<namespace>/<name>
+	LogKeyResource = "resource"
+	// LogKeyPath is the resolved Vault path
+	LogKeyPath = "path"
+	// LogKeyOp is the operation: sync or delete
+	LogKeyOp = "op"
+	// LogKeyResult is the outcome: success, no_change or error
+	LogKeyResult = "result"
+	// LogKeyDurationMS is the duration of the operation in milliseconds
+	LogKeyDurationMS = "duration_ms"
+)
+
+// Operations and results of the structured log schema.
+const (
+	LogOpSync         = "sync"
+	LogOpDelete       = "delete"
+	LogResultSuccess  = "success"
+	LogResultNoChange = "no_change"
+	LogResultError    = "error"
+)
+
+// LogSampler thins out repetitive INFO lines: of each message it logs the first occurrence
+// and then one in every 1/rate occurrences. Errors are never sampled. All methods are safe
+// to call on a nil sampler, which logs every line.
+type LogSampler struct {
+	every uint64
+
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+// NewLogSampler creates a sampler logging the given fraction of repetitive lines. A rate
+// of 1 or more returns nil, which disables sampling.
+func NewLogSampler(rate float64) (*LogSampler, error) {
+	if rate <= 0 || math.IsNaN(rate) {
+		return nil, fmt.Errorf("log sample rate must be greater than 0, got %v", rate)
+	}
+	if rate >= 1 {
+		return nil, nil
+	}
+	return &LogSampler{
+		every:  uint64(math.Round(1 / rate)),
+		counts: make(map[string]uint64),
+	}, nil
+}
+
+// Sample reports whether the next occurrence of message should be logged.
+func (s *LogSampler) Sample(message string) bool {
+	if s == nil {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count := s.counts[message]
+	s.counts[message] = count + 1
+	return count%s.every == 0
+}
+
+// logOutcome logs the outcome of a sync or delete of obj with the structured log schema.
+// Syncs that found no changes are sampled.
+func logOutcome(log logr.Logger, sampler *LogSampler, kind string, obj client.Object, path, op string, changedKeys int, duration time.Duration, err error) {
+	keysAndValues := []interface{}{
+		LogKeyResource, kind + "/" + obj.GetNamespace() + "/" + obj.GetName(),
+		LogKeyPath, path,
+		LogKeyOp, op,
+		LogKeyDurationMS, duration.Milliseconds(),
+	}
+
+	switch {
+	case err != nil:
+		log.Error(err, op+" finished", append(keysAndValues, LogKeyResult, LogResultError)...)
+	case op == LogOpSync && changedKeys == 0:
+		if sampler.Sample(op + " finished/" + LogResultNoChange) {
+			log.Info(op+" finished", append(keysAndValues, LogKeyResult, LogResultNoChange)...)
+		}
+	default:
+		log.Info(op+" finished", append(keysAndValues, LogKeyResult, LogResultSuccess)...)
+	}
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewLogSampler(t *testing.T) {
+	if _, err := NewLogSampler(0); err == nil {
+		t.Error("expected an error for a zero sample rate")
+	}
+	if sampler, err := NewLogSampler(1); err != nil || sampler != nil {
+		t.Errorf("NewLogSampler(1) = %v, %v, expected no sampling", sampler, err)
+	}
+
+	sampler, err := NewLogSampler(0.25)
+	if err != nil {
+		t.Fatalf("NewLogSampler() error = %v", err)
+	}
+
+	var logged []int
+	for i := 0; i < 10; i++ {
+		if sampler.Sample("no changes") {
+			logged = append(logged, i)
+		}
+	}
+	if len(logged) != 3 || logged[0] != 0 || logged[1] != 4 || logged[2] != 8 {
+		t.Errorf("sampled occurrences = %v, expected [0 4 8]", logged)
+	}
+
+	// Messages are sampled independently
+	if !sampler.Sample("other message") {
+		t.Error("first occurrence of another message was not logged")
+	}
+
+	var nilSampler *LogSampler
+	if !nilSampler.Sample("no changes") {
+		t.Error("nil sampler dropped a line")
+	}
+}
+
+func TestLogOutcome(t *testing.T) {
+	var lines []string
+	log := funcr.New(func(prefix, args string) {
+		lines = append(lines, args)
+	}, funcr.Options{})
+
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	sampler, _ := NewLogSampler(0.5)
+
+	logOutcome(log, sampler, "secret", secret, "secret/data/db", LogOpSync, 3, 1500*time.Millisecond, nil)
+	logOutcome(log, sampler, "secret", secret, "secret/data/db", LogOpSync, 0, time.Millisecond, nil)
+	logOutcome(log, sampler, "secret", secret, "secret/data/db", LogOpSync, 0, time.Millisecond, nil)
+	logOutcome(log, sampler, "secret", secret, "secret/data/db", LogOpSync, 0, time.Millisecond, errors.New("permission denied"))
+
+	if len(lines) != 3 {
+		t.Fatalf("logged %d lines, expected 3: %v", len(lines), lines)
+	}
+	for _, expected := range []string{`"resource"="secret/default/db"`, `"path"="secret/data/db"`, `"op"="sync"`, `"result"="success"`, `"duration_ms"=1500`} {
+		if !strings.Contains(lines[0], expected) {
+			t.Errorf("line %q does not contain %s", lines[0], expected)
+		}
+	}
+	if !strings.Contains(lines[1], `"result"="no_change"`) {
+		t.Errorf("line %q is not the first no-change outcome", lines[1])
+	}
+	if !strings.Contains(lines[2], `"result"="error"`) {
+		t.Errorf("line %q is not the error outcome", lines[2])
+	}
+}
@@ -0,0 +1,228 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the inventory of Vault paths managed by the operator.
+package controller
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// ManagedPathInventory tracks the Vault paths owned by each synced resource and exports
+// how many distinct paths the operator manages. Paths shared by several resources (such
+// as shared-secret canonical paths) are counted once. All methods are safe to call on a
+// nil inventory, which disables tracking.
+type ManagedPathInventory struct {
+	// Detailed also exports an info metric per path, labeled by a hash of the path
+	Detailed bool
+
+	mu sync.Mutex
+	// owners maps a resource key to the paths it manages
+	owners map[string][]string
+	// refs counts the resources managing each path
+	refs map[string]int
+	// preserved holds the resources whose paths are kept when they are deleted
+	preserved map[string]bool
+}
+
+// NewManagedPathInventory creates an empty inventory.
+func NewManagedPathInventory(detailed bool) *ManagedPathInventory {
+	return &ManagedPathInventory{
+		Detailed:  detailed,
+		owners:    make(map[string][]string),
+		refs:      make(map[string]int),
+		preserved: make(map[string]bool),
+	}
+}
+
+// managedPathOwnerKey identifies a resource in the inventory.
+func managedPathOwnerKey(kind string, key types.NamespacedName) string {
+	return kind + "/" + key.String()
+}
+
+// Set records paths as the complete set of Vault paths managed by the resource.
+func (m *ManagedPathInventory) Set(kind string, key types.NamespacedName, paths []string) {
+	if m == nil {
+		return
+	}
+
+	unique := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		unique[path] = true
+	}
+	sorted := make([]string, 0, len(unique))
+	for path := range unique {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	owner := managedPathOwnerKey(kind, key)
+	for _, path := range sorted {
+		m.addRef(path)
+	}
+	for _, path := range m.owners[owner] {
+		m.removeRef(path)
+	}
+	if len(sorted) == 0 {
+		delete(m.owners, owner)
+		delete(m.preserved, owner)
+	} else {
+		m.owners[owner] = sorted
+	}
+	metrics.ManagedPaths.Set(float64(len(m.refs)))
+}
+
+// SetPreserved records whether the resource keeps its paths in Vault when it is deleted.
+func (m *ManagedPathInventory) SetPreserved(kind string, key types.NamespacedName, preserve bool) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	owner := managedPathOwnerKey(kind, key)
+	if preserve && len(m.owners[owner]) > 0 {
+		m.preserved[owner] = true
+	} else {
+		delete(m.preserved, owner)
+	}
+}
+
+// Forget removes the resource from the inventory once it no longer manages any path.
+func (m *ManagedPathInventory) Forget(kind string, key types.NamespacedName) {
+	m.Set(kind, key, nil)
+}
+
+// ManagedByOthers reports whether path is managed by a resource other than the given one.
+func (m *ManagedPathInventory) ManagedByOthers(kind string, key types.NamespacedName, path string) bool {
+	if m == nil {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	refs := m.refs[path]
+	owned := m.owners[managedPathOwnerKey(kind, key)]
+	if i := sort.SearchStrings(owned, path); i < len(owned) && owned[i] == path {
+		refs--
+	}
+	return refs > 0
+}
+
+// Count returns the number of distinct paths managed.
+func (m *ManagedPathInventory) Count() int {
+	if m == nil {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.refs)
+}
+
+// Paths returns the distinct paths managed, sorted.
+func (m *ManagedPathInventory) Paths() []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make([]string, 0, len(m.refs))
+	for path := range m.refs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// addRef counts one more resource managing path. The caller must hold m.mu.
+func (m *ManagedPathInventory) addRef(path string) {
+	m.refs[path]++
+	if m.refs[path] == 1 && m.Detailed {
+		metrics.ManagedPathInfo.WithLabelValues(PathHash(path)).Set(1)
+	}
+}
+
+// removeRef counts one resource less managing path. The caller must hold m.mu.
+func (m *ManagedPathInventory) removeRef(path string) {
+	m.refs[path]--
+	if m.refs[path] > 0 {
+		return
+	}
+	delete(m.refs, path)
+	if m.Detailed {
+		metrics.ManagedPathInfo.DeleteLabelValues(PathHash(path))
+	}
+}
+
+// PathHash returns a short stable hash of a Vault path, used as a metric label so that
+// dashboards can follow individual paths without exposing them.
+func PathHash(path string) string {
+	return metrics.PathHash(path)
+}
+
+// ResourcesByNamespace returns the number of resources managing paths in each namespace.
+func (m *ManagedPathInventory) ResourcesByNamespace() map[string]int {
+	counts := make(map[string]int)
+	if m == nil {
+		return counts
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for owner := range m.owners {
+		if namespace, ok := ownerNamespace(owner); ok {
+			counts[namespace]++
+		}
+	}
+	return counts
+}
+
+// NamespacePaths returns the paths managed only by resources in namespace, leaving out the
+// paths of resources that preserve them on deletion and paths still used in other namespaces.
+func (m *ManagedPathInventory) NamespacePaths(namespace string) []string {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	candidates := make(map[string]bool)
+	keep := make(map[string]bool)
+	for owner, paths := range m.owners {
+		ownerNS, _ := ownerNamespace(owner)
+		for _, path := range paths {
+			if ownerNS != namespace || m.preserved[owner] {
+				keep[path] = true
+			} else {
+				candidates[path] = true
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(candidates))
+	for path := range candidates {
+		if !keep[path] {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ownerNamespace returns the namespace of an owner key, which has the form <kind>/<namespace>/<name>.
+func ownerNamespace(owner string) (string, bool) {
+	parts := strings.SplitN(owner, "/", 3)
+	if len(parts) != 3 {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+func TestManagedPathInventory(t *testing.T) {
+	inventory := NewManagedPathInventory(true)
+	web := types.NamespacedName{Namespace: "default", Name: "web"}
+	api := types.NamespacedName{Namespace: "default", Name: "api"}
+	shared := "secret/data/shared/default/db"
+
+	inventory.Set("deployment", web, []string{"secret/data/web/tls", shared})
+	inventory.Set("deployment", api, []string{shared})
+	if count := inventory.Count(); count != 2 {
+		t.Fatalf("Count() = %d, expected 2", count)
+	}
+	if value := testutil.ToFloat64(metrics.ManagedPaths); value != 2 {
+		t.Errorf("managed paths gauge = %v, expected 2", value)
+	}
+	if value := testutil.ToFloat64(metrics.ManagedPathInfo.WithLabelValues(PathHash(shared))); value != 1 {
+		t.Errorf("managed path info = %v, expected 1", value)
+	}
+
+	// A path shared with another resource stays managed until both forget it
+	inventory.Forget("deployment", web)
+	if count := inventory.Count(); count != 1 {
+		t.Fatalf("Count() after forgetting web = %d, expected 1", count)
+	}
+	if series := testutil.CollectAndCount(metrics.ManagedPathInfo); series != 1 {
+		t.Errorf("managed path info series = %d, expected 1", series)
+	}
+
+	// Replacing the paths of a resource releases the old ones
+	inventory.Set("deployment", api, []string{"secret/data/api"})
+	if count := inventory.Count(); count != 1 {
+		t.Fatalf("Count() after moving api = %d, expected 1", count)
+	}
+
+	inventory.Forget("deployment", api)
+	if count := inventory.Count(); count != 0 {
+		t.Errorf("Count() after forgetting api = %d, expected 0", count)
+	}
+	if series := testutil.CollectAndCount(metrics.ManagedPathInfo); series != 0 {
+		t.Errorf("managed path info series = %d, expected 0", series)
+	}
+}
+
+func TestManagedPathInventoryManagedByOthers(t *testing.T) {
+	inventory := NewManagedPathInventory(false)
+	web := types.NamespacedName{Namespace: "default", Name: "web"}
+	api := types.NamespacedName{Namespace: "default", Name: "api"}
+	inventory.Set("deployment", web, []string{"secret/data/shared", "secret/data/web"})
+	inventory.Set("deployment", api, []string{"secret/data/shared"})
+
+	if inventory.ManagedByOthers("deployment", web, "secret/data/web") {
+		t.Errorf("expected secret/data/web to be managed by web only")
+	}
+	if !inventory.ManagedByOthers("deployment", web, "secret/data/shared") {
+		t.Errorf("expected secret/data/shared to be managed by api as well")
+	}
+	if !inventory.ManagedByOthers("secret", web, "secret/data/web") {
+		t.Errorf("expected secret/data/web to be managed by another resource than the secret")
+	}
+}
+
+func TestManagedPathInventoryNil(t *testing.T) {
+	var inventory *ManagedPathInventory
+	inventory.Set("secret", types.NamespacedName{Namespace: "default", Name: "db"}, []string{"secret/data/db"})
+	inventory.Forget("secret", types.NamespacedName{Namespace: "default", Name: "db"})
+	if count := inventory.Count(); count != 0 {
+		t.Errorf("Count() = %d, expected 0", count)
+	}
+}
+
+func TestPathHash(t *testing.T) {
+	hash := PathHash("secret/data/web")
+	if len(hash) != 16 {
+		t.Errorf("PathHash() = %q, expected 16 characters", hash)
+	}
+	if hash != PathHash("secret/data/web") {
+		t.Error("PathHash() is not stable")
+	}
+	if hash == PathHash("secret/data/api") {
+		t.Error("PathHash() collides for different paths")
+	}
+}
@@ -0,0 +1,176 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the cleanup of Vault paths when a namespace is deleted.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// NamespaceCleanupRequeueDelay is how often a terminating namespace is checked again for
+// paths first synced after the previous cleanup, e.g. by syncs that raced it.
+const NamespaceCleanupRequeueDelay = 30 * time.Second
+
+// NamespaceReconciler deletes the Vault paths managed by the resources of a namespace as soon
+// as the namespace starts terminating. The resources' own finalizers delete their paths too,
+// but in a terminating namespace they race the deletion of the RoleBindings, service accounts
+// and Vault roles they depend on, and can fail and leave paths behind. Paths of resources
+// annotated with vault-sync.io/preserve-on-delete, and paths shared with resources in other
+// namespaces, are kept. Each path is deleted once per namespace: later cleanups of the same
+// terminating namespace only delete paths that were not managed before.
+type NamespaceReconciler struct {
+	client.Client
+	Log         logr.Logger
+	VaultClient VaultWriterDeleter
+	// Inventory tracks the Vault paths managed by each resource
+	Inventory *ManagedPathInventory
+	// Recorder emits Kubernetes events for the cleanup (optional)
+	Recorder events.EventRecorder
+	// SecretSizes forgets the size of deleted paths (optional)
+	SecretSizes *SecretSizeTracker
+	// Errors keeps the recent delete errors for the status page (optional)
+	Errors *ErrorLog
+
+	mu sync.Mutex
+	// cleaned holds the paths deleted so far by terminating namespace name
+	cleaned map[string]*namespaceCleanup
+}
+
+// namespaceCleanup records the paths deleted for one terminating namespace.
+type namespaceCleanup struct {
+	// uid tells a namespace recreated under the same name apart
+	uid   types.UID
+	paths map[string]bool
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Reconcile deletes the Vault paths of a terminating namespace.
+func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("namespace", req.Name)
+	ctx = vault.WithSourceNamespace(ctx, req.Name)
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, req.NamespacedName, namespace); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.forget(req.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if namespace.DeletionTimestamp.IsZero() {
+		r.forget(namespace.Name)
+		return ctrl.Result{}, nil
+	}
+
+	paths := r.pendingPaths(namespace)
+	var failed []string
+	for _, path := range paths {
+		if err := r.deletePath(ctx, namespace, path); err != nil {
+			log.Error(err, "failed to delete secret of terminating namespace from vault", "path", path)
+			failed = append(failed, path)
+			continue
+		}
+		r.markCleaned(namespace, path)
+	}
+
+	if len(paths) > 0 {
+		log.Info("cleaned up vault secrets of terminating namespace",
+			"paths", len(paths),
+			"failed", len(failed))
+	}
+	if len(failed) > 0 {
+		recordEvent(r.Recorder, namespace, corev1.EventTypeWarning, "NamespaceCleanupFailed", "Delete",
+			"Failed to delete %d of %d Vault paths of the terminating namespace", len(failed), len(paths))
+		return ctrl.Result{}, fmt.Errorf("failed to delete %d vault paths of namespace %s", len(failed), namespace.Name)
+	}
+	if len(paths) > 0 {
+		recordEvent(r.Recorder, namespace, corev1.EventTypeNormal, "NamespaceCleanup", "Delete",
+			"Deleted %d Vault paths of the terminating namespace", len(paths))
+	}
+
+	// Keep checking for new paths until the namespace is gone
+	return ctrl.Result{RequeueAfter: NamespaceCleanupRequeueDelay}, nil
+}
+
+// pendingPaths returns the paths of a terminating namespace that were not deleted yet.
+func (r *NamespaceReconciler) pendingPaths(namespace *corev1.Namespace) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cleanup := r.cleaned[namespace.Name]
+	if cleanup != nil && cleanup.uid != namespace.UID {
+		cleanup = nil
+	}
+
+	var pending []string
+	for _, path := range r.Inventory.NamespacePaths(namespace.Name) {
+		if cleanup == nil || !cleanup.paths[path] {
+			pending = append(pending, path)
+		}
+	}
+	return pending
+}
+
+// markCleaned records that path of a terminating namespace was deleted.
+func (r *NamespaceReconciler) markCleaned(namespace *corev1.Namespace, path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cleaned == nil {
+		r.cleaned = make(map[string]*namespaceCleanup)
+	}
+	cleanup := r.cleaned[namespace.Name]
+	if cleanup == nil || cleanup.uid != namespace.UID {
+		cleanup = &namespaceCleanup{uid: namespace.UID, paths: make(map[string]bool)}
+		r.cleaned[namespace.Name] = cleanup
+	}
+	cleanup.paths[path] = true
+}
+
+// forget drops the paths recorded for a namespace that is gone or no longer terminating.
+func (r *NamespaceReconciler) forget(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.cleaned, name)
+}
+
+// deletePath deletes one path of a terminating namespace from Vault.
+func (r *NamespaceReconciler) deletePath(ctx context.Context, namespace *corev1.Namespace, path string) error {
+	// Serialize with the deletion reconciles of the namespace's resources
+	unlock, err := lockVaultPath(ctx, r.VaultClient, path)
+	if err != nil {
+		return fmt.Errorf("failed to lock vault path %s: %w", path, err)
+	}
+	defer unlock()
+
+	err = r.VaultClient.DeleteSecret(ctx, path)
+	r.Errors.Record("namespace", namespace, path, LogOpDelete, err)
+	if err != nil {
+		return err
+	}
+	r.SecretSizes.Forget(path)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Only namespaces that are
+// terminating are reconciled.
+func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("namespace").
+		For(&corev1.Namespace{}, ctrlbuilder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return !obj.GetDeletionTimestamp().IsZero()
+		}))).
+		Complete(r)
+}
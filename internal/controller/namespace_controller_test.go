@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestNamespaceReconcilerCleanup(t *testing.T) {
+	ctx := context.Background()
+	inventory := NewManagedPathInventory(false)
+	shared := "secret/data/shared/db"
+	inventory.Set("deployment", types.NamespacedName{Namespace: "team-a", Name: "web"}, []string{"secret/data/team-a/web", shared})
+	inventory.Set("deployment", types.NamespacedName{Namespace: "team-b", Name: "api"}, []string{shared})
+	inventory.Set("secret", types.NamespacedName{Namespace: "team-a", Name: "db"}, []string{"secret/data/team-a/db"})
+	inventory.SetPreserved("secret", types.NamespacedName{Namespace: "team-a", Name: "db"}, true)
+
+	terminating := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:              "team-a",
+		DeletionTimestamp: ptr.To(metav1.Now()),
+		Finalizers:        []string{"example.com/hold"},
+	}}
+	active := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}}
+	vaultClient := &fakeVault{}
+	r := &NamespaceReconciler{
+		Client:      fake.NewClientBuilder().WithObjects(terminating, active).Build(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+		Inventory:   inventory,
+	}
+
+	// Active and deleted namespaces are left alone
+	for _, name := range []string{"team-b", "gone"} {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}); err != nil {
+			t.Fatalf("Reconcile(%s) error = %v", name, err)
+		}
+	}
+	if len(vaultClient.deletes) != 0 {
+		t.Fatalf("deletes = %v, expected none", vaultClient.deletes)
+	}
+
+	// Preserved paths and paths shared with other namespaces are kept
+	result, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "team-a"}})
+	if err != nil {
+		t.Fatalf("Reconcile(team-a) error = %v", err)
+	}
+	if !slices.Equal(vaultClient.deletes, []string{"secret/data/team-a/web"}) {
+		t.Errorf("deletes = %v, expected only the unshared path", vaultClient.deletes)
+	}
+	if result.RequeueAfter != NamespaceCleanupRequeueDelay {
+		t.Errorf("RequeueAfter = %v, expected %v", result.RequeueAfter, NamespaceCleanupRequeueDelay)
+	}
+
+	// Once the shared path is only used in the terminating namespace it is deleted too
+	inventory.Forget("deployment", types.NamespacedName{Namespace: "team-b", Name: "api"})
+	if paths := inventory.NamespacePaths("team-a"); !slices.Equal(paths, []string{shared, "secret/data/team-a/web"}) {
+		t.Errorf("NamespacePaths() = %v", paths)
+	}
+
+	// Later cleanups only delete the paths not deleted before
+	for i := 0; i < 2; i++ {
+		if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "team-a"}}); err != nil {
+			t.Fatalf("Reconcile(team-a) error = %v", err)
+		}
+	}
+	if !slices.Equal(vaultClient.deletes, []string{"secret/data/team-a/web", shared}) {
+		t.Errorf("deletes = %v, expected each path deleted once", vaultClient.deletes)
+	}
+}
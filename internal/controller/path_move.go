@@ -0,0 +1,223 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements moving synced secrets when the Vault path of a resource changes.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// VaultSyncedPathAnnotation records the resolved Vault path a resource was last synced to.
+// It is managed by the operator.
+const VaultSyncedPathAnnotation = "vault-sync.io/synced-path"
+
+// GetSyncedPath returns the resolved Vault path obj was last synced to, or an empty string
+// before its first sync.
+func GetSyncedPath(obj client.Object) string {
+	return obj.GetAnnotations()[VaultSyncedPathAnnotation]
+}
+
+// vaultPathMove describes the move of the secrets of a resource from the path it was last
+// synced to to its current path, after vault-sync.io/path or a prefix applied to it changed.
+type vaultPathMove struct {
+	From string
+	To   string
+	// OldPaths are deleted once the move is complete: From and the paths nested under it
+	OldPaths []string
+	// NewPaths are read back before anything is deleted
+	NewPaths []string
+}
+
+// detectVaultPathMove returns the move of obj to resolvedPath, or nil when obj was never
+// synced or was last synced to resolvedPath.
+func detectVaultPathMove(obj client.Object, resolvedPath string) *vaultPathMove {
+	synced := GetSyncedPath(obj)
+	if synced == "" || synced == resolvedPath {
+		return nil
+	}
+	return &vaultPathMove{From: synced, To: resolvedPath}
+}
+
+// verifyVaultPath checks that path holds data after a write, from its keys when the client can
+// read them without the values, and by reading it back otherwise. Documents with compressed
+// values are read back as well, to check that the values decompress. Clients that cannot
+// read are trusted to have written it. The reads go to the Vault primary, as a replica may
+// not have received the write yet.
+func verifyVaultPath(ctx context.Context, vaultClient VaultWriterDeleter, path string) error {
+	ctx = vault.WithPrimaryReads(ctx)
+	keys, err := vaultSecretKeys(ctx, vaultClient, path)
+	if err != nil {
+		return fmt.Errorf("failed to read back %s: %w", path, err)
+	}
+	if len(keys) > 0 && !slices.ContainsFunc(keys, isCompressedKey) {
+		return nil
+	}
+	reader, ok := vaultClient.(VaultReader)
+	if !ok {
+		return nil
+	}
+	data, err := reader.ReadSecret(ctx, path)
+	if err != nil {
+		return fmt.Errorf("failed to read back %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("no data found at %s", path)
+	}
+	if _, err := DecompressValues(data); err != nil {
+		return fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	return nil
+}
+
+// vaultPathExists reports whether path holds data, from its keys when the client can read them
+// without the values, and by reading it otherwise. Paths of clients that cannot read are
+// assumed to exist.
+func vaultPathExists(ctx context.Context, vaultClient VaultWriterDeleter, path string) (bool, error) {
+	keys, err := vaultSecretKeys(ctx, vaultClient, path)
+	if err != nil {
+		return false, err
+	}
+	if len(keys) > 0 {
+		return true, nil
+	}
+	reader, ok := vaultClient.(VaultReader)
+	if !ok {
+		return true, nil
+	}
+	data, err := reader.ReadSecret(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	return len(data) > 0, nil
+}
+
+// missingVaultPaths returns the paths that hold no data in Vault, e.g. because they were
+// deleted out of band. Paths that cannot be read are logged and assumed to exist.
+func missingVaultPaths(ctx context.Context, vaultClient VaultWriterDeleter, paths []string, log logr.Logger) []string {
+	var missing []string
+	for _, path := range paths {
+		exists, err := vaultPathExists(ctx, vaultClient, path)
+		if err != nil {
+			log.Error(err, "failed to check that the vault path exists", "path", path)
+			continue
+		}
+		if !exists {
+			missing = append(missing, path)
+		}
+	}
+	return missing
+}
+
+// recordSyncedPath completes a sync of obj to path. Without a move it records path as the
+// synced path, logging failures only: at worst the next sync records it again. With a move,
+// the new paths are verified first, then the old paths are deleted unless preserve is set,
+// and only then is path recorded. Old paths still managed by other resources are kept. When
+// verification or a deletion fails, the old path stays recorded and the move is retried on
+// the next sync.
+func recordSyncedPath(ctx context.Context, k8sClient client.Client, vaultClient VaultWriterDeleter, recorder events.EventRecorder, inventory *ManagedPathInventory, kind string, obj client.Object, path string, move *vaultPathMove, preserve bool, log logr.Logger) error {
+	if move == nil {
+		if GetSyncedPath(obj) == path {
+			return nil
+		}
+		if err := PatchAnnotations(ctx, k8sClient, obj, map[string]string{VaultSyncedPathAnnotation: path}); err != nil {
+			log.Error(err, "failed to record synced vault path", "path", path)
+		}
+		return nil
+	}
+
+	for _, newPath := range move.NewPaths {
+		if err := verifyVaultPath(ctx, vaultClient, newPath); err != nil {
+			metrics.PathMoves.WithLabelValues("failed").Inc()
+			recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultPathMoveFailed", "Sync",
+				"Kept %s, as the secrets written to %s could not be verified: %v", move.From, move.To, err)
+			return fmt.Errorf("failed to verify vault path move to %s: %w", move.To, err)
+		}
+	}
+
+	result := "moved"
+	if preserve {
+		result = "preserved"
+		log.Info("preserving previous vault path due to preserve-on-delete policy", "from", move.From, "to", move.To)
+	} else {
+		var failed []string
+		for _, oldPath := range move.OldPaths {
+			if inventory.ManagedByOthers(kind, client.ObjectKeyFromObject(obj), oldPath) {
+				log.Info("keeping previous vault path managed by another resource", "path", oldPath)
+				continue
+			}
+			if err := vaultClient.DeleteSecret(ctx, oldPath); err != nil {
+				log.Error(err, "failed to delete previous vault path", "path", oldPath)
+				failed = append(failed, oldPath)
+			}
+		}
+		if len(failed) > 0 {
+			metrics.PathMoves.WithLabelValues("failed").Inc()
+			recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultPathMoveFailed", "Sync",
+				"Failed to delete %s after moving secrets to %s", strings.Join(failed, ", "), move.To)
+			return fmt.Errorf("failed to delete previous vault paths %s", strings.Join(failed, ","))
+		}
+	}
+
+	if err := PatchAnnotations(ctx, k8sClient, obj, map[string]string{VaultSyncedPathAnnotation: path}); err != nil {
+		return fmt.Errorf("failed to record synced vault path %s: %w", path, err)
+	}
+	metrics.PathMoves.WithLabelValues(result).Inc()
+	if preserve {
+		recordEvent(recorder, obj, corev1.EventTypeNormal, "VaultPathMoved", "Sync",
+			"Moved secrets from %s to %s, keeping %s due to preserve-on-delete", move.From, move.To, move.From)
+	} else {
+		recordEvent(recorder, obj, corev1.EventTypeNormal, "VaultPathMoved", "Sync",
+			"Moved secrets from %s to %s", move.From, move.To)
+	}
+	log.Info("moved secrets to new vault path", "from", move.From, "to", move.To, "preserved", preserve)
+	return nil
+}
+
+// detectVaultPathMove returns the move of a workload to basePath with the paths it involves,
+// or nil when the workload was never synced or was last synced to basePath. vaultPath is the
+// path written now, which is basePath or its revision path.
+func (r *DeploymentReconciler) detectVaultPathMove(deployment client.Object, basePath, vaultPath string, vaultData map[string]interface{}, discoveredSecrets map[string]*corev1.Secret, secretNames []string, keyPolicy KeySanitizationPolicy) *vaultPathMove {
+	move := detectVaultPathMove(deployment, basePath)
+	if move == nil {
+		return nil
+	}
+
+	// Everything written under the previous base path: the base path itself, the paths of
+	// the synced revisions and the sub-paths of auto-discovered secrets. Shared-secret
+	// canonical paths do not depend on the base path.
+	move.OldPaths = []string{move.From}
+	revisions := GetSyncedRevisions(deployment)
+	for _, revision := range revisions {
+		move.OldPaths = append(move.OldPaths, r.revisionPaths(deployment, move.From, revision, secretNames)...)
+	}
+	if len(revisions) == 0 && discoveredSecrets != nil && r.SharedSecrets == nil {
+		for _, secretName := range secretNames {
+			move.OldPaths = append(move.OldPaths, r.autoDiscoveredSecretPath(deployment, move.From, secretName))
+		}
+	}
+
+	// The paths written now that hold data
+	if len(vaultData) > 0 {
+		move.NewPaths = append(move.NewPaths, vaultPath)
+	}
+	if r.SharedSecrets == nil {
+		includeKeys := GetIncludeKeys(deployment)
+		for _, secretName := range slices.Sorted(maps.Keys(discoveredSecrets)) {
+			if data, err := autoDiscoveredSecretData(discoveredSecrets[secretName], includeKeys, keyPolicy); err == nil && len(data) > 0 {
+				move.NewPaths = append(move.NewPaths, r.autoDiscoveredSecretPath(deployment, vaultPath, secretName))
+			}
+		}
+	}
+	return move
+}
@@ -0,0 +1,165 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// unreadableVault is a fakeVault whose reads find nothing, so written paths fail verification.
+type unreadableVault struct{ fakeVault }
+
+func (f *unreadableVault) ReadSecret(context.Context, string) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+// setVaultPath changes the vault-sync.io/path annotation of obj, along with extra annotations.
+func setVaultPath(t *testing.T, k8sClient client.Client, obj client.Object, path string, extra map[string]string) {
+	t.Helper()
+	if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj); err != nil {
+		t.Fatalf("failed to get object: %v", err)
+	}
+	annotations := obj.GetAnnotations()
+	annotations[VaultPathAnnotation] = path
+	for key, value := range extra {
+		annotations[key] = value
+	}
+	obj.SetAnnotations(annotations)
+	if err := k8sClient.Update(context.Background(), obj); err != nil {
+		t.Fatalf("failed to update object: %v", err)
+	}
+}
+
+// TestSecretPathMove tests that a changed path is written before the old one is deleted or preserved.
+func TestSecretPathMove(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultClient := &readableVault{}
+	r := &SecretReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+		Inventory:   NewManagedPathInventory(false),
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	reconcileUntilSynced(t, r, req)
+	if err := k8sClient.Get(ctx, req.NamespacedName, secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if got := GetSyncedPath(secret); got != "secret/data/db" {
+		t.Fatalf("synced path = %q, expected secret/data/db", got)
+	}
+
+	// Unchanged secret versions do not prevent the move
+	setVaultPath(t, k8sClient, secret, "secret/data/database", nil)
+	reconcileUntilSynced(t, r, req)
+	if got := vaultClient.secrets["secret/data/database"]["password"]; got != "s3cret" {
+		t.Errorf("password at new path = %v, expected s3cret", got)
+	}
+	if _, ok := vaultClient.secrets["secret/data/db"]; ok {
+		t.Errorf("expected the old path to be deleted")
+	}
+	if err := k8sClient.Get(ctx, req.NamespacedName, secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if got := GetSyncedPath(secret); got != "secret/data/database" {
+		t.Errorf("synced path = %q, expected secret/data/database", got)
+	}
+
+	// Preserved resources keep the old path
+	setVaultPath(t, k8sClient, secret, "secret/data/db-v2", map[string]string{VaultPreserveOnDeleteAnnotation: "true"})
+	reconcileUntilSynced(t, r, req)
+	if _, ok := vaultClient.secrets["secret/data/database"]; !ok {
+		t.Errorf("expected the old path to be preserved")
+	}
+	if _, ok := vaultClient.secrets["secret/data/db-v2"]; !ok {
+		t.Errorf("expected the secret at the new path")
+	}
+}
+
+// TestSecretPathMoveUnverified tests that the old path is kept when the new one cannot be read back.
+func TestSecretPathMoveUnverified(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Finalizers = []string{VaultSyncFinalizer}
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/database", VaultSyncedPathAnnotation: "secret/data/db"}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultClient := &unreadableVault{}
+	vaultClient.secrets = map[string]map[string]interface{}{"secret/data/db": {"password": "s3cret"}}
+	r := &SecretReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}); err == nil {
+		t.Fatalf("expected the unverified move to fail")
+	}
+	if len(vaultClient.deletes) > 0 {
+		t.Errorf("deletes = %v, expected the old path to be kept", vaultClient.deletes)
+	}
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(secret), secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if got := GetSyncedPath(secret); got != "secret/data/db" {
+		t.Errorf("synced path = %q, expected the old path to stay recorded", got)
+	}
+}
+
+// TestDeploymentPathMove tests that the sub-paths of auto-discovered secrets move with the base path.
+func TestDeploymentPathMove(t *testing.T) {
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "web-credentials"
+	secret.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	for _, path := range []string{"secret/data/web", "secret/data/frontend"} {
+		setVaultPath(t, k8sClient, deployment, path, nil)
+		reconcileUntilSynced(t, r, req)
+	}
+
+	if _, ok := vaultClient.secrets["secret/data/frontend/web-credentials"]; !ok {
+		t.Errorf("expected the secret at the new sub-path, got %v", vaultClient.secrets)
+	}
+	if _, ok := vaultClient.secrets["secret/data/web/web-credentials"]; ok || !slices.Contains(vaultClient.deletes, "secret/data/web/web-credentials") {
+		t.Errorf("deletes = %v, expected the old sub-path to be deleted", vaultClient.deletes)
+	}
+}
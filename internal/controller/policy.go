@@ -0,0 +1,200 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the external policy hook evaluated before Vault writes.
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// DefaultPolicyTimeout bounds a single policy evaluation.
+const DefaultPolicyTimeout = 5 * time.Second
+
+// PolicyInput describes a pending Vault write. It carries resource metadata, the target path
+// and the names of the keys written only, never secret values.
+type PolicyInput struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Cluster   string `json:"cluster,omitempty"`
+	// Keys are the sorted keys of the document written, including keys retained in Vault
+	Keys     []string       `json:"keys,omitempty"`
+	Resource PolicyResource `json:"resource"`
+}
+
+// PolicyResource identifies the resource a write is made for. Only the vault-sync.io/
+// annotations of the resource are forwarded: others, such as the last-applied configuration
+// of a Secret, can hold secret values.
+type PolicyResource struct {
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// PolicyDecision is the verdict of a policy evaluation.
+type PolicyDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ErrPolicyDenied is wrapped by the errors returned for writes the policy denied.
+var ErrPolicyDenied = errors.New("denied by policy")
+
+// PolicyHook asks an external endpoint whether a Vault write may proceed. The request body
+// is {"input": <PolicyInput>} and the response is {"result": <PolicyDecision>} or
+// {"result": <bool>}, which is the data API of an Open Policy Agent serving a Rego bundle,
+// so OPA can be used directly as well as any webhook speaking the same format.
+// A nil hook allows every write.
+type PolicyHook struct {
+	// URL is the endpoint evaluated, e.g. http://localhost:8181/v1/data/vaultsync/allow
+	URL string
+	// HTTPClient sends the requests (http.DefaultClient when nil)
+	HTTPClient *http.Client
+	// Timeout bounds each evaluation (DefaultPolicyTimeout when zero)
+	Timeout time.Duration
+	// FailOpen allows writes when the policy endpoint cannot be evaluated
+	FailOpen bool
+}
+
+// policyResponse is the envelope returned by the policy endpoint.
+type policyResponse struct {
+	Result json.RawMessage `json:"result"`
+}
+
+// Evaluate returns the policy decision for input. Errors reaching the endpoint or decoding
+// its answer are returned as such, leaving the fail-open decision to Check.
+func (p *PolicyHook) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	if p == nil || p.URL == "" {
+		return PolicyDecision{Allowed: true}, nil
+	}
+
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPolicyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	body, err := json.Marshal(map[string]PolicyInput{"input": input})
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to encode policy input: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to create policy request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return PolicyDecision{}, fmt.Errorf("policy request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return PolicyDecision{}, fmt.Errorf("policy endpoint returned %s", resp.Status)
+	}
+
+	var envelope policyResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&envelope); err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to decode policy response: %w", err)
+	}
+
+	// An undefined result (no rule matched) denies the write
+	var decision PolicyDecision
+	if len(envelope.Result) == 0 || string(envelope.Result) == "null" {
+		return PolicyDecision{Reason: "policy result is undefined"}, nil
+	}
+	if err := json.Unmarshal(envelope.Result, &decision.Allowed); err == nil {
+		return decision, nil
+	}
+	if err := json.Unmarshal(envelope.Result, &decision); err != nil {
+		return PolicyDecision{}, fmt.Errorf("failed to decode policy result: %w", err)
+	}
+	return decision, nil
+}
+
+// Check evaluates the policy for a write of data to path for obj and returns an error
+// wrapping ErrPolicyDenied when the write must not proceed.
+func (p *PolicyHook) Check(ctx context.Context, kind string, obj client.Object, path, clusterName string, data map[string]interface{}) error {
+	if p == nil || p.URL == "" {
+		return nil
+	}
+
+	decision, err := p.Evaluate(ctx, PolicyInput{
+		Operation: "write",
+		Path:      path,
+		Cluster:   clusterName,
+		Keys:      slices.Sorted(maps.Keys(data)),
+		Resource: PolicyResource{
+			Kind:        kind,
+			Namespace:   obj.GetNamespace(),
+			Name:        obj.GetName(),
+			Labels:      obj.GetLabels(),
+			Annotations: policyAnnotations(obj.GetAnnotations()),
+		},
+	})
+	if err != nil {
+		metrics.PolicyEvaluations.WithLabelValues("error").Inc()
+		if p.FailOpen {
+			return nil
+		}
+		return fmt.Errorf("write to %s %w: policy could not be evaluated: %v", path, ErrPolicyDenied, err)
+	}
+	if !decision.Allowed {
+		metrics.PolicyEvaluations.WithLabelValues("denied").Inc()
+		if decision.Reason != "" {
+			return fmt.Errorf("write to %s %w: %s", path, ErrPolicyDenied, decision.Reason)
+		}
+		return fmt.Errorf("write to %s %w", path, ErrPolicyDenied)
+	}
+	metrics.PolicyEvaluations.WithLabelValues("allowed").Inc()
+	return nil
+}
+
+// policyAnnotations returns the vault-sync.io/ annotations of annotations, or nil when there
+// are none.
+func policyAnnotations(annotations map[string]string) map[string]string {
+	var filtered map[string]string
+	for key, value := range annotations {
+		if !strings.HasPrefix(key, "vault-sync.io/") {
+			continue
+		}
+		if filtered == nil {
+			filtered = make(map[string]string)
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
+
+// checkWritePolicy evaluates the policy for a write of data to path for obj and records a
+// PolicyDenied warning on obj when the write must not proceed. data is the document as
+// written, after keys retained in Vault were merged in.
+func checkWritePolicy(ctx context.Context, policy *PolicyHook, recorder events.EventRecorder, kind string, obj client.Object, path, clusterName string, data map[string]interface{}) error {
+	if err := policy.Check(ctx, kind, obj, path, clusterName, data); err != nil {
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "PolicyDenied", "Sync", "Vault %v", err)
+		return err
+	}
+	return nil
+}
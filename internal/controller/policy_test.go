@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPolicyHookCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		failOpen bool
+		wantErr  string
+	}{
+		{
+			name:     "boolean allow",
+			status:   http.StatusOK,
+			response: `{"result": true}`,
+		},
+		{
+			name:     "decision allow",
+			status:   http.StatusOK,
+			response: `{"result": {"allowed": true}}`,
+		},
+		{
+			name:     "boolean deny",
+			status:   http.StatusOK,
+			response: `{"result": false}`,
+			wantErr:  "denied by policy",
+		},
+		{
+			name:     "decision deny with reason",
+			status:   http.StatusOK,
+			response: `{"result": {"allowed": false, "reason": "payments may only write under secret/data/payments"}}`,
+			wantErr:  "payments may only write under secret/data/payments",
+		},
+		{
+			name:     "undefined result",
+			status:   http.StatusOK,
+			response: `{}`,
+			wantErr:  "policy result is undefined",
+		},
+		{
+			name:     "endpoint error",
+			status:   http.StatusInternalServerError,
+			response: `{}`,
+			wantErr:  "policy could not be evaluated",
+		},
+		{
+			name:     "endpoint error with fail-open",
+			status:   http.StatusInternalServerError,
+			response: `{}`,
+			failOpen: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input PolicyInput
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Input PolicyInput `json:"input"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				input = body.Input
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			secret := &corev1.Secret{}
+			secret.Name = "db"
+			secret.Namespace = "payments"
+			secret.Labels = map[string]string{"team": "payments"}
+			secret.Annotations = map[string]string{
+				VaultPathAnnotation: "secret/data/payments/db",
+				"kubectl.kubernetes.io/last-applied-configuration": `{"data":{"password":"czNjcmV0"}}`,
+			}
+
+			hook := &PolicyHook{URL: server.URL, FailOpen: tt.failOpen}
+			err := hook.Check(context.Background(), "Secret", secret, "secret/data/payments/db", "prod", map[string]interface{}{"username": "app", "password": "s3cret"})
+
+			if input.Path != "secret/data/payments/db" || input.Cluster != "prod" || input.Operation != "write" {
+				t.Errorf("unexpected policy input %+v", input)
+			}
+			if !slices.Equal(input.Keys, []string{"password", "username"}) {
+				t.Errorf("policy keys = %v, expected the sorted keys without values", input.Keys)
+			}
+			if input.Resource.Kind != "Secret" || input.Resource.Namespace != "payments" || input.Resource.Labels["team"] != "payments" {
+				t.Errorf("unexpected policy resource %+v", input.Resource)
+			}
+			if len(input.Resource.Annotations) != 1 || input.Resource.Annotations[VaultPathAnnotation] != "secret/data/payments/db" {
+				t.Errorf("policy annotations = %v, expected only the vault-sync.io/ annotations", input.Resource.Annotations)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Check() error = %v, expected %q", err, tt.wantErr)
+			}
+			if !errors.Is(err, ErrPolicyDenied) {
+				t.Errorf("Check() error = %v, expected it to wrap ErrPolicyDenied", err)
+			}
+		})
+	}
+}
+
+func TestPolicyHookNil(t *testing.T) {
+	var hook *PolicyHook
+	if err := hook.Check(context.Background(), "Secret", &corev1.Secret{}, "secret/data/app", "", nil); err != nil {
+		t.Errorf("Check() on nil hook error = %v", err)
+	}
+}
@@ -0,0 +1,414 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the preflight checks run by --check.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// PreflightStatus is the outcome of a single preflight check.
+type PreflightStatus string
+
+// Preflight outcomes. Only failures make the report fail; warnings point at annotations
+// that will not behave as intended but do not block a rollout.
+const (
+	PreflightPass PreflightStatus = "PASS"
+	PreflightWarn PreflightStatus = "WARN"
+	PreflightFail PreflightStatus = "FAIL"
+)
+
+// PreflightResult is the outcome of a single preflight check.
+type PreflightResult struct {
+	Check  string
+	Status PreflightStatus
+	Detail string
+}
+
+// PreflightReport collects the results of the preflight checks.
+type PreflightReport struct {
+	Results []PreflightResult
+}
+
+// add records a check result.
+func (r *PreflightReport) add(check string, status PreflightStatus, format string, args ...interface{}) {
+	r.Results = append(r.Results, PreflightResult{Check: check, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+// Failed reports whether any check failed.
+func (r *PreflightReport) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status == PreflightFail {
+			return true
+		}
+	}
+	return false
+}
+
+// Print writes the report followed by a summary line.
+func (r *PreflightReport) Print(w io.Writer) error {
+	counts := make(map[PreflightStatus]int)
+	for _, result := range r.Results {
+		counts[result.Status]++
+		if _, err := fmt.Fprintf(w, "%-4s  %-12s %s\n", result.Status, result.Check, result.Detail); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d passed, %d warnings, %d failed\n",
+		counts[PreflightPass], counts[PreflightWarn], counts[PreflightFail])
+	return err
+}
+
+// Preflight validates a rollout before the controllers start: the RBAC permissions of the
+// operator, Vault connectivity and its role binding, the token's capabilities on the synced
+// paths and the vault-sync.io annotations in use.
+type Preflight struct {
+	// Client creates SelfSubjectAccessReviews
+	Client client.Client
+	// Reader lists the annotated resources (typically the manager's API reader)
+	Reader client.Reader
+	// Deployments and Secrets select the controllers that will run
+	Deployments bool
+	Secrets     bool
+	// Namespaces the controllers are restricted to (empty means all)
+	Namespaces []string
+	// OperatorNamespace holds the operator's Leases and sync history ConfigMaps
+	OperatorNamespace string
+	ClusterName       string
+	NamespaceMounts   NamespaceMounts
+	// VaultConfig is used to authenticate with Vault like the operator does at startup
+	VaultConfig vault.Config
+}
+
+// preflightPermission is a set of verbs the operator needs on a resource.
+type preflightPermission struct {
+	resource    schema.GroupResource
+	subresource string
+	verbs       []string
+	// operatorNamespace checks the permission in the operator namespace only
+	operatorNamespace bool
+	// name checks the permission on the named object only
+	name string
+}
+
+// knownAnnotations lists the vault-sync.io annotations read or written by the operator.
+var knownAnnotations = map[string]bool{
+	VaultPathAnnotation:                true,
+	VaultSecretsAnnotation:             true,
+	VaultPreserveOnDeleteAnnotation:    true,
+	VaultSecretVersionsAnnotation:      true,
+	VaultRotationCheckAnnotation:       true,
+	VaultReconcileAnnotation:           true,
+	VaultAbsolutePathAnnotation:        true,
+	VaultForceSyncAnnotation:           true,
+	VaultForceSyncConsumedAnnotation:   true,
+	VaultIncludeKeysAnnotation:         true,
+	VaultIgnoreContainersAnnotation:    true,
+	VaultDeletedPathAnnotation:         true,
+	VaultPriorityAnnotation:            true,
+	VaultSyncedConfigAnnotation:        true,
+	VaultRetainDeletedKeysAnnotation:   true,
+	VaultKeySanitizationAnnotation:     true,
+	VaultDiscoverFromAnnotation:        true,
+	VaultMaxVersionsAnnotation:         true,
+	VaultDeleteVersionAfterAnnotation:  true,
+	VaultRefreshHintAnnotation:         true,
+	VaultRevisionAnnotation:            true,
+	VaultRevisionHistoryAnnotation:     true,
+	VaultSyncedRevisionsAnnotation:     true,
+	VaultSyncedPathAnnotation:          true,
+	VaultSyncedAtAnnotation:            true,
+	VaultWaitForRolloutAnnotation:      true,
+	VaultKeyPrefixAnnotation:           true,
+	VaultParseJSONValuesAnnotation:     true,
+	VaultCompressValuesAnnotation:      true,
+	VaultTransactionalWritesAnnotation: true,
+}
+
+// Run performs every check and returns the report.
+func (p *Preflight) Run(ctx context.Context) *PreflightReport {
+	report := &PreflightReport{}
+	p.checkRBAC(ctx, report)
+	paths := p.checkAnnotations(ctx, report)
+	p.checkVault(ctx, report, paths)
+	return report
+}
+
+// namespaceScopes returns the namespaces to check, once each.
+func (p *Preflight) namespaceScopes() []string {
+	if len(p.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	namespaces := slices.Clone(p.Namespaces)
+	sort.Strings(namespaces)
+	return slices.Compact(namespaces)
+}
+
+// permissions returns the permissions required by the enabled controllers.
+func (p *Preflight) permissions() []preflightPermission {
+	secrets := schema.GroupResource{Resource: "secrets"}
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+	var permissions []preflightPermission
+	if p.Deployments {
+		permissions = append(permissions,
+			preflightPermission{resource: deployments, verbs: []string{"get", "list", "watch", "update", "patch"}},
+			preflightPermission{resource: deployments, subresource: "finalizers", verbs: []string{"update"}})
+		if !p.Secrets {
+			// Auto-discovery and custom configurations read the referenced Secrets
+			permissions = append(permissions, preflightPermission{resource: secrets, verbs: []string{"get"}})
+		}
+	}
+	if p.Secrets {
+		permissions = append(permissions,
+			preflightPermission{resource: secrets, verbs: []string{"get", "list", "watch", "update", "patch"}},
+			preflightPermission{resource: secrets, subresource: "finalizers", verbs: []string{"update"}})
+	}
+	if source, ok := p.VaultConfig.JWTSource.(*vault.TokenRequestJWTSource); ok {
+		// Vault login tokens are minted for the operator's service account
+		permissions = append(permissions, preflightPermission{resource: schema.GroupResource{Resource: "serviceaccounts"},
+			subresource: "token", verbs: []string{"create"}, operatorNamespace: true, name: source.ServiceAccount})
+	}
+	return append(permissions,
+		preflightPermission{resource: schema.GroupResource{Group: "events.k8s.io", Resource: "events"}, verbs: []string{"create", "patch"}},
+		preflightPermission{resource: schema.GroupResource{Group: "coordination.k8s.io", Resource: "leases"},
+			verbs: []string{"get", "create", "update"}, operatorNamespace: true},
+		preflightPermission{resource: schema.GroupResource{Resource: "configmaps"},
+			verbs: []string{"get", "create", "update"}, operatorNamespace: true})
+}
+
+// checkRBAC verifies the operator's permissions with SelfSubjectAccessReviews.
+func (p *Preflight) checkRBAC(ctx context.Context, report *PreflightReport) {
+	namespaces := p.namespaceScopes()
+
+	for _, permission := range p.permissions() {
+		scopes := namespaces
+		if permission.operatorNamespace {
+			scopes = []string{p.OperatorNamespace}
+		}
+		resource := permission.resource.String()
+		if permission.subresource != "" {
+			resource += "/" + permission.subresource
+		}
+		if permission.name != "" {
+			resource += " " + permission.name
+		}
+
+		for _, namespace := range scopes {
+			scope := "all namespaces"
+			if namespace != metav1.NamespaceAll {
+				scope = "namespace " + namespace
+			}
+
+			denied, err := p.deniedVerbs(ctx, namespace, permission)
+			switch {
+			case err != nil:
+				report.add("rbac", PreflightFail, "%s in %s: access review failed: %v", resource, scope, err)
+			case len(denied) > 0:
+				report.add("rbac", PreflightFail, "%s in %s: missing %s", resource, scope, strings.Join(denied, ", "))
+			default:
+				report.add("rbac", PreflightPass, "%s in %s: %s", resource, scope, strings.Join(permission.verbs, ", "))
+			}
+		}
+	}
+}
+
+// deniedVerbs asks the API server which verbs of permission the operator may not perform.
+func (p *Preflight) deniedVerbs(ctx context.Context, namespace string, permission preflightPermission) ([]string, error) {
+	var denied []string
+	for _, verb := range permission.verbs {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   namespace,
+					Verb:        verb,
+					Group:       permission.resource.Group,
+					Resource:    permission.resource.Resource,
+					Subresource: permission.subresource,
+					Name:        permission.name,
+				},
+			},
+		}
+		if err := p.Client.Create(ctx, review); err != nil {
+			return nil, err
+		}
+		if !review.Status.Allowed {
+			denied = append(denied, verb)
+		}
+	}
+	return denied, nil
+}
+
+// checkAnnotations lints the vault-sync.io annotations of the resources the controllers
+// will sync and returns the resolved Vault paths written directly by those resources.
+func (p *Preflight) checkAnnotations(ctx context.Context, report *PreflightReport) []string {
+	var kinds []schema.GroupVersionKind
+	if p.Deployments {
+		kinds = append(kinds, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	}
+	if p.Secrets {
+		kinds = append(kinds, schema.GroupVersionKind{Version: "v1", Kind: "Secret"})
+	}
+
+	namespaces := p.namespaceScopes()
+
+	var paths []string
+	for _, gvk := range kinds {
+		synced, problems := 0, 0
+		for _, namespace := range namespaces {
+			// Only metadata is listed, so Secret values are never read by the check
+			list := &metav1.PartialObjectMetadataList{}
+			list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+			if err := p.Reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+				report.add("annotations", PreflightFail, "failed to list %ss: %v", gvk.Kind, err)
+				continue
+			}
+
+			for i := range list.Items {
+				obj := &list.Items[i]
+				name := fmt.Sprintf("%s %s/%s", gvk.Kind, obj.Namespace, obj.Name)
+				for _, problem := range LintAnnotations(obj) {
+					problems++
+					report.add("annotations", PreflightWarn, "%s: %s", name, problem)
+				}
+
+				vaultPath := obj.Annotations[VaultPathAnnotation]
+				if vaultPath == "" {
+					continue
+				}
+				synced++
+				// Auto-discovered Secrets are written to sub-paths named after each Secret
+				if gvk.Kind == "Deployment" && obj.Annotations[VaultSecretsAnnotation] == "" {
+					continue
+				}
+				paths = append(paths, p.NamespaceMounts.ResolvePath(obj.Namespace, vaultPath, p.ClusterName, IsAbsolutePath(obj)))
+			}
+		}
+		if problems == 0 {
+			report.add("annotations", PreflightPass, "%ss: %d synced, no annotation problems", gvk.Kind, synced)
+		}
+	}
+
+	sort.Strings(paths)
+	return slices.Compact(paths)
+}
+
+// LintAnnotations returns the problems with the vault-sync.io annotations of an object.
+func LintAnnotations(obj client.Object) []string {
+	annotations := obj.GetAnnotations()
+	var problems []string
+
+	var keys []string
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if strings.HasPrefix(key, "vault-sync.io/") && !knownAnnotations[key] {
+			problems = append(problems, fmt.Sprintf("unknown annotation %s", key))
+		}
+	}
+
+	if _, ok := annotations[VaultPathAnnotation]; !ok {
+		for _, key := range keys {
+			if strings.HasPrefix(key, "vault-sync.io/") && knownAnnotations[key] {
+				problems = append(problems, fmt.Sprintf("%s has no effect without %s", key, VaultPathAnnotation))
+				break
+			}
+		}
+		return problems
+	}
+	if annotations[VaultPathAnnotation] == "" {
+		problems = append(problems, fmt.Sprintf("%s is empty, so the resource is not synced", VaultPathAnnotation))
+	}
+
+	if value, ok := annotations[VaultReconcileAnnotation]; ok && value != "" && value != "off" {
+		if _, err := time.ParseDuration(value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s %q, the default interval is used", VaultReconcileAnnotation, value))
+		}
+	}
+	if value := annotations[VaultRotationCheckAnnotation]; value != "" && value != RotationCheckEnabled && value != RotationCheckDisabled {
+		if _, err := time.ParseDuration(value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid %s %q, scheduled rotation checks are disabled", VaultRotationCheckAnnotation, value))
+		}
+	}
+	if value, ok := annotations[VaultPriorityAnnotation]; ok && GetSyncPriority(obj) != strings.ToLower(strings.TrimSpace(value)) {
+		problems = append(problems, fmt.Sprintf("unknown %s %q, normal priority is used", VaultPriorityAnnotation, value))
+	}
+	if _, err := GetKeySanitizationPolicy(obj); err != nil {
+		problems = append(problems, fmt.Sprintf("%v, the sync will fail", err))
+	}
+	if _, err := GetKVMetadata(obj); err != nil {
+		problems = append(problems, fmt.Sprintf("%v, the sync will fail", err))
+	}
+	if _, err := GetRevisionHistory(obj); err != nil {
+		problems = append(problems, fmt.Sprintf("%v, the sync will fail", err))
+	}
+	if value := annotations[VaultSecretsAnnotation]; value != "" {
+		if err := ValidateSecretsAnnotation(value); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// checkVault authenticates with Vault, checks its state and the token's capabilities on paths.
+func (p *Preflight) checkVault(ctx context.Context, report *PreflightReport, paths []string) {
+	vaultClient, err := vault.NewClientFromConfig(p.VaultConfig)
+	if err != nil {
+		report.add("vault", PreflightFail, "login with role %q at auth/%s on %s failed: %v",
+			p.VaultConfig.Role, p.VaultConfig.AuthPath, p.VaultConfig.Address, err)
+		return
+	}
+	report.add("vault", PreflightPass, "logged in with role %q at auth/%s on %s",
+		p.VaultConfig.Role, p.VaultConfig.AuthPath, p.VaultConfig.Address)
+
+	state, err := vaultClient.State(ctx)
+	switch {
+	case err != nil:
+		report.add("vault", PreflightFail, "health check failed: %v", err)
+	case state != vault.StateActive && state != vault.StateStandby:
+		report.add("vault", PreflightFail, "vault is %s", state)
+	default:
+		report.add("vault", PreflightPass, "vault is %s", state)
+	}
+
+	p.checkCapabilities(ctx, report, vaultClient, paths)
+}
+
+// checkCapabilities verifies that the Vault token can write every path.
+func (p *Preflight) checkCapabilities(ctx context.Context, report *PreflightReport, vaultClient *vault.Client, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	capabilities, err := vaultClient.Capabilities(ctx, paths)
+	if err != nil {
+		report.add("vault", PreflightFail, "%v", err)
+		return
+	}
+
+	writable := 0
+	for _, path := range paths {
+		granted := capabilities[path]
+		if canWrite(granted) {
+			writable++
+			continue
+		}
+		report.add("vault", PreflightFail, "token cannot write %s (capabilities: %s)", path, strings.Join(granted, ", "))
+	}
+	if writable == len(paths) {
+		report.add("vault", PreflightPass, "token can write all %d synced paths", len(paths))
+	}
+}
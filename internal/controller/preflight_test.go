@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+func TestLintAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+	}{
+		{
+			name:        "valid annotations",
+			annotations: map[string]string{VaultPathAnnotation: "secret/data/web", VaultReconcileAnnotation: "5m", VaultPriorityAnnotation: "high"},
+		},
+		{
+			name:        "typo in annotation name",
+			annotations: map[string]string{VaultPathAnnotation: "secret/data/web", "vault-sync.io/preserve-on-deletion": "true"},
+			expected:    []string{"unknown annotation vault-sync.io/preserve-on-deletion"},
+		},
+		{
+			name:        "annotations without path",
+			annotations: map[string]string{VaultSecretsAnnotation: "db"},
+			expected:    []string{"vault-sync.io/secrets has no effect without vault-sync.io/path"},
+		},
+		{
+			name: "invalid values",
+			annotations: map[string]string{
+				VaultPathAnnotation:        "secret/data/web",
+				VaultReconcileAnnotation:   "hourly",
+				VaultPriorityAnnotation:    "urgent",
+				VaultMaxVersionsAnnotation: "many",
+			},
+			expected: []string{
+				`invalid vault-sync.io/reconcile "hourly"`,
+				`unknown vault-sync.io/priority "urgent"`,
+				`invalid vault-sync.io/max-versions annotation "many"`,
+			},
+		},
+		{
+			name: "invalid secrets annotation",
+			annotations: map[string]string{
+				VaultPathAnnotation:    "secret/data/web",
+				VaultSecretsAnnotation: `[{"name": "db", "key": ["password"]}]`,
+			},
+			expected: []string{`invalid vault-sync.io/secrets: json: unknown field "key"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := LintAnnotations(newPatchTestDeployment(tt.annotations))
+			if len(problems) != len(tt.expected) {
+				t.Fatalf("LintAnnotations() = %v, expected %d problems", problems, len(tt.expected))
+			}
+			for i, expected := range tt.expected {
+				if !strings.Contains(problems[i], expected) {
+					t.Errorf("problem %q does not contain %q", problems[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestPreflightRun(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "payments"
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db", "vault-sync.io/priorty": "high"}
+
+	// The operator may do everything except update Secret finalizers, and mint tokens for
+	// its own service account only
+	k8sClient := fake.NewClientBuilder().
+		WithObjects(secret, newPatchTestDeployment(map[string]string{VaultPathAnnotation: "secret/data/web"})).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				review, ok := obj.(*authorizationv1.SelfSubjectAccessReview)
+				if !ok {
+					return c.Create(ctx, obj, opts...)
+				}
+				attributes := review.Spec.ResourceAttributes
+				review.Status.Allowed = !(attributes.Resource == "secrets" && attributes.Subresource == "finalizers") &&
+					!(attributes.Resource == "serviceaccounts" && attributes.Name != "vault-sync-operator-controller-manager")
+				return nil
+			},
+		}).
+		Build()
+
+	preflight := &Preflight{
+		Client:            k8sClient,
+		Reader:            k8sClient,
+		Deployments:       true,
+		Secrets:           true,
+		OperatorNamespace: "vault-sync-operator-system",
+		VaultConfig: vault.Config{Address: "http://127.0.0.1:1", Role: "vault-sync-operator", AuthPath: "kubernetes",
+			JWTSource: &vault.TokenRequestJWTSource{Client: k8sClient, Namespace: "vault-sync-operator-system",
+				ServiceAccount: "vault-sync-operator-controller-manager", Audience: "vault"}},
+	}
+	report := preflight.Run(context.Background())
+
+	var out bytes.Buffer
+	if err := report.Print(&out); err != nil {
+		t.Fatalf("Print() error = %v", err)
+	}
+	if !report.Failed() {
+		t.Errorf("report did not fail:\n%s", out.String())
+	}
+	for _, expected := range []string{
+		"PASS  rbac         deployments.apps in all namespaces: get, list, watch, update, patch",
+		"FAIL  rbac         secrets/finalizers in all namespaces: missing update",
+		"PASS  rbac         leases.coordination.k8s.io in namespace vault-sync-operator-system",
+		"PASS  rbac         serviceaccounts/token vault-sync-operator-controller-manager in namespace vault-sync-operator-system: create",
+		"WARN  annotations  Secret payments/db: unknown annotation vault-sync.io/priorty",
+		"PASS  annotations  Deployments: 1 synced, no annotation problems",
+		`FAIL  vault        login with role "vault-sync-operator" at auth/kubernetes`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("report does not contain %q:\n%s", expected, out.String())
+		}
+	}
+}
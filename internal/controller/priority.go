@@ -0,0 +1,125 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements sync priorities for queue ordering and rate-limiter sharing.
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Sync priorities accepted by the vault-sync.io/priority annotation.
+const (
+	SyncPriorityHigh   = "high"
+	SyncPriorityNormal = "normal"
+	SyncPriorityLow    = "low"
+)
+
+// Work queue priorities of the sync priorities. Normal priority keeps controller-runtime's
+// defaults, where unchanged objects from the initial list or a resync get handler.LowPriority.
+const (
+	highQueuePriority = 100
+	lowQueuePriority  = 2 * handler.LowPriority
+)
+
+// lowPriorityBackpressureShare is the fraction of the pending Vault request limit at which
+// low-priority syncs already back off.
+const lowPriorityBackpressureShare = 0.5
+
+// GetSyncPriority returns the sync priority of an object. Missing or unknown values are normal.
+func GetSyncPriority(obj client.Object) string {
+	switch value := strings.ToLower(strings.TrimSpace(obj.GetAnnotations()[VaultPriorityAnnotation])); value {
+	case SyncPriorityHigh, SyncPriorityLow:
+		return value
+	default:
+		return SyncPriorityNormal
+	}
+}
+
+// queuePriority returns the work queue priority of an object. Unchanged is set for events
+// from the initial list or a resync, which only lower the priority of normal objects so
+// high-priority objects are still synced first after a restart.
+func queuePriority(obj client.Object, unchanged bool) int {
+	switch GetSyncPriority(obj) {
+	case SyncPriorityHigh:
+		return highQueuePriority
+	case SyncPriorityLow:
+		return lowQueuePriority
+	default:
+		if unchanged {
+			return handler.LowPriority
+		}
+		return 0
+	}
+}
+
+// priorityBackpressureDelay reports whether a sync of the given priority in namespace should be
+// deferred because the Vault rate limiter is saturated. High-priority syncs are never deferred
+// and low-priority syncs are deferred once the queue is half full, in both cases only while the
+// namespace holds its fair share of the queue. Clients without a rate limiter never defer.
+func priorityBackpressureDelay(vc VaultWriterDeleter, namespace, priority string) (time.Duration, bool) {
+	reporter, ok := vc.(backpressureReporter)
+	if !ok {
+		return 0, false
+	}
+	switch priority {
+	case SyncPriorityHigh:
+		return 0, false
+	case SyncPriorityLow:
+		return reporter.NamespaceBackpressureDelayAt(namespace, lowPriorityBackpressureShare)
+	default:
+		return reporter.NamespaceBackpressureDelayAt(namespace, 1)
+	}
+}
+
+// PriorityEventHandler enqueues the object of each event with the work queue priority of its
+// vault-sync.io/priority annotation. Without a priority queue it behaves like
+// handler.EnqueueRequestForObject.
+type PriorityEventHandler struct{}
+
+var _ handler.EventHandler = PriorityEventHandler{}
+
+// Create implements handler.EventHandler.
+func (PriorityEventHandler) Create(_ context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueWithPriority(q, e.Object, e.IsInInitialList)
+}
+
+// Update implements handler.EventHandler.
+func (PriorityEventHandler) Update(_ context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	unchanged := e.ObjectOld != nil && e.ObjectNew != nil &&
+		e.ObjectOld.GetResourceVersion() == e.ObjectNew.GetResourceVersion()
+	enqueueWithPriority(q, e.ObjectNew, unchanged)
+}
+
+// Delete implements handler.EventHandler.
+func (PriorityEventHandler) Delete(_ context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueWithPriority(q, e.Object, false)
+}
+
+// Generic implements handler.EventHandler.
+func (PriorityEventHandler) Generic(_ context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueWithPriority(q, e.Object, false)
+}
+
+// enqueueWithPriority adds a request for obj to the queue, with its priority when the queue
+// is a priority queue.
+func enqueueWithPriority(q workqueue.TypedRateLimitingInterface[reconcile.Request], obj client.Object, unchanged bool) {
+	if obj == nil {
+		return
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)}
+	priorityQueue, ok := q.(priorityqueue.PriorityQueue[reconcile.Request])
+	if !ok {
+		q.Add(req)
+		return
+	}
+	priorityQueue.AddWithOpts(priorityqueue.AddOpts{Priority: ptr.To(queuePriority(obj, unchanged))}, req)
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestGetSyncPriority(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", SyncPriorityNormal},
+		{"high", SyncPriorityHigh},
+		{" Low ", SyncPriorityLow},
+		{"normal", SyncPriorityNormal},
+		{"urgent", SyncPriorityNormal},
+	}
+
+	for _, tt := range tests {
+		deployment := newPatchTestDeployment(map[string]string{VaultPriorityAnnotation: tt.value})
+		if got := GetSyncPriority(deployment); got != tt.expected {
+			t.Errorf("GetSyncPriority(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+// TestPriorityEventHandler tests that a high-priority Deployment from the initial list is
+// dequeued before normal and low-priority ones, as happens after an operator restart.
+func TestPriorityEventHandler(t *testing.T) {
+	queue := priorityqueue.New[reconcile.Request]("priority-test")
+	defer queue.ShutDown()
+
+	objects := map[string]string{"bulk": SyncPriorityLow, "web": "", "payments": SyncPriorityHigh}
+	for name, priority := range objects {
+		deployment := newPatchTestDeployment(map[string]string{VaultPriorityAnnotation: priority})
+		deployment.Name = name
+		PriorityEventHandler{}.Create(context.Background(), event.CreateEvent{Object: deployment, IsInInitialList: true}, queue)
+	}
+
+	expected := []struct {
+		name     string
+		priority int
+	}{
+		{"payments", highQueuePriority},
+		{"web", handler.LowPriority},
+		{"bulk", lowQueuePriority},
+	}
+	for _, want := range expected {
+		req, priority, _ := queue.GetWithPriority()
+		if req.Name != want.name || priority != want.priority {
+			t.Errorf("dequeued %s with priority %d, expected %s with priority %d", req.Name, priority, want.name, want.priority)
+		}
+		queue.Done(req)
+	}
+
+	// A genuine change of a normal Deployment is not lowered
+	deployment := newPatchTestDeployment(nil)
+	old := deployment.DeepCopy()
+	deployment.ResourceVersion = "2"
+	PriorityEventHandler{}.Update(context.Background(), event.UpdateEvent{ObjectOld: old, ObjectNew: deployment}, queue)
+	if _, priority, _ := queue.GetWithPriority(); priority != 0 {
+		t.Errorf("priority of a changed Deployment = %d, expected 0", priority)
+	}
+}
@@ -0,0 +1,138 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the PropagationTracker which measures how long Secret changes take to reach Vault.
+package controller
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// PropagationTracker records when a watch event reports a data change on an annotated Secret,
+// or on a Secret synced by a workload, and observes the delay until the change has been
+// written to Vault. Changes are keyed by the resource synced, the Secret or the workload,
+// so each reconciler kind needs its own tracker.
+// All methods are safe to call on a nil tracker, which disables the measurement.
+type PropagationTracker struct {
+	mu      sync.Mutex
+	pending map[types.NamespacedName]time.Time
+	now     func() time.Time
+}
+
+// NewPropagationTracker creates an empty tracker.
+func NewPropagationTracker() *PropagationTracker {
+	return &PropagationTracker{
+		pending: make(map[types.NamespacedName]time.Time),
+		now:     time.Now,
+	}
+}
+
+// Changed records that the Secret's data changed. The earliest unsynced change is kept,
+// so the measured delay covers every change folded into the next write.
+func (p *PropagationTracker) Changed(key types.NamespacedName) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, exists := p.pending[key]; !exists {
+		p.pending[key] = p.now()
+	}
+}
+
+// Synced observes the propagation delay of a pending change after a successful Vault write.
+func (p *PropagationTracker) Synced(key types.NamespacedName) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if changed, exists := p.pending[key]; exists {
+		metrics.PropagationDelay.Observe(p.now().Sub(changed).Seconds())
+		delete(p.pending, key)
+	}
+}
+
+// Forget drops a pending change that will not be written, e.g. because the Secret was deleted.
+func (p *PropagationTracker) Forget(key types.NamespacedName) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, key)
+}
+
+// Pending returns the number of changes not yet written to Vault.
+func (p *PropagationTracker) Pending() int {
+	if p == nil {
+		return 0
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending)
+}
+
+// Predicate returns a predicate that records data changes of annotated Secrets from update
+// events. It never filters events. Metadata-only updates, such as the operator's own
+// annotation updates, are not recorded.
+func (p *PropagationTracker) Predicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldSecret, okOld := e.ObjectOld.(*corev1.Secret)
+			newSecret, okNew := e.ObjectNew.(*corev1.Secret)
+			if !okOld || !okNew || newSecret.Annotations[VaultPathAnnotation] == "" {
+				return true
+			}
+			if !reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+				p.Changed(types.NamespacedName{Name: newSecret.Name, Namespace: newSecret.Namespace})
+			}
+			return true
+		},
+	}
+}
+
+// WorkloadHandler returns an event handler enqueueing the workloads mapFunc returns for a
+// Secret, recording a data change of the Secret as a pending change of each of them.
+func (p *PropagationTracker) WorkloadHandler(mapFunc handler.MapFunc) handler.EventHandler {
+	enqueue := handler.EnqueueRequestsFromMapFunc(mapFunc)
+	if p == nil {
+		return enqueue
+	}
+	return workloadPropagationHandler{EventHandler: enqueue, tracker: p, mapFunc: mapFunc}
+}
+
+// workloadPropagationHandler enqueues workloads like its EventHandler and records the data
+// changes of their Secrets.
+type workloadPropagationHandler struct {
+	handler.EventHandler
+	tracker *PropagationTracker
+	mapFunc handler.MapFunc
+}
+
+// Update implements handler.EventHandler.
+func (h workloadPropagationHandler) Update(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	oldSecret, okOld := e.ObjectOld.(*corev1.Secret)
+	newSecret, okNew := e.ObjectNew.(*corev1.Secret)
+	if okOld && okNew && !secretDataEqual(oldSecret.Data, newSecret.Data) {
+		for _, request := range h.mapFunc(ctx, newSecret) {
+			h.tracker.Changed(request.NamespacedName)
+		}
+	}
+	h.EventHandler.Update(ctx, e, q)
+}
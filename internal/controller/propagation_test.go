@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestPropagationTracker tests that pending changes are kept until synced or forgotten.
+func TestPropagationTracker(t *testing.T) {
+	tracker := NewPropagationTracker()
+	current := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return current }
+
+	key := types.NamespacedName{Name: "db-credentials", Namespace: "default"}
+	tracker.Changed(key)
+	current = current.Add(time.Second)
+	tracker.Changed(key)
+	if tracker.Pending() != 1 {
+		t.Fatalf("Expected 1 pending change, got %d", tracker.Pending())
+	}
+	if !tracker.pending[key].Equal(current.Add(-time.Second)) {
+		t.Errorf("Expected the earliest change to be kept, got %v", tracker.pending[key])
+	}
+
+	tracker.Synced(key)
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected no pending changes after sync, got %d", tracker.Pending())
+	}
+
+	tracker.Changed(key)
+	tracker.Forget(key)
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected no pending changes after forget, got %d", tracker.Pending())
+	}
+}
+
+// TestPropagationTrackerPredicate tests that only data changes of annotated Secrets are recorded.
+func TestPropagationTrackerPredicate(t *testing.T) {
+	newSecret := func(annotations map[string]string, value string) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte(value)}}
+		secret.Name = "db-credentials"
+		secret.Namespace = "default"
+		secret.Annotations = annotations
+		return secret
+	}
+	annotated := map[string]string{VaultPathAnnotation: "secret/data/db"}
+
+	tests := []struct {
+		name     string
+		old      *corev1.Secret
+		new      *corev1.Secret
+		expected int
+	}{
+		{
+			name:     "data change on annotated secret",
+			old:      newSecret(annotated, "old"),
+			new:      newSecret(annotated, "new"),
+			expected: 1,
+		},
+		{
+			name:     "metadata-only change",
+			old:      newSecret(annotated, "same"),
+			new:      newSecret(map[string]string{VaultPathAnnotation: "secret/data/db", VaultSecretVersionsAnnotation: "{}"}, "same"),
+			expected: 0,
+		},
+		{
+			name:     "data change on unannotated secret",
+			old:      newSecret(nil, "old"),
+			new:      newSecret(nil, "new"),
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewPropagationTracker()
+			if !tracker.Predicate().Update(event.UpdateEvent{ObjectOld: tt.old, ObjectNew: tt.new}) {
+				t.Errorf("Expected predicate not to filter events")
+			}
+			if tracker.Pending() != tt.expected {
+				t.Errorf("Pending() = %d, expected %d", tracker.Pending(), tt.expected)
+			}
+		})
+	}
+}
+
+// TestPropagationTrackerNil tests that a nil tracker is a no-op.
+func TestPropagationTrackerNil(t *testing.T) {
+	var tracker *PropagationTracker
+	key := types.NamespacedName{Name: "db-credentials", Namespace: "default"}
+	tracker.Changed(key)
+	tracker.Synced(key)
+	tracker.Forget(key)
+	if tracker.Pending() != 0 {
+		t.Errorf("Expected nil tracker to have no pending changes")
+	}
+}
+
+// TestPropagationWorkloadHandler tests that data changes of a Secret are recorded for the
+// workloads syncing it, which are enqueued either way.
+func TestPropagationWorkloadHandler(t *testing.T) {
+	tracker := NewPropagationTracker()
+	workload := types.NamespacedName{Name: "web", Namespace: "default"}
+	eventHandler := tracker.WorkloadHandler(func(_ context.Context, _ client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: workload}}
+	})
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+
+	newSecret := func(value string) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte(value)}}
+		secret.Name = "db-credentials"
+		secret.Namespace = "default"
+		return secret
+	}
+
+	eventHandler.Update(context.Background(), event.UpdateEvent{ObjectOld: newSecret("same"), ObjectNew: newSecret("same")}, queue)
+	if tracker.Pending() != 0 || queue.Len() != 1 {
+		t.Fatalf("pending = %d, queued = %d after a metadata-only change, expected 0 and 1", tracker.Pending(), queue.Len())
+	}
+
+	eventHandler.Update(context.Background(), event.UpdateEvent{ObjectOld: newSecret("old"), ObjectNew: newSecret("new")}, queue)
+	if tracker.Pending() != 1 {
+		t.Errorf("pending = %d after a data change, expected 1", tracker.Pending())
+	}
+	tracker.Synced(workload)
+	if tracker.Pending() != 0 {
+		t.Errorf("pending = %d after the workload synced, expected 0", tracker.Pending())
+	}
+}
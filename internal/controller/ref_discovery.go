@@ -0,0 +1,164 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements discovery of Secrets referenced by objects other than the pod template,
+// such as the TLS certificates of Ingresses and Gateways.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// VaultDiscoverFromAnnotation lists objects in the Deployment's namespace whose Secret
+// references are auto-discovered alongside the pod template, e.g. "ingress/web,gateway/edge".
+const VaultDiscoverFromAnnotation = "vault-sync.io/discover-from"
+
+// Kinds of objects that can be listed in the discover-from annotation.
+const (
+	// DiscoverFromIngress follows the spec.tls[].secretName fields of an Ingress
+	DiscoverFromIngress = "ingress"
+	// DiscoverFromGateway follows the listener certificateRefs of a Gateway API Gateway
+	DiscoverFromGateway = "gateway"
+)
+
+// gatewayGVK identifies Gateway API Gateways, read as unstructured objects so that the
+// operator does not depend on the Gateway API types or require its CRDs to be installed.
+var gatewayGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+
+// DiscoveryRef names an object whose Secret references are followed during auto-discovery.
+type DiscoveryRef struct {
+	Kind string
+	Name string
+}
+
+// ParseDiscoverFrom parses a comma-separated list of <kind>/<name> references.
+func ParseDiscoverFrom(value string) ([]DiscoveryRef, error) {
+	var refs []DiscoveryRef
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, name, ok := strings.Cut(entry, "/")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid discover-from entry %q, expected <kind>/<name>", entry)
+		}
+		if kind != DiscoverFromIngress && kind != DiscoverFromGateway {
+			return nil, fmt.Errorf("unsupported discover-from kind %q, expected %s or %s", kind, DiscoverFromIngress, DiscoverFromGateway)
+		}
+		refs = append(refs, DiscoveryRef{Kind: kind, Name: name})
+	}
+	return refs, nil
+}
+
+// IngressSecretNames returns the TLS Secrets referenced by an Ingress.
+func IngressSecretNames(ingress *networkingv1.Ingress) []string {
+	var names []string
+	for _, tls := range ingress.Spec.TLS {
+		if tls.SecretName != "" {
+			names = append(names, tls.SecretName)
+		}
+	}
+	return names
+}
+
+// GatewaySecretNames returns the Secrets in the Gateway's own namespace referenced by the
+// certificateRefs of its listeners. References to other kinds or namespaces are ignored.
+func GatewaySecretNames(gateway *unstructured.Unstructured) []string {
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+
+	var names []string
+	for _, listener := range listeners {
+		listenerMap, ok := listener.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		certificateRefs, _, _ := unstructured.NestedSlice(listenerMap, "tls", "certificateRefs")
+		for _, ref := range certificateRefs {
+			refMap, ok := ref.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(refMap, "group")
+			kind, _, _ := unstructured.NestedString(refMap, "kind")
+			namespace, _, _ := unstructured.NestedString(refMap, "namespace")
+			name, _, _ := unstructured.NestedString(refMap, "name")
+
+			// The group defaults to core and the kind to Secret
+			if group != "" || (kind != "" && kind != "Secret") {
+				continue
+			}
+			if namespace != "" && namespace != gateway.GetNamespace() {
+				continue
+			}
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// discoverReferencedSecrets returns the names of the Secrets referenced by the objects
+// listed in the Deployment's discover-from annotation.
+func (r *DeploymentReconciler) discoverReferencedSecrets(ctx context.Context, deployment client.Object) (map[string]bool, error) {
+	value := deployment.GetAnnotations()[VaultDiscoverFromAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+
+	refs, err := ParseDiscoverFrom(value)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(deployment.GetNamespace(), metrics.ResourceLabel(deployment.GetNamespace(), deployment.GetName()), "discover_from_error").Inc()
+		return nil, err
+	}
+
+	// Referenced objects are read uncached so the operator does not watch every
+	// Ingress and Gateway in the cluster
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+
+	secretNames := make(map[string]bool)
+	for _, ref := range refs {
+		key := types.NamespacedName{Namespace: deployment.GetNamespace(), Name: ref.Name}
+
+		var names []string
+		switch ref.Kind {
+		case DiscoverFromIngress:
+			ingress := &networkingv1.Ingress{}
+			if err := reader.Get(ctx, key, ingress); err != nil {
+				return nil, fmt.Errorf("failed to get ingress %s: %w", ref.Name, err)
+			}
+			names = IngressSecretNames(ingress)
+		case DiscoverFromGateway:
+			gateway := &unstructured.Unstructured{}
+			gateway.SetGroupVersionKind(gatewayGVK)
+			if err := reader.Get(ctx, key, gateway); err != nil {
+				if meta.IsNoMatchError(err) {
+					return nil, fmt.Errorf("failed to get gateway %s: Gateway API is not installed: %w", ref.Name, err)
+				}
+				return nil, fmt.Errorf("failed to get gateway %s: %w", ref.Name, err)
+			}
+			names = GatewaySecretNames(gateway)
+		}
+
+		for _, name := range names {
+			secretNames[name] = true
+		}
+	}
+	return secretNames, nil
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseDiscoverFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []DiscoveryRef
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			value:    "",
+			expected: nil,
+		},
+		{
+			name:  "ingress and gateway",
+			value: "ingress/web, Gateway/edge",
+			expected: []DiscoveryRef{
+				{Kind: DiscoverFromIngress, Name: "web"},
+				{Kind: DiscoverFromGateway, Name: "edge"},
+			},
+		},
+		{
+			name:    "missing name",
+			value:   "ingress/",
+			wantErr: true,
+		},
+		{
+			name:    "missing kind separator",
+			value:   "web",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported kind",
+			value:   "service/web",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, err := ParseDiscoverFrom(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDiscoverFrom() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(refs, tt.expected) {
+				t.Errorf("ParseDiscoverFrom() = %v, expected %v", refs, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGatewaySecretNames(t *testing.T) {
+	gateway := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{
+					"name": "https",
+					"tls": map[string]interface{}{
+						"certificateRefs": []interface{}{
+							map[string]interface{}{"name": "edge-cert"},
+							map[string]interface{}{"kind": "Secret", "group": "", "name": "edge-cert-ecdsa", "namespace": "default"},
+							map[string]interface{}{"kind": "Secret", "name": "other-ns-cert", "namespace": "infra"},
+							map[string]interface{}{"kind": "ConfigMap", "name": "not-a-secret"},
+						},
+					},
+				},
+				map[string]interface{}{"name": "http"},
+			},
+		},
+	}}
+	gateway.SetNamespace("default")
+
+	expected := []string{"edge-cert", "edge-cert-ecdsa"}
+	if names := GatewaySecretNames(gateway); !reflect.DeepEqual(names, expected) {
+		t.Errorf("GatewaySecretNames() = %v, expected %v", names, expected)
+	}
+}
+
+func TestDiscoverReferencedSecrets(t *testing.T) {
+	ingress := &networkingv1.Ingress{}
+	ingress.Name = "web"
+	ingress.Namespace = "default"
+	ingress.Spec.TLS = []networkingv1.IngressTLS{
+		{Hosts: []string{"example.com"}, SecretName: "web-tls"},
+		{Hosts: []string{"www.example.com"}},
+	}
+
+	r := &DeploymentReconciler{
+		Client: fake.NewClientBuilder().WithObjects(ingress).Build(),
+		Log:    logr.Discard(),
+	}
+
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultDiscoverFromAnnotation: "ingress/web"}
+
+	names, err := r.discoverReferencedSecrets(context.Background(), deployment)
+	if err != nil {
+		t.Fatalf("discoverReferencedSecrets() error = %v", err)
+	}
+	if !reflect.DeepEqual(names, map[string]bool{"web-tls": true}) {
+		t.Errorf("discoverReferencedSecrets() = %v", names)
+	}
+
+	deployment.Annotations[VaultDiscoverFromAnnotation] = "ingress/missing"
+	if _, err := r.discoverReferencedSecrets(context.Background(), deployment); err == nil {
+		t.Error("expected an error for a missing ingress")
+	}
+}
@@ -0,0 +1,103 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the RequeueStagger which spreads reconciles of many objects over time.
+package controller
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// RequeueStagger spreads the first sync of every object after startup, and its periodic
+// requeues, over a window using an offset derived from a hash of the object's key. After a
+// long downtime every object is otherwise due at once, and objects synced together keep
+// requeueing together, sending Vault a synchronized burst each interval. The offsets are
+// stable, so the load stays spread across restarts. All methods are safe to call on a nil
+// stagger, which disables staggering.
+type RequeueStagger struct {
+	// Window is the largest offset added to a sync
+	Window time.Duration
+
+	mu sync.Mutex
+	// start is when the first object was synced, so time spent waiting for leader election
+	// does not count towards the window
+	start time.Time
+	// staggered holds the objects whose first sync has been deferred to their offset
+	staggered map[string]struct{}
+}
+
+// NewRequeueStagger creates a stagger spreading syncs over window.
+func NewRequeueStagger(window time.Duration) *RequeueStagger {
+	return &RequeueStagger{
+		Window:    window,
+		staggered: make(map[string]struct{}),
+	}
+}
+
+// Offset returns the stable offset of key within the window.
+func (s *RequeueStagger) Offset(key string) time.Duration {
+	if s == nil || s.Window <= 0 {
+		return 0
+	}
+	return staggerOffset(key, s.Window)
+}
+
+// staggerOffset maps key to an offset in [0, window).
+func staggerOffset(key string, window time.Duration) time.Duration {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return time.Duration(h.Sum64() % uint64(window))
+}
+
+// InitialDelay returns how long the first sync of key after startup must wait to reach its
+// offset in the window, which starts with the first call. It returns 0 once the object has been deferred and for every object
+// after the window has passed, so syncs of objects created later are not delayed.
+func (s *RequeueStagger) InitialDelay(key string) time.Duration {
+	return s.initialDelayAt(key, time.Now())
+}
+
+// initialDelayAt implements InitialDelay for the given time.
+func (s *RequeueStagger) initialDelayAt(key string, now time.Time) time.Duration {
+	if s == nil || s.Window <= 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if !now.Before(s.start.Add(s.Window)) {
+		// Nothing is deferred any more; release the tracking set
+		s.staggered = nil
+		return 0
+	}
+	if _, deferred := s.staggered[key]; deferred {
+		return 0
+	}
+	if s.staggered == nil {
+		s.staggered = make(map[string]struct{})
+	}
+	s.staggered[key] = struct{}{}
+	if delay := s.start.Add(staggerOffset(key, s.Window)).Sub(now); delay > 0 {
+		return delay
+	}
+	return 0
+}
+
+// Periodic adds the offset of key to a periodic requeue interval. The offset is capped at a
+// tenth of the interval, so short intervals are not stretched noticeably.
+func (s *RequeueStagger) Periodic(key string, interval time.Duration) time.Duration {
+	if s == nil || s.Window <= 0 || interval <= 0 {
+		return interval
+	}
+	window := s.Window
+	if limit := interval / 10; limit < window {
+		window = limit
+	}
+	if window <= 0 {
+		return interval
+	}
+	return interval + staggerOffset(key, window)
+}
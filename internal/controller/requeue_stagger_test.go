@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestRequeueStaggerInitialDelay tests that first syncs wait for their offset once, within the window.
+func TestRequeueStaggerInitialDelay(t *testing.T) {
+	s := NewRequeueStagger(time.Minute)
+	start := time.Now()
+	key := "deployment/default/app"
+
+	delay := s.initialDelayAt(key, start)
+	if delay != s.Offset(key) {
+		t.Errorf("Expected first sync to wait for its offset %v, got %v", s.Offset(key), delay)
+	}
+	if again := s.initialDelayAt(key, start.Add(time.Second)); again != 0 {
+		t.Errorf("Expected the deferred sync not to be delayed again, got %v", again)
+	}
+
+	// Objects first seen later in the window wait only for the rest of their offset
+	other := "secret/default/db"
+	want := s.Offset(other) - 10*time.Second
+	if want < 0 {
+		want = 0
+	}
+	if got := s.initialDelayAt(other, start.Add(10*time.Second)); got != want {
+		t.Errorf("Expected delay %v for an object seen 10s into the window, got %v", want, got)
+	}
+
+	if got := s.initialDelayAt("deployment/default/late", start.Add(time.Minute)); got != 0 {
+		t.Errorf("Expected no delay after the window, got %v", got)
+	}
+}
+
+// TestRequeueStaggerSpread tests that offsets are stable and spread over the window.
+func TestRequeueStaggerSpread(t *testing.T) {
+	s := NewRequeueStagger(time.Minute)
+
+	buckets := make(map[time.Duration]int)
+	for i := 0; i < 600; i++ {
+		key := fmt.Sprintf("deployment/team-%d/app-%d", i%7, i)
+		offset := s.Offset(key)
+		if offset < 0 || offset >= time.Minute {
+			t.Fatalf("Offset %v of %s outside the window", offset, key)
+		}
+		if offset != s.Offset(key) {
+			t.Fatalf("Expected a stable offset for %s", key)
+		}
+		buckets[offset/(10*time.Second)]++
+	}
+	for bucket := time.Duration(0); bucket < 6; bucket++ {
+		if buckets[bucket] < 50 {
+			t.Errorf("Expected offsets spread over the window, bucket %d has %d of 600", bucket, buckets[bucket])
+		}
+	}
+}
+
+// TestRequeueStaggerPeriodic tests the offset added to periodic requeues.
+func TestRequeueStaggerPeriodic(t *testing.T) {
+	s := NewRequeueStagger(time.Minute)
+	key := "secret/default/db"
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		maxExtra time.Duration
+	}{
+		{"long interval uses the full window", time.Hour, time.Minute},
+		{"short interval is capped at a tenth", 5 * time.Minute, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := s.Periodic(key, tt.interval)
+			if got < tt.interval || got >= tt.interval+tt.maxExtra {
+				t.Errorf("Expected requeue in [%v, %v), got %v", tt.interval, tt.interval+tt.maxExtra, got)
+			}
+		})
+	}
+
+	if got := s.Periodic(key, 0); got != 0 {
+		t.Errorf("Expected a disabled interval to stay disabled, got %v", got)
+	}
+}
+
+// TestRequeueStaggerNil tests that a nil stagger disables staggering.
+func TestRequeueStaggerNil(t *testing.T) {
+	var s *RequeueStagger
+
+	if delay := s.InitialDelay("deployment/default/app"); delay != 0 {
+		t.Errorf("Expected nil stagger not to delay syncs, got %v", delay)
+	}
+	if got := s.Periodic("deployment/default/app", time.Minute); got != time.Minute {
+		t.Errorf("Expected nil stagger not to change requeues, got %v", got)
+	}
+}
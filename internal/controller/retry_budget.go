@@ -0,0 +1,264 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the per-resource retry budget that isolates repeatedly failing syncs.
+package controller
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// DefaultFailureWindow is how long a resource must keep failing before it is degraded.
+const DefaultFailureWindow = 15 * time.Minute
+
+// DefaultDegradedRetryInterval is how long a degraded resource waits between sync attempts.
+const DefaultDegradedRetryInterval = 30 * time.Minute
+
+// SkipReasonDegraded counts syncs deferred while a resource is degraded.
+const SkipReasonDegraded = "degraded"
+
+// DegradedResource is a resource backed off by the retry budget, shown on the status page.
+type DegradedResource struct {
+	Resource  string    `json:"resource"`
+	Failures  int       `json:"failures"`
+	Since     time.Time `json:"since"`
+	NextRetry time.Time `json:"nextRetry"`
+}
+
+// retryState is the failure record of one resource.
+type retryState struct {
+	failures    int
+	fingerprint string
+	first       time.Time
+	degraded    bool
+	since       time.Time
+	until       time.Time
+}
+
+// RetryBudget counts the consecutive sync failures of each resource. Once a resource failed
+// Threshold times in a row over at least Window it is degraded: it is retried every Interval
+// instead of at the work queue's back-off, so a few misconfigured resources cannot take most
+// of the shared Vault rate limit from the healthy ones. The window keeps the quick retries of
+// the back-off from degrading a resource over a short-lived problem. A change to the
+// resource's configuration, or a Release after a change it depends on, retries it
+// immediately, and a successful sync restores it. All methods are safe to call on a nil
+// budget, which never degrades a resource.
+type RetryBudget struct {
+	// Threshold is the number of consecutive failures that degrades a resource
+	Threshold int
+	// Window is how long a resource must keep failing before it is degraded
+	Window time.Duration
+	// Interval is how long a degraded resource waits between sync attempts
+	Interval time.Duration
+
+	mu        sync.Mutex
+	resources map[string]*retryState
+}
+
+// NewRetryBudget creates a budget degrading resources after threshold consecutive failures
+// spanning at least window, and retrying them every interval. It returns nil, disabling the
+// budget, when threshold is not positive.
+func NewRetryBudget(threshold int, window, interval time.Duration) *RetryBudget {
+	if threshold <= 0 {
+		return nil
+	}
+	if window < 0 {
+		window = 0
+	}
+	if interval <= 0 {
+		interval = DefaultDegradedRetryInterval
+	}
+	return &RetryBudget{
+		Threshold: threshold,
+		Window:    window,
+		Interval:  interval,
+		resources: make(map[string]*retryState),
+	}
+}
+
+// RetryFingerprint identifies the configuration a sync of obj was attempted with: its
+// generation, or its resource version for kinds without one such as Secrets, together with
+// the annotations that select what is synced and where.
+func RetryFingerprint(obj client.Object) string {
+	version := obj.GetResourceVersion()
+	if generation := obj.GetGeneration(); generation > 0 {
+		version = strconv.FormatInt(generation, 10)
+	}
+	return version + "/" + obj.GetAnnotations()[VaultPathAnnotation] + "/" + SyncConfigHash(obj)
+}
+
+// Hold returns how long the degraded resource key must still wait before its next sync, or
+// 0 when it is not degraded, its interval has passed or its fingerprint changed since it
+// was degraded.
+func (b *RetryBudget) Hold(key, fingerprint string) time.Duration {
+	return b.holdAt(key, fingerprint, time.Now())
+}
+
+// holdAt implements Hold for the given time.
+func (b *RetryBudget) holdAt(key, fingerprint string, now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.resources[key]
+	if !ok || !state.degraded || state.fingerprint != fingerprint {
+		return 0
+	}
+	return max(state.until.Sub(now), 0)
+}
+
+// Release lets the next sync of the degraded resource key run right away, e.g. after a
+// Secret it references changed, which its fingerprint does not cover. Its failures are
+// kept, so a sync that still fails degrades it again at once.
+func (b *RetryBudget) Release(key string) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if state, ok := b.resources[key]; ok {
+		state.until = time.Time{}
+	}
+}
+
+// Failure records a failed sync of key and reports whether the resource is degraded, and
+// whether this failure degraded it. Each failure of a degraded resource restarts its
+// interval.
+func (b *RetryBudget) Failure(key, fingerprint string) (degraded, newlyDegraded bool) {
+	return b.failureAt(key, fingerprint, time.Now())
+}
+
+// failureAt implements Failure for the given time.
+func (b *RetryBudget) failureAt(key, fingerprint string, now time.Time) (degraded, newlyDegraded bool) {
+	if b == nil {
+		return false, false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.resources[key]
+	if !ok {
+		state = &retryState{first: now}
+		b.resources[key] = state
+	}
+	state.failures++
+	state.fingerprint = fingerprint
+	if state.degraded {
+		state.until = now.Add(b.Interval)
+		return true, false
+	}
+	if state.failures < b.Threshold || now.Sub(state.first) < b.Window {
+		return false, false
+	}
+	state.degraded = true
+	state.since = now
+	state.until = now.Add(b.Interval)
+	b.updateGauge()
+	return true, true
+}
+
+// Success records a successful sync of key, resetting its failures, and reports whether the
+// resource was degraded.
+func (b *RetryBudget) Success(key string) (recovered bool) {
+	return b.Forget(key)
+}
+
+// Forget drops the failure record of key, e.g. when the resource is deleted, and reports
+// whether the resource was degraded.
+func (b *RetryBudget) Forget(key string) (wasDegraded bool) {
+	if b == nil {
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.resources[key]
+	if !ok {
+		return false
+	}
+	delete(b.resources, key)
+	if !state.degraded {
+		return false
+	}
+	b.updateGauge()
+	return true
+}
+
+// Degraded returns the degraded resources, sorted by key.
+func (b *RetryBudget) Degraded() []DegradedResource {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var degraded []DegradedResource
+	for key, state := range b.resources {
+		if state.degraded {
+			degraded = append(degraded, DegradedResource{
+				Resource:  key,
+				Failures:  state.failures,
+				Since:     state.since.UTC(),
+				NextRetry: state.until.UTC(),
+			})
+		}
+	}
+	sort.Slice(degraded, func(i, j int) bool { return degraded[i].Resource < degraded[j].Resource })
+	return degraded
+}
+
+// countsAgainstBudget reports whether a sync failure is attributed to the resource. Sealed,
+// unreachable or throttling Vault servers fail every resource alike and are not counted, so
+// an outage does not degrade the resources that are healthy.
+func countsAgainstBudget(err error) bool {
+	return !vault.IsSealed(err) && !vault.IsConnectionError(err) && !vault.IsThrottled(err)
+}
+
+// degradeOnFailure records the failed sync of obj in budget and returns the interval to
+// retry it at once the budget is exhausted, emitting a SyncDegraded event when that happens.
+func degradeOnFailure(budget *RetryBudget, recorder events.EventRecorder, obj client.Object, key string, err error) (time.Duration, bool) {
+	if budget == nil || !countsAgainstBudget(err) {
+		return 0, false
+	}
+	degraded, newlyDegraded := budget.Failure(key, RetryFingerprint(obj))
+	if !degraded {
+		return 0, false
+	}
+	if newlyDegraded {
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "SyncDegraded", "Sync",
+			"Sync failed at least %d times in a row over %s, retrying every %s until it succeeds or its configuration changes: %v",
+			budget.Threshold, budget.Window, budget.Interval, err)
+	}
+	return budget.Interval, true
+}
+
+// restoreOnSuccess resets the failures of obj after a successful sync, emitting a
+// SyncRecovered event when it was degraded.
+func restoreOnSuccess(budget *RetryBudget, recorder events.EventRecorder, obj client.Object, key string) {
+	if budget.Success(key) {
+		recordEvent(recorder, obj, corev1.EventTypeNormal, "SyncRecovered", "Sync",
+			"Sync succeeded after being degraded, retrying at the normal rate again")
+	}
+}
+
+// updateGauge sets the degraded resources gauge. The caller must hold the lock.
+func (b *RetryBudget) updateGauge() {
+	count := 0
+	for _, state := range b.resources {
+		if state.degraded {
+			count++
+		}
+	}
+	metrics.DegradedResources.Set(float64(count))
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+)
+
+func TestRetryBudget(t *testing.T) {
+	budget := NewRetryBudget(3, 10*time.Minute, time.Hour)
+	start := time.Date(2026, 1, 1, 11, 50, 0, 0, time.UTC)
+	now := start.Add(10 * time.Minute)
+	key := "deployment/default/app"
+
+	// Failures in quick succession do not degrade a resource before the window has passed
+	for i := 0; i < 3; i++ {
+		if degraded, _ := budget.failureAt(key, "1", start.Add(time.Duration(i)*time.Second)); degraded {
+			t.Fatalf("degraded after %d failures within the window", i+1)
+		}
+	}
+	if delay := budget.holdAt(key, "1", now); delay != 0 {
+		t.Errorf("holdAt() before degrading = %v, expected 0", delay)
+	}
+
+	degraded, newlyDegraded := budget.failureAt(key, "1", now)
+	if !degraded || !newlyDegraded {
+		t.Fatalf("failureAt() = %v, %v after failing for the window, expected true, true", degraded, newlyDegraded)
+	}
+	if degraded, newlyDegraded := budget.failureAt(key, "1", now); !degraded || newlyDegraded {
+		t.Errorf("failureAt() = %v, %v when already degraded, expected true, false", degraded, newlyDegraded)
+	}
+
+	if delay := budget.holdAt(key, "1", now.Add(10*time.Minute)); delay != 50*time.Minute {
+		t.Errorf("holdAt() = %v, expected 50m", delay)
+	}
+	if delay := budget.holdAt(key, "2", now.Add(10*time.Minute)); delay != 0 {
+		t.Errorf("holdAt() with a changed fingerprint = %v, expected 0", delay)
+	}
+	if delay := budget.holdAt(key, "1", now.Add(2*time.Hour)); delay != 0 {
+		t.Errorf("holdAt() after the interval = %v, expected 0", delay)
+	}
+
+	degradedResources := budget.Degraded()
+	if len(degradedResources) != 1 || degradedResources[0].Resource != key || degradedResources[0].Failures != 5 {
+		t.Errorf("Degraded() = %+v, expected %s with 5 failures", degradedResources, key)
+	}
+
+	// A released resource is retried right away, and degraded again by its next failure
+	budget.Release(key)
+	if delay := budget.holdAt(key, "1", now.Add(10*time.Minute)); delay != 0 {
+		t.Errorf("holdAt() after Release() = %v, expected 0", delay)
+	}
+	if degraded, newlyDegraded := budget.failureAt(key, "1", now.Add(10*time.Minute)); !degraded || newlyDegraded {
+		t.Errorf("failureAt() = %v, %v after Release(), expected true, false", degraded, newlyDegraded)
+	}
+
+	if !budget.Success(key) {
+		t.Error("Success() = false for a degraded resource, expected true")
+	}
+	if budget.Success(key) {
+		t.Error("Success() = true for a recovered resource, expected false")
+	}
+	if len(budget.Degraded()) != 0 {
+		t.Errorf("Degraded() = %+v after recovery, expected none", budget.Degraded())
+	}
+}
+
+func TestRetryBudgetDisabled(t *testing.T) {
+	budget := NewRetryBudget(0, 0, time.Hour)
+	if budget != nil {
+		t.Fatalf("NewRetryBudget(0) = %+v, expected nil", budget)
+	}
+	for i := 0; i < 20; i++ {
+		if degraded, _ := budget.Failure("secret/default/db", ""); degraded {
+			t.Fatal("nil budget degraded a resource")
+		}
+	}
+	budget.Release("secret/default/db")
+	if budget.Hold("secret/default/db", "") != 0 || budget.Success("secret/default/db") || budget.Degraded() != nil {
+		t.Error("nil budget reported state")
+	}
+}
+
+func TestRetryFingerprint(t *testing.T) {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{
+		Generation:      1,
+		ResourceVersion: "100",
+		Annotations:     map[string]string{VaultPathAnnotation: "secret/data/app"},
+	}}
+	fingerprint := RetryFingerprint(deployment)
+
+	deployment.ResourceVersion = "101"
+	if RetryFingerprint(deployment) != fingerprint {
+		t.Error("a status update changed the fingerprint of a Deployment")
+	}
+	deployment.Annotations[VaultSecretsAnnotation] = "db"
+	if RetryFingerprint(deployment) == fingerprint {
+		t.Error("a changed secrets annotation did not change the fingerprint")
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "100"}}
+	fingerprint = RetryFingerprint(secret)
+	secret.ResourceVersion = "101"
+	if RetryFingerprint(secret) == fingerprint {
+		t.Error("an updated Secret did not change the fingerprint")
+	}
+}
+
+func TestDegradeOnFailure(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		degraded bool
+	}{
+		{name: "permission denied", err: &api.ResponseError{StatusCode: http.StatusForbidden}, degraded: true},
+		{name: "other error", err: errors.New("secret not found"), degraded: true},
+		{name: "sealed", err: &api.ResponseError{StatusCode: http.StatusServiceUnavailable}},
+		{name: "throttled", err: &api.ResponseError{StatusCode: http.StatusTooManyRequests}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewRetryBudget(2, 0, time.Hour)
+			recorder := events.NewFakeRecorder(10)
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}}
+			key := WarmupKey("secret", secret)
+
+			var delay time.Duration
+			var degraded bool
+			for i := 0; i < 3; i++ {
+				delay, degraded = degradeOnFailure(budget, recorder, secret, key, tt.err)
+			}
+			if degraded != tt.degraded {
+				t.Fatalf("degradeOnFailure() degraded = %v, expected %v", degraded, tt.degraded)
+			}
+			if degraded && delay != time.Hour {
+				t.Errorf("degradeOnFailure() delay = %v, expected 1h", delay)
+			}
+
+			restoreOnSuccess(budget, recorder, secret, key)
+			var got []string
+			for len(recorder.Events) > 0 {
+				got = append(got, <-recorder.Events)
+			}
+			if !tt.degraded {
+				if len(got) != 0 {
+					t.Errorf("events = %v, expected none", got)
+				}
+				return
+			}
+			if len(got) != 2 || !strings.Contains(got[0], "SyncDegraded") || !strings.Contains(got[1], "SyncRecovered") {
+				t.Errorf("events = %v, expected one SyncDegraded and one SyncRecovered", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements per-revision Vault paths for Deployments.
+package controller
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/dump"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Per-revision path annotations.
+const (
+	VaultRevisionAnnotation        = "vault-sync.io/revision"         // pod-template-hash or a literal revision appended to the path
+	VaultRevisionHistoryAnnotation = "vault-sync.io/revision-history" // Previous revisions kept in Vault (default 1)
+	VaultSyncedRevisionsAnnotation = "vault-sync.io/synced-revisions" // Revisions written to Vault, oldest first
+)
+
+// RevisionPodTemplateHash selects the pod-template-hash of the current pod template as revision.
+const RevisionPodTemplateHash = "pod-template-hash"
+
+// DefaultRevisionHistory is the number of previous revisions kept in Vault, so a rollback or
+// an ongoing rollout still finds the secrets of the previous revision.
+const DefaultRevisionHistory = 1
+
+// PodTemplateHash computes the pod-template-hash label the Deployment controller gives the
+// ReplicaSet of a pod template, so a revision path matches the ReplicaSet it is used by.
+func PodTemplateHash(template corev1.PodTemplateSpec, collisionCount *int32) string {
+	hasher := fnv.New32a()
+	fmt.Fprintf(hasher, "%v", dump.ForHash(template))
+	if collisionCount != nil {
+		collisionCountBytes := make([]byte, 8)
+		binary.LittleEndian.PutUint32(collisionCountBytes, uint32(*collisionCount)) //nolint:gosec // Mirrors the Deployment controller
+		_, _ = hasher.Write(collisionCountBytes)
+	}
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// GetRevisionHistory returns the number of previous revisions kept in Vault.
+func GetRevisionHistory(obj client.Object) (int, error) {
+	value, ok := obj.GetAnnotations()[VaultRevisionHistoryAnnotation]
+	if !ok {
+		return DefaultRevisionHistory, nil
+	}
+	history, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || history < 0 {
+		return 0, fmt.Errorf("invalid %s annotation %q: expected a non-negative number", VaultRevisionHistoryAnnotation, value)
+	}
+	return history, nil
+}
+
+// GetSyncedRevisions returns the revisions written to Vault for an object, oldest first.
+func GetSyncedRevisions(obj client.Object) []string {
+	var revisions []string
+	for _, revision := range strings.Split(obj.GetAnnotations()[VaultSyncedRevisionsAnnotation], ",") {
+		if revision = strings.TrimSpace(revision); revision != "" {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions
+}
+
+// getRevision returns the revision the secrets of a workload are written under, or an empty
+// string when vault-sync.io/revision is not set.
+func (r *DeploymentReconciler) getRevision(deployment client.Object) (string, error) {
+	revision := strings.TrimSpace(deployment.GetAnnotations()[VaultRevisionAnnotation])
+	if revision == "" {
+		return "", nil
+	}
+	if revision != RevisionPodTemplateHash {
+		if strings.ContainsAny(revision, "/,") || revision == "." || revision == ".." {
+			return "", fmt.Errorf("invalid %s annotation %q: a revision must not contain '/' or ','", VaultRevisionAnnotation, revision)
+		}
+		return revision, nil
+	}
+
+	template, err := r.podTemplate(deployment)
+	if err != nil {
+		return "", err
+	}
+	var collisionCount *int32
+	switch workload := deployment.(type) {
+	case *appsv1.Deployment:
+		collisionCount = workload.Status.CollisionCount
+	case *unstructured.Unstructured:
+		if count, found, _ := unstructured.NestedInt64(workload.Object, "status", "collisionCount"); found {
+			count32 := int32(count) //nolint:gosec // collisionCount is an int32 field
+			collisionCount = &count32
+		}
+	}
+	return PodTemplateHash(template, collisionCount), nil
+}
+
+// revisionPaths returns the Vault paths written for one revision of a workload. Shared
+// secrets are not per revision, so no paths are returned for them.
+func (r *DeploymentReconciler) revisionPaths(deployment client.Object, basePath, revision string, secretNames []string) []string {
+	revisionPath := basePath + "/" + revision
+	if _, flat := GetKeyPrefix(deployment); flat || deployment.GetAnnotations()[VaultSecretsAnnotation] != "" {
+		return []string{revisionPath}
+	}
+	if r.SharedSecrets != nil {
+		return nil
+	}
+	paths := make([]string, 0, len(secretNames))
+	for _, secretName := range secretNames {
+		paths = append(paths, r.autoDiscoveredSecretPath(deployment, revisionPath, secretName))
+	}
+	return paths
+}
+
+// deleteRevisions deletes the Vault paths of the given revisions and returns the revisions
+// that could not be deleted.
+func (r *DeploymentReconciler) deleteRevisions(ctx context.Context, deployment client.Object, basePath string, revisions, secretNames []string, log logr.Logger) []string {
+	var failed []string
+	for _, revision := range revisions {
+		for _, path := range r.revisionPaths(deployment, basePath, revision, secretNames) {
+			if err := r.VaultClient.DeleteSecret(ctx, path); err != nil {
+				log.Error(err, "failed to delete revision from vault", "revision", revision, "path", path)
+				recordEvent(r.Recorder, deployment, corev1.EventTypeWarning, "RevisionDeleteFailed", "Sync",
+					"Failed to delete revision %s from %s: %v", revision, path, err)
+				failed = append(failed, revision)
+				break
+			}
+			r.SecretSizes.Forget(path)
+			log.Info("deleted previous revision from vault", "revision", revision, "path", path)
+		}
+	}
+	return failed
+}
+
+// recordRevision records that the secrets of revision were written to Vault, after which
+// the revisions beyond the revision history are deleted. Revisions whose deletion fails
+// stay recorded, so the deletion is retried after the next revision change.
+func (r *DeploymentReconciler) recordRevision(ctx context.Context, deployment client.Object, basePath, revision string, history int, secretNames []string, log logr.Logger) error {
+	// A rollback to a known revision makes it the newest one again
+	revisions := slices.DeleteFunc(GetSyncedRevisions(deployment), func(synced string) bool { return synced == revision })
+	revisions = append(revisions, revision)
+
+	if excess := len(revisions) - history - 1; excess > 0 {
+		failed := r.deleteRevisions(ctx, deployment, basePath, revisions[:excess], secretNames, log)
+		revisions = append(failed, revisions[excess:]...)
+	}
+
+	return PatchAnnotations(ctx, r.Client, deployment, map[string]string{VaultSyncedRevisionsAnnotation: strings.Join(revisions, ",")})
+}
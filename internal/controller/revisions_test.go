@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestPodTemplateHash(t *testing.T) {
+	template := corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:1"}}}}
+
+	hash := PodTemplateHash(template, nil)
+	if hash == "" || hash != PodTemplateHash(*template.DeepCopy(), nil) {
+		t.Fatalf("PodTemplateHash() = %q, expected a stable hash", hash)
+	}
+	if PodTemplateHash(template, ptr.To[int32](1)) == hash {
+		t.Error("PodTemplateHash() ignored the collision count")
+	}
+
+	template.Spec.Containers[0].Image = "app:2"
+	if PodTemplateHash(template, nil) == hash {
+		t.Error("PodTemplateHash() did not change with the pod template")
+	}
+}
+
+func TestGetRevision(t *testing.T) {
+	r := &DeploymentReconciler{}
+	tests := []struct {
+		name     string
+		revision string
+		expected string
+		wantErr  bool
+	}{
+		{name: "not set", revision: "", expected: ""},
+		{name: "literal", revision: " v1.4.2 ", expected: "v1.4.2"},
+		{name: "slash", revision: "v1/canary", wantErr: true},
+		{name: "comma", revision: "v1,v2", wantErr: true},
+		{name: "parent", revision: "..", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{}
+			deployment.Annotations = map[string]string{VaultRevisionAnnotation: tt.revision}
+			revision, err := r.getRevision(deployment)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getRevision() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if revision != tt.expected {
+				t.Errorf("getRevision() = %q, expected %q", revision, tt.expected)
+			}
+		})
+	}
+
+	deployment := &appsv1.Deployment{}
+	deployment.Annotations = map[string]string{VaultRevisionAnnotation: RevisionPodTemplateHash}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{Name: "app", Image: "app:1"}}
+	deployment.Status.CollisionCount = ptr.To[int32](2)
+	if revision, err := r.getRevision(deployment); err != nil || revision != PodTemplateHash(deployment.Spec.Template, ptr.To[int32](2)) {
+		t.Errorf("getRevision() = %q, %v, expected the pod template hash", revision, err)
+	}
+}
+
+func TestGetRevisionHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    int
+		wantErr     bool
+	}{
+		{name: "default", annotations: nil, expected: DefaultRevisionHistory},
+		{name: "set", annotations: map[string]string{VaultRevisionHistoryAnnotation: "3"}, expected: 3},
+		{name: "none kept", annotations: map[string]string{VaultRevisionHistoryAnnotation: "0"}, expected: 0},
+		{name: "negative", annotations: map[string]string{VaultRevisionHistoryAnnotation: "-1"}, wantErr: true},
+		{name: "invalid", annotations: map[string]string{VaultRevisionHistoryAnnotation: "all"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			history, err := GetRevisionHistory(obj)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRevisionHistory() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && history != tt.expected {
+				t.Errorf("GetRevisionHistory() = %d, expected %d", history, tt.expected)
+			}
+		})
+	}
+}
+
+// TestRevisionReconcile tests that a new revision is written before the oldest one is deleted.
+func TestRevisionReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "web-credentials"
+	secret.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web", VaultRevisionAnnotation: "v1"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, deployment).Build()
+	vaultClient := &fakeVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	reconcileUntilSynced(t, r, req)
+
+	for _, revision := range []string{"v2", "v3"} {
+		current := &appsv1.Deployment{}
+		if err := k8sClient.Get(ctx, req.NamespacedName, current); err != nil {
+			t.Fatalf("failed to get deployment: %v", err)
+		}
+		current.Annotations[VaultRevisionAnnotation] = revision
+		if err := k8sClient.Update(ctx, current); err != nil {
+			t.Fatalf("failed to update deployment: %v", err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	for _, path := range []string{"secret/data/web/v2/web-credentials", "secret/data/web/v3/web-credentials"} {
+		if got := vaultClient.secrets[path]["password"]; got != "s3cret" {
+			t.Errorf("password at %s = %v, expected s3cret", path, got)
+		}
+	}
+	if !reflect.DeepEqual(vaultClient.deletes, []string{"secret/data/web/v1/web-credentials"}) {
+		t.Errorf("deleted paths = %v, expected only revision v1", vaultClient.deletes)
+	}
+
+	synced := &appsv1.Deployment{}
+	if err := k8sClient.Get(ctx, req.NamespacedName, synced); err != nil {
+		t.Fatalf("failed to get deployment: %v", err)
+	}
+	if revisions := GetSyncedRevisions(synced); !reflect.DeepEqual(revisions, []string{"v2", "v3"}) {
+		t.Errorf("synced revisions = %v, expected [v2 v3]", revisions)
+	}
+
+	// Deleting the Deployment deletes every kept revision
+	if err := k8sClient.Delete(ctx, synced); err != nil {
+		t.Fatalf("failed to delete deployment: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(vaultClient.secrets) != 0 {
+		t.Errorf("paths left in vault after deletion: %v", vaultClient.secrets)
+	}
+}
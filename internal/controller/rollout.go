@@ -0,0 +1,103 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements deferring syncs while a workload is rolling out.
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// VaultWaitForRolloutAnnotation defers syncs while the workload is rolling out ("true" or
+// "false", overriding --wait-for-rollout).
+const VaultWaitForRolloutAnnotation = "vault-sync.io/wait-for-rollout"
+
+// RolloutRequeueDelay is how often a deferred sync is retried. Status updates of the
+// workload retry it sooner; the delay covers rollouts that stall without further updates.
+const RolloutRequeueDelay = 30 * time.Second
+
+// SkipReasonRolloutInProgress counts syncs deferred until a rollout has completed.
+const SkipReasonRolloutInProgress = "rollout_in_progress"
+
+// WaitsForRollout reports whether syncs of obj are deferred while it is rolling out, from
+// its vault-sync.io/wait-for-rollout annotation or else the operator default.
+func WaitsForRollout(obj client.Object, defaultWait bool) bool {
+	if value, ok := obj.GetAnnotations()[VaultWaitForRolloutAnnotation]; ok {
+		if wait, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return wait
+		}
+	}
+	return defaultWait
+}
+
+// RolloutInProgress reports whether obj is rolling out, or its rollout failed, with a short
+// description of why. Deployments follow kubectl rollout status; other workload kinds are
+// judged by their observed generation and by false Progressing and Available conditions.
+func RolloutInProgress(obj client.Object) (bool, string) {
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return deploymentRolloutInProgress(workload)
+	case *unstructured.Unstructured:
+		return workloadRolloutInProgress(workload)
+	default:
+		return false, ""
+	}
+}
+
+// deploymentRolloutInProgress applies the checks of kubectl rollout status to a Deployment.
+func deploymentRolloutInProgress(deployment *appsv1.Deployment) (bool, string) {
+	status := deployment.Status
+	if status.ObservedGeneration < deployment.Generation {
+		return true, fmt.Sprintf("generation %d not yet observed", deployment.Generation)
+	}
+	for _, condition := range status.Conditions {
+		switch {
+		case condition.Type == appsv1.DeploymentProgressing && condition.Status == corev1.ConditionFalse:
+			return true, fmt.Sprintf("rollout failed: %s", condition.Reason)
+		case condition.Type == appsv1.DeploymentAvailable && condition.Status == corev1.ConditionFalse:
+			return true, "deployment unavailable"
+		}
+	}
+	if deployment.Spec.Replicas != nil && status.UpdatedReplicas < *deployment.Spec.Replicas {
+		return true, fmt.Sprintf("%d of %d replicas updated", status.UpdatedReplicas, *deployment.Spec.Replicas)
+	}
+	if status.Replicas > status.UpdatedReplicas {
+		return true, fmt.Sprintf("%d old replicas pending termination", status.Replicas-status.UpdatedReplicas)
+	}
+	if status.AvailableReplicas < status.UpdatedReplicas {
+		return true, fmt.Sprintf("%d of %d updated replicas available", status.AvailableReplicas, status.UpdatedReplicas)
+	}
+	return false, ""
+}
+
+// workloadRolloutInProgress checks the status fields most Deployment-like kinds share. Fields
+// a kind does not have, or has in another form, are ignored.
+func workloadRolloutInProgress(workload *unstructured.Unstructured) (bool, string) {
+	if observed, found, err := unstructured.NestedInt64(workload.Object, "status", "observedGeneration"); err == nil && found && observed < workload.GetGeneration() {
+		return true, fmt.Sprintf("generation %d not yet observed", workload.GetGeneration())
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(workload.Object, "status", "conditions")
+	for _, item := range conditions {
+		condition, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		conditionType, _ := condition["type"].(string)
+		status, _ := condition["status"].(string)
+		reason, _ := condition["reason"].(string)
+		switch {
+		case conditionType == string(appsv1.DeploymentProgressing) && status == string(corev1.ConditionFalse):
+			return true, fmt.Sprintf("rollout failed: %s", reason)
+		case conditionType == string(appsv1.DeploymentAvailable) && status == string(corev1.ConditionFalse):
+			return true, strings.ToLower(workload.GetKind()) + " unavailable"
+		}
+	}
+	return false, ""
+}
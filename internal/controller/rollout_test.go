@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/utils/ptr"
+)
+
+func TestWaitsForRollout(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotation  string
+		defaultWait bool
+		expected    bool
+	}{
+		{name: "default off", expected: false},
+		{name: "default on", defaultWait: true, expected: true},
+		{name: "annotation enables", annotation: "true", expected: true},
+		{name: "annotation disables", annotation: "false", defaultWait: true, expected: false},
+		{name: "invalid annotation uses default", annotation: "maybe", defaultWait: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newPatchTestDeployment(nil)
+			if tt.annotation != "" {
+				deployment.Annotations = map[string]string{VaultWaitForRolloutAnnotation: tt.annotation}
+			}
+			if got := WaitsForRollout(deployment, tt.defaultWait); got != tt.expected {
+				t.Errorf("WaitsForRollout() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeploymentRolloutInProgress(t *testing.T) {
+	complete := appsv1.DeploymentStatus{
+		ObservedGeneration: 2,
+		Replicas:           3,
+		UpdatedReplicas:    3,
+		AvailableReplicas:  3,
+		Conditions: []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionTrue, Reason: "NewReplicaSetAvailable"},
+			{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionTrue},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		mutate   func(status *appsv1.DeploymentStatus)
+		expected bool
+	}{
+		{name: "complete", mutate: func(*appsv1.DeploymentStatus) {}},
+		{name: "generation not observed", mutate: func(s *appsv1.DeploymentStatus) { s.ObservedGeneration = 1 }, expected: true},
+		{name: "replicas not updated", mutate: func(s *appsv1.DeploymentStatus) { s.UpdatedReplicas = 1 }, expected: true},
+		{name: "old replicas remain", mutate: func(s *appsv1.DeploymentStatus) { s.Replicas = 4 }, expected: true},
+		{name: "updated replicas unavailable", mutate: func(s *appsv1.DeploymentStatus) { s.AvailableReplicas = 2 }, expected: true},
+		{
+			name: "progress deadline exceeded",
+			mutate: func(s *appsv1.DeploymentStatus) {
+				s.Conditions[0] = appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"}
+			},
+			expected: true,
+		},
+		{
+			name: "unavailable",
+			mutate: func(s *appsv1.DeploymentStatus) {
+				s.Conditions[1] = appsv1.DeploymentCondition{Type: appsv1.DeploymentAvailable, Status: corev1.ConditionFalse}
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := newPatchTestDeployment(nil)
+			deployment.Generation = 2
+			deployment.Spec.Replicas = ptr.To[int32](3)
+			deployment.Status = *complete.DeepCopy()
+			tt.mutate(&deployment.Status)
+
+			inProgress, reason := RolloutInProgress(deployment)
+			if inProgress != tt.expected {
+				t.Errorf("RolloutInProgress() = %v (%s), expected %v", inProgress, reason, tt.expected)
+			}
+			if inProgress && reason == "" {
+				t.Error("RolloutInProgress() gave no reason")
+			}
+		})
+	}
+}
+
+func TestWorkloadRolloutInProgress(t *testing.T) {
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default", "generation": int64(3)},
+		"status": map[string]interface{}{
+			// Argo Rollouts reports the observed generation as a string, which is ignored
+			"observedGeneration": "3",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Progressing", "status": "True", "reason": "NewReplicaSetAvailable"},
+				map[string]interface{}{"type": "Available", "status": "True"},
+			},
+		},
+	}}
+	if inProgress, reason := RolloutInProgress(rollout); inProgress {
+		t.Errorf("RolloutInProgress() of a completed rollout = true (%s)", reason)
+	}
+
+	if err := unstructured.SetNestedSlice(rollout.Object, []interface{}{
+		map[string]interface{}{"type": "Available", "status": "False"},
+	}, "status", "conditions"); err != nil {
+		t.Fatalf("failed to set conditions: %v", err)
+	}
+	if inProgress, _ := RolloutInProgress(rollout); !inProgress {
+		t.Error("RolloutInProgress() of an unavailable rollout = false")
+	}
+
+	if err := unstructured.SetNestedField(rollout.Object, int64(2), "status", "observedGeneration"); err != nil {
+		t.Fatalf("failed to set observed generation: %v", err)
+	}
+	rollout.Object["status"].(map[string]interface{})["conditions"] = nil
+	if inProgress, _ := RolloutInProgress(rollout); !inProgress {
+		t.Error("RolloutInProgress() with an unobserved generation = false")
+	}
+}
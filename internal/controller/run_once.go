@@ -0,0 +1,164 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the single sync sweep run by --run-once.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// syncSweepPageSize is the number of resources listed per request.
+const syncSweepPageSize = 500
+
+// SyncSweep reconciles every resource annotated for sync once, so the operator can run as a
+// Job, for example right after cluster bootstrap, instead of as a long-running controller.
+type SyncSweep struct {
+	// Client lists resources from the API server directly; a cache is never started
+	Client client.Reader
+	// Reconcilers sync the resources of their kind. They must read through the API server and
+	// share Warmup, which records the resources that synced.
+	Reconcilers map[schema.GroupVersionKind]reconcile.Reconciler
+	// Handles selects the resources to sync by kind and namespace. A nil function includes
+	// every resource.
+	Handles func(kind schema.GroupVersionKind, namespace string) bool
+	// Warmup paces the syncs and records which resources completed their sync
+	Warmup *WarmupCoordinator
+	Log    logr.Logger
+}
+
+// SyncSweepResult counts the resources handled by a sync sweep.
+type SyncSweepResult struct {
+	// Synced counts resources written to Vault or found up to date
+	Synced int
+	// Skipped counts resources reconciled without a sync, such as denylisted Secret types,
+	// resources whose annotation was removed and resources being deleted
+	Skipped int
+	// Failed counts resources whose sync failed or was still deferred when the sweep ended
+	Failed int
+}
+
+// sweepTarget is a resource reconciled by a sync sweep.
+type sweepTarget struct {
+	kind      schema.GroupVersionKind
+	key       client.ObjectKey
+	warmupKey string
+	finalized bool
+}
+
+// Run reconciles every annotated resource, and every resource still carrying the finalizer,
+// once. Syncs deferred by the reconcilers, for example while Vault is sealed or throttling,
+// are retried after the requested delay until ctx ends, when they count as failed; an error
+// is only returned when a kind cannot be listed.
+func (s *SyncSweep) Run(ctx context.Context) (SyncSweepResult, error) {
+	var result SyncSweepResult
+	targets, err := s.list(ctx)
+	if err != nil {
+		return result, err
+	}
+
+	keys := make([]string, 0, len(targets))
+	for _, target := range targets {
+		keys = append(keys, target.warmupKey)
+	}
+	s.Warmup.begin(keys, time.Now())
+	defer s.Warmup.finish("run_once")
+	s.Log.Info("sync sweep started", "total", len(targets))
+
+	for len(targets) > 0 {
+		var deferred []*sweepTarget
+		var wait time.Duration
+		for _, target := range targets {
+			res, err := s.reconcile(ctx, target)
+			log := s.Log.WithValues("kind", target.kind.Kind, "namespace", target.key.Namespace, "name", target.key.Name)
+			switch {
+			case err != nil:
+				result.Failed++
+				log.Error(err, "sync failed")
+			case s.Warmup.Synced(target.warmupKey):
+				result.Synced++
+			case res.RequeueAfter > 0:
+				deferred = append(deferred, target)
+				if wait == 0 || res.RequeueAfter < wait {
+					wait = res.RequeueAfter
+				}
+			default:
+				result.Skipped++
+			}
+		}
+		if len(deferred) == 0 {
+			break
+		}
+
+		s.Log.Info("retrying deferred syncs", "count", len(deferred), "retry_after", wait)
+		select {
+		case <-ctx.Done():
+			for _, target := range deferred {
+				result.Failed++
+				s.Log.Info("sync still deferred when the sweep ended",
+					"kind", target.kind.Kind, "namespace", target.key.Namespace, "name", target.key.Name)
+			}
+			return result, nil
+		case <-time.After(wait):
+		}
+		targets = deferred
+	}
+	return result, nil
+}
+
+// list returns the resources to reconcile, reading only their metadata to keep memory
+// bounded on clusters with many Secrets.
+func (s *SyncSweep) list(ctx context.Context) ([]*sweepTarget, error) {
+	var targets []*sweepTarget
+	for gvk := range s.Reconcilers {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		for {
+			if err := s.Client.List(ctx, list, client.Limit(syncSweepPageSize), client.Continue(list.Continue)); err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				finalized := controllerutil.ContainsFinalizer(obj, VaultSyncFinalizer)
+				if obj.Annotations[VaultPathAnnotation] == "" && !finalized {
+					continue
+				}
+				if s.Handles != nil && !s.Handles(gvk, obj.Namespace) {
+					continue
+				}
+				targets = append(targets, &sweepTarget{
+					kind:      gvk,
+					key:       client.ObjectKeyFromObject(obj),
+					warmupKey: WarmupKey(strings.ToLower(gvk.Kind), obj),
+					finalized: finalized,
+				})
+			}
+			if list.Continue == "" {
+				break
+			}
+		}
+	}
+	return targets, nil
+}
+
+// reconcile runs the reconciler of target, once more when the first run only added the
+// finalizer, which the running operator follows up on through the resulting update event.
+func (s *SyncSweep) reconcile(ctx context.Context, target *sweepTarget) (reconcile.Result, error) {
+	reconciler := s.Reconcilers[target.kind]
+	req := reconcile.Request{NamespacedName: target.key}
+	res, err := reconciler.Reconcile(ctx, req)
+	if err == nil && res.IsZero() && !target.finalized && !s.Warmup.Synced(target.warmupKey) {
+		target.finalized = true
+		res, err = reconciler.Reconcile(ctx, req)
+	}
+	target.finalized = true
+	return res, err
+}
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSyncSweep(t *testing.T) {
+	ctx := context.Background()
+	secretKind := corev1.SchemeGroupVersion.WithKind("Secret")
+
+	newSecret := func(namespace, name string, annotated bool, finalizers ...string) *corev1.Secret {
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+		secret.Name = name
+		secret.Namespace = namespace
+		secret.Finalizers = finalizers
+		if annotated {
+			secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/" + name}
+		}
+		return secret
+	}
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		newSecret("default", "db", true),
+		newSecret("default", "plain", false),
+		newSecret("default", "unannotated", false, VaultSyncFinalizer),
+		newSecret("other", "excluded", true),
+	).Build()
+	vaultClient := &readableVault{}
+	warmup := NewWarmupCoordinator(nil, math.Inf(1), time.Minute, logr.Discard())
+
+	sweep := &SyncSweep{
+		Client: k8sClient,
+		Reconcilers: map[schema.GroupVersionKind]reconcile.Reconciler{
+			secretKind: &SecretReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(), VaultClient: vaultClient, Warmup: warmup},
+		},
+		Handles: func(_ schema.GroupVersionKind, namespace string) bool { return namespace == "default" },
+		Warmup:  warmup,
+		Log:     logr.Discard(),
+	}
+	result, err := sweep.Run(ctx)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if expected := (SyncSweepResult{Synced: 1, Skipped: 1}); result != expected {
+		t.Errorf("Run() = %+v, expected %+v", result, expected)
+	}
+	if got := vaultClient.secrets["secret/data/db"]["password"]; got != "s3cret" {
+		t.Errorf("password in vault = %v, expected s3cret", got)
+	}
+	if _, ok := vaultClient.secrets["secret/data/excluded"]; ok {
+		t.Errorf("expected the secret of an unhandled namespace not to be synced")
+	}
+	unannotated := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "unannotated"}, unannotated); err != nil || len(unannotated.Finalizers) != 0 {
+		t.Errorf("finalizers = %v (%v), expected the finalizer of the unannotated secret to be removed", unannotated.Finalizers, err)
+	}
+}
+
+// sweepStub reconciles by returning its results in turn, marking the resource synced after the last one.
+type sweepStub struct {
+	results []reconcile.Result
+	err     error
+	warmup  *WarmupCoordinator
+	calls   int
+}
+
+func (s *sweepStub) Reconcile(_ context.Context, req reconcile.Request) (reconcile.Result, error) {
+	s.calls++
+	if s.err != nil {
+		return reconcile.Result{}, s.err
+	}
+	if s.calls < len(s.results) {
+		return s.results[s.calls-1], nil
+	}
+	s.warmup.MarkSynced("secret/" + req.Namespace + "/" + req.Name)
+	return s.results[len(s.results)-1], nil
+}
+
+func TestSyncSweepDeferred(t *testing.T) {
+	secretKind := corev1.SchemeGroupVersion.WithKind("Secret")
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Finalizers = []string{VaultSyncFinalizer}
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+	deferred := reconcile.Result{RequeueAfter: 10 * time.Millisecond}
+	periodic := reconcile.Result{RequeueAfter: time.Hour}
+
+	tests := []struct {
+		name           string
+		stub           *sweepStub
+		timeout        time.Duration
+		expectedResult SyncSweepResult
+		expectedCalls  int
+	}{
+		{
+			name:           "periodic requeue after the sync",
+			stub:           &sweepStub{results: []reconcile.Result{periodic}},
+			timeout:        time.Second,
+			expectedResult: SyncSweepResult{Synced: 1},
+			expectedCalls:  1,
+		},
+		{
+			name:           "deferred sync is retried",
+			stub:           &sweepStub{results: []reconcile.Result{deferred, deferred, {}}},
+			timeout:        time.Second,
+			expectedResult: SyncSweepResult{Synced: 1},
+			expectedCalls:  3,
+		},
+		{
+			name:           "still deferred when the sweep ends",
+			stub:           &sweepStub{results: []reconcile.Result{{RequeueAfter: time.Hour}, {}}},
+			timeout:        50 * time.Millisecond,
+			expectedResult: SyncSweepResult{Failed: 1},
+			expectedCalls:  1,
+		},
+		{
+			name:           "failed sync",
+			stub:           &sweepStub{err: errors.New("vault unavailable")},
+			timeout:        time.Second,
+			expectedResult: SyncSweepResult{Failed: 1},
+			expectedCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warmup := NewWarmupCoordinator(nil, math.Inf(1), time.Minute, logr.Discard())
+			tt.stub.warmup = warmup
+			sweep := &SyncSweep{
+				Client:      fake.NewClientBuilder().WithObjects(secret.DeepCopy()).Build(),
+				Reconcilers: map[schema.GroupVersionKind]reconcile.Reconciler{secretKind: tt.stub},
+				Warmup:      warmup,
+				Log:         logr.Discard(),
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			result, err := sweep.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result != tt.expectedResult {
+				t.Errorf("Run() = %+v, expected %+v", result, tt.expectedResult)
+			}
+			if tt.stub.calls != tt.expectedCalls {
+				t.Errorf("reconciles = %d, expected %d", tt.stub.calls, tt.expectedCalls)
+			}
+		})
+	}
+}
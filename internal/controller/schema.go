@@ -0,0 +1,220 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the JSON Schema of the vault-sync.io/secrets annotation.
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+)
+
+// SecretsSchemaID identifies the JSON Schema of the vault-sync.io/secrets annotation.
+const SecretsSchemaID = "https://vault-sync.io/schemas/secrets.json"
+
+// SecretsSchemaPath is where the metrics server serves the JSON Schema of the
+// vault-sync.io/secrets annotation.
+const SecretsSchemaPath = "/schemas/secrets.json"
+
+// secretRefPattern matches a secret reference: a name, or "namespace/name".
+const secretRefPattern = `^([^/]+/)?[^/]+$`
+
+var secretRefRegexp = regexp.MustCompile(secretRefPattern)
+
+// SecretsAnnotationSchema returns the JSON Schema (draft 2020-12) of the vault-sync.io/secrets
+// annotation, a JSON array of SecretConfig. It is the schema ValidateSecretsAnnotation checks,
+// published for editors, CI validators and admission webhooks. The secret configuration and
+// validation rule are defined under $defs, so other schemas can refer to them.
+func SecretsAnnotationSchema() []byte {
+	schema := map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         SecretsSchemaID,
+		"title":       VaultSecretsAnnotation,
+		"description": "Secrets and keys synced to the Vault path of vault-sync.io/path, instead of the Secrets auto-discovered from the pod template.",
+		"type":        "array",
+		"items":       map[string]interface{}{"$ref": "#/$defs/secretConfig"},
+		"$defs": map[string]interface{}{
+			"secretConfig": map[string]interface{}{
+				"type":                 "object",
+				"required":             []string{"name", "keys"},
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"name": map[string]interface{}{
+						"description": "Name of the Secret, or namespace/name of a Secret in another namespace (requires --allow-cross-namespace-refs).",
+						"type":        "string",
+						"pattern":     secretRefPattern,
+					},
+					"keys": map[string]interface{}{
+						"description": "Keys of the Secret written to Vault.",
+						"type":        "array",
+						"minItems":    1,
+						"items":       map[string]interface{}{"type": "string", "minLength": 1},
+					},
+					"prefix": map[string]interface{}{
+						"description": "Prefix prepended to the keys in Vault.",
+						"type":        "string",
+					},
+					"validate": map[string]interface{}{
+						"description":          "Validation rules checked before writing, by key. Every key must be listed in keys.",
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"$ref": "#/$defs/valueRule"},
+					},
+				},
+			},
+			"valueRule": map[string]interface{}{
+				"type":                 "object",
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"pattern": map[string]interface{}{
+						"description": "Regular expression the whole value must match.",
+						"type":        "string",
+						"format":      "regex",
+					},
+					"minLength": map[string]interface{}{
+						"description": "Minimum length of the value in characters.",
+						"type":        "integer",
+						"minimum":     0,
+					},
+					"format": map[string]interface{}{
+						"description": "Encoding the value must have.",
+						"enum":        []string{ValueFormatBase64, ValueFormatJSON, ValueFormatPEM},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(fmt.Sprintf("failed to marshal secrets annotation schema: %v", err))
+	}
+	return append(data, '\n')
+}
+
+// ValidateSecretsAnnotation checks a vault-sync.io/secrets annotation against its schema, see
+// SecretsAnnotationSchema, and the rules no schema can express: validation rules only apply
+// to synced keys, and their patterns must compile. The tests check that both agree on a
+// corpus of annotations. Unknown fields, which the sync ignores, are reported as well, as
+// they are usually misspelled, and so are fields spelled with another case.
+func ValidateSecretsAnnotation(value string) error {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.DisallowUnknownFields()
+	var configs []SecretConfig
+	if err := decoder.Decode(&configs); err != nil {
+		return fmt.Errorf("invalid %s: %w", VaultSecretsAnnotation, err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid %s: unexpected data after the array", VaultSecretsAnnotation)
+	}
+	// null matches no type of the schema, but decodes to the zero value of any field
+	var document interface{}
+	if err := json.Unmarshal([]byte(value), &document); err != nil {
+		return fmt.Errorf("invalid %s: %w", VaultSecretsAnnotation, err)
+	}
+	if location, ok := findNull(document, ""); ok {
+		return fmt.Errorf("invalid %s: %s must not be null", VaultSecretsAnnotation, location)
+	}
+	if err := checkFieldNames(document); err != nil {
+		return fmt.Errorf("invalid %s: %w", VaultSecretsAnnotation, err)
+	}
+
+	for i, config := range configs {
+		if !secretRefRegexp.MatchString(config.Name) {
+			return fmt.Errorf("invalid %s: entry %d: name %q must be <name> or <namespace>/<name>", VaultSecretsAnnotation, i, config.Name)
+		}
+		if len(config.Keys) == 0 {
+			return fmt.Errorf("invalid %s: entry %d: secret %s lists no keys", VaultSecretsAnnotation, i, config.Name)
+		}
+		for _, key := range config.Keys {
+			if key == "" {
+				return fmt.Errorf("invalid %s: entry %d: secret %s lists an empty key", VaultSecretsAnnotation, i, config.Name)
+			}
+		}
+		if err := config.validateRules(); err != nil {
+			return fmt.Errorf("invalid %s: entry %d: %w", VaultSecretsAnnotation, i, err)
+		}
+	}
+	return nil
+}
+
+// Field names of the secret configurations and validation rules, as spelled in the schema.
+var (
+	secretConfigFields = []string{"name", "keys", "prefix", "validate"}
+	valueRuleFields    = []string{"pattern", "minLength", "format"}
+)
+
+// checkFieldNames rejects the fields of a decoded annotation that are not spelled as in the
+// schema. encoding/json matches field names case-insensitively, so {"NAME": "db"} decodes,
+// while the schema and the JSON Schema validators using it reject it.
+func checkFieldNames(document interface{}) error {
+	entries, _ := document.([]interface{})
+	for i, entry := range entries {
+		config, _ := entry.(map[string]interface{})
+		if field, ok := unknownField(config, secretConfigFields); ok {
+			return fmt.Errorf("entry %d: unknown field %q, field names are case-sensitive", i, field)
+		}
+		rules, _ := config["validate"].(map[string]interface{})
+		for _, key := range sortedKeys(rules) {
+			rule, _ := rules[key].(map[string]interface{})
+			if field, ok := unknownField(rule, valueRuleFields); ok {
+				return fmt.Errorf("entry %d: rule for key %s: unknown field %q, field names are case-sensitive", i, key, field)
+			}
+		}
+	}
+	return nil
+}
+
+// unknownField returns the first field of object, in sorted order, that is not in fields.
+func unknownField(object map[string]interface{}, fields []string) (string, bool) {
+	for _, field := range sortedKeys(object) {
+		if !slices.Contains(fields, field) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// sortedKeys returns the keys of object in sorted order.
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// findNull returns the location of the first null in a decoded JSON document, e.g. "[0].prefix".
+func findNull(value interface{}, location string) (string, bool) {
+	switch value := value.(type) {
+	case nil:
+		if location == "" {
+			location = "the annotation"
+		}
+		return location, true
+	case []interface{}:
+		for i, item := range value {
+			if found, ok := findNull(item, fmt.Sprintf("%s[%d]", location, i)); ok {
+				return found, true
+			}
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(value) {
+			if found, ok := findNull(value[key], location+"."+key); ok {
+				return found, true
+			}
+		}
+	}
+	return "", false
+}
+
+// SchemaHandler serves the JSON Schema of the vault-sync.io/secrets annotation.
+type SchemaHandler struct{}
+
+// ServeHTTP implements http.Handler.
+func (SchemaHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	_, _ = w.Write(SecretsAnnotationSchema())
+}
@@ -0,0 +1,284 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestValidateSecretsAnnotation(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "valid", value: `[{"name": "db", "keys": ["username", "password"], "prefix": "db_"}]`},
+		{name: "cross-namespace reference", value: `[{"name": "shared/tls", "keys": ["tls.crt"]}]`},
+		{
+			name:  "validation rule",
+			value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"minLength": 16, "format": "base64"}}}]`,
+		},
+		{name: "not an array", value: `{"name": "db"}`, expected: "cannot unmarshal object"},
+		{name: "unknown field", value: `[{"name": "db", "keys": ["password"], "prefx": "db_"}]`, expected: `unknown field "prefx"`},
+		{name: "field in another case", value: `[{"NAME": "db", "keys": ["password"]}]`, expected: `entry 0: unknown field "NAME"`},
+		{name: "trailing data", value: `[] []`, expected: "unexpected data after the array"},
+		{name: "missing name", value: `[{"keys": ["password"]}]`, expected: `entry 0: name ""`},
+		{name: "invalid reference", value: `[{"name": "a/b/c", "keys": ["password"]}]`, expected: `name "a/b/c"`},
+		{name: "no keys", value: `[{"name": "db"}]`, expected: "secret db lists no keys"},
+		{name: "empty key", value: `[{"name": "db", "keys": [""]}]`, expected: "lists an empty key"},
+		{name: "null prefix", value: `[{"name": "db", "keys": ["password"], "prefix": null}]`, expected: "[0].prefix must not be null"},
+		{
+			name:     "rule for a key that is not synced",
+			value:    `[{"name": "db", "keys": ["password"], "validate": {"username": {"minLength": 1}}}]`,
+			expected: "the key is not synced",
+		},
+		{
+			name:     "unknown format",
+			value:    `[{"name": "db", "keys": ["password"], "validate": {"password": {"format": "hex"}}}]`,
+			expected: `unknown format "hex"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSecretsAnnotation(tt.value)
+			if tt.expected == "" {
+				if err != nil {
+					t.Errorf("ValidateSecretsAnnotation() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("ValidateSecretsAnnotation() error = %v, expected it to contain %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+// schemaAccepts reports whether value is valid against schema, a subschema of root. It
+// evaluates the keywords SecretsAnnotationSchema uses, as a JSON Schema validator would.
+func schemaAccepts(root, schema map[string]interface{}, value interface{}) bool {
+	if ref, ok := schema["$ref"].(string); ok {
+		defs := root["$defs"].(map[string]interface{})
+		return schemaAccepts(root, defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}), value)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !slices.Contains(enum, value) {
+		return false
+	}
+	switch schema["type"] {
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		if minItems, ok := schema["minItems"].(float64); ok && float64(len(items)) < minItems {
+			return false
+		}
+		for _, item := range items {
+			if !schemaAccepts(root, schema["items"].(map[string]interface{}), item) {
+				return false
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				return false
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, property := range object {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				additional, isSchema := schema["additionalProperties"].(map[string]interface{})
+				if !isSchema {
+					if schema["additionalProperties"] == false {
+						return false
+					}
+					continue
+				}
+				propertySchema = additional
+			}
+			if !schemaAccepts(root, propertySchema, property) {
+				return false
+			}
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			return false
+		}
+		if minLength, ok := schema["minLength"].(float64); ok && float64(utf8.RuneCountInString(text)) < minLength {
+			return false
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(text) {
+			return false
+		}
+	case "integer":
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		integer, err := number.Int64()
+		if err != nil {
+			return false
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && float64(integer) < minimum {
+			return false
+		}
+	}
+	return true
+}
+
+// TestSecretsAnnotationSchemaAgreement tests that ValidateSecretsAnnotation accepts exactly
+// the annotations its schema accepts, apart from the rules no schema can express.
+func TestSecretsAnnotationSchemaAgreement(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal(SecretsAnnotationSchema(), &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	tests := []struct {
+		name  string
+		value string
+		// beyondSchema marks annotations only the validator rejects
+		beyondSchema bool
+	}{
+		{name: "empty", value: `[]`},
+		{name: "minimal", value: `[{"name": "db", "keys": ["password"]}]`},
+		{name: "all fields", value: `[{"name": "shared/db", "keys": ["password"], "prefix": "db_", "validate": {"password": {"pattern": "[a-z]+", "minLength": 8, "format": "pem"}}}]`},
+		{name: "empty rule", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {}}}]`},
+		{name: "empty prefix", value: `[{"name": "db", "keys": ["password"], "prefix": ""}]`},
+		{name: "null", value: `null`},
+		{name: "object", value: `{"name": "db", "keys": ["password"]}`},
+		{name: "null entry", value: `[null]`},
+		{name: "entry not an object", value: `["db"]`},
+		{name: "missing name", value: `[{"keys": ["password"]}]`},
+		{name: "missing keys", value: `[{"name": "db"}]`},
+		{name: "empty name", value: `[{"name": "", "keys": ["password"]}]`},
+		{name: "name with two slashes", value: `[{"name": "a/b/c", "keys": ["password"]}]`},
+		{name: "name with empty namespace", value: `[{"name": "/db", "keys": ["password"]}]`},
+		{name: "name not a string", value: `[{"name": 5, "keys": ["password"]}]`},
+		{name: "null name", value: `[{"name": null, "keys": ["password"]}]`},
+		{name: "keys not an array", value: `[{"name": "db", "keys": "password"}]`},
+		{name: "null keys", value: `[{"name": "db", "keys": null}]`},
+		{name: "no keys", value: `[{"name": "db", "keys": []}]`},
+		{name: "empty key", value: `[{"name": "db", "keys": [""]}]`},
+		{name: "key not a string", value: `[{"name": "db", "keys": [1]}]`},
+		{name: "null key", value: `[{"name": "db", "keys": [null]}]`},
+		{name: "unknown field", value: `[{"name": "db", "keys": ["password"], "prefx": "db_"}]`},
+		{name: "name in another case", value: `[{"NAME": "db", "keys": ["password"]}]`},
+		{name: "prefix in another case", value: `[{"name": "db", "keys": ["password"], "Prefix": "db_"}]`},
+		{name: "field spelled twice", value: `[{"name": "db", "Name": "cache", "keys": ["password"]}]`},
+		{name: "rule field in another case", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"MinLength": 8}}}]`},
+		{name: "prefix not a string", value: `[{"name": "db", "keys": ["password"], "prefix": 1}]`},
+		{name: "null prefix", value: `[{"name": "db", "keys": ["password"], "prefix": null}]`},
+		{name: "validate not an object", value: `[{"name": "db", "keys": ["password"], "validate": []}]`},
+		{name: "null validate", value: `[{"name": "db", "keys": ["password"], "validate": null}]`},
+		{name: "null rule", value: `[{"name": "db", "keys": ["password"], "validate": {"password": null}}]`},
+		{name: "unknown rule field", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"maxLength": 8}}}]`},
+		{name: "negative minLength", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"minLength": -1}}}]`},
+		{name: "fractional minLength", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"minLength": 1.5}}}]`},
+		{name: "minLength not a number", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"minLength": "8"}}}]`},
+		{name: "unknown format", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"format": "hex"}}}]`},
+		{name: "pattern not a string", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"pattern": 1}}}]`},
+		{name: "rule for a key that is not synced", value: `[{"name": "db", "keys": ["password"], "validate": {"username": {}}}]`, beyondSchema: true},
+		{name: "pattern that does not compile", value: `[{"name": "db", "keys": ["password"], "validate": {"password": {"pattern": "("}}}]`, beyondSchema: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tt.value))
+			decoder.UseNumber()
+			var document interface{}
+			if err := decoder.Decode(&document); err != nil {
+				t.Fatalf("invalid JSON in the corpus: %v", err)
+			}
+			schemaValid := schemaAccepts(schema, schema, document)
+			err := ValidateSecretsAnnotation(tt.value)
+			switch {
+			case tt.beyondSchema && (!schemaValid || err == nil):
+				t.Errorf("schema accepts = %v, validator error = %v, expected only the validator to reject it", schemaValid, err)
+			case !tt.beyondSchema && schemaValid != (err == nil):
+				t.Errorf("schema accepts = %v, but validator error = %v", schemaValid, err)
+			}
+		})
+	}
+}
+
+// TestSecretsAnnotationSchemaFile tests that the published schema is the one the operator
+// generates.
+func TestSecretsAnnotationSchemaFile(t *testing.T) {
+	published, err := os.ReadFile(filepath.Join("..", "..", "config", "schemas", "secrets.schema.json"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if !bytes.Equal(published, SecretsAnnotationSchema()) {
+		t.Errorf("config/schemas/secrets.schema.json is out of date, run make schemas")
+	}
+
+	var schema map[string]interface{}
+	if err := json.Unmarshal(published, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	if schema["$id"] != SecretsSchemaID {
+		t.Errorf("$id = %v, expected %s", schema["$id"], SecretsSchemaID)
+	}
+}
+
+// TestExampleSecretsAnnotations tests that the secrets annotations of the examples are valid.
+func TestExampleSecretsAnnotations(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "examples", "*.yaml"))
+	if err != nil {
+		t.Fatalf("Glob() error = %v", err)
+	}
+	validated := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file) //nolint:gosec // Example files of the repository
+		if err != nil {
+			t.Fatalf("ReadFile() error = %v", err)
+		}
+		for _, document := range strings.Split(string(content), "\n---") {
+			var object struct {
+				Metadata struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"metadata"`
+			}
+			if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+				t.Fatalf("%s: invalid YAML: %v", file, err)
+			}
+			value, ok := object.Metadata.Annotations[VaultSecretsAnnotation]
+			if !ok {
+				continue
+			}
+			validated++
+			if err := ValidateSecretsAnnotation(value); err != nil {
+				t.Errorf("%s: %v", file, err)
+			}
+		}
+	}
+	if validated == 0 {
+		t.Errorf("expected the examples to contain secrets annotations")
+	}
+}
+
+func TestSchemaHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SchemaHandler{}.ServeHTTP(recorder, httptest.NewRequest("GET", SecretsSchemaPath, nil))
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/schema+json" {
+		t.Errorf("Content-Type = %q, expected application/schema+json", contentType)
+	}
+	if !bytes.Equal(recorder.Body.Bytes(), SecretsAnnotationSchema()) {
+		t.Errorf("expected the handler to serve the schema")
+	}
+}
@@ -4,15 +4,19 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
 	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
 )
 
@@ -21,24 +25,80 @@ type SecretReconciler struct {
 	client.Client
 	Scheme      *runtime.Scheme
 	Log         logr.Logger
-	VaultClient *vault.Client
+	VaultClient VaultWriterDeleter
 	ClusterName string // Optional cluster identifier for multi-cluster Vault paths
+	// SkippedSecretTypes lists Secret types that are never synced to Vault (e.g. service account tokens)
+	SkippedSecretTypes []string
+	// SecretTypes allows or denies Secret types per namespace (optional)
+	SecretTypes SecretTypePolicy
+	// Warmup paces the initial reconcile storm after startup (optional)
+	Warmup *WarmupCoordinator
+	// Stagger spreads first syncs and periodic requeues over a window (optional)
+	Stagger *RequeueStagger
+	// Recorder emits Kubernetes events for notable sync conditions (optional)
+	Recorder events.EventRecorder
+	// Name overrides the controller name, allowing several instances to run side by side
+	Name string
+	// Namespaces restricts the controller to these namespaces (empty means all)
+	Namespaces []string
+	// History records recent sync operations per Secret (optional)
+	History *SyncHistory
+	// ExternalSecretPolicy controls Secrets managed by the External Secrets Operator (warn|skip|ignore)
+	ExternalSecretPolicy string
+	// Propagation measures the delay from a Secret data change to its Vault write (optional)
+	Propagation *PropagationTracker
+	// NamespaceMounts places the paths of mapped namespaces under a fixed Vault mount prefix
+	NamespaceMounts NamespaceMounts
+	// CrossNamespace controls "namespace/name" references in the secrets annotation
+	CrossNamespace CrossNamespacePolicy
+	// Inventory tracks the Vault paths managed by each Secret (optional)
+	Inventory *ManagedPathInventory
+	// ReconcileBounds limits the vault-sync.io/reconcile intervals (30s minimum when zero)
+	ReconcileBounds ReconcileIntervalBounds
+	// DefaultReconcileInterval applies to resources without vault-sync.io/reconcile (disabled when zero)
+	DefaultReconcileInterval time.Duration
+	// Policy is evaluated before every Vault write and can deny it (optional)
+	Policy *PolicyHook
+	// LogSampler thins out repetitive log lines such as syncs without changes (optional)
+	LogSampler *LogSampler
+	// Errors keeps the recent sync and delete errors for the status page (optional)
+	Errors *ErrorLog
+	// SecretSizes tracks the size of Vault writes and warns about large ones (optional)
+	SecretSizes *SecretSizeTracker
+	// PreserveOnDelete decides which resources keep their Vault paths when they are deleted
+	PreserveOnDelete PreserveOnDeletePolicy
+	// StateBackend records what was synced in the Secret's annotations or in Vault (StateBackendAnnotation when empty)
+	StateBackend string
+	// StateHashKey keys the content hashes recorded by the Vault state backend
+	StateHashKey []byte
+	// RotationCheckPolicy overrides vault-sync.io/rotation-check operator-wide
+	// (RotationCheckPolicyAnnotation when empty)
+	RotationCheckPolicy string
+	// RetryBudget backs off resources whose syncs keep failing (optional)
+	RetryBudget *RetryBudget
 }
 
 // +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;update;patch
 // +kubebuilder:rbac:groups="",resources=secrets/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := r.Log.WithValues("secret", req.NamespacedName)
 
+	// Vault requests take turns with those of other namespaces
+	ctx = vault.WithSourceNamespace(ctx, req.Namespace)
+
 	// Fetch the Secret instance
 	secret := &corev1.Secret{}
 	err := r.Get(ctx, req.NamespacedName, secret)
 	if err != nil {
 		if client.IgnoreNotFound(err) == nil {
 			// Secret not found, probably deleted
+			r.Propagation.Forget(req.NamespacedName)
+			r.Inventory.Forget("secret", req.NamespacedName)
+			r.RetryBudget.Forget("secret/" + req.Namespace + "/" + req.Name)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "unable to fetch Secret")
@@ -48,6 +108,8 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	// Check if vault-sync is enabled for this secret (presence of vault path annotation)
 	vaultPath, vaultSyncEnabled := secret.Annotations[VaultPathAnnotation]
 	if !vaultSyncEnabled || vaultPath == "" {
+		r.Inventory.Forget("secret", req.NamespacedName)
+		r.RetryBudget.Forget(WarmupKey("secret", secret))
 		// Remove finalizer if it exists but sync is disabled
 		if controllerutil.ContainsFinalizer(secret, VaultSyncFinalizer) {
 			controllerutil.RemoveFinalizer(secret, VaultSyncFinalizer)
@@ -56,6 +118,13 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
+	// Hold writes and deletions while Vault is sealed instead of failing repeatedly
+	if holdWhileSealed(ctx, r.VaultClient, r.Recorder, secret) {
+		metrics.SyncsHeldWhileSealed.WithLabelValues("secret").Inc()
+		log.Info("vault is sealed, holding sync", "requeue_after", VaultSealedRequeueDelay)
+		return ctrl.Result{RequeueAfter: VaultSealedRequeueDelay}, nil
+	}
+
 	// Handle deletion
 	if secret.DeletionTimestamp != nil {
 		return r.handleDeletion(ctx, secret)
@@ -67,10 +136,88 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, r.Update(ctx, secret)
 	}
 
-	// Sync secret to Vault
-	if err := r.syncSecretToVault(ctx, secret); err != nil {
+	// Never sync denylisted secret types, even when explicitly annotated
+	if secretTypeDenied(secret, r.SkippedSecretTypes, r.SecretTypes) {
+		log.Info("skipping secret of denylisted type", "type", secret.Type)
+		r.Propagation.Forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	// Avoid pushing ExternalSecret targets back to Vault, which ESO would pull again
+	if skipExternalSecret(r.Recorder, secret, secret, r.ExternalSecretPolicy, log) {
+		r.Propagation.Forget(req.NamespacedName)
+		return ctrl.Result{}, nil
+	}
+
+	// Keep a degraded resource at its long retry interval until its configuration changes
+	if delay := r.RetryBudget.Hold(WarmupKey("secret", secret), RetryFingerprint(secret)); delay > 0 {
+		metrics.SyncSkipped.WithLabelValues(SkipReasonDegraded).Inc()
+		log.V(1).Info("sync degraded after repeated failures, deferring", "requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Defer the sync while the Vault rate limiter is saturated
+	priority := GetSyncPriority(secret)
+	if delay, saturated := priorityBackpressureDelay(r.VaultClient, req.Namespace, priority); saturated {
+		metrics.BackpressureRequeues.WithLabelValues("secret").Inc()
+		log.V(1).Info("vault request queue saturated, requeueing",
+			"pending_requests", pendingVaultRequests(r.VaultClient),
+			"priority", priority,
+			"requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Defer the sync while Vault asks requests to back off; high-priority syncs still go
+	// through at the lowered rate
+	if delay, throttled := vaultThrottleDelay(r.VaultClient); throttled && priority != SyncPriorityHigh {
+		metrics.BackpressureRequeues.WithLabelValues("secret").Inc()
+		log.V(1).Info("vault is throttling requests, requeueing",
+			"priority", priority,
+			"requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Spread the first syncs after startup so Vault does not receive them all at once
+	if delay := r.Stagger.InitialDelay(WarmupKey("secret", secret)); delay > 0 {
+		log.V(1).Info("staggering first sync after startup", "requeue_after", delay)
+		return ctrl.Result{RequeueAfter: delay}, nil
+	}
+
+	// Pace syncs while the startup warm-up is in progress
+	if err := r.Warmup.Wait(ctx); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Sync secret to Vault, recording attempted writes in the sync history
+	syncStart := time.Now()
+	changedKeys, err := r.syncSecretToVault(ctx, secret)
+	resolvedPath := r.NamespaceMounts.ResolvePath(secret.Namespace, vaultPath, r.ClusterName, IsAbsolutePath(secret))
+	logOutcome(log, r.LogSampler, "secret", secret, resolvedPath, LogOpSync, changedKeys, time.Since(syncStart), err)
+	r.Errors.Record("secret", secret, resolvedPath, LogOpSync, err)
+	if changedKeys > 0 || err != nil {
+		r.History.Record(ctx, "secret", secret, changedKeys, err)
+	}
+	if err != nil {
+		// Retry once Vault's back-off has passed rather than at the work queue's rate
+		if delay, throttled := vaultThrottleDelay(r.VaultClient); throttled {
+			log.Info("vault is throttling requests, requeueing failed sync", "requeue_after", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
+		// Stop competing for the shared rate limit once the retry budget is exhausted
+		if delay, degraded := degradeOnFailure(r.RetryBudget, r.Recorder, secret, WarmupKey("secret", secret), err); degraded {
+			log.Info("sync keeps failing, retrying at the degraded interval", "requeue_after", delay)
+			return ctrl.Result{RequeueAfter: delay}, nil
+		}
 		return ctrl.Result{}, err
 	}
+	restoreOnSuccess(r.RetryBudget, r.Recorder, secret, WarmupKey("secret", secret))
+	if changedKeys > 0 {
+		r.Propagation.Synced(req.NamespacedName)
+	} else {
+		r.Propagation.Forget(req.NamespacedName)
+		metrics.SyncSkipped.WithLabelValues(SkipReasonNoChange).Inc()
+	}
+	r.Warmup.MarkSynced(WarmupKey("secret", secret))
 
 	// Check if periodic reconciliation is enabled
 	reconcileInterval := r.getReconcileInterval(secret)
@@ -78,7 +225,23 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		log.V(1).Info("periodic reconciliation enabled",
 			"interval", reconcileInterval,
 			"next_reconcile", time.Now().Add(reconcileInterval))
-		return ctrl.Result{RequeueAfter: reconcileInterval}, nil
+	}
+
+	// Check if scheduled rotation checks are enabled; they are skipped when no referenced
+	// secret can change
+	rotationInterval := GetRotationCheckInterval(secret, r.Log)
+	if rotationInterval > 0 && OnlyImmutableSecrets(r.getLastKnownSecretVersions(secret)) {
+		log.V(1).Info("all referenced secrets are immutable, skipping scheduled rotation checks")
+		rotationInterval = 0
+	}
+	if rotationInterval > 0 {
+		log.V(1).Info("scheduled rotation check enabled",
+			"frequency", rotationInterval,
+			"next_check", time.Now().Add(rotationInterval))
+	}
+
+	if requeueAfter := EarliestInterval(reconcileInterval, rotationInterval); requeueAfter > 0 {
+		return ctrl.Result{RequeueAfter: r.Stagger.Periodic(WarmupKey("secret", secret), r.Warmup.DeferRequeue(requeueAfter))}, nil
 	}
 
 	return ctrl.Result{}, nil
@@ -90,48 +253,72 @@ func (r *SecretReconciler) handleDeletion(ctx context.Context, secret *corev1.Se
 
 	if controllerutil.ContainsFinalizer(secret, VaultSyncFinalizer) {
 		// Check if deletion should be preserved
-		preserveOnDelete := secret.Annotations[VaultPreserveOnDeleteAnnotation] == "true"
+		preserveOnDelete := r.PreserveOnDelete.Preserves(secret)
 
 		// Get the vault path
 		vaultPath, exists := secret.Annotations[VaultPathAnnotation]
 		if exists && vaultPath != "" && !preserveOnDelete {
 			// Create sync context
 			syncCtx := &SyncContext{
-				Client:      r.Client,
-				VaultClient: r.VaultClient,
-				Log:         r.Log,
-				ClusterName: r.ClusterName,
+				Client:          r.Client,
+				VaultClient:     r.VaultClient,
+				Log:             r.Log,
+				ClusterName:     r.ClusterName,
+				NamespaceMounts: r.NamespaceMounts,
 			}
 
 			resourceInfo := ResourceInfo{
-				Name:      secret.Name,
-				Namespace: secret.Namespace,
-				Type:      "secret",
+				Name:         secret.Name,
+				Namespace:    secret.Namespace,
+				Type:         "secret",
+				AbsolutePath: IsAbsolutePath(secret),
 			}
 
-			// Delete the secret from Vault
-			if err := syncCtx.DeleteSecretFromVault(ctx, vaultPath, resourceInfo); err != nil {
-				log.Error(err, "failed to delete secret from vault",
-					"path", vaultPath,
-					"error_details", err.Error())
-				return ctrl.Result{}, err
+			// Serialize with other reconciles targeting the same path
+			resolvedPath := r.NamespaceMounts.ResolvePath(secret.Namespace, vaultPath, r.ClusterName, resourceInfo.AbsolutePath)
+			unlock, err := lockVaultPath(ctx, r.VaultClient, resolvedPath)
+			if err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to lock vault path %s: %w", vaultPath, err)
+			}
+			defer unlock()
+
+			// Delete the secret from Vault, unless an earlier attempt already did
+			if isVaultPathDeleted(secret, resolvedPath) {
+				log.Info("secret already deleted from vault by an earlier attempt", "path", resolvedPath)
+			} else {
+				recordDeleteAttempt(r.Recorder, secret, resolvedPath)
+				deleteStart := time.Now()
+				err := syncCtx.DeleteSecretFromVault(ctx, vaultPath, resourceInfo)
+				logOutcome(log, r.LogSampler, "secret", secret, resolvedPath, LogOpDelete, 0, time.Since(deleteStart), err)
+				r.Errors.Record("secret", secret, resolvedPath, LogOpDelete, err)
+				recordDeleteOutcome(r.Recorder, secret, resolvedPath, err)
+				if err != nil {
+					return ctrl.Result{}, err
+				}
+				r.SecretSizes.Forget(resolvedPath)
+				markVaultPathDeleted(ctx, r.Client, secret, resolvedPath, log)
 			}
 		} else if preserveOnDelete {
-			log.Info("preserving vault secret due to preserve annotation",
+			log.Info("preserving vault secret due to preserve-on-delete policy",
 				"path", vaultPath,
-				"preserve_annotation", "true")
+				"preserve_annotation", secret.Annotations[VaultPreserveOnDeleteAnnotation])
+			recordEvent(r.Recorder, secret, corev1.EventTypeNormal, "VaultPathPreserved", "Delete",
+				"Keeping %s in Vault due to the preserve-on-delete policy", vaultPath)
 		}
 
 		// Remove finalizer
-		controllerutil.RemoveFinalizer(secret, VaultSyncFinalizer)
-		return ctrl.Result{}, r.Update(ctx, secret)
+		r.Inventory.Forget("secret", client.ObjectKeyFromObject(secret))
+		r.RetryBudget.Forget(WarmupKey("secret", secret))
+		r.History.Forget(ctx, "secret", secret)
+		return ctrl.Result{}, finishDeletion(ctx, r.Client, r.Recorder, secret)
 	}
 
 	return ctrl.Result{}, nil
 }
 
-// syncSecretToVault syncs the secret to Vault.
-func (r *SecretReconciler) syncSecretToVault(ctx context.Context, secret *corev1.Secret) error {
+// syncSecretToVault syncs the secret to Vault and returns the number of keys written,
+// which is zero when no changes were detected.
+func (r *SecretReconciler) syncSecretToVault(ctx context.Context, secret *corev1.Secret) (int, error) {
 	log := r.Log.WithValues("secret", secret.Name, "namespace", secret.Namespace)
 
 	// Get the vault path (we already know it exists from reconcile check)
@@ -139,16 +326,37 @@ func (r *SecretReconciler) syncSecretToVault(ctx context.Context, secret *corev1
 
 	// Create sync context
 	syncCtx := &SyncContext{
-		Client:      r.Client,
-		VaultClient: r.VaultClient,
-		Log:         r.Log,
-		ClusterName: r.ClusterName,
+		Client:               r.Client,
+		VaultClient:          r.VaultClient,
+		Log:                  r.Log,
+		ClusterName:          r.ClusterName,
+		SkippedSecretTypes:   r.SkippedSecretTypes,
+		SecretTypes:          r.SecretTypes,
+		ExternalSecretPolicy: r.ExternalSecretPolicy,
+		NamespaceMounts:      r.NamespaceMounts,
+		CrossNamespace:       r.CrossNamespace,
 	}
 
 	resourceInfo := ResourceInfo{
-		Name:      secret.Name,
-		Namespace: secret.Namespace,
-		Type:      "secret",
+		Name:         secret.Name,
+		Namespace:    secret.Namespace,
+		Type:         "secret",
+		AbsolutePath: IsAbsolutePath(secret),
+	}
+
+	// Serialize with other reconciles (e.g. the Deployment controller) targeting the same path
+	resolvedPath := r.NamespaceMounts.ResolvePath(secret.Namespace, vaultPath, r.ClusterName, resourceInfo.AbsolutePath)
+	unlock, err := lockVaultPath(ctx, r.VaultClient, resolvedPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock vault path %s: %w", vaultPath, err)
+	}
+	defer unlock()
+
+	// A changed path moves the secret: the new path is written, then the old one is deleted
+	move := detectVaultPathMove(secret, resolvedPath)
+	if move != nil {
+		move.OldPaths = []string{move.From}
+		move.NewPaths = []string{resolvedPath}
 	}
 
 	// Check if custom secrets configuration is provided
@@ -156,7 +364,6 @@ func (r *SecretReconciler) syncSecretToVault(ctx context.Context, secret *corev1
 
 	var vaultData map[string]interface{}
 	var currentSecretVersions map[string]string
-	var err error
 
 	if hasCustomConfig && secretsToSync != "" {
 		// Use custom configuration. Note: for secret-level sync, this allows referencing
@@ -165,63 +372,173 @@ func (r *SecretReconciler) syncSecretToVault(ctx context.Context, secret *corev1
 		log.Info("using custom secret configuration", "config", secretsToSync)
 		vaultData, currentSecretVersions, err = syncCtx.SyncCustomSecretsWithVersions(ctx, resourceInfo, secretsToSync, secret.Namespace)
 		if err != nil {
-			log.Error(err, "failed to sync custom secret configuration")
-			return err
+			return 0, err
 		}
 	} else {
 		// Sync all keys from this secret
 		log.Info("syncing all secret keys")
 		vaultData, currentSecretVersions, err = syncCtx.SyncAllSecretKeys(ctx, resourceInfo, secret)
 		if err != nil {
-			log.Error(err, "failed to sync all secret keys")
-			return err
+			return 0, err
 		}
 	}
 
+	// Rewrite key names according to the key sanitization policy
+	keyPolicy, err := GetKeySanitizationPolicy(secret)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(secret.Namespace, metrics.ResourceLabel(secret.Namespace, secret.Name), "key_sanitization_error").Inc()
+		log.Error(err, "invalid key sanitization annotation",
+			"annotation", secret.Annotations[VaultKeySanitizationAnnotation])
+		return 0, err
+	}
+	if vaultData, err = keyPolicy.SanitizeVaultData(vaultData); err != nil {
+		log.Error(err, "failed to sanitize secret keys")
+		return 0, err
+	}
+	if ParsesJSONValues(secret) {
+		vaultData = parseJSONValues(vaultData)
+	}
+	if CompressesValues(secret) {
+		if vaultData, err = compressValues(vaultData); err != nil {
+			log.Error(err, "failed to compress secret values")
+			return 0, err
+		}
+	}
+
+	// Validate the KV metadata settings before anything is written
+	kvMetadata, err := GetKVMetadata(secret)
+	if err != nil {
+		metrics.ConfigParseErrors.WithLabelValues(secret.Namespace, metrics.ResourceLabel(secret.Namespace, secret.Name), "kv_metadata_error").Inc()
+		log.Error(err, "invalid kv metadata annotation")
+		return 0, err
+	}
+
 	// Check if secret versions have changed (rotation detection)
 	lastKnownVersions := r.getLastKnownSecretVersions(secret)
 	var hasChanges bool
 
-	// Check if rotation detection is disabled
+	// Check if rotation detection is disabled or a manual resync was requested
 	if r.isRotationCheckDisabled(secret) {
 		log.Info("secret rotation check disabled, performing sync anyway")
 		hasChanges = true
+	} else if IsForceSyncRequested(secret) {
+		log.Info("manual resync requested, ignoring version checks",
+			"force_sync", secret.Annotations[VaultForceSyncAnnotation])
+		hasChanges = true
+	} else if move != nil {
+		log.Info("vault path changed, moving secret", "from", move.From, "to", move.To)
+		hasChanges = true
+	} else if r.StateBackend != StateBackendVault && SyncConfigChanged(secret) {
+		log.Info("secrets configuration changed, rewriting vault document without the keys no longer configured")
+		hasChanges = true
+	} else if r.StateBackend == StateBackendVault {
+		// The content hash recorded in Vault decides whether the path is written
+		unchanged, err := vaultContentUnchanged(ctx, r.VaultClient, r.StateHashKey, resolvedPath, vaultData)
+		if err != nil {
+			log.Error(err, "failed to read content hash from vault", "path", resolvedPath)
+			return 0, err
+		}
+		hasChanges = !unchanged
 	} else {
 		hasChanges = syncCtx.DetectSecretChanges(lastKnownVersions, currentSecretVersions)
 	}
 
-	if !hasChanges && len(lastKnownVersions) > 0 {
-		log.Info("no secret changes detected, skipping vault sync",
-			"last_versions", lastKnownVersions,
-			"current_versions", currentSecretVersions)
-		return nil
+	// Entries for secrets that are no longer referenced are dropped from the versions annotation
+	reportStaleSecretVersions(r.Recorder, secret, StaleSecretVersions(lastKnownVersions, currentSecretVersions), log)
+
+	// Versions recorded on the Secret cannot tell that its path was deleted in Vault, so
+	// Secrets reconciled periodically check that the path still exists and restore it
+	if !hasChanges && r.StateBackend != StateBackendVault && len(lastKnownVersions) > 0 && r.getReconcileInterval(secret) > 0 {
+		if missing := missingVaultPaths(ctx, r.VaultClient, []string{resolvedPath}, log); len(missing) > 0 {
+			log.Info("vault path deleted out of band, restoring it", "path", resolvedPath)
+			hasChanges = true
+		}
+	}
+
+	if !hasChanges && (len(lastKnownVersions) > 0 || r.StateBackend == StateBackendVault) {
+		if err := applyKVMetadata(ctx, r.VaultClient, r.Recorder, secret, kvMetadata, []string{resolvedPath}, log); err != nil {
+			return 0, err
+		}
+		if err := recordSyncedPath(ctx, r.Client, r.VaultClient, r.Recorder, r.Inventory, "secret", secret, resolvedPath, nil, false, log); err != nil {
+			return 0, err
+		}
+		if r.StateBackend != StateBackendVault {
+			recordSyncedConfig(ctx, r.Client, secret, log)
+		}
+		r.Inventory.Set("secret", client.ObjectKeyFromObject(secret), []string{resolvedPath})
+		r.Inventory.SetPreserved("secret", client.ObjectKeyFromObject(secret), r.PreserveOnDelete.Preserves(secret))
+		return 0, nil
 	}
 
-	if hasChanges {
+	if hasChanges && r.StateBackend != StateBackendVault {
 		log.Info("secret rotation detected, syncing to vault",
 			"changed_secrets", syncCtx.GetChangedSecrets(lastKnownVersions, currentSecretVersions))
 	}
 
+	// Drop keys removed from the secret unless they are retained
+	writeData, err := retainDeletedKeys(ctx, r.VaultClient, secret, resolvedPath, vaultData, log)
+	if err != nil {
+		return 0, err
+	}
+
+	// Let the policy hook deny the write, as written, before anything reaches Vault
+	if err := checkWritePolicy(ctx, r.Policy, r.Recorder, "Secret", secret, resolvedPath, r.ClusterName, writeData); err != nil {
+		log.Error(err, "vault write denied by policy", "path", resolvedPath)
+		return 0, err
+	}
+
 	// Write to Vault
-	if err := syncCtx.WriteSecretToVault(ctx, vaultPath, vaultData, resourceInfo); err != nil {
-		return err
+	if err := syncCtx.WriteSecretToVault(ctx, vaultPath, writeData, resourceInfo); err != nil {
+		return len(vaultData), err
 	}
+	r.SecretSizes.Record(r.Recorder, secret, resolvedPath, writeData)
 
 	// Update secret versions annotation for future rotation detection
-	err = UpdateSecretVersionsAnnotation(ctx, r.Client, secret, currentSecretVersions)
-	if err != nil {
+	if r.StateBackend == StateBackendVault {
+		// A hash that could not be recorded only means the data is written again on the next sync
+		if err := recordContentHash(ctx, r.VaultClient, r.StateHashKey, resolvedPath, vaultData); err != nil {
+			log.Error(err, "failed to record content hash in vault", "path", resolvedPath)
+		}
+		if err := consumeForceSync(ctx, r.Client, secret); err != nil {
+			log.Error(err, "failed to record force sync as applied")
+		}
+	} else if err := UpdateSecretVersionsAnnotation(ctx, r.Client, secret, currentSecretVersions); err != nil {
 		log.Error(err, "failed to update secret versions annotation", "versions", currentSecretVersions)
 		// Don't fail the whole operation for annotation update failure
 	}
 
-	return nil
+	if err := applyKVMetadata(ctx, r.VaultClient, r.Recorder, secret, kvMetadata, []string{resolvedPath}, log); err != nil {
+		return len(vaultData), err
+	}
+
+	// The previous path is only deleted once the secret has been written to the new one
+	if err := recordSyncedPath(ctx, r.Client, r.VaultClient, r.Recorder, r.Inventory, "secret", secret, resolvedPath, move, r.PreserveOnDelete.Preserves(secret), log); err != nil {
+		return len(vaultData), err
+	}
+
+	r.Inventory.Set("secret", client.ObjectKeyFromObject(secret), []string{resolvedPath})
+	r.Inventory.SetPreserved("secret", client.ObjectKeyFromObject(secret), r.PreserveOnDelete.Preserves(secret))
+	return len(vaultData), nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		Complete(r)
+	var watchOpts []ctrlbuilder.WatchesOption
+	if r.Propagation != nil {
+		watchOpts = append(watchOpts, ctrlbuilder.WithPredicates(r.Propagation.Predicate()))
+	}
+	// Secrets are watched with PriorityEventHandler so vault-sync.io/priority orders the queue
+	name := r.Name
+	if name == "" {
+		name = "secret"
+	}
+	builder := ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		Watches(&corev1.Secret{}, PriorityEventHandler{}, watchOpts...)
+	if len(r.Namespaces) > 0 {
+		builder = builder.WithEventFilter(NamespaceFilter(r.Namespaces))
+	}
+	return builder.Complete(r)
 }
 
 // getLastKnownSecretVersions retrieves the last known secret versions from secret annotations.
@@ -236,35 +553,38 @@ func (r *SecretReconciler) getLastKnownSecretVersions(secret *corev1.Secret) map
 
 // isRotationCheckDisabled checks if secret rotation detection is disabled for this secret.
 func (r *SecretReconciler) isRotationCheckDisabled(secret *corev1.Secret) bool {
-	rotationCheck, exists := secret.Annotations[VaultRotationCheckAnnotation]
-	return exists && rotationCheck == "disabled"
+	return rotationCheckDisabled(secret, r.RotationCheckPolicy)
 }
 
 // getReconcileInterval parses the reconciliation interval from the vault-sync.io/reconcile annotation.
-// Returns the duration if valid, or zero duration if disabled or invalid.
+// Returns the duration if valid, zero if disabled, or the default interval if unset or invalid.
 func (r *SecretReconciler) getReconcileInterval(secret *corev1.Secret) time.Duration {
 	reconcileValue, exists := secret.Annotations[VaultReconcileAnnotation]
-	if !exists || reconcileValue == "" || reconcileValue == "off" {
+	if reconcileValue == "off" {
 		return 0 // Disabled
 	}
+	if !exists || reconcileValue == "" {
+		return r.ReconcileBounds.Default(r.DefaultReconcileInterval)
+	}
 
 	duration, err := time.ParseDuration(reconcileValue)
 	if err != nil {
-		r.Log.Error(err, "invalid reconcile interval annotation, disabling periodic reconciliation",
+		r.Log.Error(err, "invalid reconcile interval annotation, using the default interval",
 			"secret", secret.Name,
 			"namespace", secret.Namespace,
-			"annotation_value", reconcileValue)
-		return 0 // Disabled on parse error
+			"annotation_value", reconcileValue,
+			"default", r.ReconcileBounds.Default(r.DefaultReconcileInterval))
+		return r.ReconcileBounds.Default(r.DefaultReconcileInterval)
 	}
 
-	// Enforce minimum interval of 30 seconds to prevent excessive reconciliation
-	if duration < 30*time.Second {
-		r.Log.Info("reconcile interval too short, using minimum of 30 seconds",
+	// Enforce the configured bounds to prevent excessive or too rare reconciliation
+	if enforced := r.ReconcileBounds.Clamp(duration); enforced != duration {
+		r.Log.Info("reconcile interval out of bounds, using the nearest bound",
 			"secret", secret.Name,
 			"namespace", secret.Namespace,
 			"requested", duration,
-			"enforced", 30*time.Second)
-		return 30 * time.Second
+			"enforced", enforced)
+		return enforced
 	}
 
 	return duration
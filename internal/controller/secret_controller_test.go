@@ -92,17 +92,35 @@ func TestSecretReconcilerGetReconcileInterval(t *testing.T) {
 			}
 		})
 	}
+
+	// Organization-wide bounds override the annotation
+	reconciler.ReconcileBounds = ReconcileIntervalBounds{Min: 5 * time.Minute, Max: 2 * time.Hour}
+	for value, expected := range map[string]time.Duration{"1m": 5 * time.Minute, "30m": 30 * time.Minute, "24h": 2 * time.Hour} {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{VaultReconcileAnnotation: value}}}
+		if result := reconciler.getReconcileInterval(secret); result != expected {
+			t.Errorf("getReconcileInterval(%s) with bounds = %v, expected %v", value, result, expected)
+		}
+	}
+
+	// The default interval applies to secrets without a valid annotation, within the bounds
+	reconciler.DefaultReconcileInterval = time.Minute
+	for value, expected := range map[string]time.Duration{"": 5 * time.Minute, "invalid": 5 * time.Minute, "off": 0, "1h": time.Hour} {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}}}
+		if value != "" {
+			secret.Annotations[VaultReconcileAnnotation] = value
+		}
+		if result := reconciler.getReconcileInterval(secret); result != expected {
+			t.Errorf("getReconcileInterval(%q) with a default = %v, expected %v", value, result, expected)
+		}
+	}
 }
 
 // TestSecretReconcilerIsRotationCheckDisabled tests the isRotationCheckDisabled method.
 func TestSecretReconcilerIsRotationCheckDisabled(t *testing.T) {
-	reconciler := &SecretReconciler{
-		Log: ctrl.Log.WithName("test"),
-	}
-
 	tests := []struct {
 		name        string
 		annotations map[string]string
+		policy      string
 		expected    bool
 	}{
 		{
@@ -131,10 +149,28 @@ func TestSecretReconcilerIsRotationCheckDisabled(t *testing.T) {
 			},
 			expected: false,
 		},
+		{
+			name:        "disabled operator-wide",
+			annotations: map[string]string{},
+			policy:      RotationCheckPolicyDisabled,
+			expected:    true,
+		},
+		{
+			name: "disabled annotation ignored when forced",
+			annotations: map[string]string{
+				VaultRotationCheckAnnotation: "disabled",
+			},
+			policy:   RotationCheckPolicyForced,
+			expected: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			reconciler := &SecretReconciler{
+				Log:                 ctrl.Log.WithName("test"),
+				RotationCheckPolicy: tt.policy,
+			}
 			secret := &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
 					Name:        "test-secret",
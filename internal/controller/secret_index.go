@@ -0,0 +1,162 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the index of the Secrets referenced by each synced workload.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// SecretReferenceIndex is the field index of the Secrets, as "namespace/name", referenced by a
+// workload with vault-sync.io/path: those listed in vault-sync.io/secrets, or else those its
+// pod template references. Secrets found through vault-sync.io/discover-from are not indexed.
+const SecretReferenceIndex = "vault-sync.io/secret-references"
+
+// indexedKinds records the kinds whose index is registered with a field indexer, so that
+// several reconcilers of one kind, e.g. of different profiles, share one index.
+var indexedKinds sync.Map // map[indexedKind]struct{}
+
+// indexedKind is a kind indexed by a field indexer.
+type indexedKind struct {
+	indexer client.FieldIndexer
+	kind    schema.GroupVersionKind
+}
+
+// indexSecretReferences registers SecretReferenceIndex for the kind synced by the reconciler,
+// unless it is already registered with indexer.
+func (r *DeploymentReconciler) indexSecretReferences(ctx context.Context, indexer client.FieldIndexer) error {
+	obj := r.newWorkload()
+	kind := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	if r.WorkloadKind != nil {
+		kind = r.WorkloadKind.GroupVersionKind
+	}
+	key := indexedKind{indexer: indexer, kind: kind}
+	if _, loaded := indexedKinds.LoadOrStore(key, struct{}{}); loaded {
+		return nil
+	}
+	if err := indexer.IndexField(ctx, obj, SecretReferenceIndex, r.referencedSecretKeys); err != nil {
+		indexedKinds.Delete(key)
+		return err
+	}
+	return nil
+}
+
+// referencedSecretKeys returns the Secrets referenced by a workload with vault-sync.io/path
+// as sorted "namespace/name" keys: those listed in its vault-sync.io/secrets annotation, or
+// else those referenced by its pod template. Invalid annotations and pod templates reference
+// nothing; the sync reports them.
+func (r *DeploymentReconciler) referencedSecretKeys(obj client.Object) []string {
+	if obj.GetAnnotations()[VaultPathAnnotation] == "" {
+		return nil
+	}
+
+	namespace := obj.GetNamespace()
+	var keys []string
+	if secretsConfig := obj.GetAnnotations()[VaultSecretsAnnotation]; secretsConfig != "" {
+		var secretConfigs []SecretConfig
+		if err := json.Unmarshal([]byte(secretsConfig), &secretConfigs); err != nil {
+			return nil
+		}
+		// References are indexed as if allowed; a denied one fails the sync anyway
+		policy := CrossNamespacePolicy{Enabled: true, AllowedNamespaces: []string{AnyNamespace}}
+		for _, secretConfig := range secretConfigs {
+			if key, err := policy.ResolveSecretRef(secretConfig.Name, namespace); err == nil {
+				keys = append(keys, key.String())
+			}
+		}
+	} else {
+		template, err := r.podTemplate(obj)
+		if err != nil {
+			return nil
+		}
+		for name := range r.extractSecretNamesFromPodTemplate(template, GetIgnoreContainers(obj)) {
+			keys = append(keys, types.NamespacedName{Namespace: namespace, Name: name}.String())
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// workloadsForSecret maps a Secret to requests for the workloads that reference it, looked up
+// in SecretReferenceIndex instead of listing the workloads of the namespace. Degraded
+// workloads are released from the retry budget, so the change is synced right away.
+func (r *DeploymentReconciler) workloadsForSecret(ctx context.Context, secret client.Object) []reconcile.Request {
+	list := r.newWorkloadList()
+	key := client.ObjectKeyFromObject(secret).String()
+	if err := r.List(ctx, list, client.MatchingFields{SecretReferenceIndex: key}); err != nil {
+		r.Log.Error(err, "unable to list workloads referencing secret", "secret", key)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	err := meta.EachListItem(list, func(item runtime.Object) error {
+		obj, err := meta.Accessor(item)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}})
+		// A changed Secret may fix what kept a degraded workload failing
+		r.RetryBudget.Release(r.kindLabel() + "/" + obj.GetNamespace() + "/" + obj.GetName())
+		return nil
+	})
+	if err != nil {
+		r.Log.Error(err, "unable to read workloads referencing secret", "secret", key)
+		return nil
+	}
+	return requests
+}
+
+// newWorkloadList returns an empty list of the kind synced by the reconciler.
+func (r *DeploymentReconciler) newWorkloadList() client.ObjectList {
+	if r.WorkloadKind == nil {
+		return &appsv1.DeploymentList{}
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(r.WorkloadKind.GroupVersion().WithKind(r.WorkloadKind.Kind + "List"))
+	return list
+}
+
+// secretContentChanged passes the Secret events that can change what a sync writes: creations,
+// deletions and updates of the data or type, but not of the metadata alone, such as the
+// annotations recorded by the operator.
+var secretContentChanged = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldSecret, ok := e.ObjectOld.(*corev1.Secret)
+		if !ok {
+			return true
+		}
+		newSecret, ok := e.ObjectNew.(*corev1.Secret)
+		if !ok {
+			return true
+		}
+		return oldSecret.Type != newSecret.Type || !secretDataEqual(oldSecret.Data, newSecret.Data)
+	},
+}
+
+// secretDataEqual reports whether two Secret data maps hold the same keys and values.
+func secretDataEqual(a, b map[string][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		other, ok := b[key]
+		if !ok || string(value) != string(other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// newIndexTestDeployment returns a Deployment whose pod template references the db Secret
+// from the app container and the proxy Secret from the proxy container.
+func newIndexTestDeployment(name string, annotations map[string]string) *appsv1.Deployment {
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Annotations: annotations}}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{
+		{Name: "app", EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}}}}},
+		{Name: "proxy", Env: []corev1.EnvVar{{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"}, Key: "token"},
+		}}}},
+	}
+	return deployment
+}
+
+func TestReferencedSecretKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    []string
+	}{
+		{name: "not synced", expected: nil},
+		{
+			name:        "auto-discovery",
+			annotations: map[string]string{VaultPathAnnotation: "secret/data/app"},
+			expected:    []string{"default/db", "default/proxy"},
+		},
+		{
+			name:        "ignored container",
+			annotations: map[string]string{VaultPathAnnotation: "secret/data/app", VaultIgnoreContainersAnnotation: "proxy"},
+			expected:    []string{"default/db"},
+		},
+		{
+			name: "secrets annotation",
+			annotations: map[string]string{
+				VaultPathAnnotation:    "secret/data/app",
+				VaultSecretsAnnotation: `[{"name":"api","keys":["key"]},{"name":"shared/ca","keys":["ca.crt"]},{"name":"api","keys":["other"]}]`,
+			},
+			expected: []string{"default/api", "shared/ca"},
+		},
+		{
+			name:        "invalid secrets annotation",
+			annotations: map[string]string{VaultPathAnnotation: "secret/data/app", VaultSecretsAnnotation: "db"},
+			expected:    nil,
+		},
+	}
+	r := &DeploymentReconciler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.referencedSecretKeys(newIndexTestDeployment("app", tt.annotations)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("referencedSecretKeys() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWorkloadsForSecret(t *testing.T) {
+	r := &DeploymentReconciler{Scheme: runtime.NewScheme(), Log: logr.Discard()}
+	r.Client = fake.NewClientBuilder().
+		WithObjects(
+			newIndexTestDeployment("web", map[string]string{VaultPathAnnotation: "secret/data/web"}),
+			newIndexTestDeployment("worker", map[string]string{VaultPathAnnotation: "secret/data/worker", VaultIgnoreContainersAnnotation: "app"}),
+			newIndexTestDeployment("unsynced", nil),
+		).
+		WithIndex(&appsv1.Deployment{}, SecretReferenceIndex, r.referencedSecretKeys).
+		Build()
+
+	names := func(secret string) []string {
+		var names []string
+		for _, req := range r.workloadsForSecret(context.Background(), &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secret, Namespace: "default"}}) {
+			names = append(names, req.Name)
+		}
+		return names
+	}
+	if got := names("db"); !reflect.DeepEqual(got, []string{"web"}) {
+		t.Errorf("workloads for db = %v, expected [web]", got)
+	}
+	if got := names("proxy"); !reflect.DeepEqual(got, []string{"web", "worker"}) {
+		t.Errorf("workloads for proxy = %v, expected [web worker]", got)
+	}
+	if got := names("other"); len(got) != 0 {
+		t.Errorf("workloads for other = %v, expected none", got)
+	}
+
+	// A change to a referenced Secret releases a degraded workload
+	r.RetryBudget = NewRetryBudget(1, 0, time.Hour)
+	r.RetryBudget.Failure("deployment/default/web", "1")
+	if r.RetryBudget.Hold("deployment/default/web", "1") == 0 {
+		t.Fatal("expected web to be degraded")
+	}
+	names("db")
+	if delay := r.RetryBudget.Hold("deployment/default/web", "1"); delay != 0 {
+		t.Errorf("Hold() after a referenced Secret changed = %v, expected 0", delay)
+	}
+}
+
+func TestSecretContentChanged(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default", ResourceVersion: "1"},
+		Data:       map[string][]byte{"password": []byte("old")},
+	}
+	annotated := secret.DeepCopy()
+	annotated.ResourceVersion = "2"
+	annotated.Annotations = map[string]string{VaultSecretVersionsAnnotation: "{}"}
+	rotated := secret.DeepCopy()
+	rotated.ResourceVersion = "3"
+	rotated.Data["password"] = []byte("new")
+	retyped := secret.DeepCopy()
+	retyped.Type = corev1.SecretTypeOpaque
+
+	tests := []struct {
+		name     string
+		updated  *corev1.Secret
+		expected bool
+	}{
+		{name: "metadata only", updated: annotated, expected: false},
+		{name: "data changed", updated: rotated, expected: true},
+		{name: "type changed", updated: retyped, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := secretContentChanged.Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: tt.updated}); got != tt.expected {
+				t.Errorf("Update() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+	if !secretContentChanged.Create(event.CreateEvent{Object: secret}) || !secretContentChanged.Delete(event.DeleteEvent{Object: secret}) {
+		t.Error("creations and deletions of secrets must pass")
+	}
+}
@@ -0,0 +1,103 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the tracking of the size of the data written to Vault.
+package controller
+
+import (
+	"encoding/json"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// DefaultLargeSecretThreshold is the default size in bytes above which a write is reported
+// as large. Vault performance degrades with KV entries of several hundred kilobytes.
+const DefaultLargeSecretThreshold = 256 * 1024
+
+// SecretSizeTracker records the serialized size of the data written to each Vault path and
+// warns about writes larger than Threshold. All methods are safe to call on a nil tracker,
+// which disables tracking.
+type SecretSizeTracker struct {
+	// Threshold is the size in bytes above which a write is reported (0 disables the warnings)
+	Threshold int
+
+	mu sync.Mutex
+	// sizes maps a Vault path to the size of its last write
+	sizes map[string]int
+}
+
+// NewSecretSizeTracker creates a tracker warning about writes larger than threshold bytes.
+func NewSecretSizeTracker(threshold int) *SecretSizeTracker {
+	return &SecretSizeTracker{
+		Threshold: threshold,
+		sizes:     make(map[string]int),
+	}
+}
+
+// SecretDataSize returns the size in bytes of data serialized as JSON, as sent to Vault.
+func SecretDataSize(data map[string]interface{}) int {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return 0
+	}
+	return len(encoded)
+}
+
+// Record records a successful write of data to path on behalf of obj, exports its size and
+// the change from the previous write, and records a LargeSecret warning on obj when the
+// write exceeds the threshold. It returns the size of the write.
+func (t *SecretSizeTracker) Record(recorder events.EventRecorder, obj client.Object, path string, data map[string]interface{}) int {
+	if t == nil {
+		return 0
+	}
+
+	size := SecretDataSize(data)
+	t.mu.Lock()
+	previous, known := t.sizes[path]
+	t.sizes[path] = size
+	t.mu.Unlock()
+
+	label := metrics.PathLabel(path)
+	metrics.SecretSizeBytes.WithLabelValues(label).Set(float64(size))
+	if known {
+		metrics.SecretSizeDelta.WithLabelValues(label).Set(float64(size - previous))
+	}
+
+	if t.Threshold > 0 && size > t.Threshold {
+		metrics.LargeSecretWrites.WithLabelValues(obj.GetNamespace()).Inc()
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "LargeSecret", "Sync",
+			"Wrote %d bytes to %s, more than the %d byte threshold; large entries degrade Vault performance",
+			size, path, t.Threshold)
+	}
+	return size
+}
+
+// Forget drops the size of a path deleted from Vault.
+func (t *SecretSizeTracker) Forget(path string) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	delete(t.sizes, path)
+	t.mu.Unlock()
+
+	label := metrics.PathLabel(path)
+	metrics.SecretSizeBytes.DeleteLabelValues(label)
+	metrics.SecretSizeDelta.DeleteLabelValues(label)
+}
+
+// Size returns the size of the last write to path and whether a write was recorded.
+func (t *SecretSizeTracker) Size(path string) (int, bool) {
+	if t == nil {
+		return 0, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	size, known := t.sizes[path]
+	return size, known
+}
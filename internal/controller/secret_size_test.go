@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+func TestSecretSizeTracker(t *testing.T) {
+	const path = "secret/data/size-test"
+	tracker := NewSecretSizeTracker(64)
+	recorder := events.NewFakeRecorder(10)
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "size-test"
+
+	small := map[string]interface{}{"password": "hunter2"}
+	size := tracker.Record(recorder, secret, path, small)
+	if size != len(`{"password":"hunter2"}`) {
+		t.Errorf("Record() = %d, expected the JSON size of the data", size)
+	}
+	if value := testutil.ToFloat64(metrics.SecretSizeBytes.WithLabelValues(metrics.PathLabel(path))); value != float64(size) {
+		t.Errorf("secret size gauge = %v, expected %d", value, size)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("a write below the threshold recorded %q", <-recorder.Events)
+	}
+
+	large := map[string]interface{}{"certificate": strings.Repeat("x", 100)}
+	largeSize := tracker.Record(recorder, secret, path, large)
+	if value := testutil.ToFloat64(metrics.SecretSizeDelta.WithLabelValues(metrics.PathLabel(path))); value != float64(largeSize-size) {
+		t.Errorf("secret size delta gauge = %v, expected %d", value, largeSize-size)
+	}
+	if value := testutil.ToFloat64(metrics.LargeSecretWrites.WithLabelValues("size-test")); value != 1 {
+		t.Errorf("large secret writes = %v, expected 1", value)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "LargeSecret") {
+			t.Errorf("event = %q, expected a LargeSecret warning", event)
+		}
+	default:
+		t.Error("a write above the threshold recorded no event")
+	}
+
+	tracker.Forget(path)
+	if _, known := tracker.Size(path); known {
+		t.Error("a forgotten path still has a size")
+	}
+	if series := testutil.CollectAndCount(metrics.SecretSizeBytes); series != 0 {
+		t.Errorf("secret size series after Forget = %d, expected 0", series)
+	}
+
+	// A nil tracker disables tracking
+	var nilTracker *SecretSizeTracker
+	if size := nilTracker.Record(recorder, secret, path, large); size != 0 {
+		t.Errorf("nil tracker Record() = %d, expected 0", size)
+	}
+	nilTracker.Forget(path)
+}
@@ -0,0 +1,76 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the per-namespace policy of Secret types allowed to be synced.
+package controller
+
+import (
+	"path"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultSkippedSecretTypes are the Secret types never synced unless configured otherwise.
+// Service account and bootstrap tokens grant access to the cluster itself.
+var DefaultSkippedSecretTypes = []string{
+	string(corev1.SecretTypeServiceAccountToken),
+	string(corev1.SecretTypeBootstrapToken),
+}
+
+// SecretTypeRule allows or denies Secret types in the namespaces it applies to.
+type SecretTypeRule struct {
+	// Namespaces lists namespace patterns in path.Match syntax, such as "team-*". A rule
+	// without namespaces applies to every namespace.
+	Namespaces []string
+	// Allow lists the only types that may be synced, when not empty
+	Allow []string
+	// Deny lists types that are never synced
+	Deny []string
+}
+
+// SecretTypePolicy decides per namespace which Secret types may be synced, in addition to
+// the operator-wide SkippedSecretTypes. It is evaluated for annotated Secrets as well as for
+// Secrets discovered or referenced by workloads, so annotating a Secret cannot export a type
+// the policy denies. The zero value allows every type.
+type SecretTypePolicy struct {
+	Rules []SecretTypeRule
+}
+
+// Denies reports whether secret may not be synced: a rule applying to its namespace denies
+// its type, or allows only other types. Secrets without a type are Opaque.
+func (p SecretTypePolicy) Denies(secret *corev1.Secret) bool {
+	secretType := string(secret.Type)
+	if secretType == "" {
+		secretType = string(corev1.SecretTypeOpaque)
+	}
+	for _, rule := range p.Rules {
+		if !rule.appliesTo(secret.Namespace) {
+			continue
+		}
+		if slices.Contains(rule.Deny, secretType) {
+			return true
+		}
+		if len(rule.Allow) > 0 && !slices.Contains(rule.Allow, secretType) {
+			return true
+		}
+	}
+	return false
+}
+
+// appliesTo reports whether the rule applies to namespace.
+func (r SecretTypeRule) appliesTo(namespace string) bool {
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	for _, pattern := range r.Namespaces {
+		if matched, _ := path.Match(pattern, namespace); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// secretTypeDenied reports whether secret may not be synced, because its type is skipped
+// operator-wide or denied by the policy of its namespace.
+func secretTypeDenied(secret *corev1.Secret, skippedTypes []string, policy SecretTypePolicy) bool {
+	return IsSecretTypeSkipped(secret, skippedTypes) || policy.Denies(secret)
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestSecretTypePolicyDenies(t *testing.T) {
+	policy := SecretTypePolicy{Rules: []SecretTypeRule{
+		{Namespaces: []string{"team-*"}, Allow: []string{"Opaque", "kubernetes.io/tls"}},
+		{Deny: []string{"kubernetes.io/dockerconfigjson"}},
+	}}
+
+	tests := []struct {
+		name       string
+		namespace  string
+		secretType corev1.SecretType
+		expected   bool
+	}{
+		{name: "allowed type", namespace: "team-a", secretType: corev1.SecretTypeTLS},
+		{name: "untyped secrets are opaque", namespace: "team-a"},
+		{name: "type outside the allowlist", namespace: "team-a", secretType: corev1.SecretTypeBasicAuth, expected: true},
+		{name: "allowlist of other namespaces", namespace: "payments", secretType: corev1.SecretTypeBasicAuth},
+		{name: "denied everywhere", namespace: "payments", secretType: corev1.SecretTypeDockerConfigJson, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{Type: tt.secretType}
+			secret.Namespace = tt.namespace
+			if got := policy.Denies(secret); got != tt.expected {
+				t.Errorf("Denies() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+
+	if (SecretTypePolicy{}).Denies(&corev1.Secret{Type: corev1.SecretTypeBootstrapToken}) {
+		t.Errorf("expected the zero policy to allow every type")
+	}
+}
+
+// TestSecretTypePolicyReconcile tests that denied types are neither synced when annotated
+// nor when discovered by a Deployment.
+func TestSecretTypePolicyReconcile(t *testing.T) {
+	ctx := context.Background()
+	policy := SecretTypePolicy{Rules: []SecretTypeRule{{Namespaces: []string{"team-*"}, Allow: []string{"Opaque"}}}}
+
+	token := &corev1.Secret{Type: corev1.SecretTypeBootstrapToken, Data: map[string][]byte{"token-id": []byte("abcdef")}}
+	token.Name = "bootstrap-token-abcdef"
+	token.Namespace = "team-a"
+	token.Annotations = map[string]string{VaultPathAnnotation: "secret/data/token"}
+	auth := &corev1.Secret{Type: corev1.SecretTypeBasicAuth, Data: map[string][]byte{"username": []byte("app")}}
+	auth.Name = "web-auth"
+	auth.Namespace = "team-a"
+	config := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	config.Name = "web-config"
+	config.Namespace = "team-a"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "team-a"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		EnvFrom: []corev1.EnvFromSource{
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: auth.Name}}},
+			{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: config.Name}}},
+		},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(token, auth, config, deployment).Build()
+	vaultClient := &fakeVault{}
+	secrets := &SecretReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(), VaultClient: vaultClient,
+		SkippedSecretTypes: DefaultSkippedSecretTypes}
+	deployments := &DeploymentReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(), VaultClient: vaultClient,
+		SecretTypes: policy}
+	for i := 0; i < 2; i++ {
+		if _, err := secrets.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(token)}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		if _, err := deployments.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+
+	if _, ok := vaultClient.secrets["secret/data/token"]; ok {
+		t.Errorf("expected the bootstrap token not to be synced by default")
+	}
+	if _, ok := vaultClient.secrets["secret/data/web/web-auth"]; ok {
+		t.Errorf("expected the basic-auth secret outside the allowlist not to be discovered")
+	}
+	if _, ok := vaultClient.secrets["secret/data/web/web-config"]; !ok {
+		t.Errorf("expected the opaque secret to be synced, got %v", vaultClient.secrets)
+	}
+}
@@ -0,0 +1,295 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the SharedSecretRegistry used to deduplicate writes of Secrets
+// that are referenced by many Deployments.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// SharedSecretReferencesDir names the directory below the shared-secrets path holding the
+// references to each canonical path, so the references outlive the operator process. The
+// references to <base>/<namespace>/<name> are the keys of <base>/.refs/<namespace>/<name>,
+// one per Deployment named <namespace>/<name>. Kubernetes names cannot start with a dot.
+const SharedSecretReferencesDir = ".refs"
+
+// SharedSecretRegistry tracks Secrets written once to a canonical Vault path and the
+// Deployments that reference them. A Secret is only rewritten when its resource version
+// changes, regardless of how many Deployments reference it.
+type SharedSecretRegistry struct {
+	// BasePath is the Vault path under which shared Secrets are written as <base>/<namespace>/<name>
+	BasePath string
+
+	mu      sync.Mutex
+	entries map[string]*sharedSecretEntry
+}
+
+// sharedSecretEntry holds the state of a single canonical path.
+type sharedSecretEntry struct {
+	namespace       string
+	name            string
+	resourceVersion string
+	references      map[string]struct{}
+	// persisted holds the references known to be recorded in Vault
+	persisted map[string]struct{}
+}
+
+// NewSharedSecretRegistry creates a registry that writes shared Secrets below basePath.
+func NewSharedSecretRegistry(basePath string) *SharedSecretRegistry {
+	return &SharedSecretRegistry{
+		BasePath: strings.TrimSuffix(basePath, "/"),
+		entries:  make(map[string]*sharedSecretEntry),
+	}
+}
+
+// CanonicalPath returns the canonical Vault path for a shared Secret.
+func (s *SharedSecretRegistry) CanonicalPath(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", s.BasePath, namespace, name)
+}
+
+// AddReference records that ref uses the Secret at canonicalPath and reports whether
+// the Secret still needs to be written for the given resource version.
+func (s *SharedSecretRegistry) AddReference(canonicalPath, namespace, name, resourceVersion, ref string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[canonicalPath]
+	if !exists {
+		entry = &sharedSecretEntry{
+			namespace:  namespace,
+			name:       name,
+			references: make(map[string]struct{}),
+			persisted:  make(map[string]struct{}),
+		}
+		s.entries[canonicalPath] = entry
+	}
+	entry.references[ref] = struct{}{}
+	metrics.SharedSecretReferences.WithLabelValues(namespace, name).Set(float64(len(entry.references)))
+
+	return entry.resourceVersion != resourceVersion
+}
+
+// MarkWritten records that the Secret at canonicalPath was written at resourceVersion.
+func (s *SharedSecretRegistry) MarkWritten(canonicalPath, resourceVersion string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exists := s.entries[canonicalPath]; exists {
+		entry.resourceVersion = resourceVersion
+	}
+}
+
+// ReferencePersisted reports whether the reference of ref to canonicalPath is known to be
+// recorded in Vault.
+func (s *SharedSecretRegistry) ReferencePersisted(canonicalPath, ref string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[canonicalPath]
+	if !exists {
+		return false
+	}
+	_, persisted := entry.persisted[ref]
+	return persisted
+}
+
+// MarkPersisted records that the reference of ref to canonicalPath is recorded in Vault.
+func (s *SharedSecretRegistry) MarkPersisted(canonicalPath, ref string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, exists := s.entries[canonicalPath]; exists {
+		entry.persisted[ref] = struct{}{}
+	}
+}
+
+// RemoveReferences drops every reference held by ref and returns the sorted canonical paths
+// it referenced. The references recorded in Vault are removed by the caller.
+func (s *SharedSecretRegistry) RemoveReferences(ref string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paths []string
+	for path, entry := range s.entries {
+		if _, exists := entry.references[ref]; !exists {
+			continue
+		}
+		paths = append(paths, path)
+		delete(entry.references, ref)
+		delete(entry.persisted, ref)
+		metrics.SharedSecretReferences.WithLabelValues(entry.namespace, entry.name).Set(float64(len(entry.references)))
+		if len(entry.references) == 0 {
+			delete(s.entries, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// References returns the sorted list of references for canonicalPath.
+func (s *SharedSecretRegistry) References(canonicalPath string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.entries[canonicalPath]
+	if !exists {
+		return nil
+	}
+
+	refs := make([]string, 0, len(entry.references))
+	for ref := range entry.references {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+// sharedReferencesPath returns the Vault path recording the references to canonicalPath,
+// which ends with <namespace>/<name>.
+func sharedReferencesPath(canonicalPath string) string {
+	base, name := path.Split(canonicalPath)
+	base, namespace := path.Split(strings.TrimSuffix(base, "/"))
+	return base + SharedSecretReferencesDir + "/" + namespace + "/" + name
+}
+
+// readSharedReferences returns the references recorded for canonicalPath.
+func readSharedReferences(ctx context.Context, reader VaultReader, canonicalPath string) (map[string]interface{}, error) {
+	refs, err := reader.ReadSecret(ctx, sharedReferencesPath(canonicalPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read references of shared secret path %s: %w", canonicalPath, err)
+	}
+	if refs == nil {
+		refs = make(map[string]interface{})
+	}
+	return refs, nil
+}
+
+// persistSharedReferences records the references of ref to canonicalPaths in Vault, skipping
+// references already recorded by this process. Clients that cannot read secrets keep the
+// references in memory only.
+func (r *DeploymentReconciler) persistSharedReferences(ctx context.Context, canonicalPaths []string, ref string) error {
+	reader, ok := r.VaultClient.(VaultReader)
+	if !ok {
+		return nil
+	}
+	for _, canonicalPath := range canonicalPaths {
+		if r.SharedSecrets.ReferencePersisted(canonicalPath, ref) {
+			continue
+		}
+		if err := r.persistSharedReference(ctx, reader, canonicalPath, ref); err != nil {
+			return err
+		}
+		r.SharedSecrets.MarkPersisted(canonicalPath, ref)
+	}
+	return nil
+}
+
+// persistSharedReference adds ref to the references recorded for canonicalPath.
+func (r *DeploymentReconciler) persistSharedReference(ctx context.Context, reader VaultReader, canonicalPath, ref string) error {
+	// The references are rewritten as a whole, so concurrent updates of a path are serialized
+	unlock, err := lockVaultPath(ctx, r.VaultClient, canonicalPath)
+	if err != nil {
+		return fmt.Errorf("failed to lock vault path %s: %w", canonicalPath, err)
+	}
+	defer unlock()
+
+	refs, err := readSharedReferences(ctx, reader, canonicalPath)
+	if err != nil {
+		return err
+	}
+	if _, exists := refs[ref]; exists {
+		return nil
+	}
+	refs[ref] = "true"
+	if err := r.VaultClient.WriteSecret(ctx, sharedReferencesPath(canonicalPath), refs); err != nil {
+		return fmt.Errorf("failed to record reference of %s to shared secret path %s: %w", ref, canonicalPath, err)
+	}
+	return nil
+}
+
+// releaseSharedSecrets removes the references of a deleted Deployment to the canonical paths
+// of its auto-discovered secrets, and deletes each path left without references unless the
+// Deployment is preserved on deletion. The paths are those known to the registry and, as
+// the registry starts empty after a restart, those of the secret versions recorded on the
+// Deployment. Paths whose references do not include the Deployment are left alone.
+func (r *DeploymentReconciler) releaseSharedSecrets(ctx context.Context, deployment client.Object, preserve bool, log logr.Logger) error {
+	ref := deployment.GetNamespace() + "/" + deployment.GetName()
+	paths := r.SharedSecrets.RemoveReferences(ref)
+	for secretName := range r.getLastKnownSecretVersions(deployment) {
+		paths = append(paths, r.autoDiscoveredSecretPath(deployment, "", secretName))
+	}
+	slices.Sort(paths)
+	paths = slices.Compact(paths)
+
+	reader, ok := r.VaultClient.(VaultReader)
+	if !ok {
+		// Without recorded references, shared data is retained
+		return nil
+	}
+
+	for _, path := range paths {
+		if err := r.releaseSharedSecret(ctx, reader, path, ref, preserve, log); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseSharedSecret removes ref from the references recorded for the canonical path and
+// deletes the path once no references are left.
+func (r *DeploymentReconciler) releaseSharedSecret(ctx context.Context, reader VaultReader, path, ref string, preserve bool, log logr.Logger) error {
+	unlock, err := lockVaultPath(ctx, r.VaultClient, path)
+	if err != nil {
+		return fmt.Errorf("failed to lock vault path %s: %w", path, err)
+	}
+	defer unlock()
+
+	refs, err := readSharedReferences(ctx, reader, path)
+	if err != nil {
+		return err
+	}
+	if _, exists := refs[ref]; !exists {
+		return nil
+	}
+	delete(refs, ref)
+
+	// References of other Deployments that this process has not recorded yet keep the path too
+	if len(refs) > 0 {
+		if err := r.VaultClient.WriteSecret(ctx, sharedReferencesPath(path), refs); err != nil {
+			return fmt.Errorf("failed to remove reference from shared secret path %s: %w", path, err)
+		}
+	}
+	if remaining := len(refs); remaining > 0 || len(r.SharedSecrets.References(path)) > 0 || preserve {
+		log.V(1).Info("keeping shared secret path", "path", path, "references", remaining, "preserved", preserve)
+		if remaining == 0 {
+			return r.deleteSharedReferences(ctx, path)
+		}
+		return nil
+	}
+	// The path goes before its references, so a failed deletion is retried
+	if err := r.VaultClient.DeleteSecret(ctx, path); err != nil {
+		return fmt.Errorf("failed to delete shared secret path %s: %w", path, err)
+	}
+	r.SecretSizes.Forget(path)
+	log.Info("deleted shared secret path after its last reference was removed", "path", path)
+	return r.deleteSharedReferences(ctx, path)
+}
+
+// deleteSharedReferences deletes the references recorded for the canonical path.
+func (r *DeploymentReconciler) deleteSharedReferences(ctx context.Context, path string) error {
+	if err := r.VaultClient.DeleteSecret(ctx, sharedReferencesPath(path)); err != nil {
+		return fmt.Errorf("failed to remove references of shared secret path %s: %w", path, err)
+	}
+	return nil
+}
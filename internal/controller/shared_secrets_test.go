@@ -0,0 +1,172 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// TestSharedSecretRegistry tests write deduplication and reference tracking.
+func TestSharedSecretRegistry(t *testing.T) {
+	registry := NewSharedSecretRegistry("secret/data/shared/")
+
+	path := registry.CanonicalPath("default", "db-credentials")
+	if path != "secret/data/shared/default/db-credentials" {
+		t.Fatalf("CanonicalPath() = %v", path)
+	}
+
+	if !registry.AddReference(path, "default", "db-credentials", "100", "default/app-a") {
+		t.Errorf("first reference should require a write")
+	}
+	registry.MarkWritten(path, "100")
+
+	if registry.AddReference(path, "default", "db-credentials", "100", "default/app-b") {
+		t.Errorf("second reference at the same version should not require a write")
+	}
+
+	if !registry.AddReference(path, "default", "db-credentials", "101", "default/app-b") {
+		t.Errorf("new resource version should require a write")
+	}
+
+	refs := registry.References(path)
+	if len(refs) != 2 || refs[0] != "default/app-a" || refs[1] != "default/app-b" {
+		t.Errorf("References() = %v, expected [default/app-a default/app-b]", refs)
+	}
+
+	registry.MarkPersisted(path, "default/app-a")
+	if !registry.ReferencePersisted(path, "default/app-a") || registry.ReferencePersisted(path, "default/app-b") {
+		t.Errorf("ReferencePersisted() does not match the persisted references")
+	}
+
+	if paths := registry.RemoveReferences("default/app-a"); len(paths) != 1 || paths[0] != path {
+		t.Errorf("RemoveReferences() = %v, expected [%s]", paths, path)
+	}
+	registry.RemoveReferences("default/app-b")
+	if refs := registry.References(path); refs != nil {
+		t.Errorf("References() after removal = %v, expected nil", refs)
+	}
+}
+
+// TestSharedSecretReferencesPersisted tests that references to a canonical path are recorded
+// in Vault, and that the path is deleted with its last reference even after
+// the in-memory registry was lost to a restart.
+func TestSharedSecretReferencesPersisted(t *testing.T) {
+	ctx := context.Background()
+	const canonicalPath = "secret/data/shared/default/db"
+	const referencesPath = "secret/data/shared/.refs/default/db"
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	objects := []client.Object{secret}
+	for _, name := range []string{"web", "api"} {
+		deployment := &appsv1.Deployment{}
+		deployment.Name = name
+		deployment.Namespace = "default"
+		deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/" + name}
+		deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+			Name:    "app",
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name}}}},
+		}}
+		objects = append(objects, deployment)
+	}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Client:        k8sClient,
+		Scheme:        runtime.NewScheme(),
+		Log:           logr.Discard(),
+		VaultClient:   vaultClient,
+		SharedSecrets: NewSharedSecretRegistry("secret/data/shared"),
+	}
+	reconcileDeployment := func(name string) {
+		t.Helper()
+		req := reconcile.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: name}}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile(%s) error = %v", name, err)
+		}
+	}
+	deleteDeployment := func(name string) {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, deployment); err != nil {
+			t.Fatalf("failed to get deployment: %v", err)
+		}
+		if err := k8sClient.Delete(ctx, deployment); err != nil {
+			t.Fatalf("failed to delete deployment: %v", err)
+		}
+		reconcileDeployment(name)
+	}
+
+	for _, name := range []string{"web", "api"} {
+		reconcileUntilSynced(t, r, reconcile.Request{NamespacedName: client.ObjectKey{Namespace: "default", Name: name}})
+	}
+	refs := vaultClient.secrets[referencesPath]
+	if refs["default/web"] == nil || refs["default/api"] == nil {
+		t.Fatalf("references = %v, expected both references", refs)
+	}
+
+	// A restart loses the in-memory references
+	r.SharedSecrets = NewSharedSecretRegistry("secret/data/shared")
+
+	deleteDeployment("web")
+	if _, exists := vaultClient.secrets[canonicalPath]; !exists {
+		t.Fatalf("canonical path deleted while still referenced")
+	}
+	if _, exists := vaultClient.secrets[referencesPath]["default/web"]; exists {
+		t.Errorf("reference of the deleted deployment still recorded: %v", vaultClient.secrets[referencesPath])
+	}
+
+	deleteDeployment("api")
+	if _, exists := vaultClient.secrets[canonicalPath]; exists {
+		t.Errorf("canonical path left in vault after its last reference was removed")
+	}
+	if _, exists := vaultClient.secrets[referencesPath]; exists {
+		t.Errorf("references left in vault after the canonical path was deleted")
+	}
+}
+
+// TestSharedReferencesPath tests that references are recorded below the shared-secrets path.
+func TestSharedReferencesPath(t *testing.T) {
+	for canonicalPath, expected := range map[string]string{
+		"secret/data/shared/default/db": "secret/data/shared/.refs/default/db",
+		"kv/prod/shared/payments/api":   "kv/prod/shared/.refs/payments/api",
+	} {
+		if got := sharedReferencesPath(canonicalPath); got != expected {
+			t.Errorf("sharedReferencesPath(%q) = %q, expected %q", canonicalPath, got, expected)
+		}
+	}
+}
+
+// TestSharedSecretManyReferences tests that more Deployments than Vault keeps custom metadata
+// entries can share a canonical path.
+func TestSharedSecretManyReferences(t *testing.T) {
+	ctx := context.Background()
+	const canonicalPath = "secret/data/shared/default/db"
+
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Log:           logr.Discard(),
+		VaultClient:   vaultClient,
+		SharedSecrets: NewSharedSecretRegistry("secret/data/shared"),
+	}
+	for i := 0; i < 100; i++ {
+		ref := fmt.Sprintf("default/app-%d", i)
+		r.SharedSecrets.AddReference(canonicalPath, "default", "db", "1", ref)
+		if err := r.persistSharedReferences(ctx, []string{canonicalPath}, ref); err != nil {
+			t.Fatalf("persistSharedReferences(%s) error = %v", ref, err)
+		}
+	}
+	if refs := vaultClient.secrets[sharedReferencesPath(canonicalPath)]; len(refs) != 100 {
+		t.Errorf("recorded %d references, expected 100", len(refs))
+	}
+}
@@ -0,0 +1,140 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the backends that record what was last synced for rotation detection.
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// State backends recording what was last synced.
+const (
+	// StateBackendAnnotation records the versions of the synced Secrets in the
+	// vault-sync.io/secret-versions annotation of each resource
+	StateBackendAnnotation = "annotation"
+	// StateBackendVault records a hash of the synced content in the KV v2 custom_metadata of
+	// each Vault path, so the operator does not write sync state back to the resources
+	StateBackendVault = "vault"
+)
+
+// ContentHashMetadataKey is the custom_metadata entry holding the hash of the synced content.
+const ContentHashMetadataKey = "vault-sync-content-hash"
+
+// MinStateHashKeyLength is the minimum length in bytes of the key of the content hashes
+// recorded by the Vault state backend.
+const MinStateHashKeyLength = 32
+
+// ValidateStateBackend checks the --state-backend setting.
+func ValidateStateBackend(backend string) error {
+	switch backend {
+	case StateBackendAnnotation, StateBackendVault:
+		return nil
+	default:
+		return fmt.Errorf("invalid state backend %q: expected %s or %s", backend, StateBackendAnnotation, StateBackendVault)
+	}
+}
+
+// ContentHash returns a hash of the data written to a Vault path. Map keys are encoded in
+// sorted order, so equal data always has the same hash.
+func ContentHash(data map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret data: %w", err)
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ContentHMAC returns an HMAC-SHA256 of the data written to a Vault path under key. Unlike
+// ContentHash, it cannot be used to test guesses of the values without the key, so it is what
+// the Vault state backend records in metadata readable by others.
+func ContentHMAC(key []byte, data map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret data: %w", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(encoded)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// LoadStateHashKey reads the key of the content hashes recorded by the Vault state backend
+// from a file, such as a mounted Secret. Surrounding whitespace is ignored.
+func LoadStateHashKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state hash key: %w", err)
+	}
+	key = bytes.TrimSpace(key)
+	if len(key) < MinStateHashKeyLength {
+		return nil, fmt.Errorf("state hash key in %s is %d bytes, expected at least %d", path, len(key), MinStateHashKeyLength)
+	}
+	return key, nil
+}
+
+// vaultContentUnchanged reports whether the content hash recorded at path matches data.
+// Clients without metadata support never match, so the data is always written. The custom
+// metadata outlives versions written out of band, so a matching hash is confirmed with the
+// keys of the current version where the subkeys endpoint serves them, without reading values.
+func vaultContentUnchanged(ctx context.Context, vc VaultWriterDeleter, key []byte, path string, data map[string]interface{}) (bool, error) {
+	reader, ok := vc.(metadataReader)
+	if !ok {
+		return false, nil
+	}
+	hash, err := ContentHMAC(key, data)
+	if err != nil {
+		return false, err
+	}
+	custom, err := reader.SecretCustomMetadata(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	if !hmac.Equal([]byte(custom[ContentHashMetadataKey]), []byte(hash)) {
+		return false, nil
+	}
+
+	keys, err := vaultSecretKeys(ctx, vc, path)
+	if err != nil {
+		return false, err
+	}
+	if keys == nil {
+		return true, nil
+	}
+	return len(keys) == len(data) && !slices.ContainsFunc(keys, func(key string) bool { _, ok := data[key]; return !ok }), nil
+}
+
+// recordContentHash records the hash of data written to path in its custom_metadata.
+func recordContentHash(ctx context.Context, vc VaultWriterDeleter, key []byte, path string, data map[string]interface{}) error {
+	writer, ok := vc.(metadataWriter)
+	if !ok {
+		return nil
+	}
+	hash, err := ContentHMAC(key, data)
+	if err != nil {
+		return err
+	}
+	_, err = writer.EnsureSecretMetadata(ctx, path, vault.KVMetadata{CustomMetadata: map[string]string{ContentHashMetadataKey: hash}})
+	return err
+}
+
+// consumeForceSync records a vault-sync.io/force-sync request as applied. It is the only
+// annotation the Vault state backend writes after a sync, and only when a resync was requested.
+func consumeForceSync(ctx context.Context, k8sClient client.Client, obj client.Object) error {
+	annotations := make(map[string]string)
+	markForceSyncConsumed(obj, annotations)
+	if len(annotations) == 0 {
+		return nil
+	}
+	return PatchAnnotations(ctx, k8sClient, obj, annotations)
+}
@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// fakeMetadataVault is a fakeVault that keeps the custom metadata of its paths.
+type fakeMetadataVault struct {
+	fakeVault
+	writes int
+	custom map[string]map[string]string
+}
+
+func (f *fakeMetadataVault) WriteSecret(ctx context.Context, path string, data map[string]interface{}) error {
+	f.writes++
+	return f.fakeVault.WriteSecret(ctx, path, data)
+}
+
+func (f *fakeMetadataVault) SecretCustomMetadata(_ context.Context, path string) (map[string]string, error) {
+	return f.custom[path], nil
+}
+
+func (f *fakeMetadataVault) EnsureSecretMetadata(_ context.Context, path string, md vault.KVMetadata) (bool, error) {
+	if f.custom == nil {
+		f.custom = make(map[string]map[string]string)
+	}
+	if f.custom[path] == nil {
+		f.custom[path] = make(map[string]string)
+	}
+	for key, value := range md.CustomMetadata {
+		f.custom[path][key] = value
+	}
+	for _, key := range md.RemoveCustomMetadata {
+		delete(f.custom[path], key)
+	}
+	return true, nil
+}
+
+func TestContentHash(t *testing.T) {
+	first, err := ContentHash(map[string]interface{}{"user": "app", "password": "s3cret"})
+	if err != nil {
+		t.Fatalf("ContentHash() error = %v", err)
+	}
+	second, _ := ContentHash(map[string]interface{}{"password": "s3cret", "user": "app"})
+	changed, _ := ContentHash(map[string]interface{}{"password": "rotated", "user": "app"})
+	if first != second {
+		t.Errorf("ContentHash() differs for equal data: %s, %s", first, second)
+	}
+	if first == changed {
+		t.Error("ContentHash() is equal for different data")
+	}
+}
+
+func TestContentHMAC(t *testing.T) {
+	data := map[string]interface{}{"password": "s3cret"}
+	first, err := ContentHMAC([]byte("key-one"), data)
+	if err != nil {
+		t.Fatalf("ContentHMAC() error = %v", err)
+	}
+	second, _ := ContentHMAC([]byte("key-two"), data)
+	plain, _ := ContentHash(data)
+	if first == second {
+		t.Error("ContentHMAC() is equal under different keys")
+	}
+	if first == plain {
+		t.Error("ContentHMAC() equals the unkeyed ContentHash()")
+	}
+}
+
+func TestLoadStateHashKey(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid")
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(valid, []byte(strings.Repeat("k", MinStateHashKeyLength)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(short, []byte("too-short"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := LoadStateHashKey(valid)
+	if err != nil || len(key) != MinStateHashKeyLength {
+		t.Errorf("LoadStateHashKey(valid) = %d bytes, %v, expected %d bytes without the newline", len(key), err, MinStateHashKeyLength)
+	}
+	if _, err := LoadStateHashKey(short); err == nil {
+		t.Error("LoadStateHashKey(short) succeeded")
+	}
+	if _, err := LoadStateHashKey(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadStateHashKey(missing) succeeded")
+	}
+}
+
+// subkeysMetadataVault is a fakeMetadataVault that also serves the keys of its secrets.
+type subkeysMetadataVault struct {
+	fakeMetadataVault
+}
+
+func (f *subkeysMetadataVault) SecretSubkeys(_ context.Context, path string) ([]string, error) {
+	return slices.Sorted(maps.Keys(f.secrets[path])), nil
+}
+
+// TestVaultContentUnchangedSubkeys tests that a matching content hash is confirmed with the
+// keys of the current version, so a version written out of band is not taken as unchanged.
+func TestVaultContentUnchangedSubkeys(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("test-state-hash-key")
+	data := map[string]interface{}{"username": "app", "password": "s3cret"}
+	vaultClient := &subkeysMetadataVault{}
+	if err := vaultClient.WriteSecret(ctx, "secret/data/db", data); err != nil {
+		t.Fatal(err)
+	}
+	if err := recordContentHash(ctx, vaultClient, key, "secret/data/db", data); err != nil {
+		t.Fatalf("recordContentHash() error = %v", err)
+	}
+
+	if unchanged, err := vaultContentUnchanged(ctx, vaultClient, key, "secret/data/db", data); err != nil || !unchanged {
+		t.Errorf("vaultContentUnchanged() = %v, %v, expected unchanged", unchanged, err)
+	}
+
+	// A write out of band keeps the custom metadata of the previous version
+	vaultClient.secrets["secret/data/db"] = map[string]interface{}{"username": "app"}
+	if unchanged, err := vaultContentUnchanged(ctx, vaultClient, key, "secret/data/db", data); err != nil || unchanged {
+		t.Errorf("vaultContentUnchanged() = %v, %v, expected a change from the missing key", unchanged, err)
+	}
+}
+
+func TestValidateStateBackend(t *testing.T) {
+	for _, backend := range []string{StateBackendAnnotation, StateBackendVault} {
+		if err := ValidateStateBackend(backend); err != nil {
+			t.Errorf("ValidateStateBackend(%s) error = %v", backend, err)
+		}
+	}
+	if err := ValidateStateBackend("configmap"); err == nil {
+		t.Error("ValidateStateBackend(configmap) succeeded")
+	}
+}
+
+func TestSecretReconcilerVaultStateBackend(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultClient := &fakeMetadataVault{}
+	r := &SecretReconciler{
+		Client:       k8sClient,
+		Scheme:       runtime.NewScheme(),
+		Log:          logr.Discard(),
+		VaultClient:  vaultClient,
+		StateBackend: StateBackendVault,
+		StateHashKey: []byte("test-state-hash-key"),
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	reconcileAndGet := func() *corev1.Secret {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+		current := &corev1.Secret{}
+		if err := k8sClient.Get(ctx, req.NamespacedName, current); err != nil {
+			t.Fatalf("failed to get secret: %v", err)
+		}
+		return current
+	}
+
+	// The sync records the hash in Vault
+	reconcileUntilSynced(t, r, req)
+	current := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, req.NamespacedName, current); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if vaultClient.writes != 1 || vaultClient.custom["secret/data/db"][ContentHashMetadataKey] == "" {
+		t.Fatalf("writes = %d with metadata %v, expected one write with a content hash", vaultClient.writes, vaultClient.custom)
+	}
+	if _, ok := current.Annotations[VaultSecretVersionsAnnotation]; ok {
+		t.Error("versions annotation written with the vault state backend")
+	}
+
+	// Unchanged content is not written again, even though the Secret's resource version changed
+	current.Labels = map[string]string{"app": "db"}
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+	current = reconcileAndGet()
+	if vaultClient.writes != 1 {
+		t.Errorf("writes = %d after a metadata-only change, expected 1", vaultClient.writes)
+	}
+
+	// Changed content is written
+	current.Data["password"] = []byte("rotated")
+	if err := k8sClient.Update(ctx, current); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+	reconcileAndGet()
+	if vaultClient.writes != 2 || vaultClient.secrets["secret/data/db"]["password"] != "rotated" {
+		t.Errorf("writes = %d, expected the rotated password to be written", vaultClient.writes)
+	}
+}
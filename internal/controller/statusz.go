@@ -0,0 +1,240 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the /statusz summary page.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// DefaultRecentErrors is the number of sync and delete errors kept for the status page.
+const DefaultRecentErrors = 10
+
+// statusTimeout bounds the Vault requests made for a single status page.
+const statusTimeout = 5 * time.Second
+
+// RecentError is a failed sync or delete shown on the status page.
+type RecentError struct {
+	Time     time.Time `json:"time"`
+	Resource string    `json:"resource"`
+	Path     string    `json:"path"`
+	Op       string    `json:"op"`
+	Error    string    `json:"error"`
+}
+
+// ErrorLog keeps the most recent sync and delete errors in memory. All methods are safe to
+// call on a nil log, which records nothing.
+type ErrorLog struct {
+	size int
+
+	mu      sync.Mutex
+	entries []RecentError
+}
+
+// NewErrorLog creates a log keeping the last size errors.
+func NewErrorLog(size int) *ErrorLog {
+	return &ErrorLog{size: size}
+}
+
+// Record adds the error of a sync or delete of obj, dropping the oldest error when full.
+// A nil error is ignored.
+func (l *ErrorLog) Record(kind string, obj client.Object, path, op string, err error) {
+	if l == nil || err == nil || l.size <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, RecentError{
+		Time:     time.Now().UTC(),
+		Resource: kind + "/" + obj.GetNamespace() + "/" + obj.GetName(),
+		Path:     path,
+		Op:       op,
+		Error:    err.Error(),
+	})
+	if len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+}
+
+// Recent returns the recorded errors, newest first.
+func (l *ErrorLog) Recent() []RecentError {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	recent := make([]RecentError, len(l.entries))
+	for i, entry := range l.entries {
+		recent[len(l.entries)-1-i] = entry
+	}
+	return recent
+}
+
+// StatusVault is the Vault client information shown on the status page.
+type StatusVault interface {
+	State(ctx context.Context) (vault.State, error)
+	TokenTTL(ctx context.Context) (time.Duration, error)
+	PendingRequests() int64
+}
+
+// Status is the summary served by StatusHandler.
+type Status struct {
+	Time    time.Time   `json:"time"`
+	Version string      `json:"version,omitempty"`
+	Vault   VaultStatus `json:"vault"`
+	// Queues holds the depth of the Vault rate limiter queue and of each controller work queue
+	Queues []QueueStatus `json:"queues"`
+	// ManagedResources counts the synced resources per namespace
+	ManagedResources map[string]int `json:"managedResources"`
+	ManagedPaths     int            `json:"managedPaths"`
+	RecentErrors     []RecentError  `json:"recentErrors"`
+	// Degraded lists the resources backed off after exhausting their retry budget
+	Degraded []DegradedResource `json:"degraded"`
+}
+
+// VaultStatus describes the Vault connection on the status page.
+type VaultStatus struct {
+	State           string  `json:"state"`
+	Error           string  `json:"error,omitempty"`
+	TokenTTLSeconds float64 `json:"tokenTTLSeconds"`
+	TokenError      string  `json:"tokenError,omitempty"`
+}
+
+// QueueStatus is the depth of a queue on the status page.
+type QueueStatus struct {
+	Name  string `json:"name"`
+	Depth int    `json:"depth"`
+}
+
+// StatusHandler serves a summary of the operator state as JSON, or as HTML for browsers,
+// built from in-process state so it stays available when Prometheus is degraded.
+type StatusHandler struct {
+	Vault StatusVault
+	// Inventory provides the managed resources and paths (optional)
+	Inventory *ManagedPathInventory
+	// Errors provides the recent sync and delete errors (optional)
+	Errors *ErrorLog
+	// RetryBudget provides the degraded resources (optional)
+	RetryBudget *RetryBudget
+	// Gatherer provides the controller work queue depths (optional)
+	Gatherer prometheus.Gatherer
+	Version  string
+}
+
+// ServeHTTP implements http.Handler.
+func (h *StatusHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := h.Status(req.Context())
+
+	if req.URL.Query().Get("format") == "html" ||
+		(req.URL.Query().Get("format") == "" && strings.Contains(req.Header.Get("Accept"), "text/html")) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = statusTemplate.Execute(w, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(status)
+}
+
+// Status collects the current status.
+func (h *StatusHandler) Status(ctx context.Context) Status {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	status := Status{
+		Time:             time.Now().UTC(),
+		Version:          h.Version,
+		ManagedResources: h.Inventory.ResourcesByNamespace(),
+		ManagedPaths:     h.Inventory.Count(),
+		RecentErrors:     h.Errors.Recent(),
+		Degraded:         h.RetryBudget.Degraded(),
+	}
+
+	state, err := h.Vault.State(ctx)
+	status.Vault.State = string(state)
+	if err != nil {
+		status.Vault.Error = err.Error()
+	}
+	if ttl, err := h.Vault.TokenTTL(ctx); err != nil {
+		status.Vault.TokenError = err.Error()
+	} else {
+		status.Vault.TokenTTLSeconds = ttl.Seconds()
+	}
+
+	status.Queues = append([]QueueStatus{{Name: "vault-rate-limiter", Depth: int(h.Vault.PendingRequests())}}, h.workQueueDepths()...)
+	return status
+}
+
+// workQueueDepths reads the depth of each controller work queue from the metrics registry.
+func (h *StatusHandler) workQueueDepths() []QueueStatus {
+	if h.Gatherer == nil {
+		return nil
+	}
+	families, err := h.Gatherer.Gather()
+	if err != nil {
+		return nil
+	}
+
+	var queues []QueueStatus
+	for _, family := range families {
+		if family.GetName() != "workqueue_depth" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "name" {
+					queues = append(queues, QueueStatus{Name: label.GetValue(), Depth: int(metric.GetGauge().GetValue())})
+				}
+			}
+		}
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+	return queues
+}
+
+// statusTemplate renders the status page for browsers.
+var statusTemplate = template.Must(template.New("statusz").Parse(`<!DOCTYPE html>
+<html><head><title>vault-sync-operator status</title>
+<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;margin-bottom:1.5em}td,th{border:1px solid #ccc;padding:4px 8px;text-align:left}</style>
+</head><body>
+<h1>vault-sync-operator {{.Version}}</h1>
+<p>Generated {{.Time.Format "2006-01-02T15:04:05Z07:00"}}</p>
+<h2>Vault</h2>
+<table>
+<tr><th>State</th><td>{{.Vault.State}}{{if .Vault.Error}} ({{.Vault.Error}}){{end}}</td></tr>
+<tr><th>Token TTL</th><td>{{if .Vault.TokenError}}{{.Vault.TokenError}}{{else}}{{.Vault.TokenTTLSeconds}}s{{end}}</td></tr>
+</table>
+<h2>Queues</h2>
+<table><tr><th>Queue</th><th>Depth</th></tr>
+{{range .Queues}}<tr><td>{{.Name}}</td><td>{{.Depth}}</td></tr>
+{{end}}</table>
+<h2>Managed resources ({{.ManagedPaths}} paths)</h2>
+<table><tr><th>Namespace</th><th>Resources</th></tr>
+{{range $namespace, $count := .ManagedResources}}<tr><td>{{$namespace}}</td><td>{{$count}}</td></tr>
+{{end}}</table>
+<h2>Recent errors</h2>
+<table><tr><th>Time</th><th>Resource</th><th>Op</th><th>Path</th><th>Error</th></tr>
+{{range .RecentErrors}}<tr><td>{{.Time.Format "2006-01-02T15:04:05Z07:00"}}</td><td>{{.Resource}}</td><td>{{.Op}}</td><td>{{.Path}}</td><td>{{.Error}}</td></tr>
+{{else}}<tr><td colspan="5">none</td></tr>
+{{end}}</table>
+<h2>Degraded resources</h2>
+<table><tr><th>Resource</th><th>Failures</th><th>Since</th><th>Next retry</th></tr>
+{{range .Degraded}}<tr><td>{{.Resource}}</td><td>{{.Failures}}</td><td>{{.Since.Format "2006-01-02T15:04:05Z07:00"}}</td><td>{{.NextRetry.Format "2006-01-02T15:04:05Z07:00"}}</td></tr>
+{{else}}<tr><td colspan="4">none</td></tr>
+{{end}}</table>
+</body></html>
+`))
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+func TestErrorLog(t *testing.T) {
+	log := NewErrorLog(2)
+	secret := &corev1.Secret{}
+	secret.Namespace = "default"
+
+	for i := 1; i <= 3; i++ {
+		secret.Name = fmt.Sprintf("db-%d", i)
+		log.Record("secret", secret, "secret/data/db", LogOpSync, fmt.Errorf("failure %d", i))
+	}
+	log.Record("secret", secret, "secret/data/db", LogOpSync, nil)
+
+	var errs []string
+	for _, entry := range log.Recent() {
+		errs = append(errs, entry.Resource+": "+entry.Error)
+	}
+	expected := []string{"secret/default/db-3: failure 3", "secret/default/db-2: failure 2"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("Recent() = %v, expected %v", errs, expected)
+	}
+
+	var nilLog *ErrorLog
+	nilLog.Record("secret", secret, "secret/data/db", LogOpSync, errors.New("ignored"))
+	if len(nilLog.Recent()) != 0 {
+		t.Error("a nil error log recorded an error")
+	}
+}
+
+// fakeStatusVault reports fixed Vault information.
+type fakeStatusVault struct {
+	state    vault.State
+	stateErr error
+	ttl      time.Duration
+	pending  int64
+}
+
+func (f *fakeStatusVault) State(context.Context) (vault.State, error) { return f.state, f.stateErr }
+
+func (f *fakeStatusVault) TokenTTL(context.Context) (time.Duration, error) { return f.ttl, nil }
+
+func (f *fakeStatusVault) PendingRequests() int64 { return f.pending }
+
+func TestStatusHandler(t *testing.T) {
+	inventory := NewManagedPathInventory(false)
+	inventory.Set("deployment", types.NamespacedName{Namespace: "payments", Name: "api"}, []string{"secret/data/api"})
+	inventory.Set("secret", types.NamespacedName{Namespace: "payments", Name: "db"}, []string{"secret/data/db"})
+	inventory.Set("secret", types.NamespacedName{Namespace: "web", Name: "tls"}, []string{"secret/data/tls"})
+
+	errorLog := NewErrorLog(DefaultRecentErrors)
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "payments"
+	errorLog.Record("secret", secret, "secret/data/db", LogOpSync, errors.New("permission denied"))
+
+	registry := prometheus.NewRegistry()
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth"}, []string{"name"})
+	registry.MustRegister(depth)
+	depth.WithLabelValues("secret").Set(3)
+
+	handler := &StatusHandler{
+		Vault:     &fakeStatusVault{state: vault.StateActive, ttl: 90 * time.Second, pending: 2},
+		Inventory: inventory,
+		Errors:    errorLog,
+		Gatherer:  registry,
+		Version:   "v1.2.3",
+	}
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/statusz", nil))
+	var status Status
+	if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid status JSON: %v", err)
+	}
+
+	if status.Vault.State != "active" || status.Vault.TokenTTLSeconds != 90 {
+		t.Errorf("vault status = %+v, expected active with a 90s token TTL", status.Vault)
+	}
+	expectedQueues := []QueueStatus{{Name: "vault-rate-limiter", Depth: 2}, {Name: "secret", Depth: 3}}
+	if !reflect.DeepEqual(status.Queues, expectedQueues) {
+		t.Errorf("queues = %v, expected %v", status.Queues, expectedQueues)
+	}
+	if !reflect.DeepEqual(status.ManagedResources, map[string]int{"payments": 2, "web": 1}) || status.ManagedPaths != 3 {
+		t.Errorf("managed resources = %v with %d paths, expected payments=2 web=1 with 3 paths", status.ManagedResources, status.ManagedPaths)
+	}
+	if len(status.RecentErrors) != 1 || status.RecentErrors[0].Error != "permission denied" {
+		t.Errorf("recent errors = %v, expected the permission error", status.RecentErrors)
+	}
+
+	// Browsers get the HTML page
+	request := httptest.NewRequest(http.MethodGet, "/statusz", nil)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if !strings.HasPrefix(recorder.Header().Get("Content-Type"), "text/html") || !strings.Contains(recorder.Body.String(), "permission denied") {
+		t.Errorf("HTML status page = %q, expected the recent error", recorder.Body.String())
+	}
+}
+
+func TestStatusHandlerVaultDown(t *testing.T) {
+	handler := &StatusHandler{Vault: &fakeStatusVault{state: vault.StateDown, stateErr: errors.New("connection refused")}}
+
+	status := handler.Status(context.Background())
+	if status.Vault.State != "down" || status.Vault.Error != "connection refused" {
+		t.Errorf("vault status = %+v, expected down with the connection error", status.Vault)
+	}
+	if len(status.ManagedResources) != 0 || len(status.RecentErrors) != 0 {
+		t.Errorf("status without inventory or error log = %+v, expected no resources or errors", status)
+	}
+}
@@ -5,30 +5,121 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/danieldonoghue/vault-sync-operator/internal/features"
 	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
-	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
 )
 
+// VaultSealedRequeueDelay is how long a reconcile is held before re-checking a sealed Vault.
+const VaultSealedRequeueDelay = 30 * time.Second
+
+// Rotation check annotation values other than a frequency.
+const (
+	RotationCheckEnabled  = "enabled"
+	RotationCheckDisabled = "disabled"
+)
+
+// Operator-wide rotation check policies, set by cluster administrators over the
+// vault-sync.io/rotation-check annotation of each resource.
+const (
+	// RotationCheckPolicyAnnotation leaves rotation detection to the annotation of each resource
+	RotationCheckPolicyAnnotation = ""
+	// RotationCheckPolicyDisabled turns off version-based change detection, so every sync writes
+	RotationCheckPolicyDisabled = "disabled"
+	// RotationCheckPolicyForced ignores vault-sync.io/rotation-check: disabled, so writes are
+	// always skipped when nothing changed
+	RotationCheckPolicyForced = "forced"
+)
+
+// MinRotationCheckInterval is the shortest allowed rotation check frequency.
+const MinRotationCheckInterval = 30 * time.Second
+
+// DefaultMinReconcileInterval is the shortest vault-sync.io/reconcile interval unless configured otherwise.
+const DefaultMinReconcileInterval = 30 * time.Second
+
+// ReconcileIntervalBounds are the organization-wide limits of vault-sync.io/reconcile intervals.
+type ReconcileIntervalBounds struct {
+	// Min is the shortest allowed interval (DefaultMinReconcileInterval when zero)
+	Min time.Duration
+	// Max is the longest allowed interval (unlimited when zero)
+	Max time.Duration
+}
+
+// Validate checks that the bounds are not negative and that the minimum does not exceed the maximum.
+func (b ReconcileIntervalBounds) Validate() error {
+	if b.Min < 0 || b.Max < 0 {
+		return fmt.Errorf("reconcile interval bounds must not be negative")
+	}
+	if b.Max > 0 && b.Max < b.minInterval() {
+		return fmt.Errorf("maximum reconcile interval %s is shorter than the minimum %s", b.Max, b.minInterval())
+	}
+	return nil
+}
+
+// Clamp returns the interval limited to the bounds.
+func (b ReconcileIntervalBounds) Clamp(interval time.Duration) time.Duration {
+	if interval < b.minInterval() {
+		return b.minInterval()
+	}
+	if b.Max > 0 && interval > b.Max {
+		return b.Max
+	}
+	return interval
+}
+
+// Default returns the default reconcile interval limited to the bounds, or zero when there is
+// no default.
+func (b ReconcileIntervalBounds) Default(interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return b.Clamp(interval)
+}
+
+// minInterval returns the effective minimum interval.
+func (b ReconcileIntervalBounds) minInterval() time.Duration {
+	if b.Min <= 0 {
+		return DefaultMinReconcileInterval
+	}
+	return b.Min
+}
+
 // SyncContext provides common context for sync operations.
 type SyncContext struct {
 	Client      client.Client
-	VaultClient *vault.Client
+	VaultClient VaultWriterDeleter
 	Log         logr.Logger
 	ClusterName string
+	// SkippedSecretTypes lists Secret types that are never synced to Vault
+	SkippedSecretTypes []string
+	// SecretTypes allows or denies Secret types per namespace
+	SecretTypes SecretTypePolicy
+	// ExternalSecretPolicy controls Secrets managed by the External Secrets Operator (warn|skip|ignore)
+	ExternalSecretPolicy string
+	// NamespaceMounts places the paths of mapped namespaces under a fixed Vault mount prefix
+	NamespaceMounts NamespaceMounts
+	// CrossNamespace controls "namespace/name" references in the secrets annotation
+	CrossNamespace CrossNamespacePolicy
 }
 
 // ResourceInfo holds information about the resource being synced.
 type ResourceInfo struct {
-	Name      string
-	Namespace string
-	Type      string // "deployment" or "secret"
+	Name         string
+	Namespace    string
+	Type         string // "deployment" or "secret"
+	AbsolutePath bool   // Set from vault-sync.io/absolute-path; disables cluster prefixing
 }
 
 // Note: SecretConfig is defined in deployment_controller.go to avoid duplication
@@ -40,7 +131,7 @@ func (sc *SyncContext) SyncCustomSecretsWithVersions(ctx context.Context, resour
 	// Parse the secrets annotation (JSON format)
 	var secretConfigs []SecretConfig
 	if err := json.Unmarshal([]byte(secretsConfig), &secretConfigs); err != nil {
-		metrics.ConfigParseErrors.WithLabelValues(resource.Namespace, resource.Name, "json_parse_error").Inc()
+		metrics.ConfigParseErrors.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name), "json_parse_error").Inc()
 		log.Error(err, "failed to parse secrets annotation",
 			"annotation", secretsConfig,
 			"error_type", "json_parse_error",
@@ -55,29 +146,66 @@ func (sc *SyncContext) SyncCustomSecretsWithVersions(ctx context.Context, resour
 	secretVersions := make(map[string]string)
 
 	for _, secretConfig := range secretConfigs {
-		secret := &corev1.Secret{}
-		secretKey := types.NamespacedName{
-			Name:      secretConfig.Name,
-			Namespace: targetNamespace, // Use the provided namespace (could be different for cross-namespace refs)
+		if err := secretConfig.validateRules(); err != nil {
+			metrics.ConfigParseErrors.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name), "validation_rule_error").Inc()
+			log.Error(err, "invalid validation rule", "secret", secretConfig.Name, "resource_type", resource.Type)
+			return nil, nil, err
 		}
 
+		// Resolve "namespace/name" references against the cross-namespace policy
+		secretKey, err := sc.CrossNamespace.ResolveSecretRef(secretConfig.Name, targetNamespace)
+		if err != nil {
+			metrics.ConfigParseErrors.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name), "cross_namespace_denied").Inc()
+			log.Error(err, "refusing secret reference",
+				"secret", secretConfig.Name,
+				"resource_type", resource.Type,
+				"resource", resource.Name)
+			return nil, nil, err
+		}
+
+		secret := &corev1.Secret{}
 		if err := sc.Client.Get(ctx, secretKey, secret); err != nil {
-			metrics.SecretNotFoundErrors.WithLabelValues(targetNamespace, secretConfig.Name).Inc()
+			metrics.SecretNotFoundErrors.WithLabelValues(secretKey.Namespace, secretKey.Name).Inc()
 			log.Error(err, "failed to get secret - it may be generated by kustomize or similar tools",
-				"secret", secretConfig.Name,
-				"target_namespace", targetNamespace,
+				"secret", secretKey.Name,
+				"target_namespace", secretKey.Namespace,
 				"resource_type", resource.Type,
 				"resource", resource.Name,
 				"suggestion", "ensure secret generators run before operator sync")
-			return nil, nil, fmt.Errorf("failed to get secret %s (check if secret generators have run): %w", secretConfig.Name, err)
+			return nil, nil, secretGetError(secretKey, err)
+		}
+
+		if secretTypeDenied(secret, sc.SkippedSecretTypes, sc.SecretTypes) {
+			log.Error(fmt.Errorf("secret type is denylisted"), "refusing to sync secret of skipped type",
+				"secret", secretConfig.Name,
+				"type", secret.Type,
+				"target_namespace", targetNamespace,
+				"resource_type", resource.Type,
+				"resource", resource.Name)
+			return nil, nil, fmt.Errorf("secret %s has type %s which is not allowed to be synced", secretConfig.Name, secret.Type)
+		}
+
+		if skipExternalSecret(nil, nil, secret, sc.ExternalSecretPolicy, log) {
+			return nil, nil, fmt.Errorf("secret %s is managed by the external secrets operator and is not allowed to be synced", secretConfig.Name)
 		}
 
 		// Track secret version for rotation detection
-		secretVersions[secretConfig.Name] = secret.ResourceVersion
+		secretVersions[secretConfig.Name] = SecretVersion(secret)
 
 		// Add specified keys to vault data
 		for _, key := range secretConfig.Keys {
 			if data, exists := secret.Data[key]; exists {
+				// Refuse values that break their validation rule
+				if err := secretConfig.validateValue(key, data); err != nil {
+					metrics.ValueValidationFailures.WithLabelValues(secretKey.Namespace, secretKey.Name, key).Inc()
+					log.Error(err, "secret value failed validation",
+						"secret", secretConfig.Name,
+						"key", key,
+						"resource_type", resource.Type,
+						"resource", resource.Name)
+					return nil, nil, err
+				}
+
 				// Use prefix if specified
 				vaultKey := key
 				if secretConfig.Prefix != "" {
@@ -113,7 +241,7 @@ func (sc *SyncContext) SyncAllSecretKeys(_ context.Context, resource ResourceInf
 
 	// Track secret version for rotation detection
 	secretVersions := map[string]string{
-		secret.Name: secret.ResourceVersion,
+		secret.Name: SecretVersion(secret),
 	}
 
 	log.Info("syncing all secret keys",
@@ -127,16 +255,14 @@ func (sc *SyncContext) SyncAllSecretKeys(_ context.Context, resource ResourceInf
 func (sc *SyncContext) WriteSecretToVault(ctx context.Context, vaultPath string, vaultData map[string]interface{}, resource ResourceInfo) error {
 	log := sc.Log.WithValues("resource_type", resource.Type, "resource", resource.Name, "namespace", resource.Namespace)
 
-	// Add cluster prefix if cluster name is configured
-	if sc.ClusterName != "" {
-		vaultPath = fmt.Sprintf("clusters/%s/%s", sc.ClusterName, vaultPath)
-	}
+	// Add namespace mount and cluster prefixes if configured
+	vaultPath = sc.NamespaceMounts.ResolvePath(resource.Namespace, vaultPath, sc.ClusterName, resource.AbsolutePath)
 
 	// Start timing the operation
 	start := time.Now()
 	defer func() {
 		duration := time.Since(start).Seconds()
-		metrics.SecretsyncDuration.WithLabelValues(resource.Namespace, resource.Name).Observe(duration)
+		metrics.SecretsyncDuration.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name)).Observe(duration)
 	}()
 
 	// Log what we're about to sync
@@ -146,44 +272,23 @@ func (sc *SyncContext) WriteSecretToVault(ctx context.Context, vaultPath string,
 
 	// Write to Vault
 	if err := sc.VaultClient.WriteSecret(ctx, vaultPath, vaultData); err != nil {
-		metrics.SecretsyncAttempts.WithLabelValues(resource.Namespace, resource.Name, "failed").Inc()
-		log.Error(err, "failed to write secret to vault",
-			"path", vaultPath,
-			"key_count", len(vaultData),
-			"error_details", err.Error())
+		metrics.SecretsyncAttempts.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name), "failed").Inc()
 		return fmt.Errorf("failed to write secret to vault: %w", err)
 	}
 
-	// Success metrics and logging
-	metrics.SecretsyncAttempts.WithLabelValues(resource.Namespace, resource.Name, "success").Inc()
-	log.Info("successfully wrote secret to vault",
-		"path", vaultPath,
-		"key_count", len(vaultData),
-		"duration_seconds", time.Since(start).Seconds())
+	// Success metrics; the outcome is logged by the caller
+	metrics.SecretsyncAttempts.WithLabelValues(resource.Namespace, metrics.ResourceLabel(resource.Namespace, resource.Name), "success").Inc()
 
 	return nil
 }
 
 // DeleteSecretFromVault deletes a secret from Vault with cluster prefixing.
 func (sc *SyncContext) DeleteSecretFromVault(ctx context.Context, vaultPath string, resource ResourceInfo) error {
-	log := sc.Log.WithValues("resource_type", resource.Type, "resource", resource.Name, "namespace", resource.Namespace)
+	// Add namespace mount and cluster prefixes if configured
+	vaultPath = sc.NamespaceMounts.ResolvePath(resource.Namespace, vaultPath, sc.ClusterName, resource.AbsolutePath)
 
-	// Add cluster prefix if cluster name is configured
-	if sc.ClusterName != "" {
-		vaultPath = fmt.Sprintf("clusters/%s/%s", sc.ClusterName, vaultPath)
-	}
-
-	// Delete the secret from Vault
-	if err := sc.VaultClient.DeleteSecret(ctx, vaultPath); err != nil {
-		log.Error(err, "failed to delete secret from vault",
-			"path", vaultPath,
-			"error_details", err.Error())
-		return err
-	}
-
-	log.Info("successfully deleted secret from vault",
-		"path", vaultPath)
-	return nil
+	// The outcome is logged by the caller
+	return sc.VaultClient.DeleteSecret(ctx, vaultPath)
 }
 
 // DetectSecretChanges compares last known versions with current versions to detect changes.
@@ -231,6 +336,338 @@ func (sc *SyncContext) GetChangedSecrets(lastVersions, currentVersions map[strin
 	return changed
 }
 
+// StaleSecretVersions returns the sorted names of secrets tracked in lastVersions that are
+// no longer referenced, and whose entries are pruned from the versions annotation.
+func StaleSecretVersions(lastVersions, currentVersions map[string]string) []string {
+	var stale []string
+	for secretName := range lastVersions {
+		if _, exists := currentVersions[secretName]; !exists {
+			stale = append(stale, secretName)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// pruneSecretVersions returns lastVersions without the entries of secrets that are no
+// longer referenced.
+func pruneSecretVersions(lastVersions, currentVersions map[string]string) map[string]string {
+	pruned := make(map[string]string, len(lastVersions))
+	for secretName, version := range lastVersions {
+		if _, exists := currentVersions[secretName]; exists {
+			pruned[secretName] = version
+		}
+	}
+	return pruned
+}
+
+// reportStaleSecretVersions logs and records an event for the secrets pruned from the
+// versions annotation of obj.
+func reportStaleSecretVersions(recorder events.EventRecorder, obj runtime.Object, stale []string, log logr.Logger) {
+	if len(stale) == 0 {
+		return
+	}
+	log.Info("pruning secrets that are no longer referenced from the versions annotation", "secrets", stale)
+	recordEvent(recorder, obj, corev1.EventTypeNormal, "SecretVersionsPruned", "Sync",
+		"Stopped tracking secrets that are no longer referenced: %s", strings.Join(stale, ", "))
+}
+
+// ApplyClusterPrefix prepends the clusters/<name>/ prefix to a Vault path when a cluster name
+// is configured. Paths marked absolute, and paths that already carry the prefix, are returned
+// unchanged so the prefix is never applied twice.
+func ApplyClusterPrefix(vaultPath, clusterName string, absolute bool) string {
+	if clusterName == "" || absolute {
+		return vaultPath
+	}
+
+	prefix := fmt.Sprintf("clusters/%s/", clusterName)
+	if strings.HasPrefix(strings.TrimPrefix(vaultPath, "/"), prefix) {
+		return strings.TrimPrefix(vaultPath, "/")
+	}
+
+	return prefix + vaultPath
+}
+
+// NamespaceMounts maps Kubernetes namespaces to the Vault mount their paths are written to
+// (e.g. payments -> kv-payments/), so path governance is enforced centrally.
+type NamespaceMounts map[string]string
+
+// ResolvePath returns the full Vault path for a resource in namespace. In a mapped namespace
+// the first segment of the path, the mount it names, is replaced by the mapped mount, so
+// secret/data/app becomes kv-payments/data/app. Paths that already start with the mapped
+// mount keep it, and single-segment paths are placed under it. The cluster prefix follows
+// the mount and its KV v2 data/ segment. The mount applies to absolute paths as well, which
+// only opt out of the cluster prefix.
+func (m NamespaceMounts) ResolvePath(namespace, vaultPath, clusterName string, absolute bool) string {
+	mount := strings.Trim(m[namespace], "/")
+	if mount == "" {
+		return ApplyClusterPrefix(vaultPath, clusterName, absolute)
+	}
+
+	mount += "/"
+	relative := strings.TrimPrefix(vaultPath, "/")
+	if after, ok := strings.CutPrefix(relative, mount); ok {
+		relative = after
+	} else if _, after, ok := strings.Cut(relative, "/"); ok {
+		relative = after
+	}
+	if after, ok := strings.CutPrefix(relative, "data/"); ok {
+		return mount + "data/" + ApplyClusterPrefix(after, clusterName, absolute)
+	}
+	return mount + ApplyClusterPrefix(relative, clusterName, absolute)
+}
+
+// IsAbsolutePath reports whether the object opts out of cluster prefixing via the
+// vault-sync.io/absolute-path annotation.
+func IsAbsolutePath(obj client.Object) bool {
+	return obj.GetAnnotations()[VaultAbsolutePathAnnotation] == "true"
+}
+
+// PreserveOnDeletePolicy decides which resources keep their Vault paths when they are deleted.
+// The zero value preserves only resources annotated with vault-sync.io/preserve-on-delete.
+type PreserveOnDeletePolicy struct {
+	// Namespaces lists namespace patterns in path.Match syntax, such as "prod-*", whose
+	// resources always preserve their paths, whatever vault-sync.io/preserve-on-delete says
+	Namespaces []string
+}
+
+// NewPreserveOnDeletePolicy creates a policy preserving the paths of resources in namespaces
+// matching patterns, rejecting malformed patterns.
+func NewPreserveOnDeletePolicy(patterns []string) (PreserveOnDeletePolicy, error) {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return PreserveOnDeletePolicy{}, fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+	}
+	return PreserveOnDeletePolicy{Namespaces: patterns}, nil
+}
+
+// Preserves reports whether obj keeps its Vault paths when it is deleted: in the namespaces
+// of the policy, or when annotated with vault-sync.io/preserve-on-delete: "true". The policy
+// is enforced, so "false" cannot opt a resource out of it; anyone able to annotate a resource
+// in a protected namespace could otherwise defeat the protection.
+func (p PreserveOnDeletePolicy) Preserves(obj client.Object) bool {
+	if obj.GetAnnotations()[VaultPreserveOnDeleteAnnotation] == "true" {
+		return true
+	}
+	for _, pattern := range p.Namespaces {
+		if matched, _ := path.Match(pattern, obj.GetNamespace()); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// recordEvent emits an event for obj when a recorder is configured.
+func recordEvent(recorder events.EventRecorder, obj runtime.Object, eventType, reason, action, note string, args ...interface{}) {
+	if recorder == nil {
+		return
+	}
+	recorder.Eventf(obj, nil, eventType, reason, action, note, args...)
+}
+
+// sealNotices records, by object UID, the start of the sealed period for which the object's
+// VaultSealed event was recorded, so that held reconciles do not repeat it.
+var sealNotices sync.Map // map[types.UID]time.Time
+
+// holdWhileSealed reports whether work on obj must be held because Vault is sealed.
+// The seal state is re-probed so that holds end as soon as Vault is unsealed. A VaultSealed
+// event is recorded once per object and sealed period. Clients without seal detection
+// never hold.
+func holdWhileSealed(ctx context.Context, vc VaultWriterDeleter, recorder events.EventRecorder, obj client.Object) bool {
+	vaultClient, ok := vc.(sealProber)
+	if !ok || !vaultClient.IsSealed() {
+		sealNotices.Delete(obj.GetUID())
+		return false
+	}
+
+	_, _ = vaultClient.State(ctx)
+	if !vaultClient.IsSealed() {
+		sealNotices.Delete(obj.GetUID())
+		return false
+	}
+
+	since := vaultClient.SealedSince()
+	if notified, ok := sealNotices.Load(obj.GetUID()); !ok || !notified.(time.Time).Equal(since) {
+		sealNotices.Store(obj.GetUID(), since)
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultSealed", "Sync",
+			"Vault is sealed; holding sync until it is unsealed")
+	}
+	return true
+}
+
+// rotationCheckDisabled reports whether version-based change detection is off for obj under the
+// operator-wide policy.
+func rotationCheckDisabled(obj client.Object, policy string) bool {
+	switch policy {
+	case RotationCheckPolicyDisabled:
+		return true
+	case RotationCheckPolicyForced:
+		return false
+	default:
+		return obj.GetAnnotations()[VaultRotationCheckAnnotation] == RotationCheckDisabled
+	}
+}
+
+// GetRotationCheckInterval parses a rotation check frequency from the vault-sync.io/rotation-check
+// annotation. A frequency schedules version comparisons on its own, independent of the
+// vault-sync.io/reconcile interval. Returns zero for "enabled", "disabled" or invalid values,
+// and when the ScheduledRotationChecks feature gate is disabled.
+func GetRotationCheckInterval(obj client.Object, log logr.Logger) time.Duration {
+	value := obj.GetAnnotations()[VaultRotationCheckAnnotation]
+	if value == "" || value == RotationCheckEnabled || value == RotationCheckDisabled {
+		return 0
+	}
+	if !features.Enabled(features.ScheduledRotationChecks) {
+		return 0
+	}
+
+	frequency, err := time.ParseDuration(value)
+	if err == nil && frequency <= 0 {
+		err = fmt.Errorf("rotation check frequency must be positive: %s", value)
+	}
+	if err != nil {
+		log.Error(err, "invalid rotation check frequency, scheduled rotation checks disabled",
+			"resource", obj.GetName(),
+			"namespace", obj.GetNamespace(),
+			"annotation_value", value)
+		return 0
+	}
+
+	// Enforce minimum frequency to prevent excessive secret reads
+	if frequency < MinRotationCheckInterval {
+		log.Info("rotation check frequency too short, using minimum",
+			"resource", obj.GetName(),
+			"namespace", obj.GetNamespace(),
+			"requested", frequency,
+			"enforced", MinRotationCheckInterval)
+		return MinRotationCheckInterval
+	}
+
+	return frequency
+}
+
+// EarliestInterval returns the smallest non-zero interval, or zero if none are set.
+func EarliestInterval(intervals ...time.Duration) time.Duration {
+	var earliest time.Duration
+	for _, interval := range intervals {
+		if interval > 0 && (earliest == 0 || interval < earliest) {
+			earliest = interval
+		}
+	}
+	return earliest
+}
+
+// Reasons recorded by the sync skipped metric.
+const (
+	SkipReasonNoChange          = "no_change"
+	SkipReasonNamespaceFiltered = "namespace_filtered"
+)
+
+// NamespaceFilter returns a predicate that only admits objects in the given namespaces.
+// Rejected events are counted as namespace_filtered skips.
+func NamespaceFilter(namespaces []string) predicate.Predicate {
+	allowed := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		allowed[ns] = true
+	}
+	return predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		if !allowed[obj.GetNamespace()] {
+			metrics.SyncSkipped.WithLabelValues(SkipReasonNamespaceFiltered).Inc()
+			return false
+		}
+		return true
+	})
+}
+
+// IsForceSyncRequested reports whether the force-sync annotation holds a value that has not been consumed yet.
+func IsForceSyncRequested(obj client.Object) bool {
+	annotations := obj.GetAnnotations()
+	value := annotations[VaultForceSyncAnnotation]
+	return value != "" && value != annotations[VaultForceSyncConsumedAnnotation]
+}
+
+// markForceSyncConsumed records the current force-sync value of obj as applied in annotations.
+func markForceSyncConsumed(obj client.Object, annotations map[string]string) {
+	if value := obj.GetAnnotations()[VaultForceSyncAnnotation]; value != "" {
+		annotations[VaultForceSyncConsumedAnnotation] = value
+	}
+}
+
+// SyncConfigHash returns a hash of the annotations that decide which keys the Vault documents
+// of obj hold: vault-sync.io/secrets, vault-sync.io/include-keys, vault-sync.io/key-sanitization,
+// vault-sync.io/key-prefix, vault-sync.io/parse-json-values and vault-sync.io/compress-values.
+func SyncConfigHash(obj client.Object) string {
+	annotations := obj.GetAnnotations()
+	config := map[string]interface{}{
+		"secrets":          annotations[VaultSecretsAnnotation],
+		"include_keys":     annotations[VaultIncludeKeysAnnotation],
+		"key_sanitization": annotations[VaultKeySanitizationAnnotation],
+	}
+	// Only hashed when set, so the hashes recorded before the annotations existed stay valid
+	if prefix, ok := annotations[VaultKeyPrefixAnnotation]; ok {
+		config["key_prefix"] = prefix
+	}
+	if parseJSON, ok := annotations[VaultParseJSONValuesAnnotation]; ok {
+		config["parse_json_values"] = parseJSON
+	}
+	if compress, ok := annotations[VaultCompressValuesAnnotation]; ok {
+		config["compress_values"] = compress
+	}
+	hash, _ := ContentHash(config)
+	return hash[:16]
+}
+
+// SyncConfigChanged reports whether the configuration of obj changed since its Vault documents
+// were last written. Secret versions alone miss such changes, such as a key removed from
+// vault-sync.io/secrets or a changed prefix, which would leave the keys no longer configured
+// in Vault. Objects last written before the hash was recorded are not considered changed.
+func SyncConfigChanged(obj client.Object) bool {
+	synced := obj.GetAnnotations()[VaultSyncedConfigAnnotation]
+	return synced != "" && synced != SyncConfigHash(obj)
+}
+
+// markSyncedConfig records the configuration hash of obj as written in annotations.
+func markSyncedConfig(obj client.Object, annotations map[string]string) {
+	annotations[VaultSyncedConfigAnnotation] = SyncConfigHash(obj)
+}
+
+// markSyncedAt records the current time as the time the secret versions were recorded in annotations.
+func markSyncedAt(annotations map[string]string) {
+	annotations[VaultSyncedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+}
+
+// GetSyncedAt returns the time the secret versions of obj were last recorded, which is when its
+// Vault documents were last written or confirmed up to date, or false when it is not known.
+func GetSyncedAt(obj client.Object) (time.Time, bool) {
+	syncedAt, err := time.Parse(time.RFC3339, obj.GetAnnotations()[VaultSyncedAtAnnotation])
+	return syncedAt, err == nil
+}
+
+// recordSyncedConfig records the configuration hash of obj when it is missing, so a later
+// configuration change of an object that has not been written since is detected. Failures
+// are logged only.
+func recordSyncedConfig(ctx context.Context, k8sClient client.Client, obj client.Object, log logr.Logger) {
+	if _, ok := obj.GetAnnotations()[VaultSyncedConfigAnnotation]; ok {
+		return
+	}
+	annotations := make(map[string]string, 1)
+	markSyncedConfig(obj, annotations)
+	if err := PatchAnnotations(ctx, k8sClient, obj, annotations); err != nil {
+		log.Error(err, "failed to record synced configuration")
+	}
+}
+
+// IsSecretTypeSkipped reports whether the secret's type is in the operator-level denylist.
+func IsSecretTypeSkipped(secret *corev1.Secret, skippedTypes []string) bool {
+	for _, t := range skippedTypes {
+		if string(secret.Type) == t {
+			return true
+		}
+	}
+	return false
+}
+
 // Note: getSecretKeys is defined in deployment_controller.go to avoid duplication
 
 // ParseSecretVersionsAnnotation parses the secret versions annotation.
@@ -258,23 +695,20 @@ func ParseSecretVersionsAnnotation(annotationValue string, log logr.Logger, reso
 }
 
 // UpdateSecretVersionsAnnotation updates a resource with current secret versions.
+// Only the operator's annotation keys are patched, so concurrent changes to the
+// resource by other controllers are preserved.
 func UpdateSecretVersionsAnnotation(ctx context.Context, k8sClient client.Client, obj client.Object, versions map[string]string) error {
 	versionsJSON, err := json.Marshal(versions)
 	if err != nil {
 		return fmt.Errorf("failed to marshal secret versions: %w", err)
 	}
 
-	// Create a copy to update
-	objCopy := obj.DeepCopyObject().(client.Object)
-	annotations := objCopy.GetAnnotations()
-	if annotations == nil {
-		annotations = make(map[string]string)
-	}
-	annotations[VaultSecretVersionsAnnotation] = string(versionsJSON)
-	objCopy.SetAnnotations(annotations)
+	annotations := map[string]string{VaultSecretVersionsAnnotation: string(versionsJSON)}
+	markForceSyncConsumed(obj, annotations)
+	markSyncedConfig(obj, annotations)
+	markSyncedAt(annotations)
 
-	// Update the object
-	if err := k8sClient.Update(ctx, objCopy); err != nil {
+	if err := PatchAnnotations(ctx, k8sClient, obj, annotations); err != nil {
 		return fmt.Errorf("failed to update resource annotations: %w", err)
 	}
 
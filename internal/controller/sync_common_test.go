@@ -1,9 +1,19 @@
 package controller
 
 import (
+	"context"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
 )
 
 // TestParseSecretVersionsAnnotation tests the ParseSecretVersionsAnnotation function.
@@ -188,3 +198,555 @@ func TestSyncContextGetChangedSecrets(t *testing.T) {
 		})
 	}
 }
+
+// TestStaleSecretVersions tests the StaleSecretVersions and pruneSecretVersions functions.
+func TestStaleSecretVersions(t *testing.T) {
+	tests := []struct {
+		name            string
+		lastVersions    map[string]string
+		currentVersions map[string]string
+		expectedStale   []string
+		expectedPruned  map[string]string
+	}{
+		{
+			name:            "nothing tracked",
+			lastVersions:    nil,
+			currentVersions: map[string]string{"secret1": "v1"},
+			expectedStale:   nil,
+			expectedPruned:  map[string]string{},
+		},
+		{
+			name:            "no stale entries",
+			lastVersions:    map[string]string{"secret1": "v1"},
+			currentVersions: map[string]string{"secret1": "v2", "secret2": "v1"},
+			expectedStale:   nil,
+			expectedPruned:  map[string]string{"secret1": "v1"},
+		},
+		{
+			name:            "secrets no longer referenced",
+			lastVersions:    map[string]string{"secret1": "v1", "old-b": "v4", "old-a": "v3"},
+			currentVersions: map[string]string{"secret1": "v1"},
+			expectedStale:   []string{"old-a", "old-b"},
+			expectedPruned:  map[string]string{"secret1": "v1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if stale := StaleSecretVersions(tt.lastVersions, tt.currentVersions); !reflect.DeepEqual(stale, tt.expectedStale) {
+				t.Errorf("StaleSecretVersions() = %v, expected %v", stale, tt.expectedStale)
+			}
+			if pruned := pruneSecretVersions(tt.lastVersions, tt.currentVersions); !reflect.DeepEqual(pruned, tt.expectedPruned) {
+				t.Errorf("pruneSecretVersions() = %v, expected %v", pruned, tt.expectedPruned)
+			}
+		})
+	}
+}
+
+// TestApplyClusterPrefix tests the ApplyClusterPrefix function.
+func TestApplyClusterPrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		vaultPath   string
+		clusterName string
+		absolute    bool
+		expected    string
+	}{
+		{
+			name:        "no cluster name",
+			vaultPath:   "secret/data/app",
+			clusterName: "",
+			expected:    "secret/data/app",
+		},
+		{
+			name:        "cluster name applied",
+			vaultPath:   "secret/data/app",
+			clusterName: "prod",
+			expected:    "clusters/prod/secret/data/app",
+		},
+		{
+			name:        "prefix already present - not applied twice",
+			vaultPath:   "clusters/prod/secret/data/app",
+			clusterName: "prod",
+			expected:    "clusters/prod/secret/data/app",
+		},
+		{
+			name:        "prefix with leading slash - normalized",
+			vaultPath:   "/clusters/prod/secret/data/app",
+			clusterName: "prod",
+			expected:    "clusters/prod/secret/data/app",
+		},
+		{
+			name:        "different cluster prefix - still applied",
+			vaultPath:   "clusters/staging/secret/data/app",
+			clusterName: "prod",
+			expected:    "clusters/prod/clusters/staging/secret/data/app",
+		},
+		{
+			name:        "absolute path - never prefixed",
+			vaultPath:   "secret/data/shared",
+			clusterName: "prod",
+			absolute:    true,
+			expected:    "secret/data/shared",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ApplyClusterPrefix(tt.vaultPath, tt.clusterName, tt.absolute)
+			if result != tt.expected {
+				t.Errorf("ApplyClusterPrefix() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNamespaceMountsResolvePath tests the NamespaceMounts.ResolvePath method.
+func TestNamespaceMountsResolvePath(t *testing.T) {
+	mounts := NamespaceMounts{"payments": "kv-payments/"}
+
+	tests := []struct {
+		name        string
+		namespace   string
+		vaultPath   string
+		clusterName string
+		absolute    bool
+		expected    string
+	}{
+		{
+			name:      "unmapped namespace",
+			namespace: "default",
+			vaultPath: "secret/data/app",
+			expected:  "secret/data/app",
+		},
+		{
+			name:      "mapped namespace",
+			namespace: "payments",
+			vaultPath: "app",
+			expected:  "kv-payments/app",
+		},
+		{
+			name:      "mount already present - not applied twice",
+			namespace: "payments",
+			vaultPath: "/kv-payments/app",
+			expected:  "kv-payments/app",
+		},
+		{
+			name:      "mount segment replaced",
+			namespace: "payments",
+			vaultPath: "secret/data/app",
+			expected:  "kv-payments/data/app",
+		},
+		{
+			name:      "mount segment replaced on a path without data segment",
+			namespace: "payments",
+			vaultPath: "secret/team/app",
+			expected:  "kv-payments/team/app",
+		},
+		{
+			name:        "cluster prefix placed after the data segment",
+			namespace:   "payments",
+			vaultPath:   "secret/data/app",
+			clusterName: "prod",
+			expected:    "kv-payments/data/clusters/prod/app",
+		},
+		{
+			name:        "mount placed before cluster prefix",
+			namespace:   "payments",
+			vaultPath:   "app",
+			clusterName: "prod",
+			expected:    "kv-payments/clusters/prod/app",
+		},
+		{
+			name:        "absolute path still mapped",
+			namespace:   "payments",
+			vaultPath:   "app",
+			clusterName: "prod",
+			absolute:    true,
+			expected:    "kv-payments/app",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := mounts.ResolvePath(tt.namespace, tt.vaultPath, tt.clusterName, tt.absolute)
+			if result != tt.expected {
+				t.Errorf("ResolvePath() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+
+	var unset NamespaceMounts
+	if result := unset.ResolvePath("payments", "app", "", false); result != "app" {
+		t.Errorf("ResolvePath() on nil mounts = %v, expected app", result)
+	}
+}
+
+// TestIsSecretTypeSkipped tests the IsSecretTypeSkipped function.
+func TestIsSecretTypeSkipped(t *testing.T) {
+	skipped := []string{string(corev1.SecretTypeServiceAccountToken)}
+
+	tests := []struct {
+		name       string
+		secretType corev1.SecretType
+		skipped    []string
+		expected   bool
+	}{
+		{
+			name:       "service account token skipped",
+			secretType: corev1.SecretTypeServiceAccountToken,
+			skipped:    skipped,
+			expected:   true,
+		},
+		{
+			name:       "opaque secret allowed",
+			secretType: corev1.SecretTypeOpaque,
+			skipped:    skipped,
+			expected:   false,
+		},
+		{
+			name:       "empty denylist allows everything",
+			secretType: corev1.SecretTypeServiceAccountToken,
+			skipped:    nil,
+			expected:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{Type: tt.secretType}
+			if result := IsSecretTypeSkipped(secret, tt.skipped); result != tt.expected {
+				t.Errorf("IsSecretTypeSkipped() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestPreserveOnDeletePolicy tests the PreserveOnDeletePolicy type.
+func TestPreserveOnDeletePolicy(t *testing.T) {
+	policy, err := NewPreserveOnDeletePolicy([]string{"prod-*", "payments"})
+	if err != nil {
+		t.Fatalf("NewPreserveOnDeletePolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      PreserveOnDeletePolicy
+		namespace   string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:      "no policy",
+			namespace: "prod-eu",
+			expected:  false,
+		},
+		{
+			name:        "annotated without policy",
+			namespace:   "default",
+			annotations: map[string]string{VaultPreserveOnDeleteAnnotation: "true"},
+			expected:    true,
+		},
+		{
+			name:      "namespace matching pattern",
+			policy:    policy,
+			namespace: "prod-eu",
+			expected:  true,
+		},
+		{
+			name:      "namespace matching name",
+			policy:    policy,
+			namespace: "payments",
+			expected:  true,
+		},
+		{
+			name:      "namespace not matching",
+			policy:    policy,
+			namespace: "staging",
+			expected:  false,
+		},
+		{
+			name:        "annotation cannot opt out of namespace policy",
+			policy:      policy,
+			namespace:   "prod-eu",
+			annotations: map[string]string{VaultPreserveOnDeleteAnnotation: "false"},
+			expected:    true,
+		},
+		{
+			name:        "annotation opts out outside policy namespaces",
+			policy:      policy,
+			namespace:   "staging",
+			annotations: map[string]string{VaultPreserveOnDeleteAnnotation: "false"},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{}
+			secret.Namespace = tt.namespace
+			secret.Annotations = tt.annotations
+			if result := tt.policy.Preserves(secret); result != tt.expected {
+				t.Errorf("Preserves() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := NewPreserveOnDeletePolicy([]string{"prod-["}); err == nil {
+		t.Errorf("expected an error for a malformed pattern")
+	}
+}
+
+// TestSyncConfigChanged tests the SyncConfigChanged function.
+func TestSyncConfigChanged(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Annotations = map[string]string{VaultSecretsAnnotation: `[{"name": "db", "keys": ["password"]}]`}
+	if SyncConfigChanged(secret) {
+		t.Errorf("expected an object without a recorded hash not to be changed")
+	}
+
+	annotations := make(map[string]string)
+	markSyncedConfig(secret, annotations)
+	secret.Annotations[VaultSyncedConfigAnnotation] = annotations[VaultSyncedConfigAnnotation]
+	if SyncConfigChanged(secret) {
+		t.Errorf("expected the recorded configuration not to be changed")
+	}
+
+	for _, annotation := range []string{VaultSecretsAnnotation, VaultIncludeKeysAnnotation, VaultKeySanitizationAnnotation, VaultKeyPrefixAnnotation, VaultParseJSONValuesAnnotation} {
+		changed := secret.DeepCopy()
+		changed.Annotations[annotation] = "changed"
+		if !SyncConfigChanged(changed) {
+			t.Errorf("expected a change of %s to be detected", annotation)
+		}
+	}
+}
+
+// TestIsForceSyncRequested tests the IsForceSyncRequested function.
+func TestIsForceSyncRequested(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			name:        "no annotation",
+			annotations: nil,
+			expected:    false,
+		},
+		{
+			name: "new value - requested",
+			annotations: map[string]string{
+				VaultForceSyncAnnotation: "2024-05-01T12:00:00Z",
+			},
+			expected: true,
+		},
+		{
+			name: "value already consumed",
+			annotations: map[string]string{
+				VaultForceSyncAnnotation:         "2024-05-01T12:00:00Z",
+				VaultForceSyncConsumedAnnotation: "2024-05-01T12:00:00Z",
+			},
+			expected: false,
+		},
+		{
+			name: "value changed after consumption",
+			annotations: map[string]string{
+				VaultForceSyncAnnotation:         "2024-05-02T08:30:00Z",
+				VaultForceSyncConsumedAnnotation: "2024-05-01T12:00:00Z",
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{}
+			secret.Annotations = tt.annotations
+			if result := IsForceSyncRequested(secret); result != tt.expected {
+				t.Errorf("IsForceSyncRequested() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGetRotationCheckInterval tests the GetRotationCheckInterval function.
+func TestGetRotationCheckInterval(t *testing.T) {
+	log := ctrl.Log.WithName("test")
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "no annotation", value: "", expected: 0},
+		{name: "enabled", value: "enabled", expected: 0},
+		{name: "disabled", value: "disabled", expected: 0},
+		{name: "valid frequency", value: "10m", expected: 10 * time.Minute},
+		{name: "below minimum", value: "5s", expected: MinRotationCheckInterval},
+		{name: "invalid value", value: "often", expected: 0},
+		{name: "negative frequency", value: "-1m", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := &corev1.Secret{}
+			if tt.value != "" {
+				secret.Annotations = map[string]string{VaultRotationCheckAnnotation: tt.value}
+			}
+			if result := GetRotationCheckInterval(secret, log); result != tt.expected {
+				t.Errorf("GetRotationCheckInterval() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestReconcileIntervalBounds tests clamping and validation of reconcile interval bounds.
+func TestReconcileIntervalBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		bounds   ReconcileIntervalBounds
+		interval time.Duration
+		expected time.Duration
+	}{
+		{name: "default minimum", bounds: ReconcileIntervalBounds{}, interval: 10 * time.Second, expected: DefaultMinReconcileInterval},
+		{name: "no default maximum", bounds: ReconcileIntervalBounds{}, interval: 24 * time.Hour, expected: 24 * time.Hour},
+		{name: "configured minimum", bounds: ReconcileIntervalBounds{Min: 5 * time.Minute}, interval: time.Minute, expected: 5 * time.Minute},
+		{name: "lower minimum", bounds: ReconcileIntervalBounds{Min: 10 * time.Second}, interval: 15 * time.Second, expected: 15 * time.Second},
+		{name: "within bounds", bounds: ReconcileIntervalBounds{Min: time.Minute, Max: time.Hour}, interval: 10 * time.Minute, expected: 10 * time.Minute},
+		{name: "configured maximum", bounds: ReconcileIntervalBounds{Max: time.Hour}, interval: 6 * time.Hour, expected: time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.bounds.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if result := tt.bounds.Clamp(tt.interval); result != tt.expected {
+				t.Errorf("Clamp(%v) = %v, expected %v", tt.interval, result, tt.expected)
+			}
+		})
+	}
+
+	if result := (ReconcileIntervalBounds{}).Default(0); result != 0 {
+		t.Errorf("Default(0) = %v, expected 0", result)
+	}
+	if result := (ReconcileIntervalBounds{Max: time.Hour}).Default(24 * time.Hour); result != time.Hour {
+		t.Errorf("Default(24h) = %v, expected 1h", result)
+	}
+
+	for _, invalid := range []ReconcileIntervalBounds{
+		{Min: -time.Second},
+		{Max: 10 * time.Second},
+		{Min: time.Hour, Max: time.Minute},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate() of %+v succeeded, expected an error", invalid)
+		}
+	}
+}
+
+// TestEarliestInterval tests the EarliestInterval function.
+func TestEarliestInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []time.Duration
+		expected  time.Duration
+	}{
+		{name: "none set", intervals: []time.Duration{0, 0}, expected: 0},
+		{name: "one set", intervals: []time.Duration{0, 5 * time.Minute}, expected: 5 * time.Minute},
+		{name: "both set", intervals: []time.Duration{time.Hour, 10 * time.Minute}, expected: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := EarliestInterval(tt.intervals...); result != tt.expected {
+				t.Errorf("EarliestInterval() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestNamespaceFilter tests that NamespaceFilter admits watched namespaces and counts filtered events.
+func TestNamespaceFilter(t *testing.T) {
+	filter := NamespaceFilter([]string{"team-a"})
+	filtered := metrics.SyncSkipped.WithLabelValues(SkipReasonNamespaceFiltered)
+
+	tests := []struct {
+		name      string
+		namespace string
+		expected  bool
+	}{
+		{name: "watched namespace", namespace: "team-a", expected: true},
+		{name: "other namespace", namespace: "team-b", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(filtered)
+			secret := &corev1.Secret{}
+			secret.Namespace = tt.namespace
+			if result := filter.Generic(event.GenericEvent{Object: secret}); result != tt.expected {
+				t.Errorf("NamespaceFilter() = %v, expected %v", result, tt.expected)
+			}
+
+			expectedSkips := 0.0
+			if !tt.expected {
+				expectedSkips = 1
+			}
+			if skips := testutil.ToFloat64(filtered) - before; skips != expectedSkips {
+				t.Errorf("namespace_filtered skips = %v, expected %v", skips, expectedSkips)
+			}
+		})
+	}
+}
+
+// fakeSealedVault is a fakeVault whose seal state is set by the test.
+type fakeSealedVault struct {
+	fakeVault
+	sealedSince time.Time
+}
+
+func (f *fakeSealedVault) IsSealed() bool {
+	return !f.sealedSince.IsZero()
+}
+
+func (f *fakeSealedVault) SealedSince() time.Time {
+	return f.sealedSince
+}
+
+func (f *fakeSealedVault) State(_ context.Context) (vault.State, error) {
+	if f.IsSealed() {
+		return vault.StateSealed, nil
+	}
+	return vault.StateActive, nil
+}
+
+// TestHoldWhileSealedEventOncePerPeriod tests that held reconciles record the VaultSealed
+// event once per sealed period.
+func TestHoldWhileSealedEventOncePerPeriod(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{}
+	secret.UID = "sealed-event-test"
+	vaultClient := &fakeSealedVault{sealedSince: time.Now()}
+	recorder := events.NewFakeRecorder(10)
+
+	for i := 0; i < 3; i++ {
+		if !holdWhileSealed(ctx, vaultClient, recorder, secret) {
+			t.Fatalf("holdWhileSealed() = false while sealed")
+		}
+	}
+	if got := len(recorder.Events); got != 1 {
+		t.Fatalf("events = %d, expected one for the sealed period", got)
+	}
+
+	// Unsealing ends the period, and sealing again starts a new one
+	vaultClient.sealedSince = time.Time{}
+	if holdWhileSealed(ctx, vaultClient, recorder, secret) {
+		t.Fatalf("holdWhileSealed() = true while unsealed")
+	}
+	vaultClient.sealedSince = time.Now().Add(time.Minute)
+	holdWhileSealed(ctx, vaultClient, recorder, secret)
+	if got := len(recorder.Events); got != 2 {
+		t.Errorf("events = %d, expected a second one for the new sealed period", got)
+	}
+}
@@ -0,0 +1,263 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the SyncHistory recorder which keeps a bounded history of sync
+// operations per resource in a per-namespace ConfigMap.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SyncHistoryConfigMapName is the name of the ConfigMap holding sync history in each namespace.
+const SyncHistoryConfigMapName = "vault-sync-history"
+
+// SyncHistoryMaxBytes bounds the data of a history ConfigMap, well below the 1 MiB limit of
+// ConfigMaps, however many resources a namespace holds. The oldest operations of the
+// namespace are dropped first.
+const SyncHistoryMaxBytes = 256 * 1024
+
+// syncHistoryMaxErrorLength bounds the error message recorded for a failed sync operation.
+const syncHistoryMaxErrorLength = 1024
+
+// SyncHistoryMaxAge is how long sync operations are retained. The history of a resource that
+// has not synced for as long is removed, so that resources deleted while the operator was
+// not running do not leave their history behind.
+const SyncHistoryMaxAge = 7 * 24 * time.Hour
+
+// Sync history results.
+const (
+	SyncResultSuccess = "success"
+	SyncResultFailed  = "failed"
+)
+
+// SyncHistoryEntry describes a single sync operation.
+type SyncHistoryEntry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Result      string    `json:"result"`
+	ChangedKeys int       `json:"changedKeys"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// SyncHistory records the last Size sync operations of each resource in the
+// vault-sync-history ConfigMap of the resource's namespace, keyed by <kind>.<name>.
+// Operations older than SyncHistoryMaxAge are dropped, the ConfigMap is kept below
+// SyncHistoryMaxBytes, and the history of a resource is removed when it is deleted. All methods are safe to call on a nil recorder, which
+// disables history.
+type SyncHistory struct {
+	// Client creates and updates the history ConfigMaps
+	Client client.Client
+	// Reader reads the history ConfigMaps uncached (typically the manager's API reader),
+	// so that the operator does not need to watch every ConfigMap in the cluster
+	Reader client.Reader
+	// Size is the number of entries retained per resource
+	Size int
+	Log  logr.Logger
+}
+
+// NewSyncHistory creates a recorder that retains size entries per resource.
+func NewSyncHistory(c client.Client, reader client.Reader, size int, log logr.Logger) *SyncHistory {
+	return &SyncHistory{
+		Client: c,
+		Reader: reader,
+		Size:   size,
+		Log:    log,
+	}
+}
+
+// SyncHistoryKey returns the ConfigMap key holding the history of obj.
+func SyncHistoryKey(kind string, obj client.Object) string {
+	return kind + "." + obj.GetName()
+}
+
+// Record appends the outcome of a sync of obj to its history. Failures to persist
+// the history are logged and never fail the sync itself.
+func (h *SyncHistory) Record(ctx context.Context, kind string, obj client.Object, changedKeys int, syncErr error) {
+	if h == nil || h.Size <= 0 {
+		return
+	}
+
+	entry := SyncHistoryEntry{
+		Timestamp:   time.Now().UTC(),
+		Result:      SyncResultSuccess,
+		ChangedKeys: changedKeys,
+	}
+	if syncErr != nil {
+		entry.Result = SyncResultFailed
+		entry.Error = syncErr.Error()
+		if len(entry.Error) > syncHistoryMaxErrorLength {
+			entry.Error = entry.Error[:syncHistoryMaxErrorLength] + "..."
+		}
+	}
+
+	key := SyncHistoryKey(kind, obj)
+	// Retry when another reconcile updated or created the ConfigMap concurrently
+	err := retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		return h.append(ctx, obj.GetNamespace(), key, entry)
+	})
+	if err != nil {
+		h.Log.Error(err, "failed to record sync history",
+			"namespace", obj.GetNamespace(),
+			"key", key)
+	}
+}
+
+// Forget removes the history of obj, once it is deleted.
+func (h *SyncHistory) Forget(ctx context.Context, kind string, obj client.Object) {
+	if h == nil || h.Size <= 0 {
+		return
+	}
+
+	key := SyncHistoryKey(kind, obj)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		err := h.Reader.Get(ctx, types.NamespacedName{Name: SyncHistoryConfigMapName, Namespace: obj.GetNamespace()}, configMap)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get sync history configmap: %w", err)
+		}
+		if _, exists := configMap.Data[key]; !exists {
+			return nil
+		}
+		delete(configMap.Data, key)
+		return h.Client.Update(ctx, configMap)
+	})
+	if err != nil {
+		h.Log.Error(err, "failed to remove sync history",
+			"namespace", obj.GetNamespace(),
+			"key", key)
+	}
+}
+
+// append adds entry to the history stored under key, creating the ConfigMap if needed.
+// Expired operations of every resource in the ConfigMap are dropped along the way.
+func (h *SyncHistory) append(ctx context.Context, namespace, key string, entry SyncHistoryEntry) error {
+	configMap := &corev1.ConfigMap{}
+	err := h.Reader.Get(ctx, types.NamespacedName{Name: SyncHistoryConfigMapName, Namespace: namespace}, configMap)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get sync history configmap: %w", err)
+	}
+	exists := err == nil
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	if err := PruneSyncHistory(configMap.Data, entry.Timestamp.Add(-SyncHistoryMaxAge)); err != nil {
+		return err
+	}
+	entries, err := TrimSyncHistory(append(ParseSyncHistory(configMap.Data[key]), entry), h.Size)
+	if err != nil {
+		return err
+	}
+	configMap.Data[key] = entries
+	if err := BoundSyncHistory(configMap.Data, SyncHistoryMaxBytes); err != nil {
+		return err
+	}
+
+	if !exists {
+		configMap.Name = SyncHistoryConfigMapName
+		configMap.Namespace = namespace
+		configMap.Labels = map[string]string{"app.kubernetes.io/managed-by": "vault-sync-operator"}
+		return h.Client.Create(ctx, configMap)
+	}
+	return h.Client.Update(ctx, configMap)
+}
+
+// ParseSyncHistory parses the entries stored under a history key. Unparseable
+// history is discarded rather than blocking new entries.
+func ParseSyncHistory(value string) []SyncHistoryEntry {
+	if value == "" {
+		return nil
+	}
+
+	var entries []SyncHistoryEntry
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil
+	}
+	return entries
+}
+
+// PruneSyncHistory drops the operations recorded before cutoff from the history ConfigMap
+// data, and the keys left without operations.
+func PruneSyncHistory(data map[string]string, cutoff time.Time) error {
+	for key, value := range data {
+		entries := ParseSyncHistory(value)
+		kept := slices.DeleteFunc(slices.Clone(entries), func(entry SyncHistoryEntry) bool {
+			return entry.Timestamp.Before(cutoff)
+		})
+		switch {
+		case len(kept) == 0:
+			delete(data, key)
+		case len(kept) < len(entries):
+			trimmed, err := TrimSyncHistory(kept, len(kept))
+			if err != nil {
+				return err
+			}
+			data[key] = trimmed
+		}
+	}
+	return nil
+}
+
+// BoundSyncHistory drops the oldest operations recorded in the history ConfigMap data, and
+// the keys left without operations, until the data takes at most maxBytes.
+func BoundSyncHistory(data map[string]string, maxBytes int) error {
+	size := 0
+	for key, value := range data {
+		size += len(key) + len(value)
+	}
+	for size > maxBytes && len(data) > 0 {
+		// Keys without parseable operations go first
+		var oldestKey string
+		var oldest []SyncHistoryEntry
+		for key, value := range data {
+			entries := ParseSyncHistory(value)
+			if len(entries) == 0 {
+				oldestKey, oldest = key, nil
+				break
+			}
+			if oldest == nil || entries[0].Timestamp.Before(oldest[0].Timestamp) {
+				oldestKey, oldest = key, entries
+			}
+		}
+
+		size -= len(oldestKey) + len(data[oldestKey])
+		if len(oldest) <= 1 {
+			delete(data, oldestKey)
+			continue
+		}
+		trimmed, err := TrimSyncHistory(oldest[1:], len(oldest)-1)
+		if err != nil {
+			return err
+		}
+		data[oldestKey] = trimmed
+		size += len(oldestKey) + len(trimmed)
+	}
+	return nil
+}
+
+// TrimSyncHistory keeps the newest size entries and marshals them for storage.
+func TrimSyncHistory(entries []SyncHistoryEntry, size int) (string, error) {
+	if len(entries) > size {
+		entries = entries[len(entries)-size:]
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sync history: %w", err)
+	}
+	return string(data), nil
+}
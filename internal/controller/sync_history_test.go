@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// TestSyncHistoryKey tests the SyncHistoryKey function.
+func TestSyncHistoryKey(t *testing.T) {
+	secret := &corev1.Secret{}
+	secret.Name = "db-credentials"
+	if key := SyncHistoryKey("secret", secret); key != "secret.db-credentials" {
+		t.Errorf("SyncHistoryKey() = %v, expected secret.db-credentials", key)
+	}
+}
+
+// TestTrimSyncHistory tests that only the newest entries are retained.
+func TestTrimSyncHistory(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var entries []SyncHistoryEntry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, SyncHistoryEntry{
+			Timestamp:   start.Add(time.Duration(i) * time.Minute),
+			Result:      SyncResultSuccess,
+			ChangedKeys: i,
+		})
+	}
+
+	value, err := TrimSyncHistory(entries, 3)
+	if err != nil {
+		t.Fatalf("TrimSyncHistory() error = %v", err)
+	}
+
+	trimmed := ParseSyncHistory(value)
+	if len(trimmed) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(trimmed))
+	}
+	if trimmed[0].ChangedKeys != 2 || trimmed[2].ChangedKeys != 4 {
+		t.Errorf("expected the newest entries to be kept, got %+v", trimmed)
+	}
+	if !trimmed[2].Timestamp.Equal(entries[4].Timestamp) {
+		t.Errorf("timestamp = %v, expected %v", trimmed[2].Timestamp, entries[4].Timestamp)
+	}
+}
+
+// TestParseSyncHistory tests parsing of stored history values.
+func TestParseSyncHistory(t *testing.T) {
+	if entries := ParseSyncHistory(""); entries != nil {
+		t.Errorf("expected no entries for an empty value, got %+v", entries)
+	}
+	if entries := ParseSyncHistory("not json"); entries != nil {
+		t.Errorf("expected invalid history to be discarded, got %+v", entries)
+	}
+
+	entries := ParseSyncHistory(`[{"timestamp":"2024-05-01T12:00:00Z","result":"failed","changedKeys":2,"error":"permission denied"}]`)
+	if len(entries) != 1 || entries[0].Result != SyncResultFailed || entries[0].Error != "permission denied" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+// TestSyncHistoryNil tests that a nil recorder is a no-op.
+func TestSyncHistoryNil(t *testing.T) {
+	var history *SyncHistory
+	history.Record(t.Context(), "secret", &corev1.Secret{}, 1, nil)
+}
+
+// TestPruneSyncHistory tests that expired operations and the keys left without any are dropped.
+func TestPruneSyncHistory(t *testing.T) {
+	cutoff := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expired, _ := TrimSyncHistory([]SyncHistoryEntry{{Timestamp: cutoff.Add(-time.Hour), Result: SyncResultSuccess}}, 10)
+	mixed, _ := TrimSyncHistory([]SyncHistoryEntry{
+		{Timestamp: cutoff.Add(-time.Hour), Result: SyncResultFailed},
+		{Timestamp: cutoff.Add(time.Hour), Result: SyncResultSuccess},
+	}, 10)
+	data := map[string]string{"deployment.gone": expired, "secret.db": mixed}
+
+	if err := PruneSyncHistory(data, cutoff); err != nil {
+		t.Fatalf("PruneSyncHistory() error = %v", err)
+	}
+	if _, exists := data["deployment.gone"]; exists {
+		t.Errorf("expected the key without recent operations to be removed")
+	}
+	if entries := ParseSyncHistory(data["secret.db"]); len(entries) != 1 || entries[0].Result != SyncResultSuccess {
+		t.Errorf("entries = %+v, expected only the recent one", entries)
+	}
+}
+
+// TestSyncHistoryForget tests that the history of a deleted resource is removed.
+func TestSyncHistoryForget(t *testing.T) {
+	ctx := context.Background()
+	secret := &corev1.Secret{}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	k8sClient := fake.NewClientBuilder().Build()
+	history := NewSyncHistory(k8sClient, k8sClient, 10, logr.Discard())
+
+	history.Record(ctx, "secret", secret, 1, nil)
+	history.Forget(ctx, "secret", secret)
+
+	configMap := &corev1.ConfigMap{}
+	if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: SyncHistoryConfigMapName}, configMap); err != nil {
+		t.Fatalf("failed to get history configmap: %v", err)
+	}
+	if _, exists := configMap.Data[SyncHistoryKey("secret", secret)]; exists {
+		t.Errorf("history of the deleted secret still recorded: %v", configMap.Data)
+	}
+}
+
+// TestBoundSyncHistory tests that the oldest operations of a namespace are dropped until the
+// history fits the size bound.
+func TestBoundSyncHistory(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data := make(map[string]string)
+	for i, key := range []string{"secret.a", "secret.b", "secret.c"} {
+		var entries []SyncHistoryEntry
+		for j := 0; j < 10; j++ {
+			entries = append(entries, SyncHistoryEntry{Timestamp: start.Add(time.Duration(j*3+i) * time.Minute), Result: SyncResultSuccess})
+		}
+		data[key], _ = TrimSyncHistory(entries, len(entries))
+	}
+	newest := ParseSyncHistory(data["secret.c"])[9]
+
+	if err := BoundSyncHistory(data, 2000); err != nil {
+		t.Fatalf("BoundSyncHistory() error = %v", err)
+	}
+	size, kept := 0, 0
+	for key, value := range data {
+		size += len(key) + len(value)
+		kept += len(ParseSyncHistory(value))
+	}
+	if size > 2000 || kept == 0 {
+		t.Errorf("history takes %d bytes with %d operations, expected at most 2000 bytes", size, kept)
+	}
+	if entries := ParseSyncHistory(data["secret.c"]); len(entries) == 0 || !entries[len(entries)-1].Timestamp.Equal(newest.Timestamp) {
+		t.Errorf("newest operation dropped: %v", data)
+	}
+	// The operations were recorded a minute apart, so the kept ones are the newest
+	for _, value := range data {
+		if entries := ParseSyncHistory(value); entries[0].Timestamp.Before(start.Add(time.Duration(30-kept) * time.Minute)) {
+			t.Errorf("old operation kept while newer ones were dropped: %v", entries[0])
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the handoff of the leader's Vault token state through the leader election Lease.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// VaultTokenStateAnnotation holds the Vault token state of the current leader on the leader
+// election Lease.
+const VaultTokenStateAnnotation = "vault-sync.io/token-state"
+
+// DefaultTokenHandoffInterval is how often the leader checks whether its token changed.
+const DefaultTokenHandoffInterval = 30 * time.Second
+
+// tokenRecord is the value of the token state annotation. It never contains the token itself.
+type tokenRecord struct {
+	// Holder is the replica that logged in with the token
+	Holder   string    `json:"holder"`
+	Accessor string    `json:"accessor"`
+	Issued   time.Time `json:"issued"`
+	// Expiry is zero for tokens that never expire
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// TokenHandoffVault is the Vault client used by the token handoff.
+type TokenHandoffVault interface {
+	TokenState() vault.TokenState
+	AccessorIssuedByClient(ctx context.Context, accessor string) (bool, error)
+	RevokeAccessor(ctx context.Context, accessor string) error
+}
+
+// TokenHandoff records the accessor and lifetime of the leader's Vault token on the leader
+// election Lease. A replica that becomes leader revokes the token recorded by its
+// predecessor before publishing its own, so the token of a crashed or replaced leader does
+// not stay valid until its TTL runs out. Since anyone able to update the Lease can record
+// any accessor, only tokens Vault reports as issued to the operator's own role are revoked.
+// It only runs on the leader, and must not run without leader election, where every replica
+// would revoke the others' tokens.
+type TokenHandoff struct {
+	// Client writes the Lease
+	Client client.Client
+	// Reader reads the Lease without caching Leases cluster-wide (typically the manager's API reader)
+	Reader      client.Reader
+	VaultClient TokenHandoffVault
+	// Namespace and LeaseName locate the leader election Lease
+	Namespace string
+	LeaseName string
+	// Identity names this replica (the pod name)
+	Identity string
+	// Interval between checks for a changed token
+	Interval time.Duration
+	Log      logr.Logger
+
+	// published is the accessor last written to the Lease
+	published string
+}
+
+// Start revokes the previous leader's token once this replica holds a token of its own, then
+// keeps the Lease up to date until ctx is done. The record is left on the Lease at shutdown
+// for the next leader. It implements manager.Runnable.
+func (h *TokenHandoff) Start(ctx context.Context) error {
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := h.sync(ctx, time.Now()); err != nil {
+			h.Log.Error(err, "failed to hand off vault token state", "lease", h.LeaseName)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection restricts the handoff to the replica that reconciles.
+func (h *TokenHandoff) NeedLeaderElection() bool {
+	return true
+}
+
+// sync revokes the token of the previous leader on the first call after this replica has
+// logged in, and publishes the state of its own token whenever it changed.
+func (h *TokenHandoff) sync(ctx context.Context, now time.Time) error {
+	state := h.VaultClient.TokenState()
+	if state.Accessor == "" || state.Accessor == h.published {
+		// Not logged in yet, or nothing changed
+		return nil
+	}
+
+	lease := &coordinationv1.Lease{}
+	key := types.NamespacedName{Namespace: h.Namespace, Name: h.LeaseName}
+	if err := h.Reader.Get(ctx, key, lease); err != nil {
+		return fmt.Errorf("failed to read leader election lease: %w", err)
+	}
+	if h.published == "" {
+		if previous, ok := parseTokenRecord(lease.Annotations[VaultTokenStateAnnotation]); ok && previous.Accessor != state.Accessor {
+			h.revoke(ctx, previous, now)
+		}
+	}
+
+	record, err := json.Marshal(tokenRecord{
+		Holder:   h.Identity,
+		Accessor: state.Accessor,
+		Issued:   state.Issued.UTC().Truncate(time.Second),
+		Expiry:   state.Expiry.UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := h.Reader.Get(ctx, key, lease); err != nil {
+			return err
+		}
+		if lease.Annotations == nil {
+			lease.Annotations = make(map[string]string)
+		}
+		lease.Annotations[VaultTokenStateAnnotation] = string(record)
+		return h.Client.Update(ctx, lease)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record vault token state: %w", err)
+	}
+	h.published = state.Accessor
+	return nil
+}
+
+// revoke revokes the token of the previous leader unless it has already expired or was not
+// issued to the operator's role. Vault rejects the accessors of tokens that expired or were
+// revoked since, which is not a failure.
+func (h *TokenHandoff) revoke(ctx context.Context, previous tokenRecord, now time.Time) {
+	log := h.Log.WithValues("previous_holder", previous.Holder)
+	if !previous.Expiry.IsZero() && !previous.Expiry.After(now) {
+		metrics.TokenHandoffs.WithLabelValues("expired").Inc()
+		log.V(1).Info("vault token of the previous leader has already expired")
+		return
+	}
+
+	issued, err := h.VaultClient.AccessorIssuedByClient(ctx, previous.Accessor)
+	if err == nil && !issued {
+		metrics.TokenHandoffs.WithLabelValues("foreign").Inc()
+		log.Info("not revoking the vault token recorded on the lease, it was not issued to the operator's role")
+		return
+	}
+	if err == nil {
+		err = h.VaultClient.RevokeAccessor(ctx, previous.Accessor)
+	}
+	switch {
+	case err == nil:
+		metrics.TokenHandoffs.WithLabelValues("revoked").Inc()
+		log.Info("revoked the vault token of the previous leader")
+	case vault.StatusCode(err) == http.StatusBadRequest:
+		metrics.TokenHandoffs.WithLabelValues("expired").Inc()
+		log.V(1).Info("vault token of the previous leader is no longer valid")
+	default:
+		metrics.TokenHandoffs.WithLabelValues("failed").Inc()
+		log.Error(err, "failed to revoke the vault token of the previous leader, it stays valid until it expires",
+			"expiry", previous.Expiry)
+	}
+}
+
+// parseTokenRecord decodes a token state annotation, reporting whether it holds an accessor.
+func parseTokenRecord(value string) (tokenRecord, bool) {
+	var record tokenRecord
+	if value == "" || json.Unmarshal([]byte(value), &record) != nil {
+		return tokenRecord{}, false
+	}
+	return record, record.Accessor != ""
+}
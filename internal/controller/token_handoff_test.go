@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/hashicorp/vault/api"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// handoffVault holds a token with the given state and records revoked accessors. Accessors
+// are reported as issued to the operator's role unless foreign is set.
+type handoffVault struct {
+	state     vault.TokenState
+	foreign   bool
+	lookupErr error
+	revokeErr error
+	revoked   []string
+}
+
+func (v *handoffVault) TokenState() vault.TokenState {
+	return v.state
+}
+
+func (v *handoffVault) AccessorIssuedByClient(_ context.Context, _ string) (bool, error) {
+	return !v.foreign, v.lookupErr
+}
+
+func (v *handoffVault) RevokeAccessor(_ context.Context, accessor string) error {
+	if v.revokeErr != nil {
+		return v.revokeErr
+	}
+	v.revoked = append(v.revoked, accessor)
+	return nil
+}
+
+func TestTokenHandoff(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	key := types.NamespacedName{Namespace: "vault-sync-operator-system", Name: "vault-sync-operator.io"}
+
+	tests := []struct {
+		name      string
+		previous  string
+		foreign   bool
+		lookupErr error
+		revokeErr error
+		revoked   []string
+		result    string
+	}{
+		{name: "no previous leader"},
+		{
+			name:     "previous token revoked",
+			previous: `{"holder":"operator-a","accessor":"accessor-a","issued":"2026-01-01T00:00:00Z","expiry":"2100-01-01T00:00:00Z"}`,
+			revoked:  []string{"accessor-a"},
+			result:   "revoked",
+		},
+		{
+			name:     "previous token without expiry revoked",
+			previous: `{"holder":"operator-a","accessor":"accessor-a","issued":"2026-01-01T00:00:00Z","expiry":"0001-01-01T00:00:00Z"}`,
+			revoked:  []string{"accessor-a"},
+			result:   "revoked",
+		},
+		{
+			name:     "previous token expired",
+			previous: `{"holder":"operator-a","accessor":"accessor-a","issued":"2020-01-01T00:00:00Z","expiry":"2020-01-02T00:00:00Z"}`,
+			result:   "expired",
+		},
+		{
+			name:      "previous token already revoked",
+			previous:  `{"holder":"operator-a","accessor":"accessor-a","issued":"2026-01-01T00:00:00Z","expiry":"2100-01-01T00:00:00Z"}`,
+			revokeErr: &api.ResponseError{StatusCode: http.StatusBadRequest},
+			result:    "expired",
+		},
+		{
+			name:      "previous token unknown to vault",
+			previous:  `{"holder":"operator-a","accessor":"accessor-a","issued":"2026-01-01T00:00:00Z","expiry":"2100-01-01T00:00:00Z"}`,
+			lookupErr: &api.ResponseError{StatusCode: http.StatusBadRequest},
+			result:    "expired",
+		},
+		{
+			name:     "token of another role not revoked",
+			previous: `{"holder":"operator-a","accessor":"accessor-other","issued":"2026-01-01T00:00:00Z","expiry":"2100-01-01T00:00:00Z"}`,
+			foreign:  true,
+			result:   "foreign",
+		},
+		{
+			name:      "revocation denied",
+			previous:  `{"holder":"operator-a","accessor":"accessor-a","issued":"2026-01-01T00:00:00Z","expiry":"2100-01-01T00:00:00Z"}`,
+			revokeErr: &api.ResponseError{StatusCode: http.StatusForbidden},
+			result:    "failed",
+		},
+		{
+			name:     "own token after a restart",
+			previous: `{"holder":"operator-b","accessor":"accessor-b","issued":"2026-01-01T00:00:00Z"}`,
+		},
+		{name: "unreadable record", previous: "not json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+			if tt.previous != "" {
+				lease.Annotations = map[string]string{VaultTokenStateAnnotation: tt.previous}
+			}
+			k8sClient := fake.NewClientBuilder().WithObjects(lease).Build()
+			vaultClient := &handoffVault{
+				state:     vault.TokenState{Accessor: "accessor-b", Issued: now, Expiry: now.Add(time.Hour)},
+				foreign:   tt.foreign,
+				lookupErr: tt.lookupErr,
+				revokeErr: tt.revokeErr,
+			}
+			handoff := &TokenHandoff{
+				Client:      k8sClient,
+				Reader:      k8sClient,
+				VaultClient: vaultClient,
+				Namespace:   key.Namespace,
+				LeaseName:   key.Name,
+				Identity:    "operator-b",
+				Interval:    DefaultTokenHandoffInterval,
+				Log:         logr.Discard(),
+			}
+
+			var before float64
+			if tt.result != "" {
+				before = testutil.ToFloat64(metrics.TokenHandoffs.WithLabelValues(tt.result))
+			}
+			if err := handoff.sync(ctx, now); err != nil {
+				t.Fatalf("sync() error = %v", err)
+			}
+			if len(vaultClient.revoked) != len(tt.revoked) || (len(tt.revoked) > 0 && vaultClient.revoked[0] != tt.revoked[0]) {
+				t.Errorf("revoked = %v, expected %v", vaultClient.revoked, tt.revoked)
+			}
+			if tt.result != "" {
+				if got := testutil.ToFloat64(metrics.TokenHandoffs.WithLabelValues(tt.result)) - before; got != 1 {
+					t.Errorf("%s handoffs = %v, expected 1", tt.result, got)
+				}
+			}
+
+			if err := k8sClient.Get(ctx, key, lease); err != nil {
+				t.Fatalf("failed to get lease: %v", err)
+			}
+			record, ok := parseTokenRecord(lease.Annotations[VaultTokenStateAnnotation])
+			if !ok || record.Holder != "operator-b" || record.Accessor != "accessor-b" {
+				t.Errorf("token state = %+v, expected the accessor of operator-b", record)
+			}
+		})
+	}
+}
+
+func TestTokenHandoffRepublishesRefreshedToken(t *testing.T) {
+	ctx := context.Background()
+	key := types.NamespacedName{Namespace: "vault-sync-operator-system", Name: "vault-sync-operator.io"}
+	lease := &coordinationv1.Lease{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	k8sClient := fake.NewClientBuilder().WithObjects(lease).Build()
+	vaultClient := &handoffVault{}
+	handoff := &TokenHandoff{
+		Client:      k8sClient,
+		Reader:      k8sClient,
+		VaultClient: vaultClient,
+		Namespace:   key.Namespace,
+		LeaseName:   key.Name,
+		Identity:    "operator-a",
+		Log:         logr.Discard(),
+	}
+
+	// Nothing is published before the first login
+	if err := handoff.sync(ctx, time.Now()); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if _, ok := lease.Annotations[VaultTokenStateAnnotation]; ok {
+		t.Error("token state published before the first login")
+	}
+
+	// A refreshed token replaces the record without revoking the leader's own earlier token
+	for _, accessor := range []string{"accessor-1", "accessor-2"} {
+		vaultClient.state = vault.TokenState{Accessor: accessor, Issued: time.Now()}
+		if err := handoff.sync(ctx, time.Now()); err != nil {
+			t.Fatalf("sync() error = %v", err)
+		}
+	}
+	if len(vaultClient.revoked) != 0 {
+		t.Errorf("revoked = %v, expected none", vaultClient.revoked)
+	}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if record, _ := parseTokenRecord(lease.Annotations[VaultTokenStateAnnotation]); record.Accessor != "accessor-2" {
+		t.Errorf("published accessor = %q, expected accessor-2", record.Accessor)
+	}
+}
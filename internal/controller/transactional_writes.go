@@ -0,0 +1,147 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements all-or-nothing writes of the several Vault documents of a sync.
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// VaultTransactionalWritesAnnotation applies the Vault documents of a sync all-or-nothing
+// ("true" or "false", overriding --transactional-writes).
+const VaultTransactionalWritesAnnotation = "vault-sync.io/transactional-writes"
+
+// StagingPathSuffix is appended to a path to stage its document before it is promoted.
+const StagingPathSuffix = ".vault-sync-staging"
+
+// WritesTransactionally reports whether the Vault documents of a sync of obj are written
+// all-or-nothing, from its vault-sync.io/transactional-writes annotation or else the
+// operator default.
+func WritesTransactionally(obj client.Object, defaultTransactional bool) bool {
+	if value, ok := obj.GetAnnotations()[VaultTransactionalWritesAnnotation]; ok {
+		if transactional, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return transactional
+		}
+	}
+	return defaultTransactional
+}
+
+// vaultDocument is one of the documents written by a sync.
+type vaultDocument struct {
+	Path string
+	Data map[string]interface{}
+}
+
+// writeVaultDocuments writes docs so that consumers see either all of them or none. Each
+// document is first written to a staging path next to its own and read back, so errors such
+// as denied paths or oversized documents surface before any real path changes. The documents
+// are then promoted to their paths; when a promotion fails, the documents already promoted
+// are restored to their previous content, or deleted when they did not exist. Staging paths
+// are destroyed with all of their versions in every case, so the staged values cannot be
+// undeleted there. Vault has no transactions, so a consumer reading during the
+// promotion may still see a mix for the duration of the writes, but never after them.
+func writeVaultDocuments(ctx context.Context, vc VaultWriterDeleter, recorder events.EventRecorder, obj client.Object, docs []vaultDocument, log logr.Logger) error {
+	reader, ok := vc.(VaultReader)
+	if !ok {
+		return fmt.Errorf("vault client cannot read secrets, which transactional writes need to roll back")
+	}
+
+	// Snapshot the current content to roll back to, from the Vault primary rather than a
+	// replica that may lag behind it
+	previous := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		data, err := reader.ReadSecret(vault.WithPrimaryReads(ctx), doc.Path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s before a transactional write: %w", doc.Path, err)
+		}
+		previous[i] = data
+	}
+
+	// Stage and verify every document
+	staged := make([]string, 0, len(docs))
+	defer func() {
+		for _, path := range staged {
+			if err := destroyVaultPath(ctx, vc, path); err != nil {
+				log.Error(err, "failed to destroy staging path", "path", path)
+			}
+		}
+	}()
+	for _, doc := range docs {
+		stagingPath := doc.Path + StagingPathSuffix
+		staged = append(staged, stagingPath)
+		if err := vc.WriteSecret(ctx, stagingPath, doc.Data); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", doc.Path, err)
+		}
+		if err := verifyVaultPath(ctx, vc, stagingPath); err != nil {
+			return fmt.Errorf("failed to verify staged %s: %w", doc.Path, err)
+		}
+	}
+
+	// Promote the documents, rolling back the promoted ones on failure
+	for i, doc := range docs {
+		err := vc.WriteSecret(ctx, doc.Path, doc.Data)
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("failed to promote %s: %w", doc.Path, err)
+		rollbackErr := rollbackVaultDocuments(ctx, vc, docs[:i], previous[:i])
+		if rollbackErr != nil {
+			metrics.TransactionalWriteRollbacks.WithLabelValues("failed").Inc()
+			recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultRollbackFailed", "Sync",
+				"Failed to roll back %d of %d Vault paths after a partial write, Vault holds a partial sync: %v", i, len(docs), rollbackErr)
+			return errors.Join(err, rollbackErr)
+		}
+		metrics.TransactionalWriteRollbacks.WithLabelValues("success").Inc()
+		recordEvent(recorder, obj, corev1.EventTypeWarning, "VaultWriteRolledBack", "Sync",
+			"Rolled back %d of %d Vault paths after a partial write: %v", i, len(docs), err)
+		return err
+	}
+	return nil
+}
+
+// destroyVaultPath removes path with all of its versions when the client supports it, and
+// deletes its current version otherwise.
+func destroyVaultPath(ctx context.Context, vc VaultWriterDeleter, path string) error {
+	if destroyer, ok := vc.(secretDestroyer); ok {
+		return destroyer.DestroySecret(ctx, path)
+	}
+	return vc.DeleteSecret(ctx, path)
+}
+
+// stagingPaths returns the staging paths of paths.
+func stagingPaths(paths []string) []string {
+	staging := make([]string, len(paths))
+	for i, path := range paths {
+		staging[i] = path + StagingPathSuffix
+	}
+	return staging
+}
+
+// rollbackVaultDocuments restores docs to their previous content, deleting the paths that
+// had none. Rollback continues past failures so as many paths as possible are restored.
+func rollbackVaultDocuments(ctx context.Context, vc VaultWriterDeleter, docs []vaultDocument, previous []map[string]interface{}) error {
+	var errs []error
+	for i, doc := range docs {
+		var err error
+		if previous[i] == nil {
+			err = vc.DeleteSecret(ctx, doc.Path)
+		} else {
+			err = vc.WriteSecret(ctx, doc.Path, previous[i])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to roll back %s: %w", doc.Path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
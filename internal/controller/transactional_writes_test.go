@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+)
+
+// partialVault fails writes to the paths in fail, as when a policy denies only some paths,
+// and records the paths destroyed with all of their versions.
+type partialVault struct {
+	readableVault
+	fail      map[string]bool
+	destroyed []string
+}
+
+func (f *partialVault) DestroySecret(_ context.Context, path string) error {
+	delete(f.secrets, path)
+	f.destroyed = append(f.destroyed, path)
+	return nil
+}
+
+func (f *partialVault) WriteSecret(ctx context.Context, path string, data map[string]interface{}) error {
+	if f.fail[path] {
+		return errors.New("permission denied")
+	}
+	return f.readableVault.WriteSecret(ctx, path, data)
+}
+
+func TestWritesTransactionally(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		defaultOn   bool
+		expected    bool
+	}{
+		{name: "default off", expected: false},
+		{name: "default on", defaultOn: true, expected: true},
+		{name: "annotation on", annotations: map[string]string{VaultTransactionalWritesAnnotation: "true"}, expected: true},
+		{name: "annotation off", annotations: map[string]string{VaultTransactionalWritesAnnotation: "false"}, defaultOn: true, expected: false},
+		{name: "invalid annotation", annotations: map[string]string{VaultTransactionalWritesAnnotation: "maybe"}, defaultOn: true, expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			if got := WritesTransactionally(deployment, tt.defaultOn); got != tt.expected {
+				t.Errorf("WritesTransactionally() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWriteVaultDocuments(t *testing.T) {
+	docs := []vaultDocument{
+		{Path: "secret/data/app/db", Data: map[string]interface{}{"password": "new"}},
+		{Path: "secret/data/app/api", Data: map[string]interface{}{"token": "new"}},
+		{Path: "secret/data/app/tls", Data: map[string]interface{}{"key": "new"}},
+	}
+	existing := func() map[string]map[string]interface{} {
+		return map[string]map[string]interface{}{
+			"secret/data/app/db":  {"password": "old"},
+			"secret/data/app/tls": {"key": "old"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		fail     string
+		expected map[string]string
+		event    string
+	}{
+		{
+			name:     "all written",
+			expected: map[string]string{"secret/data/app/db": "new", "secret/data/app/api": "new", "secret/data/app/tls": "new"},
+		},
+		{
+			name:     "staging failure changes nothing",
+			fail:     "secret/data/app/api" + StagingPathSuffix,
+			expected: map[string]string{"secret/data/app/db": "old", "secret/data/app/tls": "old"},
+		},
+		{
+			name:     "promotion failure rolls back",
+			fail:     "secret/data/app/tls",
+			expected: map[string]string{"secret/data/app/db": "old", "secret/data/app/tls": "old"},
+			event:    "VaultWriteRolledBack",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vaultClient := &partialVault{fail: map[string]bool{tt.fail: true}}
+			vaultClient.secrets = existing()
+			recorder := events.NewFakeRecorder(10)
+
+			err := writeVaultDocuments(context.Background(), vaultClient, recorder, &appsv1.Deployment{}, docs, logr.Discard())
+			if (err != nil) != (tt.fail != "") {
+				t.Fatalf("writeVaultDocuments() error = %v, expected failure: %v", err, tt.fail != "")
+			}
+
+			got := make(map[string]string)
+			for path, data := range vaultClient.secrets {
+				for _, value := range data {
+					got[path] = value.(string)
+				}
+			}
+			if len(got) != len(tt.expected) {
+				t.Errorf("vault = %v, expected %v", got, tt.expected)
+			}
+			for path, value := range tt.expected {
+				if got[path] != value {
+					t.Errorf("%s = %q, expected %q", path, got[path], value)
+				}
+			}
+			for path := range vaultClient.secrets {
+				if strings.HasSuffix(path, StagingPathSuffix) {
+					t.Errorf("staging path %s was not deleted", path)
+				}
+			}
+			for _, path := range vaultClient.deletes {
+				if strings.HasSuffix(path, StagingPathSuffix) {
+					t.Errorf("staging path %s was soft deleted, expected it to be destroyed", path)
+				}
+			}
+			if len(vaultClient.destroyed) == 0 {
+				t.Errorf("expected the staging paths to be destroyed")
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if tt.event == "" || !strings.Contains(event, tt.event) {
+					t.Errorf("event = %q, expected %q", event, tt.event)
+				}
+			default:
+				if tt.event != "" {
+					t.Errorf("expected a %s event", tt.event)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the removal of the operator's finalizers when it is uninstalled.
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// finalizerRemovalPageSize is the number of resources listed per request.
+const finalizerRemovalPageSize = 500
+
+// FinalizerRemoval removes the operator's finalizer from every resource of Kinds in the
+// cluster, so that resources can still be deleted once the operator is uninstalled.
+type FinalizerRemoval struct {
+	// Client reads from and writes to the API server directly; a cache is never started
+	Client client.Client
+	Kinds  []schema.GroupVersionKind
+	// Finalizers finalize the resources of their kind that are already being deleted, deleting
+	// their Vault data as the running operator would have. Resources of kinds without a
+	// finalizer, or of every kind when nil, only lose the finalizer and keep their Vault data.
+	Finalizers map[schema.GroupVersionKind]reconcile.Reconciler
+	Log        logr.Logger
+}
+
+// FinalizerRemovalResult counts the resources handled by a finalizer removal.
+type FinalizerRemovalResult struct {
+	// Removed counts finalizers removed without touching Vault
+	Removed int
+	// Finalized counts resources being deleted whose Vault data was deleted
+	Finalized int
+	// Failed counts resources that still carry the finalizer
+	Failed int
+}
+
+// Run removes the finalizer from every resource carrying it. Resources that cannot be
+// finalized keep the finalizer and are counted as failed, so Run can be repeated; an error is
+// only returned when a kind cannot be listed.
+func (f *FinalizerRemoval) Run(ctx context.Context) (FinalizerRemovalResult, error) {
+	var result FinalizerRemovalResult
+	for _, gvk := range f.Kinds {
+		// Metadata-only lists keep memory bounded on clusters with many Secrets
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		for {
+			if err := f.Client.List(ctx, list, client.Limit(finalizerRemovalPageSize), client.Continue(list.Continue)); err != nil {
+				return result, fmt.Errorf("failed to list %s: %w", gvk.Kind, err)
+			}
+			for i := range list.Items {
+				obj := &list.Items[i]
+				obj.SetGroupVersionKind(gvk)
+				if controllerutil.ContainsFinalizer(obj, VaultSyncFinalizer) {
+					f.remove(ctx, gvk, obj, &result)
+				}
+			}
+			if list.Continue == "" {
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// remove removes the finalizer from obj, finalizing it first when it is being deleted.
+func (f *FinalizerRemoval) remove(ctx context.Context, gvk schema.GroupVersionKind, obj *metav1.PartialObjectMetadata, result *FinalizerRemovalResult) {
+	log := f.Log.WithValues("kind", gvk.Kind, "namespace", obj.Namespace, "name", obj.Name)
+
+	if finalizer := f.Finalizers[gvk]; finalizer != nil && obj.DeletionTimestamp != nil {
+		res, err := finalizer.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(obj)})
+		if err == nil && !res.IsZero() {
+			err = fmt.Errorf("finalization was postponed by %s, vault may be sealed", res.RequeueAfter)
+		}
+		if err != nil {
+			result.Failed++
+			log.Error(err, "failed to delete vault data, keeping the finalizer")
+			return
+		}
+		result.Finalized++
+		log.Info("deleted vault data and removed finalizer")
+		return
+	}
+
+	if err := removeFinalizerPatch(ctx, f.Client, obj); err != nil {
+		result.Failed++
+		log.Error(err, "failed to remove finalizer")
+		return
+	}
+	result.Removed++
+	log.Info("removed finalizer")
+}
+
+// removeFinalizerPatch removes the operator's finalizer from obj with an optimistically locked
+// merge patch, which unlike an update also works for metadata-only objects.
+func removeFinalizerPatch(ctx context.Context, k8sClient client.Client, obj client.Object) error {
+	current := obj.DeepCopyObject().(client.Object)
+	refresh := false
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(current), current); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+		}
+		refresh = true
+
+		original := current.DeepCopyObject().(client.Object)
+		if !controllerutil.RemoveFinalizer(current, VaultSyncFinalizer) {
+			return nil
+		}
+		patch := client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+		return client.IgnoreNotFound(k8sClient.Patch(ctx, current, patch))
+	})
+}
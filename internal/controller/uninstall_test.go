@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestFinalizerRemoval(t *testing.T) {
+	ctx := context.Background()
+	deploymentKind := appsv1.SchemeGroupVersion.WithKind("Deployment")
+	secretKind := corev1.SchemeGroupVersion.WithKind("Secret")
+
+	newDeployment := func(name string, deleting bool) *appsv1.Deployment {
+		deployment := &appsv1.Deployment{}
+		deployment.Name = name
+		deployment.Namespace = "default"
+		deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/" + name}
+		deployment.Finalizers = []string{VaultSyncFinalizer}
+		if deleting {
+			deployment.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+		}
+		return deployment
+	}
+	synced := &corev1.Secret{}
+	synced.Name = "db"
+	synced.Namespace = "default"
+	synced.Finalizers = []string{VaultSyncFinalizer}
+	unmanaged := &corev1.Secret{}
+	unmanaged.Name = "tls"
+	unmanaged.Namespace = "default"
+	unmanaged.Finalizers = []string{"example.com/other"}
+
+	tests := []struct {
+		name            string
+		preserve        bool
+		expectedResult  FinalizerRemovalResult
+		expectedDeletes []string
+	}{
+		{
+			name:            "delete vault data of resources being deleted",
+			expectedResult:  FinalizerRemovalResult{Removed: 2, Finalized: 1},
+			expectedDeletes: []string{"secret/data/old"},
+		},
+		{
+			name:           "preserve vault data",
+			preserve:       true,
+			expectedResult: FinalizerRemovalResult{Removed: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().
+				WithObjects(newDeployment("web", false), newDeployment("old", true), synced.DeepCopy(), unmanaged.DeepCopy()).
+				Build()
+			vaultClient := &fakeVault{}
+
+			removal := &FinalizerRemoval{
+				Client: k8sClient,
+				Kinds:  []schema.GroupVersionKind{deploymentKind, secretKind},
+				Log:    logr.Discard(),
+			}
+			if !tt.preserve {
+				removal.Finalizers = map[schema.GroupVersionKind]reconcile.Reconciler{
+					deploymentKind: &DeploymentReconciler{Client: k8sClient, Scheme: runtime.NewScheme(), Log: logr.Discard(), VaultClient: vaultClient},
+				}
+			}
+
+			result, err := removal.Run(ctx)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			if result != tt.expectedResult {
+				t.Errorf("Run() = %+v, expected %+v", result, tt.expectedResult)
+			}
+			if !reflect.DeepEqual(vaultClient.deletes, tt.expectedDeletes) {
+				t.Errorf("vault deletes = %v, expected %v", vaultClient.deletes, tt.expectedDeletes)
+			}
+
+			// The deleting Deployment is gone and the other resources lost only our finalizer
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "old"}, &appsv1.Deployment{}); !apierrors.IsNotFound(err) {
+				t.Errorf("deleting deployment still exists: %v", err)
+			}
+			web := &appsv1.Deployment{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "web"}, web); err != nil || len(web.Finalizers) != 0 {
+				t.Errorf("deployment finalizers = %v (%v), expected none", web.Finalizers, err)
+			}
+			other := &corev1.Secret{}
+			if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(unmanaged), other); err != nil || !reflect.DeepEqual(other.Finalizers, unmanaged.Finalizers) {
+				t.Errorf("unmanaged secret finalizers = %v (%v), expected them unchanged", other.Finalizers, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the write-time value validation rules of vault-sync.io/secrets.
+package controller
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+// Value formats accepted by the format rule.
+const (
+	ValueFormatBase64 = "base64" // Standard base64 with padding
+	ValueFormatJSON   = "json"   // A valid JSON document
+	ValueFormatPEM    = "pem"    // One or more complete PEM blocks, e.g. a certificate chain
+)
+
+// ValueRule declares what a valid value of a secret key looks like. A value that breaks a
+// rule fails the sync before anything is written, so broken values never reach Vault.
+type ValueRule struct {
+	// Pattern is a regular expression the whole value must match
+	Pattern string `json:"pattern,omitempty"`
+	// MinLength is the minimum length of the value in characters
+	MinLength int `json:"minLength,omitempty"`
+	// Format is base64, json or pem
+	Format string `json:"format,omitempty"`
+}
+
+// validateRules checks that the validation rules of a secret configuration can be applied.
+func (c SecretConfig) validateRules() error {
+	keys := make([]string, 0, len(c.Validate))
+	for key := range c.Validate {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rule := c.Validate[key]
+		if !slices.Contains(c.Keys, key) {
+			return fmt.Errorf("validation rule for key %s of secret %s: the key is not synced", key, c.Name)
+		}
+		if rule.Pattern != "" {
+			if _, err := regexp.Compile(rule.Pattern); err != nil {
+				return fmt.Errorf("validation rule for key %s of secret %s: invalid pattern: %w", key, c.Name, err)
+			}
+		}
+		if rule.MinLength < 0 {
+			return fmt.Errorf("validation rule for key %s of secret %s: minLength must not be negative", key, c.Name)
+		}
+		switch rule.Format {
+		case "", ValueFormatBase64, ValueFormatJSON, ValueFormatPEM:
+		default:
+			return fmt.Errorf("validation rule for key %s of secret %s: unknown format %q, expected base64, json or pem", key, c.Name, rule.Format)
+		}
+	}
+	return nil
+}
+
+// validateValue checks a value of a secret key against its validation rule. The error never
+// contains the value itself.
+func (c SecretConfig) validateValue(key string, value []byte) error {
+	rule, ok := c.Validate[key]
+	if !ok {
+		return nil
+	}
+
+	if length := utf8.RuneCount(value); length < rule.MinLength {
+		return fmt.Errorf("key %s of secret %s failed validation: value has %d characters, at least %d required", key, c.Name, length, rule.MinLength)
+	}
+	if rule.Pattern != "" {
+		pattern := regexp.MustCompile(`^(?:` + rule.Pattern + `)$`)
+		if !pattern.Match(value) {
+			return fmt.Errorf("key %s of secret %s failed validation: value does not match pattern %q", key, c.Name, rule.Pattern)
+		}
+	}
+
+	switch rule.Format {
+	case ValueFormatBase64:
+		if _, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(value))); err != nil {
+			return fmt.Errorf("key %s of secret %s failed validation: value is not valid base64", key, c.Name)
+		}
+	case ValueFormatJSON:
+		if !json.Valid(value) {
+			return fmt.Errorf("key %s of secret %s failed validation: value is not valid JSON", key, c.Name)
+		}
+	case ValueFormatPEM:
+		if !isCompletePEM(value) {
+			return fmt.Errorf("key %s of secret %s failed validation: value is not complete PEM data", key, c.Name)
+		}
+	}
+	return nil
+}
+
+// isCompletePEM reports whether data consists of one or more complete PEM blocks, which a
+// truncated certificate or key is not.
+func isCompletePEM(data []byte) bool {
+	block, rest := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	for len(bytes.TrimSpace(rest)) > 0 {
+		if block, rest = pem.Decode(rest); block == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+const testCertificate = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIQIRi6zePL6mKjOipn+dNuaTAKBggqhkjOPQQDAjASMRAw
+DgYDVQQKEwdBY21lIENvMB4XDTE3MTAyMDE5NDMwNloXDTE4MTAyMDE5NDMwNlow
+-----END CERTIFICATE-----
+`
+
+func TestSecretConfigValidateRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate map[string]ValueRule
+		wantErr  bool
+	}{
+		{name: "no rules", validate: nil},
+		{name: "all rules", validate: map[string]ValueRule{"password": {Pattern: "[a-z]+", MinLength: 8, Format: ValueFormatBase64}}},
+		{name: "key not synced", validate: map[string]ValueRule{"token": {MinLength: 8}}, wantErr: true},
+		{name: "invalid pattern", validate: map[string]ValueRule{"password": {Pattern: "(["}}, wantErr: true},
+		{name: "negative length", validate: map[string]ValueRule{"password": {MinLength: -1}}, wantErr: true},
+		{name: "unknown format", validate: map[string]ValueRule{"password": {Format: "yaml"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := SecretConfig{Name: "db", Keys: []string{"password"}, Validate: tt.validate}
+			if err := config.validateRules(); (err != nil) != tt.wantErr {
+				t.Errorf("validateRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecretConfigValidateValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ValueRule
+		value   string
+		wantErr bool
+	}{
+		{name: "long enough", rule: ValueRule{MinLength: 4}, value: "s3cret"},
+		{name: "empty password", rule: ValueRule{MinLength: 1}, value: "", wantErr: true},
+		{name: "length in characters", rule: ValueRule{MinLength: 3}, value: "äöü"},
+		{name: "matching pattern", rule: ValueRule{Pattern: "sk_[a-z]+"}, value: "sk_live"},
+		{name: "partial match", rule: ValueRule{Pattern: "sk_[a-z]+"}, value: "sk_live!", wantErr: true},
+		{name: "base64", rule: ValueRule{Format: ValueFormatBase64}, value: "czNjcmV0\n"},
+		{name: "invalid base64", rule: ValueRule{Format: ValueFormatBase64}, value: "s3cret!", wantErr: true},
+		{name: "json", rule: ValueRule{Format: ValueFormatJSON}, value: `{"user": "app"}`},
+		{name: "truncated json", rule: ValueRule{Format: ValueFormatJSON}, value: `{"user": "ap`, wantErr: true},
+		{name: "pem", rule: ValueRule{Format: ValueFormatPEM}, value: testCertificate + testCertificate},
+		{name: "truncated pem", rule: ValueRule{Format: ValueFormatPEM}, value: testCertificate[:100], wantErr: true},
+		{name: "trailing garbage after pem", rule: ValueRule{Format: ValueFormatPEM}, value: testCertificate + testCertificate[:60], wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := SecretConfig{Name: "db", Keys: []string{"value"}, Validate: map[string]ValueRule{"value": tt.rule}}
+			err := config.validateValue("value", []byte(tt.value))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && tt.value != "" && strings.Contains(err.Error(), tt.value) {
+				t.Errorf("validateValue() error %q contains the value", err)
+			}
+		})
+	}
+}
+
+// TestSyncCustomSecretsRefusesInvalidValues tests that a value breaking its rule fails the sync.
+func TestSyncCustomSecretsRefusesInvalidValues(t *testing.T) {
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("")}}
+	secret.Name = "db-refused"
+	secret.Namespace = "default"
+	sc := &SyncContext{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard()}
+	resource := ResourceInfo{Name: "app", Namespace: "default", Type: "secret"}
+
+	config := `[{"name": "db-refused", "keys": ["password"], "validate": {"password": {"minLength": 1}}}]`
+	if _, _, err := sc.SyncCustomSecretsWithVersions(context.Background(), resource, config, "default"); err == nil {
+		t.Fatal("SyncCustomSecretsWithVersions() synced an empty password")
+	}
+	if got := testutil.ToFloat64(metrics.ValueValidationFailures.WithLabelValues("default", "db-refused", "password")); got != 1 {
+		t.Errorf("value validation failures = %v, expected 1", got)
+	}
+
+	secret.Data["password"] = []byte("s3cret")
+	sc.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultData, _, err := sc.SyncCustomSecretsWithVersions(context.Background(), resource, config, "default")
+	if err != nil || vaultData["password"] != "s3cret" {
+		t.Errorf("SyncCustomSecretsWithVersions() = %v, %v, expected the valid password", vaultData, err)
+	}
+}
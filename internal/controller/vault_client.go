@@ -0,0 +1,143 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file defines the Vault operations the reconcilers depend on.
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// VaultWriter writes secrets to Vault.
+type VaultWriter interface {
+	WriteSecret(ctx context.Context, path string, data map[string]interface{}) error
+}
+
+// VaultDeleter deletes secrets from Vault.
+type VaultDeleter interface {
+	DeleteSecret(ctx context.Context, path string) error
+}
+
+// VaultReader reads secrets from Vault.
+type VaultReader interface {
+	ReadSecret(ctx context.Context, path string) (map[string]interface{}, error)
+}
+
+// VaultReadWriter reads and writes secrets in Vault.
+type VaultReadWriter interface {
+	VaultReader
+	VaultWriter
+}
+
+// VaultWriterDeleter is the Vault client used by the reconcilers. *vault.Client implements
+// it; fakes only need these two methods. The optional interfaces below add KV metadata,
+// path locking, seal detection and backpressure when implemented.
+type VaultWriterDeleter interface {
+	VaultWriter
+	VaultDeleter
+}
+
+// KVMountEnsurer creates KV mounts that are missing, see KVMountProvisioner.
+type KVMountEnsurer interface {
+	EnsureKVMount(ctx context.Context, mount vault.KVMount) (bool, error)
+}
+
+// CapabilitiesChecker looks up the capabilities of the Vault token on the paths it writes,
+// see ACLDriftDetector.
+type CapabilitiesChecker interface {
+	Capabilities(ctx context.Context, paths []string) (map[string][]string, error)
+}
+
+// metadataWriter applies KV v2 metadata settings.
+type metadataWriter interface {
+	EnsureSecretMetadata(ctx context.Context, path string, md vault.KVMetadata) (bool, error)
+}
+
+// metadataReader reads the KV v2 custom metadata of secrets.
+type metadataReader interface {
+	SecretCustomMetadata(ctx context.Context, path string) (map[string]string, error)
+}
+
+// subkeysReader reads the top-level keys of secrets without their values.
+type subkeysReader interface {
+	SecretSubkeys(ctx context.Context, path string) ([]string, error)
+}
+
+// secretDestroyer removes secrets with all of their versions.
+type secretDestroyer interface {
+	DestroySecret(ctx context.Context, path string) error
+}
+
+// pathLocker serializes reconciles targeting the same Vault path.
+type pathLocker interface {
+	LockPath(ctx context.Context, path string) (func(), error)
+}
+
+// sealProber reports and re-probes whether Vault is sealed.
+type sealProber interface {
+	IsSealed() bool
+	SealedSince() time.Time
+	State(ctx context.Context) (vault.State, error)
+}
+
+// backpressureReporter reports whether the client's rate limiter is saturated.
+type backpressureReporter interface {
+	PendingRequests() int64
+	BackpressureDelay() (time.Duration, bool)
+	BackpressureDelayAt(fraction float64) (time.Duration, bool)
+	NamespaceBackpressureDelayAt(namespace string, fraction float64) (time.Duration, bool)
+}
+
+// throttleReporter reports whether Vault asked requests to back off.
+type throttleReporter interface {
+	ThrottleDelay() (time.Duration, bool)
+}
+
+var (
+	_ VaultWriterDeleter   = (*vault.Client)(nil)
+	_ VaultReadWriter      = (*vault.Client)(nil)
+	_ metadataWriter       = (*vault.Client)(nil)
+	_ metadataReader       = (*vault.Client)(nil)
+	_ subkeysReader        = (*vault.Client)(nil)
+	_ pathLocker           = (*vault.Client)(nil)
+	_ sealProber           = (*vault.Client)(nil)
+	_ backpressureReporter = (*vault.Client)(nil)
+	_ throttleReporter     = (*vault.Client)(nil)
+)
+
+// lockVaultPath locks path when the client supports path locking and returns the unlock function.
+func lockVaultPath(ctx context.Context, vc VaultWriterDeleter, path string) (func(), error) {
+	locker, ok := vc.(pathLocker)
+	if !ok {
+		return func() {}, nil
+	}
+	return locker.LockPath(ctx, path)
+}
+
+// pendingVaultRequests returns the number of requests waiting on the client's rate limiter.
+func pendingVaultRequests(vc VaultWriterDeleter) int64 {
+	if reporter, ok := vc.(backpressureReporter); ok {
+		return reporter.PendingRequests()
+	}
+	return 0
+}
+
+// vaultThrottleDelay reports whether Vault asked requests to back off and for how long.
+// Clients without throttle detection never report it.
+func vaultThrottleDelay(vc VaultWriterDeleter) (time.Duration, bool) {
+	if reporter, ok := vc.(throttleReporter); ok {
+		return reporter.ThrottleDelay()
+	}
+	return 0, false
+}
+
+// vaultSecretKeys returns the top-level keys of the secret at path without reading its values,
+// when the client supports it. An empty result is not conclusive, since the subkeys endpoint
+// may be unavailable, so callers fall back to reading the secret.
+func vaultSecretKeys(ctx context.Context, vc VaultWriterDeleter, path string) ([]string, error) {
+	if reader, ok := vc.(subkeysReader); ok {
+		return reader.SecretSubkeys(ctx, path)
+	}
+	return nil, nil
+}
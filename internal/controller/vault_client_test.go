@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileUntilSynced reconciles req twice, as the first reconcile of a resource only adds
+// the finalizer, and returns the result of the sync.
+func reconcileUntilSynced(t *testing.T, r reconcile.Reconciler, req reconcile.Request) reconcile.Result {
+	t.Helper()
+	var result reconcile.Result
+	for i := 0; i < 2; i++ {
+		var err error
+		if result, err = r.Reconcile(t.Context(), req); err != nil {
+			t.Fatalf("Reconcile() error = %v", err)
+		}
+	}
+	return result
+}
+
+// fakeVault is an in-memory VaultWriterDeleter.
+type fakeVault struct {
+	secrets map[string]map[string]interface{}
+	deletes []string
+}
+
+func (f *fakeVault) WriteSecret(_ context.Context, path string, data map[string]interface{}) error {
+	if f.secrets == nil {
+		f.secrets = make(map[string]map[string]interface{})
+	}
+	f.secrets[path] = data
+	return nil
+}
+
+func (f *fakeVault) DeleteSecret(_ context.Context, path string) error {
+	delete(f.secrets, path)
+	f.deletes = append(f.deletes, path)
+	return nil
+}
+
+// TestSecretReconcilerWithFakeVault tests a sync and deletion through a fake Vault client.
+func TestSecretReconcilerWithFakeVault(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	secret.Annotations = map[string]string{VaultPathAnnotation: "secret/data/db"}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultClient := &fakeVault{}
+	r := &SecretReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+
+	reconcileUntilSynced(t, r, req)
+	if got := vaultClient.secrets["secret/data/db"]["password"]; got != "s3cret" {
+		t.Fatalf("password in vault = %v, expected s3cret", got)
+	}
+
+	if err := k8sClient.Delete(ctx, secret); err != nil {
+		t.Fatalf("failed to delete secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() of the deleted secret error = %v", err)
+	}
+	if len(vaultClient.deletes) != 1 || vaultClient.deletes[0] != "secret/data/db" {
+		t.Errorf("vault deletes = %v, expected secret/data/db", vaultClient.deletes)
+	}
+}
+
+// TestSecretReconcilerConfigChange tests that keys dropped from vault-sync.io/secrets are
+// removed from Vault although the referenced secret did not change.
+func TestSecretReconcilerConfigChange(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"api_key": []byte("abc"), "secret_key": []byte("xyz")}}
+	secret.Name = "api-credentials"
+	secret.Namespace = "default"
+	secret.Annotations = map[string]string{
+		VaultPathAnnotation:    "secret/data/api-keys",
+		VaultSecretsAnnotation: `[{"name": "api-credentials", "keys": ["api_key", "secret_key"]}]`,
+	}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret).Build()
+	vaultClient := &fakeVault{}
+	r := &SecretReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+
+	reconcileUntilSynced(t, r, req)
+	if got := len(vaultClient.secrets["secret/data/api-keys"]); got != 2 {
+		t.Fatalf("keys in vault = %d, expected 2", got)
+	}
+
+	if err := k8sClient.Get(ctx, req.NamespacedName, secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	secret.Annotations[VaultSecretsAnnotation] = `[{"name": "api-credentials", "keys": ["api_key"], "prefix": "prod_"}]`
+	if err := k8sClient.Update(ctx, secret); err != nil {
+		t.Fatalf("failed to update secret: %v", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	data := vaultClient.secrets["secret/data/api-keys"]
+	if len(data) != 1 || data["prod_api_key"] != "abc" {
+		t.Errorf("data in vault = %v, expected only prod_api_key", data)
+	}
+
+	if err := k8sClient.Get(ctx, req.NamespacedName, secret); err != nil {
+		t.Fatalf("failed to get secret: %v", err)
+	}
+	if SyncConfigChanged(secret) {
+		t.Errorf("expected the new configuration to be recorded")
+	}
+}
+
+// TestDeploymentReconcilerRestoresDeletedPath tests that a periodic reconcile writes a path
+// that was deleted in Vault out of band again, although its secret did not change.
+func TestDeploymentReconcilerRestoresDeletedPath(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "db"
+	secret.Namespace = "default"
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{VaultPathAnnotation: "secret/data/web"}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{{
+		Name: "app",
+		EnvFrom: []corev1.EnvFromSource{{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "db"}},
+		}},
+	}}
+
+	k8sClient := fake.NewClientBuilder().WithObjects(deployment, secret).Build()
+	vaultClient := &readableVault{}
+	r := &DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)}
+
+	reconcileUntilSynced(t, r, req)
+
+	// Without periodic reconciliation the recorded versions are trusted
+	delete(vaultClient.secrets, "secret/data/web/db")
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if _, ok := vaultClient.secrets["secret/data/web/db"]; ok {
+		t.Fatalf("expected the path not to be checked without periodic reconciliation")
+	}
+
+	r.DefaultReconcileInterval = 5 * time.Minute
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := vaultClient.secrets["secret/data/web/db"]["password"]; got != "s3cret" {
+		t.Errorf("password in vault = %v, expected the deleted path to be restored", got)
+	}
+}
@@ -0,0 +1,104 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the controller of the VaultSyncConfig the operator is configured from.
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlbuilder "sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlcontroller "sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+	"github.com/danieldonoghue/vault-sync-operator/internal/config"
+)
+
+// VaultSyncConfigReconciler reports on the status of the VaultSyncConfig the operator was
+// started with whether it is applied, and restarts the operator when its spec changes, since
+// settings such as the Vault connection and the controller profiles are only read at startup.
+type VaultSyncConfigReconciler struct {
+	client.Client
+	Log logr.Logger
+	// Name is the name of the VaultSyncConfig the operator is configured from
+	Name string
+	// Generation is the generation applied at startup, zero when the resource did not exist
+	Generation int64
+	// Restart stops the manager so the operator restarts with the new configuration
+	Restart func()
+}
+
+// +kubebuilder:rbac:groups=vault-sync.io,resources=vaultsyncconfigs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vault-sync.io,resources=vaultsyncconfigs/status,verbs=get;update;patch
+
+// Reconcile compares the VaultSyncConfig with the generation applied at startup.
+func (r *VaultSyncConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.Log.WithValues("vaultsyncconfig", req.Name)
+
+	cfg := &v1alpha1.VaultSyncConfig{}
+	if err := r.Get(ctx, req.NamespacedName, cfg); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		// Without the resource the flags and config file apply again
+		if r.Generation != 0 {
+			log.Info("configuration resource deleted, restarting to apply the command-line configuration")
+			r.Restart()
+		}
+		return ctrl.Result{}, nil
+	}
+
+	condition := metav1.Condition{Type: v1alpha1.ConditionApplied}
+	restart := false
+	switch err := config.ValidateResource(&cfg.Spec); {
+	case err != nil:
+		// An invalid change is not applied, the operator keeps running with the previous generation
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.ReasonInvalid
+		condition.Message = err.Error()
+		log.Error(err, "invalid configuration resource, keeping the current configuration", "generation", cfg.Generation)
+	case cfg.Generation == r.Generation:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = v1alpha1.ReasonApplied
+		condition.Message = fmt.Sprintf("generation %d is applied", cfg.Generation)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = v1alpha1.ReasonRestartPending
+		condition.Message = fmt.Sprintf("the operator restarts to apply generation %d", cfg.Generation)
+		restart = true
+	}
+	condition.ObservedGeneration = cfg.Generation
+
+	current := cfg.DeepCopy()
+	cfg.Status.ObservedGeneration = cfg.Generation
+	meta.SetStatusCondition(&cfg.Status.Conditions, condition)
+	if err := r.Status().Patch(ctx, cfg, client.MergeFrom(current)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status of %s: %w", req.Name, err)
+	}
+
+	if restart {
+		log.Info("configuration resource changed, restarting to apply it",
+			"applied_generation", r.Generation,
+			"generation", cfg.Generation)
+		r.Restart()
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager. It runs on every replica, so
+// standby replicas restart with the new configuration too.
+func (r *VaultSyncConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("vaultsyncconfig").
+		For(&v1alpha1.VaultSyncConfig{}, ctrlbuilder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			return obj.GetName() == r.Name
+		}))).
+		WithOptions(ctrlcontroller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(r)
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/api/v1alpha1"
+)
+
+func TestVaultSyncConfigReconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		applied       int64
+		spec          v1alpha1.VaultSyncConfigSpec
+		missing       bool
+		wantStatus    metav1.ConditionStatus
+		wantReason    string
+		expectRestart bool
+	}{
+		{name: "applied", applied: 2, wantStatus: metav1.ConditionTrue, wantReason: v1alpha1.ReasonApplied},
+		{name: "changed", applied: 1, wantStatus: metav1.ConditionFalse, wantReason: v1alpha1.ReasonRestartPending, expectRestart: true},
+		{
+			name:       "invalid change",
+			applied:    1,
+			spec:       v1alpha1.VaultSyncConfigSpec{FeatureGates: map[string]bool{"UnknownFeature": true}},
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1alpha1.ReasonInvalid,
+		},
+		{name: "created", applied: 0, wantStatus: metav1.ConditionFalse, wantReason: v1alpha1.ReasonRestartPending, expectRestart: true},
+		{name: "deleted", applied: 2, missing: true, expectRestart: true},
+		{name: "never created", applied: 0, missing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := v1alpha1.AddToScheme(scheme); err != nil {
+				t.Fatalf("failed to build scheme: %v", err)
+			}
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&v1alpha1.VaultSyncConfig{})
+			if !tt.missing {
+				builder = builder.WithObjects(&v1alpha1.VaultSyncConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: 2},
+					Spec:       tt.spec,
+				})
+			}
+			k8sClient := builder.Build()
+
+			restarted := false
+			reconciler := &VaultSyncConfigReconciler{
+				Client:     k8sClient,
+				Log:        logr.Discard(),
+				Name:       "default",
+				Generation: tt.applied,
+				Restart:    func() { restarted = true },
+			}
+			if _, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: "default"}}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if restarted != tt.expectRestart {
+				t.Errorf("restarted = %v, expected %v", restarted, tt.expectRestart)
+			}
+			if tt.missing {
+				return
+			}
+
+			result := &v1alpha1.VaultSyncConfig{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: "default"}, result); err != nil {
+				t.Fatalf("failed to get VaultSyncConfig: %v", err)
+			}
+			condition := meta.FindStatusCondition(result.Status.Conditions, v1alpha1.ConditionApplied)
+			if condition == nil || condition.Status != tt.wantStatus || condition.Reason != tt.wantReason {
+				t.Errorf("Applied condition = %+v, expected %s/%s", condition, tt.wantStatus, tt.wantReason)
+			}
+			if result.Status.ObservedGeneration != 2 {
+				t.Errorf("observed generation = %d, expected 2", result.Status.ObservedGeneration)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements version skew detection between operator replicas.
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// VersionLeaseName is the Lease in the operator namespace where replicas report their version.
+const VersionLeaseName = "vault-sync-operator-versions"
+
+// replicaAnnotationPrefix prefixes the Lease annotation each replica reports itself in.
+const replicaAnnotationPrefix = "replicas.vault-sync.io/"
+
+// DefaultVersionSkewInterval is how often replicas report their version.
+const DefaultVersionSkewInterval = 30 * time.Second
+
+// replicaReport is the value of a replica's Lease annotation.
+type replicaReport struct {
+	Version string `json:"version"`
+	// Active is set once the replica reconciles, i.e. after it won leader election
+	Active bool      `json:"active"`
+	Seen   time.Time `json:"seen"`
+}
+
+// VersionSkewDetector periodically reports the replica's version in a shared Lease and
+// warns when replicas running different versions reconcile at the same time, as happens
+// during a rolling upgrade without leader election. It runs on every replica.
+type VersionSkewDetector struct {
+	// Client writes the Lease
+	Client client.Client
+	// Reader reads the Lease without caching Leases cluster-wide (typically the manager's API reader)
+	Reader client.Reader
+	// Namespace holds the Lease (the operator namespace)
+	Namespace string
+	// Identity names this replica (the pod name)
+	Identity string
+	Version  string
+	// Interval between reports; reports older than three intervals are pruned
+	Interval time.Duration
+	// Elected is closed once this replica reconciles (the manager's Elected channel)
+	Elected <-chan struct{}
+	Log     logr.Logger
+
+	// lastVersions is the last logged version summary, so changes are logged once
+	lastVersions string
+}
+
+// Start reports the replica's version every interval until ctx is done, then withdraws the
+// report. It implements manager.Runnable.
+func (d *VersionSkewDetector) Start(ctx context.Context) error {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.report(ctx, time.Now(), true); err != nil {
+			d.Log.Error(err, "failed to report operator version", "lease", VersionLeaseName)
+		}
+
+		select {
+		case <-ctx.Done():
+			withdrawCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := d.report(withdrawCtx, time.Now(), false); err != nil {
+				d.Log.Error(err, "failed to withdraw operator version report", "lease", VersionLeaseName)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection runs the detector on every replica, including standbys.
+func (d *VersionSkewDetector) NeedLeaderElection() bool {
+	return false
+}
+
+// active reports whether this replica currently reconciles.
+func (d *VersionSkewDetector) active() bool {
+	if d.Elected == nil {
+		return true
+	}
+	select {
+	case <-d.Elected:
+		return true
+	default:
+		return false
+	}
+}
+
+// report writes this replica's report to the Lease (or removes it when present is false),
+// prunes stale reports and exports the versions of the remaining replicas.
+func (d *VersionSkewDetector) report(ctx context.Context, now time.Time, present bool) error {
+	key := types.NamespacedName{Namespace: d.Namespace, Name: VersionLeaseName}
+	own := replicaReport{Version: d.Version, Active: d.active(), Seen: now.UTC().Truncate(time.Second)}
+	ownValue, err := json.Marshal(own)
+	if err != nil {
+		return err
+	}
+
+	var reports map[string]replicaReport
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		lease := &coordinationv1.Lease{}
+		if err := d.Reader.Get(ctx, key, lease); err != nil {
+			if !apierrors.IsNotFound(err) || !present {
+				return client.IgnoreNotFound(err)
+			}
+			lease.Name = key.Name
+			lease.Namespace = key.Namespace
+			lease.Annotations = map[string]string{replicaAnnotationPrefix + d.Identity: string(ownValue)}
+			reports = map[string]replicaReport{d.Identity: own}
+			return d.Client.Create(ctx, lease)
+		}
+
+		if lease.Annotations == nil {
+			lease.Annotations = make(map[string]string)
+		}
+		if present {
+			lease.Annotations[replicaAnnotationPrefix+d.Identity] = string(ownValue)
+		} else {
+			delete(lease.Annotations, replicaAnnotationPrefix+d.Identity)
+		}
+		reports = pruneReplicaReports(lease.Annotations, now.Add(-3*d.Interval))
+		return d.Client.Update(ctx, lease)
+	})
+	if err != nil {
+		return err
+	}
+
+	if present {
+		d.export(reports)
+	}
+	return nil
+}
+
+// pruneReplicaReports removes unreadable and stale reports from the Lease annotations and
+// returns the remaining ones by replica.
+func pruneReplicaReports(annotations map[string]string, staleBefore time.Time) map[string]replicaReport {
+	reports := make(map[string]replicaReport)
+	for name, value := range annotations {
+		identity, ok := strings.CutPrefix(name, replicaAnnotationPrefix)
+		if !ok {
+			continue
+		}
+		var report replicaReport
+		if err := json.Unmarshal([]byte(value), &report); err != nil || report.Seen.Before(staleBefore) {
+			delete(annotations, name)
+			continue
+		}
+		reports[identity] = report
+	}
+	return reports
+}
+
+// export updates the replica version metrics and warns about mixed active versions.
+func (d *VersionSkewDetector) export(reports map[string]replicaReport) {
+	replicas := make(map[string]int)
+	activeVersions := make(map[string][]string)
+	for identity, report := range reports {
+		replicas[report.Version]++
+		if report.Active {
+			activeVersions[report.Version] = append(activeVersions[report.Version], identity)
+		}
+	}
+
+	metrics.ReplicaVersions.Reset()
+	summary := make([]string, 0, len(replicas))
+	for version, count := range replicas {
+		metrics.ReplicaVersions.WithLabelValues(version).Set(float64(count))
+		summary = append(summary, fmt.Sprintf("%s=%d", version, count))
+	}
+	sort.Strings(summary)
+	if joined := strings.Join(summary, ","); joined != d.lastVersions {
+		d.lastVersions = joined
+		d.Log.Info("operator replica versions changed", "replicas_by_version", summary)
+	}
+
+	if len(activeVersions) <= 1 {
+		metrics.VersionSkew.Set(0)
+		return
+	}
+	metrics.VersionSkew.Set(1)
+
+	versions := make([]string, 0, len(activeVersions))
+	for version, identities := range activeVersions {
+		sort.Strings(identities)
+		versions = append(versions, version+"="+strings.Join(identities, "+"))
+	}
+	sort.Strings(versions)
+	d.Log.Error(nil, "operator replicas running different versions are reconciling at the same time, "+
+		"which can cause alternating Vault writes; enable leader election or finish the rollout",
+		"versions", versions)
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+func TestVersionSkewDetector(t *testing.T) {
+	ctx := context.Background()
+	k8sClient := fake.NewClientBuilder().Build()
+	now := time.Now()
+
+	newDetector := func(identity, version string, elected <-chan struct{}) *VersionSkewDetector {
+		return &VersionSkewDetector{
+			Client:    k8sClient,
+			Reader:    k8sClient,
+			Namespace: "vault-sync-operator-system",
+			Identity:  identity,
+			Version:   version,
+			Interval:  30 * time.Second,
+			Elected:   elected,
+			Log:       logr.Discard(),
+		}
+	}
+
+	// A standby replica on a new version does not reconcile, so there is no skew
+	standby := make(chan struct{})
+	oldReplica := newDetector("operator-old", "1.3.0", nil)
+	newReplica := newDetector("operator-new", "1.4.0", standby)
+	for _, d := range []*VersionSkewDetector{oldReplica, newReplica} {
+		if err := d.report(ctx, now, true); err != nil {
+			t.Fatalf("report() error = %v", err)
+		}
+	}
+	if value := testutil.ToFloat64(metrics.VersionSkew); value != 0 {
+		t.Errorf("version skew with a standby replica = %v, expected 0", value)
+	}
+	if value := testutil.ToFloat64(metrics.ReplicaVersions.WithLabelValues("1.4.0")); value != 1 {
+		t.Errorf("replicas on 1.4.0 = %v, expected 1", value)
+	}
+
+	// Both replicas reconciling on different versions is skew
+	close(standby)
+	if err := newReplica.report(ctx, now, true); err != nil {
+		t.Fatalf("report() error = %v", err)
+	}
+	if value := testutil.ToFloat64(metrics.VersionSkew); value != 1 {
+		t.Errorf("version skew with two active versions = %v, expected 1", value)
+	}
+
+	// The old replica stops reporting and is pruned once its report is stale
+	if err := newReplica.report(ctx, now.Add(2*time.Minute), true); err != nil {
+		t.Fatalf("report() error = %v", err)
+	}
+	if value := testutil.ToFloat64(metrics.VersionSkew); value != 0 {
+		t.Errorf("version skew after the old replica went away = %v, expected 0", value)
+	}
+
+	lease := &coordinationv1.Lease{}
+	key := types.NamespacedName{Namespace: "vault-sync-operator-system", Name: VersionLeaseName}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if _, ok := lease.Annotations[replicaAnnotationPrefix+"operator-old"]; ok {
+		t.Error("stale report of the old replica was not pruned")
+	}
+
+	// A replica that shuts down withdraws its report
+	if err := newReplica.report(ctx, now.Add(2*time.Minute), false); err != nil {
+		t.Fatalf("report() error = %v", err)
+	}
+	if err := k8sClient.Get(ctx, key, lease); err != nil {
+		t.Fatalf("failed to get lease: %v", err)
+	}
+	if len(lease.Annotations) != 0 {
+		t.Errorf("lease annotations after withdrawal = %v, expected none", lease.Annotations)
+	}
+}
@@ -0,0 +1,252 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements the WarmupCoordinator which paces the initial reconcile storm after startup.
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// WarmupCoordinator paces the reconciles that follow operator startup, reports progress
+// and defers periodic requeues until every annotated object has been synced once.
+// All methods are safe to call on a nil coordinator, which disables warm-up.
+type WarmupCoordinator struct {
+	// Reader lists annotated objects at startup (typically the manager's API reader)
+	Reader client.Reader
+	// Timeout ends warm-up even if some objects never complete
+	Timeout time.Duration
+	Log     logr.Logger
+	// Handles selects which annotated objects count towards warm-up by controller kind
+	// ("deployment" or "secret") and namespace. A nil function includes every object.
+	Handles func(kind, namespace string) bool
+
+	limiter *rate.Limiter
+
+	mu       sync.Mutex
+	started  bool
+	done     bool
+	total    int
+	pending  map[string]struct{}
+	early    map[string]struct{}
+	deadline time.Time
+}
+
+// NewWarmupCoordinator creates a coordinator that admits at most ratePerSecond syncs per second during warm-up.
+func NewWarmupCoordinator(reader client.Reader, ratePerSecond float64, timeout time.Duration, log logr.Logger) *WarmupCoordinator {
+	return &WarmupCoordinator{
+		Reader:  reader,
+		Timeout: timeout,
+		Log:     log,
+		limiter: rate.NewLimiter(rate.Limit(ratePerSecond), 1),
+		pending: make(map[string]struct{}),
+		early:   make(map[string]struct{}),
+	}
+}
+
+// Start lists every annotated Deployment and Secret to determine the warm-up total.
+// It implements manager.Runnable.
+func (w *WarmupCoordinator) Start(ctx context.Context) error {
+	keys, err := w.listAnnotatedObjects(ctx)
+	if err != nil {
+		w.Log.Error(err, "failed to list annotated objects, skipping warm-up")
+		w.finish("list_failed")
+		return nil
+	}
+
+	w.begin(keys, time.Now())
+	w.Log.Info("startup warm-up started", "total", len(keys), "timeout", w.Timeout)
+
+	// Enforce the timeout so warm-up cannot hold requeues back forever
+	select {
+	case <-ctx.Done():
+	case <-time.After(w.Timeout):
+		w.finish("timeout")
+	}
+	return nil
+}
+
+// NeedLeaderElection ensures warm-up only runs on the replica that reconciles.
+func (w *WarmupCoordinator) NeedLeaderElection() bool {
+	return true
+}
+
+// listAnnotatedObjects returns keys for every Deployment and Secret carrying the path annotation.
+func (w *WarmupCoordinator) listAnnotatedObjects(ctx context.Context) ([]string, error) {
+	var keys []string
+
+	deployments := &appsv1.DeploymentList{}
+	if err := w.Reader.List(ctx, deployments); err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		if w.includes("deployment", &deployments.Items[i]) {
+			keys = append(keys, WarmupKey("deployment", &deployments.Items[i]))
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := w.Reader.List(ctx, secrets); err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for i := range secrets.Items {
+		if w.includes("secret", &secrets.Items[i]) {
+			keys = append(keys, WarmupKey("secret", &secrets.Items[i]))
+		}
+	}
+
+	return keys, nil
+}
+
+// includes reports whether obj is annotated for sync and handled by a running controller.
+func (w *WarmupCoordinator) includes(kind string, obj client.Object) bool {
+	if obj.GetAnnotations()[VaultPathAnnotation] == "" {
+		return false
+	}
+	return w.Handles == nil || w.Handles(kind, obj.GetNamespace())
+}
+
+// begin records the set of objects that must sync before warm-up completes.
+func (w *WarmupCoordinator) begin(keys []string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.started = true
+	w.total = len(keys)
+	w.deadline = now.Add(w.Timeout)
+	for _, key := range keys {
+		if _, synced := w.early[key]; !synced {
+			w.pending[key] = struct{}{}
+		}
+	}
+	w.early = nil
+	w.reportLocked()
+}
+
+// WarmupKey builds the key used to track an object during warm-up.
+func WarmupKey(kind string, obj client.Object) string {
+	return fmt.Sprintf("%s/%s/%s", kind, obj.GetNamespace(), obj.GetName())
+}
+
+// Active reports whether warm-up is still in progress.
+func (w *WarmupCoordinator) Active() bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.done
+}
+
+// Wait blocks until the warm-up pacer admits another sync. It returns immediately once warm-up is complete.
+func (w *WarmupCoordinator) Wait(ctx context.Context) error {
+	if !w.Active() {
+		return nil
+	}
+	return w.limiter.Wait(ctx)
+}
+
+// MarkSynced records that the object identified by key has completed its first sync.
+func (w *WarmupCoordinator) MarkSynced(key string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return
+	}
+	if !w.started {
+		w.early[key] = struct{}{}
+		return
+	}
+	if _, exists := w.pending[key]; !exists {
+		return
+	}
+	delete(w.pending, key)
+	w.reportLocked()
+}
+
+// Synced reports whether the object identified by key has completed its first sync.
+func (w *WarmupCoordinator) Synced(key string) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.started {
+		_, synced := w.early[key]
+		return synced
+	}
+	_, pending := w.pending[key]
+	return !pending
+}
+
+// DeferRequeue extends a periodic requeue interval by the estimated time left in warm-up,
+// so periodic reconciles do not compete with objects that have not synced yet.
+func (w *WarmupCoordinator) DeferRequeue(interval time.Duration) time.Duration {
+	if w == nil || interval <= 0 {
+		return interval
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return interval
+	}
+	remaining := time.Duration(float64(len(w.pending)) / float64(w.limiter.Limit()) * float64(time.Second))
+	if w.started {
+		if untilDeadline := time.Until(w.deadline); untilDeadline < remaining {
+			remaining = untilDeadline
+		}
+	}
+	return interval + remaining
+}
+
+// finish marks warm-up complete.
+func (w *WarmupCoordinator) finish(reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return
+	}
+	w.done = true
+	metrics.WarmupInProgress.Set(0)
+	w.Log.Info("startup warm-up finished",
+		"reason", reason,
+		"synced", w.total-len(w.pending),
+		"total", w.total)
+}
+
+// reportLocked publishes progress and completes warm-up when nothing is pending. Callers must hold mu.
+func (w *WarmupCoordinator) reportLocked() {
+	synced := w.total - len(w.pending)
+	metrics.WarmupObjects.WithLabelValues("total").Set(float64(w.total))
+	metrics.WarmupObjects.WithLabelValues("synced").Set(float64(synced))
+
+	if len(w.pending) == 0 {
+		w.done = true
+		metrics.WarmupInProgress.Set(0)
+		w.Log.Info("startup warm-up complete", "synced", synced, "total", w.total)
+		return
+	}
+
+	metrics.WarmupInProgress.Set(1)
+	// Log progress roughly every 10% to keep output readable on large clusters
+	step := w.total / 10
+	if step == 0 || synced%step == 0 {
+		w.Log.Info("startup warm-up progress", "synced", synced, "total", w.total)
+	}
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// TestWarmupCoordinatorProgress tests warm-up progress tracking and completion.
+func TestWarmupCoordinatorProgress(t *testing.T) {
+	w := NewWarmupCoordinator(nil, 10, time.Minute, ctrl.Log.WithName("test"))
+
+	// Objects synced before the total is known still count towards progress
+	w.MarkSynced("deployment/default/app-a")
+
+	w.begin([]string{"deployment/default/app-a", "deployment/default/app-b", "secret/default/db"}, time.Now())
+	if !w.Active() {
+		t.Fatalf("Expected warm-up to be active with pending objects")
+	}
+
+	if deferred := w.DeferRequeue(time.Minute); deferred <= time.Minute {
+		t.Errorf("Expected requeue to be deferred during warm-up, got %v", deferred)
+	}
+
+	w.MarkSynced("deployment/default/app-b")
+	w.MarkSynced("secret/default/unknown")
+	if !w.Active() {
+		t.Fatalf("Expected warm-up to remain active until all objects are synced")
+	}
+
+	w.MarkSynced("secret/default/db")
+	if w.Active() {
+		t.Errorf("Expected warm-up to complete once all objects are synced")
+	}
+	if deferred := w.DeferRequeue(time.Minute); deferred != time.Minute {
+		t.Errorf("Expected requeue to be unchanged after warm-up, got %v", deferred)
+	}
+}
+
+// TestWarmupCoordinatorNil tests that a nil coordinator disables warm-up.
+func TestWarmupCoordinatorNil(t *testing.T) {
+	var w *WarmupCoordinator
+
+	if w.Active() {
+		t.Errorf("Expected nil coordinator to be inactive")
+	}
+	w.MarkSynced("deployment/default/app")
+	if deferred := w.DeferRequeue(time.Minute); deferred != time.Minute {
+		t.Errorf("Expected nil coordinator not to defer requeues, got %v", deferred)
+	}
+}
@@ -0,0 +1,134 @@
+// Package controller contains the Kubernetes controller logic for the vault-sync-operator.
+// This file implements support for Deployment-like workload kinds read as unstructured objects.
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultPodTemplatePath is the field path of the pod template in Deployment-like kinds such
+// as Argo Rollouts and OpenShift DeploymentConfigs.
+var DefaultPodTemplatePath = []string{"spec", "template"}
+
+// apiVersionPattern matches Kubernetes API versions such as v1 or v1alpha1.
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+((alpha|beta)[0-9]+)?$`)
+
+// WorkloadKind is a Deployment-like kind synced like Deployments: its pod template is used
+// for secret auto-discovery and its vault-sync.io annotations configure the sync.
+type WorkloadKind struct {
+	schema.GroupVersionKind
+	// TemplatePath is the field path of the pod template (DefaultPodTemplatePath when empty)
+	TemplatePath []string
+}
+
+// ParseWorkloadKinds parses a comma-separated list of kinds in kubectl's Kind.version.group
+// form, e.g. "Rollout.v1alpha1.argoproj.io,DeploymentConfig.v1.apps.openshift.io". A kind may
+// end with =<path> to name a pod template path other than spec.template, e.g.
+// "CronJob.v1.batch=spec.jobTemplate.spec.template".
+func ParseWorkloadKinds(value string) ([]WorkloadKind, error) {
+	var kinds []WorkloadKind
+	seen := make(map[schema.GroupVersionKind]bool)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kindArg, path, hasPath := strings.Cut(entry, "=")
+		gvk, _ := schema.ParseKindArg(strings.TrimSpace(kindArg))
+		if gvk == nil || gvk.Kind == "" || gvk.Group == "" || !apiVersionPattern.MatchString(gvk.Version) {
+			return nil, fmt.Errorf("invalid workload kind %q, expected Kind.version.group", entry)
+		}
+		if *gvk == appsv1.SchemeGroupVersion.WithKind("Deployment") {
+			return nil, fmt.Errorf("workload kind %q is synced by the Deployment controller", entry)
+		}
+		if seen[*gvk] {
+			return nil, fmt.Errorf("duplicate workload kind %q", entry)
+		}
+		seen[*gvk] = true
+
+		kind := WorkloadKind{GroupVersionKind: *gvk}
+		if hasPath {
+			for _, field := range strings.Split(strings.TrimSpace(path), ".") {
+				if field == "" {
+					return nil, fmt.Errorf("invalid pod template path in workload kind %q", entry)
+				}
+				kind.TemplatePath = append(kind.TemplatePath, field)
+			}
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// String returns the kind in the form accepted by ParseWorkloadKinds.
+func (k WorkloadKind) String() string {
+	value := k.Kind + "." + k.Version + "." + k.Group
+	if len(k.TemplatePath) > 0 {
+		value += "=" + strings.Join(k.TemplatePath, ".")
+	}
+	return value
+}
+
+// newWorkload returns an empty object of the kind synced by the reconciler.
+func (r *DeploymentReconciler) newWorkload() client.Object {
+	if r.WorkloadKind == nil {
+		return &appsv1.Deployment{}
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(r.WorkloadKind.GroupVersionKind)
+	return obj
+}
+
+// kindLabel names the kind synced by the reconciler in metrics, logs and inventory keys.
+func (r *DeploymentReconciler) kindLabel() string {
+	if r.WorkloadKind == nil {
+		return "deployment"
+	}
+	return strings.ToLower(r.WorkloadKind.Kind)
+}
+
+// kindName returns the kind synced by the reconciler, as used in messages.
+func (r *DeploymentReconciler) kindName() string {
+	if r.WorkloadKind == nil {
+		return "Deployment"
+	}
+	return r.WorkloadKind.Kind
+}
+
+// podTemplate returns the pod template of a workload. A workload without a pod template,
+// such as a Rollout that references a Deployment with workloadRef, has an empty template.
+func (r *DeploymentReconciler) podTemplate(obj client.Object) (corev1.PodTemplateSpec, error) {
+	var template corev1.PodTemplateSpec
+	switch workload := obj.(type) {
+	case *appsv1.Deployment:
+		return workload.Spec.Template, nil
+	case *unstructured.Unstructured:
+		path := DefaultPodTemplatePath
+		if r.WorkloadKind != nil && len(r.WorkloadKind.TemplatePath) > 0 {
+			path = r.WorkloadKind.TemplatePath
+		}
+		fields, found, err := unstructured.NestedMap(workload.Object, path...)
+		if err != nil {
+			return template, fmt.Errorf("invalid pod template at %s: %w", strings.Join(path, "."), err)
+		}
+		if !found {
+			return template, nil
+		}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, &template); err != nil {
+			return template, fmt.Errorf("invalid pod template at %s: %w", strings.Join(path, "."), err)
+		}
+		return template, nil
+	default:
+		return template, fmt.Errorf("unsupported workload type %T", obj)
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var rolloutKind = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}
+
+func TestParseWorkloadKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []WorkloadKind
+		wantErr  bool
+	}{
+		{name: "empty", value: "", expected: nil},
+		{
+			name:  "rollouts and deployment configs",
+			value: "Rollout.v1alpha1.argoproj.io, DeploymentConfig.v1.apps.openshift.io",
+			expected: []WorkloadKind{
+				{GroupVersionKind: rolloutKind},
+				{GroupVersionKind: schema.GroupVersionKind{Group: "apps.openshift.io", Version: "v1", Kind: "DeploymentConfig"}},
+			},
+		},
+		{
+			name:  "custom pod template path",
+			value: "CronJob.v1.batch=spec.jobTemplate.spec.template",
+			expected: []WorkloadKind{{
+				GroupVersionKind: schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"},
+				TemplatePath:     []string{"spec", "jobTemplate", "spec", "template"},
+			}},
+		},
+		{name: "missing version", value: "Rollout.argoproj.io", wantErr: true},
+		{name: "core kind", value: "Pod.v1", wantErr: true},
+		{name: "deployments", value: "Deployment.v1.apps", wantErr: true},
+		{name: "duplicate", value: "Rollout.v1alpha1.argoproj.io,Rollout.v1alpha1.argoproj.io", wantErr: true},
+		{name: "empty path field", value: "Rollout.v1alpha1.argoproj.io=spec..template", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kinds, err := ParseWorkloadKinds(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWorkloadKinds() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(kinds, tt.expected) {
+				t.Errorf("ParseWorkloadKinds() = %v, expected %v", kinds, tt.expected)
+			}
+		})
+	}
+}
+
+// newTestRollout returns an Argo Rollout whose pod template references the given Secret.
+func newTestRollout(secretName string) *unstructured.Unstructured {
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"name":    "app",
+						"envFrom": []interface{}{map[string]interface{}{"secretRef": map[string]interface{}{"name": secretName}}},
+					}},
+				},
+			},
+		},
+	}}
+	rollout.SetGroupVersionKind(rolloutKind)
+	rollout.SetName("web")
+	rollout.SetNamespace("default")
+	rollout.SetAnnotations(map[string]string{VaultPathAnnotation: "secret/data/web"})
+	return rollout
+}
+
+func TestWorkloadPodTemplate(t *testing.T) {
+	r := &DeploymentReconciler{WorkloadKind: &WorkloadKind{GroupVersionKind: rolloutKind}}
+
+	template, err := r.podTemplate(newTestRollout("web-credentials"))
+	if err != nil {
+		t.Fatalf("podTemplate() error = %v", err)
+	}
+	if secretNames := r.extractSecretNamesFromPodTemplate(template, nil); !secretNames["web-credentials"] {
+		t.Errorf("secrets discovered from the rollout = %v, expected web-credentials", secretNames)
+	}
+
+	// A rollout referencing a Deployment with workloadRef has no pod template
+	withoutTemplate := newTestRollout("web-credentials")
+	unstructured.RemoveNestedField(withoutTemplate.Object, "spec", "template")
+	if template, err := r.podTemplate(withoutTemplate); err != nil || len(template.Spec.Containers) != 0 {
+		t.Errorf("podTemplate() without a template = %v, %v, expected an empty template", template, err)
+	}
+}
+
+// TestWorkloadReconcile tests that a Rollout is synced like a Deployment.
+func TestWorkloadReconcile(t *testing.T) {
+	ctx := context.Background()
+
+	secret := &corev1.Secret{Data: map[string][]byte{"password": []byte("s3cret")}}
+	secret.Name = "web-credentials"
+	secret.Namespace = "default"
+	rollout := newTestRollout(secret.Name)
+
+	k8sClient := fake.NewClientBuilder().WithObjects(secret, rollout).Build()
+	vaultClient := &fakeVault{}
+	r := &DeploymentReconciler{
+		Client:       k8sClient,
+		Scheme:       runtime.NewScheme(),
+		Log:          logr.Discard(),
+		VaultClient:  vaultClient,
+		WorkloadKind: &WorkloadKind{GroupVersionKind: rolloutKind},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(rollout)}
+
+	reconcileUntilSynced(t, r, req)
+	if got := vaultClient.secrets["secret/data/web/web-credentials"]["password"]; got != "s3cret" {
+		t.Fatalf("password in vault = %v, expected s3cret", got)
+	}
+
+	synced := newTestRollout(secret.Name)
+	if err := k8sClient.Get(ctx, req.NamespacedName, synced); err != nil {
+		t.Fatalf("failed to get rollout: %v", err)
+	}
+	if synced.GetAnnotations()[VaultSecretVersionsAnnotation] == "" {
+		t.Error("secret versions annotation was not set on the rollout")
+	}
+	if !reflect.DeepEqual(synced.GetFinalizers(), []string{VaultSyncFinalizer}) {
+		t.Errorf("rollout finalizers = %v, expected %s", synced.GetFinalizers(), VaultSyncFinalizer)
+	}
+}
@@ -0,0 +1,182 @@
+// Package features implements feature gates that let risky operator behaviors be
+// enabled per cluster with --feature-gates, following the Kubernetes feature gate pattern.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature is the name of a feature gate.
+type Feature string
+
+// Feature gates known to the operator.
+const (
+	// ScheduledRotationChecks schedules version comparisons from a vault-sync.io/rotation-check frequency.
+	ScheduledRotationChecks Feature = "ScheduledRotationChecks"
+	// SyncHistory records recent sync operations in the vault-sync-history ConfigMap.
+	SyncHistory Feature = "SyncHistory"
+	// CertManagerReadiness defers syncing auto-discovered Secrets until their cert-manager
+	// Certificate is ready.
+	CertManagerReadiness Feature = "CertManagerReadiness"
+	// SecretChangeRequeue syncs the workloads referencing a Secret as soon as its data changes.
+	SecretChangeRequeue Feature = "SecretChangeRequeue"
+)
+
+// Stage is the maturity of a feature.
+type Stage string
+
+// Feature maturity stages.
+const (
+	Alpha Stage = "ALPHA"
+	Beta  Stage = "BETA"
+	GA    Stage = ""
+)
+
+// FeatureSpec describes the default and maturity of a feature.
+type FeatureSpec struct {
+	Default    bool
+	PreRelease Stage
+	// LockToDefault prevents the feature from being changed (typically once GA)
+	LockToDefault bool
+}
+
+// defaultFeatures lists every known feature with its default.
+var defaultFeatures = map[Feature]FeatureSpec{
+	ScheduledRotationChecks: {Default: true, PreRelease: Beta},
+	SyncHistory:             {Default: true, PreRelease: Beta},
+	CertManagerReadiness:    {Default: true, PreRelease: Beta},
+	SecretChangeRequeue:     {Default: false, PreRelease: Alpha},
+}
+
+// DefaultFeatureGate is the operator-wide feature gate, configured from --feature-gates.
+var DefaultFeatureGate = NewFeatureGate(defaultFeatures)
+
+// Enabled reports whether feature is enabled in the DefaultFeatureGate.
+func Enabled(feature Feature) bool {
+	return DefaultFeatureGate.Enabled(feature)
+}
+
+// FeatureGate holds the enabled state of a set of known features.
+// It implements flag.Value so it can be bound directly to a command-line flag.
+type FeatureGate struct {
+	known map[Feature]FeatureSpec
+
+	mu      sync.RWMutex
+	enabled map[Feature]bool
+}
+
+// NewFeatureGate creates a gate for the given known features, each at its default.
+func NewFeatureGate(known map[Feature]FeatureSpec) *FeatureGate {
+	return &FeatureGate{
+		known:   known,
+		enabled: make(map[Feature]bool),
+	}
+}
+
+// Set parses a comma-separated list of Feature=bool pairs, e.g. "SyncHistory=false,ScheduledRotationChecks=true".
+func (g *FeatureGate) Set(value string) error {
+	overrides := make(map[Feature]bool)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		name, raw, found := strings.Cut(pair, "=")
+		if !found {
+			return fmt.Errorf("missing bool value for feature gate %s", name)
+		}
+		feature := Feature(strings.TrimSpace(name))
+		spec, known := g.known[feature]
+		if !known {
+			return fmt.Errorf("unrecognized feature gate: %s", feature)
+		}
+
+		enabled, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid value %q for feature gate %s: %w", raw, feature, err)
+		}
+		if spec.LockToDefault && enabled != spec.Default {
+			return fmt.Errorf("feature gate %s is locked to %v", feature, spec.Default)
+		}
+		overrides[feature] = enabled
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for feature, enabled := range overrides {
+		g.enabled[feature] = enabled
+	}
+	return nil
+}
+
+// SetFromMap sets the feature gates from a map of feature names to their enabled state,
+// with the same validation as Set.
+func (g *FeatureGate) SetFromMap(gates map[string]bool) error {
+	pairs := make([]string, 0, len(gates))
+	for name, enabled := range gates {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", name, enabled))
+	}
+	return g.Set(strings.Join(pairs, ","))
+}
+
+// Validate checks that gates only sets known features to allowed values, without changing
+// the DefaultFeatureGate.
+func Validate(gates map[string]bool) error {
+	return NewFeatureGate(defaultFeatures).SetFromMap(gates)
+}
+
+// String returns the explicitly set feature gates in Set format.
+func (g *FeatureGate) String() string {
+	if g == nil {
+		return ""
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	pairs := make([]string, 0, len(g.enabled))
+	for feature, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, enabled))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Enabled reports whether feature is enabled. Unknown features are disabled.
+func (g *FeatureGate) Enabled(feature Feature) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if enabled, set := g.enabled[feature]; set {
+		return enabled
+	}
+	return g.known[feature].Default
+}
+
+// KnownFeatures returns a sorted description of every known feature for flag usage text.
+func (g *FeatureGate) KnownFeatures() []string {
+	descriptions := make([]string, 0, len(g.known))
+	for feature, spec := range g.known {
+		stage := "GA"
+		if spec.PreRelease != GA {
+			stage = string(spec.PreRelease)
+		}
+		descriptions = append(descriptions, fmt.Sprintf("%s=true|false (%s - default=%t)", feature, stage, spec.Default))
+	}
+	sort.Strings(descriptions)
+	return descriptions
+}
+
+// EnabledFeatures returns the state of every known feature, keyed by name.
+func (g *FeatureGate) EnabledFeatures() map[string]bool {
+	states := make(map[string]bool, len(g.known))
+	for feature := range g.known {
+		states[string(feature)] = g.Enabled(feature)
+	}
+	return states
+}
@@ -0,0 +1,103 @@
+package features
+
+import (
+	"testing"
+)
+
+func testFeatureGate() *FeatureGate {
+	return NewFeatureGate(map[Feature]FeatureSpec{
+		"AlphaFeature":  {Default: false, PreRelease: Alpha},
+		"BetaFeature":   {Default: true, PreRelease: Beta},
+		"LockedFeature": {Default: true, PreRelease: GA, LockToDefault: true},
+	})
+}
+
+func TestFeatureGateDefaults(t *testing.T) {
+	gate := testFeatureGate()
+
+	if gate.Enabled("AlphaFeature") {
+		t.Errorf("Expected alpha feature to be disabled by default")
+	}
+	if !gate.Enabled("BetaFeature") {
+		t.Errorf("Expected beta feature to be enabled by default")
+	}
+	if gate.Enabled("UnknownFeature") {
+		t.Errorf("Expected unknown feature to be disabled")
+	}
+}
+
+func TestFeatureGateSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+		alpha   bool
+		beta    bool
+	}{
+		{name: "empty", value: "", alpha: false, beta: true},
+		{name: "enable alpha", value: "AlphaFeature=true", alpha: true, beta: true},
+		{name: "multiple", value: "AlphaFeature=true, BetaFeature=false", alpha: true, beta: false},
+		{name: "unknown feature", value: "UnknownFeature=true", wantErr: true},
+		{name: "missing value", value: "AlphaFeature", wantErr: true},
+		{name: "invalid value", value: "AlphaFeature=maybe", wantErr: true},
+		{name: "locked feature", value: "LockedFeature=false", wantErr: true},
+		{name: "locked feature at default", value: "LockedFeature=true", alpha: false, beta: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := testFeatureGate()
+			err := gate.Set(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gate.Enabled("AlphaFeature") != tt.alpha {
+				t.Errorf("AlphaFeature enabled = %v, expected %v", gate.Enabled("AlphaFeature"), tt.alpha)
+			}
+			if gate.Enabled("BetaFeature") != tt.beta {
+				t.Errorf("BetaFeature enabled = %v, expected %v", gate.Enabled("BetaFeature"), tt.beta)
+			}
+		})
+	}
+}
+
+func TestFeatureGateSetInvalidLeavesStateUnchanged(t *testing.T) {
+	gate := testFeatureGate()
+
+	if err := gate.Set("AlphaFeature=true,UnknownFeature=true"); err == nil {
+		t.Fatalf("Expected error for unknown feature")
+	}
+	if gate.Enabled("AlphaFeature") {
+		t.Errorf("Expected a failed Set not to apply any overrides")
+	}
+}
+
+func TestFeatureGateString(t *testing.T) {
+	gate := testFeatureGate()
+	if err := gate.Set("BetaFeature=false,AlphaFeature=true"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := gate.String(); got != "AlphaFeature=true,BetaFeature=false" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestFeatureGateSetFromMap(t *testing.T) {
+	gate := testFeatureGate()
+	if err := gate.SetFromMap(map[string]bool{"AlphaFeature": true, "BetaFeature": false}); err != nil {
+		t.Fatalf("SetFromMap() error = %v", err)
+	}
+	if !gate.Enabled("AlphaFeature") || gate.Enabled("BetaFeature") {
+		t.Errorf("Expected the map to enable AlphaFeature and disable BetaFeature")
+	}
+
+	if err := gate.SetFromMap(map[string]bool{"LockedFeature": false}); err == nil {
+		t.Errorf("Expected error for a locked feature")
+	}
+	if err := Validate(map[string]bool{"UnknownFeature": true}); err == nil {
+		t.Errorf("Expected error for unknown feature")
+	}
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// PathLabelStrategy controls the value of path labels, which with the full path create one
+// series per Vault path and explode cardinality on clusters with thousands of paths.
+type PathLabelStrategy string
+
+// Path label strategies.
+const (
+	PathLabelFull     PathLabelStrategy = "full"     // The full Vault path
+	PathLabelMount    PathLabelStrategy = "mount"    // The first path segment, i.e. the secrets engine mount
+	PathLabelHashed   PathLabelStrategy = "hashed"   // PathHash of the path, matching managed_path_info
+	PathLabelDisabled PathLabelStrategy = "disabled" // Always empty, leaving one series per other label
+)
+
+// pathLabelStrategy is the strategy applied by PathLabel.
+var pathLabelStrategy atomic.Value
+
+func init() {
+	pathLabelStrategy.Store(PathLabelFull)
+}
+
+// ParsePathLabelStrategy parses a path label strategy.
+func ParsePathLabelStrategy(value string) (PathLabelStrategy, error) {
+	switch strategy := PathLabelStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case PathLabelFull, PathLabelMount, PathLabelHashed, PathLabelDisabled:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown path label strategy %q, expected full, mount, hashed or disabled", value)
+	}
+}
+
+// SetPathLabelStrategy sets the strategy applied to every path-labeled metric.
+func SetPathLabelStrategy(strategy PathLabelStrategy) {
+	pathLabelStrategy.Store(strategy)
+}
+
+// PathLabel returns the value of a path label for path under the configured strategy.
+func PathLabel(path string) string {
+	switch pathLabelStrategy.Load().(PathLabelStrategy) {
+	case PathLabelMount:
+		mount, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+		return mount
+	case PathLabelHashed:
+		return PathHash(path)
+	case PathLabelDisabled:
+		return ""
+	default:
+		return path
+	}
+}
+
+// PathHash returns a short stable hash of a Vault path, used to label per-path metrics
+// without exposing the path itself.
+func PathHash(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:8])
+}
+
+// resourceAggregation holds the namespace aggregation settings applied by ResourceLabel.
+type resourceAggregation struct {
+	detailedNamespaces map[string]bool
+}
+
+// aggregation is nil while every sync metric keeps its resource label.
+var aggregation atomic.Pointer[resourceAggregation]
+
+// SetNamespaceAggregation aggregates sync metrics per namespace by leaving their resource
+// label empty, except in detailedNamespaces, which keep per-resource series for debugging.
+func SetNamespaceAggregation(detailedNamespaces []string) {
+	settings := &resourceAggregation{detailedNamespaces: make(map[string]bool)}
+	for _, namespace := range detailedNamespaces {
+		settings.detailedNamespaces[namespace] = true
+	}
+	aggregation.Store(settings)
+}
+
+// ResourceDetailed reports whether the sync metrics of namespace keep per-resource series.
+func ResourceDetailed(namespace string) bool {
+	settings := aggregation.Load()
+	return settings == nil || settings.detailedNamespaces[namespace]
+}
+
+// ResourceLabel returns the value of the resource label of a sync metric.
+func ResourceLabel(namespace, name string) string {
+	if !ResourceDetailed(namespace) {
+		return ""
+	}
+	return name
+}
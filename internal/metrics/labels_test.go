@@ -0,0 +1,51 @@
+package metrics
+
+import "testing"
+
+func TestPathLabel(t *testing.T) {
+	defer SetPathLabelStrategy(PathLabelFull)
+
+	const path = "secret/data/clusters/prod/app"
+	tests := []struct {
+		strategy string
+		expected string
+	}{
+		{strategy: "full", expected: path},
+		{strategy: "mount", expected: "secret"},
+		{strategy: "Hashed", expected: PathHash(path)},
+		{strategy: "disabled", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			strategy, err := ParsePathLabelStrategy(tt.strategy)
+			if err != nil {
+				t.Fatalf("ParsePathLabelStrategy() error = %v", err)
+			}
+			SetPathLabelStrategy(strategy)
+			if label := PathLabel(path); label != tt.expected {
+				t.Errorf("PathLabel() = %q, expected %q", label, tt.expected)
+			}
+		})
+	}
+
+	if _, err := ParsePathLabelStrategy("namespace"); err == nil {
+		t.Error("ParsePathLabelStrategy() accepted an unknown strategy")
+	}
+}
+
+func TestResourceLabel(t *testing.T) {
+	defer aggregation.Store(nil)
+
+	if label := ResourceLabel("payments", "api"); label != "api" {
+		t.Errorf("ResourceLabel() without aggregation = %q, expected api", label)
+	}
+
+	SetNamespaceAggregation([]string{"debugging"})
+	if label := ResourceLabel("payments", "api"); label != "" || ResourceDetailed("payments") {
+		t.Errorf("ResourceLabel() with aggregation = %q, expected an empty label", label)
+	}
+	if label := ResourceLabel("debugging", "api"); label != "api" || !ResourceDetailed("debugging") {
+		t.Errorf("ResourceLabel() in a detailed namespace = %q, expected api", label)
+	}
+}
@@ -41,6 +41,32 @@ var (
 		[]string{"result"},
 	)
 
+	// VaultReauthentications tracks Vault logins by what triggered them.
+	VaultReauthentications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_reauthentications_total",
+			Help: "Total number of Vault logins by trigger (startup, expiry, 403)",
+		},
+		[]string{"trigger"},
+	)
+
+	// TokenHandoffs tracks the tokens of previous leaders handled by a new leader, by result.
+	TokenHandoffs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_token_handoffs_total",
+			Help: "Vault tokens of a previous leader handled after a leader change (labeled by result: revoked, expired, foreign, failed)",
+		},
+		[]string{"result"},
+	)
+
+	// VaultTokenTTL reports the remaining lifetime of the operator's Vault token.
+	VaultTokenTTL = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_token_ttl_seconds",
+			Help: "Remaining lifetime of the operator's Vault token in seconds (0 for tokens that never expire)",
+		},
+	)
+
 	// SecretsDiscovered tracks the number of auto-discovered secrets.
 	// BREAKING CHANGE (v0.2.0): label changed from "deployment" to "resource" to support both
 	// deployment-based and secret-level sync.
@@ -52,7 +78,7 @@ var (
 		[]string{"namespace", "resource"},
 	)
 
-	// VaultWriteErrors tracks Vault write errors by type.
+	// VaultWriteErrors tracks Vault write errors by type. The path label follows the PathLabelStrategy.
 	VaultWriteErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "vault_sync_operator_vault_write_errors_total",
@@ -79,6 +105,15 @@ var (
 		[]string{"namespace", "secret_name", "key"},
 	)
 
+	// ValueValidationFailures tracks secret values refused by a validation rule of vault-sync.io/secrets.
+	ValueValidationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_value_validation_failures_total",
+			Help: "Total number of secret values that failed their validation rule and were not synced",
+		},
+		[]string{"namespace", "secret_name", "key"},
+	)
+
 	// ConfigParseErrors tracks configuration parsing errors.
 	ConfigParseErrors = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -88,6 +123,321 @@ var (
 		[]string{"namespace", "resource", "error_type"},
 	)
 
+	// SharedSecretReferences tracks how many Deployments reference a Secret synced in shared-secret mode.
+	SharedSecretReferences = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_shared_secret_references",
+			Help: "Number of Deployments referencing a Secret written to its canonical shared path",
+		},
+		[]string{"namespace", "secret_name"},
+	)
+
+	// VaultRequestQueueDepth tracks the number of Vault requests waiting on the client rate limiter.
+	VaultRequestQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_vault_request_queue_depth",
+			Help: "Number of Vault requests currently waiting on the rate limiter",
+		},
+	)
+
+	// VaultRateLimit tracks the effective rate of the Vault client rate limiter.
+	VaultRateLimit = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_vault_rate_limit",
+			Help: "Effective Vault request rate limit in requests per second, lowered while Vault throttles requests",
+		},
+	)
+
+	// ClusterIdentityConflicts tracks checks that found another cluster writing with the same cluster name.
+	ClusterIdentityConflicts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_cluster_identity_conflicts_total",
+			Help: "Total number of checks that found another cluster writing to Vault with the same cluster name",
+		},
+	)
+
+	// VaultReplicaFailovers tracks requests sent to the primary because the performance replica failed.
+	VaultReplicaFailovers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_vault_replica_failovers_total",
+			Help: "Total number of Vault performance replica failures that sent requests to the primary",
+		},
+		[]string{"operation"},
+	)
+
+	// VaultAddressFailovers tracks switches to another of several configured Vault addresses.
+	VaultAddressFailovers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_vault_address_failovers_total",
+			Help: "Total number of switches to another Vault address after the current one did not respond",
+		},
+		[]string{"trigger"},
+	)
+
+	// VaultThrottledResponses tracks responses in which Vault asked requests to back off.
+	VaultThrottledResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_vault_throttled_responses_total",
+			Help: "Total number of Vault responses asking requests to back off (429, or 503 with Retry-After)",
+		},
+		[]string{"status"},
+	)
+
+	// BackpressureRequeues tracks reconciles deferred because the Vault rate limiter was saturated
+	// or Vault throttled requests.
+	BackpressureRequeues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_backpressure_requeues_total",
+			Help: "Total number of reconciles requeued due to Vault rate limiter saturation or throttling",
+		},
+		[]string{"controller"},
+	)
+
+	// VaultState reports the last observed Vault server state (1 for the current state, 0 otherwise).
+	VaultState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_vault_state",
+			Help: "Last observed Vault server state (active, standby, sealed, uninitialized, down)",
+		},
+		[]string{"state"},
+	)
+
+	// SyncsHeldWhileSealed tracks reconciles that were held because Vault was sealed.
+	SyncsHeldWhileSealed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_syncs_held_sealed_total",
+			Help: "Total number of reconciles held because Vault was sealed",
+		},
+		[]string{"controller"},
+	)
+
+	// PathLockWaitDuration tracks how long syncs wait for exclusive access to a Vault path.
+	PathLockWaitDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "vault_sync_operator_path_lock_wait_seconds",
+			Help:    "Time spent waiting for exclusive access to a Vault path",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	// WarmupInProgress reports whether the startup warm-up is still running (1) or complete (0).
+	WarmupInProgress = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_warmup_in_progress",
+			Help: "Whether the startup warm-up reconcile is in progress",
+		},
+	)
+
+	// WarmupObjects tracks startup warm-up progress by state ("total" or "synced").
+	WarmupObjects = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_warmup_objects",
+			Help: "Number of annotated objects in the startup warm-up by state",
+		},
+		[]string{"state"},
+	)
+
+	// AgentInjectorConflicts reports Deployments whose Vault agent injection reads a path the operator writes (1) or not (0).
+	AgentInjectorConflicts = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_agent_injector_conflict",
+			Help: "Whether a Deployment's Vault agent injection reads a path written by the operator",
+		},
+		[]string{"namespace", "resource"},
+	)
+
+	// ExternalSecretConflicts tracks syncs of Secrets managed by the External Secrets Operator.
+	ExternalSecretConflicts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_external_secret_conflicts_total",
+			Help: "Total number of conflicts detected with Secrets managed by the External Secrets Operator, counted when each conflict starts",
+		},
+		[]string{"namespace", "secret_name"},
+	)
+
+	// PropagationDelay tracks the delay between a Secret data change being observed and its successful write to Vault.
+	PropagationDelay = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "vault_sync_operator_propagation_delay_seconds",
+			Help:    "Delay between a Secret data change watch event and the successful Vault write",
+			Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600},
+		},
+	)
+
+	// SyncSkipped tracks reconciles and events that did not result in a Vault write, by reason.
+	SyncSkipped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_sync_skipped_total",
+			Help: "Total number of syncs skipped without writing to Vault",
+		},
+		[]string{"reason"}, // no_change, namespace_filtered, rollout_in_progress, certificate_not_ready, degraded
+	)
+
+	// ReplicaVersions tracks the number of running operator replicas per version.
+	ReplicaVersions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_replica_versions",
+			Help: "Number of running operator replicas per version",
+		},
+		[]string{"version"},
+	)
+
+	// VersionSkew is 1 while replicas running different versions reconcile at the same time.
+	VersionSkew = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_version_skew",
+			Help: "Whether operator replicas running different versions are reconciling at the same time (1) or not (0)",
+		},
+	)
+
+	// HeartbeatWrites tracks synthetic heartbeat writes to Vault by result.
+	HeartbeatWrites = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_heartbeat_writes_total",
+			Help: "Synthetic heartbeat writes to Vault (labeled by result: success, error)",
+		},
+		[]string{"result"},
+	)
+
+	// HeartbeatDuration tracks the latency of synthetic heartbeat writes.
+	HeartbeatDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "vault_sync_operator_heartbeat_duration_seconds",
+			Help:    "Latency of synthetic heartbeat writes to Vault in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	// HeartbeatLastSuccess is the Unix time of the last successful heartbeat write.
+	HeartbeatLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_heartbeat_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful synthetic heartbeat write to Vault",
+		},
+	)
+
+	// PolicyEvaluations tracks policy hook evaluations before Vault writes.
+	PolicyEvaluations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_policy_evaluations_total",
+			Help: "Policy hook evaluations before Vault writes (labeled by result: allowed, denied, error)",
+		},
+		[]string{"result"},
+	)
+
+	// ManagedPaths tracks the number of distinct Vault paths managed by the operator.
+	ManagedPaths = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_managed_paths",
+			Help: "Number of distinct Vault paths managed by the operator",
+		},
+	)
+
+	// PathMoves tracks Vault path moves after a change of vault-sync.io/path, by result.
+	PathMoves = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_path_moves_total",
+			Help: "Total number of moves of synced secrets to a changed Vault path",
+		},
+		[]string{"result"}, // moved, preserved, failed
+	)
+
+	// ManagedPathInfo is set to 1 for each managed Vault path, labeled by a hash of the path.
+	ManagedPathInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_managed_path_info",
+			Help: "Managed Vault paths, labeled by a hash of the path (1 while managed)",
+		},
+		[]string{"path_hash"},
+	)
+
+	// EventsSuppressed tracks warning events dropped by the event aggregator, by reason.
+	EventsSuppressed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_events_suppressed_total",
+			Help: "Total number of warning events suppressed by deduplication",
+		},
+		[]string{"reason"},
+	)
+
+	// SecretSizeBytes tracks the serialized size of the data last written to each Vault path.
+	SecretSizeBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_secret_size_bytes",
+			Help: "Serialized size in bytes of the data last written to a Vault path",
+		},
+		[]string{"path"},
+	)
+
+	// SecretSizeDelta tracks how much the size of each Vault path changed with its last write.
+	SecretSizeDelta = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_secret_size_delta_bytes",
+			Help: "Change in serialized size in bytes of the last write to a Vault path",
+		},
+		[]string{"path"},
+	)
+
+	// LargeSecretWrites tracks writes exceeding the large secret threshold, by namespace.
+	LargeSecretWrites = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_large_secret_writes_total",
+			Help: "Total number of Vault writes larger than the large secret threshold",
+		},
+		[]string{"namespace"},
+	)
+
+	// VaultUnwritablePaths tracks the managed paths the token could not write at the last ACL check.
+	VaultUnwritablePaths = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_vault_unwritable_paths",
+			Help: "Sampled managed paths the Vault token could not write at the last ACL drift check",
+		},
+	)
+
+	// VaultPermissionReductions tracks capabilities lost on managed paths since an earlier ACL check.
+	VaultPermissionReductions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_vault_permission_reductions_total",
+			Help: "Total number of ACL drift checks that found capabilities lost on a managed path",
+		},
+	)
+
+	// TransactionalWriteRollbacks tracks transactional writes undone after a partial failure, by result.
+	TransactionalWriteRollbacks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_transactional_write_rollbacks_total",
+			Help: "Transactional multi-document writes rolled back after a partial failure (labeled by result: success, failed)",
+		},
+		[]string{"result"},
+	)
+
+	// DegradedResources tracks resources backed off after exhausting their retry budget.
+	DegradedResources = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_degraded_resources",
+			Help: "Number of resources retried at the degraded interval after repeated sync failures",
+		},
+	)
+
+	// KVMountProvisions tracks declared KV mounts checked by the provisioner, by result.
+	KVMountProvisions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vault_sync_operator_kv_mount_provisions_total",
+			Help: "Declared KV mounts checked by the mount provisioner (labeled by result: created, exists, error)",
+		},
+		[]string{"result"},
+	)
+
+	// ClusterInfo is set to 1, labeled by the configured cluster name (empty in single-cluster mode).
+	ClusterInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vault_sync_operator_cluster_info",
+			Help: "Cluster name the operator prefixes Vault paths with (1 for the configured name)",
+		},
+		[]string{"cluster_name"},
+	)
+
 	// RuntimeInfo provides information about Go runtime configuration.
 	RuntimeInfo = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -104,11 +454,51 @@ func init() {
 		SecretsyncAttempts,
 		SecretsyncDuration,
 		VaultAuthAttempts,
+		VaultReauthentications,
+		TokenHandoffs,
+		VaultTokenTTL,
 		SecretsDiscovered,
 		VaultWriteErrors,
 		SecretNotFoundErrors,
 		SecretKeyMissingError,
+		ValueValidationFailures,
 		ConfigParseErrors,
+		SharedSecretReferences,
+		VaultRequestQueueDepth,
+		VaultRateLimit,
+		VaultThrottledResponses,
+		VaultReplicaFailovers,
+		VaultAddressFailovers,
+		ClusterIdentityConflicts,
+		BackpressureRequeues,
+		WarmupInProgress,
+		WarmupObjects,
+		VaultState,
+		SyncsHeldWhileSealed,
+		PathLockWaitDuration,
+		AgentInjectorConflicts,
+		ExternalSecretConflicts,
+		PropagationDelay,
+		EventsSuppressed,
+		SyncSkipped,
+		ManagedPaths,
+		ManagedPathInfo,
+		PathMoves,
+		PolicyEvaluations,
+		ReplicaVersions,
+		VersionSkew,
+		HeartbeatWrites,
+		HeartbeatDuration,
+		HeartbeatLastSuccess,
+		SecretSizeBytes,
+		SecretSizeDelta,
+		LargeSecretWrites,
+		KVMountProvisions,
+		VaultUnwritablePaths,
+		VaultPermissionReductions,
+		TransactionalWriteRollbacks,
+		DegradedResources,
+		ClusterInfo,
 		RuntimeInfo,
 	)
 }
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// workqueueMetrics maps the controller-runtime workqueue metrics to the names they are also
+// exported under in the operator's namespace.
+var workqueueMetrics = map[string]string{
+	"workqueue_depth":                             "vault_sync_operator_workqueue_depth",
+	"workqueue_retries_total":                     "vault_sync_operator_workqueue_retries_total",
+	"workqueue_longest_running_processor_seconds": "vault_sync_operator_workqueue_longest_running_processor_seconds",
+}
+
+// workqueueRegistry gathers the metrics of a registry and adds copies of the workqueue metrics
+// in the operator's namespace, without their name label, which repeats the controller label.
+type workqueueRegistry struct {
+	prometheus.Registerer
+	prometheus.Gatherer
+}
+
+// WithWorkqueueMetrics returns registry exporting the workqueue depth, retries and longest
+// running processor of each controller also as vault_sync_operator_workqueue_* metrics, so
+// dashboards and alerts can select the operator's queues by name alone. Register it as the
+// controller-runtime metrics registry before the manager starts serving metrics.
+func WithWorkqueueMetrics(registry metrics.RegistererGatherer) metrics.RegistererGatherer {
+	return &workqueueRegistry{Registerer: registry, Gatherer: registry}
+}
+
+// Gather implements prometheus.Gatherer.
+func (r *workqueueRegistry) Gather() ([]*dto.MetricFamily, error) {
+	families, err := r.Gatherer.Gather()
+	if err != nil {
+		return families, err
+	}
+	var copies []*dto.MetricFamily
+	for _, family := range families {
+		name, ok := workqueueMetrics[family.GetName()]
+		if !ok {
+			continue
+		}
+		namespaced := proto.Clone(family).(*dto.MetricFamily)
+		namespaced.Name = proto.String(name)
+		for _, metric := range namespaced.Metric {
+			labels := metric.Label[:0]
+			for _, label := range metric.Label {
+				if label.GetName() != "name" {
+					labels = append(labels, label)
+				}
+			}
+			metric.Label = labels
+		}
+		copies = append(copies, namespaced)
+	}
+	if len(copies) == 0 {
+		return families, nil
+	}
+	families = append(families, copies...)
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, nil
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestWithWorkqueueMetrics(t *testing.T) {
+	inner := prometheus.NewRegistry()
+	depth := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "workqueue_depth", Help: "depth"},
+		[]string{"name", "controller", "priority"})
+	adds := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "workqueue_adds_total", Help: "adds"},
+		[]string{"name", "controller"})
+	inner.MustRegister(depth, adds)
+	depth.WithLabelValues("secret", "secret", "").Set(3)
+	adds.WithLabelValues("secret", "secret").Inc()
+
+	families, err := WithWorkqueueMetrics(inner).Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+		if family.GetName() != "vault_sync_operator_workqueue_depth" {
+			continue
+		}
+		metric := family.GetMetric()[0]
+		labels := make(map[string]string)
+		for _, label := range metric.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		if _, found := labels["name"]; found || labels["controller"] != "secret" {
+			t.Errorf("labels = %v, expected the controller label without name", labels)
+		}
+		if value := metric.GetGauge().GetValue(); value != 3 {
+			t.Errorf("depth = %v, expected 3", value)
+		}
+	}
+
+	// Originals are kept, only depth, retries and longest running processor are copied, in order
+	expected := []string{"vault_sync_operator_workqueue_depth", "workqueue_adds_total", "workqueue_depth"}
+	if len(names) != len(expected) {
+		t.Fatalf("families = %v, expected %v", names, expected)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("families = %v, expected %v", names, expected)
+			break
+		}
+	}
+	// The copy must not change the original
+	for _, family := range families {
+		if family.GetName() == "workqueue_depth" && len(family.GetMetric()[0].GetLabel()) != 3 {
+			t.Errorf("original workqueue_depth labels = %v, expected name, controller and priority", family.GetMetric()[0].GetLabel())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities returns the capabilities of the client's token on the paths the operator
+// writes for each of paths. KV v2 paths are checked on their data/ endpoint, which is
+// inserted the same way as for deletes when a path omits it.
+func (c *Client) Capabilities(ctx context.Context, paths []string) (map[string][]string, error) {
+	if len(paths) == 0 {
+		return map[string][]string{}, nil
+	}
+
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	writePaths := make(map[string]string, len(paths))
+	query := make([]string, 0, len(paths))
+	for _, path := range paths {
+		writePath := c.preparePathForKVDelete(path)
+		if mount, err := c.mountForPath(ctx, path); err == nil {
+			writePath = kvDeletePath(mount, path)
+		}
+		writePaths[path] = writePath
+		query = append(query, writePath)
+	}
+
+	secret, err := c.namespacedClient(ctx).Logical().WriteWithContext(ctx, "sys/capabilities-self", map[string]interface{}{"paths": query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up token capabilities: %w", err)
+	}
+	if secret == nil {
+		return nil, fmt.Errorf("failed to look up token capabilities: empty response")
+	}
+
+	capabilities := make(map[string][]string, len(paths))
+	for path, writePath := range writePaths {
+		values, _ := secret.Data[writePath].([]interface{})
+		for _, value := range values {
+			if capability, ok := value.(string); ok {
+				capabilities[path] = append(capabilities[path], capability)
+			}
+		}
+	}
+	return capabilities, nil
+}
@@ -5,9 +5,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
@@ -15,13 +15,64 @@ import (
 	"golang.org/x/time/rate"
 )
 
+// DefaultMaxPendingRequests is the number of requests allowed to wait on the rate limiter
+// before the client reports itself as saturated.
+const DefaultMaxPendingRequests = 50
+
 // Client represents a Vault client with Kubernetes authentication and rate limiting.
 type Client struct {
 	client      *api.Client
 	role        string
 	authPath    string
+	jwtSource   JWTSource
 	rateLimiter *rate.Limiter
 	batchMutex  sync.Mutex
+
+	// fairness shares the rate limiter's tokens between the namespaces requests are made for
+	fairness fairQueue
+
+	// throttle lowers the rate limiter's rate while Vault asks requests to back off
+	throttle adaptiveThrottle
+
+	// replica serves reads and health checks when a performance replica is configured
+	replica *replicaRouter
+
+	// addresses moves requests to another address of the cluster when several are configured
+	addresses *addressFailover
+
+	// pendingRequests counts callers currently blocked on the rate limiter
+	pendingRequests    atomic.Int64
+	maxPendingRequests int64
+
+	// sealed caches whether Vault was last observed to be sealed
+	sealed atomic.Bool
+	// sealedSince is when Vault was first observed sealed in the current sealed period, in Unix nanoseconds
+	sealedSince atomic.Int64
+
+	// paths serializes reconciles that target the same Vault path
+	paths PathSerializer
+
+	// mounts caches the secrets engine mounts detected for deletes, per Vault namespace
+	mounts mountCache
+
+	// namespaceMapping maps Kubernetes namespaces to the Vault namespace of their requests
+	namespaceMapping map[string]string
+
+	// authMu serializes logins; authErr holds the last failed login until authFailedAt
+	// is older than authFailureCooldown
+	authMu       sync.Mutex
+	authErr      error
+	authFailedAt time.Time
+
+	// tokenIssued and tokenExpiry bound the lifetime of the current token; a zero
+	// tokenExpiry means it never expires. Guarded by authMu.
+	tokenIssued time.Time
+	tokenExpiry time.Time
+	// tokenAccessor identifies the current token without granting its use. Guarded by authMu.
+	tokenAccessor string
+	// tokenEntityID is the identity entity of the current token, if Vault assigned one.
+	// Guarded by authMu.
+	tokenEntityID string
 }
 
 // BatchOperation represents a batch operation to be performed on Vault.
@@ -33,51 +84,132 @@ type BatchOperation struct {
 
 // NewClient creates a new Vault client with Kubernetes authentication and rate limiting.
 func NewClient(vaultAddr, role, authPath string) (*Client, error) {
+	return NewClientFromConfig(Config{
+		Address:  vaultAddr,
+		Role:     role,
+		AuthPath: authPath,
+	})
+}
+
+// NewClientFromConfig creates a new Vault client from the given connection settings.
+func NewClientFromConfig(cfg Config) (*Client, error) {
+	vaultClient, err := NewUnauthenticatedClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authenticate with Kubernetes auth method
+	if err := vaultClient.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to authenticate with vault: %w", err)
+	}
+
+	return vaultClient, nil
+}
+
+// NewUnauthenticatedClient creates a Vault client from the given connection settings without
+// logging in. The first request logs in, and a failed login fails only that request, so the
+// client can be created while Vault is down. AuthenticateWithRetry logs in ahead of use.
+func NewUnauthenticatedClient(cfg Config) (*Client, error) {
 	config := api.DefaultConfig()
-	config.Address = vaultAddr
+	addresses := cfg.Addresses()
+	if len(addresses) > 0 {
+		config.Address = addresses[0]
+	}
+
+	if cfg.CACert != "" {
+		if err := config.ConfigureTLS(&api.TLSConfig{CACert: cfg.CACert}); err != nil {
+			return nil, fmt.Errorf("failed to configure vault TLS: %w", err)
+		}
+	}
 
 	client, err := api.NewClient(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vault client: %w", err)
 	}
 
+	// Only tokens obtained by the Kubernetes auth login are used, never VAULT_TOKEN
+	client.ClearToken()
+
+	if cfg.Namespace != "" {
+		client.SetNamespace(cfg.Namespace)
+	}
+	if cfg.UserAgent != "" {
+		client.AddHeader("User-Agent", cfg.UserAgent)
+	}
+	for name, value := range cfg.Headers {
+		client.AddHeader(name, value)
+	}
+
+	role := cfg.Role
+	authPath := cfg.AuthPath
+	jwtSource := cfg.JWTSource
+	if jwtSource == nil {
+		jwtSource = FileJWTSource{Path: DefaultServiceAccountTokenPath}
+	}
+
 	// Create rate limiter: allow 10 requests per second with burst of 20
 	rateLimiter := rate.NewLimiter(rate.Limit(10), 20)
 
-	vaultClient := &Client{
+	metrics.VaultRateLimit.Set(float64(rateLimiter.Limit()))
+
+	// Create the client; the token is obtained by the first authentication
+	c := &Client{
 		client:      client,
 		role:        role,
 		authPath:    authPath,
+		jwtSource:   jwtSource,
 		rateLimiter: rateLimiter,
-	}
+		addresses:   newAddressFailover(addresses),
 
-	// Authenticate with Kubernetes auth method
-	if err := vaultClient.authenticate(); err != nil {
-		return nil, fmt.Errorf("failed to authenticate with vault: %w", err)
+		namespaceMapping:   cfg.NamespaceMapping,
+		maxPendingRequests: DefaultMaxPendingRequests,
 	}
 
-	return vaultClient, nil
+	// Lower the request rate while Vault responds with 429 or 503 and Retry-After
+	client.SetCheckRetry(c.checkRetry)
+
+	if cfg.ReplicaAddress != "" {
+		if c.replica, err = newReplicaRouter(client, cfg.ReplicaAddress); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
 }
 
 // authenticate performs Kubernetes authentication with Vault.
 func (c *Client) authenticate() error {
-	// Read the service account token
-	tokenPath := "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec // This is a standard Kubernetes file path, not a credential
-	jwt, err := os.ReadFile(tokenPath)
+	// Obtain the service account token
+	ctx, cancel := context.WithTimeout(context.Background(), jwtTimeout)
+	jwt, err := c.jwtSource.JWT(ctx)
+	cancel()
 	if err != nil {
 		metrics.VaultAuthAttempts.WithLabelValues("failed").Inc()
-		return fmt.Errorf("failed to read service account token: %w", err)
+		return err
 	}
 
 	// Prepare the authentication request
 	authPath := filepath.Join("auth", c.authPath, "login")
 	data := map[string]interface{}{
 		"role": c.role,
-		"jwt":  string(jwt),
+		"jwt":  jwt,
 	}
 
-	// Authenticate
-	secret, err := c.client.Logical().Write(authPath, data)
+	// Log in with a token-less copy of the client, so the token being replaced is never sent
+	// along and requests in flight are unaffected
+	login, err := c.cloneClient()
+	if err != nil {
+		metrics.VaultAuthAttempts.WithLabelValues("failed").Inc()
+		return fmt.Errorf("failed to prepare vault login: %w", err)
+	}
+	login.ClearToken()
+	secret, err := login.Logical().Write(authPath, data)
+	// A login that got no response is retried once on the next address
+	if err != nil && addressFailed(err) && c.failover(login.Address(), failoverTriggerLogin) {
+		if login, err = c.cloneClient(); err == nil {
+			login.ClearToken()
+			secret, err = login.Logical().Write(authPath, data)
+		}
+	}
 	if err != nil {
 		metrics.VaultAuthAttempts.WithLabelValues("failed").Inc()
 		return fmt.Errorf("failed to authenticate: %w", err)
@@ -90,24 +222,89 @@ func (c *Client) authenticate() error {
 
 	// Set the token for future requests
 	c.client.SetToken(secret.Auth.ClientToken)
+	c.tokenIssued = time.Now()
+	c.tokenAccessor = secret.Auth.Accessor
+	c.tokenEntityID = secret.Auth.EntityID
+	c.tokenExpiry = time.Time{}
+	if secret.Auth.LeaseDuration > 0 {
+		c.tokenExpiry = c.tokenIssued.Add(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+	}
 	metrics.VaultAuthAttempts.WithLabelValues("success").Inc()
 
 	return nil
 }
 
+// SetMaxPendingRequests sets the rate limiter queue depth at which the client is considered saturated.
+func (c *Client) SetMaxPendingRequests(n int) {
+	c.maxPendingRequests = int64(n)
+}
+
+// waitForRateLimiter blocks on the rate limiter while tracking the queue depth. Requests take
+// turns by the Kubernetes namespace they are made for, see WithSourceNamespace.
+func (c *Client) waitForRateLimiter(ctx context.Context) error {
+	metrics.VaultRequestQueueDepth.Set(float64(c.pendingRequests.Add(1)))
+	defer func() {
+		metrics.VaultRequestQueueDepth.Set(float64(c.pendingRequests.Add(-1)))
+	}()
+
+	c.throttle.recover(c.rateLimiter, time.Now())
+	return c.fairness.Wait(ctx, c.rateLimiter, SourceNamespace(ctx))
+}
+
+// PendingRequests returns the number of requests currently waiting on the rate limiter.
+func (c *Client) PendingRequests() int64 {
+	return c.pendingRequests.Load()
+}
+
+// BackpressureDelay reports whether the rate limiter is saturated and, if so, how long
+// callers should wait before retrying so the queue can drain.
+func (c *Client) BackpressureDelay() (time.Duration, bool) {
+	return c.BackpressureDelayAt(1)
+}
+
+// BackpressureDelayAt is BackpressureDelay with the saturation threshold scaled by fraction,
+// so low-priority callers back off earlier and leave the remaining requests to others.
+func (c *Client) BackpressureDelayAt(fraction float64) (time.Duration, bool) {
+	pending := c.pendingRequests.Load()
+	threshold := int64(float64(c.maxPendingRequests) * fraction)
+	if c.maxPendingRequests <= 0 || pending < threshold {
+		return 0, false
+	}
+
+	// Time needed to drain the current queue at the configured rate
+	delay := time.Duration(float64(pending) / float64(c.rateLimiter.Limit()) * float64(time.Second))
+	if delay < time.Second {
+		delay = time.Second
+	}
+	return delay, true
+}
+
+// NamespaceBackpressureDelayAt is BackpressureDelayAt for a request of the given Kubernetes
+// namespace. A saturated queue only defers namespaces holding at least their fair share of the
+// waiting requests, so one namespace filling the queue does not keep the others out of it.
+func (c *Client) NamespaceBackpressureDelayAt(namespace string, fraction float64) (time.Duration, bool) {
+	delay, saturated := c.BackpressureDelayAt(fraction)
+	if !saturated {
+		return 0, false
+	}
+	waiting, total, namespaces := c.fairness.share(namespace)
+	if waiting*namespaces < total {
+		return 0, false
+	}
+	return delay, true
+}
+
 // WriteSecret writes a secret to Vault at the specified path with rate limiting.
 func (c *Client) WriteSecret(ctx context.Context, path string, data map[string]interface{}) error {
 	// Apply rate limiting
-	if err := c.rateLimiter.Wait(ctx); err != nil {
+	if err := c.waitForRateLimiter(ctx); err != nil {
 		return fmt.Errorf("rate limiter error: %w", err)
 	}
 
 	// Ensure we have a valid token
-	if c.client.Token() == "" {
-		if err := c.authenticate(); err != nil {
-			metrics.VaultWriteErrors.WithLabelValues("auth_failed", path).Inc()
-			return fmt.Errorf("failed to re-authenticate: %w", err)
-		}
+	if err := c.ensureAuthenticated(); err != nil {
+		metrics.VaultWriteErrors.WithLabelValues("auth_failed", metrics.PathLabel(path)).Inc()
+		return fmt.Errorf("failed to re-authenticate: %w", err)
 	}
 
 	// Optimize for large secrets: if data is too large, consider chunking or streaming
@@ -116,59 +313,111 @@ func (c *Client) WriteSecret(ctx context.Context, path string, data map[string]i
 	}
 
 	// Write the secret with KV v2 support
-	writeData := c.prepareDataForKVVersion(path, data)
-	_, err := c.client.Logical().WriteWithContext(ctx, path, writeData)
+	writeData := c.prepareDataForKVVersion(ctx, path, data)
+	err := c.retryOnDenied(ctx, func(client *api.Client) error {
+		_, err := client.Logical().WriteWithContext(ctx, path, writeData)
+		return err
+	})
 	if err != nil {
-		// Categorize the error type for better metrics
-		var errorType string
-		switch {
-		case isPermissionError(err):
-			errorType = "permission_denied"
-		case isPathError(err):
-			errorType = "invalid_path"
-		case isConnectionError(err):
-			errorType = "connection_failed"
-		default:
-			errorType = "unknown"
-		}
-
-		metrics.VaultWriteErrors.WithLabelValues(errorType, path).Inc()
+		err = c.requestError(err)
+		reason := Reason(err)
+		metrics.VaultWriteErrors.WithLabelValues(string(reason), metrics.PathLabel(path)).Inc()
 		return fmt.Errorf("failed to write secret to vault at path %s: %w", path, err)
 	}
 
 	return nil
 }
 
+// ReadSecret reads a secret from Vault at the specified path with rate limiting.
+// The result is the same for KV v1 and v2 mounts: the data/data envelope of KV v2 secrets
+// is unwrapped, so it matches what was passed to WriteSecret. KV v2 paths may omit the
+// data/ segment, like the paths written. The KV version is taken from the mount of the
+// path, or guessed from the path when the mount cannot be detected. A missing secret, or
+// one whose current version is deleted, yields nil data and no error.
+func (c *Client) ReadSecret(ctx context.Context, path string) (map[string]interface{}, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	readPath, kvVersion := path, 1
+	if mount, err := c.mountForPath(ctx, path); err == nil {
+		readPath, kvVersion = kvDeletePath(mount, path), mount.version
+	} else if isKVv2Path(path) {
+		kvVersion = 2
+	}
+
+	var secret *api.Secret
+	err := c.readPreferReplica(ctx, "read", func(client *api.Client) (err error) {
+		secret, err = client.Logical().ReadWithContext(ctx, readPath)
+		return err
+	})
+	if err != nil {
+		err = c.requestError(err)
+		return nil, fmt.Errorf("failed to read secret from vault at path %s: %w", path, err)
+	}
+	return unwrapSecretData(secret, kvVersion), nil
+}
+
+// unwrapSecretData returns the data of a read response, unwrapping the data/data envelope of
+// KV v2. A deleted KV v2 version has null data.
+func unwrapSecretData(secret *api.Secret, kvVersion int) map[string]interface{} {
+	if secret == nil {
+		return nil
+	}
+	if kvVersion == 2 {
+		data, _ := secret.Data["data"].(map[string]interface{})
+		return data
+	}
+	return secret.Data
+}
+
 // DeleteSecret deletes a secret from Vault at the specified path with rate limiting.
 func (c *Client) DeleteSecret(ctx context.Context, path string) error {
 	// Apply rate limiting
-	if err := c.rateLimiter.Wait(ctx); err != nil {
+	if err := c.waitForRateLimiter(ctx); err != nil {
 		return fmt.Errorf("rate limiter error: %w", err)
 	}
 
 	// Ensure we have a valid token
-	if c.client.Token() == "" {
-		if err := c.authenticate(); err != nil {
-			return fmt.Errorf("failed to re-authenticate: %w", err)
-		}
+	if err := c.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("failed to re-authenticate: %w", err)
 	}
 
-	// Delete the secret with KV v2 support
+	// Delete the secret according to the KV version of its mount, falling back to
+	// the path heuristic when the mount cannot be detected
 	deletePath := c.preparePathForKVDelete(path)
-	_, err := c.client.Logical().DeleteWithContext(ctx, deletePath)
+	if mount, err := c.mountForPath(ctx, path); err == nil {
+		deletePath = kvDeletePath(mount, path)
+	}
+	err := c.retryOnDenied(ctx, func(client *api.Client) error {
+		_, err := client.Logical().DeleteWithContext(ctx, deletePath)
+		return err
+	})
 	if err != nil {
+		err = c.requestError(err)
 		return fmt.Errorf("failed to delete secret from vault at path %s: %w", path, err)
 	}
 
 	return nil
 }
 
-// prepareDataForKVVersion formats data appropriately for KV v1 or v2 based on the path.
-// KV v2 paths contain "/data/" and require data to be wrapped in a "data" field.
-// KV v1 paths don't contain "/data/" and use the data directly.
-func (c *Client) prepareDataForKVVersion(path string, data map[string]interface{}) map[string]interface{} {
-	// Check if this is a KV v2 path by looking for "/data/" in the path
-	if isKVv2Path(path) {
+// prepareDataForKVVersion formats data for the KV version of the mount serving path: KV v2
+// requires data to be wrapped in a "data" field, KV v1 uses the data directly. When the
+// mount cannot be detected, paths under "secret/data/" are assumed to be on a KV v2 mount.
+func (c *Client) prepareDataForKVVersion(ctx context.Context, path string, data map[string]interface{}) map[string]interface{} {
+	kvVersion := 1
+	if mount, err := c.mountForPath(ctx, path); err == nil {
+		kvVersion = mount.version
+	} else if isKVv2Path(path) {
+		kvVersion = 2
+	}
+	if kvVersion == 2 {
 		// KV v2 requires data to be wrapped in a "data" field
 		return map[string]interface{}{
 			"data": data,
@@ -184,9 +433,9 @@ func isKVv2Path(path string) bool {
 	return len(path) > 6 && path[:6] == "secret" && (len(path) > 12 && path[6:12] == "/data/")
 }
 
-// preparePathForKVDelete returns the appropriate path for deletion based on KV version.
-// For KV v2, it ensures the path uses "/data/" for the delete operation.
-// For KV v1, it returns the path as-is.
+// preparePathForKVDelete guesses the path for deletion when the mount cannot be detected.
+// Paths under "secret/" are assumed to be on a KV v2 mount and use "/data/" for the
+// delete operation. Any other path is returned as-is.
 func (c *Client) preparePathForKVDelete(path string) string {
 	if isKVv2Path(path) {
 		// KV v2 path already has "/data/" - use as-is
@@ -206,41 +455,6 @@ func (c *Client) preparePathForKVDelete(path string) string {
 	return path
 }
 
-// Helper function to categorize errors - is the error related to permission issues?
-func isPermissionError(err error) bool {
-	// Check for common permission-related error messages
-	errStr := err.Error()
-	return containsAny(errStr, []string{"permission denied", "forbidden", "403"})
-}
-
-// Helper function to categorize errors - is the error related to path issues?
-func isPathError(err error) bool {
-	// Check for path-related error messages
-	errStr := err.Error()
-	return containsAny(errStr, []string{"invalid path", "not found", "404"})
-}
-
-// Helper function to categorize errors - is the error related to connection issues?
-func isConnectionError(err error) bool {
-	// Check for connection-related error messages
-	errStr := err.Error()
-	return containsAny(errStr, []string{"connection refused", "timeout", "network"})
-}
-
-// containsAny checks if the string contains any of the specified substrings.
-func containsAny(str string, substrings []string) bool {
-	for _, substr := range substrings {
-		if len(str) >= len(substr) {
-			for i := 0; i <= len(str)-len(substr); i++ {
-				if str[i:i+len(substr)] == substr {
-					return true
-				}
-			}
-		}
-	}
-	return false
-}
-
 // isDataTooLarge checks if the secret data is too large and needs optimization.
 func (c *Client) isDataTooLarge(data map[string]interface{}) bool {
 	// Calculate approximate size of the data
@@ -272,8 +486,11 @@ func (c *Client) writeSecretOptimized(ctx context.Context, path string, data map
 	}
 
 	// Write the secret normally but with optimization flags and KV v2 support
-	writeData := c.prepareDataForKVVersion(path, data)
-	_, err := c.client.Logical().WriteWithContext(ctx, path, writeData)
+	writeData := c.prepareDataForKVVersion(ctx, path, data)
+	err := c.retryOnDenied(ctx, func(client *api.Client) error {
+		_, err := client.Logical().WriteWithContext(ctx, path, writeData)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to write large secret (%d bytes) to vault at path %s: %w", totalSize, path, err)
 	}
@@ -297,7 +514,7 @@ func (c *Client) BatchWriteSecrets(ctx context.Context, operations []BatchOperat
 		batch := operations[i:end]
 		for _, op := range batch {
 			// Apply rate limiting for each operation
-			if err := c.rateLimiter.Wait(ctx); err != nil {
+			if err := c.waitForRateLimiter(ctx); err != nil {
 				return fmt.Errorf("rate limiter error during batch operation: %w", err)
 			}
 
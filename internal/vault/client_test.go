@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestBackpressureDelay(t *testing.T) {
+	c := &Client{
+		rateLimiter:        rate.NewLimiter(rate.Limit(10), 20),
+		maxPendingRequests: 5,
+	}
+
+	if _, saturated := c.BackpressureDelay(); saturated {
+		t.Errorf("Expected client with no pending requests to be unsaturated")
+	}
+
+	c.pendingRequests.Store(50)
+	delay, saturated := c.BackpressureDelay()
+	if !saturated {
+		t.Fatalf("Expected client with 50 pending requests to be saturated")
+	}
+	if delay != 5*time.Second {
+		t.Errorf("Expected delay of 5s, got %v", delay)
+	}
+
+	// Callers using half the threshold back off before the queue is full
+	c.pendingRequests.Store(3)
+	if _, saturated := c.BackpressureDelay(); saturated {
+		t.Errorf("Expected client with 3 pending requests to be unsaturated")
+	}
+	if _, saturated := c.BackpressureDelayAt(0.5); !saturated {
+		t.Errorf("Expected client with 3 pending requests to be saturated at half the threshold")
+	}
+
+	c.SetMaxPendingRequests(0)
+	if _, saturated := c.BackpressureDelay(); saturated {
+		t.Errorf("Expected backpressure to be disabled when max pending requests is 0")
+	}
+}
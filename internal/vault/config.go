@@ -0,0 +1,146 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables recognized for Vault connection settings. VAULT_ADDR, VAULT_CACERT and
+// VAULT_NAMESPACE follow the standard Vault CLI conventions.
+const (
+	EnvVaultAddr      = "VAULT_ADDR"
+	EnvVaultRole      = "VAULT_ROLE"
+	EnvVaultAuthPath  = "VAULT_AUTH_PATH"
+	EnvVaultNamespace = "VAULT_NAMESPACE"
+	EnvVaultCACert    = "VAULT_CACERT"
+	// EnvVaultReplicaAddr is the address of a performance replica serving reads and health checks
+	EnvVaultReplicaAddr = "VAULT_REPLICA_ADDR"
+)
+
+// ConfigDirCACertFile is the file name of a PEM CA bundle inside a mounted config directory.
+const ConfigDirCACertFile = "ca.crt"
+
+// Config holds the Vault connection settings.
+type Config struct {
+	// Address is the Vault address, or a comma-separated list of addresses of the same cluster
+	// that requests fail over between
+	Address string
+	// ReplicaAddress is a Vault Enterprise performance replica serving reads and health
+	// checks; writes always go to Address (disabled when empty)
+	ReplicaAddress string
+	Role           string
+	AuthPath       string
+	Namespace      string
+	CACert         string // Path to a PEM encoded CA bundle
+	UserAgent      string // User-Agent sent with every request (the Vault API client's default when empty)
+	// Headers are additional HTTP headers sent with every request
+	Headers map[string]string
+	// NamespaceMapping maps Kubernetes namespaces to the Vault namespace the requests made for
+	// them are sent to, instead of Namespace (see WithSourceNamespace). Logins and requests
+	// of the operator itself always use Namespace.
+	NamespaceMapping map[string]string
+	// JWTSource supplies the login JWT (the mounted service account token when nil)
+	JWTSource JWTSource
+}
+
+// Addresses returns the addresses listed in Address.
+func (c *Config) Addresses() []string {
+	var addresses []string
+	for _, address := range strings.Split(c.Address, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// reservedHeaders are managed by the Vault client and cannot be set as custom headers.
+var reservedHeaders = []string{"X-Vault-Token", "X-Vault-Namespace", "X-Vault-Wrap-TTL", "X-Vault-Request"}
+
+// UserAgent returns the User-Agent identifying an operator instance, e.g.
+// "vault-sync-operator/1.4.0 (cluster=prod-eu-1)".
+func UserAgent(version, clusterName string) string {
+	userAgent := "vault-sync-operator/" + version
+	if clusterName != "" {
+		userAgent += " (cluster=" + clusterName + ")"
+	}
+	return userAgent
+}
+
+// ParseHeaders parses a comma-separated list of Name=value pairs into custom request headers.
+func ParseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, headerValue, ok := strings.Cut(entry, "=")
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected Name=value", entry)
+		}
+		for _, reserved := range reservedHeaders {
+			if name == reserved {
+				return nil, fmt.Errorf("header %s is managed by the operator and cannot be set", name)
+			}
+		}
+		headers[name] = strings.TrimSpace(headerValue)
+	}
+	return headers, nil
+}
+
+// LoadConfigFromDir overlays settings from a directory, typically a mounted Secret, where each
+// file is named after the matching environment variable (e.g. VAULT_ADDR) and contains its value.
+// A ca.crt file is used as the CA bundle. Missing files are ignored.
+func (c *Config) LoadConfigFromDir(dir string) error {
+	fields := map[string]*string{
+		EnvVaultAddr:        &c.Address,
+		EnvVaultReplicaAddr: &c.ReplicaAddress,
+		EnvVaultRole:        &c.Role,
+		EnvVaultAuthPath:    &c.AuthPath,
+		EnvVaultNamespace:   &c.Namespace,
+	}
+
+	for name, field := range fields {
+		value, err := os.ReadFile(filepath.Join(dir, name)) //nolint:gosec // Path is built from a fixed set of file names
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("failed to read vault config file %s: %w", name, err)
+		}
+		if trimmed := strings.TrimSpace(string(value)); trimmed != "" {
+			*field = trimmed
+		}
+	}
+
+	caPath := filepath.Join(dir, ConfigDirCACertFile)
+	if _, err := os.Stat(caPath); err == nil {
+		c.CACert = caPath
+	}
+
+	return nil
+}
+
+// ApplyEnvironment overlays settings from VAULT_* environment variables that are set.
+func (c *Config) ApplyEnvironment() {
+	fields := map[string]*string{
+		EnvVaultAddr:        &c.Address,
+		EnvVaultRole:        &c.Role,
+		EnvVaultAuthPath:    &c.AuthPath,
+		EnvVaultNamespace:   &c.Namespace,
+		EnvVaultCACert:      &c.CACert,
+		EnvVaultReplicaAddr: &c.ReplicaAddress,
+	}
+
+	for name, field := range fields {
+		if value := os.Getenv(name); value != "" {
+			*field = value
+		}
+	}
+}
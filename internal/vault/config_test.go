@@ -0,0 +1,110 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigResolution(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		EnvVaultAddr:        "https://vault-from-dir:8200\n",
+		EnvVaultRole:        "dir-role",
+		EnvVaultNamespace:   "team-a",
+		ConfigDirCACertFile: "-----BEGIN CERTIFICATE-----",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := Config{
+		Address:  "http://vault:8200",
+		Role:     "vault-sync-operator",
+		AuthPath: "kubernetes",
+	}
+	if err := cfg.LoadConfigFromDir(dir); err != nil {
+		t.Fatalf("LoadConfigFromDir() error = %v", err)
+	}
+
+	if cfg.Address != "https://vault-from-dir:8200" {
+		t.Errorf("Expected address from config dir, got %s", cfg.Address)
+	}
+	if cfg.Role != "dir-role" {
+		t.Errorf("Expected role from config dir, got %s", cfg.Role)
+	}
+	if cfg.AuthPath != "kubernetes" {
+		t.Errorf("Expected default auth path to be kept, got %s", cfg.AuthPath)
+	}
+	if cfg.CACert != filepath.Join(dir, ConfigDirCACertFile) {
+		t.Errorf("Expected CA cert path from config dir, got %s", cfg.CACert)
+	}
+
+	t.Setenv(EnvVaultAddr, "https://vault-from-env:8200")
+	t.Setenv(EnvVaultAuthPath, "kubernetes-prod")
+	cfg.ApplyEnvironment()
+
+	if cfg.Address != "https://vault-from-env:8200" {
+		t.Errorf("Expected environment to override config dir, got %s", cfg.Address)
+	}
+	if cfg.AuthPath != "kubernetes-prod" {
+		t.Errorf("Expected auth path from environment, got %s", cfg.AuthPath)
+	}
+	if cfg.Namespace != "team-a" {
+		t.Errorf("Expected unset environment variable to keep config dir value, got %s", cfg.Namespace)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			value:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:     "canonicalized names",
+			value:    "x-operator-cluster=prod-eu-1, X-Team = platform",
+			expected: map[string]string{"X-Operator-Cluster": "prod-eu-1", "X-Team": "platform"},
+		},
+		{
+			name:    "missing value separator",
+			value:   "X-Team",
+			wantErr: true,
+		},
+		{
+			name:    "reserved header",
+			value:   "x-vault-token=abc",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers, err := ParseHeaders(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(headers, tt.expected) {
+				t.Errorf("ParseHeaders() = %v, expected %v", headers, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	if ua := UserAgent("1.4.0", "prod-eu-1"); ua != "vault-sync-operator/1.4.0 (cluster=prod-eu-1)" {
+		t.Errorf("UserAgent() = %q", ua)
+	}
+	if ua := UserAgent("dev", ""); ua != "vault-sync-operator/dev" {
+		t.Errorf("UserAgent() without cluster = %q", ua)
+	}
+}
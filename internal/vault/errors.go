@@ -0,0 +1,133 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ErrorReason categorizes a failed Vault request. Reasons are derived from the HTTP status of
+// Vault's response rather than its message, so they do not depend on the Vault version or on
+// the wording of errors. They are also the error_type label of the write errors metric.
+type ErrorReason string
+
+// Reasons of failed Vault requests.
+const (
+	// ReasonSealed is a 503 response without Retry-After: Vault is sealed, or has no active
+	// node to serve requests
+	ReasonSealed ErrorReason = "vault_sealed"
+	// ReasonPermissionDenied is a 401 or 403 response: the token is invalid or lacks a capability
+	ReasonPermissionDenied ErrorReason = "permission_denied"
+	// ReasonInvalidPath is a 404 or 405 response: the path or its mount does not exist
+	ReasonInvalidPath ErrorReason = "invalid_path"
+	// ReasonInvalidRequest is a 400 response: Vault rejected the request data
+	ReasonInvalidRequest ErrorReason = "invalid_request"
+	// ReasonThrottled is a 429 response, or a 503 response with Retry-After: a rate limit quota
+	// rejected the request, or Vault sheds load
+	ReasonThrottled ErrorReason = "throttled"
+	// ReasonConnection means no response was received, e.g. a refused connection or a timeout
+	ReasonConnection ErrorReason = "connection_failed"
+	// ReasonUnknown is any other error
+	ReasonUnknown ErrorReason = "unknown"
+)
+
+// loadSheddingError marks a 503 response that came with a Retry-After header. Vault sheds load
+// that way, while a sealed Vault or one without an active node answers 503 without it. The
+// header is not kept in api.ResponseError, so the client marks the error, see requestError.
+type loadSheddingError struct {
+	err error
+}
+
+func (e *loadSheddingError) Error() string { return e.err.Error() }
+
+func (e *loadSheddingError) Unwrap() error { return e.err }
+
+// StatusCode returns the HTTP status of the Vault response that failed with err, or 0 when
+// err does not carry a response.
+func StatusCode(err error) int {
+	var responseErr *api.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode
+	}
+	return 0
+}
+
+// Reason returns the category of err, which may wrap the error of a Vault request.
+func Reason(err error) ErrorReason {
+	if err == nil {
+		return ""
+	}
+	var shedding *loadSheddingError
+	switch code := StatusCode(err); code {
+	case http.StatusServiceUnavailable:
+		if errors.As(err, &shedding) {
+			return ReasonThrottled
+		}
+		return ReasonSealed
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ReasonPermissionDenied
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return ReasonInvalidPath
+	case http.StatusBadRequest:
+		return ReasonInvalidRequest
+	case http.StatusTooManyRequests:
+		return ReasonThrottled
+	case 0:
+		var netErr net.Error
+		var urlErr *url.Error
+		if errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.Is(err, context.DeadlineExceeded) {
+			return ReasonConnection
+		}
+		return ReasonUnknown
+	default:
+		return ReasonUnknown
+	}
+}
+
+// IsSealed reports whether err means Vault is sealed or has no active node.
+func IsSealed(err error) bool {
+	return Reason(err) == ReasonSealed
+}
+
+// IsPermissionDenied reports whether err means the token was rejected or lacks a capability.
+func IsPermissionDenied(err error) bool {
+	return Reason(err) == ReasonPermissionDenied
+}
+
+// IsInvalidToken reports whether err is a 403 response because the token itself is not valid,
+// e.g. revoked or malformed, rather than because it lacks a capability. Vault only tells the
+// two apart in its messages, adding "invalid token" or "bad token" to "permission denied",
+// either as separate errors or in a single multi-error message.
+func IsInvalidToken(err error) bool {
+	var responseErr *api.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusForbidden {
+		return false
+	}
+	for _, message := range responseErr.Errors {
+		if strings.Contains(message, "invalid token") || strings.Contains(message, "bad token") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInvalidPath reports whether err means the path or its mount does not exist.
+func IsInvalidPath(err error) bool {
+	return Reason(err) == ReasonInvalidPath
+}
+
+// IsThrottled reports whether err means a rate limit quota rejected the request or Vault
+// sheds load.
+func IsThrottled(err error) bool {
+	return Reason(err) == ReasonThrottled
+}
+
+// IsConnectionError reports whether err means Vault could not be reached.
+func IsConnectionError(err error) bool {
+	return Reason(err) == ReasonConnection
+}
@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestReason(t *testing.T) {
+	responseErr := func(code int, message string) error {
+		return &api.ResponseError{StatusCode: code, Errors: []string{message}}
+	}
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorReason
+	}{
+		{name: "no error", err: nil, expected: ""},
+		{name: "sealed", err: responseErr(503, "Vault is sealed"), expected: ReasonSealed},
+		{name: "load shedding", err: fmt.Errorf("failed to write secret: %w", &loadSheddingError{err: responseErr(503, "")}), expected: ReasonThrottled},
+		{name: "permission denied", err: responseErr(403, "permission denied"), expected: ReasonPermissionDenied},
+		{name: "localized permission denied", err: responseErr(403, "Zugriff verweigert"), expected: ReasonPermissionDenied},
+		{name: "invalid token", err: responseErr(401, "missing client token"), expected: ReasonPermissionDenied},
+		{name: "unknown path", err: responseErr(404, "no handler for route"), expected: ReasonInvalidPath},
+		{name: "invalid request", err: responseErr(400, "invalid data"), expected: ReasonInvalidRequest},
+		{name: "rate limited", err: responseErr(429, "request path: rate limit quota exceeded"), expected: ReasonThrottled},
+		{name: "internal error", err: responseErr(500, "internal error"), expected: ReasonUnknown},
+		{name: "wrapped response", err: fmt.Errorf("failed to write secret: %w", responseErr(403, "")), expected: ReasonPermissionDenied},
+		{name: "message mentioning 403 without a response", err: errors.New("permission denied (403)"), expected: ReasonUnknown},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: ReasonConnection},
+		{name: "deadline", err: fmt.Errorf("request failed: %w", context.DeadlineExceeded), expected: ReasonConnection},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Reason(tt.err); got != tt.expected {
+				t.Errorf("Reason() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+
+	if !IsSealed(responseErr(503, "")) || IsSealed(responseErr(403, "")) {
+		t.Errorf("IsSealed() does not match the 503 response only")
+	}
+	if !IsPermissionDenied(responseErr(403, "")) || !IsInvalidPath(responseErr(404, "")) ||
+		!IsThrottled(responseErr(429, "")) || !IsConnectionError(context.DeadlineExceeded) {
+		t.Errorf("Is* helpers do not match their reasons")
+	}
+	if code := StatusCode(fmt.Errorf("wrapped: %w", responseErr(404, ""))); code != 404 {
+		t.Errorf("StatusCode() = %d, expected 404", code)
+	}
+}
+
+func TestIsInvalidToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "no error", err: nil, expected: false},
+		{name: "invalid token", err: &api.ResponseError{StatusCode: 403, Errors: []string{"permission denied", "invalid token"}}, expected: true},
+		{name: "bad token", err: &api.ResponseError{StatusCode: 403, Errors: []string{"permission denied", "bad token"}}, expected: true},
+		{name: "multi-error message", err: &api.ResponseError{StatusCode: 403, Errors: []string{"2 errors occurred:\n\t* permission denied\n\t* invalid token\n\n"}}, expected: true},
+		{name: "policy denial", err: &api.ResponseError{StatusCode: 403, Errors: []string{"permission denied"}}, expected: false},
+		{name: "missing token", err: &api.ResponseError{StatusCode: 401, Errors: []string{"invalid token"}}, expected: false},
+		{name: "wrapped", err: fmt.Errorf("lookup failed: %w", &api.ResponseError{StatusCode: 403, Errors: []string{"invalid token"}}), expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsInvalidToken(tt.err); got != tt.expected {
+				t.Errorf("IsInvalidToken() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// Triggers of a switch to another Vault address, reported by the address failovers metric.
+const (
+	failoverTriggerRequest = "request" // A read, write or delete got no response
+	failoverTriggerLogin   = "login"   // A login got no response
+	failoverTriggerHealth  = "health"  // The sys/health check got no response
+)
+
+// addressFailover moves requests between the addresses of one Vault cluster, such as its load
+// balancer and the Services of its nodes, when the current address stops responding. All
+// methods are safe to call on a nil failover, which keeps the single configured address.
+type addressFailover struct {
+	addresses []string
+
+	mu      sync.Mutex
+	current int
+}
+
+// newAddressFailover returns a failover between addresses, starting with the first, or nil
+// for fewer than two addresses.
+func newAddressFailover(addresses []string) *addressFailover {
+	if len(addresses) < 2 {
+		return nil
+	}
+	return &addressFailover{addresses: addresses}
+}
+
+// address returns the address requests are sent to, or "" for a nil failover.
+func (f *addressFailover) address() string {
+	if f == nil {
+		return ""
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addresses[f.current]
+}
+
+// addressFailed reports whether err means the request got no response from Vault, so another
+// address may serve it. Requests canceled by the caller did not fail.
+func addressFailed(err error) bool {
+	return StatusCode(err) == 0 && !errors.Is(err, context.Canceled)
+}
+
+// cloneClient returns a copy of the API client sent to the current address. Copies of an
+// API client start out with the address it was created with, so it is set on every copy.
+func (c *Client) cloneClient() (*api.Client, error) {
+	client, err := c.client.CloneWithHeaders()
+	if err != nil {
+		return nil, err
+	}
+	if address := c.addresses.address(); address != "" {
+		if err := client.SetAddress(address); err != nil {
+			return nil, fmt.Errorf("invalid vault address %q: %w", address, err)
+		}
+	}
+	return client, nil
+}
+
+// switchAddress sends further requests to the address at index next, unless a concurrent
+// request already moved the client away from the failed address. It reports whether the
+// client no longer uses the failed address.
+func (c *Client) switchAddress(failed string, next int, trigger string) bool {
+	f := c.addresses
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c.client.Address() != failed {
+		return true
+	}
+	if err := c.client.SetAddress(f.addresses[next]); err != nil {
+		return false
+	}
+	f.current = next
+	metrics.VaultAddressFailovers.WithLabelValues(trigger).Inc()
+	return true
+}
+
+// failover moves the client from the failed address to the next one in the list. It reports
+// whether the request should be retried on the new address.
+func (c *Client) failover(failed, trigger string) bool {
+	f := c.addresses
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	next := (f.current + 1) % len(f.addresses)
+	f.mu.Unlock()
+	return c.switchAddress(failed, next, trigger)
+}
+
+// healthFailover checks the sys/health of the other addresses in list order and moves the
+// client from the failed address to the first one whose node is initialized and unsealed. It
+// reports whether the client moved.
+func (c *Client) healthFailover(ctx context.Context, failed string) bool {
+	f := c.addresses
+	if f == nil {
+		return false
+	}
+	f.mu.Lock()
+	current := f.current
+	f.mu.Unlock()
+
+	for i := 1; i < len(f.addresses); i++ {
+		next := (current + i) % len(f.addresses)
+		client, err := c.client.CloneWithHeaders()
+		if err != nil || client.SetAddress(f.addresses[next]) != nil {
+			continue
+		}
+		client.ClearToken()
+		health, err := client.Sys().HealthWithContext(ctx)
+		if err != nil || !health.Initialized || health.Sealed {
+			continue
+		}
+		return c.switchAddress(failed, next, failoverTriggerHealth)
+	}
+	return false
+}
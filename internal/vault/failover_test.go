@@ -0,0 +1,108 @@
+package vault
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// newFailoverTestClient returns a client for an unreachable address followed by two servers.
+func newFailoverTestClient(t *testing.T) (*Client, *httptest.Server, *replicaTestServer, *replicaTestServer) {
+	t.Helper()
+	unreachable := httptest.NewServer(&replicaTestServer{})
+	unreachable.Close()
+	first := &replicaTestServer{name: "first"}
+	second := &replicaTestServer{name: "second"}
+	firstServer := httptest.NewServer(first)
+	t.Cleanup(firstServer.Close)
+	secondServer := httptest.NewServer(second)
+	t.Cleanup(secondServer.Close)
+
+	client, err := NewUnauthenticatedClient(Config{
+		Address:   unreachable.URL + ", " + firstServer.URL + "," + secondServer.URL,
+		Role:      "operator",
+		AuthPath:  "kubernetes",
+		JWTSource: staticJWTSource("jwt"),
+	})
+	if err != nil {
+		t.Fatalf("NewUnauthenticatedClient() error = %v", err)
+	}
+	// Avoid waiting out the HTTP client's retries of failing requests
+	client.client.SetMaxRetries(0)
+	return client, firstServer, first, second
+}
+
+func TestAddressFailover(t *testing.T) {
+	ctx := context.Background()
+	client, firstServer, _, second := newFailoverTestClient(t)
+	logins := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerLogin))
+	requests := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerRequest))
+
+	// The login moves the client past the unreachable address
+	data, err := client.ReadSecret(ctx, "kv/app")
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if data["server"] != "first" {
+		t.Errorf("read served by %v, expected the first reachable address", data["server"])
+	}
+	if got := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerLogin)); got != logins+1 {
+		t.Errorf("login failovers = %v, expected %v", got, logins+1)
+	}
+
+	// A request that gets no response is retried on the next address
+	firstServer.Close()
+	if err := client.WriteSecret(ctx, "kv/app", map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if second.writes.Load() != 1 {
+		t.Errorf("writes = %d, expected the write to fail over to the second address", second.writes.Load())
+	}
+	if got := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerRequest)); got != requests+1 {
+		t.Errorf("request failovers = %v, expected %v", got, requests+1)
+	}
+
+	// Error responses come from Vault itself and are not failed over
+	second.failing.Store(true)
+	if err := client.WriteSecret(ctx, "kv/app", map[string]interface{}{"key": "value"}); err == nil {
+		t.Fatalf("expected WriteSecret() to return the error response")
+	}
+	if got := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerRequest)); got != requests+1 {
+		t.Errorf("request failovers = %v, expected %v", got, requests+1)
+	}
+}
+
+func TestAddressHealthFailover(t *testing.T) {
+	ctx := context.Background()
+	client, _, first, second := newFailoverTestClient(t)
+	failovers := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerHealth))
+
+	// The unreachable address is skipped for the first healthy one
+	if state, err := client.State(ctx); err != nil || state != StateActive {
+		t.Fatalf("State() = %v, %v, expected the first healthy address to be active", state, err)
+	}
+	if first.health.Load() != 2 || second.health.Load() != 0 {
+		t.Errorf("health checks first=%d second=%d, expected the first reachable address", first.health.Load(), second.health.Load())
+	}
+	if got := testutil.ToFloat64(metrics.VaultAddressFailovers.WithLabelValues(failoverTriggerHealth)); got != failovers+1 {
+		t.Errorf("health failovers = %v, expected %v", got, failovers+1)
+	}
+	if data, err := client.ReadSecret(ctx, "kv/app"); err != nil || data["server"] != "first" {
+		t.Errorf("ReadSecret() = %v, %v, expected requests to follow the health check", data, err)
+	}
+}
+
+func TestConfigAddresses(t *testing.T) {
+	cfg := Config{Address: " https://vault-0.vault-internal:8200,, https://vault.example.com "}
+	addresses := cfg.Addresses()
+	if len(addresses) != 2 || addresses[0] != "https://vault-0.vault-internal:8200" || addresses[1] != "https://vault.example.com" {
+		t.Errorf("Addresses() = %q", addresses)
+	}
+	if newAddressFailover(addresses[:1]) != nil {
+		t.Errorf("expected no failover for a single address")
+	}
+}
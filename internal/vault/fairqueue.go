@@ -0,0 +1,157 @@
+package vault
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// sourceNamespaceKey is the context key of the Kubernetes namespace a request is made for.
+type sourceNamespaceKey struct{}
+
+// WithSourceNamespace returns a context whose Vault requests are queued for the rate limiter
+// as requests of the given Kubernetes namespace.
+func WithSourceNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, sourceNamespaceKey{}, namespace)
+}
+
+// SourceNamespace returns the Kubernetes namespace requests made with ctx are queued for,
+// "" for requests of the operator itself.
+func SourceNamespace(ctx context.Context) string {
+	namespace, _ := ctx.Value(sourceNamespaceKey{}).(string)
+	return namespace
+}
+
+// fairQueue hands out the tokens of the rate limiter round-robin between the namespaces with
+// waiting requests, oldest request first within a namespace. A namespace with thousands of
+// pending syncs gets one token per turn like any other, so it cannot starve the rest; when
+// only one namespace is waiting it gets every token. The zero value is ready to use.
+type fairQueue struct {
+	mu sync.Mutex
+	// waiters holds the requests waiting in each namespace
+	waiters map[string][]chan struct{}
+	// order lists the namespaces with waiting requests in round-robin order
+	order []string
+	// next is the index in order of the namespace whose turn is next
+	next int
+	// dispatching is set while a goroutine hands out tokens
+	dispatching bool
+	// waiting counts the requests in waiters
+	waiting int
+}
+
+// Wait blocks until the request of namespace is granted a token of limiter or ctx is done.
+func (q *fairQueue) Wait(ctx context.Context, limiter *rate.Limiter, namespace string) error {
+	ready := make(chan struct{})
+
+	q.mu.Lock()
+	if q.waiters == nil {
+		q.waiters = make(map[string][]chan struct{})
+	}
+	if len(q.waiters[namespace]) == 0 {
+		q.order = append(q.order, namespace)
+	}
+	q.waiters[namespace] = append(q.waiters[namespace], ready)
+	q.waiting++
+	if !q.dispatching {
+		q.dispatching = true
+		go q.dispatch(limiter)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if !q.remove(namespace, ready) {
+			// The token was granted as the context ended
+			return nil
+		}
+		return ctx.Err()
+	}
+}
+
+// dispatch waits for tokens of limiter and grants them while requests are waiting.
+func (q *fairQueue) dispatch(limiter *rate.Limiter) {
+	for {
+		q.mu.Lock()
+		if q.waiting == 0 {
+			q.dispatching = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+
+		// The namespace is chosen once the token is available, so namespaces that start
+		// waiting in the meantime get their turn
+		if err := limiter.Wait(context.Background()); err != nil {
+			// The limiter can never grant a token; let the requests through unlimited
+			// rather than blocking them forever
+			q.mu.Lock()
+			for q.waiting > 0 {
+				q.grant()
+			}
+			q.mu.Unlock()
+			continue
+		}
+
+		q.mu.Lock()
+		if q.waiting > 0 {
+			q.grant()
+		}
+		q.mu.Unlock()
+	}
+}
+
+// grant hands a token to the oldest request of the namespace whose turn is next. Callers
+// hold mu and ensure a request is waiting.
+func (q *fairQueue) grant() {
+	if q.next >= len(q.order) {
+		q.next = 0
+	}
+	namespace := q.order[q.next]
+	waiters := q.waiters[namespace]
+	close(waiters[0])
+	q.waiting--
+	if len(waiters) == 1 {
+		delete(q.waiters, namespace)
+		q.order = slices.Delete(q.order, q.next, q.next+1)
+		return
+	}
+	q.waiters[namespace] = waiters[1:]
+	q.next++
+}
+
+// remove drops a request that is no longer waiting and reports whether it was still queued.
+// Callers hold mu.
+func (q *fairQueue) remove(namespace string, ready chan struct{}) bool {
+	waiters := q.waiters[namespace]
+	i := slices.Index(waiters, ready)
+	if i < 0 {
+		return false
+	}
+	q.waiting--
+	if len(waiters) > 1 {
+		q.waiters[namespace] = slices.Delete(waiters, i, i+1)
+		return true
+	}
+	delete(q.waiters, namespace)
+	position := slices.Index(q.order, namespace)
+	q.order = slices.Delete(q.order, position, position+1)
+	if position < q.next {
+		q.next--
+	}
+	return true
+}
+
+// share returns the number of requests waiting in namespace, in all namespaces, and the
+// number of namespaces with waiting requests.
+func (q *fairQueue) share(namespace string) (waiting, total, namespaces int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiters[namespace]), q.waiting, len(q.order)
+}
@@ -0,0 +1,126 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// queueRequests adds waiting requests to q without a dispatcher and returns their channels.
+func queueRequests(q *fairQueue, namespace string, n int) []chan struct{} {
+	if q.waiters == nil {
+		q.waiters = make(map[string][]chan struct{})
+	}
+	var queued []chan struct{}
+	for i := 0; i < n; i++ {
+		ready := make(chan struct{})
+		if len(q.waiters[namespace]) == 0 {
+			q.order = append(q.order, namespace)
+		}
+		q.waiters[namespace] = append(q.waiters[namespace], ready)
+		q.waiting++
+		queued = append(queued, ready)
+	}
+	return queued
+}
+
+func isGranted(ready chan struct{}) bool {
+	select {
+	case <-ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestFairQueueRoundRobin(t *testing.T) {
+	q := &fairQueue{}
+	noisy := queueRequests(q, "noisy", 3)
+	quiet := queueRequests(q, "quiet", 1)
+
+	// The quiet namespace gets the second token instead of waiting behind the noisy one
+	expected := []chan struct{}{noisy[0], quiet[0], noisy[1], noisy[2]}
+	for i, ready := range expected {
+		q.grant()
+		if !isGranted(ready) {
+			t.Fatalf("grant %d went to the wrong request", i)
+		}
+	}
+	if q.waiting != 0 || len(q.order) != 0 || len(q.waiters) != 0 {
+		t.Errorf("queue not empty after all grants: waiting=%d order=%v", q.waiting, q.order)
+	}
+}
+
+func TestFairQueueRemove(t *testing.T) {
+	q := &fairQueue{}
+	first := queueRequests(q, "a", 1)
+	second := queueRequests(q, "b", 2)
+	third := queueRequests(q, "c", 1)
+
+	// a and b take their turns, then the remaining request of b leaves the queue
+	q.grant()
+	q.grant()
+	if !isGranted(first[0]) || !isGranted(second[0]) {
+		t.Fatal("expected the requests of a and b to be granted first")
+	}
+	if !q.remove("b", second[1]) || q.remove("b", second[1]) {
+		t.Error("remove() should report a queued request once")
+	}
+	q.grant()
+	if !isGranted(third[0]) {
+		t.Error("expected c to be granted after b left the queue")
+	}
+	if waiting, total, namespaces := q.share("c"); waiting != 0 || total != 0 || namespaces != 0 {
+		t.Errorf("share() = %d, %d, %d, expected an empty queue", waiting, total, namespaces)
+	}
+}
+
+func TestFairQueueWait(t *testing.T) {
+	q := &fairQueue{}
+	for i := 0; i < 5; i++ {
+		if err := q.Wait(context.Background(), rate.NewLimiter(rate.Inf, 1), "default"); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+
+	// A request whose context ends leaves the queue
+	limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+	limiter.Allow()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Wait(ctx, limiter, "default"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, expected the context deadline", err)
+	}
+	if _, total, _ := q.share("default"); total != 0 {
+		t.Errorf("%d requests still queued after the context ended", total)
+	}
+}
+
+func TestNamespaceBackpressureDelay(t *testing.T) {
+	c := &Client{
+		rateLimiter:        rate.NewLimiter(rate.Limit(10), 20),
+		maxPendingRequests: 4,
+	}
+	queueRequests(&c.fairness, "noisy", 4)
+	queueRequests(&c.fairness, "quiet", 1)
+	c.pendingRequests.Store(5)
+
+	// Only the namespace holding more than its share of the saturated queue is deferred
+	if _, saturated := c.NamespaceBackpressureDelayAt("noisy", 1); !saturated {
+		t.Error("expected the noisy namespace to be deferred")
+	}
+	if _, saturated := c.NamespaceBackpressureDelayAt("quiet", 1); saturated {
+		t.Error("expected the quiet namespace to be admitted")
+	}
+	if _, saturated := c.NamespaceBackpressureDelayAt("other", 1); saturated {
+		t.Error("expected a namespace without waiting requests to be admitted")
+	}
+
+	c.pendingRequests.Store(1)
+	if _, saturated := c.NamespaceBackpressureDelayAt("noisy", 1); saturated {
+		t.Error("expected no backpressure below the threshold")
+	}
+}
@@ -3,30 +3,107 @@ package vault
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
 )
 
-// HealthCheck performs a health check against the Vault connection.
-func (c *Client) HealthCheck(ctx context.Context) error {
-	// Create a timeout context for the health check
-	healthCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+// State describes the availability of the Vault server as reported by sys/health.
+type State string
+
+// Vault server states.
+const (
+	StateActive        State = "active"
+	StateStandby       State = "standby"
+	StateSealed        State = "sealed"
+	StateUninitialized State = "uninitialized"
+	StateDown          State = "down"
+)
+
+// allStates lists every state so the state gauge can be reset on each transition.
+var allStates = []State{StateActive, StateStandby, StateSealed, StateUninitialized, StateDown}
+
+// State queries sys/health and classifies the Vault server state. The result is cached
+// so that IsSealed can be checked cheaply by the controllers. With a performance replica
+// configured its health is checked instead, unless it fails or a write found the primary sealed.
+// With several Vault addresses, a health check that gets no response moves the client to the
+// first other address whose node is initialized and unsealed.
+func (c *Client) State(ctx context.Context) (State, error) {
+	stateCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	// Check if we can reach Vault's health endpoint
-	resp, err := c.client.Logical().ReadRawWithContext(healthCtx, "sys/health")
+	if !c.IsSealed() {
+		if state, ok := c.replicaState(stateCtx); ok {
+			c.setState(state)
+			return state, nil
+		}
+	}
+
+	var state State
+	address := c.client.Address()
+	health, err := c.client.Sys().HealthWithContext(stateCtx)
+	if err != nil && c.healthFailover(stateCtx, address) {
+		health, err = c.client.Sys().HealthWithContext(stateCtx)
+	}
+	switch {
+	case err != nil:
+		state = StateDown
+	case !health.Initialized:
+		state = StateUninitialized
+	case health.Sealed:
+		state = StateSealed
+	case health.Standby:
+		state = StateStandby
+	default:
+		state = StateActive
+	}
+
+	c.setState(state)
+	if err != nil {
+		return state, fmt.Errorf("vault health request failed: %w", err)
+	}
+	return state, nil
+}
+
+// setState records the latest observed state and publishes it as a metric.
+func (c *Client) setState(state State) {
+	sealed := state == StateSealed
+	if c.sealed.Swap(sealed) != sealed && sealed {
+		c.sealedSince.Store(time.Now().UnixNano())
+	}
+	for _, s := range allStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		metrics.VaultState.WithLabelValues(string(s)).Set(value)
+	}
+}
+
+// IsSealed reports whether Vault was sealed the last time its state was observed.
+func (c *Client) IsSealed() bool {
+	return c.sealed.Load()
+}
+
+// SealedSince returns when Vault was first observed sealed in the current sealed period, or
+// the zero time when it was last observed unsealed. Each sealed period has its own time.
+func (c *Client) SealedSince() time.Time {
+	if !c.sealed.Load() {
+		return time.Time{}
+	}
+	return time.Unix(0, c.sealedSince.Load())
+}
+
+// HealthCheck performs a health check against the Vault connection.
+func (c *Client) HealthCheck(ctx context.Context) error {
+	state, err := c.State(ctx)
 	if err != nil {
 		return fmt.Errorf("vault health check failed: %w", err)
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	// Check if Vault is sealed or in standby (both are ok for health check)
-	if resp.StatusCode != http.StatusOK &&
-		resp.StatusCode != http.StatusTooManyRequests && // standby
-		resp.StatusCode != http.StatusServiceUnavailable { // sealed
-		return fmt.Errorf("vault health check returned unexpected status: %d", resp.StatusCode)
+	// Sealed and standby are ok for the health check - the operator holds writes while sealed
+	if state == StateUninitialized {
+		return fmt.Errorf("vault health check returned unexpected state: %s", state)
 	}
 
 	return nil
@@ -39,9 +116,9 @@ func (c *Client) ReadinessCheck(ctx context.Context) error {
 		return err
 	}
 
-	// Check if we have a valid token
-	if c.client.Token() == "" {
-		return fmt.Errorf("vault client not authenticated")
+	// Log in if no request has done so yet
+	if err := c.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("vault client not authenticated: %w", err)
 	}
 
 	// Try to read our own token info to verify authentication works
@@ -55,3 +132,16 @@ func (c *Client) ReadinessCheck(ctx context.Context) error {
 
 	return nil
 }
+
+// TokenTTL returns the remaining lifetime of the client token as reported by
+// auth/token/lookup-self. Tokens that never expire report zero.
+func (c *Client) TokenTTL(ctx context.Context) (time.Duration, error) {
+	lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	secret, err := c.client.Auth().Token().LookupSelfWithContext(lookupCtx)
+	if err != nil {
+		return 0, fmt.Errorf("vault token lookup failed: %w", err)
+	}
+	return secret.TokenTTL()
+}
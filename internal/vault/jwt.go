@@ -0,0 +1,86 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultServiceAccountTokenPath is the service account token mounted into every pod.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token" //nolint:gosec // This is a standard Kubernetes file path, not a credential
+
+// DefaultTokenRequestTTL is the lifetime requested for tokens minted with the TokenRequest API.
+// The API server does not issue tokens valid for less than ten minutes.
+const DefaultTokenRequestTTL = 10 * time.Minute
+
+// jwtTimeout bounds obtaining a JWT for a single login.
+const jwtTimeout = 30 * time.Second
+
+// JWTSource supplies the service account JWT presented to the Kubernetes auth method.
+type JWTSource interface {
+	JWT(ctx context.Context) (string, error)
+}
+
+// FileJWTSource reads the JWT from a mounted or projected token file.
+type FileJWTSource struct {
+	Path string
+}
+
+// JWT implements JWTSource.
+func (s FileJWTSource) JWT(_ context.Context) (string, error) {
+	jwt, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	return string(jwt), nil
+}
+
+// TokenRequestJWTSource mints a short-lived service account token bound to an audience with
+// the TokenRequest API for every login, so no long-lived token has to be mounted and the
+// token is only accepted by the Vault role configured for that audience.
+type TokenRequestJWTSource struct {
+	// Client creates the serviceaccounts/token subresource
+	Client client.Client
+	// Namespace and ServiceAccount name the account the token is issued for
+	Namespace      string
+	ServiceAccount string
+	// Audience must match the audience of the Vault Kubernetes auth role
+	Audience string
+	// TTL is the requested token lifetime (DefaultTokenRequestTTL when zero)
+	TTL time.Duration
+}
+
+// JWT implements JWTSource.
+func (s *TokenRequestJWTSource) JWT(ctx context.Context) (string, error) {
+	if s.ServiceAccount == "" {
+		return "", errors.New("no service account configured for token requests")
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = DefaultTokenRequestTTL
+	}
+	expirationSeconds := int64(ttl.Seconds())
+
+	serviceAccount := &corev1.ServiceAccount{}
+	serviceAccount.Name = s.ServiceAccount
+	serviceAccount.Namespace = s.Namespace
+	request := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			Audiences:         []string{s.Audience},
+			ExpirationSeconds: &expirationSeconds,
+		},
+	}
+	if err := s.Client.SubResource("token").Create(ctx, serviceAccount, request); err != nil {
+		return "", fmt.Errorf("failed to request token for service account %s/%s: %w", s.Namespace, s.ServiceAccount, err)
+	}
+	if request.Status.Token == "" {
+		return "", fmt.Errorf("token request for service account %s/%s returned no token", s.Namespace, s.ServiceAccount)
+	}
+	return request.Status.Token, nil
+}
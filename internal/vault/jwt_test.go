@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestFileJWTSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("mounted-token"), 0o600); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+
+	jwt, err := FileJWTSource{Path: path}.JWT(context.Background())
+	if err != nil || jwt != "mounted-token" {
+		t.Errorf("JWT() = %q, %v, expected mounted-token", jwt, err)
+	}
+
+	if _, err := (FileJWTSource{Path: filepath.Join(t.TempDir(), "missing")}).JWT(context.Background()); err == nil {
+		t.Error("JWT() of a missing file succeeded")
+	}
+}
+
+func TestTokenRequestJWTSource(t *testing.T) {
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "vault-sync"}}
+
+	var requested *authenticationv1.TokenRequest
+	k8sClient := fake.NewClientBuilder().WithObjects(serviceAccount).WithInterceptorFuncs(interceptor.Funcs{
+		SubResourceCreate: func(ctx context.Context, c client.Client, subResourceName string, obj, subResource client.Object, opts ...client.SubResourceCreateOption) error {
+			requested = subResource.(*authenticationv1.TokenRequest).DeepCopy()
+			return c.SubResource(subResourceName).Create(ctx, obj, subResource, opts...)
+		},
+	}).Build()
+
+	source := &TokenRequestJWTSource{
+		Client:         k8sClient,
+		Namespace:      "vault-sync",
+		ServiceAccount: "operator",
+		Audience:       "vault",
+	}
+	jwt, err := source.JWT(context.Background())
+	if err != nil {
+		t.Fatalf("JWT() error = %v", err)
+	}
+	if jwt == "" {
+		t.Error("JWT() returned an empty token")
+	}
+	if len(requested.Spec.Audiences) != 1 || requested.Spec.Audiences[0] != "vault" {
+		t.Errorf("requested audiences = %v, expected [vault]", requested.Spec.Audiences)
+	}
+	if requested.Spec.ExpirationSeconds == nil || *requested.Spec.ExpirationSeconds != int64(DefaultTokenRequestTTL.Seconds()) {
+		t.Errorf("requested expiration = %v, expected %v", requested.Spec.ExpirationSeconds, DefaultTokenRequestTTL)
+	}
+
+	source.TTL = time.Hour
+	if _, err := source.JWT(context.Background()); err != nil {
+		t.Fatalf("JWT() error = %v", err)
+	}
+	if *requested.Spec.ExpirationSeconds != 3600 {
+		t.Errorf("requested expiration = %d, expected 3600", *requested.Spec.ExpirationSeconds)
+	}
+
+	source.ServiceAccount = "missing"
+	if _, err := source.JWT(context.Background()); err == nil {
+		t.Error("JWT() for a missing service account succeeded")
+	}
+}
+
+// staticJWTSource returns a fixed JWT.
+type staticJWTSource string
+
+func (s staticJWTSource) JWT(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestAuthenticateWithJWTSource(t *testing.T) {
+	var login map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/kubernetes/login" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&login)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]interface{}{"client_token": "vault-token"}})
+	}))
+	defer server.Close()
+
+	client, err := NewClientFromConfig(Config{
+		Address:   server.URL,
+		Role:      "operator",
+		AuthPath:  "kubernetes",
+		JWTSource: staticJWTSource("bound-token"),
+	})
+	if err != nil {
+		t.Fatalf("NewClientFromConfig() error = %v", err)
+	}
+	if login["jwt"] != "bound-token" || login["role"] != "operator" {
+		t.Errorf("login request = %v, expected the JWT of the source", login)
+	}
+	if client.client.Token() != "vault-token" {
+		t.Errorf("client token = %q, expected vault-token", client.client.Token())
+	}
+}
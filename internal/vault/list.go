@@ -0,0 +1,65 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ListSecrets returns the keys under path as listed by Vault: the names of the secrets
+// directly under it, and of the folders under it with a trailing slash. KV v2 paths are
+// listed through the metadata endpoint, whether or not they include the data/ segment, so
+// secrets whose current version is deleted are listed too. A path with nothing under it
+// yields no keys and no error.
+func (c *Client) ListSecrets(ctx context.Context, path string) ([]string, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	mount, err := c.mountForPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	listPath := kvListPath(mount, path)
+
+	var secret *api.Secret
+	err = c.readPreferReplica(ctx, "list", func(client *api.Client) (err error) {
+		secret, err = client.Logical().ListWithContext(ctx, listPath)
+		return err
+	})
+	if err != nil {
+		err = c.requestError(err)
+		return nil, fmt.Errorf("failed to list secrets in vault at path %s: %w", listPath, err)
+	}
+	if secret == nil {
+		return nil, nil
+	}
+
+	values, _ := secret.Data["keys"].([]interface{})
+	keys := make([]string, 0, len(values))
+	for _, value := range values {
+		if key, ok := value.(string); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// kvListPath returns the path that lists the keys under path on mount. KV v1 paths are
+// listed where they are written, KV v2 paths through the metadata endpoint.
+func kvListPath(mount kvMount, path string) string {
+	if mount.version != 2 || !strings.HasPrefix(path, mount.path) {
+		return path
+	}
+	return kvMetadataPath(mount, path)
+}
@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+func TestKVListPath(t *testing.T) {
+	v1 := kvMount{path: "kv1/", version: 1}
+	v2 := kvMount{path: "secret/", version: 2}
+	tests := []struct {
+		name     string
+		mount    kvMount
+		path     string
+		expected string
+	}{
+		{name: "v2 data path", mount: v2, path: "secret/data/payments", expected: "secret/metadata/payments"},
+		{name: "v2 path without data", mount: v2, path: "secret/payments/", expected: "secret/metadata/payments/"},
+		{name: "v2 metadata path", mount: v2, path: "secret/metadata/payments", expected: "secret/metadata/payments"},
+		{name: "v1 path", mount: v1, path: "kv1/payments", expected: "kv1/payments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kvListPath(tt.mount, tt.path); got != tt.expected {
+				t.Errorf("kvListPath() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	var listed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv1/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "kv1/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+			})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case r.URL.Query().Get("list") != "true" && r.Method != "LIST":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case path == "secret/metadata/payments", path == "kv1/payments":
+			listed = append(listed, path)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"keys": []string{"gateway", "billing/", "api"}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	expected := []string{"api", "billing/", "gateway"}
+	for _, path := range []string{"secret/data/payments", "kv1/payments"} {
+		keys, err := c.ListSecrets(context.Background(), path)
+		if err != nil || !slices.Equal(keys, expected) {
+			t.Errorf("ListSecrets(%q) = %v, %v, expected %v", path, keys, err, expected)
+		}
+	}
+	if !slices.Equal(listed, []string{"secret/metadata/payments", "kv1/payments"}) {
+		t.Errorf("listed paths = %v, expected the metadata path on KV v2", listed)
+	}
+
+	if keys, err := c.ListSecrets(context.Background(), "secret/data/missing"); err != nil || len(keys) != 0 {
+		t.Errorf("ListSecrets() of an empty path = %v, %v, expected no keys", keys, err)
+	}
+}
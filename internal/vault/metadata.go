@@ -0,0 +1,241 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// KVMetadata holds the KV v2 metadata settings of a secret. Nil fields are left as they are.
+type KVMetadata struct {
+	// MaxVersions is the number of versions kept; 0 uses the mount's setting
+	MaxVersions *int
+	// DeleteVersionAfter deletes versions older than this; 0 keeps them
+	DeleteVersionAfter *time.Duration
+	// CustomMetadata entries are added to the secret's custom_metadata, keeping other entries
+	CustomMetadata map[string]string
+	// RemoveCustomMetadata lists the custom_metadata entries removed, keeping other entries
+	RemoveCustomMetadata []string
+}
+
+// IsEmpty reports whether no setting is given.
+func (m KVMetadata) IsEmpty() bool {
+	return m.MaxVersions == nil && m.DeleteVersionAfter == nil && len(m.CustomMetadata) == 0 && len(m.RemoveCustomMetadata) == 0
+}
+
+// EnsureSecretMetadata applies the KV v2 metadata settings of the secret at path through
+// the metadata endpoint, writing only when the settings in Vault differ. It reports
+// whether the metadata was updated. The path's mount must be a KV v2 mount.
+func (c *Client) EnsureSecretMetadata(ctx context.Context, path string, md KVMetadata) (bool, error) {
+	if md.IsEmpty() {
+		return false, nil
+	}
+
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return false, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return false, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	mount, err := c.mountForPath(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	if mount.version != 2 {
+		// Custom metadata from the operator-wide allowlist is best effort on KV v1 mounts
+		if md.MaxVersions == nil && md.DeleteVersionAfter == nil {
+			return false, nil
+		}
+		return false, fmt.Errorf("secret metadata requires a KV v2 mount, %s is served by %s", path, mount.path)
+	}
+	metadataPath := kvMetadataPath(mount, path)
+
+	client := c.namespacedClient(ctx)
+	current, err := client.Logical().ReadWithContext(ctx, metadataPath)
+	if err != nil {
+		err = c.requestError(err)
+		return false, fmt.Errorf("failed to read secret metadata from vault at path %s: %w", metadataPath, err)
+	}
+	var currentData map[string]interface{}
+	if current != nil {
+		currentData = current.Data
+	}
+	update := metadataUpdate(currentData, md)
+	if len(update) == 0 {
+		return false, nil
+	}
+
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return false, fmt.Errorf("rate limiter error: %w", err)
+	}
+	if _, err := client.Logical().WriteWithContext(ctx, metadataPath, update); err != nil {
+		err = c.requestError(err)
+		return false, fmt.Errorf("failed to write secret metadata to vault at path %s: %w", metadataPath, err)
+	}
+	return true, nil
+}
+
+// SecretCustomMetadata returns the custom_metadata of the secret at path. Secrets that do
+// not exist yet, secrets whose current version is deleted or destroyed and paths on KV v1
+// mounts, which have no metadata, have none, so metadata describing content that is no
+// longer readable is not relied on.
+func (c *Client) SecretCustomMetadata(ctx context.Context, path string) (map[string]string, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	mount, err := c.mountForPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if mount.version != 2 {
+		return nil, nil
+	}
+	metadataPath := kvMetadataPath(mount, path)
+
+	var current *api.Secret
+	err = c.readPreferReplica(ctx, "metadata", func(client *api.Client) (err error) {
+		current, err = client.Logical().ReadWithContext(ctx, metadataPath)
+		return err
+	})
+	if err != nil {
+		err = c.requestError(err)
+		return nil, fmt.Errorf("failed to read secret metadata from vault at path %s: %w", metadataPath, err)
+	}
+	if current == nil || !currentVersionLive(current.Data) {
+		return nil, nil
+	}
+	custom, _ := current.Data["custom_metadata"].(map[string]interface{})
+	result := make(map[string]string, len(custom))
+	for key, value := range custom {
+		if value, ok := value.(string); ok {
+			result[key] = value
+		}
+	}
+	return result, nil
+}
+
+// DestroySecret removes the secret at path with all of its versions and metadata through the
+// metadata endpoint, so that unlike DeleteSecret no version can be undeleted or read back.
+// Secrets on KV v1 mounts, which keep no versions, are deleted.
+func (c *Client) DestroySecret(ctx context.Context, path string) error {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	mount, err := c.mountForPath(ctx, path)
+	if err != nil {
+		return err
+	}
+	destroyPath := path
+	if mount.version == 2 {
+		destroyPath = kvMetadataPath(mount, path)
+	}
+	err = c.retryOnDenied(ctx, func(client *api.Client) error {
+		_, err := client.Logical().DeleteWithContext(ctx, destroyPath)
+		return err
+	})
+	if err != nil {
+		err = c.requestError(err)
+		return fmt.Errorf("failed to destroy secret in vault at path %s: %w", path, err)
+	}
+	return nil
+}
+
+// currentVersionLive reports whether the current version in a metadata response is neither
+// deleted nor destroyed.
+func currentVersionLive(metadata map[string]interface{}) bool {
+	versions, _ := metadata["versions"].(map[string]interface{})
+	version, _ := versions[fmt.Sprint(metadata["current_version"])].(map[string]interface{})
+	if version == nil {
+		return false
+	}
+	deletionTime, _ := version["deletion_time"].(string)
+	destroyed, _ := version["destroyed"].(bool)
+	return deletionTime == "" && !destroyed
+}
+
+// metadataUpdate returns the fields of md that differ from the current metadata, in the
+// format of the metadata endpoint.
+func metadataUpdate(current map[string]interface{}, md KVMetadata) map[string]interface{} {
+	update := make(map[string]interface{})
+
+	if md.MaxVersions != nil {
+		var currentMax int64 = -1
+		switch value := current["max_versions"].(type) {
+		case json.Number:
+			currentMax, _ = value.Int64()
+		case float64:
+			currentMax = int64(value)
+		}
+		if currentMax != int64(*md.MaxVersions) {
+			update["max_versions"] = *md.MaxVersions
+		}
+	}
+
+	if md.DeleteVersionAfter != nil {
+		currentAfter, err := time.ParseDuration(fmt.Sprint(current["delete_version_after"]))
+		if err != nil || currentAfter != *md.DeleteVersionAfter {
+			update["delete_version_after"] = md.DeleteVersionAfter.String()
+		}
+	}
+
+	if len(md.CustomMetadata) > 0 || len(md.RemoveCustomMetadata) > 0 {
+		// Vault replaces custom_metadata as a whole, so entries set by others are written back
+		currentCustom, _ := current["custom_metadata"].(map[string]interface{})
+		merged := make(map[string]interface{}, len(currentCustom)+len(md.CustomMetadata))
+		for key, value := range currentCustom {
+			merged[key] = value
+		}
+		changed := false
+		for key, value := range md.CustomMetadata {
+			if currentCustom[key] != value {
+				merged[key] = value
+				changed = true
+			}
+		}
+		for _, key := range md.RemoveCustomMetadata {
+			if _, exists := merged[key]; exists {
+				delete(merged, key)
+				changed = true
+			}
+		}
+		if changed {
+			update["custom_metadata"] = merged
+		}
+	}
+
+	return update
+}
+
+// kvMetadataPath returns the metadata endpoint of the secret at path on a KV v2 mount,
+// whether or not the configured path includes the data/ segment.
+func kvMetadataPath(mount kvMount, path string) string {
+	rest := strings.TrimPrefix(path, mount.path)
+	if after, ok := strings.CutPrefix(rest, "data/"); ok {
+		rest = after
+	} else if after, ok := strings.CutPrefix(rest, "metadata/"); ok {
+		rest = after
+	}
+	return mount.path + "metadata/" + rest
+}
@@ -0,0 +1,229 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+	"k8s.io/utils/ptr"
+)
+
+func TestKVMetadataPath(t *testing.T) {
+	v2 := kvMount{path: "secret/", version: 2}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "secret/app/db", expected: "secret/metadata/app/db"},
+		{path: "secret/data/app/db", expected: "secret/metadata/app/db"},
+		{path: "secret/metadata/app/db", expected: "secret/metadata/app/db"},
+	}
+
+	for _, tt := range tests {
+		if result := kvMetadataPath(v2, tt.path); result != tt.expected {
+			t.Errorf("kvMetadataPath(%s) = %v, expected %v", tt.path, result, tt.expected)
+		}
+	}
+}
+
+func TestEnsureSecretMetadata(t *testing.T) {
+	metadata := map[string]interface{}{"max_versions": 0, "delete_version_after": "0s"}
+	var writes []map[string]interface{}
+	var writePaths []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case r.Method == http.MethodGet && path == "secret/metadata/payments/gateway":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": metadata})
+		case r.Method == http.MethodPut || r.Method == http.MethodPost:
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writes = append(writes, body)
+			writePaths = append(writePaths, path)
+			for key, value := range body {
+				metadata[key] = value
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	md := KVMetadata{MaxVersions: ptr.To(5), DeleteVersionAfter: ptr.To(30 * 24 * time.Hour)}
+	updated, err := c.EnsureSecretMetadata(context.Background(), "secret/data/payments/gateway", md)
+	if err != nil || !updated {
+		t.Fatalf("EnsureSecretMetadata() = %v, %v, expected an update", updated, err)
+	}
+	if len(writes) != 1 || writePaths[0] != "secret/metadata/payments/gateway" {
+		t.Fatalf("metadata writes = %v to %v, expected one write to the metadata endpoint", writes, writePaths)
+	}
+	if writes[0]["max_versions"] != float64(5) || writes[0]["delete_version_after"] != "720h0m0s" {
+		t.Errorf("metadata write = %v", writes[0])
+	}
+
+	// Matching settings are not written again
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", md)
+	if err != nil || updated {
+		t.Errorf("EnsureSecretMetadata() = %v, %v, expected no update", updated, err)
+	}
+
+	// Only the given settings are written
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", KVMetadata{MaxVersions: ptr.To(10)})
+	if err != nil || !updated {
+		t.Fatalf("EnsureSecretMetadata() = %v, %v, expected an update", updated, err)
+	}
+	if _, ok := writes[1]["delete_version_after"]; ok || writes[1]["max_versions"] != float64(10) {
+		t.Errorf("metadata write = %v, expected only max_versions", writes[1])
+	}
+
+	// Custom metadata is merged with the entries already in Vault
+	metadata["custom_metadata"] = map[string]interface{}{"owner": "platform", "team": "payments"}
+	custom := KVMetadata{CustomMetadata: map[string]string{"team": "checkout", "cost-center": "cc-42"}}
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", custom)
+	if err != nil || !updated {
+		t.Fatalf("EnsureSecretMetadata() = %v, %v, expected an update", updated, err)
+	}
+	written, _ := writes[2]["custom_metadata"].(map[string]interface{})
+	if len(writes[2]) != 1 || len(written) != 3 || written["owner"] != "platform" || written["team"] != "checkout" || written["cost-center"] != "cc-42" {
+		t.Errorf("metadata write = %v, expected the merged custom metadata", writes[2])
+	}
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", custom)
+	if err != nil || updated {
+		t.Errorf("EnsureSecretMetadata() = %v, %v, expected no update", updated, err)
+	}
+
+	// Removed entries are dropped, keeping the others, and absent ones need no write
+	remove := KVMetadata{RemoveCustomMetadata: []string{"cost-center", "missing"}}
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", remove)
+	if err != nil || !updated {
+		t.Fatalf("EnsureSecretMetadata() = %v, %v, expected an update", updated, err)
+	}
+	written, _ = writes[3]["custom_metadata"].(map[string]interface{})
+	if len(written) != 2 || written["owner"] != "platform" || written["team"] != "checkout" {
+		t.Errorf("metadata write = %v, expected the custom metadata without cost-center", writes[3])
+	}
+	updated, err = c.EnsureSecretMetadata(context.Background(), "secret/payments/gateway", remove)
+	if err != nil || updated {
+		t.Errorf("EnsureSecretMetadata() = %v, %v, expected no update", updated, err)
+	}
+}
+
+func TestSecretCustomMetadata(t *testing.T) {
+	metadata := map[string]interface{}{
+		"current_version": 2,
+		"custom_metadata": map[string]interface{}{"vault-sync-content-hash": "abc"},
+		"versions": map[string]interface{}{
+			"1": map[string]interface{}{"deletion_time": "", "destroyed": false},
+			"2": map[string]interface{}{"deletion_time": "", "destroyed": false},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case path == "secret/metadata/payments/gateway":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": metadata})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	custom, err := c.SecretCustomMetadata(context.Background(), "secret/data/payments/gateway")
+	if err != nil || custom["vault-sync-content-hash"] != "abc" {
+		t.Errorf("SecretCustomMetadata() = %v, %v, expected the content hash", custom, err)
+	}
+
+	// Missing secrets have no custom metadata
+	custom, err = c.SecretCustomMetadata(context.Background(), "secret/payments/missing")
+	if err != nil || custom != nil {
+		t.Errorf("SecretCustomMetadata() of a missing secret = %v, %v", custom, err)
+	}
+
+	// Custom metadata of a deleted current version is ignored
+	metadata["versions"].(map[string]interface{})["2"] = map[string]interface{}{"deletion_time": "2026-01-02T03:04:05Z", "destroyed": false}
+	custom, err = c.SecretCustomMetadata(context.Background(), "secret/payments/gateway")
+	if err != nil || custom != nil {
+		t.Errorf("SecretCustomMetadata() of a deleted secret = %v, %v", custom, err)
+	}
+}
+
+func TestDestroySecret(t *testing.T) {
+	var deletes []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv1/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "kv1/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+			})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case r.Method == http.MethodDelete:
+			deletes = append(deletes, path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	// KV v2 secrets are removed through the metadata endpoint, KV v1 secrets are deleted
+	for _, path := range []string{"secret/data/payments/gateway.vault-sync-staging", "kv1/payments/gateway"} {
+		if err := c.DestroySecret(context.Background(), path); err != nil {
+			t.Fatalf("DestroySecret(%s) error = %v", path, err)
+		}
+	}
+	expected := []string{"secret/metadata/payments/gateway.vault-sync-staging", "kv1/payments/gateway"}
+	if strings.Join(deletes, ",") != strings.Join(expected, ",") {
+		t.Errorf("deletes = %v, expected %v", deletes, expected)
+	}
+}
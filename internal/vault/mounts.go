@@ -0,0 +1,116 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// kvMount describes the secrets engine mount serving a path.
+type kvMount struct {
+	// path is the mount path including its trailing slash, e.g. "secret/"
+	path string
+	// version is the KV engine version, or 0 when the mount is not a KV engine
+	version int
+}
+
+// mountCache remembers the mounts already detected, so each mount is looked up once. Mount
+// paths are relative to a Vault namespace, so mounts are cached per namespace, with "" for
+// the client's own.
+type mountCache struct {
+	mu     sync.RWMutex
+	mounts map[string][]kvMount
+}
+
+// lookup returns the cached mount serving path in namespace.
+func (m *mountCache) lookup(namespace, path string) (kvMount, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, mount := range m.mounts[namespace] {
+		if strings.HasPrefix(path, mount.path) {
+			return mount, true
+		}
+	}
+	return kvMount{}, false
+}
+
+// add caches mount of namespace, keeping longer mount paths first so nested mounts win.
+func (m *mountCache) add(namespace string, mount kvMount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mounts := m.mounts[namespace]
+	for _, existing := range mounts {
+		if existing.path == mount.path {
+			return
+		}
+	}
+	mounts = append(mounts, mount)
+	for i := len(mounts) - 1; i > 0 && len(mounts[i].path) > len(mounts[i-1].path); i-- {
+		mounts[i], mounts[i-1] = mounts[i-1], mounts[i]
+	}
+	if m.mounts == nil {
+		m.mounts = make(map[string][]kvMount)
+	}
+	m.mounts[namespace] = mounts
+}
+
+// mountForPath returns the mount serving path, asking Vault on first use. The
+// sys/internal/ui/mounts endpoint is usable by any token with a capability on the path.
+// The mount is looked up in the Vault namespace mapped for ctx.
+func (c *Client) mountForPath(ctx context.Context, path string) (kvMount, error) {
+	namespace, _ := c.requestNamespace(ctx)
+	if mount, ok := c.mounts.lookup(namespace, path); ok {
+		return mount, nil
+	}
+
+	secret, err := c.namespacedClient(ctx).Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+path)
+	if err != nil {
+		return kvMount{}, fmt.Errorf("failed to detect mount for path %s: %w", path, err)
+	}
+	if secret == nil || secret.Data == nil {
+		return kvMount{}, fmt.Errorf("no mount found for path %s", path)
+	}
+
+	mount, err := parseMountInfo(secret.Data)
+	if err != nil {
+		return kvMount{}, err
+	}
+	c.mounts.add(namespace, mount)
+	return mount, nil
+}
+
+// parseMountInfo parses a sys/internal/ui/mounts response.
+func parseMountInfo(data map[string]interface{}) (kvMount, error) {
+	mountPath, _ := data["path"].(string)
+	if mountPath == "" {
+		return kvMount{}, fmt.Errorf("mount response has no path")
+	}
+
+	mount := kvMount{path: mountPath}
+	switch data["type"] {
+	case "kv", "generic":
+		mount.version = 1
+		if options, ok := data["options"].(map[string]interface{}); ok && options["version"] == "2" {
+			mount.version = 2
+		}
+	}
+	return mount, nil
+}
+
+// kvDeletePath returns the path that deletes the secret at path on mount. KV v1 secrets
+// are deleted where they were written. KV v2 deletes go through the data/ endpoint,
+// which is inserted after the mount when the configured path omits it.
+func kvDeletePath(mount kvMount, path string) string {
+	if mount.version != 2 || !strings.HasPrefix(path, mount.path) {
+		return path
+	}
+
+	rest := strings.TrimPrefix(path, mount.path)
+	if strings.HasPrefix(rest, "data/") || strings.HasPrefix(rest, "metadata/") {
+		return path
+	}
+	return mount.path + "data/" + rest
+}
@@ -0,0 +1,253 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+func TestParseMountInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     map[string]interface{}
+		expected kvMount
+		wantErr  bool
+	}{
+		{
+			name:     "kv v2",
+			data:     map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			expected: kvMount{path: "secret/", version: 2},
+		},
+		{
+			name:     "kv v1",
+			data:     map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+			expected: kvMount{path: "secret/", version: 1},
+		},
+		{
+			name:     "kv without options",
+			data:     map[string]interface{}{"path": "legacy/", "type": "generic"},
+			expected: kvMount{path: "legacy/", version: 1},
+		},
+		{
+			name:     "other engine",
+			data:     map[string]interface{}{"path": "transit/", "type": "transit"},
+			expected: kvMount{path: "transit/"},
+		},
+		{
+			name:    "missing path",
+			data:    map[string]interface{}{"type": "kv"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mount, err := parseMountInfo(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMountInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if mount != tt.expected {
+				t.Errorf("parseMountInfo() = %+v, expected %+v", mount, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKVDeletePath(t *testing.T) {
+	v1 := kvMount{path: "secret/", version: 1}
+	v2 := kvMount{path: "secret/", version: 2}
+
+	tests := []struct {
+		name     string
+		mount    kvMount
+		path     string
+		expected string
+	}{
+		{name: "kv v1 keeps path", mount: v1, path: "secret/foo", expected: "secret/foo"},
+		{name: "kv v1 data-like path", mount: v1, path: "secret/data/foo", expected: "secret/data/foo"},
+		{name: "kv v2 inserts data", mount: v2, path: "secret/foo", expected: "secret/data/foo"},
+		{name: "kv v2 data path", mount: v2, path: "secret/data/foo", expected: "secret/data/foo"},
+		{name: "kv v2 metadata path", mount: v2, path: "secret/metadata/foo", expected: "secret/metadata/foo"},
+		{name: "kv v2 nested mount", mount: kvMount{path: "team/kv/", version: 2}, path: "team/kv/app/db", expected: "team/kv/data/app/db"},
+		{name: "non-kv mount", mount: kvMount{path: "transit/"}, path: "transit/keys/foo", expected: "transit/keys/foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := kvDeletePath(tt.mount, tt.path); result != tt.expected {
+				t.Errorf("kvDeletePath() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// fakeKVServer emulates the Vault endpoints used to delete secrets from a single KV mount.
+type fakeKVServer struct {
+	mountPath string
+	version   string
+
+	mu      sync.Mutex
+	lookups int
+	deletes []string
+	writes  map[string]map[string]interface{}
+}
+
+func (s *fakeKVServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+		s.lookups++
+		if s.version == "" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"path":    s.mountPath,
+				"type":    "kv",
+				"options": map[string]interface{}{"version": s.version},
+			},
+		})
+	case r.Method == http.MethodDelete:
+		s.deletes = append(s.deletes, path)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut || r.Method == http.MethodPost:
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if s.writes == nil {
+			s.writes = make(map[string]map[string]interface{})
+		}
+		s.writes[path] = body
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestDeleteSecretMountTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		paths    []string
+		expected []string
+	}{
+		{
+			name:     "kv v1 mount named secret",
+			version:  "1",
+			paths:    []string{"secret/foo", "secret/app/db"},
+			expected: []string{"secret/foo", "secret/app/db"},
+		},
+		{
+			name:     "kv v2 mount",
+			version:  "2",
+			paths:    []string{"secret/foo", "secret/data/app/db"},
+			expected: []string{"secret/data/foo", "secret/data/app/db"},
+		},
+		{
+			name:     "mount detection denied",
+			version:  "",
+			paths:    []string{"secret/foo"},
+			expected: []string{"secret/data/foo"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeKVServer{mountPath: "secret/", version: tt.version}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			config := api.DefaultConfig()
+			config.Address = httpServer.URL
+			config.MaxRetries = 0
+			apiClient, err := api.NewClient(config)
+			if err != nil {
+				t.Fatalf("api.NewClient() error = %v", err)
+			}
+			apiClient.SetToken("test-token")
+
+			c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+			for _, path := range tt.paths {
+				if err := c.DeleteSecret(context.Background(), path); err != nil {
+					t.Fatalf("DeleteSecret(%s) error = %v", path, err)
+				}
+			}
+
+			if strings.Join(server.deletes, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("deleted paths = %v, expected %v", server.deletes, tt.expected)
+			}
+			if tt.version != "" && server.lookups != 1 {
+				t.Errorf("mount lookups = %d, expected 1", server.lookups)
+			}
+		})
+	}
+}
+
+func TestWriteSecretMountTypes(t *testing.T) {
+	data := map[string]interface{}{"password": "p"}
+	tests := []struct {
+		name      string
+		mountPath string
+		version   string
+		path      string
+		expected  map[string]interface{}
+	}{
+		{
+			name:      "kv v2 mount not named secret",
+			mountPath: "kv-payments/",
+			version:   "2",
+			path:      "kv-payments/data/app",
+			expected:  map[string]interface{}{"data": data},
+		},
+		{
+			name:      "kv v1 mount named secret",
+			mountPath: "secret/",
+			version:   "1",
+			path:      "secret/data/app",
+			expected:  data,
+		},
+		{
+			name:      "mount detection denied",
+			mountPath: "secret/",
+			path:      "secret/data/app",
+			expected:  map[string]interface{}{"data": data},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeKVServer{mountPath: tt.mountPath, version: tt.version}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			config := api.DefaultConfig()
+			config.Address = httpServer.URL
+			config.MaxRetries = 0
+			apiClient, err := api.NewClient(config)
+			if err != nil {
+				t.Fatalf("api.NewClient() error = %v", err)
+			}
+			apiClient.SetToken("test-token")
+
+			c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+			if err := c.WriteSecret(context.Background(), tt.path, data); err != nil {
+				t.Fatalf("WriteSecret(%s) error = %v", tt.path, err)
+			}
+			if got := server.writes[tt.path]; !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("body written to %s = %v, expected %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
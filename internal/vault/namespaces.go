@@ -0,0 +1,34 @@
+package vault
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// requestNamespace returns the Vault namespace mapped to the Kubernetes namespace requests made
+// with ctx are for, see Config.NamespaceMapping. It reports false for unmapped namespaces,
+// whose requests use the client's namespace.
+func (c *Client) requestNamespace(ctx context.Context) (string, bool) {
+	if len(c.namespaceMapping) == 0 {
+		return "", false
+	}
+	namespace, ok := c.namespaceMapping[SourceNamespace(ctx)]
+	return namespace, ok
+}
+
+// namespacedClient returns the API client for requests made with ctx that need no request
+// client of their own: the shared client, or a copy of it sending the mapped Vault namespace.
+func (c *Client) namespacedClient(ctx context.Context) *api.Client {
+	if namespace, ok := c.requestNamespace(ctx); ok {
+		return c.client.WithNamespace(namespace)
+	}
+	return c.client
+}
+
+// setRequestNamespace makes client send the Vault namespace mapped for ctx, if any.
+func (c *Client) setRequestNamespace(ctx context.Context, client *api.Client) {
+	if namespace, ok := c.requestNamespace(ctx); ok {
+		client.SetNamespace(namespace)
+	}
+}
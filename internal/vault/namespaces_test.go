@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+func TestNamespaceMapping(t *testing.T) {
+	var mu sync.Mutex
+	namespaces := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		namespace := r.Header.Get("X-Vault-Namespace")
+		if strings.HasPrefix(path, "sys/internal/ui/mounts/") {
+			// Both namespaces have a secret/ mount of a different KV version
+			version := "2"
+			if namespace == "admin/finance" {
+				version = "1"
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": version}},
+			})
+			return
+		}
+		mu.Lock()
+		namespaces[path] = namespace
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	apiClient.SetNamespace("admin")
+	c := &Client{
+		client:           apiClient,
+		rateLimiter:      rate.NewLimiter(rate.Inf, 1),
+		namespaceMapping: map[string]string{"payments": "admin/finance"},
+	}
+
+	tests := []struct {
+		name      string
+		source    string
+		path      string
+		requested string
+		namespace string
+	}{
+		// The mapped namespace has a KV v1 mount, so the path is deleted as written
+		{name: "mapped namespace", source: "payments", path: "secret/payments/app", requested: "secret/payments/app", namespace: "admin/finance"},
+		{name: "unmapped namespace", source: "checkout", path: "secret/checkout/app", requested: "secret/data/checkout/app", namespace: "admin"},
+		{name: "operator request", path: "secret/operator/app", requested: "secret/data/operator/app", namespace: "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.source != "" {
+				ctx = WithSourceNamespace(ctx, tt.source)
+			}
+			if err := c.DeleteSecret(ctx, tt.path); err != nil {
+				t.Fatalf("DeleteSecret() error = %v", err)
+			}
+			mu.Lock()
+			namespace, ok := namespaces[tt.requested]
+			mu.Unlock()
+			if !ok || namespace != tt.namespace {
+				t.Errorf("namespace of %s = %q (requested %v), expected %q", tt.requested, namespace, ok, tt.namespace)
+			}
+		})
+	}
+	if _, cached := c.mounts.lookup("admin/finance", "secret/payments/app"); !cached {
+		t.Error("mount of the mapped namespace not cached separately")
+	}
+}
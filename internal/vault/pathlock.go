@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// PathSerializer serializes work on individual Vault paths. Callers waiting on the same
+// path are admitted in FIFO order, so writes to a path are applied in the order requested.
+type PathSerializer struct {
+	mu    sync.Mutex
+	paths map[string]*pathLock
+}
+
+// pathLock is the state of a single locked path.
+type pathLock struct {
+	waiters []chan struct{}
+}
+
+// Lock blocks until the caller holds the lock for path or ctx is cancelled. The returned
+// function releases the lock and must be called exactly once.
+func (s *PathSerializer) Lock(ctx context.Context, path string) (func(), error) {
+	start := time.Now()
+	defer func() {
+		metrics.PathLockWaitDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	s.mu.Lock()
+	if s.paths == nil {
+		s.paths = make(map[string]*pathLock)
+	}
+	lock, held := s.paths[path]
+	if !held {
+		s.paths[path] = &pathLock{}
+		s.mu.Unlock()
+		return func() { s.unlock(path) }, nil
+	}
+
+	ready := make(chan struct{})
+	lock.waiters = append(lock.waiters, ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return func() { s.unlock(path) }, nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		for i, waiter := range lock.waiters {
+			if waiter == ready {
+				lock.waiters = append(lock.waiters[:i], lock.waiters[i+1:]...)
+				s.mu.Unlock()
+				return nil, ctx.Err()
+			}
+		}
+		s.mu.Unlock()
+		// The lock was handed to us while cancelling - pass it on
+		s.unlock(path)
+		return nil, ctx.Err()
+	}
+}
+
+// unlock hands the lock for path to the next waiter, or releases it if nobody is waiting.
+func (s *PathSerializer) unlock(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock := s.paths[path]
+	if len(lock.waiters) == 0 {
+		delete(s.paths, path)
+		return
+	}
+
+	next := lock.waiters[0]
+	lock.waiters = lock.waiters[1:]
+	close(next)
+}
+
+// LockPath serializes work on a Vault path across all controllers sharing this client.
+// The returned function releases the lock.
+func (c *Client) LockPath(ctx context.Context, path string) (func(), error) {
+	return c.paths.Lock(ctx, path)
+}
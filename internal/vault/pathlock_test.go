@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPathSerializerOrdering(t *testing.T) {
+	var s PathSerializer
+	ctx := context.Background()
+
+	unlock, err := s.Lock(ctx, "secret/data/app")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	// A different path must not block
+	otherUnlock, err := s.Lock(ctx, "secret/data/other")
+	if err != nil {
+		t.Fatalf("Lock() on a different path error = %v", err)
+	}
+	otherUnlock()
+
+	order := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		ready := make(chan struct{})
+		go func(i int) {
+			close(ready)
+			release, err := s.Lock(ctx, "secret/data/app")
+			if err != nil {
+				t.Errorf("Lock() error = %v", err)
+				return
+			}
+			order <- i
+			release()
+		}(i)
+		<-ready
+		// Give the goroutine time to enqueue so waiters are registered in order
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	unlock()
+	for expected := 0; expected < 3; expected++ {
+		if got := <-order; got != expected {
+			t.Errorf("Expected waiter %d to acquire the lock next, got %d", expected, got)
+		}
+	}
+}
+
+func TestPathSerializerCancel(t *testing.T) {
+	var s PathSerializer
+
+	unlock, err := s.Lock(context.Background(), "secret/data/app")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.Lock(ctx, "secret/data/app"); err == nil {
+		t.Errorf("Expected Lock() to fail when the context is cancelled")
+	}
+
+	unlock()
+
+	// The cancelled waiter must not hold the lock
+	release, err := s.Lock(context.Background(), "secret/data/app")
+	if err != nil {
+		t.Fatalf("Lock() after cancellation error = %v", err)
+	}
+	release()
+}
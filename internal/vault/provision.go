@@ -0,0 +1,141 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// KVMount declares a KV secrets engine mount to create when it is missing.
+type KVMount struct {
+	// Path is the mount path, e.g. "kv-payments/" or "teams/payments/"
+	Path string
+	// Version is the KV engine version; 0 creates a KV v2 mount
+	Version int
+	// Description is shown in Vault's list of mounts
+	Description string
+	// MaxVersions is the number of versions kept per secret on a KV v2 mount; 0 uses Vault's default
+	MaxVersions int
+}
+
+// mountPath returns the path of the mount with a single trailing slash, as listed by sys/mounts.
+func (m KVMount) mountPath() string {
+	return strings.Trim(m.Path, "/") + "/"
+}
+
+// version returns the KV engine version of the mount.
+func (m KVMount) version() int {
+	if m.Version == 0 {
+		return 2
+	}
+	return m.Version
+}
+
+// EnsureKVMount creates the KV mount unless a secrets engine is already mounted at its path,
+// and reports whether it was created. A mount that is not a KV engine of the declared version
+// is reported as an error and left alone. The MaxVersions of KV v2 mounts, new or existing,
+// is applied whenever it differs, so a configuration that failed after the mount was created
+// is completed by the next call. The token needs read on sys/mounts and create and update on
+// sys/mounts/<path>, plus read and update on <path>/config when MaxVersions is set.
+func (c *Client) EnsureKVMount(ctx context.Context, mount KVMount) (bool, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return false, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return false, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	path := mount.mountPath()
+	namespace, _ := c.requestNamespace(ctx)
+	var mounts map[string]*api.MountOutput
+	err := c.retryOnDenied(ctx, func(client *api.Client) (err error) {
+		mounts, err = client.Sys().ListMountsWithContext(ctx)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list vault mounts: %w", err)
+	}
+	if existing, ok := mounts[path]; ok {
+		version := 0
+		if existing.Type == "kv" || existing.Type == "generic" {
+			version = 1
+			if existing.Options["version"] == "2" {
+				version = 2
+			}
+		}
+		if version != mount.version() {
+			return false, fmt.Errorf("mount %s exists as %s engine %q, expected KV v%d", path, kvVersionLabel(version), existing.Type, mount.version())
+		}
+		c.mounts.add(namespace, kvMount{path: path, version: version})
+		return false, c.configureKVMount(ctx, mount)
+	}
+
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return false, fmt.Errorf("rate limiter error: %w", err)
+	}
+	err = c.retryOnDenied(ctx, func(client *api.Client) error {
+		return client.Sys().MountWithContext(ctx, path, &api.MountInput{
+			Type:        "kv",
+			Description: mount.Description,
+			Options:     map[string]string{"version": strconv.Itoa(mount.version())},
+		})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create vault mount %s: %w", path, err)
+	}
+	c.mounts.add(namespace, kvMount{path: path, version: mount.version()})
+	return true, c.configureKVMount(ctx, mount)
+}
+
+// configureKVMount sets the MaxVersions of a KV v2 mount when it is declared and differs from
+// the mount's configuration.
+func (c *Client) configureKVMount(ctx context.Context, mount KVMount) error {
+	if mount.MaxVersions <= 0 || mount.version() != 2 {
+		return nil
+	}
+	configPath := mount.mountPath() + "config"
+
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return fmt.Errorf("rate limiter error: %w", err)
+	}
+	var current *api.Secret
+	err := c.retryOnDenied(ctx, func(client *api.Client) (err error) {
+		current, err = client.Logical().ReadWithContext(ctx, configPath)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read configuration of vault mount %s: %w", mount.mountPath(), err)
+	}
+	if current != nil {
+		if maxVersions, ok := current.Data["max_versions"].(json.Number); ok && maxVersions.String() == strconv.Itoa(mount.MaxVersions) {
+			return nil
+		}
+	}
+
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return fmt.Errorf("rate limiter error: %w", err)
+	}
+	err = c.retryOnDenied(ctx, func(client *api.Client) error {
+		_, err := client.Logical().WriteWithContext(ctx, configPath, map[string]interface{}{"max_versions": mount.MaxVersions})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure vault mount %s: %w", mount.mountPath(), err)
+	}
+	return nil
+}
+
+// kvVersionLabel describes a KV engine version for errors, 0 being another engine.
+func kvVersionLabel(version int) string {
+	if version == 0 {
+		return "a non-KV"
+	}
+	return fmt.Sprintf("a KV v%d", version)
+}
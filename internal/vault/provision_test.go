@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+// fakeMountServer emulates the Vault endpoints used to list, create and configure mounts.
+type fakeMountServer struct {
+	mu           sync.Mutex
+	mounts       map[string]map[string]interface{}
+	configs      map[string]map[string]interface{}
+	configWrites int
+}
+
+func (s *fakeMountServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case r.Method == http.MethodGet && path == "sys/mounts":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": s.mounts})
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "sys/mounts/"):
+		var input map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		delete(input, "config")
+		s.mounts[strings.TrimPrefix(path, "sys/mounts/")+"/"] = input
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/config"):
+		config, ok := s.configs[path]
+		if !ok {
+			config = map[string]interface{}{"max_versions": 0}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": config})
+	case (r.Method == http.MethodPut || r.Method == http.MethodPost) && strings.HasSuffix(path, "/config"):
+		var input map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&input)
+		s.configs[path] = input
+		s.configWrites++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestEnsureKVMount(t *testing.T) {
+	tests := []struct {
+		name        string
+		mount       KVMount
+		wantCreated bool
+		wantErr     bool
+		wantConfig  bool
+	}{
+		{name: "existing kv v2 mount", mount: KVMount{Path: "secret/"}},
+		{name: "existing kv v1 mount without options", mount: KVMount{Path: "legacy", Version: 1}},
+		{name: "missing mount", mount: KVMount{Path: "kv-payments", Description: "Payments"}, wantCreated: true},
+		{name: "missing nested mount with max versions", mount: KVMount{Path: "teams/payments/", MaxVersions: 5}, wantCreated: true, wantConfig: true},
+		{name: "existing mount with max versions", mount: KVMount{Path: "secret/", MaxVersions: 5}, wantConfig: true},
+		{name: "existing mount of another version", mount: KVMount{Path: "legacy"}, wantErr: true},
+		{name: "existing mount of another engine", mount: KVMount{Path: "transit"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &fakeMountServer{
+				mounts: map[string]map[string]interface{}{
+					"secret/":  {"type": "kv", "options": map[string]interface{}{"version": "2"}},
+					"legacy/":  {"type": "generic"},
+					"transit/": {"type": "transit"},
+				},
+				configs: map[string]map[string]interface{}{},
+			}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			config := api.DefaultConfig()
+			config.Address = httpServer.URL
+			config.MaxRetries = 0
+			apiClient, err := api.NewClient(config)
+			if err != nil {
+				t.Fatalf("api.NewClient() error = %v", err)
+			}
+			apiClient.SetToken("test-token")
+
+			c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+			created, err := c.EnsureKVMount(context.Background(), tt.mount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureKVMount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("EnsureKVMount() created = %v, expected %v", created, tt.wantCreated)
+			}
+
+			path := tt.mount.mountPath()
+			if tt.wantCreated {
+				input := server.mounts[path]
+				options, _ := input["options"].(map[string]interface{})
+				if input["type"] != "kv" || options["version"] != "2" || input["description"] != tt.mount.Description {
+					t.Errorf("created mount = %v, expected a KV v2 mount", input)
+				}
+			}
+			if _, ok := server.configs[path+"config"]; ok != tt.wantConfig {
+				t.Errorf("configured = %v, expected %v", ok, tt.wantConfig)
+			}
+			if _, cached := c.mounts.lookup("", path+"app"); cached == tt.wantErr {
+				t.Errorf("mount cached = %v, expected %v", cached, !tt.wantErr)
+			}
+
+			// A configuration that is already applied is not written again
+			if tt.wantConfig {
+				writes := server.configWrites
+				if _, err := c.EnsureKVMount(context.Background(), tt.mount); err != nil {
+					t.Fatalf("EnsureKVMount() again error = %v", err)
+				}
+				if server.configWrites != writes {
+					t.Errorf("config writes = %d, expected the applied configuration to be kept", server.configWrites-writes)
+				}
+			}
+		})
+	}
+}
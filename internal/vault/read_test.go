@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+func TestReadSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv1/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "kv1/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+			})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/apps/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "apps/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			// Tokens without access to mount detection fall back to the path
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		case path == "kv1/payments/gateway":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"password": "v1"},
+			})
+		case path == "apps/data/payments/gateway", path == "secret/data/payments/gateway":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     map[string]interface{}{"password": "v2"},
+					"metadata": map[string]interface{}{"version": 3},
+				},
+			})
+		case path == "apps/data/payments/deleted":
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{
+					"data":     nil,
+					"metadata": map[string]interface{}{"version": 2, "deletion_time": "2026-01-01T00:00:00Z"},
+				},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected map[string]interface{}
+	}{
+		{name: "KV v1", path: "kv1/payments/gateway", expected: map[string]interface{}{"password": "v1"}},
+		{name: "KV v2", path: "apps/data/payments/gateway", expected: map[string]interface{}{"password": "v2"}},
+		{name: "KV v2 without data segment", path: "apps/payments/gateway", expected: map[string]interface{}{"password": "v2"}},
+		{name: "KV v2 guessed from path", path: "secret/data/payments/gateway", expected: map[string]interface{}{"password": "v2"}},
+		{name: "deleted version", path: "apps/data/payments/deleted"},
+		{name: "missing", path: "apps/data/payments/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := c.ReadSecret(context.Background(), tt.path)
+			if err != nil {
+				t.Fatalf("ReadSecret() error = %v", err)
+			}
+			if !reflect.DeepEqual(data, tt.expected) {
+				t.Errorf("ReadSecret() = %v, expected %v", data, tt.expected)
+			}
+		})
+	}
+}
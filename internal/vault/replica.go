@@ -0,0 +1,200 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// replicaRetryInterval is how long reads and health checks go to the primary after the
+// replica failed, before the replica is tried again.
+const replicaRetryInterval = 30 * time.Second
+
+// replicaRouter sends reads and health checks to a nearby Vault Enterprise performance
+// replica, such as a performance secondary cluster, while writes go to the primary. The
+// replica gets its own token from a login against it, as a performance secondary does not
+// accept the service tokens of the primary. A replica that fails a request is skipped for
+// replicaRetryInterval. All methods are safe to call on a nil router, which sends every
+// request to the primary.
+type replicaRouter struct {
+	// client is configured like the primary client, with the replica's address
+	client *api.Client
+
+	mu       sync.Mutex
+	failedAt time.Time
+
+	// authMu serializes logins against the replica and guards its token
+	authMu      sync.Mutex
+	token       string
+	tokenIssued time.Time
+	tokenExpiry time.Time
+}
+
+// primaryReadsKey is the context key of requests whose reads must go to the primary.
+type primaryReadsKey struct{}
+
+// WithPrimaryReads returns a context whose reads go to the primary even when a replica is
+// configured, e.g. to read back a write the replica may not have received yet.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// primaryReads reports whether the reads made with ctx must go to the primary.
+func primaryReads(ctx context.Context) bool {
+	pinned, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return pinned
+}
+
+// newReplicaRouter creates a router for the replica at address, cloning the settings of primary.
+func newReplicaRouter(primary *api.Client, address string) (*replicaRouter, error) {
+	client, err := primary.CloneWithHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vault replica client: %w", err)
+	}
+	if err := client.SetAddress(address); err != nil {
+		return nil, fmt.Errorf("invalid vault replica address %q: %w", address, err)
+	}
+	client.ClearToken()
+	return &replicaRouter{client: client}, nil
+}
+
+// usable reports whether requests should be sent to the replica.
+func (r *replicaRouter) usable(now time.Time) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.failedAt.IsZero() || now.Sub(r.failedAt) >= replicaRetryInterval
+}
+
+// fail records that the replica failed a request of the given operation.
+func (r *replicaRouter) fail(operation string, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failedAt = now
+	metrics.VaultReplicaFailovers.WithLabelValues(operation).Inc()
+}
+
+// replicaRequestClient returns a copy of the replica client bound to the replica's token.
+func (c *Client) replicaRequestClient() (*api.Client, error) {
+	token, err := c.replicaToken()
+	if err != nil {
+		return nil, err
+	}
+	client, err := c.replica.client.CloneWithHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare vault replica request: %w", err)
+	}
+	client.SetToken(token)
+	return client, nil
+}
+
+// replicaToken returns the replica's token, logging in against the replica with the role and
+// auth path of the primary when there is no token yet or it is close to expiry.
+func (c *Client) replicaToken() (string, error) {
+	r := c.replica
+	r.authMu.Lock()
+	defer r.authMu.Unlock()
+	if r.token != "" && (r.tokenExpiry.IsZero() || time.Until(r.tokenExpiry) >= r.tokenExpiry.Sub(r.tokenIssued)/tokenRefreshDivisor) {
+		return r.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwtTimeout)
+	jwt, err := c.jwtSource.JWT(ctx)
+	cancel()
+	if err != nil {
+		return "", err
+	}
+	login, err := r.client.CloneWithHeaders()
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare vault replica login: %w", err)
+	}
+	login.ClearToken()
+	secret, err := login.Logical().Write(filepath.Join("auth", c.authPath, "login"), map[string]interface{}{
+		"role": c.role,
+		"jwt":  jwt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate against vault replica: %w", err)
+	}
+	if secret == nil || secret.Auth == nil {
+		return "", errors.New("vault replica authentication response was empty")
+	}
+
+	r.token = secret.Auth.ClientToken
+	r.tokenIssued = time.Now()
+	r.tokenExpiry = time.Time{}
+	if secret.Auth.LeaseDuration > 0 {
+		r.tokenExpiry = r.tokenIssued.Add(time.Duration(secret.Auth.LeaseDuration) * time.Second)
+	}
+	return r.token, nil
+}
+
+// dropToken forgets the replica's token after the replica denied it, so the next request
+// to the replica logs in again.
+func (r *replicaRouter) dropToken(token string) {
+	r.authMu.Lock()
+	defer r.authMu.Unlock()
+	if r.token == token {
+		r.token = ""
+	}
+}
+
+// replicaFailed reports whether err means the replica could not serve a request the primary
+// may serve: it is unreachable, sealed, throttling, or does not accept the token. Invalid
+// requests fail on the primary as well.
+func replicaFailed(err error) bool {
+	switch StatusCode(err) {
+	case http.StatusBadRequest, http.StatusNotFound:
+		return false
+	default:
+		return true
+	}
+}
+
+// readPreferReplica runs the read op on the replica when one is configured, has not failed
+// recently and ctx does not pin reads to the primary, and on the primary otherwise or when
+// the replica fails.
+func (c *Client) readPreferReplica(ctx context.Context, operation string, op func(client *api.Client) error) error {
+	if !primaryReads(ctx) && c.replica.usable(time.Now()) {
+		client, err := c.replicaRequestClient()
+		if err == nil {
+			c.setRequestNamespace(ctx, client)
+			err = op(client)
+			if err == nil || !replicaFailed(err) {
+				return err
+			}
+			if IsPermissionDenied(err) {
+				c.replica.dropToken(client.Token())
+			}
+		}
+		c.replica.fail(operation, time.Now())
+	}
+	return c.retryOnDenied(ctx, op)
+}
+
+// replicaState queries the replica's sys/health. It reports false when the replica is not
+// configured, has failed recently, or is not serving requests, so the primary is asked instead.
+func (c *Client) replicaState(ctx context.Context) (State, bool) {
+	if !c.replica.usable(time.Now()) {
+		return "", false
+	}
+	health, err := c.replica.client.Sys().HealthWithContext(ctx)
+	if err != nil || !health.Initialized || health.Sealed {
+		c.replica.fail("health", time.Now())
+		return "", false
+	}
+	if health.Standby {
+		return StateStandby, true
+	}
+	return StateActive, true
+}
@@ -0,0 +1,164 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// replicaTestServer is a Vault server answering logins, health checks, mount lookups of its
+// KV v1 kv/ mount, reads and writes, counting the requests it serves but mount lookups. It
+// only accepts the tokens it issued. Failing servers answer 503 to everything but logins.
+type replicaTestServer struct {
+	name    string
+	failing atomic.Bool
+	logins  atomic.Int32
+	reads   atomic.Int32
+	writes  atomic.Int32
+	health  atomic.Int32
+}
+
+func (s *replicaTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/auth/kubernetes/login":
+		s.logins.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": s.name + "-token", "lease_duration": 60},
+		})
+	case s.failing.Load():
+		http.Error(w, `{"errors":["unavailable"]}`, http.StatusServiceUnavailable)
+	case r.URL.Path != "/v1/sys/health" && r.Header.Get("X-Vault-Token") != s.name+"-token":
+		http.Error(w, `{"errors":["permission denied","invalid token"]}`, http.StatusForbidden)
+	case r.URL.Path == "/v1/sys/health":
+		s.health.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"initialized": true, "sealed": false, "standby": false})
+	case strings.HasPrefix(r.URL.Path, "/v1/sys/internal/ui/mounts/"):
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"path": "kv/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+		})
+	case r.Method == http.MethodGet:
+		s.reads.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"server": s.name}})
+	default:
+		s.writes.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newReplicaTestClient(t *testing.T) (*Client, *replicaTestServer, *replicaTestServer) {
+	t.Helper()
+	primary := &replicaTestServer{name: "primary"}
+	replica := &replicaTestServer{name: "replica"}
+	primaryServer := httptest.NewServer(primary)
+	t.Cleanup(primaryServer.Close)
+	replicaServer := httptest.NewServer(replica)
+	t.Cleanup(replicaServer.Close)
+
+	client, err := NewClientFromConfig(Config{
+		Address:        primaryServer.URL,
+		ReplicaAddress: replicaServer.URL,
+		Role:           "operator",
+		AuthPath:       "kubernetes",
+		JWTSource:      staticJWTSource("jwt"),
+	})
+	if err != nil {
+		t.Fatalf("NewClientFromConfig() error = %v", err)
+	}
+	// Avoid waiting out the HTTP client's retries of failing requests
+	client.client.SetMaxRetries(0)
+	client.replica.client.SetMaxRetries(0)
+	return client, primary, replica
+}
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	client, primary, replica := newReplicaTestClient(t)
+
+	data, err := client.ReadSecret(ctx, "kv/app")
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if data["server"] != "replica" {
+		t.Errorf("read served by %v, expected the replica", data["server"])
+	}
+	if primary.logins.Load() != 1 || replica.logins.Load() != 1 {
+		t.Errorf("logins primary=%d replica=%d, expected one login on each", primary.logins.Load(), replica.logins.Load())
+	}
+	if data, _ := client.ReadSecret(WithPrimaryReads(ctx), "kv/app"); data["server"] != "primary" {
+		t.Errorf("pinned read served by %v, expected the primary", data["server"])
+	}
+	if err := client.WriteSecret(ctx, "kv/app", map[string]interface{}{"key": "value"}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if primary.writes.Load() != 1 || replica.writes.Load() != 0 {
+		t.Errorf("writes primary=%d replica=%d, expected writes to go to the primary", primary.writes.Load(), replica.writes.Load())
+	}
+	if state, err := client.State(ctx); err != nil || state != StateActive {
+		t.Fatalf("State() = %v, %v", state, err)
+	}
+	if primary.health.Load() != 0 || replica.health.Load() != 1 {
+		t.Errorf("health checks primary=%d replica=%d, expected the replica to be checked", primary.health.Load(), replica.health.Load())
+	}
+}
+
+func TestReplicaFailover(t *testing.T) {
+	ctx := context.Background()
+	client, primary, replica := newReplicaTestClient(t)
+	failovers := testutil.ToFloat64(metrics.VaultReplicaFailovers.WithLabelValues("read"))
+
+	replica.failing.Store(true)
+	data, err := client.ReadSecret(ctx, "kv/app")
+	if err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if data["server"] != "primary" {
+		t.Errorf("read served by %v, expected failover to the primary", data["server"])
+	}
+	if got := testutil.ToFloat64(metrics.VaultReplicaFailovers.WithLabelValues("read")); got != failovers+1 {
+		t.Errorf("failovers = %v, expected %v", got, failovers+1)
+	}
+
+	// The failed replica is skipped until the retry interval has passed
+	replica.failing.Store(false)
+	if _, err := client.ReadSecret(ctx, "kv/app"); err != nil {
+		t.Fatalf("ReadSecret() error = %v", err)
+	}
+	if _, err := client.State(ctx); err != nil {
+		t.Fatalf("State() error = %v", err)
+	}
+	if replica.reads.Load() != 0 || primary.reads.Load() != 2 || primary.health.Load() != 1 {
+		t.Errorf("replica reads=%d primary reads=%d primary health=%d, expected the primary while the replica is skipped",
+			replica.reads.Load(), primary.reads.Load(), primary.health.Load())
+	}
+
+	client.replica.mu.Lock()
+	client.replica.failedAt = time.Now().Add(-replicaRetryInterval)
+	client.replica.mu.Unlock()
+	if data, _ := client.ReadSecret(ctx, "kv/app"); data["server"] != "replica" {
+		t.Errorf("read served by %v, expected the replica to be used again", data["server"])
+	}
+}
+
+func TestReplicaSkippedWhilePrimarySealed(t *testing.T) {
+	client, primary, replica := newReplicaTestClient(t)
+
+	client.setState(StateSealed)
+	if _, err := client.State(context.Background()); err != nil {
+		t.Fatalf("State() error = %v", err)
+	}
+	if primary.health.Load() != 1 || replica.health.Load() != 0 {
+		t.Errorf("health checks primary=%d replica=%d, expected the primary to be checked while sealed", primary.health.Load(), replica.health.Load())
+	}
+	if client.IsSealed() {
+		t.Errorf("expected the unsealed primary to end the hold")
+	}
+}
@@ -0,0 +1,42 @@
+package vault
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// DefaultSelfTestPath is the scratch path written and removed by the self-test.
+const DefaultSelfTestPath = "secret/data/vault-sync-operator/self-test"
+
+// selfTestKey is the key holding the nonce written by the self-test.
+const selfTestKey = "nonce"
+
+// SelfTest performs an end-to-end check of the operator's Vault access: it writes a random
+// nonce to path, reads it back and deletes it again. Authentication is verified when the
+// client is created, so a client that exists has already passed that step.
+func (c *Client) SelfTest(ctx context.Context, path string) error {
+	nonceBytes := make([]byte, 16)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return fmt.Errorf("self-test: failed to generate nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(nonceBytes)
+
+	if err := c.WriteSecret(ctx, path, map[string]interface{}{selfTestKey: nonce}); err != nil {
+		return fmt.Errorf("self-test write failed: %w", err)
+	}
+
+	data, err := c.ReadSecret(ctx, path)
+	if err != nil {
+		return fmt.Errorf("self-test read-back failed: %w", err)
+	}
+	if data[selfTestKey] != nonce {
+		return fmt.Errorf("self-test read-back failed: value at %s does not match what was written", path)
+	}
+
+	if err := c.DeleteSecret(ctx, path); err != nil {
+		return fmt.Errorf("self-test delete failed: %w", err)
+	}
+	return nil
+}
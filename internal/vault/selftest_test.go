@@ -0,0 +1,97 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+// memoryKVv2Server emulates a KV v2 mount at secret/ that stores secrets in memory.
+type memoryKVv2Server struct {
+	mu      sync.Mutex
+	secrets map[string]interface{}
+	// corrupt makes reads return a different value than was written
+	corrupt bool
+}
+
+func (s *memoryKVv2Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	switch {
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+		})
+	case r.Method == http.MethodPut || r.Method == http.MethodPost:
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.secrets[path] = body["data"]
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet:
+		data, ok := s.secrets[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		if s.corrupt {
+			data = map[string]interface{}{selfTestKey: "something else"}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data}})
+	case r.Method == http.MethodDelete:
+		delete(s.secrets, path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		corrupt bool
+		wantErr string
+	}{
+		{name: "write, read back and delete"},
+		{name: "read-back mismatch", corrupt: true, wantErr: "self-test read-back failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &memoryKVv2Server{secrets: make(map[string]interface{}), corrupt: tt.corrupt}
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			config := api.DefaultConfig()
+			config.Address = httpServer.URL
+			config.MaxRetries = 0
+			apiClient, err := api.NewClient(config)
+			if err != nil {
+				t.Fatalf("api.NewClient() error = %v", err)
+			}
+			apiClient.SetToken("test-token")
+			c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+			err = c.SelfTest(context.Background(), DefaultSelfTestPath)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("SelfTest() error = %v", err)
+				}
+				if len(server.secrets) != 0 {
+					t.Errorf("self-test left secrets behind: %v", server.secrets)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SelfTest() error = %v, expected %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package vault
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Backoff between authentication attempts while waiting for Vault at startup.
+var (
+	startupAuthInitialDelay = time.Second
+	startupAuthMaxDelay     = 30 * time.Second
+)
+
+// Authenticated reports whether the client holds a token from a successful login.
+func (c *Client) Authenticated() bool {
+	return c.client.Token() != ""
+}
+
+// AuthenticateWithRetry logs in with the Kubernetes auth method, retrying with exponential
+// backoff until a login succeeds or ctx is done. It returns the last login error when ctx
+// ends first, and returns at once when the client is already authenticated.
+func (c *Client) AuthenticateWithRetry(ctx context.Context, log logr.Logger) error {
+	backoff := wait.Backoff{
+		Duration: startupAuthInitialDelay,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    10, // Delays stay at the cap once it is reached
+		Cap:      startupAuthMaxDelay,
+	}
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if c.Authenticated() {
+			return nil
+		}
+		c.authMu.Lock()
+		lastErr = c.login(authTriggerStartup)
+		c.authMu.Unlock()
+		if lastErr == nil {
+			log.Info("authenticated with vault", "attempts", attempt)
+			return nil
+		}
+
+		delay := backoff.Step()
+		log.Error(lastErr, "vault authentication failed, retrying", "attempt", attempt, "retry_in", delay.String())
+		select {
+		case <-ctx.Done():
+			return lastErr
+		case <-time.After(delay):
+		}
+	}
+}
+
+// BackgroundLogin keeps retrying the login of a client that could not authenticate at
+// startup, so the operator stays alive while Vault is unavailable and becomes ready as soon
+// as a login succeeds. It implements manager.Runnable and runs on every replica.
+type BackgroundLogin struct {
+	Client *Client
+	Log    logr.Logger
+}
+
+// Start retries the login until it succeeds or ctx is done. Giving up at shutdown is not
+// an error.
+func (b *BackgroundLogin) Start(ctx context.Context) error {
+	_ = b.Client.AuthenticateWithRetry(ctx, b.Log)
+	return nil
+}
+
+// NeedLeaderElection lets standby replicas authenticate too, so they report ready.
+func (b *BackgroundLogin) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestAuthenticateWithRetry(t *testing.T) {
+	startupAuthInitialDelay, startupAuthMaxDelay = time.Millisecond, 5*time.Millisecond
+	defer func() { startupAuthInitialDelay, startupAuthMaxDelay = time.Second, 30*time.Second }()
+
+	// Vault rejects the first two logins, as while it is still starting
+	var logins atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if logins.Add(1) <= 2 {
+			http.Error(w, `{"errors":["service unavailable"]}`, http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]interface{}{"client_token": "vault-token"}})
+	}))
+	defer server.Close()
+
+	client, err := NewUnauthenticatedClient(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+	if err != nil {
+		t.Fatalf("NewUnauthenticatedClient() error = %v", err)
+	}
+	if client.Authenticated() {
+		t.Fatal("a new unauthenticated client reports a token")
+	}
+
+	if err := client.AuthenticateWithRetry(context.Background(), logr.Discard()); err != nil {
+		t.Fatalf("AuthenticateWithRetry() error = %v", err)
+	}
+	if !client.Authenticated() || logins.Load() != 3 {
+		t.Errorf("authenticated = %v after %d logins, expected a token after 3 logins", client.Authenticated(), logins.Load())
+	}
+
+	// An authenticated client does not log in again
+	if err := client.AuthenticateWithRetry(context.Background(), logr.Discard()); err != nil || logins.Load() != 3 {
+		t.Errorf("AuthenticateWithRetry() = %v after %d logins, expected no new login", err, logins.Load())
+	}
+}
+
+func TestAuthenticateWithRetryTimeout(t *testing.T) {
+	startupAuthInitialDelay, startupAuthMaxDelay = time.Millisecond, 5*time.Millisecond
+	defer func() { startupAuthInitialDelay, startupAuthMaxDelay = time.Second, 30*time.Second }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":["service unavailable"]}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := NewUnauthenticatedClient(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+	if err != nil {
+		t.Fatalf("NewUnauthenticatedClient() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.AuthenticateWithRetry(ctx, logr.Discard()); err == nil {
+		t.Error("AuthenticateWithRetry() succeeded while vault rejects every login")
+	}
+	if client.Authenticated() {
+		t.Error("client reports a token after failed logins")
+	}
+}
+
+func TestLazyAuthentication(t *testing.T) {
+	authFailureCooldown = 200 * time.Millisecond
+	defer func() { authFailureCooldown = 5 * time.Second }()
+
+	// Vault rejects logins until it is up
+	var up atomic.Bool
+	var logins, writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			logins.Add(1)
+			if !up.Load() {
+				http.Error(w, `{"errors":["role not found"]}`, http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]interface{}{"client_token": "vault-token"}})
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "vault-token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		if r.Method == http.MethodGet {
+			// Mount lookups find no mount, so writes fall back to the path
+			http.Error(w, `{"errors":["no mount"]}`, http.StatusNotFound)
+			return
+		}
+		writes.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewUnauthenticatedClient(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+	if err != nil {
+		t.Fatalf("NewUnauthenticatedClient() error = %v", err)
+	}
+	if logins.Load() != 0 {
+		t.Fatalf("creating the client logged in %d times, expected no login", logins.Load())
+	}
+
+	// A failed login fails the request, and is reused by requests within the cooldown
+	data := map[string]interface{}{"password": "s3cr3t"}
+	for i := 0; i < 3; i++ {
+		if err := client.WriteSecret(context.Background(), "kv/app", data); err == nil {
+			t.Fatal("WriteSecret() succeeded while vault rejects logins")
+		}
+	}
+	if logins.Load() != 1 {
+		t.Errorf("%d logins for 3 requests within the cooldown, expected 1", logins.Load())
+	}
+
+	// Once vault is up, the next request after the cooldown logs in
+	up.Store(true)
+	time.Sleep(250 * time.Millisecond)
+	if err := client.WriteSecret(context.Background(), "kv/app", data); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if err := client.WriteSecret(context.Background(), "kv/app", data); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if logins.Load() != 2 || writes.Load() != 2 {
+		t.Errorf("%d logins and %d writes, expected 2 logins and 2 writes", logins.Load(), writes.Load())
+	}
+}
@@ -0,0 +1,67 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SecretSubkeys returns the top-level keys of the current version of the secret at path from
+// the KV v2 subkeys endpoint, which returns the structure of a secret without its values, so
+// shape checks neither transfer nor hold secret values. It returns no keys for secrets that do
+// not exist, paths on KV v1 mounts, and Vault versions or policies without access to the
+// endpoint; callers needing certainty then read the secret instead.
+func (c *Client) SecretSubkeys(ctx context.Context, path string) ([]string, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return nil, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return nil, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	mount, err := c.mountForPath(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if mount.version != 2 {
+		return nil, nil
+	}
+	subkeysPath := kvSubkeysPath(mount, path)
+
+	// The keys verify writes that a replica may not have received yet, and a denied request
+	// means the endpoint is not granted rather than a stale token, so the primary is asked
+	// once with the current token
+	client, _, err := c.requestClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := client.Logical().ReadWithDataWithContext(ctx, subkeysPath, map[string][]string{"depth": {"1"}})
+	if err != nil {
+		// Vault before 1.10 does not serve the endpoint, and policies may not grant it
+		if code := StatusCode(err); code == http.StatusForbidden || code == http.StatusMethodNotAllowed {
+			return nil, nil
+		}
+		err = c.requestError(err)
+		return nil, fmt.Errorf("failed to read secret subkeys from vault at path %s: %w", subkeysPath, err)
+	}
+	if current == nil {
+		return nil, nil
+	}
+	subkeys, _ := current.Data["subkeys"].(map[string]interface{})
+	keys := make([]string, 0, len(subkeys))
+	for key := range subkeys {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// kvSubkeysPath returns the subkeys endpoint of the secret at path on a KV v2 mount.
+func kvSubkeysPath(mount kvMount, path string) string {
+	return mount.path + "subkeys/" + strings.TrimPrefix(kvMetadataPath(mount, path), mount.path+"metadata/")
+}
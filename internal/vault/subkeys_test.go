@@ -0,0 +1,78 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+)
+
+func TestKVSubkeysPath(t *testing.T) {
+	v2 := kvMount{path: "secret/", version: 2}
+	for path, expected := range map[string]string{
+		"secret/data/payments/gateway": "secret/subkeys/payments/gateway",
+		"secret/payments/gateway":      "secret/subkeys/payments/gateway",
+	} {
+		if got := kvSubkeysPath(v2, path); got != expected {
+			t.Errorf("kvSubkeysPath(%q) = %q, expected %q", path, got, expected)
+		}
+	}
+}
+
+func TestSecretSubkeys(t *testing.T) {
+	var depth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, "/v1/")
+		switch {
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/kv1/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "kv1/", "type": "kv", "options": map[string]interface{}{"version": "1"}},
+			})
+		case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"path": "secret/", "type": "kv", "options": map[string]interface{}{"version": "2"}},
+			})
+		case path == "secret/subkeys/payments/gateway":
+			depth = r.URL.Query().Get("depth")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"data": map[string]interface{}{"subkeys": map[string]interface{}{"password": nil, "username": nil}},
+			})
+		case path == "secret/subkeys/payments/denied":
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := api.DefaultConfig()
+	config.Address = server.URL
+	config.MaxRetries = 0
+	apiClient, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("api.NewClient() error = %v", err)
+	}
+	apiClient.SetToken("test-token")
+	c := &Client{client: apiClient, rateLimiter: rate.NewLimiter(rate.Inf, 1)}
+
+	keys, err := c.SecretSubkeys(context.Background(), "secret/data/payments/gateway")
+	if err != nil || !slices.Equal(keys, []string{"password", "username"}) {
+		t.Errorf("SecretSubkeys() = %v, %v, expected password and username", keys, err)
+	}
+	if depth != "1" {
+		t.Errorf("depth = %q, expected only the top-level keys to be requested", depth)
+	}
+
+	// Missing secrets, denied requests and KV v1 mounts have no keys
+	for _, path := range []string{"secret/data/payments/missing", "secret/data/payments/denied", "kv1/payments/gateway"} {
+		if keys, err := c.SecretSubkeys(context.Background(), path); err != nil || len(keys) != 0 {
+			t.Errorf("SecretSubkeys(%q) = %v, %v, expected no keys", path, keys, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package vault
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"golang.org/x/time/rate"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+const (
+	// throttleDefaultRetryAfter is how long requests back off after a 429 without Retry-After
+	throttleDefaultRetryAfter = 5 * time.Second
+	// throttleMaxRetryAfter caps the back-off requested by Vault
+	throttleMaxRetryAfter = 5 * time.Minute
+	// throttleMaxInlineRetry is the longest Retry-After the HTTP client waits out itself;
+	// longer ones fail the request so the reconcile is requeued instead of holding a worker
+	throttleMaxInlineRetry = 2 * time.Second
+	// throttleRecoveryInterval is how long the rate stays at each step while it recovers
+	throttleRecoveryInterval = 10 * time.Second
+	// throttleMinRate is the lowest rate the limiter is lowered to, in requests per second
+	throttleMinRate = rate.Limit(0.5)
+)
+
+// adaptiveThrottle lowers the rate of a limiter while Vault rejects requests with 429 Too
+// Many Requests, or 503 Service Unavailable with a Retry-After header. Each throttling
+// episode halves the rate and drops bursts; once the Retry-After has passed, the rate is
+// doubled every throttleRecoveryInterval until it is back at its configured value. The zero
+// value is ready to use.
+type adaptiveThrottle struct {
+	mu sync.Mutex
+	// throttled is set while the limiter runs below its base rate
+	throttled bool
+	base      rate.Limit
+	baseBurst int
+	// until is when the back-off requested by Vault ends
+	until time.Time
+	// recoverAt is when the rate is raised again
+	recoverAt time.Time
+	// shedding is when the back-off requested by the last 503 response with Retry-After ends
+	shedding time.Time
+}
+
+// observe records a throttled response that asked for requests to back off for retryAfter.
+func (t *adaptiveThrottle) observe(limiter *rate.Limiter, retryAfter time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.throttled {
+		t.throttled = true
+		t.base = limiter.Limit()
+		t.baseBurst = limiter.Burst()
+	}
+
+	// Requests in flight when Vault started throttling are rejected together; lower the rate
+	// once per episode rather than once per rejected request
+	if !now.Before(t.until) {
+		limit := limiter.Limit() / 2
+		if limit < throttleMinRate {
+			limit = throttleMinRate
+		}
+		limiter.SetLimitAt(now, limit)
+		limiter.SetBurstAt(now, 1)
+		metrics.VaultRateLimit.Set(float64(limit))
+	}
+
+	if until := now.Add(retryAfter); until.After(t.until) {
+		t.until = until
+	}
+	t.recoverAt = t.until
+}
+
+// observeShedding records a 503 response with a Retry-After of retryAfter. Requests failing
+// with 503 until then are attributed to load shedding rather than to a sealed Vault; a
+// Retry-After of less than throttleMaxInlineRetry still covers the retries waiting it out.
+func (t *adaptiveThrottle) observeShedding(retryAfter time.Duration, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until := now.Add(max(retryAfter, throttleMaxInlineRetry)); until.After(t.shedding) {
+		t.shedding = until
+	}
+}
+
+// sheddingLoad reports whether a 503 response at now follows one asking to back off.
+func (t *adaptiveThrottle) sheddingLoad(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return now.Before(t.shedding)
+}
+
+// recover raises the rate of limiter one step when the back-off has passed quietly.
+func (t *adaptiveThrottle) recover(limiter *rate.Limiter, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.throttled || now.Before(t.recoverAt) {
+		return
+	}
+	limit := limiter.Limit() * 2
+	if limit >= t.base {
+		limiter.SetLimitAt(now, t.base)
+		limiter.SetBurstAt(now, t.baseBurst)
+		metrics.VaultRateLimit.Set(float64(t.base))
+		t.throttled = false
+		return
+	}
+	limiter.SetLimitAt(now, limit)
+	metrics.VaultRateLimit.Set(float64(limit))
+	t.recoverAt = now.Add(throttleRecoveryInterval)
+}
+
+// delay returns how long the back-off requested by Vault still lasts.
+func (t *adaptiveThrottle) delay(now time.Time) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !now.Before(t.until) {
+		return 0, false
+	}
+	return t.until.Sub(now), true
+}
+
+// throttledRetryAfter reports whether resp asks for requests to back off and for how long.
+func throttledRetryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now)
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		if !ok {
+			retryAfter = throttleDefaultRetryAfter
+		}
+	case http.StatusServiceUnavailable:
+		// A sealed or standby Vault answers 503 too; only a Retry-After marks load shedding
+		if !ok {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if retryAfter > throttleMaxRetryAfter {
+		retryAfter = throttleMaxRetryAfter
+	}
+	return retryAfter, true
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if until := date.Sub(now); until > 0 {
+		return until, true
+	}
+	return 0, true
+}
+
+// checkRetry is the retry policy of the Vault HTTP client. It records throttled responses
+// before applying the default policy, and does not retry responses asking to back off longer
+// than throttleMaxInlineRetry.
+func (c *Client) checkRetry(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if retryAfter, throttled := throttledRetryAfter(resp, time.Now()); throttled {
+		metrics.VaultThrottledResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+		c.throttle.observe(c.rateLimiter, retryAfter, time.Now())
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			c.throttle.observeShedding(retryAfter, time.Now())
+		}
+		if retryAfter > throttleMaxInlineRetry {
+			return false, nil
+		}
+	}
+	return api.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// requestError classifies the error of a failed request. A 503 response is marked as load
+// shedding while Vault asks requests to back off with Retry-After, and otherwise records that
+// Vault is sealed.
+func (c *Client) requestError(err error) error {
+	if StatusCode(err) == http.StatusServiceUnavailable && c.throttle.sheddingLoad(time.Now()) {
+		return &loadSheddingError{err: err}
+	}
+	if IsSealed(err) {
+		c.setState(StateSealed)
+	}
+	return err
+}
+
+// ThrottleDelay reports whether Vault asked for requests to back off and, if so, how long
+// callers should wait before retrying.
+func (c *Client) ThrottleDelay() (time.Duration, bool) {
+	return c.throttle.delay(time.Now())
+}
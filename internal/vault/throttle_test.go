@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestThrottledRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+		want       time.Duration
+		throttled  bool
+	}{
+		{name: "429 with seconds", status: http.StatusTooManyRequests, retryAfter: "30", want: 30 * time.Second, throttled: true},
+		{name: "429 with date", status: http.StatusTooManyRequests, retryAfter: now.Add(time.Minute).Format(http.TimeFormat), want: time.Minute, throttled: true},
+		{name: "429 without header", status: http.StatusTooManyRequests, want: throttleDefaultRetryAfter, throttled: true},
+		{name: "429 with invalid header", status: http.StatusTooManyRequests, retryAfter: "soon", want: throttleDefaultRetryAfter, throttled: true},
+		{name: "429 capped", status: http.StatusTooManyRequests, retryAfter: "3600", want: throttleMaxRetryAfter, throttled: true},
+		{name: "503 with header", status: http.StatusServiceUnavailable, retryAfter: "10", want: 10 * time.Second, throttled: true},
+		{name: "503 without header is a sealed vault", status: http.StatusServiceUnavailable},
+		{name: "500 with header", status: http.StatusInternalServerError, retryAfter: "10"},
+		{name: "success", status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			got, throttled := throttledRetryAfter(resp, now)
+			if got != tt.want || throttled != tt.throttled {
+				t.Errorf("throttledRetryAfter() = %v, %v, want %v, %v", got, throttled, tt.want, tt.throttled)
+			}
+		})
+	}
+}
+
+func TestAdaptiveThrottle(t *testing.T) {
+	limiter := rate.NewLimiter(10, 20)
+	var throttle adaptiveThrottle
+	now := time.Now()
+
+	// Rejections of the same episode lower the rate once
+	throttle.observe(limiter, 20*time.Second, now)
+	throttle.observe(limiter, 20*time.Second, now.Add(time.Second))
+	if limiter.Limit() != 5 || limiter.Burst() != 1 {
+		t.Fatalf("limit = %v burst = %d, expected 5 and 1 after one episode", limiter.Limit(), limiter.Burst())
+	}
+	if delay, throttled := throttle.delay(now.Add(time.Second)); !throttled || delay != 20*time.Second {
+		t.Errorf("delay() = %v, %v, expected the extended back-off of 20s", delay, throttled)
+	}
+
+	// Nothing recovers before the back-off has passed
+	throttle.recover(limiter, now.Add(10*time.Second))
+	if limiter.Limit() != 5 {
+		t.Errorf("limit = %v, expected no recovery during the back-off", limiter.Limit())
+	}
+
+	// A new episode after the back-off lowers the rate again, down to the minimum
+	later := now.Add(time.Minute)
+	for i := 0; i < 10; i++ {
+		throttle.observe(limiter, time.Second, later)
+		later = later.Add(time.Second)
+	}
+	if limiter.Limit() != throttleMinRate {
+		t.Errorf("limit = %v, expected the minimum rate", limiter.Limit())
+	}
+	if _, throttled := throttle.delay(later); throttled {
+		t.Errorf("expected the back-off to have passed")
+	}
+
+	// The rate doubles every recovery interval until it is back at its base
+	for i := 0; i < 10 && limiter.Limit() < 10; i++ {
+		previous := limiter.Limit()
+		throttle.recover(limiter, later)
+		if limiter.Limit() <= previous {
+			t.Fatalf("limit = %v, expected recovery from %v", limiter.Limit(), previous)
+		}
+		throttle.recover(limiter, later)
+		if limiter.Limit() < 10 && limiter.Limit() != previous*2 {
+			t.Fatalf("limit = %v, expected one step per recovery interval", limiter.Limit())
+		}
+		later = later.Add(throttleRecoveryInterval)
+	}
+	if limiter.Limit() != 10 || limiter.Burst() != 20 {
+		t.Errorf("limit = %v burst = %d, expected the base rate and burst restored", limiter.Limit(), limiter.Burst())
+	}
+}
+
+func TestWriteSecretThrottled(t *testing.T) {
+	var writes atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"auth": map[string]interface{}{"client_token": "token", "lease_duration": 60},
+			})
+			return
+		}
+		if r.Method == http.MethodGet {
+			// Mount lookups find no mount, so writes fall back to the path
+			http.Error(w, `{"errors":["no mount"]}`, http.StatusNotFound)
+			return
+		}
+		writes.Add(1)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, `{"errors":["request rate limited"]}`, http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClientFromConfig(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+	if err != nil {
+		t.Fatalf("NewClientFromConfig() error = %v", err)
+	}
+
+	if err := client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"key": "value"}); err == nil {
+		t.Fatalf("expected the throttled write to fail")
+	}
+	if n := writes.Load(); n != 1 {
+		t.Errorf("writes = %d, expected a long Retry-After not to be waited out by the HTTP client", n)
+	}
+	if delay, throttled := client.ThrottleDelay(); !throttled || delay <= 25*time.Second || delay > 30*time.Second {
+		t.Errorf("ThrottleDelay() = %v, %v, expected about 30s", delay, throttled)
+	}
+	if limit := client.rateLimiter.Limit(); limit != 5 {
+		t.Errorf("limit = %v, expected the rate halved", limit)
+	}
+}
+
+// TestWriteSecretServiceUnavailable tests that a 503 response is taken for a sealed Vault
+// only without a Retry-After header.
+func TestWriteSecretServiceUnavailable(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		retryAfter string
+		sealed     bool
+	}{
+		{name: "load shedding", retryAfter: "30"},
+		{name: "sealed", sealed: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/v1/auth/kubernetes/login" {
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"auth": map[string]interface{}{"client_token": "token", "lease_duration": 60},
+					})
+					return
+				}
+				if r.Method == http.MethodGet {
+					http.Error(w, `{"errors":["no mount"]}`, http.StatusNotFound)
+					return
+				}
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				http.Error(w, `{"errors":["service unavailable"]}`, http.StatusServiceUnavailable)
+			}))
+			t.Cleanup(server.Close)
+
+			client, err := NewClientFromConfig(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+			if err != nil {
+				t.Fatalf("NewClientFromConfig() error = %v", err)
+			}
+			// Without Retry-After the HTTP client retries the 503 itself
+			client.client.SetMaxRetries(0)
+
+			err = client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"key": "value"})
+			if err == nil {
+				t.Fatalf("expected the write to fail")
+			}
+			if IsSealed(err) != tt.sealed || IsThrottled(err) == tt.sealed {
+				t.Errorf("Reason() = %q, expected sealed = %v", Reason(err), tt.sealed)
+			}
+			if client.IsSealed() != tt.sealed {
+				t.Errorf("IsSealed() = %v, expected %v", client.IsSealed(), tt.sealed)
+			}
+		})
+	}
+}
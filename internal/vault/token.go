@@ -0,0 +1,240 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// Triggers of a Vault login, reported by the reauthentications metric.
+const (
+	authTriggerStartup = "startup" // The client has no token yet
+	authTriggerExpiry  = "expiry"  // The token is close to its expiry
+	authTriggerDenied  = "403"     // Vault denied a request because the token was revoked
+)
+
+// authFailureCooldown is how long requests reuse the error of a failed login before logging
+// in again.
+var authFailureCooldown = 5 * time.Second
+
+// tokenRefreshDivisor sets when a token is replaced: once less than 1/tokenRefreshDivisor of
+// its lifetime remains.
+const tokenRefreshDivisor = 3
+
+// ensureAuthenticated logs in when the client has no token yet or its token is close to
+// expiry. Concurrent callers share a single login, and callers arriving within
+// authFailureCooldown of a failed login get its error instead of logging in again, so a
+// Vault outage does not turn every request into a login attempt.
+func (c *Client) ensureAuthenticated() error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	defer c.publishTokenTTL()
+
+	trigger := authTriggerStartup
+	if c.client.Token() != "" {
+		if !c.tokenExpiring() {
+			return nil
+		}
+		trigger = authTriggerExpiry
+	}
+
+	err := c.authErr
+	if err == nil || time.Since(c.authFailedAt) >= authFailureCooldown {
+		err = c.login(trigger)
+	}
+	// A token that failed to refresh keeps serving requests until it expires
+	if err != nil && trigger == authTriggerExpiry && time.Now().Before(c.tokenExpiry) {
+		return nil
+	}
+	return err
+}
+
+// login authenticates and records the outcome for ensureAuthenticated. Callers hold authMu.
+func (c *Client) login(trigger string) error {
+	metrics.VaultReauthentications.WithLabelValues(trigger).Inc()
+	if err := c.authenticate(); err != nil {
+		c.authErr, c.authFailedAt = err, time.Now()
+		return err
+	}
+	c.authErr = nil
+	return nil
+}
+
+// tokenExpiring reports whether the current token is close enough to expiry to be replaced.
+// Callers hold authMu.
+func (c *Client) tokenExpiring() bool {
+	if c.tokenExpiry.IsZero() {
+		return false
+	}
+	return time.Until(c.tokenExpiry) < c.tokenExpiry.Sub(c.tokenIssued)/tokenRefreshDivisor
+}
+
+// publishTokenTTL updates the token TTL metric. Callers hold authMu.
+func (c *Client) publishTokenTTL() {
+	ttl := 0.0
+	if !c.tokenExpiry.IsZero() {
+		ttl = max(time.Until(c.tokenExpiry).Seconds(), 0)
+	}
+	metrics.VaultTokenTTL.Set(ttl)
+}
+
+// TokenState describes the client's current token without granting its use.
+type TokenState struct {
+	// Accessor identifies the token, e.g. to revoke it; empty before the first login
+	Accessor string
+	Issued   time.Time
+	// Expiry is zero for tokens that never expire
+	Expiry time.Time
+}
+
+// TokenState returns the state of the current token.
+func (c *Client) TokenState() TokenState {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return TokenState{Accessor: c.tokenAccessor, Issued: c.tokenIssued, Expiry: c.tokenExpiry}
+}
+
+// RevokeAccessor revokes the token identified by accessor through
+// auth/token/revoke-accessor, e.g. the token of a replica that no longer runs. The
+// client's own token must not be revoked this way.
+func (c *Client) RevokeAccessor(ctx context.Context, accessor string) error {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	err := c.retryOnDenied(ctx, func(client *api.Client) error {
+		return client.Auth().Token().RevokeAccessorWithContext(ctx, accessor)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke token accessor: %w", err)
+	}
+	return nil
+}
+
+// AccessorIssuedByClient reports whether the token identified by accessor was issued by a
+// login like the client's own: through the same auth path for the same role and, when Vault
+// assigned both an identity entity, to the same entity. It looks the accessor up through
+// auth/token/lookup-accessor, which fails with 400 for tokens that expired or were revoked.
+func (c *Client) AccessorIssuedByClient(ctx context.Context, accessor string) (bool, error) {
+	// Apply rate limiting
+	if err := c.waitForRateLimiter(ctx); err != nil {
+		return false, fmt.Errorf("rate limiter error: %w", err)
+	}
+
+	// Ensure we have a valid token
+	if err := c.ensureAuthenticated(); err != nil {
+		return false, fmt.Errorf("failed to re-authenticate: %w", err)
+	}
+
+	var secret *api.Secret
+	err := c.retryOnDenied(ctx, func(client *api.Client) (err error) {
+		secret, err = client.Auth().Token().LookupAccessorWithContext(ctx, accessor)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to look up token accessor: %w", err)
+	}
+	if secret == nil {
+		return false, nil
+	}
+
+	c.authMu.Lock()
+	entityID := c.tokenEntityID
+	c.authMu.Unlock()
+
+	path, _ := secret.Data["path"].(string)
+	meta, _ := secret.Data["meta"].(map[string]interface{})
+	role, _ := meta["role"].(string)
+	if path != filepath.Join("auth", c.authPath, "login") || role != c.role {
+		return false, nil
+	}
+	if tokenEntityID, _ := secret.Data["entity_id"].(string); entityID != "" && tokenEntityID != "" && tokenEntityID != entityID {
+		return false, nil
+	}
+	return true, nil
+}
+
+// tokenRevoked reports whether Vault rejects token as invalid on a lookup of itself: the token
+// was revoked, for example by the handoff to a new leader or an administrator, rather than
+// denied by a policy. A lookup denied without naming the token invalid, as for roles with
+// token_no_default_policy that cannot look themselves up, does not count.
+func (c *Client) tokenRevoked(ctx context.Context, token string) bool {
+	client, err := c.cloneClient()
+	if err != nil {
+		return false
+	}
+	client.SetToken(token)
+	_, err = client.Auth().Token().LookupSelfWithContext(ctx)
+	return IsInvalidToken(err)
+}
+
+// requestClient returns a copy of the API client bound to the current token. Requests made
+// with it keep their token while a concurrent login replaces the shared one, and a denied
+// request knows which token Vault refused. It sends the Vault namespace mapped for ctx.
+func (c *Client) requestClient(ctx context.Context) (*api.Client, string, error) {
+	token := c.client.Token()
+	client, err := c.cloneClient()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to prepare vault request: %w", err)
+	}
+	client.SetToken(token)
+	c.setRequestNamespace(ctx, client)
+	return client, token, nil
+}
+
+// retryOnDenied runs op with a client bound to the current token, and runs it once more
+// with a new token when Vault denies it because the token was revoked or expired early.
+// Denials of a valid token come from policies, which a new token cannot fix, so they are
+// returned without a login however old the token is. Only one of several requests denied at
+// the same time logs in; the others find their token already replaced and retry with the new
+// one. With several Vault addresses, a request that gets no response is retried once on the
+// next address.
+func (c *Client) retryOnDenied(ctx context.Context, op func(client *api.Client) error) error {
+	client, token, err := c.requestClient(ctx)
+	if err != nil {
+		return err
+	}
+	err = op(client)
+	if err != nil && addressFailed(err) && c.failover(client.Address(), failoverTriggerRequest) {
+		if client, token, err = c.requestClient(ctx); err != nil {
+			return err
+		}
+		err = op(client)
+	}
+	if err == nil || !IsPermissionDenied(err) {
+		return err
+	}
+
+	// The token is looked up without holding authMu, so a slow lookup does not hold up the
+	// logins and token checks of other requests
+	c.authMu.Lock()
+	retry := c.client.Token() != token
+	c.authMu.Unlock()
+	if !retry && c.tokenRevoked(ctx, token) {
+		c.authMu.Lock()
+		// Another request denied at the same time may have logged in during the lookup
+		retry = c.client.Token() != token || c.login(authTriggerDenied) == nil
+		c.publishTokenTTL()
+		c.authMu.Unlock()
+	}
+
+	if !retry {
+		return err
+	}
+	client, _, retryErr := c.requestClient(ctx)
+	if retryErr != nil {
+		return err
+	}
+	return op(client)
+}
@@ -0,0 +1,247 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+)
+
+// tokenServer is a Vault server issuing token-1, token-2, ... with accessors accessor-1,
+// accessor-2, ... and a 60s lease. Writes are denied for revoked tokens and for the
+// kv/forbidden path.
+type tokenServer struct {
+	logins  atomic.Int32
+	revoked atomic.Value // string
+	// revokedAccessor is the last accessor sent to auth/token/revoke-accessor
+	revokedAccessor atomic.Value // string
+	// noLookupSelf denies auth/token/lookup-self by policy, as for roles with token_no_default_policy
+	noLookupSelf bool
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/auth/kubernetes/login" {
+		login := s.logins.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   fmt.Sprintf("token-%d", login),
+				"accessor":       fmt.Sprintf("accessor-%d", login),
+				"entity_id":      "entity-operator",
+				"lease_duration": 60,
+			},
+		})
+		return
+	}
+	if r.URL.Path == "/v1/auth/token/lookup-accessor" {
+		var body struct {
+			Accessor string `json:"accessor"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		token, ok := lookupAccessors[body.Accessor]
+		if !ok {
+			http.Error(w, `{"errors":["invalid accessor"]}`, http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": token})
+		return
+	}
+	if r.URL.Path == "/v1/auth/token/revoke-accessor" {
+		var body struct {
+			Accessor string `json:"accessor"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.revokedAccessor.Store(body.Accessor)
+	}
+	if revoked, _ := s.revoked.Load().(string); r.Header.Get("X-Vault-Token") == revoked {
+		http.Error(w, `{"errors":["permission denied","invalid token"]}`, http.StatusForbidden)
+		return
+	}
+	if r.URL.Path == "/v1/kv/forbidden" || (s.noLookupSelf && r.URL.Path == "/v1/auth/token/lookup-self") {
+		http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lookupAccessors are the tokens known to tokenServer's auth/token/lookup-accessor.
+var lookupAccessors = map[string]map[string]interface{}{
+	"accessor-previous":     {"path": "auth/kubernetes/login", "meta": map[string]interface{}{"role": "operator"}, "entity_id": "entity-operator"},
+	"accessor-other-role":   {"path": "auth/kubernetes/login", "meta": map[string]interface{}{"role": "admin"}, "entity_id": "entity-operator"},
+	"accessor-other-entity": {"path": "auth/kubernetes/login", "meta": map[string]interface{}{"role": "operator"}, "entity_id": "entity-other"},
+	"accessor-userpass":     {"path": "auth/userpass/login/admin", "meta": map[string]interface{}{"username": "admin"}},
+}
+
+func newTokenTestClient(t *testing.T) (*Client, *tokenServer) {
+	t.Helper()
+	vaultServer := &tokenServer{}
+	server := httptest.NewServer(vaultServer)
+	t.Cleanup(server.Close)
+
+	client, err := NewClientFromConfig(Config{Address: server.URL, Role: "operator", AuthPath: "kubernetes", JWTSource: staticJWTSource("jwt")})
+	if err != nil {
+		t.Fatalf("NewClientFromConfig() error = %v", err)
+	}
+	return client, vaultServer
+}
+
+// ageToken makes the current token look issued age ago with its 60s lease.
+func ageToken(c *Client, age time.Duration) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.tokenIssued = time.Now().Add(-age)
+	c.tokenExpiry = c.tokenIssued.Add(60 * time.Second)
+}
+
+func TestTokenRefreshBeforeExpiry(t *testing.T) {
+	expiryLogins := testutil.ToFloat64(metrics.VaultReauthentications.WithLabelValues(authTriggerExpiry))
+	client, vaultServer := newTokenTestClient(t)
+
+	if ttl := testutil.ToFloat64(metrics.VaultTokenTTL); ttl < 59 || ttl > 60 {
+		t.Errorf("token TTL metric = %v, expected the 60s lease", ttl)
+	}
+
+	// A token with more than a third of its lifetime left is kept
+	ageToken(client, 30*time.Second)
+	if err := client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if vaultServer.logins.Load() != 1 {
+		t.Errorf("%d logins with a token half way through its lease, expected 1", vaultServer.logins.Load())
+	}
+
+	// A token close to expiry is replaced before the request
+	ageToken(client, 50*time.Second)
+	if err := client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if vaultServer.logins.Load() != 2 || client.client.Token() != "token-2" {
+		t.Errorf("%d logins with token %s, expected a refresh to token-2", vaultServer.logins.Load(), client.client.Token())
+	}
+	if got := testutil.ToFloat64(metrics.VaultReauthentications.WithLabelValues(authTriggerExpiry)) - expiryLogins; got != 1 {
+		t.Errorf("expiry reauthentications = %v, expected 1", got)
+	}
+}
+
+func TestReauthenticateOnDenied(t *testing.T) {
+	deniedLogins := testutil.ToFloat64(metrics.VaultReauthentications.WithLabelValues(authTriggerDenied))
+	client, vaultServer := newTokenTestClient(t)
+
+	// A revoked token is replaced and the write retried
+	vaultServer.revoked.Store("token-1")
+	ageToken(client, 35*time.Second)
+	if err := client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("WriteSecret() error = %v", err)
+	}
+	if vaultServer.logins.Load() != 2 {
+		t.Errorf("%d logins after a revoked token, expected 2", vaultServer.logins.Load())
+	}
+
+	// Denials of a valid token come from policies and are returned without a login, also
+	// once the token is older
+	ageToken(client, 35*time.Second)
+	if err := client.WriteSecret(context.Background(), "kv/forbidden", map[string]interface{}{"a": "b"}); err == nil {
+		t.Error("WriteSecret() succeeded on a forbidden path")
+	}
+	if vaultServer.logins.Load() != 2 {
+		t.Errorf("%d logins after a policy denial, expected 2", vaultServer.logins.Load())
+	}
+	if got := testutil.ToFloat64(metrics.VaultReauthentications.WithLabelValues(authTriggerDenied)) - deniedLogins; got != 1 {
+		t.Errorf("403 reauthentications = %v, expected 1", got)
+	}
+
+	// A fresh token that Vault no longer accepts at all was revoked, and is replaced as well
+	ageToken(client, 0)
+	vaultServer.revoked.Store("token-2")
+	if err := client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"a": "b"}); err != nil {
+		t.Fatalf("WriteSecret() with a revoked fresh token error = %v", err)
+	}
+	if vaultServer.logins.Load() != 3 {
+		t.Errorf("%d logins after a fresh token was revoked, expected 3", vaultServer.logins.Load())
+	}
+
+	// A token whose policies do not grant lookup-self is not taken for revoked on a denial
+	vaultServer.noLookupSelf = true
+	if err := client.WriteSecret(context.Background(), "kv/forbidden", map[string]interface{}{"a": "b"}); err == nil {
+		t.Error("WriteSecret() succeeded on a forbidden path")
+	}
+	if vaultServer.logins.Load() != 3 {
+		t.Errorf("%d logins after a policy denial without lookup-self, expected 3", vaultServer.logins.Load())
+	}
+}
+
+func TestConcurrentReauthenticateOnDenied(t *testing.T) {
+	client, vaultServer := newTokenTestClient(t)
+
+	// Every write is denied with the revoked token, but only one of them logs in again
+	vaultServer.revoked.Store("token-1")
+	ageToken(client, 35*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.WriteSecret(context.Background(), "kv/app", map[string]interface{}{"a": "b"})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("WriteSecret() during a concurrent login error = %v", err)
+		}
+	}
+	if vaultServer.logins.Load() != 2 {
+		t.Errorf("%d logins after concurrent denials, expected 2", vaultServer.logins.Load())
+	}
+}
+
+func TestTokenStateAndRevokeAccessor(t *testing.T) {
+	client, vaultServer := newTokenTestClient(t)
+
+	state := client.TokenState()
+	if state.Accessor != "accessor-1" {
+		t.Errorf("TokenState().Accessor = %q, expected accessor-1", state.Accessor)
+	}
+	if lease := state.Expiry.Sub(state.Issued); lease != 60*time.Second {
+		t.Errorf("TokenState() lease = %v, expected 60s", lease)
+	}
+
+	if err := client.RevokeAccessor(context.Background(), "accessor-previous"); err != nil {
+		t.Fatalf("RevokeAccessor() error = %v", err)
+	}
+	if revoked, _ := vaultServer.revokedAccessor.Load().(string); revoked != "accessor-previous" {
+		t.Errorf("revoked accessor = %q, expected accessor-previous", revoked)
+	}
+}
+
+func TestAccessorIssuedByClient(t *testing.T) {
+	client, _ := newTokenTestClient(t)
+
+	for accessor, expected := range map[string]bool{
+		"accessor-previous":     true,
+		"accessor-other-role":   false,
+		"accessor-other-entity": false,
+		"accessor-userpass":     false,
+	} {
+		issued, err := client.AccessorIssuedByClient(context.Background(), accessor)
+		if err != nil || issued != expected {
+			t.Errorf("AccessorIssuedByClient(%s) = %v, %v, expected %v", accessor, issued, err, expected)
+		}
+	}
+
+	if _, err := client.AccessorIssuedByClient(context.Background(), "accessor-expired"); StatusCode(err) != http.StatusBadRequest {
+		t.Errorf("AccessorIssuedByClient() of an unknown accessor error = %v, expected 400", err)
+	}
+}
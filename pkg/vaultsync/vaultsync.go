@@ -0,0 +1,268 @@
+// Package vaultsync embeds the vault-sync-operator controllers into an existing
+// controller-runtime manager, so platform teams can run the Deployment and Secret
+// sync logic in their own controller binary instead of a separate operator Deployment.
+//
+// A minimal embedding creates a Vault client and adds both controllers:
+//
+//	vaultClient, err := vaultsync.NewVaultClient(vaultsync.VaultConfig{
+//		Address:  "https://vault.example.com:8200",
+//		Role:     "platform-controller",
+//		AuthPath: "kubernetes",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	if err := vaultsync.SetupWithManager(mgr, vaultsync.Options{VaultClient: vaultClient}); err != nil {
+//		return err
+//	}
+//
+// The vault_sync_operator_* metrics are registered with the controller-runtime metrics
+// registry and served by the manager's metrics endpoint. The manager's service account
+// needs the RBAC permissions of the operator's ClusterRole.
+package vaultsync
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/controller"
+	"github.com/danieldonoghue/vault-sync-operator/internal/metrics"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// VaultConfig holds the settings used to connect and authenticate to Vault.
+type VaultConfig = vault.Config
+
+// VaultClient is a rate-limited Vault client authenticated with the Kubernetes auth method.
+type VaultClient = vault.Client
+
+// VaultWriterDeleter is the Vault client used by the reconcilers. *VaultClient implements
+// it; tests and alternative backends may provide their own implementation.
+type VaultWriterDeleter = controller.VaultWriterDeleter
+
+// VaultReader reads secrets from Vault. *VaultClient implements it, returning the same data
+// for KV v1 and v2 paths.
+type VaultReader = controller.VaultReader
+
+// DeploymentReconciler syncs the Secrets referenced by annotated Deployments to Vault.
+type DeploymentReconciler = controller.DeploymentReconciler
+
+// SecretReconciler syncs annotated Secrets to Vault.
+type SecretReconciler = controller.SecretReconciler
+
+// WorkloadKind is a Deployment-like kind, such as an Argo Rollout, synced like Deployments.
+type WorkloadKind = controller.WorkloadKind
+
+// ReconcileIntervalBounds limits the intervals requested with vault-sync.io/reconcile.
+type ReconcileIntervalBounds = controller.ReconcileIntervalBounds
+
+// SecretTypePolicy allows or denies Secret types per namespace.
+type SecretTypePolicy = controller.SecretTypePolicy
+
+// SecretTypeRule allows or denies Secret types in the namespaces matching its patterns.
+type SecretTypeRule = controller.SecretTypeRule
+
+// Default controller names. They are prefixed so they do not clash with controllers of
+// the embedding manager that watch the same kinds.
+const (
+	DefaultDeploymentControllerName = "vault-sync-deployment"
+	DefaultSecretControllerName     = "vault-sync-secret"
+)
+
+// Options configures the embedded controllers.
+type Options struct {
+	// VaultClient writes and deletes the synced secrets (required)
+	VaultClient VaultWriterDeleter
+	// ClusterName prefixes Vault paths with clusters/<name>/ (optional)
+	ClusterName string
+	// Namespaces restricts the controllers to these namespaces (empty means all)
+	Namespaces []string
+	// DisableDeployments and DisableSecrets skip adding the respective controller
+	DisableDeployments bool
+	DisableSecrets     bool
+	// WorkloadKinds are Deployment-like kinds synced by additional controllers named
+	// vault-sync-<kind>; they are skipped with DisableDeployments
+	WorkloadKinds []WorkloadKind
+	// DeploymentControllerName and SecretControllerName override the default controller names
+	DeploymentControllerName string
+	SecretControllerName     string
+	// SkippedSecretTypes lists Secret types that are never synced; nil skips service account
+	// and bootstrap tokens
+	SkippedSecretTypes []string
+	// SecretTypes allows or denies Secret types per namespace (optional)
+	SecretTypes SecretTypePolicy
+	// ReconcileBounds limits vault-sync.io/reconcile intervals; the zero value enforces a 30s minimum
+	ReconcileBounds ReconcileIntervalBounds
+	// DefaultReconcileInterval applies to resources without vault-sync.io/reconcile (disabled when zero)
+	DefaultReconcileInterval time.Duration
+	// Recorder emits events; defaults to the manager's recorder named vault-sync-operator
+	Recorder events.EventRecorder
+	// Log defaults to the manager's logger named vault-sync
+	Log logr.Logger
+}
+
+// SetMetricsPathLabel sets the value of the path label of metrics such as
+// vault_sync_operator_vault_write_errors_total to full (the default), mount, hashed or
+// disabled. It applies to every embedded controller in the process.
+func SetMetricsPathLabel(strategy string) error {
+	parsed, err := metrics.ParsePathLabelStrategy(strategy)
+	if err != nil {
+		return fmt.Errorf("vaultsync: %w", err)
+	}
+	metrics.SetPathLabelStrategy(parsed)
+	return nil
+}
+
+// SetMetricsNamespaceAggregation aggregates the sync metrics of every embedded controller per
+// namespace by leaving their resource label empty, except in detailedNamespaces.
+func SetMetricsNamespaceAggregation(detailedNamespaces []string) {
+	metrics.SetNamespaceAggregation(detailedNamespaces)
+}
+
+// CompressedKeySuffix marks the keys of values written compressed with
+// vault-sync.io/compress-values.
+const CompressedKeySuffix = controller.CompressedKeySuffix
+
+// DecompressValues restores the values written compressed with vault-sync.io/compress-values
+// in data read from Vault, for example with VaultClient.ReadSecret, under their original keys.
+func DecompressValues(data map[string]interface{}) (map[string]interface{}, error) {
+	return controller.DecompressValues(data)
+}
+
+// SecretsAnnotationSchema returns the JSON Schema of the vault-sync.io/secrets annotation,
+// as published in config/schemas/secrets.schema.json.
+func SecretsAnnotationSchema() []byte {
+	return controller.SecretsAnnotationSchema()
+}
+
+// ValidateSecretsAnnotation checks a vault-sync.io/secrets annotation against its schema and
+// the rules the schema cannot express, for example in a validating admission webhook.
+func ValidateSecretsAnnotation(value string) error {
+	return controller.ValidateSecretsAnnotation(value)
+}
+
+// NewVaultClient creates a Vault client from cfg and authenticates with the Kubernetes
+// auth method using the pod's service account token.
+func NewVaultClient(cfg VaultConfig) (*VaultClient, error) {
+	return vault.NewClientFromConfig(cfg)
+}
+
+// NewLazyVaultClient creates a Vault client from cfg that logs in on its first request, so
+// it can be created while Vault is unavailable. A failed login fails only that request.
+func NewLazyVaultClient(cfg VaultConfig) (*VaultClient, error) {
+	return vault.NewUnauthenticatedClient(cfg)
+}
+
+// NewDeploymentReconciler returns a Deployment reconciler for mgr configured from opts.
+// Fields not covered by Options can be set on the result before SetupWithManager.
+func NewDeploymentReconciler(mgr ctrl.Manager, opts Options) *DeploymentReconciler {
+	opts = opts.withDefaults(mgr)
+	name := opts.DeploymentControllerName
+	if name == "" {
+		name = DefaultDeploymentControllerName
+	}
+	return &controller.DeploymentReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		Log:                      opts.Log.WithName("Deployment"),
+		VaultClient:              opts.VaultClient,
+		ClusterName:              opts.ClusterName,
+		SkippedSecretTypes:       opts.SkippedSecretTypes,
+		SecretTypes:              opts.SecretTypes,
+		ReconcileBounds:          opts.ReconcileBounds,
+		DefaultReconcileInterval: opts.DefaultReconcileInterval,
+		Recorder:                 opts.Recorder,
+		APIReader:                mgr.GetAPIReader(),
+		Name:                     name,
+		Namespaces:               opts.Namespaces,
+	}
+}
+
+// NewWorkloadReconciler returns a reconciler for a Deployment-like kind configured from opts.
+// The manager's service account needs get, list, watch, update and patch on the kind.
+func NewWorkloadReconciler(mgr ctrl.Manager, kind WorkloadKind, opts Options) *DeploymentReconciler {
+	opts = opts.withDefaults(mgr)
+	r := NewDeploymentReconciler(mgr, opts)
+	r.WorkloadKind = &kind
+	r.Log = opts.Log.WithName(kind.Kind)
+	r.Name = "vault-sync-" + strings.ToLower(kind.Kind)
+	return r
+}
+
+// NewSecretReconciler returns a Secret reconciler for mgr configured from opts.
+// Fields not covered by Options can be set on the result before SetupWithManager.
+func NewSecretReconciler(mgr ctrl.Manager, opts Options) *SecretReconciler {
+	opts = opts.withDefaults(mgr)
+	name := opts.SecretControllerName
+	if name == "" {
+		name = DefaultSecretControllerName
+	}
+	return &controller.SecretReconciler{
+		Client:                   mgr.GetClient(),
+		Scheme:                   mgr.GetScheme(),
+		Log:                      opts.Log.WithName("Secret"),
+		VaultClient:              opts.VaultClient,
+		ClusterName:              opts.ClusterName,
+		SkippedSecretTypes:       opts.SkippedSecretTypes,
+		SecretTypes:              opts.SecretTypes,
+		ReconcileBounds:          opts.ReconcileBounds,
+		DefaultReconcileInterval: opts.DefaultReconcileInterval,
+		Recorder:                 opts.Recorder,
+		Propagation:              controller.NewPropagationTracker(),
+		Name:                     name,
+		Namespaces:               opts.Namespaces,
+	}
+}
+
+// SetupWithManager adds the Deployment and Secret controllers to mgr.
+func SetupWithManager(mgr ctrl.Manager, opts Options) error {
+	if opts.VaultClient == nil {
+		return errors.New("vaultsync: a Vault client is required")
+	}
+	if err := opts.ReconcileBounds.Validate(); err != nil {
+		return fmt.Errorf("vaultsync: %w", err)
+	}
+	if err := controller.ValidateClusterName(opts.ClusterName, nil); err != nil {
+		return fmt.Errorf("vaultsync: %w", err)
+	}
+	if opts.DefaultReconcileInterval < 0 {
+		return fmt.Errorf("vaultsync: negative default reconcile interval %s", opts.DefaultReconcileInterval)
+	}
+	if !opts.DisableDeployments {
+		if err := NewDeploymentReconciler(mgr, opts).SetupWithManager(mgr); err != nil {
+			return err
+		}
+		for _, kind := range opts.WorkloadKinds {
+			if err := NewWorkloadReconciler(mgr, kind, opts).SetupWithManager(mgr); err != nil {
+				return err
+			}
+		}
+	}
+	if !opts.DisableSecrets {
+		if err := NewSecretReconciler(mgr, opts).SetupWithManager(mgr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withDefaults fills in the options left empty.
+func (o Options) withDefaults(mgr ctrl.Manager) Options {
+	if o.SkippedSecretTypes == nil {
+		o.SkippedSecretTypes = slices.Clone(controller.DefaultSkippedSecretTypes)
+	}
+	if o.Recorder == nil {
+		o.Recorder = mgr.GetEventRecorder("vault-sync-operator")
+	}
+	if o.Log.GetSink() == nil {
+		o.Log = mgr.GetLogger().WithName("vault-sync")
+	}
+	return o
+}
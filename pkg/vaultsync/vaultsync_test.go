@@ -0,0 +1,62 @@
+package vaultsync
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// nopVault is a VaultWriterDeleter that discards every request.
+type nopVault struct{}
+
+func (nopVault) WriteSecret(context.Context, string, map[string]interface{}) error { return nil }
+
+func (nopVault) DeleteSecret(context.Context, string) error { return nil }
+
+func newTestManager(t *testing.T) ctrl.Manager {
+	t.Helper()
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{
+		Metrics: metricsserver.Options{BindAddress: "0"},
+	})
+	if err != nil {
+		t.Fatalf("ctrl.NewManager() error = %v", err)
+	}
+	return mgr
+}
+
+func TestNewReconcilers(t *testing.T) {
+	mgr := newTestManager(t)
+	opts := Options{VaultClient: nopVault{}, ClusterName: "prod", Namespaces: []string{"payments"}}
+
+	deployments := NewDeploymentReconciler(mgr, opts)
+	if deployments.Name != DefaultDeploymentControllerName || deployments.ClusterName != "prod" || deployments.Recorder == nil {
+		t.Errorf("unexpected deployment reconciler %+v", deployments)
+	}
+	rollouts := NewWorkloadReconciler(mgr, WorkloadKind{GroupVersionKind: schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "Rollout"}}, opts)
+	if rollouts.Name != "vault-sync-rollout" || rollouts.WorkloadKind == nil || rollouts.ClusterName != "prod" {
+		t.Errorf("unexpected rollout reconciler %+v", rollouts)
+	}
+	secrets := NewSecretReconciler(mgr, opts)
+	if secrets.Name != DefaultSecretControllerName || len(secrets.SkippedSecretTypes) != 2 || secrets.Propagation == nil {
+		t.Errorf("unexpected secret reconciler %+v", secrets)
+	}
+}
+
+func TestSetupWithManager(t *testing.T) {
+	if err := SetupWithManager(newTestManager(t), Options{}); err == nil {
+		t.Error("SetupWithManager() without a Vault client succeeded")
+	}
+
+	// Controller names must be unique within a manager
+	mgr := newTestManager(t)
+	if err := SetupWithManager(mgr, Options{VaultClient: nopVault{}}); err != nil {
+		t.Fatalf("SetupWithManager() error = %v", err)
+	}
+	if err := SetupWithManager(mgr, Options{VaultClient: nopVault{}, DisableDeployments: true, SecretControllerName: "vault-sync-secret-2"}); err != nil {
+		t.Errorf("SetupWithManager() with a second secret controller error = %v", err)
+	}
+}
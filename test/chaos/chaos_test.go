@@ -0,0 +1,200 @@
+package chaos
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/danieldonoghue/vault-sync-operator/internal/controller"
+	"github.com/danieldonoghue/vault-sync-operator/internal/vault"
+)
+
+// workloadSecrets are the secrets referenced by the workload of every scenario, each written
+// to its own sub-path, so a sync consists of several writes that can be interrupted.
+var workloadSecrets = map[string]string{"db": "s3cret", "cache": "hunter2", "queue": "letmein"}
+
+func TestMain(m *testing.M) {
+	// Faults must reach the operator rather than be hidden by the Vault API client's retries
+	_ = os.Setenv("VAULT_MAX_RETRIES", "0")
+	os.Exit(m.Run())
+}
+
+// newWorkload returns a Deployment syncing workloadSecrets by auto-discovery, and the secrets.
+func newWorkload() (*appsv1.Deployment, []client.Object) {
+	deployment := &appsv1.Deployment{}
+	deployment.Name = "web"
+	deployment.Namespace = "default"
+	deployment.Annotations = map[string]string{controller.VaultPathAnnotation: "secret/data/web"}
+	container := corev1.Container{Name: "app"}
+	objects := []client.Object{deployment}
+	for name, password := range workloadSecrets {
+		secret := &corev1.Secret{Data: map[string][]byte{"password": []byte(password)}}
+		secret.Name = name
+		secret.Namespace = deployment.Namespace
+		objects = append(objects, secret)
+		container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{
+			SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+		})
+	}
+	deployment.Spec.Template.Spec.Containers = []corev1.Container{container}
+	return deployment, objects
+}
+
+// newReconciler returns a Deployment reconciler using a real Vault client against server.
+func newReconciler(t *testing.T, k8sClient client.Client, server *VaultServer) *controller.DeploymentReconciler {
+	t.Helper()
+	vaultClient, err := vault.NewUnauthenticatedClient(vault.Config{
+		Address:   server.Address(),
+		Role:      "vault-sync-operator",
+		AuthPath:  "kubernetes",
+		JWTSource: StaticJWT("chaos"),
+	})
+	if err != nil {
+		t.Fatalf("vault.NewUnauthenticatedClient() error = %v", err)
+	}
+	return &controller.DeploymentReconciler{
+		Client:      k8sClient,
+		Scheme:      runtime.NewScheme(),
+		Log:         logr.Discard(),
+		VaultClient: vaultClient,
+	}
+}
+
+// reconcileOnce runs one reconcile of deployment.
+func reconcileOnce(r *controller.DeploymentReconciler, deployment *appsv1.Deployment) (reconcile.Result, error) {
+	return r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(deployment)})
+}
+
+// converged reports whether every secret of the workload is in Vault and the Deployment
+// records their versions, read with the unfaulted k8sClient.
+func converged(t *testing.T, k8sClient client.Client, server *VaultServer, deployment *appsv1.Deployment) bool {
+	t.Helper()
+	current := &appsv1.Deployment{}
+	if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(deployment), current); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !controllerutil.ContainsFinalizer(current, controller.VaultSyncFinalizer) ||
+		current.Annotations[controller.VaultSecretVersionsAnnotation] == "" {
+		return false
+	}
+	for name, password := range workloadSecrets {
+		if server.Secret("secret/data/web/" + name)["password"] != password {
+			return false
+		}
+	}
+	return true
+}
+
+// TestVaultRestartMidBatch tests that a sync interrupted by a Vault restart is held while
+// Vault is sealed, and completes once it is unsealed without recording the versions of
+// secrets that were not written.
+func TestVaultRestartMidBatch(t *testing.T) {
+	RequireScenario(t, ScenarioVaultRestart)
+	server := NewVaultServer()
+	defer server.Close()
+	deployment, objects := newWorkload()
+	k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	r := newReconciler(t, k8sClient, server)
+
+	// The first reconcile adds the finalizer
+	if _, err := reconcileOnce(r, deployment); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// Vault stops after the first of the three writes
+	server.AfterWrites(1, func() { server.SetDown(true) })
+	if _, err := reconcileOnce(r, deployment); err == nil {
+		t.Fatalf("Reconcile() with Vault down = nil error, expected the failed write")
+	}
+	if converged(t, k8sClient, server, deployment) {
+		t.Fatalf("expected the interrupted sync not to record the secret versions")
+	}
+
+	// Vault comes back sealed: the sync fails once more, then is held until it is unsealed
+	server.Restart()
+	writes := server.Writes()
+	held := false
+	for i := 0; i < 3 && !held; i++ {
+		result, err := reconcileOnce(r, deployment)
+		held = err == nil && result.RequeueAfter == controller.VaultSealedRequeueDelay
+	}
+	if !held {
+		t.Fatalf("expected the sync to be held while Vault is sealed")
+	}
+	if server.Writes() != writes {
+		t.Errorf("writes while sealed = %d, expected none", server.Writes()-writes)
+	}
+
+	server.Unseal()
+	if _, err := reconcileOnce(r, deployment); err != nil {
+		t.Fatalf("Reconcile() after unseal error = %v", err)
+	}
+	if !converged(t, k8sClient, server, deployment) {
+		t.Errorf("expected every secret to be synced after Vault was unsealed")
+	}
+}
+
+// TestTokenRevokedMidSync tests that a token revoked between two writes of a sync is
+// replaced by a new login within the same sync, however recently it was issued.
+func TestTokenRevokedMidSync(t *testing.T) {
+	RequireScenario(t, ScenarioTokenRevoked)
+	server := NewVaultServer()
+	defer server.Close()
+	deployment, objects := newWorkload()
+	k8sClient := fake.NewClientBuilder().WithObjects(objects...).Build()
+	r := newReconciler(t, k8sClient, server)
+
+	if _, err := reconcileOnce(r, deployment); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	server.AfterWrites(1, server.RevokeTokens)
+	if _, err := reconcileOnce(r, deployment); err != nil {
+		t.Fatalf("Reconcile() with a revoked token error = %v", err)
+	}
+	if !converged(t, k8sClient, server, deployment) {
+		t.Errorf("expected every secret to be synced with the new token")
+	}
+	if logins := server.Logins(); logins != 2 {
+		t.Errorf("logins = %d, expected one more after the revocation", logins)
+	}
+}
+
+// TestAPIServerFlake tests that syncs converge while Kubernetes API requests fail at random,
+// and that the converged state causes no further writes.
+func TestAPIServerFlake(t *testing.T) {
+	RequireScenario(t, ScenarioAPIServerFlake)
+	const maxReconciles = 50
+	server := NewVaultServer()
+	defer server.Close()
+	deployment, objects := newWorkload()
+	base := fake.NewClientBuilder().WithObjects(objects...).Build()
+	k8sClient := NewFlakyClient(base, 0.3, Seed(t))
+	r := newReconciler(t, k8sClient, server)
+
+	reconciles := 0
+	for ; reconciles < maxReconciles && !converged(t, base, server, deployment); reconciles++ {
+		_, _ = reconcileOnce(r, deployment)
+	}
+	if !converged(t, base, server, deployment) {
+		t.Fatalf("not converged after %d reconciles with %d injected failures", reconciles, k8sClient.Failures())
+	}
+	t.Logf("converged after %d reconciles with %d injected failures", reconciles, k8sClient.Failures())
+
+	k8sClient.SetRate(0)
+	writes := server.Writes()
+	if _, err := reconcileOnce(r, deployment); err != nil {
+		t.Fatalf("Reconcile() after convergence error = %v", err)
+	}
+	if server.Writes() != writes {
+		t.Errorf("writes after convergence = %d, expected none", server.Writes()-writes)
+	}
+}
@@ -0,0 +1,83 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// FlakyClient wraps a Kubernetes client so that a fraction of its reads and writes fail with
+// 503 Service Unavailable, as they do while the API server restarts or is overloaded. The
+// failures follow seed, so a failing run can be repeated.
+type FlakyClient struct {
+	client.WithWatch
+
+	mu       sync.Mutex
+	rand     *rand.Rand
+	rate     float64
+	failures int
+}
+
+// NewFlakyClient returns base with requests failing at rate, a fraction between 0 and 1.
+func NewFlakyClient(base client.WithWatch, rate float64, seed int64) *FlakyClient {
+	c := &FlakyClient{
+		rand: rand.New(rand.NewSource(seed)), //nolint:gosec // Reproducible faults, not security
+		rate: rate,
+	}
+	c.WithWatch = interceptor.NewClient(base, interceptor.Funcs{
+		Get: func(ctx context.Context, base client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := c.fail("get"); err != nil {
+				return err
+			}
+			return base.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, base client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if err := c.fail("list"); err != nil {
+				return err
+			}
+			return base.List(ctx, list, opts...)
+		},
+		Update: func(ctx context.Context, base client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			if err := c.fail("update"); err != nil {
+				return err
+			}
+			return base.Update(ctx, obj, opts...)
+		},
+		Patch: func(ctx context.Context, base client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if err := c.fail("patch"); err != nil {
+				return err
+			}
+			return base.Patch(ctx, obj, patch, opts...)
+		},
+	})
+	return c
+}
+
+// SetRate changes the fraction of requests that fail, e.g. to 0 to end an outage.
+func (c *FlakyClient) SetRate(rate float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rate = rate
+}
+
+// Failures returns the number of requests failed so far.
+func (c *FlakyClient) Failures() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failures
+}
+
+// fail returns the error a request of verb fails with, or nil when it goes through.
+func (c *FlakyClient) fail(verb string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rand.Float64() >= c.rate {
+		return nil
+	}
+	c.failures++
+	return apierrors.NewServiceUnavailable("chaos: injected " + verb + " failure")
+}
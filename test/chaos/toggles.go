@@ -0,0 +1,71 @@
+package chaos
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Environment variables selecting what the chaos suite runs.
+const (
+	// EnvScenarios is a comma-separated list of the scenarios to run (all when unset)
+	EnvScenarios = "CHAOS_SCENARIOS"
+	// EnvSeed seeds the random faults (the current time when unset); failing runs log theirs
+	EnvSeed = "CHAOS_SEED"
+)
+
+// Scenarios of the chaos suite.
+const (
+	ScenarioVaultRestart   = "vault-restart"
+	ScenarioTokenRevoked   = "token-revoked"
+	ScenarioAPIServerFlake = "apiserver-flake"
+)
+
+// ScenarioEnabled reports whether scenario is selected by CHAOS_SCENARIOS.
+func ScenarioEnabled(scenario string) bool {
+	selected := strings.TrimSpace(os.Getenv(EnvScenarios))
+	if selected == "" {
+		return true
+	}
+	for _, name := range strings.Split(selected, ",") {
+		if strings.TrimSpace(name) == scenario {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireScenario skips t unless scenario is selected by CHAOS_SCENARIOS.
+func RequireScenario(t testing.TB, scenario string) {
+	t.Helper()
+	if !ScenarioEnabled(scenario) {
+		t.Skipf("chaos scenario %s not selected by %s", scenario, EnvScenarios)
+	}
+}
+
+// Seed returns the seed of the random faults from CHAOS_SEED, logging it with t so that a
+// failing run can be repeated.
+func Seed(t testing.TB) int64 {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	if value := os.Getenv(EnvSeed); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid %s %q: %v", EnvSeed, value, err)
+		}
+		seed = parsed
+	}
+	t.Logf("chaos seed %d (set %s to repeat)", seed, EnvSeed)
+	return seed
+}
+
+// StaticJWT is a login JWT that never changes, accepted by VaultServer.
+type StaticJWT string
+
+// JWT implements vault.JWTSource.
+func (s StaticJWT) JWT(_ context.Context) (string, error) {
+	return string(s), nil
+}
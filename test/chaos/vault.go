@@ -0,0 +1,248 @@
+// Package chaos provides the fault injection used by the resilience tests of the operator:
+// a fake Vault server that can go down, seal, and revoke its tokens at chosen points of a
+// sync, and a Kubernetes client whose requests fail at random. The scenarios in this
+// package run with go test; other tests can build on the same faults.
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// KVMount is the KV v2 mount served by VaultServer.
+const KVMount = "secret/"
+
+// VaultServer is a fake Vault server with a Kubernetes auth login and a KV v2 mount, whose
+// availability and tokens can be changed while the operator uses it.
+type VaultServer struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	down    bool
+	sealed  bool
+	tokens  map[string]bool // issued tokens, false once revoked
+	secrets map[string]map[string]interface{}
+	writes  int
+	logins  int
+	// faultAfter is the number of writes after which fault runs once (disabled when nil)
+	faultAfter int
+	fault      func()
+}
+
+// NewVaultServer starts a fake Vault server that is up and unsealed. Close it when done.
+func NewVaultServer() *VaultServer {
+	v := &VaultServer{
+		tokens:  make(map[string]bool),
+		secrets: make(map[string]map[string]interface{}),
+	}
+	v.server = httptest.NewServer(http.HandlerFunc(v.serveHTTP))
+	return v
+}
+
+// Address returns the address the server listens on.
+func (v *VaultServer) Address() string {
+	return v.server.URL
+}
+
+// Close shuts the server down.
+func (v *VaultServer) Close() {
+	v.server.Close()
+}
+
+// SetDown makes the server close every connection without a response (true), as a
+// stopped or restarting Vault does, or serve requests again (false).
+func (v *VaultServer) SetDown(down bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.down = down
+}
+
+// Seal makes every request but health checks fail with 503, as Vault does while sealed.
+func (v *VaultServer) Seal() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sealed = true
+}
+
+// Unseal serves requests again after Seal.
+func (v *VaultServer) Unseal() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.sealed = false
+}
+
+// Restart simulates a restart of Vault: the server comes back up sealed, with the tokens and
+// secrets it had before. Unseal completes the restart.
+func (v *VaultServer) Restart() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.down = false
+	v.sealed = true
+}
+
+// RevokeTokens revokes every token issued so far, as an administrator or the handoff to a
+// new leader does. Requests made with them are denied, and a new login is needed.
+func (v *VaultServer) RevokeTokens() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for token := range v.tokens {
+		v.tokens[token] = false
+	}
+}
+
+// AfterWrites runs fault once the next n secret writes succeeded, e.g. to take the server
+// down in the middle of a sync writing several secrets. Only the last fault set is run.
+func (v *VaultServer) AfterWrites(n int, fault func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.faultAfter = v.writes + n
+	v.fault = fault
+}
+
+// Secret returns the data of the current version of the KV v2 secret at path, given with or
+// without the data/ segment, or nil when it does not exist.
+func (v *VaultServer) Secret(path string) map[string]interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.secrets[secretName(path)]
+}
+
+// Writes returns the number of secret writes that succeeded.
+func (v *VaultServer) Writes() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.writes
+}
+
+// Logins returns the number of logins that succeeded.
+func (v *VaultServer) Logins() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.logins
+}
+
+// secretName returns path relative to the KV v2 mount, without the data/ segment.
+func secretName(path string) string {
+	name := strings.TrimPrefix(strings.TrimPrefix(path, KVMount), "data/")
+	return strings.Trim(name, "/")
+}
+
+// serveHTTP answers the requests the operator makes during a sync.
+func (v *VaultServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	if v.down {
+		v.mu.Unlock()
+		closeConnection(w)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if path == "sys/health" {
+		sealed := v.sealed
+		v.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"initialized": true, "sealed": sealed, "standby": false})
+		return
+	}
+	if v.sealed {
+		v.mu.Unlock()
+		writeErrors(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return
+	}
+
+	if strings.HasPrefix(path, "auth/") && strings.HasSuffix(path, "/login") {
+		v.logins++
+		token := fmt.Sprintf("token-%d", v.logins)
+		v.tokens[token] = true
+		v.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"auth": map[string]interface{}{
+			"client_token":   token,
+			"accessor":       "accessor-" + token,
+			"lease_duration": 3600,
+		}})
+		return
+	}
+	if !v.tokens[r.Header.Get("X-Vault-Token")] {
+		v.mu.Unlock()
+		writeErrors(w, http.StatusForbidden, "permission denied", "invalid token")
+		return
+	}
+
+	switch {
+	case path == "auth/token/lookup-self":
+		v.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"ttl": 3600}})
+	case strings.HasPrefix(path, "sys/internal/ui/mounts/"):
+		v.mu.Unlock()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{
+			"path": KVMount, "type": "kv", "options": map[string]interface{}{"version": "2"},
+		}})
+	case !strings.HasPrefix(path, KVMount+"data/"):
+		v.mu.Unlock()
+		writeErrors(w, http.StatusNotFound, "unsupported path")
+	case r.Method == http.MethodGet:
+		data, ok := v.secrets[secretName(path)]
+		v.mu.Unlock()
+		if !ok {
+			writeErrors(w, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"data": data}})
+	case r.Method == http.MethodPut || r.Method == http.MethodPost:
+		var body struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			v.mu.Unlock()
+			writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		v.secrets[secretName(path)] = body.Data
+		v.writes++
+		var fault func()
+		if v.fault != nil && v.writes >= v.faultAfter {
+			fault, v.fault = v.fault, nil
+		}
+		v.mu.Unlock()
+		if fault != nil {
+			fault()
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": map[string]interface{}{"version": 1}})
+	case r.Method == http.MethodDelete:
+		delete(v.secrets, secretName(path))
+		v.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		v.mu.Unlock()
+		writeErrors(w, http.StatusMethodNotAllowed)
+	}
+}
+
+// closeConnection drops the connection of a request without writing a response.
+func closeConnection(w http.ResponseWriter) {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		panic("chaos: response writer does not support hijacking")
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		panic(fmt.Sprintf("chaos: failed to hijack connection: %v", err))
+	}
+	_ = conn.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeErrors(w http.ResponseWriter, status int, errors ...string) {
+	if errors == nil {
+		errors = []string{}
+	}
+	writeJSON(w, status, map[string]interface{}{"errors": errors})
+}